@@ -0,0 +1,504 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	genCmd = &cobra.Command{
+		Use:   "gen",
+		Short: "Code generation commands",
+	}
+
+	genIOCmd = &cobra.Command{
+		Use:   "io <name>",
+		Short: "Generate the skeleton of a source and a batched sink",
+		Long: `Generate the skeleton of an IO package with a splittable source, a batched
+sink, a client interface and tests against an in-memory fake. The generated
+package compiles and its tests pass as-is; the places that need a real client
+are marked with TODOs.
+
+The source is a splittable DoFn that reads a range of record offsets with an
+offset range restriction tracker. It checkpoints when no more records are
+available and reports the watermark of the records read so far, as estimated
+by a watermark estimator.`,
+		RunE: genIOFn,
+		Args: cobra.ExactArgs(1),
+	}
+
+	genPackage string
+	genOutput  string
+)
+
+func init() {
+	genCmd.AddCommand(genIOCmd)
+	genIOCmd.Flags().StringVarP(&genPackage, "package", "p", "", "Package name. Defaults to the lowercase name with an \"io\" suffix")
+	genIOCmd.Flags().StringVarP(&genOutput, "output", "o", "", "Output directory. Defaults to the package name")
+}
+
+// ioTemplate holds the values for the IO templates.
+type ioTemplate struct {
+	// Name is the name of the external system, such as "Foo".
+	Name string
+	// Package is the package name, such as "fooio".
+	Package string
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+func genIOFn(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if !identifier.MatchString(name) {
+		return fmt.Errorf("invalid name %q: must be alphanumeric and start with a letter", name)
+	}
+
+	top := ioTemplate{Name: strings.Title(name), Package: genPackage}
+	if top.Package == "" {
+		top.Package = strings.ToLower(name) + "io"
+	}
+	dir := genOutput
+	if dir == "" {
+		dir = top.Package
+	}
+
+	paths, err := generateIO(dir, top)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		cmd.Println(path)
+	}
+	return nil
+}
+
+// generateIO writes the IO package files to the given directory. It returns
+// the paths of the files written.
+func generateIO(dir string, top ioTemplate) ([]string, error) {
+	files := map[string]*template.Template{
+		top.Package + ".go":      ioSourceTmpl,
+		top.Package + "_test.go": ioTestTmpl,
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	for filename := range files {
+		if _, err := os.Stat(filepath.Join(dir, filename)); err == nil {
+			return nil, fmt.Errorf("file %v already exists", filepath.Join(dir, filename))
+		}
+	}
+
+	var paths []string
+	for filename, tmpl := range files {
+		path := filepath.Join(dir, filename)
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, top); err != nil {
+			return nil, fmt.Errorf("failed to generate %v: %v", path, err)
+		}
+		data, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %v: %v", path, err)
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+var ioSourceTmpl = template.Must(template.New("source").Parse(`// Package {{.Package}} contains transforms for reading from and writing to {{.Name}}.
+package {{.Package}}
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/rtrackers/offsetrange"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*sizeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*readFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeFn)(nil)).Elem())
+}
+
+// Client is the interface to {{.Name}} used by the transforms. Tests may
+// substitute a fake by replacing NewClient.
+type Client interface {
+	// Size returns the number of records in the given table.
+	Size(ctx context.Context, table string) (int64, error)
+	// Read reads the records of the given table in offset order, starting
+	// at the given offset, until emit returns false or no more records are
+	// available for now.
+	Read(ctx context.Context, table string, offset int64, emit func(offset int64, ts time.Time, record []byte) bool) error
+	// Write writes a batch of records to the given table.
+	Write(ctx context.Context, table string, records [][]byte) error
+	// Close releases the resources held by the client.
+	Close() error
+}
+
+// NewClient returns a client for the given endpoint. It is called on the
+// workers, once per DoFn instance.
+var NewClient = func(ctx context.Context, endpoint string) (Client, error) {
+	// TODO: connect to {{.Name}}.
+	return nil, errors.New("{{.Package}}: client not implemented")
+}
+
+// PollInterval is the delay before records that are not yet available are
+// read again.
+var PollInterval = 5 * time.Second
+
+// Read reads all records of the given table as a PCollection<[]byte>, with
+// the timestamps of the records. The range of records is initially split
+// into the given number of shards, which are read in parallel and may be
+// split further by the runner.
+func Read(s beam.Scope, endpoint, table string, shards int) beam.PCollection {
+	s = s.Scope("{{.Package}}.Read")
+
+	imp := beam.Impulse(s)
+	size := beam.ParDo(s, &sizeFn{Endpoint: endpoint, Table: table}, imp)
+	return beam.ParDo(s, &readFn{Endpoint: endpoint, Table: table, Shards: shards}, size)
+}
+
+// sizeFn emits the number of records in the table, which determines the
+// initial restriction of the read.
+type sizeFn struct {
+	Endpoint string
+	Table    string
+}
+
+func (f *sizeFn) ProcessElement(ctx context.Context, _ []byte) (int64, error) {
+	client, err := NewClient(ctx, f.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	return client.Size(ctx, f.Table)
+}
+
+// readFn is a splittable DoFn that reads the records of the table in the
+// range of offsets of its restriction.
+type readFn struct {
+	Endpoint string
+	Table    string
+	Shards   int
+
+	client Client
+}
+
+func (f *readFn) CreateInitialRestriction(size int64) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: size}
+}
+
+func (f *readFn) SplitRestriction(size int64, r offsetrange.Restriction) []offsetrange.Restriction {
+	return r.EvenSplits(int64(f.Shards))
+}
+
+func (f *readFn) CreateTracker(r offsetrange.Restriction) *offsetrange.Tracker {
+	return offsetrange.NewTracker(r)
+}
+
+func (f *readFn) Setup(ctx context.Context) error {
+	client, err := NewClient(ctx, f.Endpoint)
+	if err != nil {
+		return err
+	}
+	f.client = client
+	return nil
+}
+
+func (f *readFn) ProcessElement(ctx context.Context, rt *offsetrange.Tracker, _ int64, emit func(beam.EventTime, []byte)) (sdf.ProcessContinuation, error) {
+	var we watermarkEstimator
+
+	start := rt.GetRestriction().(offsetrange.Restriction).Start
+	err := f.client.Read(ctx, f.Table, start, func(offset int64, ts time.Time, record []byte) bool {
+		if !rt.TryClaim(offset) {
+			return false
+		}
+		emit(beam.EventTime(ts), record)
+		we.ObserveTimestamp(ts)
+		return true
+	})
+	if err != nil {
+		return sdf.StopProcessing(), err
+	}
+	if rt.IsDone() {
+		return sdf.StopProcessing(), nil
+	}
+
+	// Not all records of the restriction are available yet. Checkpoint the
+	// rest and read it later. The output watermark is held at the latest
+	// record read until then.
+	return sdf.ResumeProcessingIn(PollInterval).WithWatermark(we.CurrentWatermark()), nil
+}
+
+func (f *readFn) Teardown() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
+
+// watermarkEstimator estimates the output watermark as the latest timestamp
+// observed. It assumes that records are read in about timestamp order.
+// TODO: adjust the estimate to how {{.Name}} orders records.
+type watermarkEstimator struct {
+	watermark time.Time
+}
+
+// ObserveTimestamp records the timestamp of a record that was output.
+func (e *watermarkEstimator) ObserveTimestamp(ts time.Time) {
+	if ts.After(e.watermark) {
+		e.watermark = ts
+	}
+}
+
+// CurrentWatermark returns the estimated watermark. It is the zero time, if
+// no record was observed.
+func (e *watermarkEstimator) CurrentWatermark() time.Time {
+	return e.watermark
+}
+
+// WriteOptions represents options for writing to {{.Name}}.
+type WriteOptions struct {
+	// BatchSize is the maximum number of records per write. Defaults to 500.
+	BatchSize int
+}
+
+// Write writes a PCollection<[]byte> to the given table in batches.
+func Write(s beam.Scope, endpoint, table string, col beam.PCollection, opts *WriteOptions) {
+	s = s.Scope("{{.Package}}.Write")
+
+	fn := &writeFn{Endpoint: endpoint, Table: table, BatchSize: 500}
+	if opts != nil && opts.BatchSize > 0 {
+		fn.BatchSize = opts.BatchSize
+	}
+	beam.ParDo0(s, fn, col)
+}
+
+// writeFn buffers records and writes them in batches. Pending records are
+// written at the end of each bundle, so that a failed write fails the bundle.
+type writeFn struct {
+	Endpoint  string
+	Table     string
+	BatchSize int
+
+	client Client
+	batch  [][]byte
+}
+
+func (f *writeFn) Setup(ctx context.Context) error {
+	client, err := NewClient(ctx, f.Endpoint)
+	if err != nil {
+		return err
+	}
+	f.client = client
+	return nil
+}
+
+func (f *writeFn) StartBundle() {
+	f.batch = nil
+}
+
+func (f *writeFn) ProcessElement(ctx context.Context, record []byte) error {
+	f.batch = append(f.batch, record)
+	if len(f.batch) < f.BatchSize {
+		return nil
+	}
+	return f.flush(ctx)
+}
+
+func (f *writeFn) FinishBundle(ctx context.Context) error {
+	return f.flush(ctx)
+}
+
+func (f *writeFn) flush(ctx context.Context) error {
+	if len(f.batch) == 0 {
+		return nil
+	}
+	if err := f.client.Write(ctx, f.Table, f.batch); err != nil {
+		return err
+	}
+	f.batch = nil
+	return nil
+}
+
+func (f *writeFn) Teardown() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
+`))
+
+var ioTestTmpl = template.Must(template.New("test").Parse(`package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+// fakeClient is an in-memory Client. The offset of a record is its index in
+// the table and its timestamp is that many seconds after the epoch. Reads
+// return at most pageSize records, if set, as if the rest were not yet
+// available.
+type fakeClient struct {
+	mu       sync.Mutex
+	tables   map[string][][]byte
+	pageSize int
+	reads    int
+	batches  int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{tables: make(map[string][][]byte)}
+}
+
+func (c *fakeClient) Size(ctx context.Context, table string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return int64(len(c.tables[table])), nil
+}
+
+func (c *fakeClient) Read(ctx context.Context, table string, offset int64, emit func(int64, time.Time, []byte) bool) error {
+	c.mu.Lock()
+	records := c.tables[table]
+	c.reads++
+	c.mu.Unlock()
+
+	for i := offset; i < int64(len(records)); i++ {
+		if c.pageSize > 0 && i-offset == int64(c.pageSize) {
+			return nil
+		}
+		if !emit(i, time.Unix(i, 0), records[i]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *fakeClient) Write(ctx context.Context, table string, records [][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tables[table] = append(c.tables[table], records...)
+	c.batches++
+	return nil
+}
+
+func (c *fakeClient) Close() error {
+	return nil
+}
+
+// useFake replaces NewClient with the given fake. It returns a function that
+// restores the original.
+func useFake(fake *fakeClient) func() {
+	orig := NewClient
+	NewClient = func(ctx context.Context, endpoint string) (Client, error) {
+		return fake, nil
+	}
+	return func() { NewClient = orig }
+}
+
+func toStringFn(b []byte) string {
+	return string(b)
+}
+
+func init() {
+	beam.RegisterFunction(toStringFn)
+}
+
+func TestRead(t *testing.T) {
+	defer func(d time.Duration) { PollInterval = d }(PollInterval)
+	PollInterval = time.Millisecond
+
+	for _, pageSize := range []int{0, 1} {
+		fake := newFakeClient()
+		fake.tables["t"] = [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+		fake.pageSize = pageSize
+		restore := useFake(fake)
+
+		p := beam.NewPipeline()
+		s := p.Root()
+		records := Read(s, "fake", "t", 2)
+		passert.Equals(s, beam.ParDo(s, toStringFn, records), "a", "b", "c", "d", "e")
+
+		err := ptest.Run(p)
+		restore()
+		if err != nil {
+			t.Fatalf("pipeline with page size %v failed: %v", pageSize, err)
+		}
+		if pageSize > 0 && fake.reads < 5 {
+			t.Errorf("read with page size %v took %v reads, want a checkpoint after each record", pageSize, fake.reads)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	fake := newFakeClient()
+	defer useFake(fake)()
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	records := beam.CreateList(s, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	Write(s, "fake", "t", records, &WriteOptions{BatchSize: 2})
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+
+	var got []string
+	for _, record := range fake.tables["t"] {
+		got = append(got, string(record))
+	}
+	sort.Strings(got)
+	if fmt.Sprint(got) != "[a b c]" {
+		t.Errorf("Write wrote %v, want [a b c]", got)
+	}
+	if fake.batches < 2 {
+		t.Errorf("Write wrote %v batches, want at least 2", fake.batches)
+	}
+}
+`))
@@ -0,0 +1,63 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerateIO verifies that the generated IO package compiles and that
+// its tests pass.
+func TestGenerateIO(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compilation of generated code in short mode")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	// Generate into the source tree, so that the SDK imports resolve.
+
+	dir, err := ioutil.TempDir(".", "gentest")
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths, err := generateIO(dir, ioTemplate{Name: "Foo", Package: "fooio"})
+	if err != nil {
+		t.Fatalf("generateIO failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, "fooio.go"), filepath.Join(dir, "fooio_test.go")}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("generateIO wrote %v, want %v", paths, want)
+	}
+	if _, err := generateIO(dir, ioTemplate{Name: "Foo", Package: "fooio"}); err == nil {
+		t.Errorf("generateIO overwrote existing files, want error")
+	}
+
+	for _, args := range [][]string{{"vet"}, {"test", "-count=1"}} {
+		cmd := exec.Command(gobin, append(args, "./"+dir)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("go %v of generated package failed: %v\n%s", args[0], err, out)
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"flag"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -114,6 +115,10 @@ func main() {
 		log.Fatalf("Failed to copy worker binary: %v", err)
 	}
 
+	// (4) If the previous worker crashed, report its last breadcrumb. The
+	// worker reports it to the runner as well, once logging is set up.
+	reportCrash(filepath.Join(*semiPersistDir, breadcrumbName))
+
 	args := []string{
 		"--worker=true",
 		"--id=" + *id,
@@ -125,6 +130,18 @@ func main() {
 	log.Fatalf("User program exited: %v", execx.Execute(prog, args...))
 }
 
+// breadcrumbName is the name of the harness crash breadcrumb. It must match
+// harness.BreadcrumbName.
+const breadcrumbName = "crash_breadcrumb.json"
+
+func reportCrash(filename string) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return // ok: no crash
+	}
+	log.Printf("Previous worker crashed. Last breadcrumb: %s", data)
+}
+
 func copyExe(from, to string) error {
 	src, err := os.Open(from)
 	if err != nil {
@@ -21,7 +21,6 @@ import (
 	"reflect"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
-	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/coderx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
@@ -80,6 +79,46 @@ func UnwrapCoder(c Coder) *coder.Coder {
 	return c.coder
 }
 
+// IntegerEncoding is the encoding of inferred coders for integer types.
+type IntegerEncoding int
+
+const (
+	// ZigZagEncoding encodes signed integers as zig-zag varints and unsigned
+	// integers as varints. Small values of either sign are compact. It is the
+	// default.
+	ZigZagEncoding IntegerEncoding = iota
+	// VarIntEncoding encodes integers as varints without the zig-zag scheme,
+	// as in the Beam standard coding scheme. Negative values take 10 bytes.
+	VarIntEncoding
+	// FixedEncoding encodes integers as fixed-width big-endian values.
+	FixedEncoding
+)
+
+var integerEncoding = ZigZagEncoding
+
+// SetIntegerEncoding selects the encoding of inferred coders for integer types.
+// It applies to coders inferred after the call, so it should be called before
+// pipeline construction. Coders of existing PCollections are not changed.
+func SetIntegerEncoding(e IntegerEncoding) {
+	integerEncoding = e
+}
+
+func newIntegerCoder(t reflect.Type) (*coder.CustomCoder, error) {
+	if integerEncoding == FixedEncoding {
+		return coderx.NewFixedInt(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return coderx.NewVarUintZ(t) // no sign: zig-zag is a no-op
+	default:
+		if integerEncoding == VarIntEncoding {
+			return coderx.NewVarInt(t)
+		}
+		return coderx.NewVarIntZ(t)
+	}
+}
+
 // NewCoder infers a Coder for any bound full type.
 func NewCoder(t FullType) Coder {
 	c, err := inferCoder(t)
@@ -95,20 +134,22 @@ func inferCoder(t FullType) (*coder.Coder, error) {
 	switch t.Class() {
 	case typex.Concrete, typex.Container:
 		switch t.Type() {
-		case reflectx.Int, reflectx.Int8, reflectx.Int16, reflectx.Int32, reflectx.Int64:
-			c, err := coderx.NewVarIntZ(t.Type())
+		case reflectx.Int, reflectx.Int8, reflectx.Int16, reflectx.Int32, reflectx.Int64,
+			reflectx.Uint, reflectx.Uint8, reflectx.Uint16, reflectx.Uint32, reflectx.Uint64:
+			c, err := newIntegerCoder(t.Type())
 			if err != nil {
 				return nil, err
 			}
 			return &coder.Coder{Kind: coder.Custom, T: t, Custom: c}, nil
-		case reflectx.Uint, reflectx.Uint8, reflectx.Uint16, reflectx.Uint32, reflectx.Uint64:
-			c, err := coderx.NewVarUintZ(t.Type())
+		case reflectx.Float32, reflectx.Float64:
+			c, err := coderx.NewFloat(t.Type())
 			if err != nil {
 				return nil, err
 			}
 			return &coder.Coder{Kind: coder.Custom, T: t, Custom: c}, nil
-		case reflectx.Float32, reflectx.Float64:
-			c, err := coderx.NewFloat(t.Type())
+
+		case reflectx.Time:
+			c, err := coderx.NewTime(t.Type())
 			if err != nil {
 				return nil, err
 			}
@@ -128,6 +169,27 @@ func inferCoder(t FullType) (*coder.Coder, error) {
 				}
 				return &coder.Coder{Kind: coder.Custom, T: t, Custom: c}, nil
 			}
+			if coderx.IsBig(t.Type()) {
+				c, err := coderx.NewBig(t.Type())
+				if err != nil {
+					return nil, err
+				}
+				return &coder.Coder{Kind: coder.Custom, T: t, Custom: c}, nil
+			}
+			if typex.IsMap(t.Type()) {
+				c, err := coderx.NewMap(t.Type())
+				if err != nil {
+					return nil, err
+				}
+				return &coder.Coder{Kind: coder.Custom, T: t, Custom: c}, nil
+			}
+			if t.Type().Kind() == reflect.Ptr {
+				c, err := coderx.NewNullable(t.Type())
+				if err != nil {
+					return nil, err
+				}
+				return &coder.Coder{Kind: coder.Custom, T: t, Custom: c}, nil
+			}
 
 			c, err := newJSONCoder(t.Type())
 			if err != nil {
@@ -148,7 +210,7 @@ func inferCoder(t FullType) (*coder.Coder, error) {
 		case typex.CoGBKType:
 			return &coder.Coder{Kind: coder.CoGBK, T: t, Components: c}, nil
 		case typex.WindowedValueType:
-			return &coder.Coder{Kind: coder.WindowedValue, T: t, Components: c, Window: window.NewGlobalWindow()}, nil
+			return &coder.Coder{Kind: coder.WindowedValue, T: t, Components: c, Window: coder.NewGlobalWindow()}, nil
 
 		default:
 			panic(fmt.Sprintf("Unexpected composite type: %v", t))
@@ -222,3 +284,35 @@ func newJSONCoder(t reflect.Type) (*coder.CustomCoder, error) {
 	}
 	return c, nil
 }
+
+// JSONOptions configure the encoding of an explicit JSON coder, notably the
+// field naming strategy and strictness on unknown fields.
+type JSONOptions = coderx.JSONOptions
+
+// Field naming strategies for JSONOptions.
+const (
+	JSONFieldName = coderx.JSONFieldName
+	JSONSnakeCase = coderx.JSONSnakeCase
+	JSONCamelCase = coderx.JSONCamelCase
+	JSONLowerCase = coderx.JSONLowerCase
+)
+
+// NewJSONCoder returns an explicit JSON coder for the given concrete type with
+// the given options. The options are part of the coder, so PCollections of the
+// same type may use different options. It is not inferred by default and must
+// be attached to PCollections with SetCoder. The type must be registered with
+// RegisterType. For example:
+//
+//    events := beam.ParDo(s, parseFn, lines)
+//    events.SetCoder(beam.NewJSONCoder(reflect.TypeOf(Event{}), beam.JSONOptions{
+//        Naming:                beam.JSONSnakeCase,
+//        DisallowUnknownFields: true,
+//    }))
+//
+func NewJSONCoder(t reflect.Type, opts JSONOptions) Coder {
+	c, err := coderx.NewJSON(t, opts)
+	if err != nil {
+		panic(fmt.Sprintf("invalid coder: %v", err))
+	}
+	return Coder{&coder.Coder{Kind: coder.Custom, T: typex.New(t), Custom: c}}
+}
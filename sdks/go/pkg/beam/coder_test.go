@@ -16,9 +16,11 @@
 package beam_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 )
 
@@ -41,3 +43,27 @@ func TestJSONCoder(t *testing.T) {
 		}
 	}
 }
+
+func TestIntegerEncoding(t *testing.T) {
+	defer beam.SetIntegerEncoding(beam.ZigZagEncoding)
+
+	tests := []struct {
+		enc  beam.IntegerEncoding
+		t    reflect.Type
+		want string
+	}{
+		{beam.ZigZagEncoding, reflectx.Int32, "int32[varintz]"},
+		{beam.ZigZagEncoding, reflectx.Uint, "uint[varuintz]"},
+		{beam.VarIntEncoding, reflectx.Int64, "int64[varint]"},
+		{beam.VarIntEncoding, reflectx.Uint8, "uint8[varuintz]"},
+		{beam.FixedEncoding, reflectx.Int, "int[fixedint]"},
+		{beam.FixedEncoding, reflectx.Uint16, "uint16[fixedint]"},
+	}
+
+	for _, test := range tests {
+		beam.SetIntegerEncoding(test.enc)
+		if got := beam.NewCoder(typex.New(test.t)).String(); got != test.want {
+			t.Errorf("NewCoder(%v) with encoding %v = %v, want %v", test.t, test.enc, got, test.want)
+		}
+	}
+}
@@ -17,20 +17,30 @@ package beam
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 )
 
+func init() {
+	RegisterType(reflect.TypeOf((*combineFuncs)(nil)).Elem())
+}
+
 // Combine inserts a global Combine transform into the pipeline. It
-// expects a PCollection<T> as input where T is a concrete type.
+// expects a PCollection<T> as input where T is a concrete type. The
+// combinefn is either a binary merge function, (A, A) -> A, a struct with
+// the CombineFn methods or the result of CombineFuncs.
 func Combine(s Scope, combinefn interface{}, col PCollection) PCollection {
 	return Must(TryCombine(s, combinefn, col))
 }
 
 // CombinePerKey inserts a GBK and per-key Combine transform into the pipeline. It
 // expects a PCollection<KV<K,T>>. The CombineFn may optionally take a key parameter.
-func CombinePerKey(s Scope, combinefn interface{}, col PCollection) PCollection {
-	return Must(TryCombinePerKey(s, combinefn, col))
+// Options, such as WithHotKeyFanout, may be given.
+func CombinePerKey(s Scope, combinefn interface{}, col PCollection, opts ...CombineOption) PCollection {
+	return Must(TryCombinePerKey(s, combinefn, col, opts...))
 }
 
 // TryCombine attempts to insert a global Combine transform into the pipeline. It may fail
@@ -48,8 +58,11 @@ func TryCombine(s Scope, combinefn interface{}, col PCollection) (PCollection, e
 // TryCombinePerKey attempts to insert a per-key Combine transform into the pipeline. It may fail
 // for multiple reasons, notably that the combinefn is not valid or cannot be bound
 // -- due to type mismatch, say -- to the incoming PCollection.
-func TryCombinePerKey(s Scope, combinefn interface{}, col PCollection) (PCollection, error) {
+func TryCombinePerKey(s Scope, combinefn interface{}, col PCollection, opts ...CombineOption) (PCollection, error) {
 	ValidateKVType(col)
+	if fanout := parseCombineOpts(opts); fanout != nil {
+		return tryCombinePerKeyWithFanout(s, combinefn, col, fanout)
+	}
 	col, err := TryGroupByKey(s, col)
 	if err != nil {
 		return PCollection{}, fmt.Errorf("failed to group by key: %v", err)
@@ -62,12 +75,76 @@ func combine(s Scope, combinefn interface{}, col PCollection) (PCollection, erro
 	if err != nil {
 		return PCollection{}, fmt.Errorf("invalid CombineFn: %v", err)
 	}
+	if a := fn.AccumulatorType(); typex.IsConcrete(a) || typex.IsContainer(a) {
+		// Accumulators are encoded if partially combined values are shipped
+		// between workers, so reject accumulators without a coder upfront.
+		if _, err := inferCoder(typex.New(a)); err != nil {
+			return PCollection{}, fmt.Errorf("invalid CombineFn: no coder for accumulator %v: %v", a, err)
+		}
+	}
 
 	edge, err := graph.NewCombine(s.real, s.scope, fn, col.n)
 	if err != nil {
 		return PCollection{}, err
 	}
+	edge.Label = s.name
 	ret := PCollection{edge.Output[0].To}
 	ret.SetCoder(NewCoder(ret.Type()))
 	return ret, nil
 }
+
+// CombineFuncs returns a CombineFn of the four-function form, as an
+// alternative to a struct with the CombineFn methods:
+//
+//    create:  () -> A
+//    addInput: (A, [K,] I) -> A
+//    merge:   (A, A) -> A
+//    extract: A -> O
+//
+// All functions but merge may be nil. For example:
+//
+//    mean := beam.Combine(s, beam.CombineFuncs(nil, addFn, mergeFn, extractFn), col)
+//
+// The functions must be registered if the pipeline executes remotely.
+func CombineFuncs(create, addInput, merge, extract interface{}) interface{} {
+	if merge == nil {
+		panic("CombineFuncs: merge function must be non-nil")
+	}
+	return &combineFuncs{
+		CreateAccumulator: encodedFuncOrNil(create),
+		AddInput:          encodedFuncOrNil(addInput),
+		MergeAccumulators: encodedFuncOrNil(merge),
+		ExtractOutput:     encodedFuncOrNil(extract),
+	}
+}
+
+func encodedFuncOrNil(fn interface{}) *EncodedFunc {
+	if fn == nil {
+		return nil
+	}
+	return &EncodedFunc{Fn: reflectx.MakeFunc(fn)}
+}
+
+// combineFuncs is a CombineFn given as individual functions.
+type combineFuncs struct {
+	CreateAccumulator *EncodedFunc `json:"create,omitempty"`
+	AddInput          *EncodedFunc `json:"add,omitempty"`
+	MergeAccumulators *EncodedFunc `json:"merge"`
+	ExtractOutput     *EncodedFunc `json:"extract,omitempty"`
+}
+
+// Funcs returns the present functions by CombineFn method name.
+func (f *combineFuncs) Funcs() map[string]reflectx.Func {
+	ret := make(map[string]reflectx.Func)
+	for name, fn := range map[string]*EncodedFunc{
+		"CreateAccumulator": f.CreateAccumulator,
+		"AddInput":          f.AddInput,
+		"MergeAccumulators": f.MergeAccumulators,
+		"ExtractOutput":     f.ExtractOutput,
+	} {
+		if fn != nil {
+			ret[name] = fn.Fn
+		}
+	}
+	return ret
+}
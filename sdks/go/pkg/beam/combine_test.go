@@ -0,0 +1,162 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+type meanAccum struct {
+	Sum, Count int
+}
+
+func addMean(a meanAccum, x int) meanAccum {
+	return meanAccum{Sum: a.Sum + x, Count: a.Count + 1}
+}
+
+func mergeMean(a, b meanAccum) meanAccum {
+	return meanAccum{Sum: a.Sum + b.Sum, Count: a.Count + b.Count}
+}
+
+func extractMean(a meanAccum) float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return float64(a.Sum) / float64(a.Count)
+}
+
+func init() {
+	beam.RegisterFunction(addMean)
+	beam.RegisterFunction(mergeMean)
+	beam.RegisterFunction(extractMean)
+	beam.RegisterType(reflect.TypeOf((*meanFn)(nil)).Elem())
+}
+
+func TestCombineFuncs(t *testing.T) {
+	p, s, col := ptest.Create([]interface{}{1, 2, 3, 6})
+	mean := beam.Combine(s, beam.CombineFuncs(nil, addMean, mergeMean, extractMean), col)
+	passert.Equals(s, mean, 3.0)
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Combine(CombineFuncs) failed: %v", err)
+	}
+}
+
+type badMergeFn struct{}
+
+func (badMergeFn) MergeAccumulators(a int, b string) int { return a }
+
+type badAddFn struct{}
+
+func (badAddFn) AddInput(a string, b int) string { return a }
+func (badAddFn) MergeAccumulators(a, b int) int  { return a }
+
+type badExtractFn struct{}
+
+func (badExtractFn) MergeAccumulators(a, b int) int { return a }
+func (badExtractFn) ExtractOutput(a string) string  { return a }
+
+type noCoderFn struct{}
+
+func (noCoderFn) AddInput(a chan int, b int) chan int      { return a }
+func (noCoderFn) MergeAccumulators(a, b chan int) chan int { return a }
+func (noCoderFn) ExtractOutput(a chan int) int             { return 0 }
+
+func TestCombineInvalid(t *testing.T) {
+	tests := []interface{}{
+		&badMergeFn{},
+		&badAddFn{},
+		&badExtractFn{},
+		&noCoderFn{},
+		beam.CombineFuncs(nil, addMean, mergeMean, func(a int) int { return a }),
+	}
+
+	for _, fn := range tests {
+		p := beam.NewPipeline()
+		s := p.Root()
+		col := beam.Create(s, 1, 2, 3)
+		if _, err := beam.TryCombine(s, fn, col); err == nil {
+			t.Errorf("TryCombine(%T) succeeded, want error", fn)
+		}
+	}
+}
+
+// meanFn is a CombineFn with a list merge.
+type meanFn struct{}
+
+func (meanFn) AddInput(a meanAccum, x int) meanAccum { return addMean(a, x) }
+func (meanFn) ExtractOutput(a meanAccum) float64     { return extractMean(a) }
+
+func (meanFn) MergeAccumulators(list []meanAccum) meanAccum {
+	var ret meanAccum
+	for _, a := range list {
+		ret = mergeMean(ret, a)
+	}
+	return ret
+}
+
+func sumInts(a, b int) int {
+	return a + b
+}
+
+func hotKeys(key string) int {
+	if key == "hot" {
+		return 8
+	}
+	return 0
+}
+
+func formatKV(k string, v beam.T) string {
+	return fmt.Sprintf("%v:%v", k, v)
+}
+
+func TestCombinePerKeyWithHotKeyFanout(t *testing.T) {
+	var kvs []interface{}
+	for i := 1; i <= 100; i++ {
+		kvs = append(kvs, i)
+	}
+
+	tests := []struct {
+		fn     interface{}
+		fanout interface{}
+		exp    []interface{}
+	}{
+		{sumInts, 4, []interface{}{"hot:5050", "cold:1"}},
+		{sumInts, hotKeys, []interface{}{"hot:5050", "cold:1"}},
+		{&meanFn{}, 3, []interface{}{"hot:50.5", "cold:1"}},
+		{beam.CombineFuncs(nil, addMean, mergeMean, extractMean), hotKeys, []interface{}{"hot:50.5", "cold:1"}},
+	}
+
+	for _, test := range tests {
+		p, s, col := ptest.Create(kvs)
+		hot := beam.ParDo(s, func(v int) (string, int) { return "hot", v }, col)
+		cold := beam.ParDo(s, func(v int) (string, int) { return "cold", 1 }, beam.Create(s, 1))
+		in := beam.Flatten(s, hot, cold)
+
+		out := beam.CombinePerKey(s, test.fn, in, beam.WithHotKeyFanout(test.fanout))
+		passert.Equals(s, beam.ParDo(s, formatKV, out), test.exp...)
+
+		if err := ptest.Run(p); err != nil {
+			t.Errorf("CombinePerKey(%T, fanout=%v) failed: %v", test.fn, test.fanout, err)
+		}
+	}
+}
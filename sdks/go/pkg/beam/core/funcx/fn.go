@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 )
@@ -65,6 +66,9 @@ const (
 	// FnType indicates a function input parameter that is a type for a coder. It
 	// is only valid for coders.
 	FnType FnParamKind = 0x40
+	// FnRTracker indicates a function input parameter that implements
+	// sdf.RTracker. It marks the DoFn as splittable.
+	FnRTracker FnParamKind = 0x80
 )
 
 func (k FnParamKind) String() string {
@@ -83,6 +87,8 @@ func (k FnParamKind) String() string {
 		return "Emit"
 	case FnType:
 		return "Type"
+	case FnRTracker:
+		return "RTracker"
 	default:
 		return fmt.Sprintf("%v", int(k))
 	}
@@ -103,6 +109,8 @@ const (
 	RetEventTime ReturnKind = 0x1
 	RetValue     ReturnKind = 0x2
 	RetError     ReturnKind = 0x4
+
+	RetProcessContinuation ReturnKind = 0x8
 )
 
 func (k ReturnKind) String() string {
@@ -113,6 +121,8 @@ func (k ReturnKind) String() string {
 		return "EventTime"
 	case RetValue:
 		return "Value"
+	case RetProcessContinuation:
+		return "ProcessContinuation"
 	default:
 		return fmt.Sprintf("%v", int(k))
 	}
@@ -164,6 +174,17 @@ func (u *Fn) EventTime() (pos int, exists bool) {
 	return -1, false
 }
 
+// RTracker returns (index, true) iff the function expects a restriction
+// tracker.
+func (u *Fn) RTracker() (pos int, exists bool) {
+	for i, p := range u.Param {
+		if p.Kind == FnRTracker {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
 // Error returns (index, true) iff the function returns an error.
 func (u *Fn) Error() (pos int, exists bool) {
 	for i, p := range u.Ret {
@@ -184,6 +205,17 @@ func (u *Fn) OutEventTime() (pos int, exists bool) {
 	return -1, false
 }
 
+// ProcessContinuation returns (index, true) iff the function returns an
+// sdf.ProcessContinuation.
+func (u *Fn) ProcessContinuation() (pos int, exists bool) {
+	for i, p := range u.Ret {
+		if p.Kind == RetProcessContinuation {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
 // Params returns the parameter indices that matches the given mask.
 func (u *Fn) Params(mask FnParamKind) []int {
 	var ret []int
@@ -226,7 +258,9 @@ func New(fn reflectx.Func) (*Fn, error) {
 			kind = FnEventTime
 		case t == reflectx.Type:
 			kind = FnType
-		case typex.IsContainer(t), typex.IsConcrete(t), typex.IsUniversal(t):
+		case t.Implements(sdf.RTrackerType):
+			kind = FnRTracker
+		case typex.IsContainer(t), typex.IsConcrete(t), typex.IsNullable(t), typex.IsUniversal(t):
 			kind = FnValue
 		case IsEmit(t):
 			kind = FnEmit
@@ -251,7 +285,9 @@ func New(fn reflectx.Func) (*Fn, error) {
 			kind = RetError
 		case t == typex.EventTimeType:
 			kind = RetEventTime
-		case typex.IsContainer(t), typex.IsConcrete(t), typex.IsUniversal(t):
+		case t == sdf.ProcessContinuationType:
+			kind = RetProcessContinuation
+		case typex.IsContainer(t), typex.IsConcrete(t), typex.IsNullable(t), typex.IsUniversal(t):
 			kind = RetValue
 		default:
 			return nil, fmt.Errorf("bad return type for %s: %v", fn.Name(), t)
@@ -289,7 +325,7 @@ func SubReturns(list []ReturnParam, indices ...int) []ReturnParam {
 }
 
 // The order of present parameters and return values must be as follows:
-// func(FnContext?, FnEventTime?, FnType?, (FnValue, SideInput*)?, FnEmit*) (RetEventTime?, RetEventTime?, RetError?)
+// func(FnContext?, FnEventTime?, FnType?, FnRTracker?, (FnValue, SideInput*)?, FnEmit*) (RetEventTime?, RetValue*, RetProcessContinuation?, RetError?)
 //     where ? indicates 0 or 1, and * indicates any number.
 //     and  a SideInput is one of FnValue or FnIter or FnReIter
 // Note: Fns with inputs must have at least one FnValue as the main input.
@@ -316,6 +352,7 @@ var (
 	errContextParam             = errors.New("may only have a single context.Context parameter and it must be the first parameter")
 	errEventTimeParamPrecedence = errors.New("may only have a single beam.EventTime parameter and it must preceed the main input parameter")
 	errReflectTypePrecedence    = errors.New("may only have a single reflect.Type parameter and it must preceed the main input parameter")
+	errRTrackerPrecedence       = errors.New("may only have a single sdf.RTracker parameter and it must preceed the main input parameter")
 	errSideInputPrecedence      = errors.New("side input parameters must follow main input parameter")
 	errInputPrecedence          = errors.New("inputs parameters must preceed emit function parameters")
 )
@@ -327,6 +364,7 @@ const (
 	psContext
 	psEventTime
 	psType
+	psRTracker
 	psInput
 	psOutput
 )
@@ -341,6 +379,8 @@ func nextParamState(cur paramState, transition FnParamKind) (paramState, error)
 			return psEventTime, nil
 		case FnType:
 			return psType, nil
+		case FnRTracker:
+			return psRTracker, nil
 		}
 	case psContext:
 		switch transition {
@@ -348,13 +388,22 @@ func nextParamState(cur paramState, transition FnParamKind) (paramState, error)
 			return psEventTime, nil
 		case FnType:
 			return psType, nil
+		case FnRTracker:
+			return psRTracker, nil
 		}
 	case psEventTime:
 		switch transition {
 		case FnType:
 			return psType, nil
+		case FnRTracker:
+			return psRTracker, nil
 		}
 	case psType:
+		switch transition {
+		case FnRTracker:
+			return psRTracker, nil
+		}
+	case psRTracker:
 		// Completely handled by the default clause
 	case psInput:
 		switch transition {
@@ -375,6 +424,8 @@ func nextParamState(cur paramState, transition FnParamKind) (paramState, error)
 		return -1, errEventTimeParamPrecedence
 	case FnType:
 		return -1, errReflectTypePrecedence
+	case FnRTracker:
+		return -1, errRTrackerPrecedence
 	case FnValue:
 		return psInput, nil
 	case FnIter, FnReIter:
@@ -389,6 +440,7 @@ func nextParamState(cur paramState, transition FnParamKind) (paramState, error)
 var (
 	errEventTimeRetPrecedence = errors.New("beam.EventTime must be first return parameter")
 	errErrorPrecedence        = errors.New("error must be the final return parameter")
+	errContinuationPrecedence = errors.New("sdf.ProcessContinuation must follow the output return parameters")
 )
 
 type retState int
@@ -397,6 +449,7 @@ const (
 	rsStart retState = iota
 	rsEventTime
 	rsOutput
+	rsContinuation
 	rsError
 )
 
@@ -409,6 +462,11 @@ func nextRetState(cur retState, transition ReturnKind) (retState, error) {
 		}
 	case rsEventTime, rsOutput:
 		// Identical to the default cases.
+	case rsContinuation:
+		switch transition {
+		case RetValue, RetProcessContinuation:
+			return -1, errContinuationPrecedence
+		}
 	case rsError:
 		// This is a terminal state. No valid transitions. error must be the final return value.
 		return -1, errErrorPrecedence
@@ -419,6 +477,8 @@ func nextRetState(cur retState, transition ReturnKind) (retState, error) {
 		return -1, errEventTimeRetPrecedence
 	case RetValue:
 		return rsOutput, nil
+	case RetProcessContinuation:
+		return rsContinuation, nil
 	case RetError:
 		return rsError, nil
 	default:
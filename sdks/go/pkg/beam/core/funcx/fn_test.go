@@ -21,6 +21,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 )
@@ -118,6 +119,32 @@ func TestNew(t *testing.T) {
 			},
 			Err: errEventTimeParamPrecedence,
 		},
+		{
+			Name:  "good-rtracker",
+			Fn:    func(context.Context, sdf.RTracker, string, func(int)) {},
+			Param: []FnParamKind{FnContext, FnRTracker, FnValue, FnEmit},
+		},
+		{
+			Name: "good-continuation",
+			Fn: func(sdf.RTracker, string) (sdf.ProcessContinuation, error) {
+				return sdf.StopProcessing(), nil
+			},
+			Param: []FnParamKind{FnRTracker, FnValue},
+			Ret:   []ReturnKind{RetProcessContinuation, RetError},
+		},
+		{
+			Name: "errContinuationPrecedence: before value",
+			Fn: func(sdf.RTracker, string) (sdf.ProcessContinuation, string) {
+				return sdf.StopProcessing(), ""
+			},
+			Err: errContinuationPrecedence,
+		},
+		{
+			Name: "errRTrackerPrecedence: after value",
+			Fn: func(string, sdf.RTracker) {
+			},
+			Err: errRTrackerPrecedence,
+		},
 		{
 			Name: "errReflectTypePrecedence: after value",
 			Fn: func(int, reflect.Type) {
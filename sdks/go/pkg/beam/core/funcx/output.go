@@ -72,5 +72,5 @@ func UnfoldEmit(t reflect.Type) ([]reflect.Type, bool) {
 }
 
 func isInParam(t reflect.Type) bool {
-	return typex.IsConcrete(t) || typex.IsUniversal(t) || typex.IsContainer(t)
+	return typex.IsConcrete(t) || typex.IsNullable(t) || typex.IsUniversal(t) || typex.IsContainer(t)
 }
@@ -76,7 +76,7 @@ func isOutParam(t reflect.Type) bool {
 	if t.Kind() != reflect.Ptr {
 		return false
 	}
-	return typex.IsConcrete(t.Elem()) || typex.IsUniversal(t.Elem()) || typex.IsContainer(t.Elem())
+	return typex.IsConcrete(t.Elem()) || typex.IsNullable(t.Elem()) || typex.IsUniversal(t.Elem()) || typex.IsContainer(t.Elem())
 }
 
 // IsReIter returns true iff the supplied type is a functional iterator generator.
@@ -69,24 +69,49 @@ func Bind(fn *funcx.Fn, typedefs map[string]reflect.Type, in ...typex.FullType)
 		return nil, nil, nil, nil, err
 	}
 
-	subst, err := typex.Bind(inbound, in)
+	var origins []string
+	for i := range inbound {
+		if i == 0 {
+			origins = append(origins, "main input")
+		} else {
+			origins = append(origins, fmt.Sprintf("side input %v", i))
+		}
+	}
+	subst, trace, err := typex.BindTrace(inbound, in, origins)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
 	for k, v := range typedefs {
 		if _, exists := subst[k]; exists {
-			return nil, nil, nil, nil, fmt.Errorf("type %v already defined by fn", k)
+			return nil, nil, nil, nil, &typex.BindingError{Msg: fmt.Sprintf("type %v already defined by fn", k), Trace: trace}
 		}
 		subst[k] = v
+		trace = append(trace, typex.Binding{Name: k, Type: v, Origin: "type definition"})
 	}
 
-	out, err := typex.Substitute(outbound, subst)
-	if err != nil {
-		return nil, nil, nil, nil, err
+	var out []typex.FullType
+	for i, t := range outbound {
+		repl, err := typex.Substitute([]typex.FullType{t}, subst)
+		if err != nil {
+			return nil, nil, nil, nil, &typex.BindingError{Msg: fmt.Sprintf("failed to bind %v %v: %v", outboundName(fn, i), t, err), Trace: trace}
+		}
+		out = append(out, repl[0])
 	}
 	return inbound, kinds, outbound, out, nil
 }
 
+// outboundName describes the i'th outbound type of the given function: the
+// direct output, if any, comes first.
+func outboundName(fn *funcx.Fn, i int) string {
+	if len(fn.Returns(funcx.RetValue)) > 0 {
+		if i == 0 {
+			return "return value"
+		}
+		i--
+	}
+	return fmt.Sprintf("emitter %v", i)
+}
+
 func findOutbound(fn *funcx.Fn) ([]typex.FullType, error) {
 	ret := trimIllegal(returnTypes(funcx.SubReturns(fn.Ret, fn.Returns(funcx.RetValue)...)))
 	params := funcx.SubParams(fn.Param, fn.Params(funcx.FnEmit)...)
@@ -17,6 +17,7 @@ package graph
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
@@ -205,3 +206,50 @@ func TestBindWithTypedefs(t *testing.T) {
 		}
 	}
 }
+
+func TestBindTrace(t *testing.T) {
+	tests := []struct {
+		In   []typex.FullType
+		Fn   interface{}
+		Want []string // lines of the error, in order
+	}{
+		{ // Main and side input disagree on T
+			[]typex.FullType{
+				typex.NewKV(typex.New(reflectx.String), typex.New(reflectx.Int)),
+				typex.New(reflectx.Int),
+			},
+			func(typex.T, int, typex.T, func(typex.T)) {},
+			[]string{
+				"bind conflict for T: string != int",
+				"binding trace:",
+				"\tT=string (main input KV<T,int>, key)",
+				"\tT=int (side input 1 T)",
+			},
+		},
+		{ // Emitter uses a universal not bound by any input
+			[]typex.FullType{typex.New(reflectx.String)},
+			func(typex.T, func(typex.T, typex.U)) {},
+			[]string{
+				"failed to bind emitter 0 KV<T,U>: type variable not bound: U",
+				"binding trace:",
+				"\tT=string (main input T)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		fn, err := funcx.New(reflectx.MakeFunc(test.Fn))
+		if err != nil {
+			t.Errorf("Invalid Fn: %v", err)
+			continue
+		}
+		_, _, _, _, err = Bind(fn, nil, test.In...)
+		if err == nil {
+			t.Errorf("Bind(%v, %v) succeeded, want error", fn, test.In)
+			continue
+		}
+		if want := strings.Join(test.Want, "\n"); err.Error() != want {
+			t.Errorf("Bind(%v, %v) failed with:\n%v\nwant:\n%v", fn, test.In, err, want)
+		}
+	}
+}
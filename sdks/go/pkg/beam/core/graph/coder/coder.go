@@ -146,9 +146,9 @@ type Coder struct {
 	Kind Kind
 	T    typex.FullType
 
-	Components []*Coder       // WindowedValue, KV, CoGBK
-	Custom     *CustomCoder   // Custom
-	Window     *window.Window // WindowedValue
+	Components []*Coder     // WindowedValue, KV, CoGBK
+	Custom     *CustomCoder // Custom
+	Window     *WindowCoder // WindowedValue
 }
 
 // Equals returns true iff the two coders are equal. It assumes that
@@ -219,8 +219,52 @@ func IsW(c *Coder) bool {
 	return c.Kind == WindowedValue
 }
 
+// WindowKind is the kind of a window coder.
+type WindowKind string
+
+const (
+	// GlobalWindow encodes the global window as the empty string.
+	GlobalWindow WindowKind = "GWC"
+	// IntervalWindow encodes an interval window as its end timestamp and
+	// its size.
+	IntervalWindow WindowKind = "IWC"
+)
+
+// WindowCoder is a description of how to encode and decode windows.
+type WindowCoder struct {
+	Kind WindowKind
+}
+
+// NewGlobalWindow returns a coder for the global window.
+func NewGlobalWindow() *WindowCoder {
+	return &WindowCoder{Kind: GlobalWindow}
+}
+
+// NewIntervalWindow returns a coder for interval windows.
+func NewIntervalWindow() *WindowCoder {
+	return &WindowCoder{Kind: IntervalWindow}
+}
+
+// NewWindowCoder returns the coder for the windows of the given windowing
+// strategy.
+func NewWindowCoder(w *window.Window) *WindowCoder {
+	if w.Kind() == window.GlobalWindow {
+		return NewGlobalWindow()
+	}
+	return NewIntervalWindow()
+}
+
+// Equals returns true iff the window coders are equal.
+func (w *WindowCoder) Equals(o *WindowCoder) bool {
+	return o != nil && w.Kind == o.Kind
+}
+
+func (w *WindowCoder) String() string {
+	return string(w.Kind)
+}
+
 // NewW returns a WindowedValue coder for the window of elements.
-func NewW(c *Coder, w *window.Window) *Coder {
+func NewW(c *Coder, w *WindowCoder) *Coder {
 	if c == nil {
 		panic("coder must not be nil")
 	}
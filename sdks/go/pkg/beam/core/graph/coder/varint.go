@@ -25,24 +25,30 @@ import (
 // this special handling.
 var ErrVarIntTooLong = errors.New("varint too long")
 
+// MaxVarIntLen is the maximum length of a varint-encoded uint64.
+const MaxVarIntLen = 10
+
 // EncodeVarUint64 encodes an uint64.
 func EncodeVarUint64(value uint64, w io.Writer) error {
-	var ret []byte
-	for {
+	var buf [MaxVarIntLen]byte
+	n := PutVarUint64(buf[:], value)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+// PutVarUint64 encodes an uint64 into the given buffer, which must be at least
+// MaxVarIntLen bytes long, and returns the number of bytes written. It allows
+// callers to reuse a scratch buffer.
+func PutVarUint64(buf []byte, value uint64) int {
+	i := 0
+	for value >= 0x80 {
 		// Encode next 7 bits + terminator bit
-		bits := value & 0x7f
+		buf[i] = byte(value) | 0x80
 		value >>= 7
-
-		var mask uint64
-		if value != 0 {
-			mask = 0x80
-		}
-		ret = append(ret, (byte)(bits|mask))
-		if value == 0 {
-			_, err := w.Write(ret)
-			return err
-		}
+		i++
 	}
+	buf[i] = byte(value)
+	return i + 1
 }
 
 // Variable-length encoding for integers.
@@ -55,19 +61,23 @@ func EncodeVarUint64(value uint64, w io.Writer) error {
 // at a time here. If not, we may need a more sophisticated reader than
 // io.Reader with lookahead, say.
 
-// DecodeVarUint64 decodes an uint64.
+// DecodeVarUint64 decodes an uint64. It reads a byte at a time, so readers
+// that implement io.ByteReader are faster.
 func DecodeVarUint64(r io.Reader) (uint64, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &byteReader{r: r}
+	}
+
 	var ret uint64
 	var shift uint
-
-	data := make([]byte, 1)
 	for {
 		// Get 7 bits from next byte
-		if n, err := r.Read(data); n < 1 {
+		b, err := br.ReadByte()
+		if err != nil {
 			return 0, err
 		}
 
-		b := data[0]
 		bits := (uint64)(b & 0x7f)
 
 		if shift >= 64 || (shift == 63 && bits > 1) {
@@ -96,3 +106,19 @@ func DecodeVarInt(r io.Reader) (int32, error) {
 	}
 	return (int32)(ret), nil
 }
+
+// byteReader adapts an io.Reader to an io.ByteReader.
+type byteReader struct {
+	r    io.Reader
+	data [1]byte
+}
+
+func (b *byteReader) ReadByte() (byte, error) {
+	if n, err := b.r.Read(b.data[:]); n < 1 {
+		if err == nil {
+			err = io.ErrNoProgress
+		}
+		return 0, err
+	}
+	return b.data[0], nil
+}
@@ -17,6 +17,7 @@ package coder
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -92,3 +93,35 @@ func TestEncodeDecodeVarInt(t *testing.T) {
 		}
 	}
 }
+
+// plainReader hides the io.ByteReader implementation of the underlying reader.
+type plainReader struct {
+	r io.Reader
+}
+
+func (p plainReader) Read(buf []byte) (int, error) {
+	return p.r.Read(buf)
+}
+
+func TestDecodeVarUint64PlainReader(t *testing.T) {
+	var buf bytes.Buffer
+	for _, v := range []uint64{0, 300, 18446744073709551615} {
+		if err := EncodeVarUint64(v, &buf); err != nil {
+			t.Fatalf("EncodeVarUint64(%v) failed: %v", v, err)
+		}
+	}
+
+	r := plainReader{&buf}
+	for _, want := range []uint64{0, 300, 18446744073709551615} {
+		got, err := DecodeVarUint64(r)
+		if err != nil {
+			t.Fatalf("DecodeVarUint64(<%v>) failed: %v", want, err)
+		}
+		if got != want {
+			t.Errorf("DecodeVarUint64(<%v>) = %v, want %v", want, got, want)
+		}
+	}
+	if _, err := DecodeVarUint64(r); err != io.EOF {
+		t.Errorf("DecodeVarUint64(<empty>) failed with %v, want EOF", err)
+	}
+}
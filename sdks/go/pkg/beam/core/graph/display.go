@@ -0,0 +1,44 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import "fmt"
+
+// DisplayItem is a key/value pair of static information about a transform,
+// such as a file pattern, query or threshold, shown in runner UIs. DoFns
+// and CombineFns may provide display data by implementing a
+// "DisplayData() []DisplayItem" method, which is invoked at construction time.
+type DisplayItem struct {
+	// Key identifies the item within the transform.
+	Key string
+	// Label is an optional human-readable name of the item.
+	Label string
+	// Value is the value of the item. Strings, booleans, integers, floats,
+	// time.Time and time.Duration values are typed in the pipeline proto,
+	// where integers and floats are widened to 64 bits. Other values are
+	// shown in their default format.
+	Value interface{}
+}
+
+func (d DisplayItem) String() string {
+	return fmt.Sprintf("%v=%v", d.Key, d.Value)
+}
+
+// DisplayData returns the display data of the function, if it has a
+// "DisplayData" method.
+func (f *Fn) DisplayData() []DisplayItem {
+	return f.displayData
+}
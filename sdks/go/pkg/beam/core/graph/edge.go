@@ -31,12 +31,13 @@ type Opcode string
 
 // Valid opcodes.
 const (
-	Impulse  Opcode = "Impulse"
-	ParDo    Opcode = "ParDo"
-	CoGBK    Opcode = "CoGBK"
-	External Opcode = "External"
-	Flatten  Opcode = "Flatten"
-	Combine  Opcode = "Combine"
+	Impulse    Opcode = "Impulse"
+	ParDo      Opcode = "ParDo"
+	CoGBK      Opcode = "CoGBK"
+	External   Opcode = "External"
+	Flatten    Opcode = "Flatten"
+	Combine    Opcode = "Combine"
+	WindowInto Opcode = "WindowInto"
 )
 
 // InputKind represents the role of the input and its shape.
@@ -145,13 +146,21 @@ type MultiEdge struct {
 	parent *Scope
 
 	Op        Opcode
-	DoFn      *DoFn      // ParDo
-	CombineFn *CombineFn // Combine
-	Value     []byte     // Impulse
-	Payload   *Payload   // External
+	DoFn      *DoFn          // ParDo
+	CombineFn *CombineFn     // Combine
+	Value     []byte         // Impulse
+	Payload   *Payload       // External
+	WindowFn  *window.Window // WindowInto
 
 	Input  []*Inbound
 	Output []*Outbound
+
+	// Label is the user-provided name of the transform, if any. It overrides
+	// the name derived from the function or opcode.
+	Label string
+
+	// DisplayData is static information about the transform for runner UIs.
+	DisplayData []DisplayItem
 }
 
 // ID returns the graph-local identifier for the edge.
@@ -159,8 +168,12 @@ func (e *MultiEdge) ID() int {
 	return e.id
 }
 
-// Name returns a not-necessarily-unique name for the edge.
+// Name returns a not-necessarily-unique name for the edge: the label, if any,
+// or else the function name or opcode.
 func (e *MultiEdge) Name() string {
+	if e.Label != "" {
+		return e.Label
+	}
 	if e.DoFn != nil {
 		return e.DoFn.Name()
 	}
@@ -215,6 +228,7 @@ func NewCoGBK(g *Graph, s *Scope, ns []*Node) (*MultiEdge, error) {
 
 	t := typex.NewCoGBK(comp...)
 	out := g.NewNode(t, w)
+	out.SetBounded(inputBounded(ns))
 
 	// (2) Add CoGBK edge
 
@@ -252,10 +266,41 @@ func NewFlatten(g *Graph, s *Scope, in []*Node) (*MultiEdge, error) {
 	for _, n := range in {
 		edge.Input = append(edge.Input, &Inbound{Kind: Main, From: n, Type: t})
 	}
-	edge.Output = []*Outbound{{To: g.NewNode(t, w), Type: t}}
+	out := g.NewNode(t, w)
+	out.SetBounded(inputBounded(in))
+
+	edge.Output = []*Outbound{{To: out, Type: t}}
 	return edge, nil
 }
 
+// NewWindowInto inserts a new WindowInto edge into the graph, which assigns
+// the elements of the input to windows of the given windowing strategy.
+func NewWindowInto(g *Graph, s *Scope, wfn *window.Window, in *Node) *MultiEdge {
+	t := in.Type()
+
+	edge := g.NewEdge(s)
+	edge.Op = WindowInto
+	edge.WindowFn = wfn
+	edge.Input = []*Inbound{{Kind: Main, From: in, Type: t}}
+
+	out := g.NewNode(t, wfn)
+	out.SetBounded(in.Bounded())
+	edge.Output = []*Outbound{{To: out, Type: t}}
+	return edge
+}
+
+// NewWindowIntoWithLateData inserts a new WindowInto edge into the graph with
+// a second output in the global window for the elements that arrive after
+// their windows have expired.
+func NewWindowIntoWithLateData(g *Graph, s *Scope, wfn *window.Window, in *Node) *MultiEdge {
+	edge := NewWindowInto(g, s, wfn, in)
+
+	late := g.NewNode(in.Type(), window.NewGlobalWindow())
+	late.SetBounded(in.Bounded())
+	edge.Output = append(edge.Output, &Outbound{To: late, Type: in.Type()})
+	return edge
+}
+
 // NewExternal inserts an External transform. The system makes no assumptions about
 // what this transform might do.
 func NewExternal(g *Graph, s *Scope, payload *Payload, in []*Node, out []typex.FullType) *MultiEdge {
@@ -267,6 +312,7 @@ func NewExternal(g *Graph, s *Scope, payload *Payload, in []*Node, out []typex.F
 	}
 	for _, t := range out {
 		n := g.NewNode(t, inputWindow(in))
+		n.SetBounded(inputBounded(in))
 		edge.Output = append(edge.Output, &Outbound{To: n, Type: t})
 	}
 	return edge
@@ -287,15 +333,20 @@ func newDoFnNode(op Opcode, g *Graph, s *Scope, u *DoFn, in []*Node, typedefs ma
 	if err != nil {
 		return nil, err
 	}
+	if u.IsStateful() && !typex.IsKV(in[0].Type()) {
+		return nil, fmt.Errorf("stateful DoFn %v requires KV main input, got %v", u.Name(), in[0].Type())
+	}
 
 	edge := g.NewEdge(s)
 	edge.Op = op
 	edge.DoFn = u
+	edge.DisplayData = (*Fn)(u).DisplayData()
 	for i := 0; i < len(in); i++ {
 		edge.Input = append(edge.Input, &Inbound{Kind: kinds[i], From: in[i], Type: inbound[i]})
 	}
 	for i := 0; i < len(out); i++ {
 		n := g.NewNode(out[i], inputWindow(in))
+		n.SetBounded(inputBounded(in))
 		edge.Output = append(edge.Output, &Outbound{To: n, Type: outbound[i]})
 	}
 	return edge, nil
@@ -365,9 +416,11 @@ func NewCombine(g *Graph, s *Scope, u *CombineFn, in *Node) (*MultiEdge, error)
 	edge := g.NewEdge(s)
 	edge.Op = Combine
 	edge.CombineFn = u
+	edge.DisplayData = (*Fn)(u).DisplayData()
 	edge.Input = []*Inbound{{Kind: kinds[0], From: in, Type: inbound[0]}}
 	for i := 0; i < len(out); i++ {
 		n := g.NewNode(out[i], in.Window())
+		n.SetBounded(in.Bounded())
 		edge.Output = append(edge.Output, &Outbound{To: n, Type: outbound[i]})
 	}
 	return edge, nil
@@ -394,3 +447,13 @@ func inputWindow(in []*Node) *window.Window {
 	}
 	return in[0].Window()
 }
+
+// inputBounded returns true iff all the input nodes are bounded.
+func inputBounded(in []*Node) bool {
+	for _, n := range in {
+		if !n.Bounded() {
+			return false
+		}
+	}
+	return true
+}
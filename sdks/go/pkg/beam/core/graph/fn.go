@@ -18,8 +18,13 @@ package graph
 import (
 	"fmt"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/timers"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 )
 
@@ -38,6 +43,8 @@ type Fn struct {
 	// methods holds the public methods (or the function) by their beam
 	// names.
 	methods map[string]*funcx.Fn
+	// displayData holds the result of the "DisplayData" method, if present.
+	displayData []DisplayItem
 }
 
 // Name returns the name of the function or struct.
@@ -70,6 +77,13 @@ type DynFn struct {
 	Gen func(string, reflect.Type, []byte) reflectx.Func
 }
 
+// FuncSet is implemented by struct receivers that supply functions in place
+// of their methods, such as the four-function form of a CombineFn. The
+// functions are keyed by method name. The receiver is serialized as usual.
+type FuncSet interface {
+	Funcs() map[string]reflectx.Func
+}
+
 // NewFn pre-processes a function, dynamic function or struct for graph
 // construction.
 func NewFn(fn interface{}) (*Fn, error) {
@@ -100,7 +114,20 @@ func NewFn(fn interface{}) (*Fn, error) {
 		fallthrough
 
 	case reflect.Struct:
+		if set, ok := fn.(FuncSet); ok {
+			methods := make(map[string]*funcx.Fn)
+			for name, f := range set.Funcs() {
+				m, err := funcx.New(f)
+				if err != nil {
+					return nil, fmt.Errorf("function %v invalid: %v", name, err)
+				}
+				methods[name] = m
+			}
+			return &Fn{Recv: fn, methods: methods}, nil
+		}
+
 		methods := make(map[string]*funcx.Fn)
+		var displayData []DisplayItem
 		for i := 0; i < val.Type().NumMethod(); i++ {
 			m := val.Type().Method(i)
 			if m.PkgPath != "" {
@@ -109,6 +136,16 @@ func NewFn(fn interface{}) (*Fn, error) {
 			if m.Name == "String" {
 				continue // skip: harmless
 			}
+			if m.Name == displayDataName {
+				// Display data is not user code in the pipeline, so it is
+				// obtained once here rather than as a funcx.Fn.
+				fn, ok := val.Method(i).Interface().(func() []DisplayItem)
+				if !ok {
+					return nil, fmt.Errorf("bad %v method: %v, want func() []DisplayItem", displayDataName, val.Method(i).Type())
+				}
+				displayData = fn()
+				continue
+			}
 
 			// CAVEAT(herohde) 5/22/2017: The type val.Type.Method.Type is not
 			// the same as val.Method.Type: the former has the explicit receiver.
@@ -124,7 +161,7 @@ func NewFn(fn interface{}) (*Fn, error) {
 			}
 			methods[m.Name] = f
 		}
-		return &Fn{Recv: fn, methods: methods}, nil
+		return &Fn{Recv: fn, methods: methods, displayData: displayData}, nil
 
 	default:
 		return nil, fmt.Errorf("value %v must be function or (ptr to) struct", fn)
@@ -138,6 +175,15 @@ const (
 	processElementName = "ProcessElement"
 	finishBundleName   = "FinishBundle"
 	teardownName       = "Teardown"
+	outputCapacityName = "OutputCapacity"
+	timestampSkewName  = "AllowedTimestampSkew"
+	displayDataName    = "DisplayData"
+	onTimerName        = "OnTimer"
+
+	createInitialRestrictionName = "CreateInitialRestriction"
+	splitRestrictionName         = "SplitRestriction"
+	createTrackerName            = "CreateTracker"
+	truncateRestrictionName      = "TruncateRestriction"
 
 	createAccumulatorName = "CreateAccumulator"
 	addInputName          = "AddInput"
@@ -176,11 +222,211 @@ func (f *DoFn) TeardownFn() *funcx.Fn {
 	return f.methods[teardownName]
 }
 
+// OutputCapacityFn returns the "OutputCapacity" function, if present.
+func (f *DoFn) OutputCapacityFn() *funcx.Fn {
+	return f.methods[outputCapacityName]
+}
+
+// OutputCapacity returns the expected number of outputs per input element,
+// as hinted by the "OutputCapacity" method or a static hint registered with
+// RegisterOutputCapacity. It returns 0 if there is no hint. The method should
+// only be invoked after Setup, if the hint depends on the DoFn configuration.
+func (f *DoFn) OutputCapacity() int {
+	if fn := f.OutputCapacityFn(); fn != nil {
+		return fn.Fn.Call(nil)[0].(int)
+	}
+	outputCapacitiesMu.Lock()
+	defer outputCapacitiesMu.Unlock()
+	return outputCapacities[f.Name()]
+}
+
+// AllowedTimestampSkewFn returns the "AllowedTimestampSkew" function, if present.
+func (f *DoFn) AllowedTimestampSkewFn() *funcx.Fn {
+	return f.methods[timestampSkewName]
+}
+
+// TimestampSkew returns how far output timestamps may be earlier than the
+// timestamp of the input element and how violations are handled. The allowed
+// skew is given by the "AllowedTimestampSkew" method, if present, or else by
+// RegisterTimestampSkew. The policy defaults to SkewError. It returns false
+// if the DoFn has neither, in which case output timestamps are not checked.
+func (f *DoFn) TimestampSkew() (time.Duration, SkewPolicy, bool) {
+	timestampSkewsMu.Lock()
+	skew, ok := timestampSkews[f.Name()]
+	timestampSkewsMu.Unlock()
+
+	if fn := f.AllowedTimestampSkewFn(); fn != nil {
+		skew.allowed = fn.Fn.Call(nil)[0].(time.Duration)
+		ok = true
+	}
+	return skew.allowed, skew.policy, ok
+}
+
+// OnTimerFn returns the "OnTimer" function, if present.
+func (f *DoFn) OnTimerFn() *funcx.Fn {
+	return f.methods[onTimerName]
+}
+
+// CreateInitialRestrictionFn returns the "CreateInitialRestriction" function,
+// if present.
+func (f *DoFn) CreateInitialRestrictionFn() *funcx.Fn {
+	return f.methods[createInitialRestrictionName]
+}
+
+// SplitRestrictionFn returns the "SplitRestriction" function, if present.
+func (f *DoFn) SplitRestrictionFn() *funcx.Fn {
+	return f.methods[splitRestrictionName]
+}
+
+// CreateTrackerFn returns the "CreateTracker" function, if present.
+func (f *DoFn) CreateTrackerFn() *funcx.Fn {
+	return f.methods[createTrackerName]
+}
+
+// TruncateRestrictionFn returns the "TruncateRestriction" function, if
+// present.
+func (f *DoFn) TruncateRestrictionFn() *funcx.Fn {
+	return f.methods[truncateRestrictionName]
+}
+
+// IsSplittable returns true iff the ProcessElement method of the DoFn takes a
+// restriction tracker. See package sdf.
+func (f *DoFn) IsSplittable() bool {
+	_, ok := f.ProcessElementFn().RTracker()
+	return ok
+}
+
+// StateKeys returns the keys of the state cells and timers declared as
+// exported fields of the DoFn struct, in field order.
+func (f *DoFn) StateKeys() []string {
+	var ret []string
+	for _, c := range cells(f.Recv) {
+		ret = append(ret, c.StateKey())
+	}
+	return ret
+}
+
+// TimerKeys returns the keys of the timers declared as exported fields of the
+// DoFn struct, in field order.
+func (f *DoFn) TimerKeys() []string {
+	var ret []string
+	for _, c := range cells(f.Recv) {
+		switch t := c.(type) {
+		case timers.EventTime:
+			ret = append(ret, t.Key)
+		case timers.ProcessingTime:
+			ret = append(ret, t.Key)
+		}
+	}
+	return ret
+}
+
+// BagKeys returns the keys of the bag state cells declared as exported fields
+// of the DoFn struct, in field order.
+func (f *DoFn) BagKeys() []string {
+	var ret []string
+	for _, c := range cells(f.Recv) {
+		if b, ok := c.(state.Bag); ok {
+			ret = append(ret, b.Key)
+		}
+	}
+	return ret
+}
+
+// OrderedListKeys returns the keys of the ordered list state cells declared
+// as exported fields of the DoFn struct, in field order.
+func (f *DoFn) OrderedListKeys() []string {
+	var ret []string
+	for _, c := range cells(f.Recv) {
+		if l, ok := c.(state.OrderedList); ok {
+			ret = append(ret, l.Key)
+		}
+	}
+	return ret
+}
+
+// IsStateful returns true iff the DoFn declares state cells or timers. The
+// main input of stateful DoFns must be KV, which partitions the state.
+func (f *DoFn) IsStateful() bool {
+	return len(cells(f.Recv)) > 0
+}
+
 // Name returns the name of the function or struct.
 func (f *DoFn) Name() string {
 	return (*Fn)(f).Name()
 }
 
+var (
+	outputCapacities   = make(map[string]int)
+	outputCapacitiesMu sync.Mutex
+)
+
+// RegisterOutputCapacity registers a static hint of the expected number of
+// outputs per input element for the given DoFn function or struct. Struct
+// DoFns may instead implement an "OutputCapacity() int" method, which
+// overrides the registered hint. The hint is used to pre-size output buffers
+// of high-fanout DoFns and must be registered on workers as well, so it should
+// be called in init() only.
+func RegisterOutputCapacity(fn interface{}, n int) {
+	outputCapacitiesMu.Lock()
+	defer outputCapacitiesMu.Unlock()
+	outputCapacities[registeredName(fn)] = n
+}
+
+// SkewPolicy defines how output timestamps that are earlier than the input
+// timestamp by more than the allowed skew are handled.
+type SkewPolicy int
+
+const (
+	// SkewError fails the bundle. It is the default, once a skew is registered.
+	SkewError SkewPolicy = iota
+	// SkewClamp moves the timestamp forward to the earliest allowed time.
+	SkewClamp
+)
+
+func (p SkewPolicy) String() string {
+	switch p {
+	case SkewError:
+		return "Error"
+	case SkewClamp:
+		return "Clamp"
+	default:
+		return fmt.Sprintf("SkewPolicy(%d)", int(p))
+	}
+}
+
+type timestampSkew struct {
+	allowed time.Duration
+	policy  SkewPolicy
+}
+
+var (
+	timestampSkews   = make(map[string]timestampSkew)
+	timestampSkewsMu sync.Mutex
+)
+
+// RegisterTimestampSkew registers the allowed timestamp skew of outputs and the
+// policy for violations for the given DoFn function or struct. Struct DoFns may
+// instead implement an "AllowedTimestampSkew() time.Duration" method, which
+// overrides the registered skew but not the policy. Output timestamps of DoFns
+// with neither are not checked. It must be registered on workers as well, so
+// it should be called in init() only.
+func RegisterTimestampSkew(fn interface{}, allowed time.Duration, policy SkewPolicy) {
+	timestampSkewsMu.Lock()
+	defer timestampSkewsMu.Unlock()
+	timestampSkews[registeredName(fn)] = timestampSkew{allowed: allowed, policy: policy}
+}
+
+// registeredName returns the name under which static hints for the given
+// DoFn function or struct are registered. It matches Name.
+func registeredName(fn interface{}) string {
+	if reflect.TypeOf(fn).Kind() == reflect.Func {
+		return reflectx.FunctionName(fn)
+	}
+	t := reflectx.SkipPtr(reflect.TypeOf(fn))
+	return fmt.Sprintf("%v.%v", t.PkgPath(), t.Name())
+}
+
 // TODO(herohde) 5/19/2017: we can sometimes detect whether the main input must be
 // a KV or not based on the other signatures (unless we're more loose about which
 // sideinputs are present). Bind should respect that.
@@ -202,13 +448,30 @@ func AsDoFn(fn *Fn) (*DoFn, error) {
 	if fn.Fn != nil {
 		fn.methods[processElementName] = fn.Fn
 	}
-	if err := verifyValidNames(fn, setupName, startBundleName, processElementName, finishBundleName, teardownName); err != nil {
+	if err := verifyValidNames(fn, setupName, startBundleName, processElementName, finishBundleName, teardownName, outputCapacityName, timestampSkewName, onTimerName, createInitialRestrictionName, splitRestrictionName, createTrackerName, truncateRestrictionName); err != nil {
 		return nil, err
 	}
 
 	if _, ok := fn.methods[processElementName]; !ok {
 		return nil, fmt.Errorf("failed to find %v method: %v", processElementName, fn)
 	}
+	if c, ok := fn.methods[outputCapacityName]; ok {
+		if t := c.Fn.Type(); t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0) != reflectx.Int {
+			return nil, fmt.Errorf("bad %v method: %v, want func() int", outputCapacityName, t)
+		}
+	}
+	if c, ok := fn.methods[timestampSkewName]; ok {
+		if t := c.Fn.Type(); t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0) != reflect.TypeOf(time.Duration(0)) {
+			return nil, fmt.Errorf("bad %v method: %v, want func() time.Duration", timestampSkewName, t)
+		}
+	}
+
+	if err := verifyStateKeys(fn); err != nil {
+		return nil, err
+	}
+	if err := verifySplittable((*DoFn)(fn)); err != nil {
+		return nil, err
+	}
 
 	// TODO(herohde) 5/18/2017: validate the signatures, incl. consistency.
 
@@ -283,10 +546,182 @@ func AsCombineFn(fn *Fn) (*CombineFn, error) {
 	if _, ok := fn.methods[mergeAccumulatorsName]; !ok {
 		return nil, fmt.Errorf("failed to find %v method: %v", mergeAccumulatorsName, fn)
 	}
+	if err := verifyCombineFn((*CombineFn)(fn)); err != nil {
+		return nil, err
+	}
+	return (*CombineFn)(fn), nil
+}
 
-	// TODO(herohde) 5/24/2017: validate the signatures, incl. consistency.
+// AccumulatorType returns the accumulator type of the CombineFn.
+func (f *CombineFn) AccumulatorType() reflect.Type {
+	return f.MergeAccumulatorsFn().Ret[f.MergeAccumulatorsFn().Returns(funcx.RetValue)[0]].T
+}
 
-	return (*CombineFn)(fn), nil
+// verifyCombineFn checks that the CombineFn methods agree on a single
+// accumulator type A:
+//
+//    MergeAccumulators: (A, A) -> A or []A -> A
+//    CreateAccumulator: () -> A
+//    AddInput:          (A, [K,] I) -> A
+//    ExtractOutput:     A -> O
+//    Compact:           A -> A
+//
+// where each method may take a context.Context and return an error.
+func verifyCombineFn(f *CombineFn) error {
+	merge := f.MergeAccumulatorsFn()
+	in, out := merge.Params(funcx.FnValue), merge.Returns(funcx.RetValue)
+	if len(out) != 1 {
+		return fmt.Errorf("bad %v method: %v, want (A, A) -> A or []A -> A", mergeAccumulatorsName, merge.Fn.Type())
+	}
+	a := merge.Ret[out[0]].T
+	switch {
+	case len(in) == 2 && merge.Param[in[0]].T == a && merge.Param[in[1]].T == a:
+	case len(in) == 1 && merge.Param[in[0]].T == reflect.SliceOf(a):
+	default:
+		return fmt.Errorf("bad %v method: %v, want (%v, %v) -> %v or []%v -> %v", mergeAccumulatorsName, merge.Fn.Type(), a, a, a, a, a)
+	}
+	if a == typex.KVType || a == typex.CoGBKType {
+		return fmt.Errorf("bad %v method: accumulator %v must not be a tuple type", mergeAccumulatorsName, a)
+	}
+
+	if c := f.CreateAccumulatorFn(); c != nil {
+		in, out := c.Params(funcx.FnValue), c.Returns(funcx.RetValue)
+		if len(in) != 0 || len(out) != 1 || c.Ret[out[0]].T != a {
+			return fmt.Errorf("bad %v method: %v, want () -> %v", createAccumulatorName, c.Fn.Type(), a)
+		}
+	}
+	if c := f.AddInputFn(); c != nil {
+		in, out := c.Params(funcx.FnValue), c.Returns(funcx.RetValue)
+		if len(in) < 2 || len(in) > 3 || c.Param[in[0]].T != a || len(out) != 1 || c.Ret[out[0]].T != a {
+			return fmt.Errorf("bad %v method: %v, want (%v, [K,] I) -> %v", addInputName, c.Fn.Type(), a, a)
+		}
+	}
+	if c := f.ExtractOutputFn(); c != nil {
+		in, out := c.Params(funcx.FnValue), c.Returns(funcx.RetValue)
+		if len(in) != 1 || c.Param[in[0]].T != a || len(out) != 1 {
+			return fmt.Errorf("bad %v method: %v, want %v -> O", extractOutputName, c.Fn.Type(), a)
+		}
+	}
+	if c := f.CompactFn(); c != nil {
+		in, out := c.Params(funcx.FnValue), c.Returns(funcx.RetValue)
+		if len(in) != 1 || c.Param[in[0]].T != a || len(out) != 1 || c.Ret[out[0]].T != a {
+			return fmt.Errorf("bad %v method: %v, want %v -> %v", compactName, c.Fn.Type(), a, a)
+		}
+	}
+	return nil
+}
+
+// cells returns the state cells and timers declared as exported fields of
+// the struct receiver, if any.
+func cells(recv interface{}) []state.Cell {
+	if recv == nil {
+		return nil
+	}
+	val := reflect.Indirect(reflect.ValueOf(recv))
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	var ret []state.Cell
+	for i := 0; i < val.NumField(); i++ {
+		if val.Type().Field(i).PkgPath != "" {
+			continue // skip: unexported
+		}
+		if c, ok := val.Field(i).Interface().(state.Cell); ok {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func verifyStateKeys(fn *Fn) error {
+	seen := make(map[string]bool)
+	for _, c := range cells(fn.Recv) {
+		key := c.StateKey()
+		if key == "" {
+			return fmt.Errorf("state cell or timer of type %T has no key", c)
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate state cell or timer key: %v", key)
+		}
+		seen[key] = true
+
+		if e, ok := c.(state.Encoded); ok && e.StateCodec() != "" {
+			if _, err := state.LookupCodec(e.StateCodec()); err != nil {
+				return fmt.Errorf("invalid state cell %v: %v", key, err)
+			}
+		}
+	}
+
+	_, hasOnTimer := fn.methods[onTimerName]
+	timerKeys := (*DoFn)(fn).TimerKeys()
+	switch {
+	case hasOnTimer && len(timerKeys) == 0:
+		return fmt.Errorf("%v method present, but no timers declared", onTimerName)
+	case !hasOnTimer && len(timerKeys) > 0:
+		return fmt.Errorf("timers %v declared, but no %v method", timerKeys, onTimerName)
+	}
+	return nil
+}
+
+// verifySplittable checks that the restriction methods of a splittable DoFn
+// agree on a single restriction type R and the tracker type T of
+// ProcessElement:
+//
+//    CreateInitialRestriction: ([K,] I) -> R
+//    SplitRestriction:         ([K,] I, R) -> []R
+//    CreateTracker:            R -> T
+//    TruncateRestriction:      ([K,] I, R) -> R
+//
+// Non-splittable DoFns must not have these methods.
+func verifySplittable(f *DoFn) error {
+	initial, split, tracker, truncate := f.CreateInitialRestrictionFn(), f.SplitRestrictionFn(), f.CreateTrackerFn(), f.TruncateRestrictionFn()
+	if !f.IsSplittable() {
+		if initial != nil || split != nil || tracker != nil || truncate != nil {
+			return fmt.Errorf("restriction methods present, but %v takes no restriction tracker", processElementName)
+		}
+		if _, ok := f.ProcessElementFn().ProcessContinuation(); ok {
+			return fmt.Errorf("%v returns a process continuation, but takes no restriction tracker", processElementName)
+		}
+		return nil
+	}
+	if f.IsStateful() {
+		return fmt.Errorf("splittable DoFn %v must not be stateful", f.Name())
+	}
+	if initial == nil || tracker == nil {
+		return fmt.Errorf("splittable DoFn %v must have %v and %v methods", f.Name(), createInitialRestrictionName, createTrackerName)
+	}
+
+	process := f.ProcessElementFn()
+	pos, _ := process.RTracker()
+	t := process.Param[pos].T
+	main := len(process.Params(funcx.FnValue))
+	if main > 2 {
+		main = 2 // side inputs follow the main input
+	}
+
+	in, out := initial.Params(funcx.FnValue), initial.Returns(funcx.RetValue)
+	if len(in) < 1 || len(in) > main || len(out) != 1 {
+		return fmt.Errorf("bad %v method: %v, want ([K,] I) -> R", createInitialRestrictionName, initial.Fn.Type())
+	}
+	r := initial.Ret[out[0]].T
+
+	if split != nil {
+		in, out := split.Params(funcx.FnValue), split.Returns(funcx.RetValue)
+		if len(in) < 2 || split.Param[in[len(in)-1]].T != r || len(out) != 1 || split.Ret[out[0]].T != reflect.SliceOf(r) {
+			return fmt.Errorf("bad %v method: %v, want ([K,] I, %v) -> []%v", splitRestrictionName, split.Fn.Type(), r, r)
+		}
+	}
+	if truncate != nil {
+		in, out := truncate.Params(funcx.FnValue), truncate.Returns(funcx.RetValue)
+		if len(in) < 2 || truncate.Param[in[len(in)-1]].T != r || len(out) != 1 || truncate.Ret[out[0]].T != r {
+			return fmt.Errorf("bad %v method: %v, want ([K,] I, %v) -> %v", truncateRestrictionName, truncate.Fn.Type(), r, r)
+		}
+	}
+	in, out = tracker.Params(funcx.FnValue), tracker.Returns(funcx.RetValue)
+	if len(in) != 1 || tracker.Param[in[0]].T != r || len(out) != 1 || !tracker.Ret[out[0]].T.AssignableTo(t) {
+		return fmt.Errorf("bad %v method: %v, want %v -> %v", createTrackerName, tracker.Fn.Type(), r, t)
+	}
+	return nil
 }
 
 func verifyValidNames(fn *Fn, names ...string) error {
@@ -18,6 +18,7 @@ package graph
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
@@ -25,18 +26,21 @@ import (
 
 // Graph represents an in-progress deferred execution graph and is easily
 // translatable to the model graph. This graph representation allows precise
-// control over scope and connectivity.
+// control over scope and connectivity. It is safe to add scopes, edges and
+// nodes from multiple goroutines.
 type Graph struct {
+	mu     sync.Mutex
 	scopes []*Scope
 	edges  []*MultiEdge
 	nodes  []*Node
+	params map[string]string // runtime parameter -> default
 
 	root *Scope
 }
 
 // New returns an empty graph with the scope set to the root.
 func New() *Graph {
-	root := &Scope{0, "root", nil}
+	root := &Scope{id: 0, Label: "root"}
 	return &Graph{root: root}
 }
 
@@ -46,21 +50,45 @@ func (g *Graph) Root() *Scope {
 }
 
 // NewScope creates and returns a new scope that is a child of the supplied scope.
+// If the parent already has a child scope with the given name, a suffix such
+// as '1 is added to the label to keep the hierarchy unambiguous.
 func (g *Graph) NewScope(parent *Scope, name string) *Scope {
 	if parent == nil {
 		panic("Scope is nil")
 	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	label := name
+	for i := 1; g.hasChildScope(parent, label); i++ {
+		label = fmt.Sprintf("%v'%v", name, i)
+	}
+
 	id := len(g.scopes) + 1
-	s := &Scope{id: id, Label: name, Parent: parent}
+	s := &Scope{id: id, Label: label, Parent: parent}
 	g.scopes = append(g.scopes, s)
 	return s
 }
 
+func (g *Graph) hasChildScope(parent *Scope, label string) bool {
+	for _, s := range g.scopes {
+		if s.Parent == parent && s.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
 // NewEdge creates a new edge of the graph in the supplied scope.
 func (g *Graph) NewEdge(parent *Scope) *MultiEdge {
 	if parent == nil {
 		panic("Scope is nil")
 	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	id := len(g.edges) + 1
 	e := &MultiEdge{id: id, parent: parent}
 	g.edges = append(g.edges, e)
@@ -72,16 +100,53 @@ func (g *Graph) NewNode(t typex.FullType, w *window.Window) *Node {
 	if !typex.IsBound(t) {
 		panic(fmt.Sprintf("Node type not bound: %v", t))
 	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	id := len(g.nodes) + 1
 	n := &Node{id: id, t: t, w: w}
 	g.nodes = append(g.nodes, n)
 	return n
 }
 
+// DeclareParameter declares a runtime parameter of the graph with the given
+// default, which is empty if none. A parameter may be declared more than
+// once, but only with the same default.
+func (g *Graph) DeclareParameter(name, def string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if prev, ok := g.params[name]; ok && prev != def {
+		return fmt.Errorf("runtime parameter %v already declared with default %q", name, prev)
+	}
+	if g.params == nil {
+		g.params = make(map[string]string)
+	}
+	g.params[name] = def
+	return nil
+}
+
+// Parameters returns the declared runtime parameters of the graph and their
+// defaults.
+func (g *Graph) Parameters() map[string]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ret := make(map[string]string)
+	for k, v := range g.params {
+		ret[k] = v
+	}
+	return ret
+}
+
 // Build performs finalization on the graph. It verifies the correctness of the
 // graph structure, typechecks the plan and returns a slice of the edges in
 // the graph.
 func (g *Graph) Build() ([]*MultiEdge, []*Node, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	// Build a map of all nodes listed in g.nodes.
 	nodes := make(map[*Node]bool)
 	for _, n := range g.nodes {
@@ -110,10 +175,13 @@ func (g *Graph) Build() ([]*MultiEdge, []*Node, error) {
 			return nil, nil, fmt.Errorf("node %v is reachable by edge %v, but it's not in same graph", n.id, e.id)
 		}
 	}
-	return g.edges, g.nodes, nil
+	return append([]*MultiEdge(nil), g.edges...), append([]*Node(nil), g.nodes...), nil
 }
 
 func (g *Graph) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	var nodes []string
 	for _, node := range g.nodes {
 		nodes = append(nodes, node.String())
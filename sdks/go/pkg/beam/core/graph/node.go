@@ -39,6 +39,10 @@ type Node struct {
 
 	// w defines the kind of windowing used.
 	w *window.Window
+
+	// unbounded is true iff the data is unbounded, i.e., produced directly or
+	// indirectly by an unbounded source. Nodes are bounded by default.
+	unbounded bool
 }
 
 // ID returns the graph-local identifier for the node.
@@ -56,6 +60,17 @@ func (n *Node) Window() *window.Window {
 	return n.w
 }
 
+// Bounded returns true iff the data is bounded.
+func (n *Node) Bounded() bool {
+	return !n.unbounded
+}
+
+// SetBounded sets whether the data is bounded. Unbounded sources must mark
+// their output, which is then propagated to downstream nodes.
+func (n *Node) SetBounded(bounded bool) {
+	n.unbounded = !bounded
+}
+
 func (n *Node) String() string {
 	return fmt.Sprintf("{%v: %v/%v/%v}", n.id, n.t, n.w, n.Coder)
 }
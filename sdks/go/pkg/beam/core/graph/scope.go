@@ -24,6 +24,10 @@ type Scope struct {
 	Label string
 	// Parent is the parent scope, if nested.
 	Parent *Scope
+	// Environment is the container image that the transforms in this scope
+	// are pinned to, if any. Otherwise, they inherit the environment of the
+	// parent scope.
+	Environment string
 }
 
 // ID returns the graph-local identifier for the scope.
@@ -31,6 +35,18 @@ func (s *Scope) ID() int {
 	return s.id
 }
 
+// Env returns the environment of the transforms in the scope: its own, if
+// pinned, or else that of the nearest pinned ancestor. It returns "" if no
+// enclosing scope is pinned, in which case the default environment is used.
+func (s *Scope) Env() string {
+	for ; s != nil; s = s.Parent {
+		if s.Environment != "" {
+			return s.Environment
+		}
+	}
+	return ""
+}
+
 func (s *Scope) String() string {
 	if s.Parent == nil {
 		return s.Label
@@ -0,0 +1,114 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"fmt"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// Validate checks the graph for problems that would otherwise only surface
+// at submission or execution time. Unlike Build, it does not stop at the
+// first problem, but returns all problems found. It checks for:
+//
+//   (1) Nodes without a coder or with a coder of a different type.
+//   (2) Nodes that are unconnected or edges whose outputs are all unconsumed.
+//   (3) CoGBK on non-KV input or on unbounded input in the global window.
+//   (4) Inbound and outbound types that do not match the node types.
+//
+// Edges with some consumed outputs are not reported for the others, because
+// ignoring some outputs of a multi-output ParDo is common. Combine edges with
+// CoGBK input are exempt from (4), because their inbound type intentionally
+// differs from the node type.
+func (g *Graph) Validate() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	producers := make(map[*Node]*MultiEdge)
+	consumers := make(map[*Node]int)
+	for _, e := range g.edges {
+		for _, in := range e.Input {
+			consumers[in.From]++
+		}
+		for _, out := range e.Output {
+			producers[out.To] = e
+		}
+	}
+
+	var problems []error
+	for _, n := range g.nodes {
+		if n.Coder == nil {
+			problems = append(problems, fmt.Errorf("node %v has no coder: coder inference failed or it was not set", n))
+		} else if !typex.IsEqual(n.Coder.T, n.Type()) {
+			problems = append(problems, fmt.Errorf("node %v has coder of type %v, want %v", n, n.Coder.T, n.Type()))
+		}
+
+		if _, produced := producers[n]; !produced && consumers[n] == 0 {
+			problems = append(problems, fmt.Errorf("node %v is unconnected", n))
+		}
+	}
+
+	for _, e := range g.edges {
+		if len(e.Output) > 0 && !anyConsumed(e.Output, consumers) {
+			for _, out := range e.Output {
+				problems = append(problems, fmt.Errorf("node %v produced by %v is never consumed", out.To, describe(e)))
+			}
+		}
+
+		if e.Op == CoGBK {
+			for _, in := range e.Input {
+				if !typex.IsKV(in.From.Type()) {
+					problems = append(problems, fmt.Errorf("%v: input %v must be KV", describe(e), in.From))
+				}
+				// An unbounded input in the global window is only ever grouped,
+				// if a trigger fires before the end of the window.
+				if w := in.From.Window(); !in.From.Bounded() && w.Kind() == window.GlobalWindow && w.Trigger().Kind == window.DefaultTrigger {
+					problems = append(problems, fmt.Errorf("%v: input %v is unbounded and must be windowed or triggered before grouping", describe(e), in.From))
+				}
+			}
+		}
+
+		if e.Op == Combine && typex.IsCoGBK(e.Input[0].From.Type()) {
+			continue
+		}
+		for i, in := range e.Input {
+			if !typex.IsStructurallyAssignable(in.From.Type(), in.Type) {
+				problems = append(problems, fmt.Errorf("%v: input %v of type %v does not match %v", describe(e), i, in.From.Type(), in.Type))
+			}
+		}
+		for i, out := range e.Output {
+			if !typex.IsStructurallyAssignable(out.Type, out.To.Type()) {
+				problems = append(problems, fmt.Errorf("%v: output %v of type %v does not match %v", describe(e), i, out.To.Type(), out.Type))
+			}
+		}
+	}
+	return problems
+}
+
+func describe(e *MultiEdge) string {
+	return fmt.Sprintf("%v %v in scope %v", e.Op, e.Name(), e.Scope())
+}
+
+func anyConsumed(list []*Outbound, consumers map[*Node]int) bool {
+	for _, out := range list {
+		if consumers[out.To] > 0 {
+			return true
+		}
+	}
+	return false
+}
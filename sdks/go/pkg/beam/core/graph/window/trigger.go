@@ -0,0 +1,193 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TriggerKind is the kind of a trigger.
+type TriggerKind string
+
+const (
+	// DefaultTrigger fires when the watermark passes the end of the window
+	// and again for each late element.
+	DefaultTrigger TriggerKind = "Default"
+	// AlwaysTrigger fires for every element.
+	AlwaysTrigger TriggerKind = "Always"
+	// NeverTrigger never fires. Elements are only emitted when the window
+	// expires.
+	NeverTrigger TriggerKind = "Never"
+	// AfterWatermarkTrigger fires once when the watermark passes the end of
+	// the window, with optional early and late firings.
+	AfterWatermarkTrigger TriggerKind = "AfterWatermark"
+	// AfterProcessingTimeTrigger fires once a delay in processing time after
+	// the first element of a pane arrives.
+	AfterProcessingTimeTrigger TriggerKind = "AfterProcessingTime"
+	// AfterCountTrigger fires once a pane holds a number of elements.
+	AfterCountTrigger TriggerKind = "AfterCount"
+	// RepeatTrigger fires whenever its subtrigger fires.
+	RepeatTrigger TriggerKind = "Repeat"
+	// AfterAnyTrigger fires once any of its subtriggers fire.
+	AfterAnyTrigger TriggerKind = "AfterAny"
+	// AfterAllTrigger fires once all of its subtriggers have fired.
+	AfterAllTrigger TriggerKind = "AfterAll"
+	// AfterEachTrigger fires for each of its subtriggers in sequence.
+	AfterEachTrigger TriggerKind = "AfterEach"
+)
+
+// Trigger determines when the grouped elements of a window are emitted as
+// panes. Triggers are immutable and composed with the functions below. For
+// example, hourly windows with early results every 100 elements and a pane
+// for each late element are triggered by:
+//
+//    window.TriggerAfterWatermark().
+//        EarlyFiring(window.TriggerRepeat(window.TriggerAfterCount(100))).
+//        LateFiring(window.TriggerAlways())
+type Trigger struct {
+	Kind TriggerKind
+
+	Count int32         // AfterCount
+	Delay time.Duration // AfterProcessingTime
+	Early *Trigger      // AfterWatermark, if any
+	Late  *Trigger      // AfterWatermark, if any
+	Sub   []*Trigger    // Repeat, AfterAny, AfterAll, AfterEach
+}
+
+// TriggerDefault returns the default trigger.
+func TriggerDefault() *Trigger {
+	return &Trigger{Kind: DefaultTrigger}
+}
+
+// TriggerAlways returns a trigger that fires for every element.
+func TriggerAlways() *Trigger {
+	return &Trigger{Kind: AlwaysTrigger}
+}
+
+// TriggerNever returns a trigger that never fires.
+func TriggerNever() *Trigger {
+	return &Trigger{Kind: NeverTrigger}
+}
+
+// TriggerAfterWatermark returns a trigger that fires when the watermark
+// passes the end of the window.
+func TriggerAfterWatermark() *Trigger {
+	return &Trigger{Kind: AfterWatermarkTrigger}
+}
+
+// TriggerAfterProcessingTime returns a trigger that fires the given delay
+// after the first element of a pane arrives.
+func TriggerAfterProcessingTime(delay time.Duration) *Trigger {
+	if delay < 0 {
+		panic(fmt.Sprintf("invalid processing time delay: %v", delay))
+	}
+	return &Trigger{Kind: AfterProcessingTimeTrigger, Delay: delay}
+}
+
+// TriggerAfterCount returns a trigger that fires once a pane holds the given
+// number of elements.
+func TriggerAfterCount(n int32) *Trigger {
+	if n <= 0 {
+		panic(fmt.Sprintf("invalid element count: %v", n))
+	}
+	return &Trigger{Kind: AfterCountTrigger, Count: n}
+}
+
+// TriggerRepeat returns a trigger that fires whenever the given trigger
+// fires.
+func TriggerRepeat(t *Trigger) *Trigger {
+	return &Trigger{Kind: RepeatTrigger, Sub: []*Trigger{t}}
+}
+
+// TriggerAfterAny returns a trigger that fires once any of the given
+// triggers fire.
+func TriggerAfterAny(list ...*Trigger) *Trigger {
+	return newComposite(AfterAnyTrigger, list)
+}
+
+// TriggerAfterAll returns a trigger that fires once all of the given
+// triggers have fired.
+func TriggerAfterAll(list ...*Trigger) *Trigger {
+	return newComposite(AfterAllTrigger, list)
+}
+
+// TriggerAfterEach returns a trigger that fires for each of the given
+// triggers in sequence.
+func TriggerAfterEach(list ...*Trigger) *Trigger {
+	return newComposite(AfterEachTrigger, list)
+}
+
+func newComposite(k TriggerKind, list []*Trigger) *Trigger {
+	if len(list) == 0 {
+		panic(fmt.Sprintf("%v trigger needs subtriggers", k))
+	}
+	return &Trigger{Kind: k, Sub: append([]*Trigger(nil), list...)}
+}
+
+// EarlyFiring returns a copy of an AfterWatermark trigger that also fires
+// for the given trigger before the end of the window.
+func (t *Trigger) EarlyFiring(early *Trigger) *Trigger {
+	if t.Kind != AfterWatermarkTrigger {
+		panic(fmt.Sprintf("early firings require an AfterWatermark trigger: %v", t))
+	}
+	ret := *t
+	ret.Early = early
+	return &ret
+}
+
+// LateFiring returns a copy of an AfterWatermark trigger that also fires
+// for the given trigger after the end of the window.
+func (t *Trigger) LateFiring(late *Trigger) *Trigger {
+	if t.Kind != AfterWatermarkTrigger {
+		panic(fmt.Sprintf("late firings require an AfterWatermark trigger: %v", t))
+	}
+	ret := *t
+	ret.Late = late
+	return &ret
+}
+
+// Equals returns true iff the triggers are identical.
+func (t *Trigger) Equals(o *Trigger) bool {
+	return t.String() == o.String()
+}
+
+func (t *Trigger) String() string {
+	switch t.Kind {
+	case AfterProcessingTimeTrigger:
+		return fmt.Sprintf("%v(%v)", t.Kind, t.Delay)
+	case AfterCountTrigger:
+		return fmt.Sprintf("%v(%v)", t.Kind, t.Count)
+	case AfterWatermarkTrigger:
+		var opts []string
+		if t.Early != nil {
+			opts = append(opts, fmt.Sprintf("early=%v", t.Early))
+		}
+		if t.Late != nil {
+			opts = append(opts, fmt.Sprintf("late=%v", t.Late))
+		}
+		return fmt.Sprintf("%v(%v)", t.Kind, strings.Join(opts, ","))
+	case RepeatTrigger, AfterAnyTrigger, AfterAllTrigger, AfterEachTrigger:
+		var subs []string
+		for _, s := range t.Sub {
+			subs = append(subs, s.String())
+		}
+		return fmt.Sprintf("%v(%v)", t.Kind, strings.Join(subs, ","))
+	default:
+		return string(t.Kind)
+	}
+}
@@ -16,14 +16,33 @@
 // Package window contains window representation and utilities.
 package window
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
 
 // Window defines the types of windowing used in a pipeline and contains
 // the data and code to support executing a windowing strategy.
 type Window struct {
 	k Kind
-	// TODO: pointer to windowing function
-	// TODO: other fields
+
+	size   time.Duration // fixed and sliding windows
+	period time.Duration // sliding windows
+	gap    time.Duration // sessions
+
+	name   string    // custom
+	assign *funcx.Fn // custom
+	merge  *funcx.Fn // custom, if merging
+
+	trigger    *Trigger      // nil if default
+	lateness   time.Duration // allowed lateness
+	accumulate bool          // accumulating panes
 }
 
 // Kind is the semantic type of window.
@@ -32,14 +51,205 @@ type Kind string
 const (
 	// GlobalWindow is the default window into which all elements are placed.
 	GlobalWindow Kind = "GW"
+	// FixedWindows places elements into consecutive, non-overlapping windows
+	// of a fixed size.
+	FixedWindows Kind = "FIX"
+	// SlidingWindows places elements into overlapping windows of a fixed
+	// size that start every period.
+	SlidingWindows Kind = "SLI"
+	// Sessions places elements into windows that extend by a gap past each
+	// element and merges the windows of a key that overlap.
+	Sessions Kind = "SES"
+	// Custom places elements into interval windows assigned, and optionally
+	// merged, by user functions.
+	Custom Kind = "CUS"
+)
+
+// AccumulationMode determines how the panes of a window relate when its
+// trigger fires repeatedly.
+type AccumulationMode string
+
+const (
+	// Discarding emits only the elements that arrived since the last pane.
+	// It is the default.
+	Discarding AccumulationMode = "discarding"
+	// Accumulating emits all elements of the window so far in each pane.
+	Accumulating AccumulationMode = "accumulating"
 )
 
 // NewGlobalWindow returns the default window to be used for a collection.
 func NewGlobalWindow() *Window {
 	return &Window{k: GlobalWindow}
 }
+
+// NewFixedWindows returns fixed windows of the given size. Windows are
+// aligned to the Unix epoch.
+func NewFixedWindows(size time.Duration) *Window {
+	if size <= 0 {
+		panic(fmt.Sprintf("invalid fixed window size: %v", size))
+	}
+	return &Window{k: FixedWindows, size: size}
+}
+
+// NewSlidingWindows returns sliding windows of the given size that start
+// every period. Windows are aligned to the Unix epoch.
+func NewSlidingWindows(period, size time.Duration) *Window {
+	if period <= 0 || size <= 0 {
+		panic(fmt.Sprintf("invalid sliding window period %v and size %v", period, size))
+	}
+	return &Window{k: SlidingWindows, period: period, size: size}
+}
+
+// NewSessions returns session windows with the given gap.
+func NewSessions(gap time.Duration) *Window {
+	if gap <= 0 {
+		panic(fmt.Sprintf("invalid session gap: %v", gap))
+	}
+	return &Window{k: Sessions, gap: gap}
+}
+
+// Type signatures of custom assign/merge functions for verification.
+var (
+	intervalsType = reflect.TypeOf((*[]IntervalWindow)(nil)).Elem()
+
+	assignSig = &funcx.Signature{
+		Args:   []reflect.Type{typex.EventTimeType},
+		Return: []reflect.Type{intervalsType},
+	}
+
+	mergeSig = &funcx.Signature{
+		Args:   []reflect.Type{intervalsType},
+		Return: []reflect.Type{intervalsType, reflect.TypeOf((*[]int)(nil)).Elem()},
+	}
+)
+
+// NewCustom returns custom windows with the given name, which assign each
+// element to interval windows based on its timestamp using a function
+//
+//    assign : EventTime -> []IntervalWindow
+//
+// and, if merge is not nil, merge the windows of a key when grouped using a
+// function of the same form as MergeIntervals:
+//
+//    merge : []IntervalWindow -> ([]IntervalWindow, []int)
+//
+// The functions must be registered with beam.RegisterFunction to be usable
+// on remote workers.
+func NewCustom(name string, assign, merge interface{}) (*Window, error) {
+	if name == "" {
+		return nil, fmt.Errorf("custom windows must have a name")
+	}
+	if err := funcx.Satisfy(assign, assignSig); err != nil {
+		return nil, fmt.Errorf("assign has incorrect signature: %v", err)
+	}
+	// Windows are not data, so the functions are not processed by funcx.New.
+
+	w := &Window{k: Custom, name: name, assign: &funcx.Fn{Fn: reflectx.MakeFunc(assign)}}
+	if merge != nil {
+		if err := funcx.Satisfy(merge, mergeSig); err != nil {
+			return nil, fmt.Errorf("merge has incorrect signature: %v", err)
+		}
+		w.merge = &funcx.Fn{Fn: reflectx.MakeFunc(merge)}
+	}
+	return w, nil
+}
+
 func (w *Window) String() string {
-	return string(w.k)
+	var opts []string
+	if t := w.Trigger(); t.Kind != DefaultTrigger {
+		opts = append(opts, t.String())
+	}
+	if w.lateness > 0 {
+		opts = append(opts, fmt.Sprintf("lateness=%v", w.lateness))
+	}
+	if w.accumulate {
+		opts = append(opts, string(Accumulating))
+	}
+	if len(opts) > 0 {
+		return fmt.Sprintf("%v{%v}", w.fnString(), strings.Join(opts, ","))
+	}
+	return w.fnString()
+}
+
+// fnString returns the description of how elements are windowed.
+func (w *Window) fnString() string {
+	switch w.k {
+	case FixedWindows:
+		return fmt.Sprintf("%v[%v]", w.k, w.size)
+	case SlidingWindows:
+		return fmt.Sprintf("%v[%v@%v]", w.k, w.size, w.period)
+	case Sessions:
+		return fmt.Sprintf("%v[%v]", w.k, w.gap)
+	case Custom:
+		return fmt.Sprintf("%v[%v]", w.k, w.name)
+	default:
+		return string(w.k)
+	}
+}
+
+// Trigger returns the trigger of the windowing strategy.
+func (w *Window) Trigger() *Trigger {
+	if w.trigger == nil {
+		return TriggerDefault()
+	}
+	return w.trigger
+}
+
+// WithTrigger returns a copy of the windowing strategy that emits panes as
+// determined by the given trigger.
+func (w *Window) WithTrigger(t *Trigger) *Window {
+	ret := *w
+	ret.trigger = t
+	return &ret
+}
+
+// AccumulationMode returns the accumulation mode of the windowing strategy.
+func (w *Window) AccumulationMode() AccumulationMode {
+	if w.accumulate {
+		return Accumulating
+	}
+	return Discarding
+}
+
+// WithAccumulationMode returns a copy of the windowing strategy that emits
+// panes in the given mode.
+func (w *Window) WithAccumulationMode(m AccumulationMode) *Window {
+	switch m {
+	case Discarding, Accumulating:
+	default:
+		panic(fmt.Sprintf("invalid accumulation mode: %v", m))
+	}
+	ret := *w
+	ret.accumulate = m == Accumulating
+	return &ret
+}
+
+// AllowedLateness returns how long after the end of a window, as measured by
+// the watermark, late elements are still grouped into it.
+func (w *Window) AllowedLateness() time.Duration {
+	return w.lateness
+}
+
+// WithAllowedLateness returns a copy of the windowing strategy that groups
+// elements that arrive up to the given duration after the watermark has passed
+// the end of their window. Later elements are dropped. The default is zero.
+func (w *Window) WithAllowedLateness(d time.Duration) *Window {
+	if d < 0 {
+		panic(fmt.Sprintf("invalid allowed lateness: %v", d))
+	}
+	ret := *w
+	ret.lateness = d
+	return &ret
+}
+
+// IsExpired returns true iff the watermark has passed the end of the given
+// window by more than the allowed lateness, such that elements in it are
+// dropped. The global window never expires.
+func (w *Window) IsExpired(win typex.Window, watermark typex.EventTime) bool {
+	if _, ok := win.(SingleGlobalWindow); ok {
+		return false
+	}
+	return time.Time(watermark).After(time.Time(win.MaxTimestamp()).Add(w.lateness))
 }
 
 // Kind returns the kind of the window.
@@ -47,25 +257,140 @@ func (w *Window) Kind() Kind {
 	return w.k
 }
 
+// Size returns the size of fixed and sliding windows.
+func (w *Window) Size() time.Duration {
+	return w.size
+}
+
+// Period returns the period of sliding windows.
+func (w *Window) Period() time.Duration {
+	return w.period
+}
+
+// Gap returns the gap of session windows.
+func (w *Window) Gap() time.Duration {
+	return w.gap
+}
+
+// Name returns the name of custom windows.
+func (w *Window) Name() string {
+	return w.name
+}
+
+// AssignFn returns the assign function of custom windows.
+func (w *Window) AssignFn() *funcx.Fn {
+	return w.assign
+}
+
+// MergeFn returns the merge function of custom windows, if any.
+func (w *Window) MergeFn() *funcx.Fn {
+	return w.merge
+}
+
+// IsMerging returns true iff the windows of a key may be merged when grouped,
+// such as for sessions.
+func (w *Window) IsMerging() bool {
+	return w.k == Sessions || w.merge != nil
+}
+
+// MergeWindows merges the given windows of a key. It returns the merged
+// windows and, for each given window, the index of the merged window that
+// contains it. Windows are returned unchanged if not merging.
+func (w *Window) MergeWindows(list []IntervalWindow) ([]IntervalWindow, []int, error) {
+	switch {
+	case w.k == Sessions:
+		merged, index := MergeIntervals(list)
+		return merged, index, nil
+
+	case w.merge != nil:
+		ret := w.merge.Fn.Call([]interface{}{list})
+		merged, index := ret[0].([]IntervalWindow), ret[1].([]int)
+		if len(index) != len(list) {
+			return nil, nil, fmt.Errorf("%v merged %v windows into %v indices", w, len(list), len(index))
+		}
+		for _, i := range index {
+			if i < 0 || i >= len(merged) {
+				return nil, nil, fmt.Errorf("%v merged into window %v of %v", w, i, len(merged))
+			}
+		}
+		return merged, index, nil
+
+	default:
+		index := make([]int, len(list))
+		for i := range index {
+			index[i] = i
+		}
+		return list, index, nil
+	}
+}
+
 // Equals returns true iff the windows have the same kind and underlying behavior.
 // Built-in window types (such as global window) are only equal to the same
 // instances of the window. A user-defined window that happens to match a
 // built-in will not match on Equals().
 func (w *Window) Equals(o *Window) bool {
+	return w.Trigger().Equals(o.Trigger()) && w.lateness == o.lateness && w.accumulate == o.accumulate && w.equalsFn(o)
+}
+
+func (w *Window) equalsFn(o *Window) bool {
 	switch w.Kind() {
-	case GlobalWindow:
-		return o.Kind() == w.Kind()
+	case GlobalWindow, FixedWindows, SlidingWindows, Sessions:
+		return o.Kind() == w.Kind() && o.size == w.size && o.period == w.period && o.gap == w.gap
+	case Custom:
+		return o.Kind() == Custom && o.name == w.name && fnName(o.assign) == fnName(w.assign) && fnName(o.merge) == fnName(w.merge)
 	default:
 		panic(fmt.Sprintf("unknown window type: %v", w))
-		// TODO(wcn): implement other window types
 	}
 }
 
-// TODO: Sessions, FixedWindows, etc.
+// AssignWindows returns the windows that an element with the given timestamp
+// is placed into.
+func (w *Window) AssignWindows(t typex.EventTime) []typex.Window {
+	ts := time.Time(t)
+
+	switch w.k {
+	case GlobalWindow:
+		return []typex.Window{SingleGlobalWindow{}}
+
+	case FixedWindows:
+		start := alignedStart(ts, w.size)
+		return []typex.Window{newIntervalWindow(start, w.size)}
 
-// CustomWindow is the base-case windowing that relies on a user
-// specified function for windowing behavior
-// var CustomWindow WindowKind
+	case SlidingWindows:
+		var ret []typex.Window
+		for start := alignedStart(ts, w.period); start.After(ts.Add(-w.size)); start = start.Add(-w.period) {
+			ret = append(ret, newIntervalWindow(start, w.size))
+		}
+		return ret
 
-// TODO(wcn): establish the other window types when we are
-// ready to support them.
+	case Sessions:
+		return []typex.Window{newIntervalWindow(ts, w.gap)}
+
+	case Custom:
+		var ret []typex.Window
+		for _, iw := range w.assign.Fn.Call([]interface{}{t})[0].([]IntervalWindow) {
+			ret = append(ret, iw)
+		}
+		return ret
+
+	default:
+		panic(fmt.Sprintf("unknown window type: %v", w))
+	}
+}
+
+func fnName(fn *funcx.Fn) string {
+	if fn == nil {
+		return ""
+	}
+	return fn.Fn.Name()
+}
+
+// alignedStart returns the latest multiple of size since the Unix epoch no
+// later than the given time.
+func alignedStart(t time.Time, size time.Duration) time.Time {
+	rem := time.Duration(t.UnixNano() % int64(size))
+	if rem < 0 {
+		rem += size
+	}
+	return t.Add(-rem)
+}
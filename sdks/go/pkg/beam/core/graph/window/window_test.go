@@ -0,0 +1,197 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func interval(start, end int64) IntervalWindow {
+	return IntervalWindow{Start: typex.EventTime(time.Unix(start, 0)), End: typex.EventTime(time.Unix(end, 0))}
+}
+
+func TestAssignWindows(t *testing.T) {
+	tests := []struct {
+		w    *Window
+		t    int64
+		want []typex.Window
+	}{
+		{NewGlobalWindow(), 12, []typex.Window{SingleGlobalWindow{}}},
+		{NewFixedWindows(10 * time.Second), 12, []typex.Window{interval(10, 20)}},
+		{NewFixedWindows(10 * time.Second), 20, []typex.Window{interval(20, 30)}},
+		{NewFixedWindows(10 * time.Second), -5, []typex.Window{interval(-10, 0)}},
+		{NewSlidingWindows(5*time.Second, 10*time.Second), 12, []typex.Window{interval(10, 20), interval(5, 15)}},
+		{NewSessions(5 * time.Second), 12, []typex.Window{interval(12, 17)}},
+	}
+
+	for _, test := range tests {
+		got := test.w.AssignWindows(typex.EventTime(time.Unix(test.t, 0)))
+		if len(got) != len(test.want) {
+			t.Errorf("%v.AssignWindows(%v) = %v, want %v", test.w, test.t, got, test.want)
+			continue
+		}
+		for i, w := range got {
+			if !w.Equals(test.want[i]) {
+				t.Errorf("%v.AssignWindows(%v) = %v, want %v", test.w, test.t, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMergeIntervals(t *testing.T) {
+	list := []IntervalWindow{interval(11, 16), interval(1, 6), interval(25, 30), interval(3, 8), interval(12, 17)}
+
+	merged, index := MergeIntervals(list)
+
+	want := []IntervalWindow{interval(1, 8), interval(11, 17), interval(25, 30)}
+	if len(merged) != len(want) {
+		t.Fatalf("MergeIntervals(%v) = %v, want %v", list, merged, want)
+	}
+	for i, w := range merged {
+		if !w.Equals(want[i]) {
+			t.Errorf("MergeIntervals(%v) = %v, want %v", list, merged, want)
+		}
+	}
+	for i, w := range list {
+		if m := merged[index[i]]; !m.Intersects(w) {
+			t.Errorf("MergeIntervals(%v) placed %v into %v", list, w, m)
+		}
+	}
+}
+
+func everyThird(t typex.EventTime) []IntervalWindow {
+	start := time.Time(t).Truncate(3 * time.Second)
+	return []IntervalWindow{{Start: typex.EventTime(start), End: typex.EventTime(start.Add(3 * time.Second))}}
+}
+
+func badMerge(list []IntervalWindow) ([]IntervalWindow, []int) {
+	return list, nil
+}
+
+func TestCustom(t *testing.T) {
+	if _, err := NewCustom("", everyThird, nil); err == nil {
+		t.Errorf("NewCustom without name succeeded, want error")
+	}
+	if _, err := NewCustom("bad", func(int) []IntervalWindow { return nil }, nil); err == nil {
+		t.Errorf("NewCustom with bad assign succeeded, want error")
+	}
+	if _, err := NewCustom("bad", everyThird, everyThird); err == nil {
+		t.Errorf("NewCustom with bad merge succeeded, want error")
+	}
+
+	w, err := NewCustom("third", everyThird, nil)
+	if err != nil {
+		t.Fatalf("NewCustom failed: %v", err)
+	}
+	if w.IsMerging() {
+		t.Errorf("%v.IsMerging() = true, want false", w)
+	}
+	if got := w.AssignWindows(typex.EventTime(time.Unix(7, 0))); len(got) != 1 || !got[0].Equals(interval(6, 9)) {
+		t.Errorf("%v.AssignWindows(7) = %v, want [%v]", w, got, interval(6, 9))
+	}
+
+	m, err := NewCustom("third", everyThird, MergeIntervals)
+	if err != nil {
+		t.Fatalf("NewCustom failed: %v", err)
+	}
+	if !m.IsMerging() || m.Equals(w) || !m.Equals(m) {
+		t.Errorf("%v is not a distinct merging window", m)
+	}
+	merged, _, err := m.MergeWindows([]IntervalWindow{interval(1, 4), interval(3, 6)})
+	if err != nil || len(merged) != 1 || !merged[0].Equals(interval(1, 6)) {
+		t.Errorf("%v.MergeWindows() = %v, %v, want [%v]", m, merged, err, interval(1, 6))
+	}
+
+	bad, err := NewCustom("bad", everyThird, badMerge)
+	if err != nil {
+		t.Fatalf("NewCustom failed: %v", err)
+	}
+	if _, _, err := bad.MergeWindows([]IntervalWindow{interval(1, 4)}); err == nil {
+		t.Errorf("%v.MergeWindows() succeeded, want error", bad)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	early := TriggerAfterWatermark().EarlyFiring(TriggerRepeat(TriggerAfterCount(10)))
+	tests := []struct {
+		t    *Trigger
+		want string
+	}{
+		{TriggerDefault(), "Default"},
+		{TriggerAfterProcessingTime(time.Minute), "AfterProcessingTime(1m0s)"},
+		{early, "AfterWatermark(early=Repeat(AfterCount(10)))"},
+		{early.LateFiring(TriggerAlways()), "AfterWatermark(early=Repeat(AfterCount(10)),late=Always)"},
+		{TriggerAfterAny(TriggerAfterCount(2), TriggerNever()), "AfterAny(AfterCount(2),Never)"},
+	}
+	for _, test := range tests {
+		if got := test.t.String(); got != test.want {
+			t.Errorf("Trigger = %v, want %v", got, test.want)
+		}
+	}
+
+	w := NewFixedWindows(time.Minute)
+	triggered := w.WithTrigger(early)
+	if w.Trigger().Kind != DefaultTrigger || w.Equals(triggered) {
+		t.Errorf("WithTrigger(%v) modified %v", early, w)
+	}
+	if !triggered.Trigger().Equals(early) || !triggered.Equals(w.WithTrigger(early)) {
+		t.Errorf("%v does not have trigger %v", triggered, early)
+	}
+	if !w.Equals(w.WithTrigger(TriggerDefault())) {
+		t.Errorf("%v does not equal itself with the default trigger", w)
+	}
+
+	acc := triggered.WithAccumulationMode(Accumulating)
+	if got, want := acc.String(), "FIX[1m0s]{AfterWatermark(early=Repeat(AfterCount(10))),accumulating}"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+	if acc.Equals(triggered) || triggered.AccumulationMode() != Discarding {
+		t.Errorf("WithAccumulationMode(%v) modified %v", Accumulating, triggered)
+	}
+}
+
+func TestAllowedLateness(t *testing.T) {
+	w := NewFixedWindows(10 * time.Second).WithAllowedLateness(5 * time.Second)
+	if got, want := w.String(), "FIX[10s]{lateness=5s}"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+	if w.Equals(NewFixedWindows(10 * time.Second)) {
+		t.Errorf("%v equals the window without allowed lateness", w)
+	}
+
+	tests := []struct {
+		watermark int64
+		expired   bool
+	}{
+		{5, false},
+		{10, false},
+		{14, false},
+		{15, true},
+	}
+	for _, test := range tests {
+		wm := typex.EventTime(time.Unix(test.watermark, 0))
+		if got := w.IsExpired(interval(0, 10), wm); got != test.expired {
+			t.Errorf("IsExpired([0, 10), %v) = %v, want %v", test.watermark, got, test.expired)
+		}
+		if w.IsExpired(SingleGlobalWindow{}, wm) {
+			t.Errorf("IsExpired(global, %v) = true, want false", test.watermark)
+		}
+	}
+}
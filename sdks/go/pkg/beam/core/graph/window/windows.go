@@ -0,0 +1,117 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// This file defines the concrete windows that elements are assigned to.
+
+// endOfGlobalWindow is the largest timestamp of the global window, which is
+// one day before the largest timestamp of the Beam model.
+var endOfGlobalWindow = typex.EventTime(time.Unix(math.MaxInt64/1000000, 0).Add(-24 * time.Hour))
+
+// SingleGlobalWindow is the single window of the global windowing.
+type SingleGlobalWindow struct{}
+
+// MaxTimestamp returns the largest timestamp in the global window.
+func (SingleGlobalWindow) MaxTimestamp() typex.EventTime {
+	return endOfGlobalWindow
+}
+
+// Equals returns true iff the other window is the global window.
+func (SingleGlobalWindow) Equals(o typex.Window) bool {
+	_, ok := o.(SingleGlobalWindow)
+	return ok
+}
+
+func (SingleGlobalWindow) String() string {
+	return "[*]"
+}
+
+// IntervalWindow is the window of event times in [Start, End).
+type IntervalWindow struct {
+	Start, End typex.EventTime
+}
+
+func newIntervalWindow(start time.Time, size time.Duration) IntervalWindow {
+	return IntervalWindow{Start: typex.EventTime(start), End: typex.EventTime(start.Add(size))}
+}
+
+// MaxTimestamp returns the largest timestamp in the window, which is one
+// millisecond before its end.
+func (w IntervalWindow) MaxTimestamp() typex.EventTime {
+	return typex.EventTime(time.Time(w.End).Add(-time.Millisecond))
+}
+
+// Equals returns true iff the other window is the same interval.
+func (w IntervalWindow) Equals(o typex.Window) bool {
+	other, ok := o.(IntervalWindow)
+	return ok && time.Time(w.Start).Equal(time.Time(other.Start)) && time.Time(w.End).Equal(time.Time(other.End))
+}
+
+// Intersects returns true iff the windows overlap.
+func (w IntervalWindow) Intersects(o IntervalWindow) bool {
+	return time.Time(w.Start).Before(time.Time(o.End)) && time.Time(o.Start).Before(time.Time(w.End))
+}
+
+// Span returns the smallest window that contains both windows.
+func (w IntervalWindow) Span(o IntervalWindow) IntervalWindow {
+	ret := w
+	if time.Time(o.Start).Before(time.Time(ret.Start)) {
+		ret.Start = o.Start
+	}
+	if time.Time(o.End).After(time.Time(ret.End)) {
+		ret.End = o.End
+	}
+	return ret
+}
+
+func (w IntervalWindow) String() string {
+	return fmt.Sprintf("[%v:%v)", time.Time(w.Start).UnixNano()/int64(time.Millisecond), time.Time(w.End).UnixNano()/int64(time.Millisecond))
+}
+
+// MergeIntervals merges overlapping interval windows, as done for sessions.
+// It returns the merged windows in order of start and, for each given window,
+// the index of the merged window that contains it.
+func MergeIntervals(list []IntervalWindow) ([]IntervalWindow, []int) {
+	order := make([]int, len(list))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return time.Time(list[order[i]].Start).Before(time.Time(list[order[j]].Start))
+	})
+
+	var merged []IntervalWindow
+	index := make([]int, len(list))
+	for _, i := range order {
+		w := list[i]
+		if n := len(merged); n > 0 && merged[n-1].Intersects(w) {
+			merged[n-1] = merged[n-1].Span(w)
+		} else {
+			merged = append(merged, w)
+		}
+		index[i] = len(merged) - 1
+	}
+	return merged, index
+}
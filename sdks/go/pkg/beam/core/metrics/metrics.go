@@ -79,6 +79,14 @@ func SetPTransformID(ctx context.Context, id string) context.Context {
 	return context.WithValue(ctx, ptransformKey, id)
 }
 
+// BundleID returns the id of the current bundle, if set.
+func BundleID(ctx context.Context) string {
+	if id := ctx.Value(bundleKey); id != nil {
+		return id.(string)
+	}
+	return ""
+}
+
 func getContextKey(ctx context.Context, n name) key {
 	key := key{name: n, bundle: "(bundle id unset)", ptransform: "(ptransform id unset)"}
 	if id := ctx.Value(bundleKey); id != nil {
@@ -161,6 +169,10 @@ func NewCounter(ns, n string) Counter {
 // Inc increments the counter within the given PTransform context by v.
 func (m Counter) Inc(ctx context.Context, v int64) {
 	key := getContextKey(ctx, m.name)
+	if c, ok := counters.Load(key); ok {
+		c.(*counter).inc(v)
+		return
+	}
 	cs := &counter{
 		value: v,
 	}
@@ -228,6 +240,10 @@ func NewDistribution(ns, n string) Distribution {
 // Update updates the distribution within the given PTransform context with v.
 func (m Distribution) Update(ctx context.Context, v int64) {
 	key := getContextKey(ctx, m.name)
+	if d, ok := distributions.Load(key); ok {
+		d.(*distribution).update(v)
+		return
+	}
 	ds := &distribution{
 		count: 1,
 		sum:   v,
@@ -262,6 +278,20 @@ func (m *distribution) update(v int64) {
 	m.mu.Unlock()
 }
 
+// merge merges the values of the given distribution into this one.
+func (m *distribution) merge(o *distribution) {
+	m.mu.Lock()
+	if o.min < m.min {
+		m.min = o.min
+	}
+	if o.max > m.max {
+		m.max = o.max
+	}
+	m.count += o.count
+	m.sum += o.sum
+	m.mu.Unlock()
+}
+
 func (m *distribution) String() string {
 	return fmt.Sprintf("count: %d sum: %d min: %d max: %d", m.count, m.sum, m.min, m.max)
 }
@@ -303,6 +333,10 @@ func NewGauge(ns, n string) Gauge {
 // Set sets the gauge to the given value, and associates it with the current time on the clock.
 func (m Gauge) Set(ctx context.Context, v int64) {
 	key := getContextKey(ctx, m.name)
+	if g, ok := gauges.Load(key); ok {
+		g.(*gauge).set(v)
+		return
+	}
 	gs := &gauge{
 		t: now(),
 		v: v,
@@ -346,6 +380,15 @@ func (m *gauge) set(v int64) {
 	m.mu.Unlock()
 }
 
+// merge sets the gauge to the value of the given gauge, if set later.
+func (m *gauge) merge(o *gauge) {
+	m.mu.Lock()
+	if o.t.After(m.t) {
+		m.t, m.v = o.t, o.v
+	}
+	m.mu.Unlock()
+}
+
 func (m *gauge) toProto() *fnexecution_v1.Metrics_User {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -449,6 +492,7 @@ func dumpTo(p func(format string, args ...interface{})) {
 func Clear() {
 	mu.Lock()
 	store = make(map[string]map[string]map[name]userMetric)
+	progress = make(map[string]map[string]*Progress)
 	counters = sync.Map{}
 	distributions = sync.Map{}
 	gauges = sync.Map{}
@@ -471,5 +515,63 @@ func ClearBundleData(b string) {
 		}
 	}
 	delete(store, b)
+	delete(progress, b)
+	mu.Unlock()
+}
+
+// MergeBundleData merges the metrics of bundle from into bundle to and
+// removes bundle from, such as to commit the metrics of a bundle attempt that
+// succeeded. Counters are summed, distributions merged and gauges hold the
+// latest value. The progress of the bundles is merged likewise.
+func MergeBundleData(from, to string) {
+	mu.Lock()
+	pts := store[from]
+	delete(store, from)
+	for _, p := range progress[from] {
+		addProgress(to, *p)
+	}
+	delete(progress, from)
 	mu.Unlock()
+
+	for pt, ms := range pts {
+		for n, m := range ms {
+			src := key{name: n, bundle: from, ptransform: pt}
+			dst := key{name: n, bundle: to, ptransform: pt}
+
+			switch m := m.(type) {
+			case *counter:
+				counters.Delete(src)
+				m.mu.Lock()
+				cs := &counter{value: m.value}
+				m.mu.Unlock()
+				if c, loaded := counters.LoadOrStore(dst, cs); loaded {
+					c.(*counter).inc(cs.value)
+				} else {
+					storeMetric(dst, cs)
+				}
+
+			case *distribution:
+				distributions.Delete(src)
+				m.mu.Lock()
+				ds := &distribution{count: m.count, sum: m.sum, min: m.min, max: m.max}
+				m.mu.Unlock()
+				if d, loaded := distributions.LoadOrStore(dst, ds); loaded {
+					d.(*distribution).merge(ds)
+				} else {
+					storeMetric(dst, ds)
+				}
+
+			case *gauge:
+				gauges.Delete(src)
+				m.mu.Lock()
+				gs := &gauge{t: m.t, v: m.v}
+				m.mu.Unlock()
+				if g, loaded := gauges.LoadOrStore(dst, gs); loaded {
+					g.(*gauge).merge(gs)
+				} else {
+					storeMetric(dst, gs)
+				}
+			}
+		}
+	}
 }
@@ -0,0 +1,126 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"sort"
+)
+
+// Progress is the progress of a PTransform, aggregated over bundles: the
+// elements processed and output, by local input and output name, and the
+// sampled encoded sizes of the elements read or written on the data plane.
+// Progress is reported by the SDK rather than by user code, so it is stored
+// apart from user metrics.
+type Progress struct {
+	PTransform   string
+	Processed    map[string]int64
+	Output       map[string]int64
+	SampledSizes map[string]DistributionValue
+}
+
+// DistributionValue is the value of a distribution.
+type DistributionValue struct {
+	Count, Sum, Min, Max int64
+}
+
+// merge merges the given distribution into d.
+func (d *DistributionValue) merge(o DistributionValue) {
+	if o.Count == 0 {
+		return
+	}
+	if d.Count == 0 || o.Min < d.Min {
+		d.Min = o.Min
+	}
+	if d.Count == 0 || o.Max > d.Max {
+		d.Max = o.Max
+	}
+	d.Count += o.Count
+	d.Sum += o.Sum
+}
+
+// add aggregates the given progress of the same PTransform into p.
+func (p *Progress) add(o Progress) {
+	for n, v := range o.Processed {
+		if p.Processed == nil {
+			p.Processed = make(map[string]int64)
+		}
+		p.Processed[n] += v
+	}
+	for n, v := range o.Output {
+		if p.Output == nil {
+			p.Output = make(map[string]int64)
+		}
+		p.Output[n] += v
+	}
+	for n, v := range o.SampledSizes {
+		if p.SampledSizes == nil {
+			p.SampledSizes = make(map[string]DistributionValue)
+		}
+		d := p.SampledSizes[n]
+		d.merge(v)
+		p.SampledSizes[n] = d
+	}
+}
+
+// progress is a map of BundleIDs to PTransformIDs to progress. It is
+// protected by mu, like store.
+var progress = make(map[string]map[string]*Progress)
+
+// AddProgress aggregates the given progress into the progress of its
+// PTransform in the current bundle.
+func AddProgress(ctx context.Context, p Progress) {
+	b := BundleID(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	addProgress(b, p)
+}
+
+// addProgress aggregates progress into the given bundle. It must be called
+// with mu held.
+func addProgress(b string, p Progress) {
+	pts, ok := progress[b]
+	if !ok {
+		pts = make(map[string]*Progress)
+		progress[b] = pts
+	}
+	cur, ok := pts[p.PTransform]
+	if !ok {
+		cur = &Progress{PTransform: p.PTransform}
+		pts[p.PTransform] = cur
+	}
+	cur.add(p)
+}
+
+// BundleProgress returns the progress of the PTransforms in the given bundle,
+// sorted by PTransform. For the direct runner, the bundle is the execution
+// plan.
+func BundleProgress(bundle string) []Progress {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var ret []Progress
+	for pt, p := range progress[bundle] {
+		cp := Progress{PTransform: pt}
+		cp.add(*p)
+		ret = append(ret, cp)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].PTransform < ret[j].PTransform
+	})
+	return ret
+}
@@ -0,0 +1,58 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBundleProgress(t *testing.T) {
+	Clear()
+	defer Clear()
+
+	b1 := SetBundleID(context.Background(), "b1")
+	AddProgress(b1, Progress{PTransform: "B", Processed: map[string]int64{"i0": 2}})
+	AddProgress(b1, Progress{PTransform: "A", Output: map[string]int64{"i0": 3}})
+	AddProgress(b1, Progress{PTransform: "A", Output: map[string]int64{"i0": 1}, SampledSizes: map[string]DistributionValue{"out": {Count: 2, Sum: 10, Min: 4, Max: 6}}})
+
+	attempt := SetBundleID(context.Background(), "attempt")
+	AddProgress(attempt, Progress{PTransform: "A", SampledSizes: map[string]DistributionValue{"out": {Count: 1, Sum: 2, Min: 2, Max: 2}}})
+	MergeBundleData("attempt", "b1")
+
+	want := []Progress{
+		{PTransform: "A", Output: map[string]int64{"i0": 4}, SampledSizes: map[string]DistributionValue{"out": {Count: 3, Sum: 12, Min: 2, Max: 6}}},
+		{PTransform: "B", Processed: map[string]int64{"i0": 2}},
+	}
+	if got := BundleProgress("b1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("BundleProgress(b1) = %+v, want %+v", got, want)
+	}
+	if got := BundleProgress("attempt"); len(got) != 0 {
+		t.Errorf("BundleProgress(attempt) = %+v, want none", got)
+	}
+
+	// Progress is not a user metric.
+
+	if got := BundleResults("b1"); len(got) != 0 {
+		t.Errorf("BundleResults(b1) = %+v, want none", got)
+	}
+
+	ClearBundleData("b1")
+	if got := BundleProgress("b1"); len(got) != 0 {
+		t.Errorf("BundleProgress(b1) after clear = %+v, want none", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// Filter selects metric results by step and name. Empty fields match any
+// value.
+type Filter struct {
+	// Step is the PTransform that reported the metric, such as
+	// "main.extractFn".
+	Step      string
+	Namespace string
+	Name      string
+}
+
+// Matches returns true iff the result is selected by the filter.
+func (f Filter) Matches(r Result) bool {
+	return (f.Step == "" || f.Step == r.PTransform) &&
+		(f.Namespace == "" || f.Namespace == r.Namespace) &&
+		(f.Name == "" || f.Name == r.Name)
+}
+
+// QueryResults are the metric results selected by a query, by kind.
+type QueryResults struct {
+	Counters      []Result
+	Distributions []Result
+	Gauges        []Result
+}
+
+// ResultSet is the queryable set of metric results of a job. It also holds
+// the progress of the PTransforms of the job, which is kept apart from the
+// user metric results.
+type ResultSet struct {
+	results  []Result
+	progress []Progress
+}
+
+// NewResultSet returns a ResultSet of the given results.
+func NewResultSet(results []Result) *ResultSet {
+	return &ResultSet{results: results}
+}
+
+// WithProgress sets the progress of the set and returns the set.
+func (s *ResultSet) WithProgress(progress []Progress) *ResultSet {
+	s.progress = progress
+	return s
+}
+
+// Progress returns the progress of the PTransforms, sorted by PTransform.
+func (s *ResultSet) Progress() []Progress {
+	return s.progress
+}
+
+// All returns all metric results.
+func (s *ResultSet) All() []Result {
+	return s.results
+}
+
+// Query returns the metric results selected by the filter.
+func (s *ResultSet) Query(f Filter) QueryResults {
+	var ret QueryResults
+	for _, r := range s.results {
+		if !f.Matches(r) {
+			continue
+		}
+		switch r.Kind {
+		case CounterKind:
+			ret.Counters = append(ret.Counters, r)
+		case DistributionKind:
+			ret.Distributions = append(ret.Distributions, r)
+		case GaugeKind:
+			ret.Gauges = append(ret.Gauges, r)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,204 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Kinds of metric results.
+const (
+	CounterKind      = "counter"
+	DistributionKind = "distribution"
+	GaugeKind        = "gauge"
+)
+
+// Result is the committed value of a metric for a PTransform, aggregated over
+// all bundles: counters are summed, distributions merged and gauges hold
+// the latest value. Only the fields relevant for the kind are set.
+type Result struct {
+	PTransform string `json:"ptransform"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+
+	Value     int64     `json:"value"`               // counter, gauge
+	Count     int64     `json:"count,omitempty"`     // distribution
+	Sum       int64     `json:"sum,omitempty"`       // distribution
+	Min       int64     `json:"min,omitempty"`       // distribution
+	Max       int64     `json:"max,omitempty"`       // distribution
+	Timestamp time.Time `json:"timestamp,omitempty"` // gauge
+}
+
+// Results returns all metrics available locally, aggregated over bundles and
+// sorted by PTransform, namespace and name. Metrics are only available locally
+// for pipelines executed in-process, such as by the direct runner.
+func Results() []Result {
+	return results(func(string) bool { return true })
+}
+
+// BundleResults returns the metrics of the given bundle, aggregated and sorted
+// like Results. For the direct runner, the bundle is the execution plan.
+func BundleResults(bundle string) []Result {
+	return results(func(b string) bool { return b == bundle })
+}
+
+// results aggregates the metrics of the bundles for which keep returns true.
+func results(keep func(bundle string) bool) []Result {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	type rkey struct {
+		pt   string
+		name name
+	}
+	agg := make(map[rkey]*Result)
+
+	for b, pts := range store {
+		if !keep(b) {
+			continue
+		}
+		for pt, ms := range pts {
+			for n, m := range ms {
+				k := rkey{pt: pt, name: n}
+				r, ok := agg[k]
+				if !ok {
+					r = &Result{PTransform: pt, Namespace: n.namespace, Name: n.name}
+					agg[k] = r
+				}
+				r.add(m)
+			}
+		}
+	}
+
+	var ret []Result
+	for _, r := range agg {
+		ret = append(ret, *r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.PTransform != b.PTransform {
+			return a.PTransform < b.PTransform
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return ret
+}
+
+// add aggregates the value of the given metric cell into the result.
+func (r *Result) add(m userMetric) {
+	switch m := m.(type) {
+	case *counter:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		r.Kind = CounterKind
+		r.Value += m.value
+
+	case *distribution:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if r.Kind == "" || m.min < r.Min {
+			r.Min = m.min
+		}
+		if r.Kind == "" || m.max > r.Max {
+			r.Max = m.max
+		}
+		r.Kind = DistributionKind
+		r.Count += m.count
+		r.Sum += m.sum
+
+	case *gauge:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		r.Kind = GaugeKind
+		if m.t.After(r.Timestamp) {
+			r.Timestamp = m.t
+			r.Value = m.v
+		}
+
+	default:
+		panic(fmt.Sprintf("unexpected metric cell: %T", m))
+	}
+}
+
+// WriteJSON writes the results as an indented JSON array.
+func WriteJSON(w io.Writer, results []Result) error {
+	// Omit the timestamp for metrics other than gauges.
+	type jsonResult struct {
+		Result
+		Timestamp *time.Time `json:"timestamp,omitempty"`
+	}
+
+	list := []jsonResult{}
+	for _, r := range results {
+		jr := jsonResult{Result: r}
+		if !r.Timestamp.IsZero() {
+			ts := r.Timestamp.UTC()
+			jr.Timestamp = &ts
+		}
+		list = append(list, jr)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// csvHeader is the header row written by WriteCSV.
+var csvHeader = []string{"ptransform", "namespace", "name", "kind", "value", "count", "sum", "min", "max", "timestamp"}
+
+// WriteCSV writes the results as CSV with a header row. Fields not relevant
+// for the kind of a metric are empty.
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		row := []string{r.PTransform, r.Namespace, r.Name, r.Kind, "", "", "", "", "", ""}
+		switch r.Kind {
+		case CounterKind:
+			row[4] = strconv.FormatInt(r.Value, 10)
+		case DistributionKind:
+			row[5] = strconv.FormatInt(r.Count, 10)
+			row[6] = strconv.FormatInt(r.Sum, 10)
+			row[7] = strconv.FormatInt(r.Min, 10)
+			row[8] = strconv.FormatInt(r.Max, 10)
+		case GaugeKind:
+			row[4] = strconv.FormatInt(r.Value, 10)
+			row[9] = r.Timestamp.UTC().Format(time.RFC3339Nano)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,152 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResults(t *testing.T) {
+	Clear()
+	defer Clear()
+	now = testclock(time.Date(2018, 3, 15, 12, 0, 0, 0, time.UTC))
+	defer func() { now = time.Now }()
+
+	c := NewCounter("ns", "count")
+	c.Inc(ctxWith("b1", "A"), 2)
+	c.Inc(ctxWith("b2", "A"), 3)
+	c.Inc(ctxWith("b1", "B"), 1)
+
+	d := NewDistribution("ns", "dist")
+	d.Update(ctxWith("b1", "A"), 5)
+	d.Update(ctxWith("b2", "A"), 1)
+	d.Update(ctxWith("b2", "A"), 9)
+
+	g := NewGauge("ns", "gauge")
+	g.Set(ctxWith("b1", "A"), 7)
+
+	want := []Result{
+		{PTransform: "A", Namespace: "ns", Name: "count", Kind: CounterKind, Value: 5},
+		{PTransform: "A", Namespace: "ns", Name: "dist", Kind: DistributionKind, Count: 3, Sum: 15, Min: 1, Max: 9},
+		{PTransform: "A", Namespace: "ns", Name: "gauge", Kind: GaugeKind, Value: 7, Timestamp: now()},
+		{PTransform: "B", Namespace: "ns", Name: "count", Kind: CounterKind, Value: 1},
+	}
+	results := Results()
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("Results() = %+v, want %+v", results, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, results); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	csv := strings.Join([]string{
+		"ptransform,namespace,name,kind,value,count,sum,min,max,timestamp",
+		"A,ns,count,counter,5,,,,,",
+		"A,ns,dist,distribution,,3,15,1,9,",
+		"A,ns,gauge,gauge,7,,,,,2018-03-15T12:00:00Z",
+		"B,ns,count,counter,1,,,,,",
+	}, "\n") + "\n"
+	if got := buf.String(); got != csv {
+		t.Errorf("WriteCSV = %q, want %q", got, csv)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, results[1:3]); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, `"sum": 15`) || strings.Count(got, `"timestamp"`) != 1 {
+		t.Errorf("WriteJSON = %v, want sum and a single gauge timestamp", got)
+	}
+}
+
+func TestBundleResultsQuery(t *testing.T) {
+	Clear()
+	defer Clear()
+
+	c := NewCounter("ns", "count")
+	c.Inc(ctxWith("b1", "A"), 2)
+	c.Inc(ctxWith("b2", "A"), 3)
+	c.Inc(ctxWith("b1", "B"), 1)
+
+	d := NewDistribution("ns", "dist")
+	d.Update(ctxWith("b1", "A"), 5)
+
+	set := NewResultSet(BundleResults("b1"))
+	if got := len(set.All()); got != 3 {
+		t.Fatalf("len(BundleResults(b1)) = %v, want 3", got)
+	}
+
+	tests := []struct {
+		f                   Filter
+		counters, dists, gs int
+	}{
+		{Filter{}, 2, 1, 0},
+		{Filter{Step: "A"}, 1, 1, 0},
+		{Filter{Name: "count"}, 2, 0, 0},
+		{Filter{Step: "B", Name: "dist"}, 0, 0, 0},
+		{Filter{Namespace: "other"}, 0, 0, 0},
+	}
+	for _, test := range tests {
+		q := set.Query(test.f)
+		if len(q.Counters) != test.counters || len(q.Distributions) != test.dists || len(q.Gauges) != test.gs {
+			t.Errorf("Query(%+v) = %+v, want %v counters, %v distributions, %v gauges", test.f, q, test.counters, test.dists, test.gs)
+		}
+	}
+	if q := set.Query(Filter{Step: "A", Name: "count"}); len(q.Counters) != 1 || q.Counters[0].Value != 2 {
+		t.Errorf("Query(A, count) = %+v, want a counter of 2 in bundle b1", q.Counters)
+	}
+}
+
+func TestMergeBundleData(t *testing.T) {
+	Clear()
+	defer Clear()
+
+	c := NewCounter("ns", "count")
+	c.Inc(ctxWith("b1", "A"), 2)
+	c.Inc(ctxWith("attempt", "A"), 3)
+
+	d := NewDistribution("ns", "dist")
+	d.Update(ctxWith("b1", "A"), 5)
+	d.Update(ctxWith("attempt", "A"), 1)
+	d.Update(ctxWith("attempt", "B"), 9)
+
+	MergeBundleData("attempt", "b1")
+
+	want := []Result{
+		{PTransform: "A", Namespace: "ns", Name: "count", Kind: CounterKind, Value: 5},
+		{PTransform: "A", Namespace: "ns", Name: "dist", Kind: DistributionKind, Count: 2, Sum: 6, Min: 1, Max: 5},
+		{PTransform: "B", Namespace: "ns", Name: "dist", Kind: DistributionKind, Count: 1, Sum: 9, Min: 9, Max: 9},
+	}
+	if got := BundleResults("b1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("BundleResults(b1) = %+v, want %+v", got, want)
+	}
+	if got := BundleResults("attempt"); len(got) != 0 {
+		t.Errorf("BundleResults(attempt) = %+v, want none", got)
+	}
+
+	// The merged cells are updated as usual.
+
+	c.Inc(ctxWith("b1", "A"), 1)
+	if got := BundleResults("b1")[0].Value; got != 6 {
+		t.Errorf("count = %v, want 6", got)
+	}
+}
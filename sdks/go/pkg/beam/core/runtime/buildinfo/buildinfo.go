@@ -0,0 +1,149 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buildinfo captures information about the pipeline binary, such as
+// the SDK and Go versions, VCS revision and build settings, for recording
+// with submitted pipelines. Environment variables are captured only if
+// requested with CaptureEnv, as they may hold secrets.
+package buildinfo
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+)
+
+// SDKVersion is the version of the Beam Go SDK.
+const SDKVersion = "0.3.0"
+
+// settings are the build settings recorded, if present.
+var settings = map[string]bool{
+	"-compiler":   true,
+	"-gcflags":    true,
+	"-ldflags":    true,
+	"-race":       true,
+	"-tags":       true,
+	"-trimpath":   true,
+	"CGO_ENABLED": true,
+	"GOARCH":      true,
+	"GOOS":        true,
+}
+
+var (
+	env   = make(map[string]bool)
+	envMu sync.Mutex
+)
+
+// CaptureEnv requests that the given environment variables be recorded with
+// the build information, if set.
+func CaptureEnv(names ...string) {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	for _, name := range names {
+		env[name] = true
+	}
+}
+
+// Info is information about the pipeline binary.
+type Info struct {
+	// SDKVersion is the Beam Go SDK version.
+	SDKVersion string
+	// GoVersion is the version of the Go toolchain that built the binary.
+	GoVersion string
+	// Path is the main package path, if known.
+	Path string
+	// Revision is the VCS revision, if known.
+	Revision string
+	// Time is the VCS commit time, if known.
+	Time string
+	// Modified is true iff the working tree had local modifications.
+	Modified bool
+	// Settings holds selected build settings, such as "-tags" and "GOOS".
+	Settings map[string]string
+	// Env holds the captured environment variables that are set.
+	Env map[string]string
+}
+
+// Get returns the information about the current binary.
+func Get() Info {
+	ret := Info{
+		SDKVersion: SDKVersion,
+		GoVersion:  runtime.Version(),
+		Settings:   map[string]string{"GOOS": runtime.GOOS, "GOARCH": runtime.GOARCH},
+		Env:        make(map[string]string),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		ret.Path = bi.Path
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				ret.Revision = s.Value
+			case "vcs.time":
+				ret.Time = s.Value
+			case "vcs.modified":
+				ret.Modified = s.Value == "true"
+			default:
+				if settings[s.Key] {
+					ret.Settings[s.Key] = s.Value
+				}
+			}
+		}
+	}
+
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	for name := range env {
+		if v, ok := os.LookupEnv(name); ok {
+			ret.Env[name] = v
+		}
+	}
+	return ret
+}
+
+// DisplayData returns the information as display data ordered by key,
+// omitting unknown values.
+func (i Info) DisplayData() []graph.DisplayItem {
+	ret := []graph.DisplayItem{
+		{Key: "sdkVersion", Label: "SDK Version", Value: i.SDKVersion},
+		{Key: "goVersion", Label: "Go Version", Value: i.GoVersion},
+	}
+	if i.Path != "" {
+		ret = append(ret, graph.DisplayItem{Key: "mainPath", Label: "Main Package", Value: i.Path})
+	}
+	if i.Revision != "" {
+		ret = append(ret, graph.DisplayItem{Key: "vcsRevision", Label: "VCS Revision", Value: i.Revision})
+		ret = append(ret, graph.DisplayItem{Key: "vcsModified", Label: "VCS Modified", Value: i.Modified})
+	}
+	if i.Time != "" {
+		ret = append(ret, graph.DisplayItem{Key: "vcsTime", Label: "VCS Time", Value: i.Time})
+	}
+	for k, v := range i.Settings {
+		ret = append(ret, graph.DisplayItem{Key: fmt.Sprintf("build:%v", k), Value: v})
+	}
+	for k, v := range i.Env {
+		ret = append(ret, graph.DisplayItem{Key: fmt.Sprintf("env:%v", k), Value: v})
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].Key < ret[b].Key
+	})
+	return ret
+}
@@ -0,0 +1,48 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buildinfo
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	os.Setenv("BUILDINFO_TEST_VAR", "foo")
+	CaptureEnv("BUILDINFO_TEST_VAR", "BUILDINFO_TEST_UNSET")
+
+	info := Get()
+	if info.SDKVersion != SDKVersion || info.GoVersion != runtime.Version() {
+		t.Errorf("Get() = %+v, want SDK version %v and Go version %v", info, SDKVersion, runtime.Version())
+	}
+	if info.Settings["GOOS"] != runtime.GOOS {
+		t.Errorf("Get().Settings[GOOS] = %v, want %v", info.Settings["GOOS"], runtime.GOOS)
+	}
+	if len(info.Env) != 1 || info.Env["BUILDINFO_TEST_VAR"] != "foo" {
+		t.Errorf("Get().Env = %v, want only BUILDINFO_TEST_VAR=foo", info.Env)
+	}
+
+	keys := make(map[string]bool)
+	for _, item := range info.DisplayData() {
+		keys[item.Key] = true
+	}
+	for _, key := range []string{"sdkVersion", "goVersion", "build:GOOS", "env:BUILDINFO_TEST_VAR"} {
+		if !keys[key] {
+			t.Errorf("DisplayData() missing %v: %v", key, info.DisplayData())
+		}
+	}
+}
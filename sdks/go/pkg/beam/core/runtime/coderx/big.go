@@ -0,0 +1,104 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func init() {
+	runtime.RegisterFunction(encBig)
+	runtime.RegisterFunction(decBig)
+}
+
+var (
+	bigIntType   = reflect.TypeOf((*big.Int)(nil)).Elem()
+	bigRatType   = reflect.TypeOf((*big.Rat)(nil)).Elem()
+	bigFloatType = reflect.TypeOf((*big.Float)(nil)).Elem()
+)
+
+// IsBig returns true iff the given type is big.Int, big.Rat or big.Float, or
+// a pointer to one of them.
+func IsBig(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case bigIntType, bigRatType, bigFloatType:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewBig returns a coder for the given math/big type, which may be a pointer.
+// It uses the gob encoding of the value, which is exact and deterministic, so
+// big values can be used as GBK keys. Pointers are prefixed by a presence
+// byte to handle nil.
+func NewBig(t reflect.Type) (*coder.CustomCoder, error) {
+	if !IsBig(t) {
+		return nil, fmt.Errorf("not a big.Int, big.Rat or big.Float type: %v", t)
+	}
+	return coder.NewCustomCoder("big", t, encBig, decBig)
+}
+
+func encBig(t reflect.Type, v typex.T) ([]byte, error) {
+	val := reflect.ValueOf(v)
+	if t.Kind() != reflect.Ptr {
+		// The gob methods have pointer receivers.
+		ptr := reflect.New(t)
+		ptr.Elem().Set(val)
+		return ptr.Interface().(gob.GobEncoder).GobEncode()
+	}
+
+	if val.IsNil() {
+		return []byte{0}, nil
+	}
+	data, err := val.Interface().(gob.GobEncoder).GobEncode()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{1}, data...), nil
+}
+
+func decBig(t reflect.Type, data []byte) (typex.T, error) {
+	if t.Kind() != reflect.Ptr {
+		ptr := reflect.New(t)
+		if err := ptr.Interface().(gob.GobDecoder).GobDecode(data); err != nil {
+			return nil, err
+		}
+		return ptr.Elem().Interface(), nil
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid big encoding: empty")
+	}
+	if data[0] == 0 {
+		return reflect.Zero(t).Interface(), nil
+	}
+	ptr := reflect.New(t.Elem())
+	if err := ptr.Interface().(gob.GobDecoder).GobDecode(data[1:]); err != nil {
+		return nil, err
+	}
+	return ptr.Interface(), nil
+}
@@ -0,0 +1,84 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestBig(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+
+	tests := []interface{}{
+		big.NewInt(0),
+		huge,
+		*huge,
+		(*big.Int)(nil),
+		big.NewRat(-1, 3),
+		*big.NewRat(22, 7),
+		big.NewFloat(1.25),
+		new(big.Float).SetPrec(200).Quo(big.NewFloat(1), big.NewFloat(3)),
+		(*big.Rat)(nil),
+	}
+
+	for _, v := range tests {
+		typ := reflect.TypeOf(v)
+
+		if _, err := NewBig(typ); err != nil {
+			t.Fatalf("NewBig(%v) failed: %v", typ, err)
+		}
+
+		data, err := encBig(typ, v)
+		if err != nil {
+			t.Fatalf("enc(%v) failed: %v", v, err)
+		}
+		result, err := decBig(typ, data)
+		if err != nil {
+			t.Fatalf("dec(enc(%v)) failed: %v", v, err)
+		}
+
+		if reflect.TypeOf(result) != typ {
+			t.Errorf("type(dec(enc(%v))) = %v, want %v", v, reflect.TypeOf(result), typ)
+		}
+		if got, want := format(result), format(v); got != want {
+			t.Errorf("dec(enc(%v)) = %v, want %v", v, got, want)
+		}
+	}
+
+	if _, err := NewBig(reflect.TypeOf(0)); err == nil {
+		t.Errorf("NewBig(int) succeeded, want error")
+	}
+}
+
+// format prints the exact value of a big number.
+func format(v interface{}) string {
+	switch n := v.(type) {
+	case big.Int:
+		return n.String()
+	case big.Rat:
+		return n.String()
+	case *big.Float:
+		if n == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("%v/%v", n.Text('p', 0), n.Prec())
+	default:
+		return fmt.Sprint(v)
+	}
+}
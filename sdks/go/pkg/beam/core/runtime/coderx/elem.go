@@ -0,0 +1,139 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+// encElem encodes a nested value, such as the target of a pointer or a map
+// entry, with the coder that would be inferred for its type: varintz for
+// integers, float for floats, raw bytes for strings and []byte, time for
+// time.Time, big for math/big types, the map coder for maps and JSON
+// otherwise. It is used by coders that cannot be composed from component
+// coders, because custom coders are serialized by type only.
+func encElem(t reflect.Type, val reflect.Value) ([]byte, error) {
+	switch t {
+	case reflectx.Int, reflectx.Int8, reflectx.Int16, reflectx.Int32, reflectx.Int64:
+		return encVarIntZ(val.Interface()), nil
+	case reflectx.Uint, reflectx.Uint8, reflectx.Uint16, reflectx.Uint32, reflectx.Uint64:
+		return encVarUintZ(val.Interface()), nil
+	case reflectx.Float32, reflectx.Float64:
+		return encFloat(val.Interface()), nil
+	case reflectx.String:
+		return []byte(val.String()), nil
+	case reflectx.ByteSlice:
+		return val.Bytes(), nil
+	case reflectx.Time:
+		return encTime(val.Interface()), nil
+	}
+	if IsBig(t) {
+		return encBig(t, val.Interface())
+	}
+	if typex.IsMap(t) {
+		return encMap(t, val.Interface())
+	}
+	return json.Marshal(val.Interface())
+}
+
+// decElem decodes a nested value encoded by encElem.
+func decElem(t reflect.Type, data []byte) (reflect.Value, error) {
+	var ret typex.T
+	var err error
+
+	switch t {
+	case reflectx.Int, reflectx.Int8, reflectx.Int16, reflectx.Int32, reflectx.Int64:
+		ret, err = decVarIntZ(t, data)
+	case reflectx.Uint, reflectx.Uint8, reflectx.Uint16, reflectx.Uint32, reflectx.Uint64:
+		ret, err = decVarUintZ(t, data)
+	case reflectx.Float32, reflectx.Float64:
+		ret, err = decFloat(t, data)
+	case reflectx.String:
+		ret = string(data)
+	case reflectx.ByteSlice:
+		ret = append([]byte(nil), data...)
+	case reflectx.Time:
+		ret, err = decTime(data)
+	default:
+		if IsBig(t) {
+			ret, err = decBig(t, data)
+			break
+		}
+		if typex.IsMap(t) {
+			ret, err = decMap(t, data)
+			break
+		}
+		val := reflect.New(t)
+		if err := json.Unmarshal(data, val.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		return val.Elem(), nil
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(ret), nil
+}
+
+// IsDeterministic returns true iff values of the given type have an encoding
+// under the inferred coders that is suitable for comparing values: equal
+// values are always encoded to identical bytes and distinct values to
+// distinct bytes. The JSON fallback is not, if the value contains interfaces,
+// whose dynamic types are lost, or struct fields that are not encoded, such
+// as unexported fields, which would make distinct values compare equal.
+func IsDeterministic(t reflect.Type) bool {
+	return isDeterministic(t, make(map[reflect.Type]bool))
+}
+
+func isDeterministic(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return true // recursive type: decided by the first occurrence
+	}
+	seen[t] = true
+
+	if t == reflectx.Time || IsBig(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return false
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return isDeterministic(t.Elem(), seen)
+	case reflect.Map:
+		return isDeterministic(t.Key(), seen) && isDeterministic(t.Elem(), seen)
+	case reflect.Struct:
+		if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+			return true // custom encoding: assumed to be faithful
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+				return false // not encoded
+			}
+			if !isDeterministic(f.Type, seen) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
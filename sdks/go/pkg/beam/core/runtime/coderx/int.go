@@ -17,9 +17,12 @@ package coderx
 
 import (
 	"encoding/binary"
+	"fmt"
+	"reflect"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 )
 
@@ -59,6 +62,68 @@ func init() {
 	runtime.RegisterFunction(decUint64)
 	runtime.RegisterFunction(encInt64)
 	runtime.RegisterFunction(decInt64)
+	runtime.RegisterFunction(encFixedInt)
+	runtime.RegisterFunction(decFixedInt)
+}
+
+// NewFixedInt returns a fixed-width big-endian coder for the given integer
+// type. The width is the size of the type, except int and uint always use
+// 8 bytes to be independent of the platform.
+func NewFixedInt(t reflect.Type) (*coder.CustomCoder, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return coder.NewCustomCoder("fixedint", t, encFixedInt, decFixedInt)
+	default:
+		return nil, fmt.Errorf("not an integer type: %v", t)
+	}
+}
+
+func fixedIntWidth(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint:
+		return 8
+	default:
+		return int(t.Size())
+	}
+}
+
+func encFixedInt(t reflect.Type, v typex.T) []byte {
+	val := reflect.ValueOf(v)
+
+	var n uint64
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = uint64(val.Int())
+	default:
+		n = val.Uint()
+	}
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	return buf[8-fixedIntWidth(t):]
+}
+
+func decFixedInt(t reflect.Type, data []byte) (typex.T, error) {
+	width := fixedIntWidth(t)
+	if len(data) != width {
+		return nil, fmt.Errorf("invalid fixed-width encoding of %v: %v, want %v bytes", t, data, width)
+	}
+
+	var buf [8]byte
+	copy(buf[8-width:], data)
+	n := binary.BigEndian.Uint64(buf[:])
+
+	ret := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Sign-extend from the encoded width.
+		shift := uint(64 - 8*width)
+		ret.SetInt(int64(n<<shift) >> shift)
+	default:
+		ret.SetUint(n)
+	}
+	return ret.Interface(), nil
 }
 
 func encUint32(v uint32) []byte {
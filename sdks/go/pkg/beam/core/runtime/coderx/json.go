@@ -0,0 +1,559 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func init() {
+	for _, fn := range jsonEncoders {
+		runtime.RegisterFunction(fn)
+	}
+	for _, fn := range jsonDecoders {
+		runtime.RegisterFunction(fn)
+	}
+}
+
+// JSONNaming is the naming strategy for struct fields in a JSON coder. It
+// applies to exported fields without an explicit name in their json tag.
+type JSONNaming int
+
+const (
+	// JSONFieldName uses the Go field name as is, like encoding/json.
+	JSONFieldName JSONNaming = iota
+	// JSONSnakeCase uses lower-case words separated by underscores: UserID -> user_id.
+	JSONSnakeCase
+	// JSONCamelCase uses a lower-case initial word: UserID -> userID.
+	JSONCamelCase
+	// JSONLowerCase uses the lower-cased field name: UserID -> userid.
+	JSONLowerCase
+)
+
+// JSONOptions configure the encoding of a JSON coder.
+type JSONOptions struct {
+	// Naming is the field naming strategy.
+	Naming JSONNaming
+	// OmitEmpty omits empty field values, as if every field was tagged
+	// with "omitempty".
+	OmitEmpty bool
+	// DisallowUnknownFields makes decoding fail if the input contains object
+	// keys that do not match any field, instead of ignoring them.
+	DisallowUnknownFields bool
+}
+
+// NewJSON returns a JSON coder for the given type with the given options.
+// Unlike the default JSON coding, struct fields are matched exactly when
+// decoding. Map keys are sorted, so the encoding is deterministic for types
+// that do not implement json.Marshaler.
+//
+// The options are part of the coder, so coders of the same type may use
+// different options for different PCollections. The type must be registered
+// with the runtime to be decoded as-is on remote workers.
+func NewJSON(t reflect.Type, opts JSONOptions) (*coder.CustomCoder, error) {
+	if !typex.IsConcrete(t) {
+		return nil, fmt.Errorf("not a concrete type: %v", t)
+	}
+	enc, ok := jsonEncoders[jsonEncKey{opts.Naming, opts.OmitEmpty}]
+	if !ok {
+		return nil, fmt.Errorf("invalid JSON field naming: %v", opts.Naming)
+	}
+	dec := jsonDecoders[jsonDecKey{opts.Naming, opts.DisallowUnknownFields}]
+	return coder.NewCustomCoder("jsonx", t, enc, dec)
+}
+
+// A custom coder is serialized with the symbols of its functions, so the
+// options of a JSON coder are carried by the identity of its functions. Every
+// combination of options that applies to encoding and decoding has its own
+// function.
+
+type jsonEncKey struct {
+	naming    JSONNaming
+	omitEmpty bool
+}
+
+type jsonDecKey struct {
+	naming          JSONNaming
+	disallowUnknown bool
+}
+
+var jsonEncoders = map[jsonEncKey]func(reflect.Type, typex.T) ([]byte, error){
+	{JSONFieldName, false}: encJSON,
+	{JSONFieldName, true}:  encJSONOmitEmpty,
+	{JSONSnakeCase, false}: encJSONSnakeCase,
+	{JSONSnakeCase, true}:  encJSONSnakeCaseOmitEmpty,
+	{JSONCamelCase, false}: encJSONCamelCase,
+	{JSONCamelCase, true}:  encJSONCamelCaseOmitEmpty,
+	{JSONLowerCase, false}: encJSONLowerCase,
+	{JSONLowerCase, true}:  encJSONLowerCaseOmitEmpty,
+}
+
+var jsonDecoders = map[jsonDecKey]func(reflect.Type, []byte) (typex.T, error){
+	{JSONFieldName, false}: decJSON,
+	{JSONFieldName, true}:  decJSONStrict,
+	{JSONSnakeCase, false}: decJSONSnakeCase,
+	{JSONSnakeCase, true}:  decJSONSnakeCaseStrict,
+	{JSONCamelCase, false}: decJSONCamelCase,
+	{JSONCamelCase, true}:  decJSONCamelCaseStrict,
+	{JSONLowerCase, false}: decJSONLowerCase,
+	{JSONLowerCase, true}:  decJSONLowerCaseStrict,
+}
+
+func encJSON(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONFieldName})
+}
+
+func encJSONOmitEmpty(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONFieldName, OmitEmpty: true})
+}
+
+func encJSONSnakeCase(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONSnakeCase})
+}
+
+func encJSONSnakeCaseOmitEmpty(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONSnakeCase, OmitEmpty: true})
+}
+
+func encJSONCamelCase(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONCamelCase})
+}
+
+func encJSONCamelCaseOmitEmpty(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONCamelCase, OmitEmpty: true})
+}
+
+func encJSONLowerCase(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONLowerCase})
+}
+
+func encJSONLowerCaseOmitEmpty(t reflect.Type, v typex.T) ([]byte, error) {
+	return encodeJSON(v, JSONOptions{Naming: JSONLowerCase, OmitEmpty: true})
+}
+
+func decJSON(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONFieldName})
+}
+
+func decJSONStrict(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONFieldName, DisallowUnknownFields: true})
+}
+
+func decJSONSnakeCase(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONSnakeCase})
+}
+
+func decJSONSnakeCaseStrict(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONSnakeCase, DisallowUnknownFields: true})
+}
+
+func decJSONCamelCase(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONCamelCase})
+}
+
+func decJSONCamelCaseStrict(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONCamelCase, DisallowUnknownFields: true})
+}
+
+func decJSONLowerCase(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONLowerCase})
+}
+
+func decJSONLowerCaseStrict(t reflect.Type, data []byte) (typex.T, error) {
+	return decodeJSON(t, data, JSONOptions{Naming: JSONLowerCase, DisallowUnknownFields: true})
+}
+
+func encodeJSON(v typex.T, opts JSONOptions) ([]byte, error) {
+	return json.Marshal(toJSONValue(reflect.ValueOf(v), opts))
+}
+
+func decodeJSON(t reflect.Type, data []byte, opts JSONOptions) (typex.T, error) {
+	ret := reflect.New(t)
+	if err := fromJSONValue(ret.Elem(), data, opts); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %v", t, err)
+	}
+	return ret.Elem().Interface(), nil
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	jsonRawMessageType  = reflect.TypeOf(json.RawMessage{})
+	emptyInterfaceType  = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// toJSONValue converts the given value into a tree of values that encode with
+// the field names and omissions required by the options.
+func toJSONValue(v reflect.Value, opts JSONOptions) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toJSONValue(v.Elem(), opts)
+
+	case reflect.Struct:
+		ret := make(map[string]interface{})
+		for _, f := range jsonFields(v.Type(), opts) {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || f.omitEmpty && isEmptyJSONValue(fv) {
+				continue
+			}
+			ret[f.name] = toJSONValue(fv, opts)
+		}
+		return ret
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface() // base64, like encoding/json
+		}
+		fallthrough
+
+	case reflect.Array:
+		ret := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			ret[i] = toJSONValue(v.Index(i), opts)
+		}
+		return ret
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		// Retain the key type so that encoding/json handles key conversion.
+		ret := reflect.MakeMap(reflect.MapOf(v.Type().Key(), emptyInterfaceType))
+		for _, key := range v.MapKeys() {
+			elm := reflect.Zero(emptyInterfaceType)
+			if val := toJSONValue(v.MapIndex(key), opts); val != nil {
+				elm = reflect.ValueOf(val)
+			}
+			ret.SetMapIndex(key, elm)
+		}
+		return ret.Interface()
+
+	default:
+		return v.Interface()
+	}
+}
+
+// fromJSONValue decodes the data into the given addressable value, using the
+// field names required by the options.
+func fromJSONValue(v reflect.Value, data []byte, opts JSONOptions) error {
+	if reflect.PtrTo(v.Type()).Implements(jsonUnmarshalerType) {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+
+	isNull := bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if isNull {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return fromJSONValue(v.Elem(), data, opts)
+
+	case reflect.Struct:
+		if isNull {
+			return nil
+		}
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, f := range jsonFields(v.Type(), opts) {
+			raw, ok := m[f.name]
+			if !ok {
+				continue
+			}
+			seen[f.name] = true
+			fv, err := allocFieldByIndex(v, f.index)
+			if err != nil {
+				return fmt.Errorf("field %v: %v", f.name, err)
+			}
+			if err := fromJSONValue(fv, raw, opts); err != nil {
+				return fmt.Errorf("field %v: %v", f.name, err)
+			}
+		}
+		if opts.DisallowUnknownFields {
+			for key := range m {
+				if !seen[key] {
+					return fmt.Errorf("unknown field %q in %v", key, v.Type())
+				}
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		if isNull {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return json.Unmarshal(data, v.Addr().Interface())
+		}
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		ret := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, raw := range list {
+			if err := fromJSONValue(ret.Index(i), raw, opts); err != nil {
+				return err
+			}
+		}
+		v.Set(ret)
+		return nil
+
+	case reflect.Array:
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len() && i < len(list); i++ {
+			if err := fromJSONValue(v.Index(i), list[i], opts); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if isNull {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		// Let encoding/json handle key conversion.
+		m := reflect.New(reflect.MapOf(v.Type().Key(), jsonRawMessageType))
+		if err := json.Unmarshal(data, m.Interface()); err != nil {
+			return err
+		}
+		ret := reflect.MakeMap(v.Type())
+		for _, key := range m.Elem().MapKeys() {
+			elm := reflect.New(v.Type().Elem()).Elem()
+			if err := fromJSONValue(elm, m.Elem().MapIndex(key).Bytes(), opts); err != nil {
+				return err
+			}
+			ret.SetMapIndex(key, elm)
+		}
+		v.Set(ret)
+		return nil
+
+	default:
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+}
+
+// jsonField is a struct field, possibly promoted from an embedded struct, and
+// its JSON name.
+type jsonField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// jsonFields returns the encoded fields of the given struct type. Fields of
+// embedded structs without a json name are promoted, unless shadowed by a
+// shallower field of the same name.
+func jsonFields(t reflect.Type, opts JSONOptions) []jsonField {
+	var ret []jsonField
+	var embedded []jsonField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, flags := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, flags = tag[:idx], tag[idx+1:]
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for _, sub := range jsonFields(ft, opts) {
+				sub.index = append([]int{i}, sub.index...)
+				embedded = append(embedded, sub)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+
+		if name == "" {
+			name = opts.Naming.apply(f.Name)
+		}
+		ret = append(ret, jsonField{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: opts.OmitEmpty || hasJSONFlag(flags, "omitempty"),
+		})
+	}
+
+	names := make(map[string]bool)
+	for _, f := range ret {
+		names[f.name] = true
+	}
+	for _, f := range embedded {
+		if !names[f.name] {
+			names[f.name] = true
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// fieldByIndex returns the nested field, or false if it is within a nil
+// embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+// allocFieldByIndex returns the nested field, allocating nil embedded pointers.
+func allocFieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, idx := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					if !v.CanSet() {
+						return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct: %v", v.Type())
+					}
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(idx)
+	}
+	return v, nil
+}
+
+func hasJSONFlag(flags, flag string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyJSONValue matches the "omitempty" semantics of encoding/json.
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+func (n JSONNaming) apply(name string) string {
+	switch n {
+	case JSONSnakeCase:
+		words := splitWords(name)
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, "_")
+	case JSONCamelCase:
+		words := splitWords(name)
+		if len(words) > 0 {
+			words[0] = strings.ToLower(words[0])
+		}
+		return strings.Join(words, "")
+	case JSONLowerCase:
+		return strings.ToLower(name)
+	default:
+		return name
+	}
+}
+
+// splitWords splits a Go identifier into words at case transitions. Runs of
+// upper-case letters are treated as acronyms: HTTPServer -> HTTP, Server.
+func splitWords(name string) []string {
+	runes := []rune(name)
+
+	var ret []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		switch {
+		case cur == '_':
+			if start < i {
+				ret = append(ret, string(runes[start:i]))
+			}
+			start = i + 1
+		case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			ret = append(ret, string(runes[start:i]))
+			start = i
+		case unicode.IsLower(cur) && unicode.IsUpper(prev) && start < i-1:
+			ret = append(ret, string(runes[start:i-1]))
+			start = i - 1
+		}
+	}
+	if start < len(runes) {
+		ret = append(ret, string(runes[start:]))
+	}
+	return ret
+}
@@ -0,0 +1,187 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+type jsonInner struct {
+	ZipCode string
+}
+
+type jsonTestRecord struct {
+	UserID    int
+	HTTPHost  string `json:",omitempty"`
+	Tagged    string `json:"tag"`
+	Skipped   string `json:"-"`
+	Tags      map[string]int
+	Addresses []jsonInner
+	jsonInner
+	private int
+}
+
+func TestJSONNaming(t *testing.T) {
+	tests := []struct {
+		naming JSONNaming
+		in     string
+		out    string
+	}{
+		{JSONFieldName, "UserID", "UserID"},
+		{JSONSnakeCase, "UserID", "user_id"},
+		{JSONSnakeCase, "HTTPServer", "http_server"},
+		{JSONSnakeCase, "Field2Name", "field2_name"},
+		{JSONCamelCase, "UserID", "userID"},
+		{JSONCamelCase, "HTTPServer", "httpServer"},
+		{JSONLowerCase, "HTTPServer", "httpserver"},
+	}
+
+	for _, test := range tests {
+		if got := test.naming.apply(test.in); got != test.out {
+			t.Errorf("naming(%v).apply(%v) = %v, want %v", test.naming, test.in, got, test.out)
+		}
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	rec := jsonTestRecord{
+		UserID:    42,
+		Tagged:    "t",
+		Skipped:   "s",
+		Tags:      map[string]int{"b": 2, "a": 1},
+		Addresses: []jsonInner{{ZipCode: "12345"}},
+		jsonInner: jsonInner{ZipCode: "54321"},
+		private:   3,
+	}
+
+	tests := []struct {
+		opts JSONOptions
+		out  string
+	}{
+		{
+			JSONOptions{},
+			`{"Addresses":[{"ZipCode":"12345"}],"Tags":{"a":1,"b":2},"UserID":42,"ZipCode":"54321","tag":"t"}`,
+		},
+		{
+			JSONOptions{Naming: JSONSnakeCase},
+			`{"addresses":[{"zip_code":"12345"}],"tag":"t","tags":{"a":1,"b":2},"user_id":42,"zip_code":"54321"}`,
+		},
+	}
+
+	for _, test := range tests {
+		c := newTestJSON(t, test.opts)
+
+		data, err := c.enc(rec)
+		if err != nil {
+			t.Fatalf("enc(%v) failed: %v", rec, err)
+		}
+		if string(data) != test.out {
+			t.Errorf("enc(%v) = %v, want %v", rec, string(data), test.out)
+		}
+
+		decoded, err := c.dec(data)
+		if err != nil {
+			t.Fatalf("dec(%v) failed: %v", string(data), err)
+		}
+		want := rec
+		want.Skipped = ""
+		want.private = 0
+		if !reflect.DeepEqual(decoded, want) {
+			t.Errorf("dec(enc(%v)) = %v, want %v", rec, decoded, want)
+		}
+	}
+}
+
+func TestJSONOmitEmpty(t *testing.T) {
+	c := newTestJSON(t, JSONOptions{OmitEmpty: true})
+
+	data, err := c.enc(jsonTestRecord{UserID: 1})
+	if err != nil {
+		t.Fatalf("enc failed: %v", err)
+	}
+	if want := `{"UserID":1}`; string(data) != want {
+		t.Errorf("enc = %v, want %v", string(data), want)
+	}
+}
+
+func TestJSONUnknownFields(t *testing.T) {
+	data := []byte(`{"UserID":1,"Unknown":true}`)
+
+	if _, err := newTestJSON(t, JSONOptions{}).dec(data); err != nil {
+		t.Errorf("dec(%v) failed: %v, want unknown field ignored", string(data), err)
+	}
+
+	if _, err := newTestJSON(t, JSONOptions{DisallowUnknownFields: true}).dec(data); err == nil {
+		t.Errorf("dec(%v) succeeded, want unknown field error", string(data))
+	}
+}
+
+// TestJSONPerCoder checks that coders of the same type keep their own options.
+func TestJSONPerCoder(t *testing.T) {
+	plain := newTestJSON(t, JSONOptions{})
+	snake := newTestJSON(t, JSONOptions{Naming: JSONSnakeCase, OmitEmpty: true})
+
+	rec := jsonTestRecord{UserID: 1}
+	for _, test := range []struct {
+		c    testJSON
+		want string
+	}{
+		{snake, `{"user_id":1}`},
+		{plain, `{"Addresses":null,"Tags":null,"UserID":1,"ZipCode":"","tag":""}`},
+	} {
+		data, err := test.c.enc(rec)
+		if err != nil {
+			t.Fatalf("enc failed: %v", err)
+		}
+		if string(data) != test.want {
+			t.Errorf("enc = %v, want %v", string(data), test.want)
+		}
+	}
+
+	if _, err := NewJSON(jsonTestRecordType, JSONOptions{Naming: JSONNaming(42)}); err == nil {
+		t.Error("NewJSON with invalid naming succeeded, want error")
+	}
+}
+
+var jsonTestRecordType = reflect.TypeOf(jsonTestRecord{})
+
+// testJSON calls the functions of a JSON coder for jsonTestRecord.
+type testJSON struct {
+	enc func(v typex.T) ([]byte, error)
+	dec func(data []byte) (typex.T, error)
+}
+
+func newTestJSON(t *testing.T, opts JSONOptions) testJSON {
+	c, err := NewJSON(jsonTestRecordType, opts)
+	if err != nil {
+		t.Fatalf("NewJSON(%+v) failed: %v", opts, err)
+	}
+	return testJSON{
+		enc: func(v typex.T) ([]byte, error) {
+			ret := c.Enc.Fn.Call([]interface{}{jsonTestRecordType, v})
+			err, _ := ret[1].(error)
+			return ret[0].([]byte), err
+		},
+		dec: func(data []byte) (typex.T, error) {
+			ret := c.Dec.Fn.Call([]interface{}{jsonTestRecordType, data})
+			err, _ := ret[1].(error)
+			return ret[0], err
+		},
+	}
+}
@@ -0,0 +1,144 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func init() {
+	runtime.RegisterFunction(encMap)
+	runtime.RegisterFunction(decMap)
+}
+
+// NewMap returns a deterministic coder for the given map type. Entries are
+// written in the order of their encoded keys, so equal maps always have equal
+// encodings and can be used as GBK keys. Sets, i.e., maps with empty struct
+// values, encode the keys only.
+func NewMap(t reflect.Type) (*coder.CustomCoder, error) {
+	if !typex.IsMap(t) {
+		return nil, fmt.Errorf("not a map type: %v", t)
+	}
+	if !typex.IsConcrete(t) {
+		return nil, fmt.Errorf("map key and value types must be concrete: %v", t)
+	}
+	return coder.NewCustomCoder("map", t, encMap, decMap)
+}
+
+type mapEntry struct {
+	key, value []byte
+}
+
+func encMap(t reflect.Type, v typex.T) ([]byte, error) {
+	val := reflect.ValueOf(v)
+	set := typex.IsSet(t)
+
+	entries := make([]mapEntry, 0, val.Len())
+	for _, k := range val.MapKeys() {
+		key, err := encElem(t.Key(), k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode key %v: %v", k, err)
+		}
+		e := mapEntry{key: key}
+		if !set {
+			e.value, err = encElem(t.Elem(), val.MapIndex(k))
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode value for key %v: %v", k, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	var buf bytes.Buffer
+	writeUvarint(&buf, uint64(len(entries)))
+	for _, e := range entries {
+		writeUvarint(&buf, uint64(len(e.key)))
+		buf.Write(e.key)
+		if !set {
+			writeUvarint(&buf, uint64(len(e.value)))
+			buf.Write(e.value)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decMap(t reflect.Type, data []byte) (typex.T, error) {
+	set := typex.IsSet(t)
+
+	n, data, err := readUvarint(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid map encoding: %v", err)
+	}
+	ret := reflect.MakeMapWithSize(t, int(n))
+	for i := uint64(0); i < n; i++ {
+		var key, value []byte
+		if key, data, err = readBytes(data); err != nil {
+			return nil, fmt.Errorf("invalid map encoding: %v", err)
+		}
+		k, err := decElem(t.Key(), key)
+		if err != nil {
+			return nil, err
+		}
+
+		v := reflect.Zero(t.Elem())
+		if !set {
+			if value, data, err = readBytes(data); err != nil {
+				return nil, fmt.Errorf("invalid map encoding: %v", err)
+			}
+			if v, err = decElem(t.Elem(), value); err != nil {
+				return nil, err
+			}
+		}
+		ret.SetMapIndex(k, v)
+	}
+	return ret.Interface(), nil
+}
+
+func writeUvarint(buf *bytes.Buffer, n uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	size := binary.PutUvarint(tmp[:], n)
+	buf.Write(tmp[:size])
+}
+
+func readUvarint(data []byte) (uint64, []byte, error) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 {
+		return 0, nil, fmt.Errorf("bad length")
+	}
+	return n, data[size:], nil
+}
+
+func readBytes(data []byte) ([]byte, []byte, error) {
+	n, data, err := readUvarint(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if uint64(len(data)) < n {
+		return nil, nil, fmt.Errorf("truncated data: %v bytes, want %v", len(data), n)
+	}
+	return data[:n], data[n:], nil
+}
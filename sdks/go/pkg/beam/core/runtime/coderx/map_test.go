@@ -0,0 +1,78 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	tests := []interface{}{
+		map[string]int{},
+		map[string]int{"a": 1, "b": -2, "c": 3},
+		map[int]string{1: "a", 2: "b"},
+		map[jsonInner][]byte{{ZipCode: "1"}: []byte("x"), {ZipCode: "2"}: nil},
+		map[string]map[int]float64{"a": {1: 1.5}, "b": {}},
+		map[string]struct{}{"a": {}, "b": {}},
+	}
+
+	for _, v := range tests {
+		typ := reflect.TypeOf(v)
+
+		if _, err := NewMap(typ); err != nil {
+			t.Fatalf("NewMap(%v) failed: %v", typ, err)
+		}
+
+		data, err := encMap(typ, v)
+		if err != nil {
+			t.Fatalf("enc(%v) failed: %v", v, err)
+		}
+		result, err := decMap(typ, data)
+		if err != nil {
+			t.Fatalf("dec(enc(%v)) failed: %v", v, err)
+		}
+
+		if reflect.TypeOf(result) != typ {
+			t.Errorf("type(dec(enc(%v))) = %v, want %v", v, reflect.TypeOf(result), typ)
+		}
+		if !reflect.DeepEqual(result, v) {
+			t.Errorf("dec(enc(%v)) = %v, want id", v, result)
+		}
+	}
+}
+
+func TestMapDeterministic(t *testing.T) {
+	typ := reflect.TypeOf(map[string]int{})
+
+	var last []byte
+	for i := 0; i < 10; i++ {
+		m := make(map[string]int)
+		for j := 0; j < 20; j++ {
+			m[string(rune('a'+j))] = j
+		}
+
+		data, err := encMap(typ, m)
+		if err != nil {
+			t.Fatalf("enc(%v) failed: %v", m, err)
+		}
+		if last != nil && !bytes.Equal(data, last) {
+			t.Fatalf("enc(%v) = %v, want %v", m, data, last)
+		}
+		last = data
+	}
+}
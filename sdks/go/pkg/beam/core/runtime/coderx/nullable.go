@@ -0,0 +1,76 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func init() {
+	runtime.RegisterFunction(encNullable)
+	runtime.RegisterFunction(decNullable)
+}
+
+// NewNullable returns a coder for the given pointer type that handles nil. The
+// encoding is a presence byte followed by the encoding of the pointed-to value,
+// if present.
+func NewNullable(t reflect.Type) (*coder.CustomCoder, error) {
+	if t.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("not a pointer type: %v", t)
+	}
+	if !typex.IsConcrete(t.Elem()) {
+		return nil, fmt.Errorf("pointer element type must be concrete: %v", t)
+	}
+	return coder.NewCustomCoder("nullable", t, encNullable, decNullable)
+}
+
+func encNullable(t reflect.Type, v typex.T) ([]byte, error) {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() || val.IsNil() {
+		return []byte{0}, nil
+	}
+
+	data, err := encElem(t.Elem(), val.Elem())
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{1}, data...), nil
+}
+
+func decNullable(t reflect.Type, data []byte) (typex.T, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid nullable encoding: empty")
+	}
+	switch data[0] {
+	case 0:
+		return reflect.Zero(t).Interface(), nil
+	case 1:
+		elm, err := decElem(t.Elem(), data[1:])
+		if err != nil {
+			return nil, err
+		}
+		ret := reflect.New(t.Elem())
+		ret.Elem().Set(elm)
+		return ret.Interface(), nil
+	default:
+		return nil, fmt.Errorf("invalid nullable encoding: bad presence byte %v", data[0])
+	}
+}
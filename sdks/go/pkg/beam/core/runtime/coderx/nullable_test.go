@@ -0,0 +1,75 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNullable(t *testing.T) {
+	i := -42
+	u := uint16(7)
+	f := 3.5
+	s := "foo"
+	b := []byte("bar")
+	r := jsonInner{ZipCode: "12345"}
+
+	tests := []interface{}{
+		&i,
+		(*int)(nil),
+		&u,
+		&f,
+		&s,
+		(*string)(nil),
+		&b,
+		&r,
+		(*jsonInner)(nil),
+	}
+
+	for _, v := range tests {
+		typ := reflect.TypeOf(v)
+
+		if _, err := NewNullable(typ); err != nil {
+			t.Fatalf("NewNullable(%v) failed: %v", typ, err)
+		}
+
+		data, err := encNullable(typ, v)
+		if err != nil {
+			t.Fatalf("enc(%v) failed: %v", v, err)
+		}
+		result, err := decNullable(typ, data)
+		if err != nil {
+			t.Fatalf("dec(enc(%v)) failed: %v", v, err)
+		}
+
+		if reflect.TypeOf(result) != typ {
+			t.Errorf("type(dec(enc(%v))) = %v, want %v", v, reflect.TypeOf(result), typ)
+		}
+		if !reflect.DeepEqual(result, v) {
+			t.Errorf("dec(enc(%v)) = %v, want id", v, result)
+		}
+	}
+}
+
+func TestNullableInvalid(t *testing.T) {
+	if _, err := NewNullable(reflect.TypeOf(0)); err == nil {
+		t.Errorf("NewNullable(int) succeeded, want error for non-pointer type")
+	}
+	if _, err := decNullable(reflect.TypeOf((*int)(nil)), []byte{2}); err == nil {
+		t.Errorf("dec([2]) succeeded, want error for bad presence byte")
+	}
+}
@@ -0,0 +1,102 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func init() {
+	runtime.RegisterFunction(encTime)
+	runtime.RegisterFunction(decTime)
+}
+
+// NewTime returns a coder for time.Time. The encoding is the zig-zag varint
+// Unix seconds, the varint nanoseconds, the zig-zag varint zone offset in
+// seconds and the location name. It preserves the instant and time zone, but
+// not the monotonic clock reading. The encoding is deterministic, so times can
+// be used as GBK keys. Note that equal instants in different time zones are
+// distinct keys.
+func NewTime(t reflect.Type) (*coder.CustomCoder, error) {
+	if t != reflectx.Time {
+		return nil, fmt.Errorf("not a time.Time type: %v", t)
+	}
+	return coder.NewCustomCoder("time", t, encTime, decTime)
+}
+
+func encTime(v typex.T) []byte {
+	t := v.(time.Time)
+	_, offset := t.Zone()
+
+	var buf bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutVarint(tmp[:], t.Unix())])
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(t.Nanosecond()))])
+	buf.Write(tmp[:binary.PutVarint(tmp[:], int64(offset))])
+	buf.WriteString(t.Location().String())
+	return buf.Bytes()
+}
+
+func decTime(data []byte) (typex.T, error) {
+	sec, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid time encoding for: %v", data)
+	}
+	data = data[n:]
+	nsec, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid time encoding for: %v", data)
+	}
+	data = data[n:]
+	offset, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid time encoding for: %v", data)
+	}
+	name := string(data[n:])
+
+	t := time.Unix(sec, int64(nsec))
+	return t.In(decLocation(t, name, int(offset))), nil
+}
+
+// decLocation returns the named location, if available and consistent with
+// the offset, and a fixed zone otherwise. The local time zone of the encoder
+// is not meaningful to the decoder, so it always becomes a fixed zone.
+func decLocation(t time.Time, name string, offset int) *time.Location {
+	switch name {
+	case "UTC":
+		if offset == 0 {
+			return time.UTC
+		}
+	case "Local", "":
+		return time.FixedZone("", offset)
+	default:
+		if loc, err := time.LoadLocation(name); err == nil {
+			if _, off := t.In(loc).Zone(); off == offset {
+				return loc
+			}
+		}
+	}
+	return time.FixedZone(name, offset)
+}
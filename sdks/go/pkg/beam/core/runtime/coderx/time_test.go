@@ -0,0 +1,72 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coderx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	tests := []time.Time{
+		time.Unix(0, 0).UTC(),
+		time.Date(2018, 3, 15, 12, 30, 45, 123456789, time.UTC),
+		time.Date(1969, 7, 20, 20, 17, 40, 0, time.UTC),
+		time.Date(2018, 3, 15, 12, 30, 45, 0, time.FixedZone("EST5", -5*3600)),
+		time.Date(2018, 3, 15, 12, 30, 45, 0, time.FixedZone("", 5*3600+1800)),
+	}
+	if loc, err := time.LoadLocation("Europe/Berlin"); err == nil {
+		tests = append(tests, time.Date(2018, 7, 1, 8, 0, 0, 0, loc))
+	}
+
+	for _, v := range tests {
+		data := encTime(v)
+		result, err := decTime(data)
+		if err != nil {
+			t.Fatalf("dec(enc(%v)) failed: %v", v, err)
+		}
+
+		got := result.(time.Time)
+		if !got.Equal(v) {
+			t.Errorf("dec(enc(%v)) = %v, want same instant", v, got)
+		}
+		if got.Location().String() != v.Location().String() {
+			t.Errorf("dec(enc(%v)) location = %v, want %v", v, got.Location(), v.Location())
+		}
+		if got.String() != v.String() {
+			t.Errorf("dec(enc(%v)) = %v, want id", v, got)
+		}
+		if !bytes.Equal(encTime(got), data) {
+			t.Errorf("enc(dec(enc(%v))) = %v, want %v", v, encTime(got), data)
+		}
+	}
+}
+
+func TestTimeLocal(t *testing.T) {
+	v := time.Date(2018, 3, 15, 12, 30, 45, 0, time.Local)
+
+	result, err := decTime(encTime(v))
+	if err != nil {
+		t.Fatalf("dec(enc(%v)) failed: %v", v, err)
+	}
+
+	got := result.(time.Time)
+	_, want := v.Zone()
+	if _, offset := got.Zone(); !got.Equal(v) || offset != want {
+		t.Errorf("dec(enc(%v)) = %v, want same instant and offset", v, got)
+	}
+}
@@ -30,6 +30,8 @@ func init() {
 	runtime.RegisterFunction(decVarIntZ)
 	runtime.RegisterFunction(encVarUintZ)
 	runtime.RegisterFunction(decVarUintZ)
+	runtime.RegisterFunction(encVarInt)
+	runtime.RegisterFunction(decVarInt)
 }
 
 // NewVarIntZ returns a varint coder for the given integer type. It uses a zig-zag scheme,
@@ -54,6 +56,35 @@ func NewVarUintZ(t reflect.Type) (*coder.CustomCoder, error) {
 	}
 }
 
+// NewVarInt returns a varint coder for the given signed integer type without
+// the zig-zag scheme, as in the Beam standard coding scheme. Negative values
+// always take 10 bytes, so it is only compact for non-negative values.
+func NewVarInt(t reflect.Type) (*coder.CustomCoder, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return coder.NewCustomCoder("varint", t, encVarInt, decVarInt)
+	default:
+		return nil, fmt.Errorf("not a signed integer type: %v", t)
+	}
+}
+
+func encVarInt(v typex.T) []byte {
+	val := reflect.ValueOf(v).Int()
+	ret := make([]byte, binary.MaxVarintLen64)
+	size := binary.PutUvarint(ret, uint64(val))
+	return ret[:size]
+}
+
+func decVarInt(t reflect.Type, data []byte) (typex.T, error) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid varint encoding for: %v", data)
+	}
+	ret := reflect.New(t).Elem()
+	ret.SetInt(int64(n))
+	return ret.Interface(), nil
+}
+
 func encVarIntZ(v typex.T) []byte {
 	var val int64
 	switch n := v.(type) {
@@ -82,3 +82,69 @@ func TestVarUintZ(t *testing.T) {
 		}
 	}
 }
+
+func TestVarInt(t *testing.T) {
+	tests := []interface{}{
+		int(1),
+		int(-1),
+		int8(-128),
+		int16(16),
+		int32(-32),
+		int64(1 << 40),
+		int64(-64),
+	}
+
+	for _, v := range tests {
+		typ := reflect.ValueOf(v).Type()
+
+		data := encVarInt(v)
+		result, err := decVarInt(typ, data)
+		if err != nil {
+			t.Fatalf("dec(enc(%v)) failed: %v", v, err)
+		}
+		if v != result {
+			t.Errorf("dec(enc(%v)) = %v, want id", v, result)
+		}
+	}
+
+	if got := len(encVarInt(int64(1))); got != 1 {
+		t.Errorf("len(enc(1)) = %v, want 1", got)
+	}
+	if got := len(encVarInt(int64(-1))); got != 10 {
+		t.Errorf("len(enc(-1)) = %v, want 10", got)
+	}
+}
+
+func TestFixedInt(t *testing.T) {
+	tests := []struct {
+		v     interface{}
+		width int
+	}{
+		{int(-1), 8},
+		{int8(-128), 1},
+		{int16(300), 2},
+		{int32(-32), 4},
+		{int64(-1 << 40), 8},
+		{uint(1), 8},
+		{uint8(255), 1},
+		{uint16(65535), 2},
+		{uint32(1 << 31), 4},
+		{uint64(1 << 63), 8},
+	}
+
+	for _, test := range tests {
+		typ := reflect.ValueOf(test.v).Type()
+
+		data := encFixedInt(typ, test.v)
+		if len(data) != test.width {
+			t.Errorf("len(enc(%v)) = %v, want %v", test.v, len(data), test.width)
+		}
+		result, err := decFixedInt(typ, data)
+		if err != nil {
+			t.Fatalf("dec(enc(%v)) failed: %v", test.v, err)
+		}
+		if test.v != result {
+			t.Errorf("dec(enc(%v)) = %v, want id", test.v, result)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/ioutilx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
@@ -140,26 +141,30 @@ func MakeElementDecoder(c *coder.Coder) ElementDecoder {
 	}
 }
 
-type bytesEncoder struct{}
+// bytesEncoder encodes []byte and string values without reflection. It reuses
+// a scratch buffer for the length prefix.
+type bytesEncoder struct {
+	scratch [coder.MaxVarIntLen]byte
+}
 
-func (*bytesEncoder) Encode(val FullValue, w io.Writer) error {
+func (c *bytesEncoder) Encode(val FullValue, w io.Writer) error {
 	// Encoding: size (varint) + raw data
-	var data []byte
 	switch v := val.Elm.(type) {
 	case []byte:
-		data = v
+		if err := writeSize(c.scratch[:], len(v), w); err != nil {
+			return err
+		}
+		_, err := w.Write(v)
+		return err
 	case string:
-		data = []byte(v)
+		if err := writeSize(c.scratch[:], len(v), w); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, v) // avoids a copy, if supported by w
+		return err
 	default:
 		return fmt.Errorf("received unknown value type: want []byte or string, got %T", v)
 	}
-	size := len(data)
-
-	if err := coder.EncodeVarInt((int32)(size), w); err != nil {
-		return err
-	}
-	_, err := w.Write(data)
-	return err
 }
 
 type bytesDecoder struct{}
@@ -167,15 +172,28 @@ type bytesDecoder struct{}
 func (*bytesDecoder) Decode(r io.Reader) (FullValue, error) {
 	// Encoding: size (varint) + raw data
 
-	size, err := coder.DecodeVarInt(r)
+	data, err := readSized(r)
 	if err != nil {
 		return FullValue{}, err
 	}
-	data, err := ioutilx.ReadN(r, (int)(size))
+	return FullValue{Elm: data}, nil
+}
+
+// writeSize writes the length prefix of encoded data using the given scratch
+// buffer.
+func writeSize(scratch []byte, size int, w io.Writer) error {
+	n := coder.PutVarUint64(scratch, uint64(uint32(size)))
+	_, err := w.Write(scratch[:n])
+	return err
+}
+
+// readSized reads length-prefixed data.
+func readSized(r io.Reader) ([]byte, error) {
+	size, err := coder.DecodeVarInt(r)
 	if err != nil {
-		return FullValue{}, err
+		return nil, err
 	}
-	return FullValue{Elm: data}, nil
+	return ioutilx.ReadN(r, (int)(size))
 }
 
 type varIntEncoder struct{}
@@ -202,6 +220,8 @@ func (*varIntDecoder) Decode(r io.Reader) (FullValue, error) {
 type customEncoder struct {
 	t   reflect.Type
 	enc Encoder
+
+	scratch [coder.MaxVarIntLen]byte
 }
 
 func (c *customEncoder) Encode(val FullValue, w io.Writer) error {
@@ -214,8 +234,7 @@ func (c *customEncoder) Encode(val FullValue, w io.Writer) error {
 
 	// (2) Add length prefix
 
-	size := len(data)
-	if err := coder.EncodeVarInt((int32)(size), w); err != nil {
+	if err := writeSize(c.scratch[:], len(data), w); err != nil {
 		return err
 	}
 	_, err = w.Write(data)
@@ -230,11 +249,7 @@ type customDecoder struct {
 func (c *customDecoder) Decode(r io.Reader) (FullValue, error) {
 	// (1) Read length-prefixed encoded data
 
-	size, err := coder.DecodeVarInt(r)
-	if err != nil {
-		return FullValue{}, err
-	}
-	data, err := ioutilx.ReadN(r, (int)(size))
+	data, err := readSized(r)
 	if err != nil {
 		return FullValue{}, err
 	}
@@ -243,7 +258,7 @@ func (c *customDecoder) Decode(r io.Reader) (FullValue, error) {
 
 	val, err := c.dec.Decode(c.t, data)
 	if err != nil {
-		return FullValue{}, err
+		return FullValue{}, userDecodeError{err}
 	}
 	return FullValue{Elm: val}, err
 }
@@ -276,10 +291,112 @@ func (c *kvDecoder) Decode(r io.Reader) (FullValue, error) {
 
 }
 
-// TODO(herohde) 4/7/2017: actually handle windows.
+// WindowEncoder handles Window serialization to a byte stream. The encoder
+// can be reused, even if an error is encountered.
+type WindowEncoder interface {
+	// Encode serializes the given windows into the writer.
+	Encode([]typex.Window, io.Writer) error
+}
+
+// MakeWindowEncoder returns a WindowEncoder for the given window coder.
+func MakeWindowEncoder(c *coder.WindowCoder) WindowEncoder {
+	switch c.Kind {
+	case coder.GlobalWindow:
+		return &globalWindowEncoder{}
+	case coder.IntervalWindow:
+		return &intervalWindowEncoder{}
+	default:
+		panic(fmt.Sprintf("Unexpected window coder: %v", c))
+	}
+}
+
+// WindowDecoder handles Window deserialization from a byte stream. The decoder
+// can be reused, even if an error is encountered.
+type WindowDecoder interface {
+	// Decode deserializes windows from the reader.
+	Decode(io.Reader) ([]typex.Window, error)
+}
+
+// MakeWindowDecoder returns a WindowDecoder for the given window coder.
+func MakeWindowDecoder(c *coder.WindowCoder) WindowDecoder {
+	switch c.Kind {
+	case coder.GlobalWindow:
+		return &globalWindowDecoder{}
+	case coder.IntervalWindow:
+		return &intervalWindowDecoder{}
+	default:
+		panic(fmt.Sprintf("Unexpected window coder: %v", c))
+	}
+}
+
+type globalWindowEncoder struct{}
+
+func (*globalWindowEncoder) Encode(ws []typex.Window, w io.Writer) error {
+	// GlobalWindow is encoded into the empty string. A nil list of
+	// windows is the global window.
+
+	return coder.EncodeInt32(1, w) // #windows
+}
+
+type globalWindowDecoder struct{}
+
+func (*globalWindowDecoder) Decode(r io.Reader) ([]typex.Window, error) {
+	_, err := coder.DecodeInt32(r) // #windows
+	return nil, err
+}
+
+type intervalWindowEncoder struct{}
+
+func (*intervalWindowEncoder) Encode(ws []typex.Window, w io.Writer) error {
+	// Encoding: End (EventTime), Span (varint millis)
+
+	if len(ws) == 0 {
+		return fmt.Errorf("missing interval window")
+	}
+	if err := coder.EncodeInt32(int32(len(ws)), w); err != nil { // #windows
+		return err
+	}
+	for _, elm := range ws {
+		iw, ok := elm.(window.IntervalWindow)
+		if !ok {
+			return fmt.Errorf("window %v is not an interval window", elm)
+		}
+		if err := coder.EncodeEventTime(iw.End, w); err != nil {
+			return err
+		}
+		span := time.Time(iw.End).Sub(time.Time(iw.Start)) / time.Millisecond
+		if err := coder.EncodeVarUint64(uint64(span), w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type intervalWindowDecoder struct{}
+
+func (*intervalWindowDecoder) Decode(r io.Reader) ([]typex.Window, error) {
+	n, err := coder.DecodeInt32(r) // #windows
+	if err != nil {
+		return nil, err
+	}
+	ws := make([]typex.Window, n)
+	for i := range ws {
+		end, err := coder.DecodeEventTime(r)
+		if err != nil {
+			return nil, err
+		}
+		span, err := coder.DecodeVarUint64(r)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Time(end).Add(-time.Duration(span) * time.Millisecond)
+		ws[i] = window.IntervalWindow{Start: typex.EventTime(start), End: end}
+	}
+	return ws, nil
+}
 
 // EncodeWindowedValueHeader serializes a windowed value header.
-func EncodeWindowedValueHeader(t typex.EventTime, w io.Writer) error {
+func EncodeWindowedValueHeader(enc WindowEncoder, ws []typex.Window, t typex.EventTime, w io.Writer) error {
 	// Encoding: Timestamp, Window, Pane (header) + Element
 
 	if (time.Time)(t).IsZero() {
@@ -288,30 +405,39 @@ func EncodeWindowedValueHeader(t typex.EventTime, w io.Writer) error {
 	if err := coder.EncodeEventTime(t, w); err != nil {
 		return err
 	}
-	if err := coder.EncodeInt32(1, w); err != nil { // #windows
+	if err := enc.Encode(ws, w); err != nil {
 		return err
 	}
-	// Ignore GlobalWindow, for now. It encoded into the empty string.
 
 	_, err := w.Write([]byte{0xf}) // NO_FIRING pane
 	return err
 }
 
 // DecodeWindowedValueHeader deserializes a windowed value header.
-func DecodeWindowedValueHeader(r io.Reader) (typex.EventTime, error) {
+func DecodeWindowedValueHeader(dec WindowDecoder, r io.Reader) ([]typex.Window, typex.EventTime, error) {
 	// Encoding: Timestamp, Window, Pane (header) + Element
 
 	t, err := coder.DecodeEventTime(r)
 	if err != nil {
-		return typex.EventTime(time.Time{}), err
+		return nil, typex.EventTime(time.Time{}), err
 	}
-	if _, err := coder.DecodeInt32(r); err != nil { // #windows
-		return typex.EventTime(time.Time{}), err
+	ws, err := dec.Decode(r)
+	if err != nil {
+		return nil, typex.EventTime(time.Time{}), err
 	}
 	if _, err := ioutilx.ReadN(r, 1); err != nil { // NO_FIRING pane
-		return typex.EventTime(time.Time{}), err
+		return nil, typex.EventTime(time.Time{}), err
+	}
+	return ws, t, nil
+}
+
+// windowCoder returns the window coder of a windowed value coder, or the
+// global window coder otherwise.
+func windowCoder(c *coder.Coder) *coder.WindowCoder {
+	if coder.IsW(c) && c.Window != nil {
+		return c.Window
 	}
-	return t, nil
+	return coder.NewGlobalWindow()
 }
 
 func convertIfNeeded(v interface{}) FullValue {
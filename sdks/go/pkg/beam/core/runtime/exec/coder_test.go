@@ -0,0 +1,122 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func TestBytesCoder(t *testing.T) {
+	enc := MakeElementEncoder(coder.NewBytes())
+	dec := MakeElementDecoder(coder.NewBytes())
+
+	tests := []interface{}{
+		"",
+		"hello",
+		strings.Repeat("x", 300),
+		[]byte{},
+		[]byte{0, 1, 2, 0xff},
+	}
+
+	var buf bytes.Buffer
+	for _, v := range tests {
+		if err := enc.Encode(FullValue{Elm: v}, &buf); err != nil {
+			t.Fatalf("Encode(%v) failed: %v", v, err)
+		}
+	}
+	for _, v := range tests {
+		val, err := dec.Decode(&buf)
+		if err != nil {
+			t.Fatalf("Decode(Encode(%v)) failed: %v", v, err)
+		}
+		if got, want := string(val.Elm.([]byte)), fmt.Sprintf("%s", v); got != want {
+			t.Errorf("Decode(Encode(%v)) = %q, want %q", v, got, want)
+		}
+	}
+
+	if err := enc.Encode(FullValue{Elm: 42}, &buf); err == nil {
+		t.Errorf("Encode(42) succeeded, want error")
+	}
+}
+
+func TestBytesEncoderAllocs(t *testing.T) {
+	enc := MakeElementEncoder(coder.NewBytes())
+
+	var buf bytes.Buffer
+	buf.Grow(1 << 10)
+	val := FullValue{Elm: "some string element"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Reset()
+		if err := enc.Encode(val, &buf); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Encode allocated %v times per string, want 0", allocs)
+	}
+}
+
+func TestWindowedValueHeader(t *testing.T) {
+	ts := typex.EventTime(time.Unix(100, 0))
+	iw := func(start, end int64) typex.Window {
+		return window.IntervalWindow{Start: typex.EventTime(time.Unix(start, 0)), End: typex.EventTime(time.Unix(end, 0))}
+	}
+
+	tests := []struct {
+		c  *coder.WindowCoder
+		ws []typex.Window
+	}{
+		{coder.NewGlobalWindow(), nil},
+		{coder.NewIntervalWindow(), []typex.Window{iw(90, 110)}},
+		{coder.NewIntervalWindow(), []typex.Window{iw(90, 110), iw(95, 115)}},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := EncodeWindowedValueHeader(MakeWindowEncoder(test.c), test.ws, ts, &buf); err != nil {
+			t.Fatalf("EncodeWindowedValueHeader(%v, %v) failed: %v", test.c, test.ws, err)
+		}
+		ws, et, err := DecodeWindowedValueHeader(MakeWindowDecoder(test.c), &buf)
+		if err != nil {
+			t.Fatalf("DecodeWindowedValueHeader(%v) failed: %v", test.c, err)
+		}
+		if !time.Time(et).Equal(time.Time(ts)) {
+			t.Errorf("DecodeWindowedValueHeader(%v) timestamp = %v, want %v", test.c, et, ts)
+		}
+		if len(ws) != len(test.ws) {
+			t.Fatalf("DecodeWindowedValueHeader(%v) = %v, want %v", test.c, ws, test.ws)
+		}
+		for i, w := range ws {
+			if !w.Equals(test.ws[i]) {
+				t.Errorf("DecodeWindowedValueHeader(%v) = %v, want %v", test.c, ws, test.ws)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeWindowedValueHeader(MakeWindowEncoder(coder.NewIntervalWindow()), nil, ts, &buf); err == nil {
+		t.Errorf("EncodeWindowedValueHeader(interval, nil) succeeded, want error")
+	}
+}
@@ -16,6 +16,7 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 	"github.com/apache/beam/sdks/go/pkg/beam/util/errorx"
@@ -126,7 +128,7 @@ func (n *Combine) ProcessElement(ctx context.Context, value FullValue, values ..
 		if err != nil {
 			return n.fail(err)
 		}
-		return n.Out.ProcessElement(ctx, FullValue{Elm: value.Elm, Elm2: out, Timestamp: value.Timestamp})
+		return n.Out.ProcessElement(ctx, FullValue{Elm: value.Elm, Elm2: out, Timestamp: value.Timestamp, Windows: value.Windows})
 	}
 
 	// Accumulate globally
@@ -255,3 +257,150 @@ func (n *Combine) fail(err error) error {
 func (n *Combine) String() string {
 	return fmt.Sprintf("Combine[%v] Keyed:%v (Use:%v) Out:%v", path.Base(n.Fn.Name()), n.IsPerKey, n.UsesKey, n.Out.ID())
 }
+
+// mergeAccumulators merges two accumulators using either form of the
+// MergeAccumulators function.
+func (n *Combine) mergeAccumulators(ctx context.Context, a, b interface{}) (interface{}, error) {
+	fn := n.Fn.MergeAccumulatorsFn()
+	in := fn.Params(funcx.FnValue)
+
+	opt := &MainInput{Key: FullValue{Elm: a, Elm2: b}}
+	if len(in) == 1 {
+		list := reflect.MakeSlice(fn.Param[in[0]].T, 0, 2)
+		list = reflect.Append(list, reflect.ValueOf(a), reflect.ValueOf(b))
+		opt = &MainInput{Key: FullValue{Elm: list.Interface()}}
+	}
+	val, err := Invoke(ctx, fn, opt)
+	if err != nil {
+		return nil, n.fail(fmt.Errorf("MergeAccumulators failed: %v", err))
+	}
+	return val.Elm, nil
+}
+
+// LiftedCombine is the pre-shuffle half of a lifted per-key combine. It adds
+// the KV<K,V> values of each key to an accumulator within the bundle and
+// emits the partial accumulators as KV<K,A> when the bundle finishes, which
+// reduces the data to shuffle. Keys are compared by their encoding.
+type LiftedCombine struct {
+	*Combine
+	KeyCoder *coder.Coder
+
+	enc   ElementEncoder
+	cache map[string]FullValue
+}
+
+func (n *LiftedCombine) Up(ctx context.Context) error {
+	if err := n.Combine.Up(ctx); err != nil {
+		return err
+	}
+	n.enc = MakeElementEncoder(n.KeyCoder)
+	return nil
+}
+
+func (n *LiftedCombine) StartBundle(ctx context.Context, id string, data DataManager) error {
+	if err := n.Combine.StartBundle(ctx, id, data); err != nil {
+		return err
+	}
+	n.cache = make(map[string]FullValue)
+	return nil
+}
+
+func (n *LiftedCombine) ProcessElement(ctx context.Context, value FullValue, values ...ReStream) error {
+	if n.status != Active {
+		return fmt.Errorf("invalid status for lifted combine %v: %v", n.UID, n.status)
+	}
+
+	var buf bytes.Buffer
+	if err := n.enc.Encode(FullValue{Elm: value.Elm}, &buf); err != nil {
+		return n.fail(fmt.Errorf("failed to encode key %v for lifted combine: %v", value.Elm, err))
+	}
+	key := buf.String()
+
+	entry, ok := n.cache[key]
+	if !ok {
+		a, err := n.newAccum(ctx, value.Elm)
+		if err != nil {
+			return n.fail(err)
+		}
+		entry = FullValue{Elm: value.Elm, Elm2: a, Timestamp: value.Timestamp}
+	}
+
+	var a interface{}
+	var err error
+	if n.Fn.AddInputFn() == nil && ok {
+		a, err = n.mergeAccumulators(ctx, entry.Elm2, value.Elm2)
+	} else {
+		a, err = n.addInput(ctx, entry.Elm2, value.Elm, value.Elm2, value.Timestamp, !ok)
+	}
+	if err != nil {
+		return n.fail(err)
+	}
+	entry.Elm2 = a
+	n.cache[key] = entry
+	return nil
+}
+
+func (n *LiftedCombine) FinishBundle(ctx context.Context) error {
+	for key, entry := range n.cache {
+		if err := n.Out.ProcessElement(ctx, entry); err != nil {
+			return n.fail(err)
+		}
+		delete(n.cache, key)
+	}
+	return n.Combine.FinishBundle(ctx)
+}
+
+func (n *LiftedCombine) String() string {
+	return fmt.Sprintf("LiftedCombine[%v] Keyed:%v Out:%v", path.Base(n.Fn.Name()), n.UsesKey, n.Out.ID())
+}
+
+// MergeAccumulators is the post-shuffle half of a lifted per-key combine. It
+// merges the grouped partial accumulators of each key and emits the
+// extracted output as KV<K,O>.
+type MergeAccumulators struct {
+	*Combine
+}
+
+func (n *MergeAccumulators) ProcessElement(ctx context.Context, value FullValue, values ...ReStream) error {
+	if n.status != Active {
+		return fmt.Errorf("invalid status for merge accumulators %v: %v", n.UID, n.status)
+	}
+
+	var a interface{}
+	first := true
+
+	stream := values[0].Open()
+	for {
+		v, err := stream.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return n.fail(err)
+		}
+		if first {
+			a, first = v.Elm, false
+			continue
+		}
+		if a, err = n.mergeAccumulators(ctx, a, v.Elm); err != nil {
+			return err
+		}
+	}
+	stream.Close()
+
+	if first {
+		var err error
+		if a, err = n.newAccum(ctx, value.Elm); err != nil {
+			return n.fail(err)
+		}
+	}
+	out, err := n.extract(ctx, a)
+	if err != nil {
+		return n.fail(err)
+	}
+	return n.Out.ProcessElement(ctx, FullValue{Elm: value.Elm, Elm2: out, Timestamp: value.Timestamp, Windows: value.Windows})
+}
+
+func (n *MergeAccumulators) String() string {
+	return fmt.Sprintf("MergeAccumulators[%v] Out:%v", path.Base(n.Fn.Name()), n.Out.ID())
+}
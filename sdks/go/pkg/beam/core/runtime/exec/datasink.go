@@ -35,9 +35,18 @@ type DataSink struct {
 	Coder  *coder.Coder
 
 	enc   ElementEncoder
+	wEnc  WindowEncoder
 	w     io.WriteCloser
+	buf   bytes.Buffer
 	count int64
+	size  int64
 	start time.Time
+	sizes sizeSampler
+}
+
+// grower is an optional interface for writers that can pre-allocate space.
+type grower interface {
+	Grow(n int)
 }
 
 func (n *DataSink) ID() UnitID {
@@ -46,6 +55,7 @@ func (n *DataSink) ID() UnitID {
 
 func (n *DataSink) Up(ctx context.Context) error {
 	n.enc = MakeElementEncoder(coder.SkipW(n.Coder))
+	n.wEnc = MakeWindowEncoder(windowCoder(n.Coder))
 	return nil
 }
 
@@ -58,23 +68,28 @@ func (n *DataSink) StartBundle(ctx context.Context, id string, data DataManager)
 	}
 	n.w = w
 	atomic.StoreInt64(&n.count, 0)
+	n.size = 0
 	n.start = time.Now()
+	n.sizes.reset()
 	return nil
 }
 
 func (n *DataSink) ProcessElement(ctx context.Context, value FullValue, values ...ReStream) error {
 	// Marshal the pieces into a temporary buffer since they must be transmitted on FnAPI as a single
-	// unit.
-	var b bytes.Buffer
+	// unit. The buffer is reused across elements.
+	b := &n.buf
+	b.Reset()
 
 	atomic.AddInt64(&n.count, 1)
-	if err := EncodeWindowedValueHeader(value.Timestamp, &b); err != nil {
+	if err := EncodeWindowedValueHeader(n.wEnc, value.Windows, value.Timestamp, b); err != nil {
 		return err
 	}
 
-	if err := n.enc.Encode(value, &b); err != nil {
+	if err := n.enc.Encode(value, b); err != nil {
 		return fmt.Errorf("failed to encode element %v with coder %v: %v", value, n.enc, err)
 	}
+	n.size += int64(b.Len())
+	n.sizes.sample(int64(b.Len()))
 
 	if _, err := n.w.Write(b.Bytes()); err != nil {
 		return err
@@ -82,8 +97,22 @@ func (n *DataSink) ProcessElement(ctx context.Context, value FullValue, values .
 	return nil
 }
 
+// Reserve pre-sizes the writer for the given number of elements, based on the
+// average encoded element size in the bundle so far.
+func (n *DataSink) Reserve(elms int) {
+	g, ok := n.w.(grower)
+	count := atomic.LoadInt64(&n.count)
+	if !ok || count == 0 {
+		return
+	}
+	g.Grow(elms * int(n.size/count))
+}
+
 func (n *DataSink) FinishBundle(ctx context.Context) error {
-	log.Infof(ctx, "DataSource: %d elements in %d ns", atomic.LoadInt64(&n.count), time.Now().Sub(n.start))
+	count := atomic.LoadInt64(&n.count)
+	log.Infof(ctx, "DataSink: %d elements (%d bytes) in %d ns", count, n.size, time.Now().Sub(n.start))
+	reportShuffleWrite(ctx, n.Target.ID, n.size)
+	reportSampledSizes(ctx, n.Target, &n.sizes)
 	return n.w.Close()
 }
 
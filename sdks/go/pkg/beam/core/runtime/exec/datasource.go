@@ -38,6 +38,7 @@ type DataSource struct {
 	source DataReader
 	count  int64
 	start  time.Time
+	sizes  sizeSampler
 }
 
 func (n *DataSource) ID() UnitID {
@@ -52,6 +53,7 @@ func (n *DataSource) StartBundle(ctx context.Context, id string, data DataManage
 	n.sid = StreamID{Port: n.Port, Target: n.Target, InstID: id}
 	n.source = data
 	n.start = time.Now()
+	n.sizes.reset()
 	atomic.StoreInt64(&n.count, 0)
 	return n.Out.StartBundle(ctx, id, data)
 }
@@ -64,13 +66,17 @@ func (n *DataSource) Process(ctx context.Context) error {
 	defer r.Close()
 
 	c := coder.SkipW(n.Coder)
+	wd := MakeWindowDecoder(windowCoder(n.Coder))
 	switch {
 	case coder.IsCoGBK(c):
 		ck := MakeElementDecoder(c.Components[0])
 		cv := MakeElementDecoder(c.Components[1])
+		ev := MakeElementEncoder(c.Components[1])
+		cr := newRecordingReader(r)
+		defer n.reportRead(ctx, cr)
 
 		for {
-			t, err := DecodeWindowedValueHeader(r)
+			ws, t, err := DecodeWindowedValueHeader(wd, cr)
 			if err != nil {
 				if err == io.EOF {
 					return nil
@@ -80,19 +86,34 @@ func (n *DataSource) Process(ctx context.Context) error {
 
 			// Decode key
 
-			key, err := ck.Decode(r)
+			cr.Mark()
+			key, err := ck.Decode(cr)
 			if err != nil {
-				return fmt.Errorf("source decode failed: %v", err)
+				// The values of the key cannot be skipped.
+				derr := n.decodeError(c.Components[0], cr, err)
+				derr.Recoverable = false
+				return derr
 			}
 			key.Timestamp = t
+			key.Windows = ws
 
 			// TODO(herohde) 4/30/2017: the State API will be handle re-iterations
 			// and only "small" value streams would be inline. Presumably, that
-			// would entail buffering the whole stream. We do that for now.
-
-			var buf []FullValue
+			// would entail buffering the whole stream. We do that for now, but
+			// spill to disk if the values of the key are too large.
+
+			buf := newSpillBuffer(ev, cv, SpillThreshold)
+			add := func() error {
+				start := cr.n
+				cr.Mark()
+				value, err := cv.Decode(cr)
+				if err != nil {
+					return handleDecodeError(ctx, n.decodeError(c.Components[1], cr, err))
+				}
+				return buf.Add(value, cr.n-start)
+			}
 
-			size, err := coder.DecodeInt32(r)
+			size, err := coder.DecodeInt32(cr)
 			if err != nil {
 				return fmt.Errorf("stream size decoding failed: %v", err)
 			}
@@ -104,18 +125,18 @@ func (n *DataSource) Process(ctx context.Context) error {
 				atomic.AddInt64(&n.count, int64(size))
 
 				for i := int32(0); i < size; i++ {
-					value, err := cv.Decode(r)
-					if err != nil {
-						return fmt.Errorf("stream value decode failed: %v", err)
+					if err := add(); err != nil {
+						buf.Close()
+						return err
 					}
-					buf = append(buf, value)
 				}
 			} else {
 				// Multi-chunked stream.
 
 				for {
-					chunk, err := coder.DecodeVarUint64(r)
+					chunk, err := coder.DecodeVarUint64(cr)
 					if err != nil {
+						buf.Close()
 						return fmt.Errorf("stream chunk size decoding failed: %v", err)
 					}
 
@@ -127,27 +148,41 @@ func (n *DataSource) Process(ctx context.Context) error {
 
 					atomic.AddInt64(&n.count, int64(chunk))
 					for i := uint64(0); i < chunk; i++ {
-						value, err := cv.Decode(r)
-						if err != nil {
-							return fmt.Errorf("stream value decode failed: %v", err)
+						if err := add(); err != nil {
+							buf.Close()
+							return err
 						}
-						buf = append(buf, value)
 					}
 				}
 			}
 
-			values := &FixedReStream{Buf: buf}
-			if err := n.Out.ProcessElement(ctx, key, values); err != nil {
+			if buf.Spilled() {
+				log.Infof(ctx, "DataSource: spilled %v values of a large key to disk", buf.spilled)
+			}
+			values, err := buf.ReStream()
+			if err == nil {
+				err = n.Out.ProcessElement(ctx, key, values)
+			}
+			if cerr := buf.Close(); err == nil && cerr != nil {
+				err = fmt.Errorf("failed to remove spill file: %v", cerr)
+			}
+			if err != nil {
 				return err
 			}
 		}
 
+	case PrefetchElements > 0:
+		return n.processPrefetched(ctx, c, wd, r)
+
 	default:
 		ec := MakeElementDecoder(c)
+		rr := newRecordingReader(r)
+		defer n.reportRead(ctx, rr)
 
 		for {
 			atomic.AddInt64(&n.count, 1)
-			t, err := DecodeWindowedValueHeader(r)
+			start := rr.n
+			ws, t, err := DecodeWindowedValueHeader(wd, rr)
 			if err != nil {
 				if err == io.EOF {
 					return nil
@@ -155,11 +190,17 @@ func (n *DataSource) Process(ctx context.Context) error {
 				return fmt.Errorf("source failed: %v", err)
 			}
 
-			elm, err := ec.Decode(r)
+			rr.Mark()
+			elm, err := ec.Decode(rr)
 			if err != nil {
-				return fmt.Errorf("source decode failed: %v", err)
+				if err := handleDecodeError(ctx, n.decodeError(c, rr, err)); err != nil {
+					return err
+				}
+				continue // skip: handled
 			}
 			elm.Timestamp = t
+			elm.Windows = ws
+			n.sizes.sample(rr.n - start)
 
 			// log.Printf("READ: %v %v", elm.Key.Type(), elm.Key.Interface())
 
@@ -170,8 +211,27 @@ func (n *DataSource) Process(ctx context.Context) error {
 	}
 }
 
+// reportRead records the bytes read from the data plane.
+func (n *DataSource) reportRead(ctx context.Context, r *recordingReader) {
+	reportShuffleRead(ctx, n.Target.ID, r.n)
+}
+
+// decodeError returns a DecodeError for an element of the given coder, which
+// was read from the recording reader.
+func (n *DataSource) decodeError(c *coder.Coder, r *recordingReader, err error) *DecodeError {
+	_, recoverable := err.(userDecodeError)
+	return &DecodeError{
+		Coder:       c.String(),
+		Transform:   fmt.Sprintf("%v (DataSource %v for %v)", n.Target.ID, n.UID, n.Out.ID()),
+		Data:        r.Recorded(),
+		Err:         err,
+		Recoverable: recoverable,
+	}
+}
+
 func (n *DataSource) FinishBundle(ctx context.Context) error {
 	log.Infof(ctx, "DataSource: %d elements in %d ns", atomic.LoadInt64(&n.count), time.Now().Sub(n.start))
+	reportSampledSizes(ctx, n.Target, &n.sizes)
 	n.sid = StreamID{}
 	n.source = nil
 	return n.Out.FinishBundle(ctx)
@@ -0,0 +1,143 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MaxDecodeErrorBytes is the maximum number of bytes of an undecodable
+// element included in a DecodeError.
+const MaxDecodeErrorBytes = 64
+
+// DecodeError is returned when an element of a data stream cannot be
+// decoded. It identifies the coder and the consuming transform and holds
+// the first bytes of the element for diagnosis.
+type DecodeError struct {
+	// Coder is the coder of the element.
+	Coder string
+	// Transform identifies the transform that consumes the element.
+	Transform string
+	// Data holds the first bytes of the element, at most MaxDecodeErrorBytes.
+	Data []byte
+	// Err is the underlying error.
+	Err error
+	// Recoverable is true iff the data stream can continue after the element,
+	// i.e., the length-prefixed element was read in full, but the decode
+	// function of its custom coder failed.
+	Recoverable bool
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode element with coder %v for %v: %v. First %v bytes of the element:\n%v", e.Coder, e.Transform, e.Err, len(e.Data), strings.TrimSuffix(hex.Dump(e.Data), "\n"))
+}
+
+// DecodeErrorHandler handles recoverable decode errors. If it returns nil,
+// the element is skipped and processing continues. Handlers may, for
+// example, write the data to a dead-letter location and record a metric.
+type DecodeErrorHandler func(ctx context.Context, err *DecodeError) error
+
+var (
+	decodeErrorHandler   DecodeErrorHandler
+	decodeErrorHandlerMu sync.Mutex
+)
+
+// RegisterDecodeErrorHandler registers a handler for recoverable decode
+// errors. By default, decode errors fail the bundle. It should be called in
+// init() only.
+func RegisterDecodeErrorHandler(h DecodeErrorHandler) {
+	decodeErrorHandlerMu.Lock()
+	defer decodeErrorHandlerMu.Unlock()
+
+	decodeErrorHandler = h
+}
+
+// handleDecodeError returns nil, if the decode error is recoverable and a
+// registered handler accepts it. Otherwise, it returns the error.
+func handleDecodeError(ctx context.Context, err *DecodeError) error {
+	decodeErrorHandlerMu.Lock()
+	h := decodeErrorHandler
+	decodeErrorHandlerMu.Unlock()
+
+	if !err.Recoverable || h == nil {
+		return err
+	}
+	return h(ctx, err)
+}
+
+// userDecodeError marks a failure of the decode function of a custom coder,
+// after which the data stream is intact.
+type userDecodeError struct {
+	err error
+}
+
+func (e userDecodeError) Error() string {
+	return e.err.Error()
+}
+
+// recordingReader counts the bytes read from the underlying reader and
+// records the first bytes read since the last mark.
+type recordingReader struct {
+	r   io.Reader
+	n   int64
+	buf []byte
+}
+
+func newRecordingReader(r io.Reader) *recordingReader {
+	return &recordingReader{r: r, buf: make([]byte, 0, MaxDecodeErrorBytes)}
+}
+
+func (c *recordingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if rest := cap(c.buf) - len(c.buf); rest > 0 {
+		if rest > n {
+			rest = n
+		}
+		c.buf = append(c.buf, p[:rest]...)
+	}
+	return n, err
+}
+
+// buffered is an optional interface for readers that can report the number
+// of bytes that can be read without blocking.
+type buffered interface {
+	Buffered() int
+}
+
+// Buffered returns the number of bytes that can be read without blocking, if
+// known, and zero otherwise.
+func (c *recordingReader) Buffered() int {
+	if b, ok := c.r.(buffered); ok {
+		return b.Buffered()
+	}
+	return 0
+}
+
+// Mark starts recording the bytes of a new element.
+func (c *recordingReader) Mark() {
+	c.buf = c.buf[:0]
+}
+
+// Recorded returns a copy of the bytes recorded since the last mark.
+func (c *recordingReader) Recorded() []byte {
+	return append([]byte(nil), c.buf...)
+}
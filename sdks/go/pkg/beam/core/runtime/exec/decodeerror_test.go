@@ -0,0 +1,119 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func strictEnc(s string) []byte {
+	return []byte(s)
+}
+
+func strictDec(data []byte) (string, error) {
+	if string(data) == "bad" {
+		return "", errors.New("bad element")
+	}
+	return string(data), nil
+}
+
+// sourceData returns the encoded stream of the given elements and, if
+// truncated, cuts off the last byte.
+func sourceData(t *testing.T, c *coder.Coder, elms []string, truncated bool) fixedData {
+	enc := MakeElementEncoder(c)
+	var buf bytes.Buffer
+	for _, elm := range elms {
+		EncodeWindowedValueHeader(MakeWindowEncoder(coder.NewGlobalWindow()), nil, typex.EventTime(time.Unix(1, 0)), &buf)
+		if err := enc.Encode(FullValue{Elm: elm}, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := buf.Bytes()
+	if truncated {
+		data = data[:len(data)-1]
+	}
+	return fixedData(data)
+}
+
+func runSource(c *coder.Coder, data fixedData) (*CaptureNode, error) {
+	out := &CaptureNode{UID: 2}
+	source := &DataSource{UID: 1, Target: Target{ID: "read"}, Coder: coder.NewW(c, coder.NewGlobalWindow()), Out: out}
+	p, err := NewPlan("a", []Unit{source, out})
+	if err != nil {
+		return nil, err
+	}
+	return out, p.Execute(context.Background(), "1", data)
+}
+
+func TestDataSourceDecodeError(t *testing.T) {
+	cc, err := coder.NewCustomCoder("strict", reflectx.String, strictEnc, strictDec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &coder.Coder{Kind: coder.Custom, T: typex.New(reflectx.String), Custom: cc}
+	elms := []string{"a", "bad", "c"}
+
+	// (1) Without a handler, the bundle fails with the element bytes.
+
+	_, err = runSource(c, sourceData(t, c, elms, false))
+	if err == nil {
+		t.Fatalf("Execute succeeded, want decode error")
+	}
+	for _, want := range []string{"strict", "read", "bad element", "03 62 61 64", "|.bad|"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Execute failed with %v, want error containing %q", err, want)
+		}
+	}
+
+	// (2) With a handler, the element is skipped.
+
+	var handled []*DecodeError
+	RegisterDecodeErrorHandler(func(ctx context.Context, err *DecodeError) error {
+		handled = append(handled, err)
+		return nil
+	})
+	defer RegisterDecodeErrorHandler(nil)
+
+	out, err := runSource(c, sourceData(t, c, elms, false))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(out.Elements) != 2 || out.Elements[0].Elm != "a" || out.Elements[1].Elm != "c" {
+		t.Errorf("Execute output %v, want [a c]", out.Elements)
+	}
+	if len(handled) != 1 || !handled[0].Recoverable || string(handled[0].Data) != "\x03bad" {
+		t.Errorf("handled %v, want a single recoverable error for bad", handled)
+	}
+
+	// (3) Truncated data is not recoverable.
+
+	handled = nil
+	if _, err := runSource(c, sourceData(t, c, elms, true)); err == nil {
+		t.Errorf("Execute succeeded on truncated data, want decode error")
+	}
+	if len(handled) != 1 {
+		t.Errorf("handled %v errors, want 1 for bad only", len(handled))
+	}
+}
@@ -56,6 +56,12 @@ func (m *Flatten) ProcessElement(ctx context.Context, elm FullValue, values ...R
 	return m.Out.ProcessElement(ctx, elm, values...)
 }
 
+// AdvanceTime forwards the time downstream. Flatten does not hold back time
+// across its inputs, so it assumes that only one of them advances time.
+func (m *Flatten) AdvanceTime(ctx context.Context, t Time) error {
+	return MultiAdvanceTime(ctx, t, m.Out)
+}
+
 func (m *Flatten) FinishBundle(ctx context.Context) error {
 	m.seen++
 	if m.seen < m.N {
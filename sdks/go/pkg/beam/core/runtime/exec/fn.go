@@ -22,6 +22,7 @@ import (
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 )
@@ -30,13 +31,23 @@ import (
 type MainInput struct {
 	Key    FullValue
 	Values []ReStream
+	// RTracker is the restriction tracker of a splittable DoFn, if any.
+	RTracker sdf.RTracker
 }
 
 // Invoke invokes the fn with the given values. The extra values must match the non-main
 // side input and emitters. It returns the direct output, if any.
 func Invoke(ctx context.Context, fn *funcx.Fn, opt *MainInput, extra ...interface{}) (*FullValue, error) {
+	val, _, err := invoke(ctx, fn, opt, extra...)
+	return val, err
+}
+
+// invoke is Invoke, but also returns the process continuation returned by
+// the fn, if any.
+func invoke(ctx context.Context, fn *funcx.Fn, opt *MainInput, extra ...interface{}) (*FullValue, sdf.ProcessContinuation, error) {
+	var cont sdf.ProcessContinuation
 	if fn == nil {
-		return nil, nil // ok: nothing to Invoke
+		return nil, cont, nil // ok: nothing to Invoke
 	}
 
 	// (1) Populate contexts
@@ -56,6 +67,9 @@ func Invoke(ctx context.Context, fn *funcx.Fn, opt *MainInput, extra ...interfac
 		if index, ok := fn.EventTime(); ok {
 			args[index] = opt.Key.Timestamp
 		}
+		if index, ok := fn.RTracker(); ok {
+			args[index] = opt.RTracker
+		}
 
 		args[in[i]] = Convert(opt.Key.Elm, fn.Param[in[i]].T)
 		i++
@@ -67,15 +81,18 @@ func Invoke(ctx context.Context, fn *funcx.Fn, opt *MainInput, extra ...interfac
 		for _, iter := range opt.Values {
 			param := fn.Param[in[i]]
 
-			if param.Kind != funcx.FnIter {
-				return nil, fmt.Errorf("GBK/CoGBK result values must be iterable: %v", param)
-			}
-
 			// TODO(herohde) 12/12/2017: allow form conversion on GBK results?
 
-			it := makeIter(param.T, iter)
-			it.Init()
-			args[in[i]] = it.Value()
+			switch param.Kind {
+			case funcx.FnIter:
+				it := makeIter(param.T, iter)
+				it.Init()
+				args[in[i]] = it.Value()
+			case funcx.FnReIter:
+				args[in[i]] = makeReIter(param.T, iter).Value()
+			default:
+				return nil, cont, fmt.Errorf("GBK/CoGBK result values must be iterable: %v", param)
+			}
 			i++
 		}
 	}
@@ -91,10 +108,13 @@ func Invoke(ctx context.Context, fn *funcx.Fn, opt *MainInput, extra ...interfac
 
 	ret, err := reflectx.CallNoPanic(fn.Fn, args)
 	if err != nil {
-		return nil, err
+		return nil, cont, err
 	}
 	if index, ok := fn.Error(); ok && ret[index] != nil {
-		return nil, ret[index].(error)
+		return nil, cont, ret[index].(error)
+	}
+	if index, ok := fn.ProcessContinuation(); ok {
+		cont = ret[index].(sdf.ProcessContinuation)
 	}
 
 	// (5) Return direct output, if any.
@@ -102,6 +122,10 @@ func Invoke(ctx context.Context, fn *funcx.Fn, opt *MainInput, extra ...interfac
 	out := fn.Returns(funcx.RetValue)
 	if len(out) > 0 {
 		value := &FullValue{}
+		if opt != nil {
+			// Outputs have the timestamp of the input, unless set explicitly.
+			value.Timestamp = opt.Key.Timestamp
+		}
 		if index, ok := fn.OutEventTime(); ok {
 			value.Timestamp = ret[index].(typex.EventTime)
 		}
@@ -110,10 +134,10 @@ func Invoke(ctx context.Context, fn *funcx.Fn, opt *MainInput, extra ...interfac
 		if len(out) > 1 {
 			value.Elm2 = ret[out[1]]
 		}
-		return value, nil
+		return value, cont, nil
 	}
 
-	return nil, nil
+	return nil, cont, nil
 }
 
 func makeSideInputs(fn *funcx.Fn, in []*graph.Inbound, side []ReStream) ([]ReusableInput, error) {
@@ -143,7 +167,7 @@ func makeSideInputs(fn *funcx.Fn, in []*graph.Inbound, side []ReStream) ([]Reusa
 	return ret, nil
 }
 
-func makeEmitters(fn *funcx.Fn, nodes []Node) ([]ReusableEmitter, error) {
+func makeEmitters(fn *funcx.Fn, nodes []ElementProcessor) ([]ReusableEmitter, error) {
 	if len(nodes) == 0 {
 		return nil, nil // ok: no output nodes
 	}
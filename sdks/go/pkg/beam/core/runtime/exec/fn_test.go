@@ -91,6 +91,32 @@ func TestInvoke(t *testing.T) {
 			Args:     []interface{}{3},
 			Expected: 6,
 		},
+		{
+			// GBK values as Iter
+			Fn: func(k int, iter func(*int) bool) int {
+				sum, v := k, 0
+				for iter(&v) {
+					sum += v
+				}
+				return sum
+			},
+			Opt:      &MainInput{Key: FullValue{Elm: 1}, Values: []ReStream{&FixedReStream{Buf: makeValues(2, 3)}}},
+			Expected: 6,
+		},
+		{
+			// GBK values as ReIter
+			Fn: func(k int, reiter func() func(*int) bool) int {
+				sum, v := k, 0
+				for pass := 0; pass < 2; pass++ {
+					for iter := reiter(); iter(&v); {
+						sum += v
+					}
+				}
+				return sum
+			},
+			Opt:      &MainInput{Key: FullValue{Elm: 1}, Values: []ReStream{&FixedReStream{Buf: makeValues(2, 3)}}},
+			Expected: 11,
+		},
 		{
 			// EventTime
 			Fn:       func(ts typex.EventTime, a int) int { return time.Time(ts).Nanosecond() + a },
@@ -36,7 +36,8 @@ type FullValue struct {
 	Elm2 interface{} // KV value, if not invalid
 
 	Timestamp typex.EventTime
-	// TODO: Window, pane
+	Windows   []typex.Window // nil is the global window
+	// TODO: pane
 }
 
 func (v FullValue) String() string {
@@ -93,6 +94,22 @@ func (s *FixedStream) Read() (FullValue, error) {
 // Convert converts type of the runtime value to the desired one. It is needed
 // to drop the universal type and convert Aggregate types.
 func Convert(v interface{}, to reflect.Type) interface{} {
+	// Fast path for the common string and []byte values, which are decoded
+	// as []byte.
+	switch elm := v.(type) {
+	case string:
+		if to == reflectx.String {
+			return elm
+		}
+	case []byte:
+		switch to {
+		case reflectx.ByteSlice:
+			return elm
+		case reflectx.String:
+			return string(elm)
+		}
+	}
+
 	from := reflect.TypeOf(v)
 
 	switch {
@@ -115,6 +132,19 @@ func Convert(v interface{}, to reflect.Type) interface{} {
 		}
 		return ret.Interface()
 
+	case typex.IsMap(from) && typex.IsMap(to):
+		// Convert map[A]B to map[C]D.
+
+		value := reflect.ValueOf(v)
+
+		ret := reflect.MakeMapWithSize(to, value.Len())
+		for _, k := range value.MapKeys() {
+			key := reflect.ValueOf(Convert(k.Interface(), to.Key()))
+			elm := reflect.ValueOf(Convert(value.MapIndex(k).Interface(), to.Elem()))
+			ret.SetMapIndex(key, elm)
+		}
+		return ret.Interface()
+
 	default:
 		switch {
 		// Perform conservative type conversions.
@@ -49,6 +49,20 @@ func (m *Multiplex) ProcessElement(ctx context.Context, elm FullValue, values ..
 	return nil
 }
 
+// Reserve forwards the hint to all downstream nodes that accept it.
+func (m *Multiplex) Reserve(n int) {
+	for _, out := range m.Out {
+		if r, ok := out.(Reserver); ok {
+			r.Reserve(n)
+		}
+	}
+}
+
+// AdvanceTime forwards the time to all downstream nodes that observe it.
+func (m *Multiplex) AdvanceTime(ctx context.Context, t Time) error {
+	return MultiAdvanceTime(ctx, t, m.Out...)
+}
+
 func (m *Multiplex) FinishBundle(ctx context.Context) error {
 	return MultiFinishBundle(ctx, m.Out...)
 }
@@ -16,14 +16,22 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"path"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/apache/beam/sdks/go/pkg/beam"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/timers"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/util/errorx"
 )
@@ -35,12 +43,47 @@ type ParDo struct {
 	Inbound []*graph.Inbound
 	Side    []ReStream
 	Out     []Node
+	// State is the state and timers of a stateful DoFn. It must be set by
+	// runners that support them.
+	State StateStore
+	// Resume makes a splittable DoFn process the restrictions it checkpoints
+	// at the end of the bundle, once their delay has passed. Checkpoints are
+	// otherwise left to the runner. See Checkpoint.
+	Resume bool
 
 	PID       string
 	ready     bool
+	sampler   *Sampler // records the element being processed, if any
+	processed int64    // input elements in the bundle, accessed atomically
+	outputs   []int64  // output elements in the bundle, by output, accessed atomically
 	sideinput []ReusableInput
 	emitters  []ReusableEmitter
 	extra     []interface{}
+	capacity  int
+	reservers []Reserver
+
+	skew       time.Duration
+	skewPolicy graph.SkewPolicy
+	checkSkew  bool
+	input      *typex.EventTime // timestamp of the current input, if any
+	windows    []typex.Window   // windows of the current input, if any
+	keyEnc     ElementEncoder   // encoder of the state key, if stateful
+	cache      *stateCache      // state accessed in the current bundle, if stateful
+	merger     WindowMerger     // merges the state of windows, if stateful and merging
+	wfn        *window.Window   // windowing strategy of the input, if stateful
+	bags       map[string]bool  // bag state cells, if merging
+	lists      map[string]bool  // ordered list state cells, if merging
+	now        Time             // last time, if stateful
+	fired      bool             // OnTimer takes a timers.Fired, if stateful
+
+	// The restriction being processed by a splittable DoFn, if any. It is
+	// guarded by mu, because the runner may split it concurrently.
+	mu       sync.Mutex
+	rt       sdf.RTracker
+	rtElm    FullValue
+	draining bool            // restrictions are truncated before processing
+	deferred []resumption    // restrictions checkpointed in the current bundle
+	held     typex.EventTime // watermark of the output, if resumed
 
 	status Status
 	err    errorx.GuardedError
@@ -55,10 +98,50 @@ func (n *ParDo) Up(ctx context.Context) error {
 		return fmt.Errorf("invalid status for pardo %v: %v, want Initializing", n.UID, n.status)
 	}
 	n.status = Up
+	n.outputs = make([]int64, len(n.Out))
+
+	if n.Fn.IsStateful() {
+		if n.State == nil {
+			return n.fail(fmt.Errorf("stateful DoFn %v is not supported by the runner", n.Fn.Name()))
+		}
+		n.keyEnc = MakeElementEncoder(n.Inbound[0].From.Coder.Components[0])
+		n.wfn = n.Inbound[0].From.Window()
+		if fn := n.Fn.OnTimerFn(); fn != nil {
+			if pos := fn.Params(funcx.FnValue); len(pos) > 1 {
+				n.fired = fn.Param[pos[1]].T == reflect.TypeOf(timers.Fired{})
+			}
+		}
+
+		if n.wfn.IsMerging() {
+			merger, ok := n.State.(WindowMerger)
+			if !ok {
+				return n.fail(fmt.Errorf("stateful DoFn %v with merging windows %v is not supported by the runner", n.Fn.Name(), n.wfn))
+			}
+			n.merger = merger
+			n.bags = make(map[string]bool)
+			for _, key := range n.Fn.BagKeys() {
+				n.bags[key] = true
+			}
+			n.lists = make(map[string]bool)
+			for _, key := range n.Fn.OrderedListKeys() {
+				n.lists[key] = true
+			}
+		}
+	}
 
 	if _, err := Invoke(ctx, n.Fn.SetupFn(), nil); err != nil {
 		return n.fail(err)
 	}
+
+	n.skew, n.skewPolicy, n.checkSkew = n.Fn.TimestampSkew()
+
+	if n.capacity = n.Fn.OutputCapacity(); n.capacity > 0 {
+		for _, out := range n.Out {
+			if r, ok := out.(Reserver); ok {
+				n.reservers = append(n.reservers, r)
+			}
+		}
+	}
 	return nil
 }
 
@@ -67,6 +150,11 @@ func (n *ParDo) StartBundle(ctx context.Context, id string, data DataManager) er
 		return fmt.Errorf("invalid status for pardo %v: %v, want Up", n.UID, n.status)
 	}
 	n.status = Active
+	n.sampler = samplerOf(ctx)
+	n.resetCounts()
+	n.mu.Lock()
+	n.deferred, n.held = nil, typex.EventTime{}
+	n.mu.Unlock()
 
 	if err := MultiStartBundle(ctx, id, data, n.Out...); err != nil {
 		return n.fail(err)
@@ -77,13 +165,19 @@ func (n *ParDo) StartBundle(ctx context.Context, id string, data DataManager) er
 
 	// NOTE(herohde) 12/13/2017: we require that side input is available on StartBundle.
 
+	if err := n.bindSideInputs(ctx, id, data); err != nil {
+		return n.fail(err)
+	}
 	if err := n.initIfNeeded(); err != nil {
 		return n.fail(err)
 	}
+	if n.keyEnc != nil {
+		n.cache = newStateCache(n.State)
+	}
 
 	// TODO(BEAM-3303): what to set for StartBundle/FinishBundle emitter timestamp?
 
-	if _, err := n.invokeDataFn(ctx, beam.EventTime{}, n.Fn.StartBundleFn(), nil); err != nil {
+	if _, err := n.invokeDataFn(ctx, typex.EventTime{}, n.Fn.StartBundleFn(), nil); err != nil {
 		return n.fail(err)
 	}
 	return nil
@@ -94,18 +188,92 @@ func (n *ParDo) ProcessElement(ctx context.Context, elm FullValue, values ...ReS
 		return fmt.Errorf("invalid status for pardo %v: %v, want Active", n.UID, n.status)
 	}
 
+	if n.keyEnc != nil && len(elm.Windows) == 1 && n.wfn.IsExpired(elm.Windows[0], n.now.Watermark) {
+		return nil // window expired and its state collected: drop element
+	}
+	if n.keyEnc != nil && len(elm.Windows) > 1 {
+		// State is partitioned by window, so the element is processed in each
+		// of its windows separately.
+		for _, w := range elm.Windows {
+			single := elm
+			single.Windows = []typex.Window{w}
+			if err := n.ProcessElement(ctx, single, values...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	ctx = metrics.SetPTransformID(ctx, n.PID)
+	atomic.AddInt64(&n.processed, 1)
+	if n.sampler != nil {
+		prev := n.sampler.set(sampled{transform: n.PID, elm: elm, since: time.Now()})
+		defer n.sampler.set(prev)
+	}
+
+	for _, r := range n.reservers {
+		r.Reserve(n.capacity)
+	}
+
+	if n.keyEnc != nil {
+		w, err := n.mergeWindow(elm.Elm, stateWindow(elm.Windows))
+		if err != nil {
+			return n.fail(err)
+		}
+		if ctx, err = n.withState(ctx, elm.Elm, w); err != nil {
+			return n.fail(err)
+		}
+	}
+
+	if n.Fn.IsSplittable() {
+		if err := n.processRestrictions(ctx, elm, values...); err != nil {
+			return n.fail(err)
+		}
+		return nil
+	}
+	_, err := n.process(ctx, elm, nil, values...)
+	return err
+}
 
-	val, err := n.invokeDataFn(ctx, elm.Timestamp, n.Fn.ProcessElementFn(), &MainInput{Key: elm, Values: values})
+// process invokes ProcessElement on the element and forwards the direct
+// output, if any. It returns the process continuation, if any.
+func (n *ParDo) process(ctx context.Context, elm FullValue, rt sdf.RTracker, values ...ReStream) (sdf.ProcessContinuation, error) {
+	n.input, n.windows = &elm.Timestamp, elm.Windows
+	val, cont, err := n.invokeProcessFn(ctx, elm.Timestamp, n.Fn.ProcessElementFn(), &MainInput{Key: elm, Values: values, RTracker: rt})
+	n.input, n.windows = nil, nil
 	if err != nil {
-		return n.fail(err)
+		return cont, n.fail(err)
 	}
 
 	// Forward direct output, if any. It is always a main output.
 	if val != nil {
-		return n.Out[0].ProcessElement(ctx, *val, values...)
+		if err := n.checkTimestamp(val, elm.Timestamp); err != nil {
+			return cont, n.fail(err)
+		}
+		val.Windows = elm.Windows
+		n.countOutput(0)
+		return cont, n.Out[0].ProcessElement(ctx, *val, values...)
 	}
-	return nil
+	return cont, nil
+}
+
+// checkTimestamp applies the skew policy to an output of the given input. It
+// either returns an error or moves the output timestamp forward, if the output
+// is earlier than the input by more than the allowed skew. Elements emitted
+// outside ProcessElement, or by DoFns without a skew, are not checked.
+func (n *ParDo) checkTimestamp(val *FullValue, input typex.EventTime) error {
+	if !n.checkSkew {
+		return nil
+	}
+	in, out := time.Time(input), time.Time(val.Timestamp)
+	if in.Sub(out) <= n.skew {
+		return nil
+	}
+	if n.skewPolicy == graph.SkewClamp {
+		val.Timestamp = typex.EventTime(in.Add(-n.skew))
+		return nil
+	}
+	return fmt.Errorf("cannot output with timestamp %v in %v: output timestamps must be no earlier than the timestamp of the current input (%v) minus the allowed skew (%v). See AllowedTimestampSkew", out, path.Base(n.Fn.Name()), in, n.skew)
 }
 
 func (n *ParDo) FinishBundle(ctx context.Context) error {
@@ -114,15 +282,194 @@ func (n *ParDo) FinishBundle(ctx context.Context) error {
 	}
 	n.status = Up
 
-	if _, err := n.invokeDataFn(ctx, beam.EventTime{}, n.Fn.FinishBundleFn(), nil); err != nil {
+	if n.keyEnc != nil {
+		// The input is complete, so time passes all timers.
+		if err := n.fireTimers(ctx, Time{Watermark: EndOfTime, ProcessingTime: time.Time(EndOfTime)}); err != nil {
+			return n.fail(err)
+		}
+		if err := n.cache.Flush(); err != nil {
+			return n.fail(err)
+		}
+	}
+	if n.Resume {
+		if err := n.resume(ctx); err != nil {
+			return n.fail(err)
+		}
+	}
+	if _, err := n.invokeDataFn(ctx, typex.EventTime{}, n.Fn.FinishBundleFn(), nil); err != nil {
 		return n.fail(err)
 	}
+	n.reportCounts(ctx)
 	if err := MultiFinishBundle(ctx, n.Out...); err != nil {
 		return n.fail(err)
 	}
 	return nil
 }
 
+// AdvanceTime fires the timers that are due at the given time, if stateful,
+// and forwards the time downstream. The state of windows that have expired is
+// then discarded, if supported by the store.
+func (n *ParDo) AdvanceTime(ctx context.Context, t Time) error {
+	if n.status != Active {
+		return fmt.Errorf("invalid status for pardo %v: %v, want Active", n.UID, n.status)
+	}
+
+	if n.keyEnc != nil {
+		n.now = t
+		if err := n.fireTimers(ctx, t); err != nil {
+			return n.fail(err)
+		}
+		if err := n.expireState(t.Watermark); err != nil {
+			return n.fail(err)
+		}
+	}
+	if n.Resume {
+		n.mu.Lock()
+		t = n.holdTime(t)
+		n.mu.Unlock()
+	}
+	if err := MultiAdvanceTime(ctx, t, n.Out...); err != nil {
+		return n.fail(err)
+	}
+	return nil
+}
+
+// expireState discards the state of expired windows, if supported.
+func (n *ParDo) expireState(watermark typex.EventTime) error {
+	expirer, ok := n.State.(StateExpirer)
+	if !ok {
+		return nil
+	}
+	// Pending mutations are written first, so they do not outlive the state.
+	if err := n.cache.Flush(); err != nil {
+		return err
+	}
+	return expirer.Expire(watermark, n.wfn)
+}
+
+// withState returns a context with the state of the given key and window
+// and, if known, the processing time.
+func (n *ParDo) withState(ctx context.Context, key interface{}, w typex.Window) (context.Context, error) {
+	id, err := n.encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !n.now.ProcessingTime.IsZero() {
+		ctx = timers.SetNow(ctx, n.now.ProcessingTime)
+	}
+	return state.SetProvider(ctx, n.cache.Provider(id, key, w)), nil
+}
+
+// mergeWindow merges the window into the active windows of the key, if the
+// input windows are merging, and returns the merged window that holds the
+// state. Other windows are returned unchanged.
+func (n *ParDo) mergeWindow(key interface{}, w typex.Window) (typex.Window, error) {
+	if n.merger == nil || w == nil {
+		return w, nil
+	}
+	iw, ok := w.(window.IntervalWindow)
+	if !ok {
+		return nil, fmt.Errorf("cannot merge window %v: not an interval window", w)
+	}
+	id, err := n.encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merging moves state in the store, so pending mutations are written first.
+	if err := n.cache.Flush(); err != nil {
+		return nil, err
+	}
+	return n.merger.MergeWindow(id, key, iw, n.wfn, n.mergeState)
+}
+
+// mergeState combines the values of a state cell of merged windows. Bags are
+// concatenated and ordered lists are merged by timestamp. Other cells keep
+// the value of the window merged into.
+func (n *ParDo) mergeState(cell string, into, from interface{}) interface{} {
+	switch {
+	case n.bags[cell]:
+		a, b := into.([]interface{}), from.([]interface{})
+		return append(append([]interface{}(nil), a...), b...)
+	case n.lists[cell]:
+		return state.MergeOrderedLists(into.([]state.TimestampedValue), from.([]state.TimestampedValue))
+	default:
+		return into
+	}
+}
+
+func (n *ParDo) encodeKey(key interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := n.keyEnc.Encode(FullValue{Elm: key}, &buf); err != nil {
+		return "", fmt.Errorf("failed to encode state key %v: %v", key, err)
+	}
+	return buf.String(), nil
+}
+
+// stateWindow returns the window that partitions the state of an element in
+// the given windows, or nil for the global window.
+func stateWindow(ws []typex.Window) typex.Window {
+	if len(ws) == 0 {
+		return nil
+	}
+	return ws[0]
+}
+
+// fireTimers invokes OnTimer for all timers that are due at the given time,
+// in order, including timers set while firing. Event-time timers fire at the
+// time they are set to. Processing-time timers fire at the last watermark,
+// but no later than the end of their window.
+func (n *ParDo) fireTimers(ctx context.Context, t Time) error {
+	ctx = metrics.SetPTransformID(ctx, n.PID)
+	for {
+		timer, ok := n.State.NextTimer(t)
+		if !ok {
+			return nil
+		}
+		ctx, err := n.withState(ctx, timer.Key, timer.Window)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range n.reservers {
+			r.Reserve(n.capacity)
+		}
+
+		ts := timer.At
+		if timer.ProcessingTime {
+			ts = n.now.Watermark
+			if w := timer.Window; w != nil && time.Time(ts).After(time.Time(w.MaxTimestamp())) {
+				ts = w.MaxTimestamp()
+			}
+		}
+
+		n.input, n.windows = &ts, nil
+		if timer.Window != nil {
+			n.windows = []typex.Window{timer.Window}
+		}
+		var name interface{} = timer.Name
+		if n.fired {
+			name = timers.Fired{Key: timer.Name, Tag: timer.Tag}
+		}
+		val, err := n.invokeDataFn(ctx, ts, n.Fn.OnTimerFn(), &MainInput{Key: FullValue{Elm: timer.Key, Elm2: name, Timestamp: ts, Windows: n.windows}})
+		windows := n.windows
+		n.input, n.windows = nil, nil
+		if err != nil {
+			return err
+		}
+		if val != nil {
+			if err := n.checkTimestamp(val, ts); err != nil {
+				return err
+			}
+			val.Windows = windows
+			n.countOutput(0)
+			if err := n.Out[0].ProcessElement(ctx, *val); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (n *ParDo) Down(ctx context.Context) error {
 	if n.status == Down {
 		return n.err.Error()
@@ -149,7 +496,11 @@ func (n *ParDo) initIfNeeded() error {
 	if err != nil {
 		return n.fail(err)
 	}
-	n.emitters, err = makeEmitters(n.Fn.ProcessElementFn(), n.Out)
+	var out []ElementProcessor
+	for i, o := range n.Out {
+		out = append(out, &skewCheck{n: n, index: i, out: o})
+	}
+	n.emitters, err = makeEmitters(n.Fn.ProcessElementFn(), out)
 	if err != nil {
 		return n.fail(err)
 	}
@@ -162,28 +513,85 @@ func (n *ParDo) initIfNeeded() error {
 	return err
 }
 
+// bindSideInputs binds the side inputs read through the State API to the
+// bundle. Their values are read again for each bundle after the first, which
+// reads them in initIfNeeded.
+func (n *ParDo) bindSideInputs(ctx context.Context, id string, data DataManager) error {
+	bound := false
+	for _, s := range n.Side {
+		if s, ok := s.(*StateSideInput); ok {
+			if err := s.bind(ctx, id, data); err != nil {
+				return err
+			}
+			bound = true
+		}
+	}
+	if !bound || !n.ready {
+		return nil
+	}
+
+	side, err := makeSideInputs(n.Fn.ProcessElementFn(), n.Inbound, n.Side)
+	if err != nil {
+		return err
+	}
+	n.sideinput = side
+	for i, s := range side {
+		n.extra[i] = s.Value()
+	}
+	return nil
+}
+
 func (n *ParDo) invokeDataFn(ctx context.Context, ts typex.EventTime, fn *funcx.Fn, opt *MainInput) (*FullValue, error) {
+	val, _, err := n.invokeProcessFn(ctx, ts, fn, opt)
+	return val, err
+}
+
+// invokeProcessFn is invokeDataFn, but also returns the process continuation
+// returned by the fn, if any.
+func (n *ParDo) invokeProcessFn(ctx context.Context, ts typex.EventTime, fn *funcx.Fn, opt *MainInput) (*FullValue, sdf.ProcessContinuation, error) {
+	var cont sdf.ProcessContinuation
 	if fn == nil {
-		return nil, nil
+		return nil, cont, nil
 	}
 
 	for _, e := range n.emitters {
 		if err := e.Init(ctx, ts); err != nil {
-			return nil, err
+			return nil, cont, err
 		}
 	}
 	for _, s := range n.sideinput {
 		if err := s.Init(); err != nil {
-			return nil, err
+			return nil, cont, err
 		}
 	}
-	val, err := Invoke(ctx, fn, opt, n.extra...)
+	val, cont, err := invoke(ctx, fn, opt, n.extra...)
 	for _, s := range n.sideinput {
 		if err := s.Reset(); err != nil {
-			return nil, err
+			return nil, cont, err
 		}
 	}
-	return val, err
+	return val, cont, err
+}
+
+// skewCheck applies the skew policy of a ParDo to the elements emitted during
+// ProcessElement. Emitted elements are placed in the windows of the input.
+type skewCheck struct {
+	n     *ParDo
+	index int
+	out   ElementProcessor
+}
+
+func (c *skewCheck) ProcessElement(ctx context.Context, elm FullValue, values ...ReStream) error {
+	if c.n.input != nil {
+		if err := c.n.checkTimestamp(&elm, *c.n.input); err != nil {
+			return err
+		}
+	}
+	if elm.Windows == nil {
+		elm.Windows = c.n.windows
+	}
+	c.n.countOutput(c.index)
+	return c.out.ProcessElement(ctx, elm, values...)
 }
 
 func (n *ParDo) fail(err error) error {
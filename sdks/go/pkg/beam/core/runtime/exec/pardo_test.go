@@ -17,7 +17,10 @@ package exec
 
 import (
 	"context"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
@@ -95,3 +98,169 @@ func TestParDo(t *testing.T) {
 		t.Errorf("pardo(sumFn) side input = %v, want %v", extractValues(sum.Elements...), extractValues(expectedSum...))
 	}
 }
+
+type fanOutFn struct {
+	N int
+}
+
+func (f *fanOutFn) OutputCapacity() int {
+	return f.N
+}
+
+func (f *fanOutFn) ProcessElement(elm int, emit func(int)) {
+	for i := 0; i < f.N; i++ {
+		emit(elm)
+	}
+}
+
+// reserveNode is a CaptureNode that records capacity hints.
+type reserveNode struct {
+	CaptureNode
+	Reserved []int
+}
+
+func (n *reserveNode) Reserve(elms int) {
+	n.Reserved = append(n.Reserved, elms)
+}
+
+type pairFn struct{}
+
+func (f *pairFn) ProcessElement(elm int, emit func(int)) {
+	emit(elm)
+	emit(elm)
+}
+
+// TestParDoOutputCapacity verifies that the output capacity hint of a DoFn is
+// passed to downstream nodes before each element.
+func TestParDoOutputCapacity(t *testing.T) {
+	graph.RegisterOutputCapacity(&pairFn{}, 2)
+
+	tests := []struct {
+		fn   interface{}
+		want []int
+	}{
+		{&fanOutFn{N: 3}, []int{3, 3}},
+		{&pairFn{}, []int{2, 2}},
+	}
+
+	for _, test := range tests {
+		out := runFanOut(t, test.fn)
+		if len(out.Elements) != 2*test.want[0] {
+			t.Errorf("pardo(%T) = %v, want %v elements", test.fn, extractValues(out.Elements...), 2*test.want[0])
+		}
+		if !reflect.DeepEqual(out.Reserved, test.want) {
+			t.Errorf("pardo(%T) reserved %v, want %v", test.fn, out.Reserved, test.want)
+		}
+	}
+}
+
+func runFanOut(t *testing.T, dofn interface{}) *reserveNode {
+	fn, err := graph.NewDoFn(dofn)
+	if err != nil {
+		t.Fatalf("invalid function: %v", err)
+	}
+
+	g := graph.New()
+	nN := g.NewNode(typex.New(reflectx.Int), window.NewGlobalWindow())
+
+	edge, err := graph.NewParDo(g, g.Root(), fn, []*graph.Node{nN}, nil)
+	if err != nil {
+		t.Fatalf("invalid pardo: %v", err)
+	}
+
+	out := &reserveNode{CaptureNode: CaptureNode{UID: 1}}
+	pardo := &ParDo{UID: 2, Fn: edge.DoFn, Inbound: edge.Input, Out: []Node{out}}
+	n := &FixedRoot{UID: 3, Elements: makeValues(1, 2), Out: pardo}
+
+	p, err := NewPlan("a", []Unit{n, pardo, out})
+	if err != nil {
+		t.Fatalf("failed to construct plan: %v", err)
+	}
+
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if err := p.Down(context.Background()); err != nil {
+		t.Fatalf("down failed: %v", err)
+	}
+	return out
+}
+
+type shiftFn struct {
+	Shift, Skew time.Duration
+}
+
+func (f *shiftFn) AllowedTimestampSkew() time.Duration {
+	return f.Skew
+}
+
+func (f *shiftFn) ProcessElement(ts typex.EventTime, elm int, emit func(typex.EventTime, int)) {
+	emit(typex.EventTime(time.Time(ts).Add(-f.Shift)), elm)
+}
+
+func backdateFn(ts typex.EventTime, elm int) (typex.EventTime, int) {
+	return typex.EventTime(time.Time(ts).Add(-time.Hour)), elm
+}
+
+func uncheckedBackdateFn(ts typex.EventTime, elm int) (typex.EventTime, int) {
+	return typex.EventTime(time.Time(ts).Add(-time.Hour)), elm
+}
+
+// TestParDoTimestampSkew verifies that output timestamps earlier than the input
+// timestamp are handled according to the skew policy of the DoFn.
+func TestParDoTimestampSkew(t *testing.T) {
+	graph.RegisterTimestampSkew(backdateFn, 10*time.Minute, graph.SkewClamp)
+
+	base := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		fn   interface{}
+		want time.Time
+		err  string
+	}{
+		{&shiftFn{Shift: 0}, base, ""},
+		{&shiftFn{Shift: time.Minute}, time.Time{}, "output timestamps must be no earlier"},
+		{&shiftFn{Shift: time.Minute, Skew: time.Hour}, base.Add(-time.Minute), ""},
+		{backdateFn, base.Add(-10 * time.Minute), ""},
+		{uncheckedBackdateFn, base.Add(-time.Hour), ""}, // not checked by default
+	}
+
+	for _, test := range tests {
+		fn, err := graph.NewDoFn(test.fn)
+		if err != nil {
+			t.Fatalf("invalid function: %v", err)
+		}
+
+		g := graph.New()
+		nN := g.NewNode(typex.New(reflectx.Int), window.NewGlobalWindow())
+
+		edge, err := graph.NewParDo(g, g.Root(), fn, []*graph.Node{nN}, nil)
+		if err != nil {
+			t.Fatalf("invalid pardo: %v", err)
+		}
+
+		out := &CaptureNode{UID: 1}
+		pardo := &ParDo{UID: 2, Fn: edge.DoFn, Inbound: edge.Input, Out: []Node{out}}
+		n := &FixedRoot{UID: 3, Elements: []FullValue{{Elm: 1, Timestamp: typex.EventTime(base)}}, Out: pardo}
+
+		p, err := NewPlan("a", []Unit{n, pardo, out})
+		if err != nil {
+			t.Fatalf("failed to construct plan: %v", err)
+		}
+
+		err = p.Execute(context.Background(), "1", nil)
+		p.Down(context.Background())
+
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("pardo(%v) failed with %v, want error containing %q", fn.Name(), err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("pardo(%v) failed: %v", fn.Name(), err)
+		}
+		if len(out.Elements) != 1 || !time.Time(out.Elements[0].Timestamp).Equal(test.want) {
+			t.Errorf("pardo(%v) = %v, want timestamp %v", fn.Name(), out.Elements, test.want)
+		}
+	}
+}
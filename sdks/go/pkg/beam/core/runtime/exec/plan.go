@@ -34,8 +34,11 @@ type Plan struct {
 	roots    []Root
 	units    []Unit
 	parDoIds []string
+	dataIds  []string
+	pardos   []*ParDo
 
-	status Status
+	status  Status
+	sampler Sampler
 
 	// TODO: there can be more than 1 DataSource in a bundle.
 	source *DataSource
@@ -45,7 +48,8 @@ type Plan struct {
 func NewPlan(id string, units []Unit) (*Plan, error) {
 	var roots []Root
 	var source *DataSource
-	var pardoIDs []string
+	var pardoIDs, dataIDs []string
+	var pardos []*ParDo
 
 	for _, u := range units {
 		if u == nil {
@@ -56,9 +60,14 @@ func NewPlan(id string, units []Unit) (*Plan, error) {
 		}
 		if s, ok := u.(*DataSource); ok {
 			source = s
+			dataIDs = append(dataIDs, s.Target.ID)
 		}
 		if p, ok := u.(*ParDo); ok {
 			pardoIDs = append(pardoIDs, p.PID)
+			pardos = append(pardos, p)
+		}
+		if s, ok := u.(*DataSink); ok {
+			dataIDs = append(dataIDs, s.Target.ID)
 		}
 	}
 	if len(roots) == 0 {
@@ -71,6 +80,8 @@ func NewPlan(id string, units []Unit) (*Plan, error) {
 		roots:    roots,
 		units:    units,
 		parDoIds: pardoIDs,
+		dataIds:  dataIDs,
+		pardos:   pardos,
 		source:   source,
 	}, nil
 }
@@ -85,6 +96,7 @@ func (p *Plan) ID() string {
 // be reused for further bundles. Does not panic. Blocking.
 func (p *Plan) Execute(ctx context.Context, id string, manager DataManager) error {
 	ctx = metrics.SetBundleID(ctx, p.id)
+	ctx = withSampler(ctx, &p.sampler)
 	if p.status == Initializing {
 		for _, u := range p.units {
 			if err := callNoPanic(ctx, u.Up); err != nil {
@@ -130,6 +142,10 @@ func (p *Plan) Down(ctx context.Context) error {
 	if p.status == Down {
 		return nil // ok: already down
 	}
+	if p.status == Initializing {
+		p.status = Down
+		return nil // ok: never brought up, so nothing to tear down
+	}
 	p.status = Down
 
 	var errs []error
@@ -157,6 +173,17 @@ func (p *Plan) String() string {
 	return fmt.Sprintf("Plan[%v]:\n%v", p.ID(), strings.Join(units, "\n"))
 }
 
+// Sample returns a snapshot of the element being processed by the plan, if
+// any. It is safe to call while the plan executes.
+func (p *Plan) Sample() Sample {
+	return p.sampler.Sample()
+}
+
+// Progress returns a snapshot of input progress of the plan.
+func (p *Plan) Progress() ProgressReportSnapshot {
+	return p.source.Progress()
+}
+
 // Metrics returns a snapshot of input progress of the plan, and associated metrics.
 func (p *Plan) Metrics() *fnpb.Metrics {
 	snapshot := p.source.Progress()
@@ -178,7 +205,93 @@ func (p *Plan) Metrics() *fnpb.Metrics {
 			User: metrics.ToProto(p.id, pt),
 		}
 	}
+	// ParDos report the elements processed and output in the bundle so far.
+	for _, n := range p.pardos {
+		addElementCounts(transforms[n.PID], n.ElementCounts())
+	}
+	// Data plane transforms report the shuffle bytes read or written.
+	for _, pt := range p.dataIds {
+		if t, ok := transforms[pt]; ok {
+			t.User = metrics.ToProto(p.id, pt)
+			continue
+		}
+		transforms[pt] = &fnpb.Metrics_PTransform{
+			User: metrics.ToProto(p.id, pt),
+		}
+	}
 	return &fnpb.Metrics{
 		Ptransforms: transforms,
 	}
 }
+
+// Drain marks the drainable units of the plan as draining, so that they
+// reduce their remaining work. It may be called while the plan executes.
+func (p *Plan) Drain() {
+	for _, u := range p.units {
+		if d, ok := u.(Drainable); ok {
+			d.Drain()
+		}
+	}
+}
+
+// Checkpoint collects the work deferred by the units of the plan in the
+// last bundle as the residual roots of a split. It returns nil, if no work
+// was deferred.
+func (p *Plan) Checkpoint() (*fnpb.BundleSplit, error) {
+	var ret *fnpb.BundleSplit
+	for _, u := range p.units {
+		c, ok := u.(Checkpointer)
+		if !ok {
+			continue
+		}
+		list, err := c.Checkpoint()
+		if err != nil {
+			return nil, fmt.Errorf("failed to checkpoint %v: %v", u.ID(), err)
+		}
+		for _, res := range list {
+			if ret == nil {
+				ret = &fnpb.BundleSplit{}
+			}
+			ret.ResidualRoots = append(ret.ResidualRoots, &fnpb.BundleSplit_Application{
+				PtransformId: res.PTransformID,
+				InputId:      res.InputID,
+				Element:      res.Residual,
+			})
+		}
+	}
+	return ret, nil
+}
+
+// Split asks the splittable units of the plan to split off the unstarted
+// work of the current bundle, keeping the given fraction of it. It returns
+// nil, if no work was split off. It may be called while the plan executes.
+func (p *Plan) Split(fraction float64) (*fnpb.BundleSplit, error) {
+	var ret *fnpb.BundleSplit
+	for _, u := range p.units {
+		s, ok := u.(Splittable)
+		if !ok {
+			continue
+		}
+		res, err := s.Split(fraction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split %v: %v", u.ID(), err)
+		}
+		if res == nil {
+			continue
+		}
+		if ret == nil {
+			ret = &fnpb.BundleSplit{}
+		}
+		ret.PrimaryRoots = append(ret.PrimaryRoots, &fnpb.BundleSplit_Application{
+			PtransformId: res.PTransformID,
+			InputId:      res.InputID,
+			Element:      res.Primary,
+		})
+		ret.ResidualRoots = append(ret.ResidualRoots, &fnpb.BundleSplit_Application{
+			PtransformId: res.PTransformID,
+			InputId:      res.InputID,
+			Element:      res.Residual,
+		})
+	}
+	return ret, nil
+}
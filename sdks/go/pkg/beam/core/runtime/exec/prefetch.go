@@ -0,0 +1,198 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+)
+
+// PrefetchElements is the maximum number of elements that DataSource reads
+// and decodes ahead of processing. Elements are decoded concurrently with the
+// processing of earlier ones, which hides the latency of the data plane. A
+// non-positive value disables prefetching. Grouped input is not prefetched.
+// The harness sets it from the "prefetch_elements" pipeline option, if
+// present.
+var PrefetchElements = 1000
+
+// PrefetchBytes is the maximum encoded size in bytes of the elements that
+// DataSource decodes ahead of processing. At least one element is prefetched
+// regardless of its size. A non-positive value bounds the prefetched elements
+// by number only. The harness sets it from the "prefetch_bytes" pipeline
+// option, if present.
+var PrefetchBytes int64 = 16 << 20
+
+// prefetched is a batch of decoded elements and their encoded size.
+type prefetched struct {
+	elms []FullValue
+	size int64
+}
+
+// prefetcher passes batches of decoded elements from a decoding goroutine to
+// the processing one. The elements in flight are bounded in number and size.
+type prefetcher struct {
+	maxElms  int
+	maxBytes int64 // if positive
+	out      chan prefetched
+	stop     chan struct{}
+	err      error // set before out is closed
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	elms    int   // in flight
+	bytes   int64 // in flight
+	stopped bool
+}
+
+func newPrefetcher(elms int, bytes int64) *prefetcher {
+	p := &prefetcher{maxElms: elms, maxBytes: bytes, out: make(chan prefetched, elms), stop: make(chan struct{})}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// full returns whether a batch of the given number and size of elements
+// exceeds the capacity. A batch is always accepted if none are in flight.
+func (p *prefetcher) full(elms int, size int64) bool {
+	if p.elms == 0 {
+		return false
+	}
+	return p.elms+elms > p.maxElms || (p.maxBytes > 0 && p.bytes+size > p.maxBytes)
+}
+
+// put passes a batch of elements to the processing goroutine, blocking while
+// the prefetched elements are at capacity. It returns false if stopped.
+func (p *prefetcher) put(elms []FullValue, size int64) bool {
+	p.mu.Lock()
+	for p.full(len(elms), size) && !p.stopped {
+		p.cond.Wait()
+	}
+	stopped := p.stopped
+	p.elms += len(elms)
+	p.bytes += size
+	p.mu.Unlock()
+
+	if stopped {
+		return false
+	}
+	select {
+	case p.out <- prefetched{elms: elms, size: size}:
+		return true
+	case <-p.stop:
+		return false
+	}
+}
+
+// release frees the capacity of a processed batch.
+func (p *prefetcher) release(b prefetched) {
+	p.mu.Lock()
+	p.elms -= len(b.elms)
+	p.bytes -= b.size
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+// close stops the decoding goroutine, which returns once its current read,
+// if any, completes.
+func (p *prefetcher) close() {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.stop)
+	}
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// processPrefetched decodes the elements of the reader on a separate
+// goroutine and processes them as they become available.
+func (n *DataSource) processPrefetched(ctx context.Context, c *coder.Coder, wd WindowDecoder, r io.Reader) error {
+	p := newPrefetcher(PrefetchElements, PrefetchBytes)
+	defer p.close()
+
+	go n.prefetch(ctx, p, c, wd, newRecordingReader(r))
+
+	for b := range p.out {
+		p.release(b)
+		for _, elm := range b.elms {
+			atomic.AddInt64(&n.count, 1)
+			if err := n.Out.ProcessElement(ctx, elm); err != nil {
+				return err
+			}
+		}
+	}
+	return p.err
+}
+
+// prefetch decodes the elements of the reader and passes them to the
+// prefetcher, until the input is exhausted, decoding fails or the prefetcher
+// is closed. The elements are passed in batches of those that could be
+// decoded without blocking on the reader, such as the elements of a chunk
+// received on the data plane, which amortizes the handoff per element.
+func (n *DataSource) prefetch(ctx context.Context, p *prefetcher, c *coder.Coder, wd WindowDecoder, rr *recordingReader) {
+	defer close(p.out)
+	defer n.reportRead(ctx, rr)
+
+	var batch []FullValue
+	var size int64
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		ok := p.put(batch, size)
+		batch, size = nil, 0
+		return ok
+	}
+	defer flush()
+
+	ec := MakeElementDecoder(c)
+	for {
+		if rr.Buffered() == 0 || len(batch) >= p.maxElms {
+			// Pass on the batch, before blocking on the reader.
+			if !flush() {
+				return
+			}
+		}
+
+		start := rr.n
+		ws, t, err := DecodeWindowedValueHeader(wd, rr)
+		if err != nil {
+			if err != io.EOF {
+				p.err = fmt.Errorf("source failed: %v", err)
+			}
+			return
+		}
+
+		rr.Mark()
+		elm, err := ec.Decode(rr)
+		if err != nil {
+			if err := handleDecodeError(ctx, n.decodeError(c, rr, err)); err != nil {
+				p.err = err
+				return
+			}
+			continue // skip: handled
+		}
+		elm.Timestamp = t
+		elm.Windows = ws
+
+		n.sizes.sample(rr.n - start)
+		batch = append(batch, elm)
+		size += rr.n - start
+	}
+}
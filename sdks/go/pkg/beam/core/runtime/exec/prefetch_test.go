@@ -0,0 +1,144 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// failNode fails processing the element at the given index.
+type failNode struct {
+	CaptureNode
+	At int
+}
+
+func (n *failNode) ProcessElement(ctx context.Context, elm FullValue, values ...ReStream) error {
+	if len(n.Elements) == n.At {
+		return errors.New("processing failed")
+	}
+	return n.CaptureNode.ProcessElement(ctx, elm, values...)
+}
+
+func varIntData(elms []interface{}) fixedData {
+	enc := MakeElementEncoder(coder.NewVarInt())
+	var buf bytes.Buffer
+	for _, elm := range elms {
+		EncodeWindowedValueHeader(MakeWindowEncoder(coder.NewGlobalWindow()), nil, typex.EventTime(time.Unix(1, 0)), &buf)
+		enc.Encode(FullValue{Elm: elm}, &buf)
+	}
+	return fixedData(buf.Bytes())
+}
+
+func TestDataSourcePrefetch(t *testing.T) {
+	defer func(elms int, bytes int64) { PrefetchElements, PrefetchBytes = elms, bytes }(PrefetchElements, PrefetchBytes)
+
+	var want []interface{}
+	for i := int32(0); i < 50; i++ {
+		want = append(want, i)
+	}
+	data := varIntData(want)
+
+	tests := []struct {
+		elms  int
+		bytes int64
+	}{
+		{0, 0}, // disabled
+		{1, 0},
+		{3, 1}, // a single element at a time
+		{1000, 16 << 20},
+	}
+	for _, test := range tests {
+		PrefetchElements, PrefetchBytes = test.elms, test.bytes
+
+		out, err := runSource(coder.NewVarInt(), data)
+		if err != nil {
+			t.Fatalf("prefetch(%v, %v): Execute failed: %v", test.elms, test.bytes, err)
+		}
+		var got []interface{}
+		for _, elm := range out.Elements {
+			got = append(got, elm.Elm)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("prefetch(%v, %v) = %v, want %v", test.elms, test.bytes, got, want)
+		}
+
+		// A failure stops the prefetching.
+
+		fail := &failNode{CaptureNode: CaptureNode{UID: 2}, At: 10}
+		source := &DataSource{UID: 1, Target: Target{ID: "read"}, Coder: coder.NewW(coder.NewVarInt(), coder.NewGlobalWindow()), Out: fail}
+		p, err := NewPlan("a", []Unit{source, fail})
+		if err != nil {
+			t.Fatalf("NewPlan failed: %v", err)
+		}
+		if err := p.Execute(context.Background(), "1", data); err == nil {
+			t.Errorf("prefetch(%v, %v): Execute succeeded, want error", test.elms, test.bytes)
+		}
+		if len(fail.Elements) != 10 {
+			t.Errorf("prefetch(%v, %v) processed %v elements before failing, want 10", test.elms, test.bytes, len(fail.Elements))
+		}
+	}
+}
+
+// bufferedReader reports all of its remaining data as buffered.
+type bufferedReader struct {
+	*bytes.Reader
+}
+
+func (r bufferedReader) Buffered() int {
+	return r.Len()
+}
+
+func TestPrefetchBatches(t *testing.T) {
+	var elms []interface{}
+	for i := int32(0); i < 50; i++ {
+		elms = append(elms, i)
+	}
+	data := varIntData(elms)
+
+	tests := []struct {
+		r       io.Reader
+		batches int
+	}{
+		{bytes.NewReader(data), 50}, // unknown buffering: one element at a time
+		{bufferedReader{bytes.NewReader(data)}, 5},
+	}
+	for _, test := range tests {
+		n := &DataSource{UID: 1, Target: Target{ID: "read"}}
+		p := newPrefetcher(10, 0)
+		go n.prefetch(context.Background(), p, coder.NewVarInt(), MakeWindowDecoder(coder.NewGlobalWindow()), newRecordingReader(test.r))
+
+		batches, count := 0, 0
+		for b := range p.out {
+			p.release(b)
+			batches++
+			count += len(b.elms)
+		}
+		if p.err != nil {
+			t.Fatalf("prefetch failed: %v", p.err)
+		}
+		if batches != test.batches || count != len(elms) {
+			t.Errorf("prefetch(%T) = %v elements in %v batches, want %v in %v", test.r, count, batches, len(elms), test.batches)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	fnpb "github.com/apache/beam/sdks/go/pkg/beam/model/fnexecution_v1"
+)
+
+// The sizes of the first sampleAll elements of a bundle are sampled, and one
+// in every sampleEvery elements after that.
+const (
+	sampleAll   = 10
+	sampleEvery = 100
+)
+
+// sizeSampler samples the encoded sizes of the elements of a bundle read or
+// written on the data plane. It is safe to snapshot while the bundle is
+// processed.
+type sizeSampler struct {
+	mu                   sync.Mutex
+	n                    int64
+	count, sum, min, max int64
+}
+
+// reset resets the sampler for a new bundle.
+func (s *sizeSampler) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n, s.count, s.sum, s.min, s.max = 0, 0, 0, 0, 0
+}
+
+// sample records the size of the next element, if sampled.
+func (s *sizeSampler) sample(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.n++
+	if s.n > sampleAll && s.n%sampleEvery != 0 {
+		return
+	}
+	if s.count == 0 || size < s.min {
+		s.min = size
+	}
+	if s.count == 0 || size > s.max {
+		s.max = size
+	}
+	s.count++
+	s.sum += size
+}
+
+// snapshot returns the distribution of the sampled sizes so far, and false if
+// no element was sampled.
+func (s *sizeSampler) snapshot() (metrics.DistributionValue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return metrics.DistributionValue{Count: s.count, Sum: s.sum, Min: s.min, Max: s.max}, s.count > 0
+}
+
+// reportSampledSizes reports the sampled sizes of the finished bundle as the
+// progress of the data plane transform of the target, by its local input or
+// output name.
+func reportSampledSizes(ctx context.Context, target Target, s *sizeSampler) {
+	d, ok := s.snapshot()
+	if !ok {
+		return
+	}
+	metrics.AddProgress(ctx, metrics.Progress{
+		PTransform:   target.ID,
+		SampledSizes: map[string]metrics.DistributionValue{target.Name: d},
+	})
+}
+
+// ElementCounts holds the elements processed and output by a ParDo in the
+// current bundle.
+type ElementCounts struct {
+	Processed int64
+	Output    []int64 // by output index
+}
+
+// mainInputName is the local name of the main input of a ParDo, as used in
+// pipeline translation.
+const mainInputName = "i0"
+
+// outputName returns the local name of the output with the given index.
+func outputName(i int) string {
+	return fmt.Sprintf("i%v", i)
+}
+
+// ElementCounts returns the elements processed and output in the current
+// bundle so far. It is safe to call while the bundle is processed.
+func (n *ParDo) ElementCounts() ElementCounts {
+	ret := ElementCounts{Processed: atomic.LoadInt64(&n.processed)}
+	for i := range n.outputs {
+		ret.Output = append(ret.Output, atomic.LoadInt64(&n.outputs[i]))
+	}
+	return ret
+}
+
+// resetCounts resets the element counts for a new bundle.
+func (n *ParDo) resetCounts() {
+	atomic.StoreInt64(&n.processed, 0)
+	for i := range n.outputs {
+		atomic.StoreInt64(&n.outputs[i], 0)
+	}
+}
+
+// countOutput counts an element output to the given output.
+func (n *ParDo) countOutput(i int) {
+	atomic.AddInt64(&n.outputs[i], 1)
+}
+
+// reportCounts reports the element counts of the finished bundle as the
+// progress of the bundle, which is kept apart from user metrics.
+func (n *ParDo) reportCounts(ctx context.Context) {
+	counts := n.ElementCounts()
+	p := metrics.Progress{
+		PTransform: n.PID,
+		Processed:  map[string]int64{mainInputName: counts.Processed},
+		Output:     map[string]int64{},
+	}
+	for i, count := range counts.Output {
+		p.Output[outputName(i)] = count
+	}
+	metrics.AddProgress(ctx, p)
+}
+
+// addElementCounts adds the element counts to the processed elements of the
+// transform. Counts of ParDos with the same transform id are summed.
+func addElementCounts(t *fnpb.Metrics_PTransform, counts ElementCounts) {
+	if t.ProcessedElements == nil {
+		t.ProcessedElements = &fnpb.Metrics_PTransform_ProcessedElements{
+			Measured: &fnpb.Metrics_PTransform_Measured{
+				InputElementCounts:  map[string]int64{},
+				OutputElementCounts: map[string]int64{},
+			},
+		}
+	}
+	m := t.ProcessedElements.Measured
+	m.InputElementCounts[mainInputName] += counts.Processed
+	for i, count := range counts.Output {
+		m.OutputElementCounts[outputName(i)] += count
+	}
+}
@@ -0,0 +1,136 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func evenFn(x int, emit func(int)) {
+	if x%2 == 0 {
+		emit(x)
+	}
+}
+
+func identityFn(x int) int {
+	return x
+}
+
+func TestElementCounts(t *testing.T) {
+	out := &CaptureNode{UID: 1}
+	inner := newTestParDo(t, 2, "identity", identityFn, out)
+	outer := newTestParDo(t, 3, "even", evenFn, inner)
+	n := &FixedRoot{UID: 4, Elements: makeValues(0, 1, 2, 3, 4, 5, 6, 7, 8, 9), Out: outer}
+
+	p, err := NewPlan("counts", []Unit{n, outer, inner, out})
+	if err != nil {
+		t.Fatalf("failed to construct plan: %v", err)
+	}
+	defer metrics.ClearBundleData(p.ID())
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	want := map[string][2]int64{"even": {10, 5}, "identity": {5, 5}}
+
+	// Progress reports the counts of the current bundle.
+
+	transforms := p.Metrics().GetPtransforms()
+	for pt, w := range want {
+		m := transforms[pt].GetProcessedElements().GetMeasured()
+		if in, out := m.GetInputElementCounts()["i0"], m.GetOutputElementCounts()["i0"]; in != w[0] || out != w[1] {
+			t.Errorf("progress of %v = %v in, %v out, want %v in, %v out", pt, in, out, w[0], w[1])
+		}
+	}
+
+	// The counts of the finished bundle are reported as its progress, and
+	// not as user metrics.
+
+	progress := map[string]metrics.Progress{}
+	for _, pr := range metrics.BundleProgress(p.ID()) {
+		progress[pr.PTransform] = pr
+	}
+	for pt, w := range want {
+		pr := progress[pt]
+		if in, out := pr.Processed["i0"], pr.Output["i0"]; in != w[0] || out != w[1] {
+			t.Errorf("progress of %v = %v in, %v out, want %v in, %v out", pt, in, out, w[0], w[1])
+		}
+	}
+	if got := metrics.BundleResults(p.ID()); len(got) != 0 {
+		t.Errorf("user metrics = %+v, want none", got)
+	}
+}
+
+func TestSizeSampler(t *testing.T) {
+	var s sizeSampler
+	for i := 0; i < 250; i++ {
+		s.sample(8)
+	}
+
+	// The first elements are sampled and then one in every 100.
+
+	if d, ok := s.snapshot(); !ok || d != (metrics.DistributionValue{Count: 12, Sum: 96, Min: 8, Max: 8}) {
+		t.Errorf("snapshot = %+v, want 12 samples of 8 bytes", d)
+	}
+
+	s.reset()
+	if d, ok := s.snapshot(); ok {
+		t.Errorf("snapshot after reset = %+v, want none", d)
+	}
+}
+
+func TestPlanSampledSizes(t *testing.T) {
+	c := coder.NewW(coder.NewVarInt(), coder.NewGlobalWindow())
+	enc := MakeElementEncoder(coder.SkipW(c))
+	var in bytes.Buffer
+	for i := int32(0); i < 20; i++ {
+		EncodeWindowedValueHeader(MakeWindowEncoder(coder.NewGlobalWindow()), nil, typex.EventTime(time.Unix(1, 0)), &in)
+		enc.Encode(FullValue{Elm: i}, &in)
+	}
+	data := &copyData{in: in.Bytes()}
+
+	sink := &DataSink{UID: 2, Target: Target{ID: "write", Name: "out"}, Coder: c}
+	source := &DataSource{UID: 1, Target: Target{ID: "read", Name: "in"}, Coder: c, Out: sink}
+	p, err := NewPlan("sizes", []Unit{source, sink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer metrics.ClearBundleData(p.ID())
+	if err := p.Execute(context.Background(), "1", data); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	// Only the first 10 elements are sampled, and the sizes are reported as
+	// progress by local input and output name.
+
+	progress := map[string]metrics.Progress{}
+	for _, pr := range metrics.BundleProgress(p.ID()) {
+		progress[pr.PTransform] = pr
+	}
+	for pt, name := range map[string]string{"read": "in", "write": "out"} {
+		d := progress[pt].SampledSizes[name]
+		if d.Count != sampleAll || d.Min <= 0 || d.Sum != d.Count*d.Min {
+			t.Errorf("sampled sizes of %v = %+v, want %v samples of equal size", pt, d, sampleAll)
+		}
+	}
+}
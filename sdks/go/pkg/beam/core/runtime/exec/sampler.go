@@ -0,0 +1,89 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package exec
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxSampleLen is the maximum length of the element of a sample.
+const maxSampleLen = 200
+
+// Sample is a snapshot of the processing of a bundle: the transform that is
+// processing an element, if any, the element and for how long.
+type Sample struct {
+	Transform string
+	Element   string
+	Duration  time.Duration
+}
+
+// sampled is the element being processed by a transform.
+type sampled struct {
+	transform string
+	elm       FullValue
+	since     time.Time
+}
+
+// Sampler records the element that is being processed in a bundle, such
+// that stuck bundles can be diagnosed. ParDos record the elements they
+// process, if the sampler is in the context of the bundle.
+type Sampler struct {
+	mu  sync.Mutex
+	cur sampled
+}
+
+// set records the element being processed and returns the prior one, which
+// is restored once the element is processed.
+func (s *Sampler) set(v sampled) sampled {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.cur
+	s.cur = v
+	return prev
+}
+
+// Sample returns a snapshot of the element being processed, if any.
+func (s *Sampler) Sample() Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cur.transform == "" {
+		return Sample{}
+	}
+	elm := fmt.Sprint(s.cur.elm.Elm)
+	if s.cur.elm.Elm2 != nil {
+		elm = fmt.Sprintf("(%v, %v)", s.cur.elm.Elm, s.cur.elm.Elm2)
+	}
+	if len(elm) > maxSampleLen {
+		elm = elm[:maxSampleLen] + "..."
+	}
+	return Sample{Transform: s.cur.transform, Element: elm, Duration: time.Since(s.cur.since)}
+}
+
+type samplerKey struct{}
+
+// withSampler returns a context with the sampler of the bundle.
+func withSampler(ctx context.Context, s *Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, s)
+}
+
+// samplerOf returns the sampler of the bundle, if any.
+func samplerOf(ctx context.Context) *Sampler {
+	s, _ := ctx.Value(samplerKey{}).(*Sampler)
+	return s
+}
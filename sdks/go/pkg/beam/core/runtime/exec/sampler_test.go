@@ -0,0 +1,83 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package exec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+var samples []Sample
+
+func sampleFn(ctx context.Context, x int) int {
+	samples = append(samples, samplerOf(ctx).Sample())
+	return x
+}
+
+func sampleEmitFn(ctx context.Context, x int, emit func(int)) {
+	emit(x + 1)
+	samples = append(samples, samplerOf(ctx).Sample())
+}
+
+func newTestParDo(t *testing.T, uid UnitID, pid string, fn interface{}, out Node) *ParDo {
+	dofn, err := graph.NewDoFn(fn)
+	if err != nil {
+		t.Fatalf("invalid function: %v", err)
+	}
+	g := graph.New()
+	in := g.NewNode(typex.New(reflectx.Int), window.NewGlobalWindow())
+	edge, err := graph.NewParDo(g, g.Root(), dofn, []*graph.Node{in}, nil)
+	if err != nil {
+		t.Fatalf("invalid pardo: %v", err)
+	}
+	return &ParDo{UID: uid, PID: pid, Fn: edge.DoFn, Inbound: edge.Input, Out: []Node{out}}
+}
+
+func TestSampler(t *testing.T) {
+	samples = nil
+
+	out := &CaptureNode{UID: 1}
+	inner := newTestParDo(t, 2, "inner", sampleFn, out)
+	outer := newTestParDo(t, 3, "outer", sampleEmitFn, inner)
+	n := &FixedRoot{UID: 4, Elements: makeValues(10), Out: outer}
+
+	p, err := NewPlan("a", []Unit{n, outer, inner, out})
+	if err != nil {
+		t.Fatalf("failed to construct plan: %v", err)
+	}
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	// The sample is that of the innermost ParDo processing an element.
+
+	want := []Sample{{Transform: "inner", Element: "11"}, {Transform: "outer", Element: "10"}}
+	if len(samples) != len(want) {
+		t.Fatalf("samples = %v, want %v", samples, want)
+	}
+	for i, s := range samples {
+		if s.Transform != want[i].Transform || s.Element != want[i].Element {
+			t.Errorf("sample %v = %v, want %v", i, s, want[i])
+		}
+	}
+	if s := p.Sample(); s.Transform != "" {
+		t.Errorf("Sample() after bundle = %v, want none", s)
+	}
+}
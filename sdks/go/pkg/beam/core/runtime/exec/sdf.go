@@ -0,0 +1,312 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// Splittable is implemented by units that can give up unstarted work of the
+// current bundle when requested by the runner, such as the unclaimed part of
+// the restriction processed by a splittable DoFn.
+type Splittable interface {
+	Unit
+	// Split splits off the remaining work of the unit, keeping the given
+	// fraction of it. It returns nil, if no work was split off. It may be
+	// called concurrently with processing.
+	Split(fraction float64) (*SplitResult, error)
+}
+
+// SplitResult is the outcome of a split: the current element with the
+// primary restriction, which the bundle keeps processing, and with the
+// residual restriction, which must be processed elsewhere. Both are encoded
+// as a windowed element followed by the restriction as JSON. Checkpoints
+// have no primary.
+type SplitResult struct {
+	PTransformID, InputID string
+	Primary, Residual     []byte
+}
+
+// Checkpointer is implemented by units that defer work of the current bundle
+// to be processed later, such as splittable DoFns that return
+// sdf.ResumeProcessingIn.
+type Checkpointer interface {
+	Unit
+	// Checkpoint returns and clears the work deferred in the current bundle.
+	Checkpoint() ([]*SplitResult, error)
+}
+
+// Drainable is implemented by units that can reduce their remaining work
+// when the pipeline is drained, such as splittable DoFns that process
+// unbounded restrictions.
+type Drainable interface {
+	Unit
+	// Drain marks the unit as draining. It may be called concurrently with
+	// processing.
+	Drain()
+}
+
+// resumption is a restriction checkpointed by a splittable DoFn, which is
+// to be processed after the given time. It holds back the watermark of the
+// output.
+type resumption struct {
+	elm    FullValue
+	values []ReStream
+	r      interface{}
+	due    time.Time
+	hold   typex.EventTime
+}
+
+// mainInputID is the local name of the main input of transforms created by
+// the marshaller.
+const mainInputID = "i0"
+
+// processRestrictions processes an element of a splittable DoFn. The initial
+// restriction of the element is split, if supported by the DoFn, and each
+// restriction is processed with its own tracker.
+func (n *ParDo) processRestrictions(ctx context.Context, elm FullValue, values ...ReStream) error {
+	initial := n.Fn.CreateInitialRestrictionFn()
+	if elm.Elm2 != nil && len(initial.Params(funcx.FnValue)) < 2 {
+		return fmt.Errorf("CreateInitialRestriction of %v must take the key and value of the main input", n.Fn.Name())
+	}
+	r, err := Invoke(ctx, initial, &MainInput{Key: elm})
+	if err != nil {
+		return err
+	}
+	rs := []interface{}{r.Elm}
+	if fn := n.Fn.SplitRestrictionFn(); fn != nil {
+		split, err := Invoke(ctx, fn, &MainInput{Key: elm}, r.Elm)
+		if err != nil {
+			return err
+		}
+		list := reflect.ValueOf(split.Elm)
+		rs = nil
+		for i := 0; i < list.Len(); i++ {
+			rs = append(rs, list.Index(i).Interface())
+		}
+	}
+
+	for _, r := range rs {
+		if err := n.processRestriction(ctx, elm, r, values...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processRestriction processes a restriction of an element with a new
+// tracker. If the DoFn asks to resume processing later, the unclaimed part
+// of the restriction is checkpointed.
+func (n *ParDo) processRestriction(ctx context.Context, elm FullValue, r interface{}, values ...ReStream) error {
+	r, err := n.truncateRestriction(ctx, elm, r)
+	if err != nil {
+		return err
+	}
+	tracker, err := Invoke(ctx, n.Fn.CreateTrackerFn(), nil, r)
+	if err != nil {
+		return err
+	}
+	rt := tracker.Elm.(sdf.RTracker)
+
+	n.mu.Lock()
+	n.rt, n.rtElm = rt, elm
+	n.mu.Unlock()
+
+	cont, err := n.process(ctx, elm, rt, values...)
+
+	n.mu.Lock()
+	n.rt = nil
+	if err == nil && cont.ShouldResume() && rt.GetError() == nil {
+		var residual interface{}
+		if _, residual, err = rt.TrySplit(0); err == nil && residual != nil {
+			hold := elm.Timestamp
+			if t, ok := cont.Watermark(); ok {
+				hold = typex.EventTime(t)
+			}
+			n.deferred = append(n.deferred, resumption{elm: elm, values: values, r: residual, due: time.Now().Add(cont.ResumeDelay()), hold: hold})
+		}
+	}
+	n.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if err := rt.GetError(); err != nil {
+		return fmt.Errorf("invalid claim for restriction %v in %v: %v", rt.GetRestriction(), n.Fn.Name(), err)
+	}
+	if !rt.IsDone() {
+		return fmt.Errorf("restriction %v not fully processed by %v: ProcessElement must claim positions until TryClaim fails or return a process continuation", rt.GetRestriction(), n.Fn.Name())
+	}
+	return nil
+}
+
+// resume processes the checkpointed restrictions in the order they are due,
+// waiting for their delay to pass, until no checkpoints remain. Time is
+// advanced downstream before each, as the watermark held back by the
+// checkpoints progresses.
+func (n *ParDo) resume(ctx context.Context) error {
+	ctx = metrics.SetPTransformID(ctx, n.PID)
+	for {
+		n.mu.Lock()
+		if len(n.deferred) == 0 {
+			n.mu.Unlock()
+			return nil
+		}
+		next := 0
+		for i, d := range n.deferred {
+			if d.due.Before(n.deferred[next].due) {
+				next = i
+			}
+		}
+		t := n.holdTime(Time{Watermark: EndOfTime, ProcessingTime: time.Now()})
+		d := n.deferred[next]
+		n.deferred = append(n.deferred[:next], n.deferred[next+1:]...)
+		draining := n.draining
+		n.mu.Unlock()
+
+		if err := MultiAdvanceTime(ctx, t, n.Out...); err != nil {
+			return err
+		}
+
+		// If draining, the restriction is resumed right away to be truncated.
+
+		if wait := time.Until(d.due); wait > 0 && !draining {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := n.processRestriction(ctx, d.elm, d.r, d.values...); err != nil {
+			return err
+		}
+	}
+}
+
+// holdTime returns the time of the output given the time of the input. The
+// watermark is held back by the checkpointed restrictions and never moves
+// backwards. It must be called with mu held.
+func (n *ParDo) holdTime(t Time) Time {
+	for _, d := range n.deferred {
+		if time.Time(d.hold).Before(time.Time(t.Watermark)) {
+			t.Watermark = d.hold
+		}
+	}
+	if time.Time(t.Watermark).Before(time.Time(n.held)) {
+		t.Watermark = n.held
+	}
+	n.held = t.Watermark
+	return t
+}
+
+// Checkpoint returns the restrictions checkpointed in the current bundle,
+// if not resumed by the ParDo itself. The runner decides when to process
+// them, as the delay cannot be reported.
+func (n *ParDo) Checkpoint() ([]*SplitResult, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var ret []*SplitResult
+	for _, d := range n.deferred {
+		r, err := n.encodeRestriction(d.elm, d.r)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &SplitResult{PTransformID: n.PID, InputID: mainInputID, Residual: r})
+	}
+	n.deferred = nil
+	return ret, nil
+}
+
+// truncateRestriction returns the restriction truncated by the DoFn, if
+// draining and supported, and the restriction unchanged otherwise.
+func (n *ParDo) truncateRestriction(ctx context.Context, elm FullValue, r interface{}) (interface{}, error) {
+	n.mu.Lock()
+	draining := n.draining
+	n.mu.Unlock()
+
+	fn := n.Fn.TruncateRestrictionFn()
+	if !draining || fn == nil {
+		return r, nil
+	}
+	truncated, err := Invoke(ctx, fn, &MainInput{Key: elm}, r)
+	if err != nil {
+		return nil, err
+	}
+	return truncated.Elm, nil
+}
+
+// Drain truncates the restrictions that are processed from now on, if the
+// DoFn is splittable and supports truncation. The restriction being
+// processed, if any, is not affected.
+func (n *ParDo) Drain() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.draining = true
+}
+
+// Split splits off the unclaimed part of the restriction being processed,
+// if any, keeping the given fraction of it.
+func (n *ParDo) Split(fraction float64) (*SplitResult, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.rt == nil {
+		return nil, nil
+	}
+	primary, residual, err := n.rt.TrySplit(fraction)
+	if err != nil || residual == nil {
+		return nil, err
+	}
+	p, err := n.encodeRestriction(n.rtElm, primary)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.encodeRestriction(n.rtElm, residual)
+	if err != nil {
+		return nil, err
+	}
+	return &SplitResult{PTransformID: n.PID, InputID: mainInputID, Primary: p, Residual: r}, nil
+}
+
+// encodeRestriction encodes the element with the given restriction.
+func (n *ParDo) encodeRestriction(elm FullValue, r interface{}) ([]byte, error) {
+	from := n.Inbound[0].From
+	var buf bytes.Buffer
+	if err := EncodeWindowedValueHeader(MakeWindowEncoder(coder.NewWindowCoder(from.Window())), elm.Windows, elm.Timestamp, &buf); err != nil {
+		return nil, err
+	}
+	if err := MakeElementEncoder(from.Coder).Encode(elm, &buf); err != nil {
+		return nil, fmt.Errorf("failed to encode element %v: %v", elm, err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode restriction %v: %v", r, err)
+	}
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,249 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/rtrackers/offsetrange"
+	fnpb "github.com/apache/beam/sdks/go/pkg/beam/model/fnexecution_v1"
+)
+
+// rangeFn emits the offsets [0, n) of each element n, split in halves.
+type rangeFn struct {
+	claimed func(int64) // called after each claim, if set
+}
+
+func (f *rangeFn) CreateInitialRestriction(n int32) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: int64(n)}
+}
+
+func (f *rangeFn) SplitRestriction(n int32, r offsetrange.Restriction) []offsetrange.Restriction {
+	return r.EvenSplits(2)
+}
+
+func (f *rangeFn) CreateTracker(r offsetrange.Restriction) *offsetrange.Tracker {
+	return offsetrange.NewTracker(r)
+}
+
+func (f *rangeFn) ProcessElement(rt *offsetrange.Tracker, n int32, emit func(int64)) {
+	for i := rt.GetRestriction().(offsetrange.Restriction).Start; rt.TryClaim(i); i++ {
+		emit(i)
+		if f.claimed != nil {
+			f.claimed(i)
+		}
+	}
+}
+
+// incompleteFn claims only the first offset of its restriction.
+type incompleteFn struct {
+	rangeFn
+}
+
+func (f *incompleteFn) ProcessElement(rt *offsetrange.Tracker, n int32, emit func(int64)) {
+	rt.TryClaim(rt.GetRestriction().(offsetrange.Restriction).Start)
+}
+
+// pollFn claims at most three offsets at a time and asks to resume the rest
+// of its restriction later.
+type pollFn struct {
+	rangeFn
+}
+
+func (f *pollFn) ProcessElement(rt *offsetrange.Tracker, n int32, emit func(int64)) (sdf.ProcessContinuation, error) {
+	start := rt.GetRestriction().(offsetrange.Restriction).Start
+	for i := start; i < start+3; i++ {
+		if !rt.TryClaim(i) {
+			return sdf.StopProcessing(), nil
+		}
+		emit(i)
+	}
+	return sdf.ResumeProcessingIn(time.Millisecond), nil
+}
+
+// truncateFn truncates each restriction to its first offset when drained.
+type truncateFn struct {
+	rangeFn
+}
+
+func (f *truncateFn) TruncateRestriction(n int32, r offsetrange.Restriction) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: r.Start, End: r.Start + 1}
+}
+
+func newRangePlan(t *testing.T, dofn interface{}) (*Plan, *CaptureNode) {
+	fn, err := graph.NewDoFn(dofn)
+	if err != nil {
+		t.Fatalf("invalid function: %v", err)
+	}
+	if !fn.IsSplittable() {
+		t.Fatalf("%v is not splittable", fn.Name())
+	}
+
+	g := graph.New()
+	in := g.NewNode(typex.New(reflectx.Int32), window.NewGlobalWindow())
+	in.Coder = coder.NewVarInt()
+	edge, err := graph.NewParDo(g, g.Root(), fn, []*graph.Node{in}, nil)
+	if err != nil {
+		t.Fatalf("invalid pardo: %v", err)
+	}
+
+	out := &CaptureNode{UID: 1}
+	pardo := &ParDo{UID: 2, PID: "range", Fn: edge.DoFn, Inbound: edge.Input, Out: []Node{out}}
+	elm := FullValue{Elm: int32(10), Windows: []typex.Window{window.SingleGlobalWindow{}}}
+	root := &FixedRoot{UID: 3, Elements: []FullValue{elm}, Out: pardo}
+
+	p, err := NewPlan("a", []Unit{root, pardo, out})
+	if err != nil {
+		t.Fatalf("failed to construct plan: %v", err)
+	}
+	return p, out
+}
+
+func TestSplittableParDo(t *testing.T) {
+	p, out := newRangePlan(t, &rangeFn{})
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	want := []interface{}{int64(0), int64(1), int64(2), int64(3), int64(4), int64(5), int64(6), int64(7), int64(8), int64(9)}
+	if got := extractValues(out.Elements...); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParDo(rangeFn) = %v, want %v", got, want)
+	}
+
+	p, _ = newRangePlan(t, &incompleteFn{})
+	if err := p.Execute(context.Background(), "1", nil); err == nil {
+		t.Errorf("ParDo(incompleteFn) succeeded, want error for unprocessed restriction")
+	}
+}
+
+func TestSplittableParDoDrain(t *testing.T) {
+	p, out := newRangePlan(t, &truncateFn{})
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if got := len(out.Elements); got != 10 {
+		t.Errorf("ParDo(truncateFn) emitted %v elements, want 10", got)
+	}
+
+	// Once drained, both halves of the restriction are truncated.
+
+	p, out = newRangePlan(t, &truncateFn{})
+	p.Drain()
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if got, want := extractValues(out.Elements...), []interface{}{int64(0), int64(5)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("drained ParDo(truncateFn) = %v, want %v", got, want)
+	}
+}
+
+func TestSplittableParDoSplit(t *testing.T) {
+	// The runner splits off all unclaimed offsets of the first half, [0, 5),
+	// after offset 1 is claimed.
+
+	fn := &rangeFn{}
+	p, out := newRangePlan(t, fn)
+	var split *fnpb.BundleSplit
+	fn.claimed = func(i int64) {
+		if i != 1 {
+			return
+		}
+		var err error
+		if split, err = p.Split(0); err != nil {
+			t.Fatalf("Split failed: %v", err)
+		}
+	}
+
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	want := []interface{}{int64(0), int64(1), int64(5), int64(6), int64(7), int64(8), int64(9)}
+	if got := extractValues(out.Elements...); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParDo(rangeFn) = %v, want %v", got, want)
+	}
+
+	if split == nil || len(split.PrimaryRoots) != 1 || len(split.ResidualRoots) != 1 {
+		t.Fatalf("Split(0) = %v, want one primary and one residual", split)
+	}
+	primary, residual := split.PrimaryRoots[0], split.ResidualRoots[0]
+	if residual.PtransformId != "range" || residual.InputId != "i0" {
+		t.Errorf("residual application = %v/%v, want range/i0", residual.PtransformId, residual.InputId)
+	}
+	if !bytes.HasSuffix(primary.Element, []byte(`{"Start":0,"End":2}`)) {
+		t.Errorf("primary element = %q, want restriction [0, 2)", primary.Element)
+	}
+	if !bytes.HasSuffix(residual.Element, []byte(`{"Start":2,"End":5}`)) {
+		t.Errorf("residual element = %q, want restriction [2, 5)", residual.Element)
+	}
+}
+
+func TestSplittableParDoCheckpoint(t *testing.T) {
+	// Each half of the restriction is checkpointed after three offsets.
+
+	p, out := newRangePlan(t, &pollFn{})
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	want := []interface{}{int64(0), int64(1), int64(2), int64(5), int64(6), int64(7)}
+	if got := extractValues(out.Elements...); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParDo(pollFn) = %v, want %v", got, want)
+	}
+
+	checkpoint, err := p.Checkpoint()
+	if err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if checkpoint == nil || len(checkpoint.PrimaryRoots) != 0 || len(checkpoint.ResidualRoots) != 2 {
+		t.Fatalf("Checkpoint() = %v, want two residuals", checkpoint)
+	}
+	for i, r := range []string{`{"Start":3,"End":5}`, `{"Start":8,"End":10}`} {
+		if elm := checkpoint.ResidualRoots[i].Element; !bytes.HasSuffix(elm, []byte(r)) {
+			t.Errorf("residual element %v = %q, want restriction %v", i, elm, r)
+		}
+	}
+	if checkpoint, _ := p.Checkpoint(); checkpoint != nil {
+		t.Errorf("second Checkpoint() = %v, want nil", checkpoint)
+	}
+
+	// If resumed by the ParDo, the checkpoints are processed at the end of
+	// the bundle.
+
+	p, out = newRangePlan(t, &pollFn{})
+	for _, u := range p.units {
+		if pardo, ok := u.(*ParDo); ok {
+			pardo.Resume = true
+		}
+	}
+	if err := p.Execute(context.Background(), "1", nil); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	want = []interface{}{int64(0), int64(1), int64(2), int64(5), int64(6), int64(7), int64(3), int64(4), int64(8), int64(9)}
+	if got := extractValues(out.Elements...); !reflect.DeepEqual(got, want) {
+		t.Errorf("resumed ParDo(pollFn) = %v, want %v", got, want)
+	}
+	if checkpoint, _ := p.Checkpoint(); checkpoint != nil {
+		t.Errorf("Checkpoint() of resumed ParDo = %v, want nil", checkpoint)
+	}
+}
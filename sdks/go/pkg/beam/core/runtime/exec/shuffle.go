@@ -0,0 +1,48 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+)
+
+// ShuffleNamespace is the metrics namespace of the data crossing fusion
+// boundaries, such as GroupByKey or Reshuffle. The metrics are reported for
+// the data plane read and write transforms of each stage, so the cost of a
+// shuffle is the bytes written by the producing stages and read by the
+// consuming stage.
+const ShuffleNamespace = "beam:shuffle"
+
+var (
+	shuffleBytesRead    = metrics.NewCounter(ShuffleNamespace, "bytes_read")
+	shuffleBytesWritten = metrics.NewCounter(ShuffleNamespace, "bytes_written")
+)
+
+// reportShuffleRead records the bytes read by a bundle from the
+// data plane for the given transform.
+func reportShuffleRead(ctx context.Context, ptransform string, bytes int64) {
+	ctx = metrics.SetPTransformID(ctx, ptransform)
+	shuffleBytesRead.Inc(ctx, bytes)
+}
+
+// reportShuffleWrite records the bytes written by a bundle to
+// the data plane for the given transform.
+func reportShuffleWrite(ctx context.Context, ptransform string, bytes int64) {
+	ctx = metrics.SetPTransformID(ctx, ptransform)
+	shuffleBytesWritten.Inc(ctx, bytes)
+}
@@ -0,0 +1,96 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// copyData is a DataManager that serves a fixed byte stream and captures
+// the written bytes.
+type copyData struct {
+	in  []byte
+	out bytes.Buffer
+}
+
+func (d *copyData) OpenRead(ctx context.Context, id StreamID) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(d.in)), nil
+}
+
+func (d *copyData) OpenWrite(ctx context.Context, id StreamID) (io.WriteCloser, error) {
+	return nopWriteCloser{&d.out}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestPlanShuffleMetrics(t *testing.T) {
+	c := coder.NewW(coder.NewVarInt(), coder.NewGlobalWindow())
+	enc := MakeElementEncoder(coder.SkipW(c))
+	var in bytes.Buffer
+	for i := int32(0); i < 10; i++ {
+		EncodeWindowedValueHeader(MakeWindowEncoder(coder.NewGlobalWindow()), nil, typex.EventTime(time.Unix(1, 0)), &in)
+		enc.Encode(FullValue{Elm: i * 1000}, &in)
+	}
+	data := &copyData{in: in.Bytes()}
+
+	sink := &DataSink{UID: 2, Target: Target{ID: "write"}, Coder: c}
+	source := &DataSource{UID: 1, Target: Target{ID: "read"}, Coder: c, Out: sink}
+	p, err := NewPlan("shuffle", []Unit{source, sink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer metrics.ClearBundleData(p.ID())
+
+	if err := p.Execute(context.Background(), "1", data); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	want := map[string]int64{
+		"read":  int64(in.Len()),
+		"write": int64(data.out.Len()),
+	}
+	if want["write"] != want["read"] {
+		t.Errorf("wrote %v bytes, want %v", want["write"], want["read"])
+	}
+	m := p.Metrics()
+	for pt, bytes := range want {
+		users := m.GetPtransforms()[pt].GetUser()
+		if len(users) != 1 {
+			t.Fatalf("metrics for %v = %v, want 1 shuffle counter", pt, users)
+		}
+		if ns := users[0].GetMetricName().GetNamespace(); ns != ShuffleNamespace {
+			t.Errorf("metric namespace for %v = %v, want %v", pt, ns, ShuffleNamespace)
+		}
+		if got := users[0].GetCounterData().GetValue(); got != bytes {
+			t.Errorf("shuffle bytes for %v = %v, want %v", pt, got, bytes)
+		}
+	}
+}
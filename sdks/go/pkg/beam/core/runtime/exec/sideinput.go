@@ -0,0 +1,113 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+)
+
+// SideInputReader is implemented by DataManagers that read side inputs through
+// the State API of the runner, such as the one of the harness.
+type SideInputReader interface {
+	// OpenSideInput opens the encoded values of the side input in the given
+	// encoded window. The stream id identifies the State API port, the
+	// transform and its side input, by local input name, and the bundle.
+	OpenSideInput(ctx context.Context, id StreamID, w []byte) (io.ReadCloser, error)
+}
+
+// StateSideInput is a side input of a ParDo in the global window that is read
+// through the State API. It is bound to each bundle before use, so that its
+// values are those of the bundle, which the reader may serve from a cache.
+type StateSideInput struct {
+	Port   Port
+	Target Target // transform and local input name of the side input
+	Coder  *coder.Coder
+
+	ctx    context.Context
+	sid    StreamID
+	reader SideInputReader
+	dec    ElementDecoder
+}
+
+// bind binds the side input to the given bundle. The DataManager of the
+// bundle must be a SideInputReader.
+func (s *StateSideInput) bind(ctx context.Context, id string, data DataManager) error {
+	r, ok := data.(SideInputReader)
+	if !ok {
+		return fmt.Errorf("side input %v of %v cannot be read: no State API", s.Target.Name, s.Target.ID)
+	}
+	if s.dec == nil {
+		s.dec = MakeElementDecoder(s.Coder)
+	}
+	s.ctx, s.reader = ctx, r
+	s.sid = StreamID{Port: s.Port, Target: s.Target, InstID: id}
+	return nil
+}
+
+// Open opens the values of the side input. Failures to read them are returned
+// by the stream.
+func (s *StateSideInput) Open() Stream {
+	// The global window encodes to no bytes.
+	r, err := s.reader.OpenSideInput(s.ctx, s.sid, nil)
+	if err != nil {
+		return &errStream{err: fmt.Errorf("failed to open side input %v: %v", s.sid, err)}
+	}
+	return &sideInputStream{r: r, br: bufio.NewReader(r), dec: s.dec, sid: s.sid}
+}
+
+// sideInputStream decodes the values of a side input.
+type sideInputStream struct {
+	r   io.ReadCloser
+	br  *bufio.Reader
+	dec ElementDecoder
+	sid StreamID
+}
+
+func (s *sideInputStream) Read() (FullValue, error) {
+	if _, err := s.br.Peek(1); err != nil {
+		if err == io.EOF {
+			return FullValue{}, io.EOF
+		}
+		return FullValue{}, fmt.Errorf("failed to read side input %v: %v", s.sid, err)
+	}
+	v, err := s.dec.Decode(s.br)
+	if err != nil {
+		return FullValue{}, fmt.Errorf("failed to decode side input %v: %v", s.sid, err)
+	}
+	return v, nil
+}
+
+func (s *sideInputStream) Close() error {
+	return s.r.Close()
+}
+
+// errStream is a Stream that fails.
+type errStream struct {
+	err error
+}
+
+func (s *errStream) Read() (FullValue, error) {
+	return FullValue{}, s.err
+}
+
+func (s *errStream) Close() error {
+	return nil
+}
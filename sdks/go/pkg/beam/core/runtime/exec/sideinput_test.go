@@ -0,0 +1,123 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+// sideInputData serves the encoded values of a side input by bundle.
+type sideInputData struct {
+	DataManager
+	values map[string][]byte // by bundle
+	opened []StreamID
+}
+
+func (d *sideInputData) OpenSideInput(ctx context.Context, id StreamID, w []byte) (io.ReadCloser, error) {
+	d.opened = append(d.opened, id)
+	return ioutil.NopCloser(bytes.NewReader(d.values[id.InstID])), nil
+}
+
+func encodeInts(t *testing.T, vs ...int32) []byte {
+	var buf bytes.Buffer
+	enc := MakeElementEncoder(coder.NewVarInt())
+	for _, v := range vs {
+		if err := enc.Encode(FullValue{Elm: v}, &buf); err != nil {
+			t.Fatalf("failed to encode %v: %v", v, err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func addSumFn(x int, side []int32, emit func(int)) {
+	for _, v := range side {
+		x += int(v)
+	}
+	emit(x)
+}
+
+func newSideInputParDo(t *testing.T, out Node) *ParDo {
+	fn, err := graph.NewDoFn(addSumFn)
+	if err != nil {
+		t.Fatalf("invalid function: %v", err)
+	}
+	g := graph.New()
+	in := g.NewNode(typex.New(reflectx.Int), window.NewGlobalWindow())
+	side := g.NewNode(typex.New(reflectx.Int32), window.NewGlobalWindow())
+	edge, err := graph.NewParDo(g, g.Root(), fn, []*graph.Node{in, side}, nil)
+	if err != nil {
+		t.Fatalf("invalid pardo: %v", err)
+	}
+	return &ParDo{UID: 2, PID: "add", Fn: edge.DoFn, Inbound: edge.Input, Out: []Node{out}, Side: []ReStream{
+		&StateSideInput{Port: Port{URL: "state"}, Target: Target{ID: "add", Name: "i1"}, Coder: coder.NewVarInt()},
+	}}
+}
+
+func TestStateSideInput(t *testing.T) {
+	out := &CaptureNode{UID: 1}
+	pardo := newSideInputParDo(t, out)
+	n := &FixedRoot{UID: 3, Elements: makeValues(1, 2), Out: pardo}
+	p, err := NewPlan("side", []Unit{n, pardo, out})
+	if err != nil {
+		t.Fatalf("failed to construct plan: %v", err)
+	}
+
+	// The side input is read for each bundle.
+
+	data := &sideInputData{values: map[string][]byte{
+		"1": encodeInts(t, 10, 20),
+		"2": encodeInts(t, 100),
+	}}
+	for _, id := range []string{"1", "2"} {
+		if err := p.Execute(context.Background(), id, data); err != nil {
+			t.Fatalf("execute of bundle %v failed: %v", id, err)
+		}
+	}
+	expected := makeValues(31, 32, 101, 102)
+	if !equalList(out.Elements, expected) {
+		t.Errorf("pardo(addSumFn) = %v, want %v", extractValues(out.Elements...), extractValues(expected...))
+	}
+	for i, id := range data.opened {
+		want := StreamID{Port: Port{URL: "state"}, Target: Target{ID: "add", Name: "i1"}, InstID: []string{"1", "2"}[i]}
+		if id != want {
+			t.Errorf("opened side input %v, want %v", id, want)
+		}
+	}
+}
+
+func TestStateSideInputNoStateAPI(t *testing.T) {
+	out := &CaptureNode{UID: 1}
+	pardo := newSideInputParDo(t, out)
+	n := &FixedRoot{UID: 3, Elements: makeValues(1), Out: pardo}
+	p, err := NewPlan("side", []Unit{n, pardo, out})
+	if err != nil {
+		t.Fatalf("failed to construct plan: %v", err)
+	}
+	if err := p.Execute(context.Background(), "1", nil); err == nil || !strings.Contains(err.Error(), "no State API") {
+		t.Errorf("execute = %v, want failure without State API", err)
+	}
+}
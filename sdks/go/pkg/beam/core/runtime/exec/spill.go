@@ -0,0 +1,161 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// SpillThreshold is the size in bytes of the encoded values of a single key
+// of a grouped input, above which DataSource writes further values to a local
+// temporary file instead of holding them in memory. It allows hot keys to
+// degrade gracefully instead of running out of memory. A non-positive value
+// disables spilling. The harness sets it from the "spill_threshold_mb"
+// pipeline option, if present.
+var SpillThreshold int64 = 256 << 20
+
+// SpillDir is the directory of spill files. If empty, the default directory
+// for temporary files is used. The harness sets it from the "spill_dir"
+// pipeline option, if present.
+var SpillDir string
+
+// spillBuffer holds the values of a single key. Values are kept in memory
+// until their encoded size exceeds the threshold, after which they are
+// written to a temporary file.
+type spillBuffer struct {
+	enc       ElementEncoder
+	dec       ElementDecoder
+	threshold int64
+
+	buf  []FullValue
+	size int64 // encoded size of the values in memory
+
+	file    *os.File
+	w       *bufio.Writer
+	spilled int64 // number of values in the file
+}
+
+func newSpillBuffer(enc ElementEncoder, dec ElementDecoder, threshold int64) *spillBuffer {
+	return &spillBuffer{enc: enc, dec: dec, threshold: threshold}
+}
+
+// Add adds a value of the given encoded size.
+func (b *spillBuffer) Add(value FullValue, size int64) error {
+	if b.file == nil && (b.threshold <= 0 || b.size+size <= b.threshold) {
+		b.buf = append(b.buf, value)
+		b.size += size
+		return nil
+	}
+	if b.file == nil {
+		f, err := ioutil.TempFile(SpillDir, "beam-spill-")
+		if err != nil {
+			return fmt.Errorf("failed to create spill file: %v", err)
+		}
+		b.file = f
+		b.w = bufio.NewWriter(f)
+	}
+	if err := b.enc.Encode(value, b.w); err != nil {
+		return fmt.Errorf("failed to spill value: %v", err)
+	}
+	b.spilled++
+	return nil
+}
+
+// Spilled returns true iff any values were written to disk.
+func (b *spillBuffer) Spilled() bool {
+	return b.file != nil
+}
+
+// ReStream returns the values added so far. It must not be used after Close.
+func (b *spillBuffer) ReStream() (ReStream, error) {
+	if b.file == nil {
+		return &FixedReStream{Buf: b.buf}, nil
+	}
+	if err := b.w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush spill file: %v", err)
+	}
+	return &spillReStream{buf: b.buf, path: b.file.Name(), n: b.spilled, dec: b.dec}, nil
+}
+
+// Close removes the spill file, if any.
+func (b *spillBuffer) Close() error {
+	b.buf = nil
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	b.file.Close()
+	b.file, b.w = nil, nil
+	return os.Remove(name)
+}
+
+// spillReStream is a ReStream of in-memory values followed by values in a
+// spill file.
+type spillReStream struct {
+	buf  []FullValue
+	path string
+	n    int64
+	dec  ElementDecoder
+}
+
+func (s *spillReStream) Open() Stream {
+	return &spillStream{mem: &FixedStream{Buf: s.buf}, s: s}
+}
+
+// spillStream reads the values of a spillReStream. The file is opened once
+// the in-memory values are exhausted.
+type spillStream struct {
+	mem  *FixedStream
+	s    *spillReStream
+	f    *os.File
+	r    *bufio.Reader
+	read int64
+}
+
+func (s *spillStream) Read() (FullValue, error) {
+	if v, err := s.mem.Read(); err != io.EOF {
+		return v, err
+	}
+	if s.read == s.s.n {
+		return FullValue{}, io.EOF
+	}
+	if s.f == nil {
+		f, err := os.Open(s.s.path)
+		if err != nil {
+			return FullValue{}, fmt.Errorf("failed to open spill file: %v", err)
+		}
+		s.f = f
+		s.r = bufio.NewReader(f)
+	}
+	v, err := s.s.dec.Decode(s.r)
+	if err != nil {
+		return FullValue{}, fmt.Errorf("failed to read spilled value: %v", err)
+	}
+	s.read++
+	return v, nil
+}
+
+func (s *spillStream) Close() error {
+	s.mem.Close()
+	if s.f != nil {
+		return s.f.Close()
+	}
+	return nil
+}
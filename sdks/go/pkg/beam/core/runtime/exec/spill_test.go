@@ -0,0 +1,149 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func readAll(t *testing.T, s ReStream) []interface{} {
+	stream := s.Open()
+	defer stream.Close()
+
+	var ret []interface{}
+	for {
+		v, err := stream.Read()
+		if err == io.EOF {
+			return ret
+		}
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		ret = append(ret, v.Elm)
+	}
+}
+
+func TestSpillBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SpillDir = dir
+	defer func() { SpillDir = "" }()
+
+	c := coder.NewVarInt()
+	b := newSpillBuffer(MakeElementEncoder(c), MakeElementDecoder(c), 3)
+	var want []interface{}
+	for i := int32(0); i < 10; i++ {
+		if err := b.Add(FullValue{Elm: i}, 1); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		want = append(want, i)
+	}
+	if !b.Spilled() || len(b.buf) != 3 || b.spilled != 7 {
+		t.Errorf("buffer holds %v values in memory and %v on disk, want 3 and 7", len(b.buf), b.spilled)
+	}
+
+	s, err := b.ReStream()
+	if err != nil {
+		t.Fatalf("ReStream failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if got := readAll(t, s); !reflect.DeepEqual(got, want) {
+			t.Errorf("iteration %v = %v, want %v", i, got, want)
+		}
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("spill files remain after Close: %v", files)
+	}
+}
+
+// iterNode reads all grouped values when processing an element.
+type iterNode struct {
+	CaptureNode
+	Values [][]interface{}
+	t      *testing.T
+}
+
+func (n *iterNode) ProcessElement(ctx context.Context, elm FullValue, values ...ReStream) error {
+	n.Values = append(n.Values, readAll(n.t, values[0]))
+	return n.CaptureNode.ProcessElement(ctx, elm, values...)
+}
+
+// fixedData is a DataManager that serves a fixed byte stream.
+type fixedData []byte
+
+func (d fixedData) OpenRead(ctx context.Context, id StreamID) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(d)), nil
+}
+
+func (d fixedData) OpenWrite(ctx context.Context, id StreamID) (io.WriteCloser, error) {
+	panic("not supported")
+}
+
+func TestDataSourceSpill(t *testing.T) {
+	defer func(old int64) { SpillThreshold = old }(SpillThreshold)
+	SpillThreshold = 4
+
+	c := coder.NewVarInt()
+	enc := MakeElementEncoder(c)
+	var data bytes.Buffer
+	for key := int32(1); key <= 2; key++ {
+		EncodeWindowedValueHeader(MakeWindowEncoder(coder.NewGlobalWindow()), nil, typex.EventTime(time.Unix(1, 0)), &data)
+		enc.Encode(FullValue{Elm: key}, &data)
+		coder.EncodeInt32(int32(5*key), &data)
+		for i := int32(0); i < 5*key; i++ {
+			enc.Encode(FullValue{Elm: i}, &data)
+		}
+	}
+
+	out := &iterNode{CaptureNode: CaptureNode{UID: 2}, t: t}
+	source := &DataSource{UID: 1, Coder: coder.NewW(coder.NewCoGBK([]*coder.Coder{c, c}), coder.NewGlobalWindow()), Out: out}
+	p, err := NewPlan("a", []Unit{source, out})
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	if err := p.Execute(context.Background(), "1", fixedData(data.Bytes())); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	var want [][]interface{}
+	for key := int32(1); key <= 2; key++ {
+		var values []interface{}
+		for i := int32(0); i < 5*key; i++ {
+			values = append(values, i)
+		}
+		want = append(want, values)
+	}
+	if !reflect.DeepEqual(out.Values, want) {
+		t.Errorf("grouped values = %v, want %v", out.Values, want)
+	}
+}
@@ -0,0 +1,204 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// EndOfTime is the watermark once the input is exhausted. It is after any
+// timestamp that timers may be set to.
+var EndOfTime = typex.EventTime(time.Unix(1<<62, 0))
+
+// StateStore holds the state and timers of a stateful ParDo, partitioned by
+// key and window. Keys are identified by their encoding and the window is nil
+// for the global window. State mutations are batched per bundle, so the store
+// only sees the last mutation of each cell when the bundle finishes.
+type StateStore interface {
+	// Provider returns the state of the key with the given encoding in the
+	// given window.
+	Provider(id string, key interface{}, w typex.Window) state.Provider
+	// NextTimer removes and returns the earliest timer that is due at the
+	// given time across all keys and windows, if any. Event-time timers are
+	// due once the watermark reaches them and processing-time timers once the
+	// processing time does. Event-time timers are returned first and timers
+	// with the same time in the order they were set.
+	NextTimer(t Time) (Timer, bool)
+}
+
+// Timer is a timer that is due to fire.
+type Timer struct {
+	Key    interface{}
+	Window typex.Window // nil if global
+	Name   string       // key of the timer
+	Tag    string       // tag of the timer, if in a timer family
+	// At is the time the timer was set to. It is a processing time, if
+	// the timer is a processing-time timer.
+	At             typex.EventTime
+	ProcessingTime bool
+}
+
+// WindowMerger is implemented by StateStores that support merging windows,
+// such as sessions. It must be implemented for stateful ParDos with merging
+// input windows.
+type WindowMerger interface {
+	// MergeWindow adds the window to the active windows of the key with the
+	// given encoding and merges them using the windowing strategy. It returns
+	// the merged window that contains the given window. The state of windows
+	// merged into another is combined cell by cell using the merge function
+	// and their timers are moved, keeping the later timestamp.
+	MergeWindow(id string, key interface{}, w window.IntervalWindow, wfn *window.Window, merge StateMergeFn) (window.IntervalWindow, error)
+}
+
+// StateExpirer is implemented by StateStores that garbage collect the state
+// of expired windows.
+type StateExpirer interface {
+	// Expire discards the state and timers of all windows that the watermark
+	// has passed by more than the allowed lateness of the windowing strategy.
+	Expire(watermark typex.EventTime, wfn *window.Window) error
+}
+
+// StateMergeFn combines the values of a state cell of two windows that are
+// merged into one.
+type StateMergeFn func(cell string, into, from interface{}) interface{}
+
+// stateCache is a write-back cache of the state of a stateful ParDo for the
+// duration of a bundle. Reads are served from the cache after the first
+// access of a cell and mutations are coalesced per cell, so that the store
+// sees at most one read and one write or clear per key and cell per bundle,
+// no matter how often the DoFn accesses the state. Mutations are visible to
+// later reads in the same bundle and are written to the store on Flush.
+// Timers are not cached, because the runner must see them to fire them.
+type stateCache struct {
+	store StateStore
+	keys  map[string]*cachedState
+	order []string // keys and windows in order of first access
+}
+
+func newStateCache(store StateStore) *stateCache {
+	return &stateCache{store: store, keys: make(map[string]*cachedState)}
+}
+
+// Provider returns the cached state of the key with the given encoding in
+// the given window.
+func (c *stateCache) Provider(id string, key interface{}, w typex.Window) state.Provider {
+	ck := id
+	if w != nil {
+		ck = fmt.Sprintf("%v/%v", id, w)
+	}
+	s, ok := c.keys[ck]
+	if !ok {
+		s = &cachedState{p: c.store.Provider(id, key, w), cells: make(map[string]*cachedCell)}
+		c.keys[ck] = s
+		c.order = append(c.order, ck)
+	}
+	return s
+}
+
+// Flush writes all pending mutations to the store and empties the cache.
+func (c *stateCache) Flush() error {
+	for _, id := range c.order {
+		if err := c.keys[id].flush(); err != nil {
+			return err
+		}
+	}
+	c.keys = make(map[string]*cachedState)
+	c.order = nil
+	return nil
+}
+
+// cachedState is the cached state of a single key.
+type cachedState struct {
+	p     state.Provider
+	cells map[string]*cachedCell
+	dirty []string // cells with pending mutations, in order of first mutation
+}
+
+type cachedCell struct {
+	value interface{}
+	ok    bool // true iff the cell is non-empty
+	dirty bool
+}
+
+func (s *cachedState) Read(key string) (interface{}, bool, error) {
+	if c, ok := s.cells[key]; ok {
+		return c.value, c.ok, nil
+	}
+	value, ok, err := s.p.Read(key)
+	if err != nil {
+		return nil, false, err
+	}
+	s.cells[key] = &cachedCell{value: value, ok: ok}
+	return value, ok, nil
+}
+
+func (s *cachedState) Write(key string, value interface{}) error {
+	s.mutate(key, value, true)
+	return nil
+}
+
+func (s *cachedState) Clear(key string) error {
+	s.mutate(key, nil, false)
+	return nil
+}
+
+func (s *cachedState) mutate(key string, value interface{}, ok bool) {
+	c, exists := s.cells[key]
+	if !exists {
+		c = &cachedCell{}
+		s.cells[key] = c
+	}
+	if !c.dirty {
+		c.dirty = true
+		s.dirty = append(s.dirty, key)
+	}
+	c.value, c.ok = value, ok
+}
+
+func (s *cachedState) SetTimer(key, tag string, t typex.EventTime) error {
+	return s.p.SetTimer(key, tag, t)
+}
+
+func (s *cachedState) SetProcessingTimer(key, tag string, t time.Time) error {
+	return s.p.SetProcessingTimer(key, tag, t)
+}
+
+func (s *cachedState) ClearTimer(key, tag string) error {
+	return s.p.ClearTimer(key, tag)
+}
+
+func (s *cachedState) flush() error {
+	for _, key := range s.dirty {
+		c := s.cells[key]
+		var err error
+		if c.ok {
+			err = s.p.Write(key, c.value)
+		} else {
+			err = s.p.Clear(key)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write state %v: %v", key, err)
+		}
+		c.dirty = false
+	}
+	s.dirty = nil
+	return nil
+}
@@ -0,0 +1,121 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// countingStore is a StateStore that records all calls to its providers.
+type countingStore struct {
+	values map[string]interface{}
+	calls  []string
+}
+
+func (s *countingStore) Provider(id string, key interface{}, w typex.Window) state.Provider {
+	return &countingProvider{id: id, store: s}
+}
+
+func (s *countingStore) NextTimer(t Time) (Timer, bool) {
+	return Timer{}, false
+}
+
+type countingProvider struct {
+	id    string
+	store *countingStore
+}
+
+func (p *countingProvider) Read(key string) (interface{}, bool, error) {
+	p.store.calls = append(p.store.calls, "read "+p.id+"/"+key)
+	v, ok := p.store.values[p.id+"/"+key]
+	return v, ok, nil
+}
+
+func (p *countingProvider) Write(key string, value interface{}) error {
+	p.store.calls = append(p.store.calls, "write "+p.id+"/"+key)
+	p.store.values[p.id+"/"+key] = value
+	return nil
+}
+
+func (p *countingProvider) Clear(key string) error {
+	p.store.calls = append(p.store.calls, "clear "+p.id+"/"+key)
+	delete(p.store.values, p.id+"/"+key)
+	return nil
+}
+
+func (p *countingProvider) SetTimer(key, tag string, t typex.EventTime) error {
+	p.store.calls = append(p.store.calls, "timer "+p.id+"/"+key)
+	return nil
+}
+
+func (p *countingProvider) SetProcessingTimer(key, tag string, t time.Time) error {
+	p.store.calls = append(p.store.calls, "timer "+p.id+"/"+key)
+	return nil
+}
+
+func (p *countingProvider) ClearTimer(key, tag string) error {
+	return nil
+}
+
+func TestStateCache(t *testing.T) {
+	store := &countingStore{values: map[string]interface{}{"a/count": 5, "b/old": 1}}
+	cache := newStateCache(store)
+
+	for i := 0; i < 3; i++ {
+		p := cache.Provider("a", "a", nil)
+		v, _, _ := p.Read("count")
+		p.Write("count", v.(int)+1)
+	}
+	b := cache.Provider("b", "b", nil)
+	b.Write("old", 2)
+	b.Clear("old")
+	if _, ok, _ := b.Read("old"); ok {
+		t.Errorf("Read(old) after Clear = true, want false")
+	}
+	b.SetTimer("t", "", typex.EventTime{})
+
+	want := []string{"read a/count", "timer b/t"}
+	if !reflect.DeepEqual(store.calls, want) {
+		t.Errorf("calls before Flush = %v, want %v", store.calls, want)
+	}
+
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	want = append(want, "write a/count", "clear b/old")
+	if !reflect.DeepEqual(store.calls, want) {
+		t.Errorf("calls after Flush = %v, want %v", store.calls, want)
+	}
+	if v := store.values["a/count"]; v != 8 {
+		t.Errorf("a/count = %v, want 8", v)
+	}
+	if _, ok := store.values["b/old"]; ok {
+		t.Errorf("b/old present after Flush, want cleared")
+	}
+
+	// The cache is empty after Flush, so state is re-read from the store.
+	if v, _, _ := cache.Provider("a", "a", nil).Read("count"); v != 8 {
+		t.Errorf("Read(count) after Flush = %v, want 8", v)
+	}
+	if n := len(store.calls); store.calls[n-1] != "read a/count" {
+		t.Errorf("last call = %v, want read a/count", store.calls[n-1])
+	}
+}
@@ -67,16 +67,9 @@ func UnmarshalPlan(desc *fnpb.ProcessBundleDescriptor) (*Plan, error) {
 				return nil, err
 			}
 
-			if cid == "" {
-				u.Coder, err = b.makeCoderForPCollection(pid)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				u.Coder, err = b.coders.Coder(cid) // Expected to be windowed coder
-				if err != nil {
-					return nil, err
-				}
+			u.Coder, err = b.makePortCoder(cid, pid)
+			if err != nil {
+				return nil, err
 			}
 		}
 
@@ -157,11 +150,10 @@ func (b *builder) makeWindow(id string) (*window.Window, error) {
 	if !ok {
 		return nil, fmt.Errorf("windowing strategy %v not found", id)
 	}
-	if urn := ws.GetWindowFn().GetSpec().GetUrn(); urn != graphx.URNGlobalWindowsWindowFn {
-		return nil, fmt.Errorf("unsupported window type: %v", urn)
+	w, err := graphx.UnmarshalWindowingStrategy(ws)
+	if err != nil {
+		return nil, err
 	}
-
-	w := window.NewGlobalWindow()
 	b.windowing[id] = w
 	return w, nil
 }
@@ -191,6 +183,54 @@ func (b *builder) makeCoderForPCollection(id string) (*coder.Coder, error) {
 	return coder.SkipW(c), nil
 }
 
+// makePortCoder returns the coder for the data port of the given PCollection.
+// The port coder is expected to be a windowed coder, if present.
+func (b *builder) makePortCoder(cid, pid string) (*coder.Coder, error) {
+	if cid == "" {
+		return b.makeCoderForPCollection(pid)
+	}
+	wire, err := b.coders.Coder(cid)
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.makeCoderForPCollection(pid)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapLengthPrefixed(wire, c), nil
+}
+
+// unwrapLengthPrefixed replaces the length-prefixed bytes in a wire coder with
+// the corresponding custom coder of the PCollection. A runner may substitute
+// length-prefixed bytes for coders it does not understand. Custom coders are
+// implicitly length prefixed, so the encoding is the same, but the elements
+// must be decoded as the type the DoFns expect rather than as raw bytes.
+func unwrapLengthPrefixed(wire, c *coder.Coder) *coder.Coder {
+	switch {
+	case wire.Kind == coder.WindowedValue:
+		return coder.NewW(unwrapLengthPrefixed(wire.Components[0], coder.SkipW(c)), wire.Window)
+
+	case wire.Kind == coder.Bytes && c.Kind == coder.Custom:
+		return c
+
+	case wire.Kind != c.Kind || len(wire.Components) != len(c.Components):
+		return wire
+
+	case wire.Kind == coder.KV || wire.Kind == coder.CoGBK:
+		components := make([]*coder.Coder, len(wire.Components))
+		for i, elm := range wire.Components {
+			components[i] = unwrapLengthPrefixed(elm, c.Components[i])
+		}
+		if wire.Kind == coder.KV {
+			return coder.NewKV(components)
+		}
+		return coder.NewCoGBK(components)
+
+	default:
+		return wire
+	}
+}
+
 func (b *builder) makePCollection(id string) (Node, error) {
 	if n, exists := b.nodes[id]; exists {
 		return n, nil
@@ -308,7 +348,25 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 					break
 				}
 
-				panic("NYI: side input")
+				// Side inputs are read through the State API, by local input name.
+
+				port := Port{URL: b.desc.GetStateApiServiceDescriptor().GetUrl()}
+				for i := 1; i < len(in); i++ {
+					name := fmt.Sprintf("i%v", i)
+					pid, ok := transform.GetInputs()[name]
+					if !ok {
+						return nil, fmt.Errorf("side input %v of %v not found in %v", name, n.Fn.Name(), transform.GetInputs())
+					}
+					if w := in[i].From.Window(); w.Kind() != window.GlobalWindow {
+						return nil, fmt.Errorf("side input %v of %v in %v not supported: only the global window is", name, n.Fn.Name(), w)
+					}
+					c, err := b.makeCoderForPCollection(pid)
+					if err != nil {
+						return nil, err
+					}
+					n.Side = append(n.Side, &StateSideInput{Port: port, Target: Target{ID: id.to, Name: name}, Coder: c})
+				}
+				u = n
 
 			case graph.Combine:
 				n := &Combine{UID: b.idgen.New(), Out: out[0]}
@@ -367,6 +425,21 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 			return nil, fmt.Errorf("unexpected payload: %v", tp)
 		}
 
+	case graphx.URNWindow:
+		var wp pb.WindowIntoPayload
+		if err := proto.Unmarshal(payload, &wp); err != nil {
+			return nil, fmt.Errorf("invalid WindowInto payload for %v: %v", transform, err)
+		}
+		wfn, err := graphx.UnmarshalWindowFn(wp.GetWindowFn().GetSpec())
+		if err != nil {
+			return nil, err
+		}
+		w := &WindowInto{UID: b.idgen.New(), Fn: wfn, Out: out[0]}
+		if len(out) > 1 {
+			w.Late = out[1]
+		}
+		u = w
+
 	case urnDataSink:
 		port, cid, err := unmarshalPort(payload)
 		if err != nil {
@@ -378,16 +451,9 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 		for key, pid := range transform.GetInputs() {
 			sink.Target = Target{ID: id.to, Name: key}
 
-			if cid == "" {
-				sink.Coder, err = b.makeCoderForPCollection(pid)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				sink.Coder, err = b.coders.Coder(cid) // Expected to be windowed coder
-				if err != nil {
-					return nil, err
-				}
+			sink.Coder, err = b.makePortCoder(cid, pid)
+			if err != nil {
+				return nil, err
 			}
 		}
 		u = sink
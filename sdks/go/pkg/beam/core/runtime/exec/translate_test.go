@@ -0,0 +1,74 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/coderx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func TestUnwrapLengthPrefixed(t *testing.T) {
+	c, err := coderx.NewVarIntZ(reflectx.Int)
+	if err != nil {
+		t.Fatalf("NewVarIntZ failed: %v", err)
+	}
+	num := &coder.Coder{Kind: coder.Custom, T: typex.New(reflectx.Int), Custom: c}
+	gw := coder.NewGlobalWindow()
+
+	tests := []struct {
+		wire, pcol, want *coder.Coder
+	}{
+		{
+			// Length-prefixed bytes decode as the wrapped custom coder.
+			coder.NewW(coder.NewBytes(), gw),
+			num,
+			coder.NewW(num, gw),
+		},
+		{
+			coder.NewW(coder.NewKV([]*coder.Coder{coder.NewVarInt(), coder.NewBytes()}), gw),
+			coder.NewW(coder.NewKV([]*coder.Coder{coder.NewVarInt(), num}), gw),
+			coder.NewW(coder.NewKV([]*coder.Coder{coder.NewVarInt(), num}), gw),
+		},
+		{
+			coder.NewW(coder.NewCoGBK([]*coder.Coder{coder.NewBytes(), coder.NewBytes()}), gw),
+			coder.NewCoGBK([]*coder.Coder{num, coder.NewBytes()}),
+			coder.NewW(coder.NewCoGBK([]*coder.Coder{num, coder.NewBytes()}), gw),
+		},
+		{
+			// Genuine bytes are kept.
+			coder.NewW(coder.NewBytes(), gw),
+			coder.NewBytes(),
+			coder.NewW(coder.NewBytes(), gw),
+		},
+		{
+			// Mismatched structure keeps the wire coder.
+			coder.NewW(coder.NewVarInt(), gw),
+			num,
+			coder.NewW(coder.NewVarInt(), gw),
+		},
+	}
+
+	for _, test := range tests {
+		got := unwrapLengthPrefixed(test.wire, test.pcol)
+		if !got.Equals(test.want) {
+			t.Errorf("unwrapLengthPrefixed(%v, %v) = %v, want %v", test.wire, test.pcol, got, test.want)
+		}
+	}
+}
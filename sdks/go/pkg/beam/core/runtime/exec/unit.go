@@ -17,6 +17,9 @@ package exec
 
 import (
 	"context"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 )
 
 // UnitID is a unit identifier. Used for debugging.
@@ -67,6 +70,34 @@ type ElementProcessor interface {
 	ProcessElement(ctx context.Context, elm FullValue, values ...ReStream) error
 }
 
+// Reserver is an optional interface for nodes that can pre-size their buffers
+// for a known number of upcoming elements, such as the outputs of a DoFn with
+// an output capacity hint.
+type Reserver interface {
+	// Reserve hints that n elements are about to be processed.
+	Reserve(n int)
+}
+
+// Time is the progress of time in a bundle: the input watermark and the
+// processing time. Runners that simulate streaming, such as the direct runner
+// replaying a TestStream, advance time explicitly while processing a bundle.
+type Time struct {
+	// Watermark is the input watermark. All later input elements are
+	// expected to have timestamps after it.
+	Watermark typex.EventTime
+	// ProcessingTime is the current processing time.
+	ProcessingTime time.Time
+}
+
+// TimeAdvancer is an optional interface for nodes that observe the progress
+// of time within a bundle, such as stateful ParDos that fire event-time timers.
+// Nodes are responsible for forwarding time to downstream nodes, as
+// appropriate. Nodes that hold back their input, such as GBK, do not.
+type TimeAdvancer interface {
+	// AdvanceTime signals that time has advanced to the given time.
+	AdvanceTime(ctx context.Context, t Time) error
+}
+
 // Node represents an single-bundle processing unit. Each node contains
 // its processing continuation, notably other nodes.
 type Node interface {
@@ -62,6 +62,19 @@ func MultiFinishBundle(ctx context.Context, list ...Node) error {
 	return nil
 }
 
+// MultiAdvanceTime calls AdvanceTime on the nodes that observe time.
+// Convenience function.
+func MultiAdvanceTime(ctx context.Context, t Time, list ...Node) error {
+	for _, n := range list {
+		if a, ok := n.(TimeAdvancer); ok {
+			if err := a.AdvanceTime(ctx, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // IDs returns the unit IDs of the given nodes.
 func IDs(list ...Node) []UnitID {
 	var ret []UnitID
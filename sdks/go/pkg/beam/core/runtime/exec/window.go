@@ -0,0 +1,104 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// WindowInto places each element into the windows assigned by the window
+// function, based on the element timestamp. If Late is set, elements in
+// windows that have expired, as of the watermark, are emitted to Late in the
+// global window instead.
+type WindowInto struct {
+	UID  UnitID
+	Fn   *window.Window
+	Out  Node
+	Late Node // optional
+
+	watermark typex.EventTime
+}
+
+func (w *WindowInto) ID() UnitID {
+	return w.UID
+}
+
+func (w *WindowInto) Up(ctx context.Context) error {
+	return nil
+}
+
+func (w *WindowInto) StartBundle(ctx context.Context, id string, data DataManager) error {
+	return MultiStartBundle(ctx, id, data, w.outputs()...)
+}
+
+func (w *WindowInto) ProcessElement(ctx context.Context, elm FullValue, values ...ReStream) error {
+	if w.Fn.Kind() == window.GlobalWindow {
+		elm.Windows = nil // the global window
+	} else {
+		elm.Windows = w.Fn.AssignWindows(elm.Timestamp)
+	}
+	if w.Late == nil || elm.Windows == nil {
+		return w.Out.ProcessElement(ctx, elm, values...)
+	}
+
+	var live []typex.Window
+	for _, win := range elm.Windows {
+		if !w.Fn.IsExpired(win, w.watermark) {
+			live = append(live, win)
+		}
+	}
+	if len(live) < len(elm.Windows) {
+		late := elm
+		late.Windows = nil
+		if err := w.Late.ProcessElement(ctx, late, values...); err != nil {
+			return err
+		}
+	}
+	if len(live) == 0 {
+		return nil
+	}
+	elm.Windows = live
+	return w.Out.ProcessElement(ctx, elm, values...)
+}
+
+// AdvanceTime records the watermark and forwards the time downstream.
+func (w *WindowInto) AdvanceTime(ctx context.Context, t Time) error {
+	w.watermark = t.Watermark
+	return MultiAdvanceTime(ctx, t, w.outputs()...)
+}
+
+func (w *WindowInto) FinishBundle(ctx context.Context) error {
+	return MultiFinishBundle(ctx, w.outputs()...)
+}
+
+func (w *WindowInto) outputs() []Node {
+	if w.Late == nil {
+		return []Node{w.Out}
+	}
+	return []Node{w.Out, w.Late}
+}
+
+func (w *WindowInto) Down(ctx context.Context) error {
+	return nil
+}
+
+func (w *WindowInto) String() string {
+	return fmt.Sprintf("WindowInto[%v]. Out:%v", w.Fn, w.Out.ID())
+}
@@ -20,7 +20,6 @@ import (
 	"fmt"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
-	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx/v1"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/protox"
@@ -47,6 +46,20 @@ const (
 	urnCoGBKList   = "beam:go:coder:cogbklist:v1" // CoGBK representation. Not a coder.
 )
 
+// modelCoders are the coders that runners are expected to understand. Any
+// other coder is opaque to the runner and is wrapped in a length prefix coder,
+// so that the runner can handle its encoding as opaque bytes.
+var modelCoders = map[string]bool{
+	urnBytesCoder:           true,
+	urnVarIntCoder:          true,
+	urnLengthPrefixCoder:    true,
+	urnKVCoder:              true,
+	urnIterableCoder:        true,
+	urnWindowedValueCoder:   true,
+	urnGlobalWindow:         true,
+	urnIntervalWindowsCoder: true,
+}
+
 // MarshalCoders marshals a list of coders into model coders.
 func MarshalCoders(coders []*coder.Coder) ([]string, map[string]*pb.Coder) {
 	b := NewCoderMarshaller()
@@ -75,7 +88,7 @@ type CoderUnmarshaller struct {
 	models map[string]*pb.Coder
 
 	coders  map[string]*coder.Coder
-	windows map[string]*window.Window
+	windows map[string]*coder.WindowCoder
 }
 
 // NewCoderUnmarshaller returns a new CoderUnmarshaller.
@@ -83,7 +96,7 @@ func NewCoderUnmarshaller(m map[string]*pb.Coder) *CoderUnmarshaller {
 	return &CoderUnmarshaller{
 		models:  m,
 		coders:  make(map[string]*coder.Coder),
-		windows: make(map[string]*window.Window),
+		windows: make(map[string]*coder.WindowCoder),
 	}
 }
 
@@ -118,8 +131,8 @@ func (b *CoderUnmarshaller) Coder(id string) (*coder.Coder, error) {
 	return ret, nil
 }
 
-// Window unmarshals a window with the given id.
-func (b *CoderUnmarshaller) Window(id string) (*window.Window, error) {
+// Window unmarshals a window coder with the given id.
+func (b *CoderUnmarshaller) Window(id string) (*coder.WindowCoder, error) {
 	if w, exists := b.windows[id]; exists {
 		return w, nil
 	}
@@ -132,7 +145,12 @@ func (b *CoderUnmarshaller) Window(id string) (*window.Window, error) {
 	urn := c.GetSpec().GetSpec().GetUrn()
 	switch urn {
 	case urnGlobalWindow:
-		w := window.NewGlobalWindow()
+		w := coder.NewGlobalWindow()
+		b.windows[id] = w
+		return w, nil
+
+	case urnIntervalWindowsCoder:
+		w := coder.NewIntervalWindow()
 		b.windows[id] = w
 		return w, nil
 
@@ -210,21 +228,33 @@ func (b *CoderUnmarshaller) makeCoder(c *pb.Coder) (*coder.Coder, error) {
 		if err != nil {
 			return nil, err
 		}
-		if elm.GetSpec().GetSpec().GetUrn() != urnCustomCoder {
-			// TODO(herohde) 11/17/2017: revisit this restriction
-			return nil, fmt.Errorf("expected length prefix of custom coder only: %v", elm)
-		}
+		switch elm.GetSpec().GetSpec().GetUrn() {
+		case urnCustomCoder:
+			// Custom coders are implicitly length prefixed, so the wrapper is
+			// dropped.
+
+			var ref v1.CustomCoder
+			if err := protox.DecodeBase64(string(elm.GetSpec().GetSpec().GetPayload()), &ref); err != nil {
+				return nil, err
+			}
+			custom, err := decodeCustomCoder(&ref)
+			if err != nil {
+				return nil, err
+			}
+			t := typex.New(custom.Type)
+			return &coder.Coder{Kind: coder.Custom, T: t, Custom: custom}, nil
 
-		var ref v1.CustomCoder
-		if err := protox.DecodeBase64(string(elm.GetSpec().GetSpec().GetPayload()), &ref); err != nil {
-			return nil, err
-		}
-		custom, err := decodeCustomCoder(&ref)
-		if err != nil {
-			return nil, err
+		case urnBytesCoder:
+			// A runner may replace a coder it does not understand with
+			// length-prefixed bytes. Bytes are implicitly length prefixed.
+			// The harness decodes data port elements with the wrapped
+			// coder of the PCollection instead, if known.
+			return coder.NewBytes(), nil
+
+		default:
+			// TODO(herohde) 11/17/2017: revisit this restriction
+			return nil, fmt.Errorf("expected length prefix of custom coder or bytes only: %v", elm)
 		}
-		t := typex.New(custom.Type)
-		return &coder.Coder{Kind: coder.Custom, T: t, Custom: custom}, nil
 
 	case urnWindowedValueCoder:
 		if len(components) != 2 {
@@ -328,7 +358,7 @@ func (b *CoderMarshaller) Add(c *coder.Coder) string {
 				// TODO(BEAM-3204): coders should not have environments.
 			},
 		})
-		return b.internBuiltInCoder(urnLengthPrefixCoder, inner)
+		return b.lengthPrefixUnknown(inner)
 
 	case coder.KV:
 		comp := b.AddMulti(c.Components)
@@ -342,7 +372,7 @@ func (b *CoderMarshaller) Add(c *coder.Coder) string {
 			// TODO(BEAM-490): don't inject union coder for CoGBK.
 
 			union := b.internBuiltInCoder(urnCoGBKList, comp[1:]...)
-			value = b.internBuiltInCoder(urnLengthPrefixCoder, union)
+			value = b.lengthPrefixUnknown(union)
 		}
 
 		stream := b.internBuiltInCoder(urnIterableCoder, value)
@@ -375,13 +405,16 @@ func (b *CoderMarshaller) AddMulti(list []*coder.Coder) []string {
 }
 
 // AddWindow adds a window coder.
-func (b *CoderMarshaller) AddWindow(w *window.Window) string {
-	switch w.Kind() {
-	case window.GlobalWindow:
+func (b *CoderMarshaller) AddWindow(w *coder.WindowCoder) string {
+	switch w.Kind {
+	case coder.GlobalWindow:
 		return b.internBuiltInCoder(urnGlobalWindow)
 
+	case coder.IntervalWindow:
+		return b.internBuiltInCoder(urnIntervalWindowsCoder)
+
 	default:
-		panic(fmt.Sprintf("Unexpected window kind: %v", w.Kind()))
+		panic(fmt.Sprintf("Unexpected window kind: %v", w.Kind))
 	}
 }
 
@@ -402,6 +435,15 @@ func (b *CoderMarshaller) internBuiltInCoder(urn string, components ...string) s
 	})
 }
 
+// lengthPrefixUnknown wraps the coder with the given id in a length prefix
+// coder, unless it is a model coder understood by runners.
+func (b *CoderMarshaller) lengthPrefixUnknown(id string) string {
+	if modelCoders[b.coders[id].GetSpec().GetSpec().GetUrn()] {
+		return id
+	}
+	return b.internBuiltInCoder(urnLengthPrefixCoder, id)
+}
+
 func (b *CoderMarshaller) internCoder(coder *pb.Coder) string {
 	key := proto.MarshalTextString(coder)
 	if id, exists := b.coder2id[key]; exists {
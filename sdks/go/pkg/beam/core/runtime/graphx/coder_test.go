@@ -17,14 +17,15 @@ package graphx_test
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
-	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
 )
 
 func init() {
@@ -64,7 +65,7 @@ func TestMarshalUnmarshalCoders(t *testing.T) {
 		},
 		{
 			"W<bytes>",
-			coder.NewW(coder.NewBytes(), window.NewGlobalWindow()),
+			coder.NewW(coder.NewBytes(), coder.NewGlobalWindow()),
 		},
 		{
 			"KV<foo,bar>",
@@ -93,6 +94,53 @@ func TestMarshalUnmarshalCoders(t *testing.T) {
 	}
 }
 
+// TestMarshalLengthPrefixUnknown verifies that coders unknown to runners are
+// only referenced through length prefix coders.
+func TestMarshalLengthPrefixUnknown(t *testing.T) {
+	foo := custom("foo", reflectx.Bool)
+	bar := custom("bar", reflectx.String)
+	baz := custom("baz", reflectx.Int)
+
+	c := coder.NewW(coder.NewCoGBK([]*coder.Coder{foo, bar, baz}), coder.NewGlobalWindow())
+	ids, m := graphx.MarshalCoders([]*coder.Coder{c, foo})
+
+	isModel := func(id string) bool {
+		return strings.HasPrefix(m[id].GetSpec().GetSpec().GetUrn(), "beam:coder:")
+	}
+	for _, id := range ids {
+		if !isModel(id) {
+			t.Errorf("coder %v = %v is not a model coder", id, m[id])
+		}
+	}
+	for id, c := range m {
+		if c.GetSpec().GetSpec().GetUrn() == "beam:coder:length_prefix:v1" {
+			continue
+		}
+		for _, comp := range c.GetComponentCoderIds() {
+			if !isModel(comp) {
+				t.Errorf("component %v of coder %v = %v is not length prefixed", comp, id, m[comp])
+			}
+		}
+	}
+}
+
+// TestUnmarshalLengthPrefixBytes verifies that length-prefixed bytes, which a
+// runner may substitute for unknown coders, are decoded as bytes.
+func TestUnmarshalLengthPrefixBytes(t *testing.T) {
+	m := map[string]*pb.Coder{
+		"bytes": {Spec: &pb.SdkFunctionSpec{Spec: &pb.FunctionSpec{Urn: "beam:coder:bytes:v1"}}},
+		"lp":    {Spec: &pb.SdkFunctionSpec{Spec: &pb.FunctionSpec{Urn: "beam:coder:length_prefix:v1"}}, ComponentCoderIds: []string{"bytes"}},
+	}
+
+	coders, err := graphx.UnmarshalCoders([]string{"lp"}, m)
+	if err != nil {
+		t.Fatalf("Unmarshal(LP<bytes>) failed: %v", err)
+	}
+	if len(coders) != 1 || !coders[0].Equals(coder.NewBytes()) {
+		t.Errorf("Unmarshal(LP<bytes>) = %v, want bytes", coders)
+	}
+}
+
 func enc(in typex.T) ([]byte, error) {
 	panic("enc is fake")
 }
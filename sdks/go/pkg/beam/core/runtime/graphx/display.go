@@ -0,0 +1,140 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphx
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+// MarshalDisplayData converts the display data of a transform into the model
+// representation. Values are encoded as well-known wrapper types in Any.
+func MarshalDisplayData(id, urn string, items []graph.DisplayItem) (*pb.DisplayData, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	ret := &pb.DisplayData{}
+	for _, item := range items {
+		t, msg, err := displayValue(item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("bad display data %v: %v", item.Key, err)
+		}
+		value, err := ptypes.MarshalAny(msg)
+		if err != nil {
+			return nil, err
+		}
+		ret.Items = append(ret.Items, &pb.DisplayData_Item{
+			Id:    &pb.DisplayData_Identifier{TransformId: id, TransformUrn: urn, Key: item.Key},
+			Type:  t,
+			Value: value,
+			Label: item.Label,
+		})
+	}
+	return ret, nil
+}
+
+// UnmarshalDisplayData converts model display data into display items.
+func UnmarshalDisplayData(data *pb.DisplayData) ([]graph.DisplayItem, error) {
+	var ret []graph.DisplayItem
+	for _, item := range data.GetItems() {
+		var msg ptypes.DynamicAny
+		if err := ptypes.UnmarshalAny(item.GetValue(), &msg); err != nil {
+			return nil, fmt.Errorf("bad display data %v: %v", item.GetId().GetKey(), err)
+		}
+
+		var value interface{}
+		switch m := msg.Message.(type) {
+		case *wrappers.StringValue:
+			value = m.GetValue()
+		case *wrappers.Int64Value:
+			value = m.GetValue()
+		case *wrappers.DoubleValue:
+			value = m.GetValue()
+		case *wrappers.BoolValue:
+			value = m.GetValue()
+		case *timestamp.Timestamp:
+			t, err := ptypes.Timestamp(m)
+			if err != nil {
+				return nil, err
+			}
+			value = t
+		case *duration.Duration:
+			d, err := ptypes.Duration(m)
+			if err != nil {
+				return nil, err
+			}
+			value = d
+		default:
+			return nil, fmt.Errorf("bad display data %v: unexpected value %v", item.GetId().GetKey(), proto.MarshalTextString(m))
+		}
+		ret = append(ret, graph.DisplayItem{Key: item.GetId().GetKey(), Label: item.GetLabel(), Value: value})
+	}
+	return ret, nil
+}
+
+// displayValue returns the model type and value of a display data value.
+// Integers are widened to int64 and floats to float64.
+func displayValue(v interface{}) (pb.DisplayData_Type_Enum, proto.Message, error) {
+	switch x := v.(type) {
+	case string:
+		return pb.DisplayData_Type_STRING, &wrappers.StringValue{Value: x}, nil
+	case bool:
+		return pb.DisplayData_Type_BOOLEAN, &wrappers.BoolValue{Value: x}, nil
+	case int:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case int8:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case int16:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case int32:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case int64:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: x}, nil
+	case uint:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case uint8:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case uint16:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case uint32:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case uint64:
+		return pb.DisplayData_Type_INTEGER, &wrappers.Int64Value{Value: int64(x)}, nil
+	case float32:
+		return pb.DisplayData_Type_FLOAT, &wrappers.DoubleValue{Value: float64(x)}, nil
+	case float64:
+		return pb.DisplayData_Type_FLOAT, &wrappers.DoubleValue{Value: x}, nil
+	case time.Time:
+		ts, err := ptypes.TimestampProto(x)
+		if err != nil {
+			return 0, nil, err
+		}
+		return pb.DisplayData_Type_TIMESTAMP, ts, nil
+	case time.Duration:
+		return pb.DisplayData_Type_DURATION, ptypes.DurationProto(x), nil
+	default:
+		return pb.DisplayData_Type_STRING, &wrappers.StringValue{Value: fmt.Sprint(v)}, nil
+	}
+}
@@ -23,7 +23,6 @@ import (
 	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
-	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx/v1"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
@@ -260,6 +259,15 @@ func EncodeUserFn(u *funcx.Fn) (*v1.UserFn, error) {
 // extracting the preprocessed representation, expanding all inputs and outputs
 // of the function.
 func DecodeUserFn(ref *v1.UserFn) (*funcx.Fn, error) {
+	fn, err := resolveUserFn(ref)
+	if err != nil {
+		return nil, err
+	}
+	return funcx.New(reflectx.MakeFunc(fn))
+}
+
+// resolveUserFn returns the function referenced by the wire representation.
+func resolveUserFn(ref *v1.UserFn) (interface{}, error) {
 	t, err := decodeType(ref.GetType())
 	if err != nil {
 		return nil, err
@@ -269,7 +277,7 @@ func DecodeUserFn(ref *v1.UserFn) (*funcx.Fn, error) {
 	if err != nil {
 		return nil, fmt.Errorf("decode: failed to find symbol %v: %v", ref.Name, err)
 	}
-	return funcx.New(reflectx.MakeFunc(fn))
+	return fn, nil
 }
 
 func encodeFullType(t typex.FullType) (*v1.FullType, error) {
@@ -718,13 +726,14 @@ type CoderRef struct {
 
 // Exported types are used for translation lookup.
 const (
-	WindowedValueType = "kind:windowed_value"
-	BytesType         = "kind:bytes"
-	VarIntType        = "kind:varint"
-	GlobalWindowType  = "kind:global_window"
-	streamType        = "kind:stream"
-	pairType          = "kind:pair"
-	lengthPrefixType  = "kind:length_prefix"
+	WindowedValueType  = "kind:windowed_value"
+	BytesType          = "kind:bytes"
+	VarIntType         = "kind:varint"
+	GlobalWindowType   = "kind:global_window"
+	IntervalWindowType = "kind:interval_window"
+	streamType         = "kind:stream"
+	pairType           = "kind:pair"
+	lengthPrefixType   = "kind:length_prefix"
 
 	cogbklistType = "kind:cogbklist" // CoGBK representation. Not a coder.
 )
@@ -894,6 +903,11 @@ func DecodeCoderRef(c *CoderRef) (*coder.Coder, error) {
 		if len(c.Components) != 1 {
 			return nil, fmt.Errorf("bad length prefix: %+v", c)
 		}
+		if c.Components[0].Type == BytesType {
+			// Replacement of an unknown coder by the runner. Bytes are implicitly
+			// length prefixed.
+			return coder.NewBytes(), nil
+		}
 
 		var ref v1.CustomCoder
 		if err := protox.DecodeBase64(c.Components[0].Type, &ref); err != nil {
@@ -949,21 +963,25 @@ func isCoGBKList(ref *CoderRef) ([]*CoderRef, bool) {
 // encodeWindow translates the preprocessed representation of a Beam coder
 // into the wire representation, capturing the underlying types used by
 // the coder.
-func encodeWindow(w *window.Window) (*CoderRef, error) {
-	switch w.Kind() {
-	case window.GlobalWindow:
+func encodeWindow(w *coder.WindowCoder) (*CoderRef, error) {
+	switch w.Kind {
+	case coder.GlobalWindow:
 		return &CoderRef{Type: GlobalWindowType}, nil
+	case coder.IntervalWindow:
+		return &CoderRef{Type: IntervalWindowType}, nil
 	default:
-		return nil, fmt.Errorf("bad window kind: %v", w.Kind())
+		return nil, fmt.Errorf("bad window kind: %v", w.Kind)
 	}
 }
 
 // decodeWindow receives the wire representation of a Beam coder, extracting
 // the preprocessed representation, expanding all types used by the coder.
-func decodeWindow(w *CoderRef) (*window.Window, error) {
+func decodeWindow(w *CoderRef) (*coder.WindowCoder, error) {
 	switch w.Type {
 	case GlobalWindowType:
-		return window.NewGlobalWindow(), nil
+		return coder.NewGlobalWindow(), nil
+	case IntervalWindowType:
+		return coder.NewIntervalWindow(), nil
 	default:
 		return nil, fmt.Errorf("bad window: %v", w.Type)
 	}
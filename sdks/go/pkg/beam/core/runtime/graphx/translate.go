@@ -17,9 +17,12 @@ package graphx
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx/v1"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/protox"
 	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
@@ -35,7 +38,10 @@ const (
 	URNCombine = "beam:transform:combine:v1"
 	URNWindow  = "beam:transform:window:v1"
 
-	URNGlobalWindowsWindowFn = "beam:windowfn:global_windows:v0.1"
+	URNGlobalWindowsWindowFn  = "beam:windowfn:global_windows:v0.1"
+	URNFixedWindowsWindowFn   = "beam:windowfn:fixed_windows:v0.1"
+	URNSlidingWindowsWindowFn = "beam:windowfn:sliding_windows:v0.1"
+	URNSessionsWindowFn       = "beam:windowfn:session_windows:v0.1"
 
 	// SDK constants
 
@@ -44,8 +50,15 @@ const (
 	// uses the model pipeline and no longer falls back to Java.
 	URNJavaDoFn = "urn:beam:dofn:javasdk:0.1"
 	URNDoFn     = "beam:go:transform:dofn:v1"
+
+	// URNCustomWindowFn is the WindowFn of custom windows, whose functions
+	// are only available to the Go SDK.
+	URNCustomWindowFn = "beam:go:windowfn:v1"
 )
 
+// defaultEnvID is the id of the default environment of the pipeline.
+const defaultEnvID = "go"
+
 // TODO(herohde) 11/6/2017: move some of the configuration into the graph during construction.
 
 // Options for marshalling a graph into a model pipeline.
@@ -58,6 +71,7 @@ type Options struct {
 func Marshal(edges []*graph.MultiEdge, opt *Options) (*pb.Pipeline, error) {
 	tree := NewScopeTree(edges)
 	EnsureUniqueNames(tree)
+	QualifyNames(tree)
 
 	m := newMarshaller(opt.ContainerImageURL)
 
@@ -69,9 +83,15 @@ func Marshal(edges []*graph.MultiEdge, opt *Options) (*pb.Pipeline, error) {
 		roots = append(roots, m.addScopeTree(t))
 	}
 
+	data, err := MarshalDisplayData("", "", buildinfo.Get().DisplayData())
+	if err != nil {
+		return nil, err
+	}
+
 	p := &pb.Pipeline{
 		Components:       m.build(),
 		RootTransformIds: roots,
+		DisplayData:      data,
 	}
 	return p, nil
 }
@@ -83,6 +103,7 @@ type marshaller struct {
 	pcollections map[string]*pb.PCollection
 	windowing    map[string]*pb.WindowingStrategy
 	environments map[string]*pb.Environment
+	pinned       map[string]string // image -> environment id
 
 	coders *CoderMarshaller
 }
@@ -94,6 +115,7 @@ func newMarshaller(imageURL string) *marshaller {
 		pcollections: make(map[string]*pb.PCollection),
 		windowing:    make(map[string]*pb.WindowingStrategy),
 		environments: make(map[string]*pb.Environment),
+		pinned:       make(map[string]string),
 		coders:       NewCoderMarshaller(),
 	}
 }
@@ -191,6 +213,11 @@ func (m *marshaller) addMultiEdge(edge NamedEdge) string {
 		Inputs:     inputs,
 		Outputs:    outputs,
 	}
+	data, err := MarshalDisplayData(id, transform.Spec.Urn, edge.Edge.DisplayData)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to serialize display data of %v: %v", edge.Edge, err))
+	}
+	transform.DisplayData = data
 
 	m.transforms[id] = transform
 	return id
@@ -212,7 +239,7 @@ func (m *marshaller) expandCoGBK(edge NamedEdge) string {
 		m.addNode(in.From)
 
 		out := fmt.Sprintf("%v_inject%v", nodeID(in.From), i)
-		m.addPCollection(out, kvCoderID)
+		m.addPCollection(out, kvCoderID, in.From.Bounded(), in.From.Window())
 
 		// Inject(i)
 
@@ -226,11 +253,11 @@ func (m *marshaller) expandCoGBK(edge NamedEdge) string {
 						Inject: &v1.InjectPayload{N: (int32)(i)},
 					}),
 				},
-				EnvironmentId: m.addDefaultEnv(),
+				EnvironmentId: m.addEnv(edge.Edge),
 			},
 		}
 		inject := &pb.PTransform{
-			UniqueName: injectID,
+			UniqueName: fmt.Sprintf("%v_inject%v", edge.Name, i),
 			Spec: &pb.FunctionSpec{
 				Urn:     URNParDo,
 				Payload: protox.MustEncode(payload),
@@ -246,11 +273,11 @@ func (m *marshaller) expandCoGBK(edge NamedEdge) string {
 	// Flatten
 
 	out := fmt.Sprintf("%v_flatten", nodeID(edge.Edge.Output[0].To))
-	m.addPCollection(out, kvCoderID)
+	m.addPCollection(out, kvCoderID, edge.Edge.Output[0].To.Bounded(), edge.Edge.Output[0].To.Window())
 
 	flattenID := fmt.Sprintf("%v_flatten", id)
 	flatten := &pb.PTransform{
-		UniqueName: flattenID,
+		UniqueName: fmt.Sprintf("%v_flatten", edge.Name),
 		Spec:       &pb.FunctionSpec{Urn: URNFlatten},
 		Inputs:     inputs,
 		Outputs:    map[string]string{"i0": out},
@@ -260,7 +287,7 @@ func (m *marshaller) expandCoGBK(edge NamedEdge) string {
 	// CoGBK
 
 	gbkOut := fmt.Sprintf("%v_out", nodeID(edge.Edge.Output[0].To))
-	m.addPCollection(gbkOut, gbkCoderID)
+	m.addPCollection(gbkOut, gbkCoderID, edge.Edge.Output[0].To.Bounded(), edge.Edge.Output[0].To.Window())
 
 	gbk := &pb.PTransform{
 		UniqueName: edge.Name,
@@ -276,12 +303,12 @@ func (m *marshaller) expandCoGBK(edge NamedEdge) string {
 
 	expandID := fmt.Sprintf("%v_expand", id)
 	expand := &pb.PTransform{
-		UniqueName: expandID,
+		UniqueName: fmt.Sprintf("%v_expand", edge.Name),
 		Spec: &pb.FunctionSpec{
 			Urn:     URNExpand,
 			Payload: protox.MustEncode(&v1.TransformPayload{Urn: URNExpand}),
 		},
-		Inputs:  map[string]string{"i0": out},
+		Inputs:  map[string]string{"i0": gbkOut},
 		Outputs: map[string]string{"i0": nodeID(edge.Edge.Output[0].To)},
 	}
 	m.transforms[expandID] = expand
@@ -291,7 +318,7 @@ func (m *marshaller) expandCoGBK(edge NamedEdge) string {
 func (m *marshaller) makePayload(edge *graph.MultiEdge) *pb.FunctionSpec {
 	switch edge.Op {
 	case graph.Impulse:
-		return &pb.FunctionSpec{Urn: URNImpulse}
+		return &pb.FunctionSpec{Urn: URNImpulse, Payload: edge.Value}
 
 	case graph.ParDo, graph.Combine:
 		payload := &pb.ParDoPayload{
@@ -300,7 +327,7 @@ func (m *marshaller) makePayload(edge *graph.MultiEdge) *pb.FunctionSpec {
 					Urn:     URNJavaDoFn,
 					Payload: []byte(mustEncodeMultiEdgeBase64(edge)),
 				},
-				EnvironmentId: m.addDefaultEnv(),
+				EnvironmentId: m.addEnv(edge),
 			},
 		}
 		return &pb.FunctionSpec{Urn: URNParDo, Payload: protox.MustEncode(payload)}
@@ -311,6 +338,13 @@ func (m *marshaller) makePayload(edge *graph.MultiEdge) *pb.FunctionSpec {
 	case graph.CoGBK:
 		return &pb.FunctionSpec{Urn: URNGBK}
 
+	case graph.WindowInto:
+		spec, err := makeWindowIntoPayload(edge.WindowFn)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to serialize window fn of %v: %v", edge, err))
+		}
+		return spec
+
 	case graph.External:
 		return &pb.FunctionSpec{Urn: edge.Payload.URN, Payload: edge.Payload.Data}
 
@@ -324,62 +358,95 @@ func (m *marshaller) addNode(n *graph.Node) string {
 	if _, exists := m.pcollections[id]; exists {
 		return id
 	}
-	// TODO(herohde) 11/15/2017: expose UniqueName to user. Handle unbounded and windowing.
-	return m.addPCollection(id, m.coders.Add(n.Coder))
+	// TODO(herohde) 11/15/2017: expose UniqueName to user.
+	return m.addPCollection(id, m.coders.Add(n.Coder), n.Bounded(), n.Window())
 }
 
-func (m *marshaller) addPCollection(id, cid string) string {
+func (m *marshaller) addPCollection(id, cid string, bounded bool, w *window.Window) string {
+	isBounded := pb.IsBounded_BOUNDED
+	if !bounded {
+		isBounded = pb.IsBounded_UNBOUNDED
+	}
 	col := &pb.PCollection{
 		UniqueName:          id,
 		CoderId:             cid,
-		IsBounded:           pb.IsBounded_BOUNDED,
-		WindowingStrategyId: m.addWindowingStrategy(window.NewGlobalWindow()),
+		IsBounded:           isBounded,
+		WindowingStrategyId: m.addWindowingStrategy(w),
 	}
 	m.pcollections[id] = col
 	return id
 }
 
 func (m *marshaller) addDefaultEnv() string {
-	const id = "go"
-	if _, exists := m.environments[id]; !exists {
-		m.environments[id] = &pb.Environment{Url: m.imageURL}
+	if _, exists := m.environments[defaultEnvID]; !exists {
+		m.environments[defaultEnvID] = &pb.Environment{Url: m.imageURL}
+	}
+	return defaultEnvID
+}
+
+// addEnv adds the environment of the edge, which is the default environment
+// unless the edge is in a pinned scope.
+func (m *marshaller) addEnv(edge *graph.MultiEdge) string {
+	image := edge.Scope().Env()
+	if image == "" || image == m.imageURL {
+		return m.addDefaultEnv()
+	}
+	if id, exists := m.pinned[image]; exists {
+		return id
 	}
+	id := fmt.Sprintf("go%v", len(m.pinned)+1)
+	m.pinned[image] = id
+	m.environments[id] = &pb.Environment{Url: image}
 	return id
 }
 
 func (m *marshaller) addWindowingStrategy(w *window.Window) string {
-	if w.Kind() != window.GlobalWindow {
-		panic(fmt.Sprintf("Unsupported window type supplied: %v", w))
+	id := "global"
+	if !w.Equals(window.NewGlobalWindow()) {
+		id = w.String()
 	}
-
-	const id = "global"
 	if _, exists := m.windowing[id]; !exists {
-		wcid := m.coders.AddWindow(w)
-
-		ws := &pb.WindowingStrategy{
-			WindowFn: &pb.SdkFunctionSpec{
-				Spec: &pb.FunctionSpec{
-					Urn: URNGlobalWindowsWindowFn,
-				},
-			},
-			MergeStatus:      pb.MergeStatus_NON_MERGING,
-			AccumulationMode: pb.AccumulationMode_DISCARDING,
-			WindowCoderId:    wcid,
-			Trigger: &pb.Trigger{
-				Trigger: &pb.Trigger_Default_{
-					Default: &pb.Trigger_Default{},
-				},
-			},
-			OutputTime:      pb.OutputTime_END_OF_WINDOW,
-			ClosingBehavior: pb.ClosingBehavior_EMIT_IF_NONEMPTY,
-			AllowedLateness: 0,
-			OnTimeBehavior:  pb.OnTimeBehavior_FIRE_ALWAYS,
+		ws, err := MarshalWindowingStrategy(m.coders, w)
+		if err != nil {
+			panic(fmt.Sprintf("Unsupported window type supplied: %v", w))
 		}
 		m.windowing[id] = ws
 	}
 	return id
 }
 
+// MarshalWindowingStrategy translates a window into the model windowing
+// strategy. The window coder is added to the given coders.
+func MarshalWindowingStrategy(c *CoderMarshaller, w *window.Window) (*pb.WindowingStrategy, error) {
+	fn, err := MarshalWindowFn(w)
+	if err != nil {
+		return nil, err
+	}
+	wcid := c.AddWindow(coder.NewWindowCoder(w))
+
+	merge := pb.MergeStatus_NON_MERGING
+	if w.IsMerging() {
+		merge = pb.MergeStatus_NEEDS_MERGE
+	}
+	mode := pb.AccumulationMode_DISCARDING
+	if w.AccumulationMode() == window.Accumulating {
+		mode = pb.AccumulationMode_ACCUMULATING
+	}
+
+	ws := &pb.WindowingStrategy{
+		WindowFn:         fn,
+		MergeStatus:      merge,
+		AccumulationMode: mode,
+		WindowCoderId:    wcid,
+		Trigger:          MarshalTrigger(w.Trigger()),
+		OutputTime:       pb.OutputTime_END_OF_WINDOW,
+		ClosingBehavior:  pb.ClosingBehavior_EMIT_IF_NONEMPTY,
+		AllowedLateness:  int64(w.AllowedLateness() / time.Millisecond),
+		OnTimeBehavior:   pb.OnTimeBehavior_FIRE_ALWAYS,
+	}
+	return ws, nil
+}
+
 func mustEncodeMultiEdgeBase64(edge *graph.MultiEdge) string {
 	ref, err := EncodeMultiEdge(edge)
 	if err != nil {
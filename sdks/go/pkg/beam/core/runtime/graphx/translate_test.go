@@ -16,7 +16,11 @@
 package graphx_test
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
@@ -25,11 +29,15 @@ import (
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
 	"github.com/golang/protobuf/proto"
 )
 
 func init() {
 	runtime.RegisterFunction(pickFn)
+	runtime.RegisterFunction(assignFn)
+	runtime.RegisterFunction(window.MergeIntervals)
+	runtime.RegisterType(reflect.TypeOf((*queryFn)(nil)).Elem())
 }
 
 func pickFn(a int, small, big func(int)) {
@@ -88,3 +96,399 @@ func TestParDo(t *testing.T) {
 		t.Errorf("bad ParDo translation: %v", proto.MarshalTextString(p))
 	}
 }
+
+// TestCompositeNames verifies that scopes are translated to composite
+// transforms with fully-qualified, unique names.
+func TestCompositeNames(t *testing.T) {
+	g := graph.New()
+
+	outer := g.NewScope(g.Root(), "outer")
+	for _, s := range []*graph.Scope{g.NewScope(outer, "inner"), g.NewScope(outer, "inner")} {
+		dofn, err := graph.NewDoFn(pickFn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := g.NewNode(intT(), window.NewGlobalWindow())
+		in.Coder = intCoder()
+
+		e, err := graph.NewParDo(g, s, dofn, []*graph.Node{in}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Output[0].To.Coder = intCoder()
+		e.Output[1].To.Coder = intCoder()
+	}
+
+	edges, _, err := g.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make(map[string][]string)
+	for _, transform := range p.GetComponents().GetTransforms() {
+		var subs []string
+		for _, id := range transform.GetSubtransforms() {
+			subs = append(subs, p.GetComponents().GetTransforms()[id].GetUniqueName())
+		}
+		sort.Strings(subs)
+		names[transform.GetUniqueName()] = subs
+	}
+
+	fn := reflectx.FunctionName(pickFn)
+	expected := map[string][]string{
+		"outer":               {"outer/inner", "outer/inner'1"},
+		"outer/inner":         {"outer/inner/" + fn},
+		"outer/inner'1":       {"outer/inner'1/" + fn},
+		"outer/inner/" + fn:   nil,
+		"outer/inner'1/" + fn: nil,
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Marshal() transforms = %v, want %v", names, expected)
+	}
+}
+
+// TestUnmarshal verifies that a marshalled graph can be unmarshalled and
+// marshalled again to an equivalent pipeline.
+func TestUnmarshal(t *testing.T) {
+	g := graph.New()
+
+	impulse := graph.NewImpulse(g, g.Root(), []byte("seed"))
+	impulse.Output[0].To.Coder = coder.NewBytes()
+
+	e := pick(t, g)
+	flatten, err := graph.NewFlatten(g, g.NewScope(g.Root(), "merge"), []*graph.Node{e.Output[0].To, e.Output[1].To})
+	if err != nil {
+		t.Fatal(err)
+	}
+	flatten.Output[0].To.Coder = intCoder()
+
+	ws := window.NewSessions(time.Minute).
+		WithTrigger(window.TriggerAfterWatermark().LateFiring(window.TriggerAlways())).
+		WithAllowedLateness(time.Hour).
+		WithAccumulationMode(window.Accumulating)
+	windowed := graph.NewWindowIntoWithLateData(g, g.Root(), ws, flatten.Output[0].To)
+	windowed.Output[0].To.Coder = intCoder()
+	windowed.Output[1].To.Coder = intCoder()
+
+	kvCoder := coder.NewKV([]*coder.Coder{intCoder(), intCoder()})
+	var kvs []*graph.Node
+	for i := 0; i < 2; i++ {
+		n := g.NewNode(kvCoder.T, window.NewGlobalWindow())
+		n.Coder = kvCoder
+		kvs = append(kvs, n)
+	}
+	for _, in := range [][]*graph.Node{kvs, kvs[:1]} {
+		gbk, err := graph.NewCoGBK(g, g.NewScope(g.Root(), "group"), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		components := []*coder.Coder{intCoder()}
+		for range in {
+			components = append(components, intCoder())
+		}
+		gbk.Output[0].To.Coder = coder.NewCoGBK(components)
+	}
+
+	edges, _, err := g.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g2, err := graphx.Unmarshal(p)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	edges2, _, err := g2.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edges2) != len(edges) {
+		t.Errorf("Unmarshal returned %v edges, want %v", len(edges2), len(edges))
+	}
+	for _, edge := range edges2 {
+		if edge.Op == graph.Impulse && string(edge.Value) != "seed" {
+			t.Errorf("Unmarshal impulse value = %q, want %q", edge.Value, "seed")
+		}
+		if edge.Op == graph.WindowInto {
+			if got := edge.Output[0].To.Window(); !got.Equals(ws) {
+				t.Errorf("Unmarshal windowing strategy = %v, want %v", got, ws)
+			}
+			if len(edge.Output) != 2 {
+				t.Errorf("Unmarshal WindowInto has %v outputs, want 2 with late data", len(edge.Output))
+			}
+		}
+	}
+
+	p2, err := graphx.Marshal(edges2, &graphx.Options{ContainerImageURL: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, want := describe(t, p2), describe(t, p)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal(Unmarshal(p)) = %v, want %v", got, want)
+	}
+	if got, want := p2.GetComponents().GetEnvironments(), p.GetComponents().GetEnvironments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal(Unmarshal(p)) environments = %v, want %v", got, want)
+	}
+	if got, want := p2.GetComponents().GetWindowingStrategies(), p.GetComponents().GetWindowingStrategies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal(Unmarshal(p)) windowing strategies = %v, want %v", got, want)
+	}
+}
+
+func assignFn(t typex.EventTime) []window.IntervalWindow {
+	return []window.IntervalWindow{{Start: t, End: typex.EventTime(time.Time(t).Add(time.Minute))}}
+}
+
+// TestCustomWindowFn verifies that custom windows can be serialized.
+func TestCustomWindowFn(t *testing.T) {
+	for _, merge := range []interface{}{nil, window.MergeIntervals} {
+		w, err := window.NewCustom("minute", assignFn, merge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn, err := graphx.MarshalWindowFn(w)
+		if err != nil {
+			t.Fatalf("MarshalWindowFn(%v) failed: %v", w, err)
+		}
+		w2, err := graphx.UnmarshalWindowFn(fn.GetSpec())
+		if err != nil {
+			t.Fatalf("UnmarshalWindowFn(%v) failed: %v", fn, err)
+		}
+		if !w2.Equals(w) || w2.IsMerging() != w.IsMerging() {
+			t.Errorf("UnmarshalWindowFn(MarshalWindowFn(%v)) = %v, want %v", w, w2, w)
+		}
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	tests := []*window.Trigger{
+		window.TriggerDefault(),
+		window.TriggerNever(),
+		window.TriggerAfterWatermark().
+			EarlyFiring(window.TriggerRepeat(window.TriggerAfterProcessingTime(time.Minute))).
+			LateFiring(window.TriggerAlways()),
+		window.TriggerAfterEach(window.TriggerAfterCount(5), window.TriggerAfterAll(window.TriggerAfterCount(2), window.TriggerNever())),
+		window.TriggerAfterAny(window.TriggerAfterWatermark(), window.TriggerAfterCount(100)),
+	}
+	for _, trigger := range tests {
+		got, err := graphx.UnmarshalTrigger(graphx.MarshalTrigger(trigger))
+		if err != nil {
+			t.Fatalf("UnmarshalTrigger(%v) failed: %v", trigger, err)
+		}
+		if !got.Equals(trigger) {
+			t.Errorf("UnmarshalTrigger(MarshalTrigger(%v)) = %v, want %v", trigger, got, trigger)
+		}
+	}
+}
+
+// describe returns an id-independent description of the transforms of a
+// pipeline keyed by unique name. PCollections are described by their
+// producer and coder.
+func describe(t *testing.T, p *pb.Pipeline) map[string]string {
+	comp := p.GetComponents()
+
+	producers := make(map[string]string)
+	for _, transform := range comp.GetTransforms() {
+		if len(transform.GetSubtransforms()) > 0 {
+			continue
+		}
+		for key, col := range transform.GetOutputs() {
+			producers[col] = transform.GetUniqueName() + "." + key
+		}
+	}
+	coders := graphx.NewCoderUnmarshaller(comp.GetCoders())
+	pcollection := func(id string) string {
+		c, err := coders.Coder(comp.GetPcollections()[id].GetCoderId())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%v:%v", producers[id], c)
+	}
+
+	ret := make(map[string]string)
+	for _, transform := range comp.GetTransforms() {
+		var subs, in, out []string
+		for _, id := range transform.GetSubtransforms() {
+			subs = append(subs, comp.GetTransforms()[id].GetUniqueName())
+		}
+		for key, col := range transform.GetInputs() {
+			if len(subs) > 0 {
+				key = "" // composites use pcollection ids as local names
+			}
+			in = append(in, key+"="+pcollection(col))
+		}
+		for key, col := range transform.GetOutputs() {
+			if len(subs) > 0 {
+				key = ""
+			}
+			out = append(out, key+"="+pcollection(col))
+		}
+		sort.Strings(subs)
+		sort.Strings(in)
+		sort.Strings(out)
+
+		spec := transform.GetSpec()
+		ret[transform.GetUniqueName()] = fmt.Sprintf("%v(%x) %v %v -> %v", spec.GetUrn(), spec.GetPayload(), subs, in, out)
+	}
+	return ret
+}
+
+type queryFn struct {
+	Query string
+	Limit int
+}
+
+func (f *queryFn) DisplayData() []graph.DisplayItem {
+	return []graph.DisplayItem{
+		{Key: "query", Label: "Query", Value: f.Query},
+		{Key: "limit", Value: f.Limit},
+		{Key: "timeout", Value: 5 * time.Second},
+	}
+}
+
+func (f *queryFn) ProcessElement(a int) int {
+	return a
+}
+
+// TestDisplayData verifies that display data is attached to the edge and
+// translated into the pipeline proto and back.
+func TestDisplayData(t *testing.T) {
+	g := graph.New()
+
+	dofn, err := graph.NewDoFn(&queryFn{Query: "SELECT 1", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := g.NewNode(intT(), window.NewGlobalWindow())
+	in.Coder = intCoder()
+
+	e, err := graph.NewParDo(g, g.Root(), dofn, []*graph.Node{in}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Output[0].To.Coder = intCoder()
+
+	want := []graph.DisplayItem{
+		{Key: "query", Label: "Query", Value: "SELECT 1"},
+		{Key: "limit", Value: int64(10)},
+		{Key: "timeout", Value: 5 * time.Second},
+	}
+	if len(e.DisplayData) != len(want) {
+		t.Fatalf("NewParDo display data = %v, want %v", e.DisplayData, want)
+	}
+
+	edges, _, err := g.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, transform := range p.GetComponents().GetTransforms() {
+		if n := len(transform.GetDisplayData().GetItems()); n != len(want) {
+			t.Errorf("Marshal display data has %v items, want %v: %v", n, len(want), transform.GetDisplayData())
+		}
+	}
+
+	g2, err := graphx.Unmarshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges2, _, err := g2.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := edges2[0].DisplayData; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal display data = %v, want %v", got, want)
+	}
+}
+
+// TestEnvironments verifies that transforms in pinned scopes are translated
+// with their own environment.
+func TestEnvironments(t *testing.T) {
+	g := graph.New()
+
+	pinned := g.NewScope(g.Root(), "native")
+	pinned.Environment = "native-image"
+	nested := g.NewScope(pinned, "nested")
+
+	var edges []*graph.MultiEdge
+	for _, s := range []*graph.Scope{g.Root(), pinned, nested} {
+		dofn, err := graph.NewDoFn(pickFn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := g.NewNode(intT(), window.NewGlobalWindow())
+		in.Coder = intCoder()
+
+		e, err := graph.NewParDo(g, s, dofn, []*graph.Node{in}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Output[0].To.Coder = intCoder()
+		e.Output[1].To.Coder = intCoder()
+		edges = append(edges, e)
+	}
+
+	p, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: "default-image"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envs := make(map[string]string)
+	for _, transform := range p.GetComponents().GetTransforms() {
+		if transform.GetSpec().GetUrn() != graphx.URNParDo {
+			continue
+		}
+		var payload pb.ParDoPayload
+		if err := proto.Unmarshal(transform.GetSpec().GetPayload(), &payload); err != nil {
+			t.Fatal(err)
+		}
+		env := p.GetComponents().GetEnvironments()[payload.GetDoFn().GetEnvironmentId()]
+		envs[transform.GetUniqueName()] = env.GetUrl()
+	}
+
+	fn := reflectx.FunctionName(pickFn)
+	want := map[string]string{
+		fn:                    "default-image",
+		"native/" + fn:        "native-image",
+		"native/nested/" + fn: "native-image",
+	}
+	if !reflect.DeepEqual(envs, want) {
+		t.Errorf("Marshal environments = %v, want %v", envs, want)
+	}
+	if n := len(p.GetComponents().GetEnvironments()); n != 2 {
+		t.Errorf("Marshal created %v environments, want 2", n)
+	}
+
+	g2, err := graphx.Unmarshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges2, _, err := g2.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, e := range edges2 {
+		got[e.Scope().String()] = e.Scope().Env()
+	}
+	wantScopes := map[string]string{
+		"root":               "",
+		"root/native":        "native-image",
+		"root/native/nested": "native-image",
+	}
+	if !reflect.DeepEqual(got, wantScopes) {
+		t.Errorf("Unmarshal environments = %v, want %v", got, wantScopes)
+	}
+}
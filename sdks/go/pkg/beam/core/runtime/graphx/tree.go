@@ -95,9 +95,9 @@ func (t *treeBuilder) addScope(s *graph.Scope) *ScopeTree {
 // recursively. Any conflict is resolved by adding '1, '2, etc to the name.
 func EnsureUniqueNames(tree *ScopeTree) {
 	seen := make(map[string]bool)
-	for _, edge := range tree.Edges {
-		edge.Name = findFreeName(seen, edge.Name)
-		seen[edge.Name] = true
+	for i, edge := range tree.Edges {
+		tree.Edges[i].Name = findFreeName(seen, edge.Name)
+		seen[tree.Edges[i].Name] = true
 	}
 
 	for _, s := range tree.Children {
@@ -108,6 +108,25 @@ func EnsureUniqueNames(tree *ScopeTree) {
 	}
 }
 
+// QualifyNames prefixes the names in the tree with the names of the
+// enclosing scopes, such as "Outer/Inner/name", to form the fully-qualified
+// names used by runners to show the composite hierarchy. The root scope is not
+// included. The names should be unique within each scope, so that the result
+// is unique for the whole tree.
+func QualifyNames(tree *ScopeTree) {
+	qualifyNames(tree, "")
+}
+
+func qualifyNames(tree *ScopeTree, prefix string) {
+	for i, edge := range tree.Edges {
+		tree.Edges[i].Name = prefix + edge.Name
+	}
+	for _, s := range tree.Children {
+		s.Scope.Name = prefix + s.Scope.Name
+		qualifyNames(s, s.Scope.Name+"/")
+	}
+}
+
 func findFreeName(seen map[string]bool, name string) string {
 	if !seen[name] {
 		return name
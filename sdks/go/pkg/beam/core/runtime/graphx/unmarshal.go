@@ -0,0 +1,518 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx/v1"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/protox"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+	"github.com/golang/protobuf/proto"
+)
+
+// Unmarshal converts a model pipeline into a graph. It is the inverse of
+// Marshal: composite transforms become scopes, PCollections become nodes
+// with the type of their coder and primitive transforms become edges. The
+// expansion of CoGBK with multiple inputs is collapsed back into a single
+// edge. Transforms that are not Go primitives are represented as External
+// edges with their spec as payload.
+func Unmarshal(p *pb.Pipeline) (*graph.Graph, error) {
+	u := newUnmarshaller(p.GetComponents())
+
+	for _, id := range p.GetRootTransformIds() {
+		if err := u.addTransform(u.g.Root(), "", id); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range u.order {
+		if err := u.visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return u.g, nil
+}
+
+type unmarshaller struct {
+	comp   *pb.Components
+	coders *CoderUnmarshaller
+	g      *graph.Graph
+
+	scopes    map[string]*graph.Scope // primitive transform id -> enclosing scope
+	order     []string                // primitive transform ids in tree order
+	producers map[string]string       // pcollection id -> transform id
+	consumers map[string][]string     // pcollection id -> transform ids
+	nodes     map[string]*graph.Node  // pcollection id -> node
+	visited   map[string]bool         // transform id -> translated (or in progress)
+}
+
+func newUnmarshaller(comp *pb.Components) *unmarshaller {
+	u := &unmarshaller{
+		comp:      comp,
+		coders:    NewCoderUnmarshaller(comp.GetCoders()),
+		g:         graph.New(),
+		scopes:    make(map[string]*graph.Scope),
+		producers: make(map[string]string),
+		consumers: make(map[string][]string),
+		nodes:     make(map[string]*graph.Node),
+		visited:   make(map[string]bool),
+	}
+
+	// The Inject, Flatten and Expand transforms of an expanded CoGBK are
+	// not subtransforms of any composite, so producers and consumers are
+	// computed from all primitive transforms.
+
+	var ids []string
+	for id := range comp.GetTransforms() {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		transform := comp.GetTransforms()[id]
+		if len(transform.GetSubtransforms()) > 0 {
+			continue
+		}
+		for _, out := range transform.GetOutputs() {
+			u.producers[out] = id
+		}
+		for _, in := range transform.GetInputs() {
+			u.consumers[in] = append(u.consumers[in], id)
+		}
+	}
+	return u
+}
+
+// addTransform records the scope of the transform with the given id and
+// creates scopes for composites. Composite names are qualified by the name
+// of the enclosing composite, if any, which is stripped for the scope label.
+func (u *unmarshaller) addTransform(parent *graph.Scope, prefix, id string) error {
+	transform, ok := u.comp.GetTransforms()[id]
+	if !ok {
+		return fmt.Errorf("transform %v not found", id)
+	}
+	if _, exists := u.scopes[id]; exists {
+		return fmt.Errorf("transform %v is present more than once", id)
+	}
+
+	if subs := transform.GetSubtransforms(); len(subs) > 0 {
+		name := transform.GetUniqueName()
+		s := u.g.NewScope(parent, strings.TrimPrefix(name, prefix))
+		u.scopes[id] = s
+		for _, sub := range subs {
+			if err := u.addTransform(s, name+"/", sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	u.scopes[id] = parent
+	u.order = append(u.order, id)
+	return nil
+}
+
+// visit translates the primitive transform with the given id, after the
+// producers of its inputs.
+func (u *unmarshaller) visit(id string) error {
+	if u.visited[id] {
+		return nil
+	}
+	u.visited[id] = true
+
+	transform := u.comp.GetTransforms()[id]
+	for _, in := range sortedValues(transform.GetInputs()) {
+		if from, ok := u.producers[in]; ok {
+			if err := u.visit(from); err != nil {
+				return err
+			}
+		}
+	}
+	if err := u.addEdge(id, transform); err != nil {
+		return fmt.Errorf("failed to unmarshal transform %v: %v", transform.GetUniqueName(), err)
+	}
+	return nil
+}
+
+func (u *unmarshaller) addEdge(id string, transform *pb.PTransform) error {
+	s := u.scopes[id]
+	spec := transform.GetSpec()
+
+	switch spec.GetUrn() {
+	case URNImpulse:
+		out, err := u.outputs(transform)
+		if err != nil {
+			return err
+		}
+		edge := u.g.NewEdge(s)
+		edge.Op = graph.Impulse
+		edge.Value = spec.GetPayload()
+		edge.Output = outbound(out)
+		return nil
+
+	case URNParDo:
+		var payload pb.ParDoPayload
+		if err := proto.Unmarshal(spec.GetPayload(), &payload); err != nil {
+			return fmt.Errorf("invalid ParDo payload: %v", err)
+		}
+		fn := payload.GetDoFn().GetSpec()
+		switch fn.GetUrn() {
+		case URNInject:
+			return nil // part of an expanded CoGBK
+		case URNJavaDoFn:
+			u.pin(s, payload.GetDoFn().GetEnvironmentId())
+			return u.addUserEdge(s, transform, string(fn.GetPayload()))
+		default:
+			return u.addExternal(s, transform)
+		}
+
+	case URNFlatten:
+		if u.isInjectFlatten(transform) {
+			return nil // part of an expanded CoGBK
+		}
+		in, err := u.inputs(transform)
+		if err != nil {
+			return err
+		}
+		out, err := u.outputs(transform)
+		if err != nil {
+			return err
+		}
+		edge := u.g.NewEdge(s)
+		edge.Op = graph.Flatten
+		for _, n := range in {
+			edge.Input = append(edge.Input, &graph.Inbound{Kind: graph.Main, From: n, Type: out[0].Type()})
+		}
+		edge.Output = outbound(out)
+		return nil
+
+	case URNWindow:
+		var payload pb.WindowIntoPayload
+		if err := proto.Unmarshal(spec.GetPayload(), &payload); err != nil {
+			return fmt.Errorf("invalid WindowInto payload: %v", err)
+		}
+		wfn, err := UnmarshalWindowFn(payload.GetWindowFn().GetSpec())
+		if err != nil {
+			return err
+		}
+		in, err := u.inputs(transform)
+		if err != nil {
+			return err
+		}
+		out, err := u.outputs(transform)
+		if err != nil {
+			return err
+		}
+		edge := u.g.NewEdge(s)
+		edge.Op = graph.WindowInto
+		edge.WindowFn = wfn
+		edge.Input = []*graph.Inbound{{Kind: graph.Main, From: in[0], Type: in[0].Type()}}
+		edge.Output = outbound(out)
+		return nil
+
+	case URNGBK:
+		return u.addCoGBK(s, transform)
+
+	case URNExpand:
+		return nil // part of an expanded CoGBK
+
+	default:
+		return u.addExternal(s, transform)
+	}
+}
+
+// addUserEdge adds a ParDo or Combine edge from its serialized form.
+func (u *unmarshaller) addUserEdge(s *graph.Scope, transform *pb.PTransform, data string) error {
+	var tp v1.TransformPayload
+	if err := protox.DecodeBase64(data, &tp); err != nil {
+		return fmt.Errorf("invalid transform payload: %v", err)
+	}
+	if tp.GetUrn() != URNDoFn {
+		return fmt.Errorf("unexpected transform payload: %v", tp.GetUrn())
+	}
+	op, fn, inbound, outbound, err := DecodeMultiEdge(tp.GetEdge())
+	if err != nil {
+		return err
+	}
+
+	in, err := u.inputs(transform)
+	if err != nil {
+		return err
+	}
+	out, err := u.outputs(transform)
+	if err != nil {
+		return err
+	}
+	if len(in) != len(inbound) || len(out) != len(outbound) {
+		return fmt.Errorf("edge has %v inputs and %v outputs, transform has %v and %v", len(inbound), len(outbound), len(in), len(out))
+	}
+
+	edge := u.g.NewEdge(s)
+	edge.Op = op
+	switch op {
+	case graph.ParDo:
+		edge.DoFn, err = graph.AsDoFn(fn)
+	case graph.Combine:
+		edge.CombineFn, err = graph.AsCombineFn(fn)
+	default:
+		err = fmt.Errorf("unexpected opcode: %v", op)
+	}
+	if err != nil {
+		return err
+	}
+
+	edge.DisplayData, err = UnmarshalDisplayData(transform.GetDisplayData())
+	if err != nil {
+		return err
+	}
+
+	for i, n := range in {
+		inbound[i].From = n
+	}
+	for i, n := range out {
+		outbound[i].To = n
+	}
+	edge.Input = inbound
+	edge.Output = outbound
+	return nil
+}
+
+// addCoGBK adds a CoGBK edge. If the GBK is the result of expanding a CoGBK
+// with multiple inputs, the inputs of the Inject transforms and the output
+// of the Expand transform are used.
+func (u *unmarshaller) addCoGBK(s *graph.Scope, transform *pb.PTransform) error {
+	edge := u.g.NewEdge(s)
+	edge.Op = graph.CoGBK
+
+	flatten, ok := u.comp.GetTransforms()[u.producers[onlyValue(transform.GetInputs())]]
+	if !ok || !u.isInjectFlatten(flatten) {
+		in, err := u.inputs(transform)
+		if err != nil {
+			return err
+		}
+		out, err := u.outputs(transform)
+		if err != nil {
+			return err
+		}
+		edge.Input = []*graph.Inbound{{Kind: graph.Main, From: in[0], Type: in[0].Type()}}
+		edge.Output = outbound(out)
+		return nil
+	}
+
+	// Order the inputs by the union index of their Inject transform.
+
+	injects := make(map[int]string)
+	for _, col := range flatten.GetInputs() {
+		inject := u.comp.GetTransforms()[u.producers[col]]
+		n, err := injectIndex(inject)
+		if err != nil {
+			return err
+		}
+		injects[n] = onlyValue(inject.GetInputs())
+	}
+	for i := 0; i < len(injects); i++ {
+		col, ok := injects[i]
+		if !ok {
+			return fmt.Errorf("missing input %v of expanded CoGBK", i)
+		}
+		n, err := u.node(col)
+		if err != nil {
+			return err
+		}
+		edge.Input = append(edge.Input, &graph.Inbound{Kind: graph.Main, From: n, Type: n.Type()})
+	}
+
+	var expand *pb.PTransform
+	for _, id := range u.consumers[onlyValue(transform.GetOutputs())] {
+		if t := u.comp.GetTransforms()[id]; t.GetSpec().GetUrn() == URNExpand {
+			expand = t
+		}
+	}
+	if expand == nil {
+		return fmt.Errorf("missing Expand of expanded CoGBK")
+	}
+	out, err := u.outputs(expand)
+	if err != nil {
+		return err
+	}
+	edge.Output = outbound(out)
+	return nil
+}
+
+// addExternal adds an External edge for a transform that is not a Go
+// primitive.
+func (u *unmarshaller) addExternal(s *graph.Scope, transform *pb.PTransform) error {
+	in, err := u.inputs(transform)
+	if err != nil {
+		return err
+	}
+	out, err := u.outputs(transform)
+	if err != nil {
+		return err
+	}
+
+	edge := u.g.NewEdge(s)
+	edge.Op = graph.External
+	edge.Payload = &graph.Payload{URN: transform.GetSpec().GetUrn(), Data: transform.GetSpec().GetPayload()}
+	edge.DisplayData, err = UnmarshalDisplayData(transform.GetDisplayData())
+	if err != nil {
+		return err
+	}
+	for _, n := range in {
+		edge.Input = append(edge.Input, &graph.Inbound{Kind: graph.Main, From: n, Type: n.Type()})
+	}
+	edge.Output = outbound(out)
+	return nil
+}
+
+// pin pins the scope to the environment with the given id, unless it is the
+// default environment or already the environment of the scope.
+func (u *unmarshaller) pin(s *graph.Scope, id string) {
+	if id == "" || id == defaultEnvID {
+		return
+	}
+	if image := u.comp.GetEnvironments()[id].GetUrl(); s.Env() != image {
+		s.Environment = image
+	}
+}
+
+// isInjectFlatten returns true iff the transform is a Flatten of Inject
+// transforms, as produced by the CoGBK expansion.
+func (u *unmarshaller) isInjectFlatten(transform *pb.PTransform) bool {
+	if transform.GetSpec().GetUrn() != URNFlatten || len(transform.GetInputs()) == 0 {
+		return false
+	}
+	for _, col := range transform.GetInputs() {
+		inject, ok := u.comp.GetTransforms()[u.producers[col]]
+		if !ok {
+			return false
+		}
+		if _, err := injectIndex(inject); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// injectIndex returns the union index of an Inject transform.
+func injectIndex(transform *pb.PTransform) (int, error) {
+	var payload pb.ParDoPayload
+	if err := proto.Unmarshal(transform.GetSpec().GetPayload(), &payload); err != nil {
+		return 0, err
+	}
+	fn := payload.GetDoFn().GetSpec()
+	if transform.GetSpec().GetUrn() != URNParDo || fn.GetUrn() != URNInject {
+		return 0, fmt.Errorf("not an Inject transform: %v", transform.GetUniqueName())
+	}
+	var tp v1.TransformPayload
+	if err := proto.Unmarshal(fn.GetPayload(), &tp); err != nil {
+		return 0, fmt.Errorf("invalid Inject payload: %v", err)
+	}
+	return int(tp.GetInject().GetN()), nil
+}
+
+// inputs returns the nodes of the transform inputs ordered by local name.
+func (u *unmarshaller) inputs(transform *pb.PTransform) ([]*graph.Node, error) {
+	return u.nodeList(transform.GetInputs())
+}
+
+// outputs returns the nodes of the transform outputs ordered by local name.
+func (u *unmarshaller) outputs(transform *pb.PTransform) ([]*graph.Node, error) {
+	return u.nodeList(transform.GetOutputs())
+}
+
+func (u *unmarshaller) nodeList(m map[string]string) ([]*graph.Node, error) {
+	var ret []*graph.Node
+	for _, id := range sortedValues(m) {
+		n, err := u.node(id)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, n)
+	}
+	return ret, nil
+}
+
+// node returns the node for the given pcollection, creating it if needed.
+func (u *unmarshaller) node(id string) (*graph.Node, error) {
+	if n, exists := u.nodes[id]; exists {
+		return n, nil
+	}
+	col, ok := u.comp.GetPcollections()[id]
+	if !ok {
+		return nil, fmt.Errorf("pcollection %v not found", id)
+	}
+	c, err := u.coders.Coder(col.GetCoderId())
+	if err != nil {
+		return nil, err
+	}
+	ws, ok := u.comp.GetWindowingStrategies()[col.GetWindowingStrategyId()]
+	if !ok {
+		return nil, fmt.Errorf("windowing strategy %v for pcollection %v not found", col.GetWindowingStrategyId(), id)
+	}
+	w, err := UnmarshalWindowingStrategy(ws)
+	if err != nil {
+		return nil, fmt.Errorf("bad window fn for pcollection %v: %v", id, err)
+	}
+	if !typex.IsBound(c.T) {
+		return nil, fmt.Errorf("coder type for pcollection %v is not bound: %v", id, c.T)
+	}
+
+	n := u.g.NewNode(c.T, w)
+	n.Coder = c
+	n.SetBounded(col.GetIsBounded() != pb.IsBounded_UNBOUNDED)
+	u.nodes[id] = n
+	return n, nil
+}
+
+func outbound(nodes []*graph.Node) []*graph.Outbound {
+	var ret []*graph.Outbound
+	for _, n := range nodes {
+		ret = append(ret, &graph.Outbound{To: n, Type: n.Type()})
+	}
+	return ret
+}
+
+// sortedValues returns the values of the map ordered by key length, then key,
+// so that local names "i0", "i1", .. are in numeric order.
+func sortedValues(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	var ret []string
+	for _, k := range keys {
+		ret = append(ret, m[k])
+	}
+	return ret
+}
+
+func onlyValue(m map[string]string) string {
+	for _, v := range m {
+		return v
+	}
+	return ""
+}
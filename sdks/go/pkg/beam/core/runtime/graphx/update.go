@@ -0,0 +1,140 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+	"github.com/golang/protobuf/proto"
+)
+
+// CheckUpdate compares a new model pipeline against a previously submitted
+// one and returns the reasons, if any, why the new pipeline cannot replace
+// the running one in place. Primitive transforms are matched by their unique
+// name and must keep their kind and the coders, windowing and boundedness of
+// their outputs. Transforms may be added, but not removed, because the state
+// of removed transforms would be lost. The user code of matched transforms may
+// change freely.
+func CheckUpdate(prev, next *pb.Pipeline) []error {
+	prevLeaves := leaves(prev)
+	nextLeaves := leaves(next)
+
+	var names []string
+	for name := range prevLeaves {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		a := prevLeaves[name]
+		b, ok := nextLeaves[name]
+		if !ok {
+			problems = append(problems, fmt.Errorf("transform %v was removed", name))
+			continue
+		}
+		if x, y := a.GetSpec().GetUrn(), b.GetSpec().GetUrn(); x != y {
+			problems = append(problems, fmt.Errorf("transform %v changed kind from %v to %v", name, x, y))
+			continue
+		}
+
+		for _, tag := range sortedKeys(a.GetOutputs()) {
+			id, ok := b.GetOutputs()[tag]
+			if !ok {
+				problems = append(problems, fmt.Errorf("transform %v lost output %v", name, tag))
+				continue
+			}
+			for _, reason := range comparePCollections(prev.GetComponents(), a.GetOutputs()[tag], next.GetComponents(), id) {
+				problems = append(problems, fmt.Errorf("transform %v output %v %v", name, tag, reason))
+			}
+		}
+	}
+	return problems
+}
+
+// leaves returns the primitive transforms of the pipeline by unique name.
+func leaves(p *pb.Pipeline) map[string]*pb.PTransform {
+	ret := make(map[string]*pb.PTransform)
+	for _, t := range p.GetComponents().GetTransforms() {
+		if len(t.GetSubtransforms()) == 0 {
+			ret[t.GetUniqueName()] = t
+		}
+	}
+	return ret
+}
+
+func comparePCollections(ac *pb.Components, aid string, bc *pb.Components, bid string) []string {
+	a, b := ac.GetPcollections()[aid], bc.GetPcollections()[bid]
+
+	var ret []string
+	if x, y := describeCoder(ac, a.GetCoderId()), describeCoder(bc, b.GetCoderId()); x != y {
+		ret = append(ret, fmt.Sprintf("changed coder from %v to %v", x, y))
+	}
+	if x, y := describeWindowing(ac, a.GetWindowingStrategyId()), describeWindowing(bc, b.GetWindowingStrategyId()); x != y {
+		ret = append(ret, fmt.Sprintf("changed windowing from %v to %v", x, y))
+	}
+	if x, y := a.GetIsBounded(), b.GetIsBounded(); x != y {
+		ret = append(ret, fmt.Sprintf("changed from %v to %v", x, y))
+	}
+	return ret
+}
+
+// describeCoder returns a structural description of a coder, which does not
+// depend on the ids of the coders.
+func describeCoder(c *pb.Components, id string) string {
+	coder, ok := c.GetCoders()[id]
+	if !ok {
+		return fmt.Sprintf("<unknown coder %v>", id)
+	}
+	spec := coder.GetSpec().GetSpec()
+	ret := spec.GetUrn()
+	if len(spec.GetPayload()) > 0 {
+		ret += fmt.Sprintf("[%x]", spec.GetPayload())
+	}
+	if len(coder.GetComponentCoderIds()) > 0 {
+		var comps []string
+		for _, cid := range coder.GetComponentCoderIds() {
+			comps = append(comps, describeCoder(c, cid))
+		}
+		ret += "<" + strings.Join(comps, ",") + ">"
+	}
+	return ret
+}
+
+// describeWindowing returns a structural description of a windowing
+// strategy, which does not depend on the ids of the coders.
+func describeWindowing(c *pb.Components, id string) string {
+	ws, ok := c.GetWindowingStrategies()[id]
+	if !ok {
+		return fmt.Sprintf("<unknown windowing strategy %v>", id)
+	}
+	ws = proto.Clone(ws).(*pb.WindowingStrategy)
+	coder := describeCoder(c, ws.GetWindowCoderId())
+	ws.WindowCoderId = ""
+	return fmt.Sprintf("%v/%v", proto.CompactTextString(ws), coder)
+}
+
+func sortedKeys(m map[string]string) []string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
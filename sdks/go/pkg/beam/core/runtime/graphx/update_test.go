@@ -0,0 +1,70 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphx_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+)
+
+func TestCheckUpdate(t *testing.T) {
+	build := func(n int, change func(e *graph.MultiEdge)) *pb.Pipeline {
+		g := graph.New()
+		var edges []*graph.MultiEdge
+		for i := 0; i < n; i++ {
+			e := pick(t, g)
+			if change != nil {
+				change(e)
+			}
+			edges = append(edges, e)
+		}
+		p, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: "foo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	prev := build(1, nil)
+
+	tests := []struct {
+		name string
+		next *pb.Pipeline
+		want []string
+	}{
+		{"same", build(1, nil), nil},
+		{"added", build(2, nil), nil},
+		{"renamed", build(1, func(e *graph.MultiEdge) { e.Label = "renamed" }), []string{"was removed"}},
+		{"coder", build(1, func(e *graph.MultiEdge) { e.Output[1].To.Coder = coder.NewBytes() }), []string{"output i1 changed coder"}},
+		{"unbounded", build(1, func(e *graph.MultiEdge) { e.Output[0].To.SetBounded(false) }), []string{"output i0 changed from BOUNDED to UNBOUNDED"}},
+	}
+	for _, test := range tests {
+		problems := graphx.CheckUpdate(prev, test.next)
+		if len(problems) != len(test.want) {
+			t.Errorf("CheckUpdate(%v) = %v, want %v", test.name, problems, test.want)
+			continue
+		}
+		for i, want := range test.want {
+			if !strings.Contains(problems[i].Error(), want) {
+				t.Errorf("CheckUpdate(%v)[%v] = %v, want it to contain %q", test.name, i, problems[i], want)
+			}
+		}
+	}
+}
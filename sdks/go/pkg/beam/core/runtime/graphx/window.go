@@ -0,0 +1,361 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphx
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx/v1"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/protox"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+)
+
+// MarshalWindowFn translates a window into the standard model WindowFn.
+func MarshalWindowFn(w *window.Window) (*pb.SdkFunctionSpec, error) {
+	var spec *pb.FunctionSpec
+	switch w.Kind() {
+	case window.GlobalWindow:
+		spec = &pb.FunctionSpec{Urn: URNGlobalWindowsWindowFn}
+
+	case window.FixedWindows:
+		payload, err := proto.Marshal(&pb.FixedWindowsPayload{
+			Size: ptypes.DurationProto(w.Size()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		spec = &pb.FunctionSpec{Urn: URNFixedWindowsWindowFn, Payload: payload}
+
+	case window.SlidingWindows:
+		payload, err := proto.Marshal(&pb.SlidingWindowsPayload{
+			Size:   ptypes.DurationProto(w.Size()),
+			Period: ptypes.DurationProto(w.Period()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		spec = &pb.FunctionSpec{Urn: URNSlidingWindowsWindowFn, Payload: payload}
+
+	case window.Sessions:
+		payload, err := proto.Marshal(&pb.SessionsPayload{
+			GapSize: ptypes.DurationProto(w.Gap()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		spec = &pb.FunctionSpec{Urn: URNSessionsWindowFn, Payload: payload}
+
+	case window.Custom:
+		payload, err := encodeCustomWindowFn(w)
+		if err != nil {
+			return nil, err
+		}
+		spec = &pb.FunctionSpec{Urn: URNCustomWindowFn, Payload: payload}
+
+	default:
+		return nil, fmt.Errorf("unexpected window kind: %v", w)
+	}
+	return &pb.SdkFunctionSpec{Spec: spec}, nil
+}
+
+// UnmarshalWindowFn translates a standard model WindowFn into a window.
+func UnmarshalWindowFn(spec *pb.FunctionSpec) (*window.Window, error) {
+	switch spec.GetUrn() {
+	case URNGlobalWindowsWindowFn:
+		return window.NewGlobalWindow(), nil
+
+	case URNFixedWindowsWindowFn:
+		var payload pb.FixedWindowsPayload
+		if err := proto.Unmarshal(spec.GetPayload(), &payload); err != nil {
+			return nil, err
+		}
+		size, err := ptypes.Duration(payload.GetSize())
+		if err != nil {
+			return nil, err
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid fixed window size: %v", size)
+		}
+		return window.NewFixedWindows(size), nil
+
+	case URNSlidingWindowsWindowFn:
+		var payload pb.SlidingWindowsPayload
+		if err := proto.Unmarshal(spec.GetPayload(), &payload); err != nil {
+			return nil, err
+		}
+		size, err := ptypes.Duration(payload.GetSize())
+		if err != nil {
+			return nil, err
+		}
+		period, err := ptypes.Duration(payload.GetPeriod())
+		if err != nil {
+			return nil, err
+		}
+		if size <= 0 || period <= 0 {
+			return nil, fmt.Errorf("invalid sliding window size or period: %v, %v", size, period)
+		}
+		return window.NewSlidingWindows(period, size), nil
+
+	case URNSessionsWindowFn:
+		var payload pb.SessionsPayload
+		if err := proto.Unmarshal(spec.GetPayload(), &payload); err != nil {
+			return nil, err
+		}
+		gap, err := ptypes.Duration(payload.GetGapSize())
+		if err != nil {
+			return nil, err
+		}
+		if gap <= 0 {
+			return nil, fmt.Errorf("invalid session gap: %v", gap)
+		}
+		return window.NewSessions(gap), nil
+
+	case URNCustomWindowFn:
+		return decodeCustomWindowFn(spec.GetPayload())
+
+	default:
+		return nil, fmt.Errorf("unsupported window fn: %v", spec.GetUrn())
+	}
+}
+
+// customWindowFn is the serialized form of custom windows.
+type customWindowFn struct {
+	Name   string     `json:"name"`
+	Assign *v1.UserFn `json:"assign"`
+	Merge  *v1.UserFn `json:"merge,omitempty"`
+}
+
+func encodeCustomWindowFn(w *window.Window) ([]byte, error) {
+	ref := customWindowFn{Name: w.Name()}
+
+	var err error
+	if ref.Assign, err = EncodeUserFn(w.AssignFn()); err != nil {
+		return nil, fmt.Errorf("bad assign of %v: %v", w, err)
+	}
+	if w.MergeFn() != nil {
+		if ref.Merge, err = EncodeUserFn(w.MergeFn()); err != nil {
+			return nil, fmt.Errorf("bad merge of %v: %v", w, err)
+		}
+	}
+	return json.Marshal(ref)
+}
+
+func decodeCustomWindowFn(data []byte) (*window.Window, error) {
+	var ref customWindowFn
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, fmt.Errorf("bad custom window fn: %v", err)
+	}
+
+	assign, err := resolveUserFn(ref.Assign)
+	if err != nil {
+		return nil, err
+	}
+	var merge interface{}
+	if ref.Merge != nil {
+		if merge, err = resolveUserFn(ref.Merge); err != nil {
+			return nil, err
+		}
+	}
+	return window.NewCustom(ref.Name, assign, merge)
+}
+
+// makeWindowIntoPayload returns the WindowInto transform spec for the window.
+func makeWindowIntoPayload(w *window.Window) (*pb.FunctionSpec, error) {
+	fn, err := MarshalWindowFn(w)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.FunctionSpec{Urn: URNWindow, Payload: protox.MustEncode(&pb.WindowIntoPayload{WindowFn: fn})}, nil
+}
+
+// UnmarshalWindowingStrategy translates a model windowing strategy into a
+// window, including its trigger.
+func UnmarshalWindowingStrategy(ws *pb.WindowingStrategy) (*window.Window, error) {
+	w, err := UnmarshalWindowFn(ws.GetWindowFn().GetSpec())
+	if err != nil {
+		return nil, err
+	}
+	if lateness := ws.GetAllowedLateness(); lateness > 0 {
+		w = w.WithAllowedLateness(time.Duration(lateness) * time.Millisecond)
+	}
+	if ws.GetAccumulationMode() == pb.AccumulationMode_ACCUMULATING {
+		w = w.WithAccumulationMode(window.Accumulating)
+	}
+	if ws.GetTrigger() == nil {
+		return w, nil
+	}
+	t, err := UnmarshalTrigger(ws.GetTrigger())
+	if err != nil {
+		return nil, err
+	}
+	if t.Kind == window.DefaultTrigger {
+		return w, nil
+	}
+	return w.WithTrigger(t), nil
+}
+
+// MarshalTrigger translates a trigger into the model trigger.
+func MarshalTrigger(t *window.Trigger) *pb.Trigger {
+	switch t.Kind {
+	case window.DefaultTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_Default_{Default: &pb.Trigger_Default{}}}
+	case window.AlwaysTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_Always_{Always: &pb.Trigger_Always{}}}
+	case window.NeverTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_Never_{Never: &pb.Trigger_Never{}}}
+
+	case window.AfterWatermarkTrigger:
+		eow := &pb.Trigger_AfterEndOfWindow{}
+		if t.Early != nil {
+			eow.EarlyFirings = MarshalTrigger(t.Early)
+		}
+		if t.Late != nil {
+			eow.LateFirings = MarshalTrigger(t.Late)
+		}
+		return &pb.Trigger{Trigger: &pb.Trigger_AfterEndOfWindow_{AfterEndOfWindow: eow}}
+
+	case window.AfterProcessingTimeTrigger:
+		delay := &pb.TimestampTransform{
+			TimestampTransform: &pb.TimestampTransform_Delay_{
+				Delay: &pb.TimestampTransform_Delay{DelayMillis: int64(t.Delay / time.Millisecond)},
+			},
+		}
+		return &pb.Trigger{Trigger: &pb.Trigger_AfterProcessingTime_{
+			AfterProcessingTime: &pb.Trigger_AfterProcessingTime{TimestampTransforms: []*pb.TimestampTransform{delay}},
+		}}
+
+	case window.AfterCountTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_ElementCount_{ElementCount: &pb.Trigger_ElementCount{ElementCount: t.Count}}}
+
+	case window.RepeatTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_Repeat_{Repeat: &pb.Trigger_Repeat{Subtrigger: MarshalTrigger(t.Sub[0])}}}
+	case window.AfterAnyTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_AfterAny_{AfterAny: &pb.Trigger_AfterAny{Subtriggers: marshalTriggers(t.Sub)}}}
+	case window.AfterAllTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_AfterAll_{AfterAll: &pb.Trigger_AfterAll{Subtriggers: marshalTriggers(t.Sub)}}}
+	case window.AfterEachTrigger:
+		return &pb.Trigger{Trigger: &pb.Trigger_AfterEach_{AfterEach: &pb.Trigger_AfterEach{Subtriggers: marshalTriggers(t.Sub)}}}
+
+	default:
+		panic(fmt.Sprintf("unexpected trigger: %v", t))
+	}
+}
+
+func marshalTriggers(list []*window.Trigger) []*pb.Trigger {
+	var ret []*pb.Trigger
+	for _, t := range list {
+		ret = append(ret, MarshalTrigger(t))
+	}
+	return ret
+}
+
+// UnmarshalTrigger translates a model trigger into a trigger.
+func UnmarshalTrigger(t *pb.Trigger) (*window.Trigger, error) {
+	switch x := t.GetTrigger().(type) {
+	case *pb.Trigger_Default_:
+		return window.TriggerDefault(), nil
+	case *pb.Trigger_Always_:
+		return window.TriggerAlways(), nil
+	case *pb.Trigger_Never_:
+		return window.TriggerNever(), nil
+
+	case *pb.Trigger_AfterEndOfWindow_:
+		ret := window.TriggerAfterWatermark()
+		if early := x.AfterEndOfWindow.GetEarlyFirings(); early != nil {
+			e, err := UnmarshalTrigger(early)
+			if err != nil {
+				return nil, err
+			}
+			ret = ret.EarlyFiring(e)
+		}
+		if late := x.AfterEndOfWindow.GetLateFirings(); late != nil {
+			l, err := UnmarshalTrigger(late)
+			if err != nil {
+				return nil, err
+			}
+			ret = ret.LateFiring(l)
+		}
+		return ret, nil
+
+	case *pb.Trigger_AfterProcessingTime_:
+		var delay time.Duration
+		for _, tt := range x.AfterProcessingTime.GetTimestampTransforms() {
+			d, ok := tt.GetTimestampTransform().(*pb.TimestampTransform_Delay_)
+			if !ok {
+				return nil, fmt.Errorf("unsupported timestamp transform: %v", tt)
+			}
+			delay += time.Duration(d.Delay.GetDelayMillis()) * time.Millisecond
+		}
+		return window.TriggerAfterProcessingTime(delay), nil
+
+	case *pb.Trigger_ElementCount_:
+		if n := x.ElementCount.GetElementCount(); n > 0 {
+			return window.TriggerAfterCount(n), nil
+		}
+		return nil, fmt.Errorf("invalid element count trigger: %v", t)
+
+	case *pb.Trigger_Repeat_:
+		sub, err := UnmarshalTrigger(x.Repeat.GetSubtrigger())
+		if err != nil {
+			return nil, err
+		}
+		return window.TriggerRepeat(sub), nil
+
+	case *pb.Trigger_AfterAny_:
+		list, err := unmarshalTriggers(x.AfterAny.GetSubtriggers())
+		if err != nil {
+			return nil, err
+		}
+		return window.TriggerAfterAny(list...), nil
+
+	case *pb.Trigger_AfterAll_:
+		list, err := unmarshalTriggers(x.AfterAll.GetSubtriggers())
+		if err != nil {
+			return nil, err
+		}
+		return window.TriggerAfterAll(list...), nil
+
+	case *pb.Trigger_AfterEach_:
+		list, err := unmarshalTriggers(x.AfterEach.GetSubtriggers())
+		if err != nil {
+			return nil, err
+		}
+		return window.TriggerAfterEach(list...), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported trigger: %v", t)
+	}
+}
+
+func unmarshalTriggers(list []*pb.Trigger) ([]*window.Trigger, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("missing subtriggers")
+	}
+	var ret []*window.Trigger
+	for _, t := range list {
+		u, err := UnmarshalTrigger(t)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, u)
+	}
+	return ret, nil
+}
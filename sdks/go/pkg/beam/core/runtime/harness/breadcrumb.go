@@ -0,0 +1,230 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+// BreadcrumbName is the name of the crash breadcrumb file in the worker's
+// semi-persistent directory. The container boot code uses the same name.
+const BreadcrumbName = "crash_breadcrumb.json"
+
+var (
+	// BreadcrumbFile is the file, if any, where the harness periodically
+	// records what it is doing. If the process is killed, such as for running
+	// out of memory, the last breadcrumb is reported when the worker restarts.
+	BreadcrumbFile string
+
+	// BreadcrumbInterval is the period between breadcrumbs.
+	BreadcrumbInterval = 5 * time.Second
+)
+
+// Breadcrumb captures the state of the harness at a point in time.
+type Breadcrumb struct {
+	Time    time.Time     `json:"time"`
+	Bundles []BundleCrumb `json:"bundles,omitempty"`
+	Memory  MemoryCrumb   `json:"memory"`
+	Panic   string        `json:"panic,omitempty"`
+}
+
+// BundleCrumb captures the progress of an active bundle.
+type BundleCrumb struct {
+	// Instruction is the ProcessBundle instruction id.
+	Instruction string `json:"instruction"`
+	// Plan is the bundle descriptor id, which identifies the stage.
+	Plan string `json:"plan"`
+	// Transform is the id of the transform reading the bundle input.
+	Transform string `json:"transform,omitempty"`
+	// Elements is the number of input elements read so far in the bundle.
+	Elements int64 `json:"elements"`
+	// Start is the time the bundle started, if known.
+	Start time.Time `json:"start"`
+	// Step is the transform processing an element, if any.
+	Step string `json:"step,omitempty"`
+	// Element is a sample of the element being processed by the step.
+	Element string `json:"element,omitempty"`
+	// ElementTime is how long the step has been processing the element.
+	ElementTime time.Duration `json:"element_time,omitempty"`
+}
+
+// MemoryCrumb captures the memory use of the harness, in bytes.
+type MemoryCrumb struct {
+	HeapAlloc uint64 `json:"heap_alloc"`
+	HeapInuse uint64 `json:"heap_inuse"`
+	Sys       uint64 `json:"sys"`
+	NumGC     uint32 `json:"num_gc"`
+}
+
+func (b *Breadcrumb) String() string {
+	ret := fmt.Sprintf("at %v, heap %v MB of %v MB total after %v GCs", b.Time.Format(time.RFC3339), b.Memory.HeapAlloc>>20, b.Memory.Sys>>20, b.Memory.NumGC)
+	if b.Panic != "" {
+		ret += fmt.Sprintf(", panic: %v", b.Panic)
+	}
+	if len(b.Bundles) == 0 {
+		return ret + ", no active bundles"
+	}
+	for _, c := range b.Bundles {
+		ret += fmt.Sprintf("\n  bundle %v of stage %v: %v elements read by %v", c.Instruction, c.Plan, c.Elements, c.Transform)
+		if !c.Start.IsZero() {
+			ret += fmt.Sprintf(" in %v", b.Time.Sub(c.Start).Round(time.Millisecond))
+		}
+		if c.Step != "" {
+			ret += fmt.Sprintf(", %v processing %v for %v", c.Step, c.Element, c.ElementTime.Round(time.Millisecond))
+		}
+	}
+	return ret
+}
+
+// WriteBreadcrumb writes the breadcrumb to the given file. The file is
+// replaced atomically, so a crash while writing leaves the prior breadcrumb.
+func WriteBreadcrumb(filename string, b *Breadcrumb) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// ReadBreadcrumb reads a breadcrumb from the given file.
+func ReadBreadcrumb(filename string) (*Breadcrumb, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var b Breadcrumb
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid breadcrumb %v: %v", filename, err)
+	}
+	return &b, nil
+}
+
+// SetupBreadcrumbs places breadcrumbs in the given directory.
+func SetupBreadcrumbs(dir string) {
+	BreadcrumbFile = filepath.Join(dir, BreadcrumbName)
+}
+
+// RecordCrash writes a final breadcrumb for a harness panic outside user code.
+// It is a no-op if breadcrumbs are not enabled.
+func RecordCrash(r interface{}) {
+	if BreadcrumbFile == "" {
+		return
+	}
+	b := snapshot(current)
+	b.Panic = fmt.Sprint(r)
+	if err := WriteBreadcrumb(BreadcrumbFile, b); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write crash breadcrumb: %v", err)
+	}
+}
+
+// current is the control of the running harness, if any.
+var current *control
+
+// reportBreadcrumb logs the breadcrumb left by a prior worker, if any, and
+// removes it. It must be called once remote logging is set up, so that the
+// crash is surfaced to the runner.
+func reportBreadcrumb(ctx context.Context) {
+	if BreadcrumbFile == "" {
+		return
+	}
+	b, err := ReadBreadcrumb(BreadcrumbFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Warnf(ctx, "Failed to read crash breadcrumb: %v", err)
+	} else {
+		log.Errorf(ctx, "Previous worker crashed %v", b)
+	}
+	os.Remove(BreadcrumbFile)
+}
+
+// leaveBreadcrumbs periodically records the state of the harness until the
+// context is done. The breadcrumb is removed on return, as the harness then
+// exits normally.
+func leaveBreadcrumbs(ctx context.Context, c *control) {
+	if BreadcrumbFile == "" {
+		return
+	}
+
+	ticker := time.NewTicker(BreadcrumbInterval)
+	defer ticker.Stop()
+	for {
+		if err := WriteBreadcrumb(BreadcrumbFile, snapshot(c)); err != nil {
+			log.Warnf(ctx, "Failed to write crash breadcrumb: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			os.Remove(BreadcrumbFile)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// snapshot returns a breadcrumb for the current state of the harness.
+func snapshot(c *control) *Breadcrumb {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	b := &Breadcrumb{
+		Time: time.Now(),
+		Memory: MemoryCrumb{
+			HeapAlloc: m.HeapAlloc,
+			HeapInuse: m.HeapInuse,
+			Sys:       m.Sys,
+			NumGC:     m.NumGC,
+		},
+	}
+	if c == nil {
+		return b
+	}
+
+	c.mu.Lock()
+	for id, plan := range c.active {
+		progress := plan.Progress()
+		sample := plan.Sample()
+		b.Bundles = append(b.Bundles, BundleCrumb{
+			Instruction: id,
+			Plan:        plan.ID(),
+			Transform:   progress.ID,
+			Elements:    progress.Count,
+			Start:       c.started[id],
+			Step:        sample.Transform,
+			Element:     sample.Element,
+			ElementTime: sample.Duration,
+		})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(b.Bundles, func(i, j int) bool { return b.Bundles[i].Instruction < b.Bundles[j].Instruction })
+	return b
+}
@@ -0,0 +1,96 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+)
+
+func TestBreadcrumbs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "breadcrumb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetupBreadcrumbs(dir)
+	defer func() { BreadcrumbFile = "" }()
+
+	out := &exec.Discard{UID: 2}
+	plan, err := exec.NewPlan("stage", []exec.Unit{&exec.DataSource{UID: 1, Out: out}, out})
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	c := &control{active: map[string]*exec.Plan{"bundle": plan}}
+
+	// (1) The harness leaves breadcrumbs while running.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		leaveBreadcrumbs(ctx, c)
+		close(done)
+	}()
+
+	var b *Breadcrumb
+	for i := 0; i < 100 && b == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		b, _ = ReadBreadcrumb(filepath.Join(dir, BreadcrumbName))
+	}
+	if b == nil {
+		t.Fatalf("no breadcrumb written")
+	}
+	if len(b.Bundles) != 1 || b.Bundles[0].Instruction != "bundle" || b.Bundles[0].Plan != "stage" {
+		t.Errorf("breadcrumb bundles = %v, want bundle of stage", b.Bundles)
+	}
+	if b.Memory.Sys == 0 {
+		t.Errorf("breadcrumb memory = %v, want memory stats", b.Memory)
+	}
+
+	// (2) A normal exit removes the breadcrumb.
+
+	cancel()
+	<-done
+	if _, err := os.Stat(BreadcrumbFile); !os.IsNotExist(err) {
+		t.Errorf("breadcrumb remains after exit: %v", err)
+	}
+
+	// (3) A crash leaves a breadcrumb, which is reported and removed on restart.
+
+	current = c
+	defer func() { current = nil }()
+	RecordCrash("boom")
+
+	b, err = ReadBreadcrumb(BreadcrumbFile)
+	if err != nil {
+		t.Fatalf("ReadBreadcrumb failed: %v", err)
+	}
+	if b.Panic != "boom" || !strings.Contains(b.String(), "bundle bundle of stage stage") {
+		t.Errorf("crash breadcrumb = %v, want panic boom in bundle of stage", b)
+	}
+
+	reportBreadcrumb(context.Background())
+	if _, err := os.Stat(BreadcrumbFile); !os.IsNotExist(err) {
+		t.Errorf("breadcrumb remains after report: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// DataCompressionOption is the pipeline option that selects the compression
+// of the FnAPI data channels, such as "gzip". The compressor must be
+// registered with grpc/encoding and the runner must support it as well.
+// Compression trades CPU for network and mainly pays off for highly
+// compressible elements sent across zones. Other compressors, such as
+// snappy, can be plugged in by registering them with
+// encoding.RegisterCompressor in an init function of the pipeline binary.
+const DataCompressionOption = "data_compression"
+
+func init() {
+	if encoding.GetCompressor(gzipName) == nil {
+		encoding.RegisterCompressor(&gzipCompressor{})
+	}
+}
+
+// dataCallOptions returns the call options for the data channels given the
+// compression, if any. An unknown compressor disables compression.
+func dataCallOptions(ctx context.Context, compression string) []grpc.CallOption {
+	switch compression {
+	case "", "none":
+		return nil
+	}
+	if encoding.GetCompressor(compression) == nil {
+		log.Errorf(ctx, "Unknown data compression %q, sending uncompressed data", compression)
+		return nil
+	}
+	log.Infof(ctx, "Using %v compression for data channels", compression)
+	return []grpc.CallOption{grpc.UseCompressor(compression)}
+}
+
+// setupDataCompression configures compression of the data channels from the
+// pipeline options.
+func setupDataCompression(ctx context.Context, m *DataManager) {
+	m.opts = dataCallOptions(ctx, runtime.GlobalOptions.Get(DataCompressionOption))
+}
+
+const gzipName = "gzip"
+
+// gzipCompressor is a gzip grpc/encoding compressor, which pools writers
+// to avoid their considerable allocation cost.
+type gzipCompressor struct {
+	pool sync.Pool
+}
+
+func (c *gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if z, ok := c.pool.Get().(*gzipWriter); ok {
+		z.Reset(w)
+		return z, nil
+	}
+	return &gzipWriter{Writer: gzip.NewWriter(w), pool: &c.pool}, nil
+}
+
+func (c *gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func (c *gzipCompressor) Name() string {
+	return gzipName
+}
+
+type gzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (z *gzipWriter) Close() error {
+	defer z.pool.Put(z)
+	return z.Writer.Close()
+}
@@ -0,0 +1,80 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+)
+
+func TestGzipCompressor(t *testing.T) {
+	c := encoding.GetCompressor("gzip")
+	if c == nil {
+		t.Fatalf("gzip compressor not registered")
+	}
+
+	msg := strings.Repeat("highly compressible ", 1000)
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		if err != nil {
+			t.Fatalf("Compress failed: %v", err)
+		}
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if buf.Len() >= len(msg)/10 {
+			t.Errorf("compressed size = %v, want less than %v", buf.Len(), len(msg)/10)
+		}
+
+		r, err := c.Decompress(&buf)
+		if err != nil {
+			t.Fatalf("Decompress failed: %v", err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll failed: %v", err)
+		}
+		if string(data) != msg {
+			t.Errorf("round trip %v = %v bytes, want %v", i, len(data), len(msg))
+		}
+	}
+}
+
+func TestDataCallOptions(t *testing.T) {
+	tests := []struct {
+		compression string
+		n           int
+	}{
+		{"", 0},
+		{"none", 0},
+		{"gzip", 1},
+		{"unknown", 0},
+	}
+	for _, test := range tests {
+		if got := dataCallOptions(context.Background(), test.compression); len(got) != test.n {
+			t.Errorf("dataCallOptions(%q) = %v options, want %v", test.compression, len(got), test.n)
+		}
+	}
+}
@@ -28,9 +28,17 @@ import (
 	"google.golang.org/grpc"
 )
 
+// chunkSize is the number of bytes of encoded elements batched into a single
+// gRPC message. Max is slightly higher. The harness sets it from the
+// "data_chunk_size" pipeline option, if present.
+var chunkSize = int(4e6)
+
 const (
-	chunkSize   = int(4e6) // Bytes to put in a single gRPC message. Max is slightly higher.
-	bufElements = 20       // Number of chunks buffered per reader.
+	bufElements = 20 // Number of chunks buffered per reader.
+	bufSends    = 10 // Number of chunks queued for sending per channel.
+
+	reconnectBackoff    = 100 * time.Millisecond // Delay before reconnecting a failed data stream.
+	maxReconnectBackoff = 10 * time.Second
 )
 
 // This is a reduced version of the full gRPC interface to help with testing.
@@ -45,6 +53,7 @@ type dataClient interface {
 // are generally used, each managing multiple logical byte streams.
 type DataManager struct {
 	ports map[string]*DataChannel
+	opts  []grpc.CallOption // options of the data streams, notably compression
 	mu    sync.Mutex
 }
 
@@ -75,7 +84,7 @@ func (m *DataManager) open(ctx context.Context, port exec.Port) (*DataChannel, e
 		return con, nil
 	}
 
-	ch, err := NewDataChannel(ctx, port)
+	ch, err := NewDataChannel(ctx, port, m.opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -84,29 +93,58 @@ func (m *DataManager) open(ctx context.Context, port exec.Port) (*DataChannel, e
 }
 
 // DataChannel manages a single grpc connection to the FnHarness.
+//
+// If the data stream fails, the streams of in-flight bundles fail, such that
+// the bundles fail and are retried by the runner, and the data stream is
+// re-established on the same connection for later bundles. Outgoing data is
+// sent by a single goroutine from a bounded queue, which blocks writers while
+// full.
 type DataChannel struct {
 	cc     *grpc.ClientConn
-	client dataClient
+	client dataClient                 // protected by mu
+	open   func() (dataClient, error) // re-establishes the stream, if set
 	port   exec.Port
+	sendq  chan outgoing
 
 	writers map[string]*dataWriter
 	readers map[string]*dataReader
-	// TODO: early/late closed, bad instructions, finer locks, reconnect?
+	// TODO: early/late closed, bad instructions, finer locks?
 
 	mu sync.Mutex
 }
 
-func NewDataChannel(ctx context.Context, port exec.Port) (*DataChannel, error) {
+// outgoing is a message queued for sending by a writer.
+type outgoing struct {
+	msg  *pb.Elements
+	w    *dataWriter
+	done chan error // receives the result of the send, if set
+}
+
+// NewDataChannel connects a data channel to the given port. The call options
+// apply to the data stream.
+func NewDataChannel(ctx context.Context, port exec.Port, opts ...grpc.CallOption) (*DataChannel, error) {
 	cc, err := dial(ctx, port.URL, 15*time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %v", err)
 	}
-	client, err := pb.NewBeamFnDataClient(cc).Data(ctx)
+	open := func() (dataClient, error) {
+		client, err := pb.NewBeamFnDataClient(cc).Data(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to data service: %v", err)
+		}
+		return client, nil
+	}
+	client, err := open()
 	if err != nil {
 		cc.Close()
-		return nil, fmt.Errorf("failed to connect to data service: %v", err)
+		return nil, err
 	}
-	return makeDataChannel(ctx, cc, client, port)
+	ret, err := makeDataChannel(ctx, cc, client, port)
+	if err != nil {
+		return nil, err
+	}
+	ret.open = open
+	return ret, nil
 }
 
 func makeDataChannel(ctx context.Context, cc *grpc.ClientConn, client dataClient, port exec.Port) (*DataChannel, error) {
@@ -114,10 +152,12 @@ func makeDataChannel(ctx context.Context, cc *grpc.ClientConn, client dataClient
 		cc:      cc,
 		client:  client,
 		port:    port,
+		sendq:   make(chan outgoing, bufSends),
 		writers: make(map[string]*dataWriter),
 		readers: make(map[string]*dataReader),
 	}
 	go ret.read(ctx)
+	go ret.send(ctx)
 
 	return ret, nil
 }
@@ -131,16 +171,34 @@ func (c *DataChannel) OpenWrite(ctx context.Context, id exec.StreamID) (io.Write
 }
 
 func (c *DataChannel) read(ctx context.Context) {
+	for {
+		err := c.receive(ctx)
+		if err == io.EOF {
+			// TODO(herohde) 10/12/2017: can this happen before shutdown? Reconnect?
+			log.Warnf(ctx, "DataChannel %v closed", c.port)
+			return
+		}
+
+		log.Errorf(ctx, "DataChannel %v failed: %v", c.port, err)
+		c.fail(fmt.Errorf("data channel %v failed: %v", c.port, err))
+		if err := c.reconnect(ctx); err != nil {
+			log.Errorf(ctx, "DataChannel %v not re-established: %v", c.port, err)
+			return
+		}
+	}
+}
+
+// receive passes the incoming data to the readers until the stream fails.
+func (c *DataChannel) receive(ctx context.Context) error {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+
 	cache := make(map[string]*dataReader)
 	for {
-		msg, err := c.client.Recv()
+		msg, err := client.Recv()
 		if err != nil {
-			if err == io.EOF {
-				// TODO(herohde) 10/12/2017: can this happen before shutdown? Reconnect?
-				log.Warnf(ctx, "DataChannel %v closed", c.port)
-				return
-			}
-			panic(fmt.Errorf("channel %v bad: %v", c.port, err))
+			return err
 		}
 
 		recordStreamReceive(msg)
@@ -172,7 +230,7 @@ func (c *DataChannel) read(ctx context.Context) {
 			}
 			if len(elm.GetData()) == 0 {
 				// Sentinel EOF segment for stream. Close buffer to signal EOF.
-				close(r.buf)
+				r.end(nil)
 
 				// Clean up local bookkeeping. We'll never see another message
 				// for it again. We have to be careful not to remove the real
@@ -195,6 +253,79 @@ func (c *DataChannel) read(ctx context.Context) {
 	}
 }
 
+// fail ends the streams of the in-flight bundles with the given error.
+// Incoming streams end once their buffered data is read.
+func (c *DataChannel) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, r := range c.readers {
+		r.end(err)
+		delete(c.readers, id)
+	}
+	for id, w := range c.writers {
+		if w.err == nil {
+			w.err = err
+		}
+		delete(c.writers, id)
+	}
+}
+
+// reconnect re-establishes the data stream with exponential backoff, until
+// it succeeds or the context is done.
+func (c *DataChannel) reconnect(ctx context.Context) error {
+	if c.open == nil {
+		return fmt.Errorf("reconnect not supported")
+	}
+
+	backoff := reconnectBackoff
+	for {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		client, err := c.open()
+		if err == nil {
+			c.mu.Lock()
+			c.client = client
+			c.mu.Unlock()
+
+			log.Infof(ctx, "DataChannel %v re-established", c.port)
+			return nil
+		}
+		log.Warnf(ctx, "DataChannel %v: %v", c.port, err)
+
+		if backoff *= 2; backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+// send sends the queued messages. gRPC requires a single goroutine to send
+// on a stream. A failed send fails the writer.
+func (c *DataChannel) send(ctx context.Context) {
+	for out := range c.sendq {
+		c.mu.Lock()
+		client := c.client
+		c.mu.Unlock()
+
+		recordStreamSend(out.msg)
+		err := client.Send(out.msg)
+		if err != nil {
+			c.mu.Lock()
+			if out.w.err == nil {
+				out.w.err = fmt.Errorf("data channel %v failed: %v", c.port, err)
+			}
+			c.mu.Unlock()
+		}
+		if out.done != nil {
+			out.done <- err
+		}
+	}
+}
+
 func (c *DataChannel) makeReader(ctx context.Context, id exec.StreamID) *dataReader {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -204,12 +335,14 @@ func (c *DataChannel) makeReader(ctx context.Context, id exec.StreamID) *dataRea
 		return r
 	}
 
-	r := &dataReader{id: sid, buf: make(chan []byte, bufElements), done: make(chan bool, 1), channel: c}
+	r := &dataReader{id: sid, buf: make(chan []byte, bufElements), done: make(chan bool, 1), closed: make(chan struct{}), channel: c}
 	c.readers[sid] = r
 	return r
 }
 
 func (c *DataChannel) removeReader(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	delete(c.readers, id)
 }
 
@@ -231,12 +364,29 @@ type dataReader struct {
 	id        string
 	buf       chan []byte
 	done      chan bool
+	closed    chan struct{} // unblocks reads once closed
 	cur       []byte
 	channel   *DataChannel
 	completed bool
+	ended     bool  // buf closed, set by the channel
+	err       error // failure of the stream, if any, set before buf is closed
 }
 
+// end ends the stream, if not already ended, with the given error, if any.
+// It is only called by the goroutine that receives the data.
+func (r *dataReader) end(err error) {
+	if r.ended {
+		return
+	}
+	r.ended = true
+	r.err = err
+	close(r.buf)
+}
+
+// Close ends the stream. Pending and further reads fail, as a prefetching
+// reader may still be reading.
 func (r *dataReader) Close() error {
+	close(r.closed)
 	r.done <- true
 	r.channel.removeReader(r.id)
 	return nil
@@ -244,9 +394,9 @@ func (r *dataReader) Close() error {
 
 func (r *dataReader) Read(buf []byte) (int, error) {
 	if r.cur == nil {
-		b, ok := <-r.buf
-		if !ok {
-			return 0, io.EOF
+		b, err := r.next()
+		if err != nil {
+			return 0, err
 		}
 		r.cur = b
 	}
@@ -262,24 +412,66 @@ func (r *dataReader) Read(buf []byte) (int, error) {
 	return n, nil
 }
 
+// Buffered returns the number of bytes that can be read without blocking.
+// Decoders use it to decode the elements of a chunk in a batch.
+func (r *dataReader) Buffered() int {
+	return len(r.cur)
+}
+
+// ReadByte reads a single byte. It speeds up decoding of varints.
+func (r *dataReader) ReadByte() (byte, error) {
+	for len(r.cur) == 0 {
+		b, err := r.next()
+		if err != nil {
+			return 0, err
+		}
+		r.cur = b
+	}
+
+	ret := r.cur[0]
+	if len(r.cur) == 1 {
+		r.cur = nil
+	} else {
+		r.cur = r.cur[1:]
+	}
+	return ret, nil
+}
+
+// next returns the next chunk of the stream.
+func (r *dataReader) next() ([]byte, error) {
+	select {
+	case b, ok := <-r.buf:
+		if !ok {
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, io.EOF
+		}
+		return b, nil
+	case <-r.closed:
+		return nil, io.ErrClosedPipe
+	}
+}
+
 type dataWriter struct {
 	buf []byte
 
-	id exec.StreamID
-	ch *DataChannel
+	id  exec.StreamID
+	ch  *DataChannel
+	err error // failure of the stream, if any, protected by ch.mu
 }
 
+// Close flushes the buffered data and ends the stream. It waits until the
+// data is sent.
 func (w *dataWriter) Close() error {
-	// Don't acquire the locks as Flush will do so.
-	err := w.Flush()
-	if err != nil {
+	if err := w.Flush(); err != nil {
 		return err
 	}
 
-	// Now acquire the locks since we're sending.
 	w.ch.mu.Lock()
-	defer w.ch.mu.Unlock()
 	delete(w.ch.writers, w.id.String())
+	w.ch.mu.Unlock()
+
 	target := &pb.Target{PrimitiveTransformReference: w.id.Target.ID, Name: w.id.Target.Name}
 	msg := &pb.Elements{
 		Data: []*pb.Elements_Data{
@@ -293,14 +485,20 @@ func (w *dataWriter) Close() error {
 
 	// TODO(wcn): if this send fails, we have a data channel that's lingering that
 	// the runner is still waiting on. Need some way to identify these and resolve them.
-	recordStreamSend(msg)
-	return w.ch.client.Send(msg)
+	done := make(chan error, 1)
+	w.ch.sendq <- outgoing{msg: msg, w: w, done: done}
+	if err := <-done; err != nil {
+		return err
+	}
+	return w.error()
 }
 
+// Flush queues the buffered data for sending, blocking while the queue of
+// the channel is full. It fails if the stream has failed.
 func (w *dataWriter) Flush() error {
-	w.ch.mu.Lock()
-	defer w.ch.mu.Unlock()
-
+	if err := w.error(); err != nil {
+		return err
+	}
 	if w.buf == nil {
 		return nil
 	}
@@ -316,13 +514,50 @@ func (w *dataWriter) Flush() error {
 		},
 	}
 	w.buf = nil
-	recordStreamSend(msg)
-	return w.ch.client.Send(msg)
+	w.ch.sendq <- outgoing{msg: msg, w: w}
+	return nil
+}
+
+func (w *dataWriter) error() error {
+	w.ch.mu.Lock()
+	defer w.ch.mu.Unlock()
+	return w.err
+}
+
+// Grow pre-allocates space for at least n more bytes in the buffer, up to the
+// chunk size. The buffer at least doubles when it grows, so that reserving
+// space for each element is amortized constant time.
+func (w *dataWriter) Grow(n int) {
+	if limit := chunkSize - len(w.buf); n > limit {
+		n = limit
+	}
+	if cap(w.buf)-len(w.buf) >= n {
+		return
+	}
+	size := 2 * cap(w.buf)
+	if size < len(w.buf)+n {
+		size = len(w.buf) + n
+	}
+	if size > chunkSize {
+		size = chunkSize
+	}
+	buf := make([]byte, len(w.buf), size)
+	copy(buf, w.buf)
+	w.buf = buf
 }
 
+// Write batches the data into chunks of up to chunkSize bytes. Data larger
+// than a chunk is sent in a chunk of its own.
 func (w *dataWriter) Write(p []byte) (n int, err error) {
 	if len(p) > chunkSize {
-		panic(fmt.Sprintf("Incoming message too big for transport: %d > %d", len(p), chunkSize))
+		if err := w.Flush(); err != nil {
+			return 0, err
+		}
+		w.buf = append([]byte(nil), p...)
+		if err := w.Flush(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
 	}
 
 	if len(w.buf)+len(p) > chunkSize {
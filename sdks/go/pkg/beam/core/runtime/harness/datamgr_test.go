@@ -17,9 +17,11 @@ package harness
 
 import (
 	"context"
+	"errors"
 	"io"
 	"io/ioutil"
 	"log"
+	"reflect"
 	"testing"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
@@ -102,3 +104,171 @@ func TestDataChannelTerminateOnClose(t *testing.T) {
 	// channel, meaning consumer code isn't stuck.
 	<-done
 }
+
+// idleClient receives no data until stopped. It signals receiving, if set.
+type idleClient struct {
+	stop chan bool
+	recv chan bool
+}
+
+func (f *idleClient) Recv() (*pb.Elements, error) {
+	if f.recv != nil {
+		f.recv <- true
+	}
+	<-f.stop
+	return nil, io.EOF
+}
+
+func (f *idleClient) Send(*pb.Elements) error {
+	return nil
+}
+
+func TestDataReaderCloseUnblocksRead(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	client := &idleClient{stop: make(chan bool)}
+	defer close(client.stop)
+
+	c, err := makeDataChannel(context.Background(), nil, client, exec.Port{})
+	if err != nil {
+		t.Fatalf("Unexpected error in makeDataChannel: %v", err)
+	}
+	r, _ := c.OpenRead(context.Background(), exec.StreamID{Target: exec.Target{ID: "ptr", Name: "instruction_name"}, InstID: "inst_ref"})
+
+	// A prefetching reader may be blocked reading when the stream is closed.
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 4))
+		errc <- err
+	}()
+	r.Close()
+	if err := <-errc; err != io.ErrClosedPipe {
+		t.Errorf("Read() = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+// failingClient fails once released.
+type failingClient struct {
+	release chan bool
+}
+
+func (f *failingClient) Recv() (*pb.Elements, error) {
+	<-f.release
+	return nil, errors.New("connection reset")
+}
+
+func (f *failingClient) Send(*pb.Elements) error {
+	return errors.New("connection reset")
+}
+
+func TestDataChannelReconnect(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	ctx := context.Background()
+	broken := &failingClient{release: make(chan bool)}
+	next := &idleClient{stop: make(chan bool), recv: make(chan bool, 1)}
+	defer close(next.stop)
+
+	c, err := makeDataChannel(ctx, nil, broken, exec.Port{})
+	if err != nil {
+		t.Fatalf("Unexpected error in makeDataChannel: %v", err)
+	}
+	c.open = func() (dataClient, error) {
+		return next, nil
+	}
+
+	id := exec.StreamID{Target: exec.Target{ID: "ptr", Name: "instruction_name"}, InstID: "inst_ref"}
+	r, _ := c.OpenRead(ctx, id)
+	w, _ := c.OpenWrite(ctx, id)
+	close(broken.release)
+
+	// The streams of the in-flight bundle fail and the data stream is
+	// re-established for later bundles.
+
+	if _, err := r.Read(make([]byte, 4)); err == nil || err == io.EOF {
+		t.Errorf("Read() = %v, want failure", err)
+	}
+	r.Close()
+	<-next.recv
+	if err := w.Close(); err == nil {
+		t.Errorf("Close() succeeded, want failure")
+	}
+
+	w, _ = c.OpenWrite(ctx, exec.StreamID{Target: id.Target, InstID: "next_ref"})
+	if _, err := w.Write([]byte{1, 2, 3}); err != nil {
+		t.Errorf("Write() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+}
+
+// sendingClient records the sizes of the data sent.
+type sendingClient struct {
+	idleClient
+	sizes []int
+}
+
+func (f *sendingClient) Send(msg *pb.Elements) error {
+	for _, d := range msg.Data {
+		f.sizes = append(f.sizes, len(d.Data))
+	}
+	return nil
+}
+
+func TestDataWriterChunks(t *testing.T) {
+	defer func(size int) { chunkSize = size }(chunkSize)
+	chunkSize = 8
+
+	client := &sendingClient{idleClient: idleClient{stop: make(chan bool)}}
+	defer close(client.stop)
+
+	c, err := makeDataChannel(context.Background(), nil, client, exec.Port{})
+	if err != nil {
+		t.Fatalf("Unexpected error in makeDataChannel: %v", err)
+	}
+	w, _ := c.OpenWrite(context.Background(), exec.StreamID{Target: exec.Target{ID: "ptr", Name: "instruction_name"}, InstID: "inst_ref"})
+
+	// Elements are batched up to the chunk size. Larger ones are sent alone.
+
+	for _, n := range []int{3, 3, 3, 20, 1} {
+		if _, err := w.Write(make([]byte, n)); err != nil {
+			t.Fatalf("Write(%v) failed: %v", n, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	want := []int{6, 3, 20, 1, 0}
+	if !reflect.DeepEqual(client.sizes, want) {
+		t.Errorf("sent chunks of %v bytes, want %v", client.sizes, want)
+	}
+}
+
+func TestDataWriterGrow(t *testing.T) {
+	defer func(size int) { chunkSize = size }(chunkSize)
+	chunkSize = 64
+
+	w := &dataWriter{}
+
+	// Reserving space per element grows the buffer geometrically, up to
+	// the chunk size.
+
+	var caps []int
+	for i := 0; i < 8; i++ {
+		w.Grow(4)
+		w.buf = append(w.buf, 1, 2, 3, 4)
+		if n := len(caps); n == 0 || caps[n-1] != cap(w.buf) {
+			caps = append(caps, cap(w.buf))
+		}
+	}
+	want := []int{4, 8, 16, 32}
+	if !reflect.DeepEqual(caps, want) {
+		t.Errorf("buffer capacities %v, want %v", caps, want)
+	}
+
+	w.Grow(100)
+	if cap(w.buf) != chunkSize {
+		t.Errorf("cap after Grow(100) = %v, want chunk size %v", cap(w.buf), chunkSize)
+	}
+}
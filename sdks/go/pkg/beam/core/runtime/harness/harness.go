@@ -20,9 +20,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/hooks"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
@@ -34,15 +37,32 @@ import (
 
 // TODO(herohde) 2/8/2017: for now, assume we stage a full binary (not a plugin).
 
+// ShutdownTimeout is the maximum time the harness waits for in-flight
+// bundles to finish when shutting down.
+var ShutdownTimeout = 30 * time.Second
+
 // Main is the main entrypoint for the Go harness. It runs at "runtime" -- not
 // "pipeline-construction time" -- on each worker. It is a FnAPI client and
 // ultimately responsible for correctly executing user code.
+//
+// Main returns once the control channel is closed or the context is done,
+// such as when the worker is terminated. The harness then shuts down: active
+// bundles are drained and finish, if they do so within ShutdownTimeout, the
+// DoFns of idle plans are torn down and the remaining logs are sent.
 func Main(ctx context.Context, loggingEndpoint, controlEndpoint string) error {
+	// The harness keeps running on shutdown, until drained.
+	shutdown := ctx.Done()
+	ctx = detach(ctx)
+
 	hooks.DeserializeHooksFromOptions(ctx)
 
 	hooks.RunInitHooks(ctx)
 	setupRemoteLogging(ctx, loggingEndpoint)
 	recordHeader()
+	setupSpilling(ctx)
+	setupPrefetching(ctx)
+	setupChunking(ctx)
+	setupSideInputCache(ctx)
 
 	// Connect to FnAPI control server. Receive and execute work.
 	// TODO: setup data manager, DoFn register
@@ -64,68 +84,225 @@ func Main(ctx context.Context, loggingEndpoint, controlEndpoint string) error {
 
 	var wg sync.WaitGroup
 	respc := make(chan *fnpb.InstructionResponse, 100)
+	stopResponses := make(chan struct{})
 
 	wg.Add(1)
 
 	// gRPC requires all writers to a stream be the same goroutine, so this is the
-	// goroutine for managing responses back to the control service.
+	// goroutine for managing responses back to the control service. Once
+	// stopped, it sends the pending responses and returns.
 	go func() {
 		defer wg.Done()
-		for resp := range respc {
+		send := func(resp *fnpb.InstructionResponse) {
 			log.Debugf(ctx, "RESP: %v", proto.MarshalTextString(resp))
 
 			if err := client.Send(resp); err != nil {
 				log.Errorf(ctx, "Failed to respond: %v", err)
 			}
 		}
+		for {
+			select {
+			case resp := <-respc:
+				send(resp)
+			case <-stopResponses:
+				for {
+					select {
+					case resp := <-respc:
+						send(resp)
+					default:
+						return
+					}
+				}
+			}
+		}
 	}()
 
 	ctrl := &control{
-		plans:  make(map[string]*exec.Plan),
-		active: make(map[string]*exec.Plan),
-		data:   &DataManager{},
+		plans:   make(map[string]*exec.Plan),
+		active:  make(map[string]*exec.Plan),
+		started: make(map[string]time.Time),
+		splits:  make(map[string]*fnpb.BundleSplit),
+		data:    &DataManager{},
+		state:   &StateChannelManager{},
+		cache:   newSideInputCache(sideInputCacheSize),
 	}
+	setupDataCompression(ctx, ctrl.data)
+	current = ctrl
+
+	reportBreadcrumb(ctx)
+	crumbCtx, stopCrumbs := context.WithCancel(ctx)
+	go leaveBreadcrumbs(crumbCtx, ctrl)
+	setupStatus(crumbCtx, ctrl, setupProfiling(crumbCtx))
 
 	// gRPC requires all readers of a stream be the same goroutine, so this goroutine
 	// is responsible for managing the network data. All it does is pull data from
-	// the stream, and hand off the message to a goroutine to actually be handled,
-	// so as to avoid blocking the underlying network channel.
+	// the stream, and hand off the message to be handled, so as to avoid blocking
+	// the underlying network channel.
+	reqc := make(chan *fnpb.InstructionRequest)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			req, err := client.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+			reqc <- req
+		}
+	}()
+
+	var bundles sync.WaitGroup // in-flight bundles
+	var drained chan struct{}  // closed once drained, if shutting down
+	var ret error
+
+loop:
 	for {
-		req, err := client.Recv()
-		if err != nil {
-			close(respc)
-			wg.Wait()
+		select {
+		case req := <-reqc:
+			if drained != nil && req.GetProcessBundle() != nil {
+				respc <- fail(req.GetInstructionId(), "harness shutting down")
+				continue
+			}
+
+			// Handle the control message.
+			// TODO(wcn): implement a rate limiter for 'heavy' messages?
+			fn := func(ctx context.Context, req *fnpb.InstructionRequest) {
+				log.Debugf(ctx, "RECV: %v", proto.MarshalTextString(req))
+				recordInstructionRequest(req)
+
+				ctx = hooks.RunRequestHooks(ctx, req)
+				resp := ctrl.handleInstruction(ctx, req)
+
+				hooks.RunResponseHooks(ctx, req, resp)
+
+				recordInstructionResponse(resp)
+				if resp != nil {
+					respc <- resp
+				}
+			}
+
+			if req.GetProcessBundle() != nil {
+				// Only process bundles in a goroutine. We at least need to process instructions for
+				// each plan serially. Perhaps just invoke plan.Execute async?
+				bundles.Add(1)
+				go func() {
+					defer bundles.Done()
+					fn(ctx, req)
+				}()
+			} else {
+				fn(ctx, req)
+			}
 
-			if err == io.EOF {
-				recordFooter()
-				return nil
+		case err := <-errc:
+			if err != io.EOF {
+				ret = fmt.Errorf("recv failed: %v", err)
 			}
-			return fmt.Errorf("recv failed: %v", err)
+			break loop
+
+		case <-shutdown:
+			log.Infof(ctx, "Harness shutting down: draining active bundles")
+			shutdown = nil
+			ctrl.drain()
+			drained = make(chan struct{})
+			go func() {
+				bundles.Wait()
+				close(drained)
+			}()
+
+		case <-drained:
+			break loop
 		}
+	}
 
-		// Launch a goroutine to handle the control message.
-		// TODO(wcn): implement a rate limiter for 'heavy' messages?
-		fn := func(ctx context.Context, req *fnpb.InstructionRequest) {
-			log.Debugf(ctx, "RECV: %v", proto.MarshalTextString(req))
-			recordInstructionRequest(req)
+	ctrl.shutdown(ctx, &bundles)
+	close(stopResponses)
+	wg.Wait()
+	stopCrumbs()
+	recordFooter()
+	flushLogs(ShutdownTimeout)
+	return ret
+}
 
-			ctx = hooks.RunRequestHooks(ctx, req)
-			resp := ctrl.handleInstruction(ctx, req)
+// detached is a context with the values of its parent that is never done.
+type detached struct {
+	context.Context
+}
 
-			hooks.RunResponseHooks(ctx, req, resp)
+func detach(ctx context.Context) context.Context {
+	return detached{ctx}
+}
 
-			recordInstructionResponse(resp)
-			if resp != nil {
-				respc <- resp
-			}
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+// setupSpilling configures spilling of large grouped values to disk from the
+// "spill_threshold_mb" and "spill_dir" pipeline options, if present.
+func setupSpilling(ctx context.Context) {
+	if mb := runtime.GlobalOptions.Get("spill_threshold_mb"); mb != "" {
+		n, err := strconv.ParseInt(mb, 10, 64)
+		if err != nil {
+			log.Errorf(ctx, "Invalid spill_threshold_mb option %q, using default: %v", mb, err)
+		} else {
+			exec.SpillThreshold = n << 20
+		}
+	}
+	if dir := runtime.GlobalOptions.Get("spill_dir"); dir != "" {
+		exec.SpillDir = dir
+	}
+}
+
+// setupPrefetching configures the read-ahead of DataSources from the
+// "prefetch_elements" and "prefetch_bytes" pipeline options, if present.
+func setupPrefetching(ctx context.Context) {
+	if elms := runtime.GlobalOptions.Get("prefetch_elements"); elms != "" {
+		n, err := strconv.Atoi(elms)
+		if err != nil {
+			log.Errorf(ctx, "Invalid prefetch_elements option %q, using default: %v", elms, err)
+		} else {
+			exec.PrefetchElements = n
 		}
+	}
+	if bytes := runtime.GlobalOptions.Get("prefetch_bytes"); bytes != "" {
+		n, err := strconv.ParseInt(bytes, 10, 64)
+		if err != nil {
+			log.Errorf(ctx, "Invalid prefetch_bytes option %q, using default: %v", bytes, err)
+		} else {
+			exec.PrefetchBytes = n
+		}
+	}
+}
+
+// setupChunking configures the size of the batches of encoded elements sent
+// on the data channels from the "data_chunk_size" pipeline option, if present.
+func setupChunking(ctx context.Context) {
+	if size := runtime.GlobalOptions.Get("data_chunk_size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			log.Errorf(ctx, "Invalid data_chunk_size option %q, using default: %v", size, chunkSize)
+		} else {
+			chunkSize = n
+		}
+	}
+}
 
-		if req.GetProcessBundle() != nil {
-			// Only process bundles in a goroutine. We at least need to process instructions for
-			// each plan serially. Perhaps just invoke plan.Execute async?
-			go fn(ctx, req)
+// setupSideInputCache configures the capacity of the side input cache from the
+// "side_input_cache_mb" pipeline option, if present.
+func setupSideInputCache(ctx context.Context) {
+	if mb := runtime.GlobalOptions.Get("side_input_cache_mb"); mb != "" {
+		n, err := strconv.ParseInt(mb, 10, 64)
+		if err != nil || n < 0 {
+			log.Errorf(ctx, "Invalid side_input_cache_mb option %q, using default: %v", mb, sideInputCacheSize>>20)
 		} else {
-			fn(ctx, req)
+			sideInputCacheSize = n << 20
 		}
 	}
 }
@@ -136,9 +313,49 @@ type control struct {
 	// plans that are actively being executed.
 	// a plan can only be in one of these maps at any time.
 	active map[string]*exec.Plan // protected by mu
+	// start times of the active bundles.
+	started map[string]time.Time // protected by mu
+	// splits of active bundles not yet reported to the runner.
+	splits map[string]*fnpb.BundleSplit // protected by mu
 	mu     sync.Mutex
 
-	data *DataManager
+	data  *DataManager
+	state *StateChannelManager
+	// cache holds the side inputs read through the State API across bundles.
+	cache *sideInputCache
+}
+
+// drain makes the active bundles reduce their remaining work, such as by
+// truncating the restrictions of splittable DoFns.
+func (c *control) drain() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, plan := range c.active {
+		plan.Drain()
+	}
+}
+
+// shutdown waits up to ShutdownTimeout for the in-flight bundles to finish
+// and tears down the plans that are not active.
+func (c *control) shutdown(ctx context.Context, bundles *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		bundles.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(ShutdownTimeout):
+		log.Errorf(ctx, "Harness shutting down with bundles still active after %v", ShutdownTimeout)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, plan := range c.plans {
+		if err := plan.Down(ctx); err != nil {
+			log.Errorf(ctx, "Failed to tear down plan %v: %v", id, err)
+		}
+	}
 }
 
 func (c *control) handleInstruction(ctx context.Context, req *fnpb.InstructionRequest) *fnpb.InstructionResponse {
@@ -183,6 +400,7 @@ func (c *control) handleInstruction(ctx context.Context, req *fnpb.InstructionRe
 		// Make the plan active, and remove it from candidates
 		// since a plan can't be run concurrently.
 		c.active[id] = plan
+		c.started[id] = time.Now()
 		delete(c.plans, ref)
 		c.mu.Unlock()
 
@@ -190,23 +408,40 @@ func (c *control) handleInstruction(ctx context.Context, req *fnpb.InstructionRe
 			return fail(id, "execution plan for %v not found", ref)
 		}
 
-		err := plan.Execute(ctx, id, c.data)
+		data := &bundleData{DataManager: c.data, state: c.state, cache: c.cache, tokens: msg.GetCacheTokens()}
+		err := plan.Execute(ctx, id, data)
 		m := plan.Metrics()
+		// Metrics are reported per bundle, so the metrics of the plan are
+		// cleared for its next bundle, even if this one failed.
+		metrics.ClearBundleData(plan.ID())
+		// Restrictions checkpointed by splittable DoFns are returned as
+		// residuals for the runner to resume.
+		checkpoint, cerr := plan.Checkpoint()
 		// Move the plan back to the candidate state
 		c.mu.Lock()
 		c.plans[plan.ID()] = plan
 		delete(c.active, id)
+		delete(c.started, id)
+		split := c.splits[id]
+		delete(c.splits, id)
 		c.mu.Unlock()
 
 		if err != nil {
 			return fail(id, "execute failed: %v", err)
 		}
+		if cerr != nil {
+			return fail(id, "checkpoint failed: %v", cerr)
+		}
+		if checkpoint != nil {
+			split = mergeSplits(split, checkpoint)
+		}
 
 		return &fnpb.InstructionResponse{
 			InstructionId: id,
 			Response: &fnpb.InstructionResponse_ProcessBundle{
 				ProcessBundle: &fnpb.ProcessBundleResponse{
 					Metrics: m,
+					Split:   split,
 				},
 			},
 		}
@@ -219,6 +454,8 @@ func (c *control) handleInstruction(ctx context.Context, req *fnpb.InstructionRe
 		ref := msg.GetInstructionReference()
 		c.mu.Lock()
 		plan, ok := c.active[ref]
+		split := c.splits[ref]
+		delete(c.splits, ref)
 		c.mu.Unlock()
 		if !ok {
 			return fail(id, "execution plan for %v not found", ref)
@@ -231,6 +468,7 @@ func (c *control) handleInstruction(ctx context.Context, req *fnpb.InstructionRe
 			Response: &fnpb.InstructionResponse_ProcessBundleProgress{
 				ProcessBundleProgress: &fnpb.ProcessBundleProgressResponse{
 					Metrics: m,
+					Split:   split,
 				},
 			},
 		}
@@ -240,6 +478,25 @@ func (c *control) handleInstruction(ctx context.Context, req *fnpb.InstructionRe
 
 		log.Debugf(ctx, "PB Split: %v", msg)
 
+		// The split is reported with the next progress or bundle response,
+		// as the split response carries no result.
+		ref := msg.GetInstructionReference()
+		c.mu.Lock()
+		plan, ok := c.active[ref]
+		c.mu.Unlock()
+		if !ok {
+			return fail(id, "execution plan for %v not found", ref)
+		}
+		split, err := plan.Split(msg.GetFractionOfRemainder().GetValue())
+		if err != nil {
+			return fail(id, "split failed: %v", err)
+		}
+		if split != nil {
+			c.mu.Lock()
+			c.splits[ref] = mergeSplits(c.splits[ref], split)
+			c.mu.Unlock()
+		}
+
 		return &fnpb.InstructionResponse{
 			InstructionId: id,
 			Response: &fnpb.InstructionResponse_ProcessBundleSplit{
@@ -252,6 +509,23 @@ func (c *control) handleInstruction(ctx context.Context, req *fnpb.InstructionRe
 	}
 }
 
+// mergeSplits combines a split with an earlier split of the same bundle that
+// has not been reported. The primary roots of the later split, if any,
+// replace the earlier ones, while all residual roots are kept.
+func mergeSplits(prev, next *fnpb.BundleSplit) *fnpb.BundleSplit {
+	if prev == nil {
+		return next
+	}
+	primary := next.PrimaryRoots
+	if len(primary) == 0 {
+		primary = prev.PrimaryRoots
+	}
+	return &fnpb.BundleSplit{
+		PrimaryRoots:  primary,
+		ResidualRoots: append(prev.ResidualRoots, next.ResidualRoots...),
+	}
+}
+
 func fail(id, format string, args ...interface{}) *fnpb.InstructionResponse {
 	dummy := &fnpb.InstructionResponse_Register{Register: &fnpb.RegisterResponse{}}
 
@@ -26,6 +26,8 @@ import (
 
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"runtime/debug"
 
@@ -70,23 +72,45 @@ func hook() {
 		runtime.GlobalOptions.Import(opt.Options)
 	}
 
+	// Breadcrumbs are kept in the semi-persistent directory, which survives
+	// worker restarts, so that crashes can be reported by the next worker.
+	harness.SetupBreadcrumbs(*semiPersistDir)
+
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Fprintf(os.Stderr, "Worker panic: %v", r)
+			harness.RecordCrash(r)
 			debug.PrintStack()
 			os.Exit(2)
 		}
 	}()
 
 	// Since Init() is hijacking main, it's appropriate to do as main
-	// does, and establish the background context here.
+	// does, and establish the background context here. It is cancelled on
+	// SIGTERM, such as when the worker is scaled down, to make the harness
+	// finish its bundles and tear down before exiting.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM)
+	terminated := make(chan bool, 1)
+	go func() {
+		<-sigc
+		terminated <- true
+		cancel()
+	}()
 
-	if err := harness.Main(context.Background(), *loggingEndpoint, *controlEndpoint); err != nil {
+	if err := harness.Main(ctx, *loggingEndpoint, *controlEndpoint); err != nil {
 		fmt.Fprintf(os.Stderr, "Worker failed: %v", err)
 		os.Exit(1)
 	}
 
 	fmt.Fprint(os.Stderr, "Worker exited successfully!")
+	select {
+	case <-terminated:
+		os.Exit(0)
+	default:
+	}
 	for {
 		// Just hang around until we're terminated.
 		time.Sleep(time.Hour)
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
@@ -49,7 +50,8 @@ func tryGetInstID(ctx context.Context) (string, bool) {
 }
 
 type logger struct {
-	out chan<- *pb.LogEntry
+	out     chan<- *pb.LogEntry
+	pending *int64 // entries not yet sent, accessed atomically
 }
 
 func (l *logger) Log(ctx context.Context, sev log.Severity, calldepth int, msg string) {
@@ -67,11 +69,13 @@ func (l *logger) Log(ctx context.Context, sev log.Severity, calldepth int, msg s
 		entry.InstructionReference = id
 	}
 
+	atomic.AddInt64(l.pending, 1)
 	select {
 	case l.out <- entry:
 		// ok
 	default:
 		// buffer full: drop to stderr.
+		atomic.AddInt64(l.pending, -1)
 		fmt.Fprintln(os.Stderr, msg)
 	}
 }
@@ -97,15 +101,40 @@ func convertSeverity(sev log.Severity) pb.LogEntry_Severity_Enum {
 // try to reconnect, if a connection goes bad. Falls back to stdout.
 func setupRemoteLogging(ctx context.Context, endpoint string) {
 	buf := make(chan *pb.LogEntry, 2000)
-	log.SetLogger(&logger{out: buf})
+	w := &remoteWriter{buffer: buf, endpoint: endpoint}
+	log.SetLogger(&logger{out: buf, pending: &w.pending})
 
-	w := &remoteWriter{buf, endpoint}
+	logs = w
 	go w.Run(ctx)
 }
 
+// logs is the remote log writer, if set up.
+var logs *remoteWriter
+
+// flushLogs waits up to the given timeout for the buffered log entries to
+// be sent.
+func flushLogs(timeout time.Duration) {
+	if logs == nil {
+		return
+	}
+	logs.flush(timeout)
+}
+
 type remoteWriter struct {
 	buffer   chan *pb.LogEntry
 	endpoint string
+	pending  int64 // entries not yet sent, accessed atomically
+}
+
+func (w *remoteWriter) flush(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&w.pending) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
 }
 
 func (w *remoteWriter) Run(ctx context.Context) error {
@@ -141,7 +170,9 @@ func (w *remoteWriter) connect(ctx context.Context) error {
 
 		recordLogEntries(list)
 
-		if err := client.Send(list); err != nil {
+		err := client.Send(list)
+		atomic.AddInt64(&w.pending, -1)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to send message: %v\n%v", err, msg)
 			return err
 		}
@@ -0,0 +1,80 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	fnpb "github.com/apache/beam/sdks/go/pkg/beam/model/fnexecution_v1"
+)
+
+var processed = metrics.NewCounter("harness_test", "processed")
+
+// countingRoot increments a counter of a data transform once per bundle.
+type countingRoot struct {
+	teardownRoot
+}
+
+func (n *countingRoot) Process(ctx context.Context) error {
+	processed.Inc(metrics.SetPTransformID(ctx, "write"), 1)
+	return nil
+}
+
+func TestBundleMetrics(t *testing.T) {
+	sink := &exec.DataSink{UID: 2, Target: exec.Target{ID: "write"}, Coder: coder.NewW(coder.NewVarInt(), coder.NewGlobalWindow())}
+	plan, err := exec.NewPlan("plan", []exec.Unit{&countingRoot{}, sink})
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	c := &control{
+		plans:   map[string]*exec.Plan{"plan": plan},
+		active:  make(map[string]*exec.Plan),
+		started: make(map[string]time.Time),
+		splits:  make(map[string]*fnpb.BundleSplit),
+	}
+
+	// The metrics of each bundle are reported separately.
+
+	for _, id := range []string{"1", "2"} {
+		resp := c.handleInstruction(context.Background(), &fnpb.InstructionRequest{
+			InstructionId: id,
+			Request: &fnpb.InstructionRequest_ProcessBundle{
+				ProcessBundle: &fnpb.ProcessBundleRequest{ProcessBundleDescriptorReference: "plan"},
+			},
+		})
+		if resp.GetError() != "" {
+			t.Fatalf("bundle %v failed: %v", id, resp.GetError())
+		}
+
+		var got []int64
+		for _, m := range resp.GetProcessBundle().GetMetrics().GetPtransforms()["write"].GetUser() {
+			if m.GetMetricName().GetName() == "processed" {
+				got = append(got, m.GetCounterData().GetValue())
+			}
+		}
+		if len(got) != 1 || got[0] != 1 {
+			t.Errorf("bundle %v reported processed = %v, want [1]", id, got)
+		}
+	}
+	if got := metrics.BundleResults(plan.ID()); len(got) != 0 {
+		t.Errorf("metrics of plan after bundles = %+v, want none", got)
+	}
+}
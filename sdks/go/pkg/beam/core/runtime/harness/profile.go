@@ -0,0 +1,234 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+// Pipeline options of worker profiling. With ProfileLocationOption set to a
+// local directory, or to a location with a registered ProfileUploader, such
+// as "gs://bucket/profiles" with the Dataflow runner, workers periodically record a CPU profile of the duration given by CPUProfileOption,
+// such as "30s", and a heap profile, if HeapProfileOption is "true". Profiles
+// are recorded every ProfileIntervalOption, "10m" by default, and are named by
+// worker host, kind and time. Profiles can also be recorded on demand from
+// the /profile endpoint of the worker status page, see StatusAddressOption.
+const (
+	ProfileLocationOption = "worker_profile_location"
+	CPUProfileOption      = "worker_cpu_profile"
+	HeapProfileOption     = "worker_heap_profile"
+	ProfileIntervalOption = "worker_profile_interval"
+)
+
+const defaultProfileInterval = 10 * time.Minute
+
+// ProfileUploader uploads worker profiles to the profile locations of a
+// scheme, such as "gs".
+type ProfileUploader interface {
+	// Upload writes the profile with the given name under the location.
+	Upload(ctx context.Context, location, name string, data []byte) error
+}
+
+var (
+	uploaders   = make(map[string]ProfileUploader)
+	uploadersMu sync.Mutex
+)
+
+// RegisterProfileUploader registers the uploader of the profile locations with
+// the given scheme, such as "gs" for "gs://bucket/profiles". Runners register
+// uploaders for the locations they support. Locations without a scheme are
+// local directories. If multiple uploaders are registered for the same scheme,
+// the last registration wins.
+func RegisterProfileUploader(scheme string, u ProfileUploader) {
+	uploadersMu.Lock()
+	defer uploadersMu.Unlock()
+	uploaders[scheme] = u
+}
+
+// profiler records CPU and heap profiles of the worker and writes them to
+// the profile location, if any.
+type profiler struct {
+	location string // local directory or location of an uploader, if any
+	host     string
+
+	mu sync.Mutex // serializes CPU profiles
+}
+
+// setupProfiling returns the profiler of the worker and starts the periodic
+// profiling, if enabled by the pipeline options, until the context is done.
+func setupProfiling(ctx context.Context) *profiler {
+	p := &profiler{location: runtime.GlobalOptions.Get(ProfileLocationOption)}
+	p.host, _ = os.Hostname()
+	if p.location == "" {
+		return p
+	}
+
+	var cpu time.Duration
+	if opt := runtime.GlobalOptions.Get(CPUProfileOption); opt != "" {
+		d, err := time.ParseDuration(opt)
+		if err != nil {
+			log.Errorf(ctx, "Invalid %v option %q, CPU profiling disabled: %v", CPUProfileOption, opt, err)
+		} else {
+			cpu = d
+		}
+	}
+	heap := runtime.GlobalOptions.Get(HeapProfileOption) == "true"
+	interval := defaultProfileInterval
+	if opt := runtime.GlobalOptions.Get(ProfileIntervalOption); opt != "" {
+		d, err := time.ParseDuration(opt)
+		if err != nil || d <= 0 {
+			log.Errorf(ctx, "Invalid %v option %q, using default: %v", ProfileIntervalOption, opt, interval)
+		} else {
+			interval = d
+		}
+	}
+	if cpu > 0 || heap {
+		go p.run(ctx, cpu, heap, interval)
+	}
+	return p
+}
+
+// run records the enabled profiles every interval, until the context is done.
+func (p *profiler) run(ctx context.Context, cpu time.Duration, heap bool, interval time.Duration) {
+	log.Infof(ctx, "Recording worker profiles every %v to %v", interval, p.location)
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		if cpu > 0 {
+			if _, err := p.record(ctx, "cpu", cpu); err != nil {
+				log.Warnf(ctx, "Failed to record CPU profile: %v", err)
+			}
+		}
+		if heap {
+			if _, err := p.record(ctx, "heap", 0); err != nil {
+				log.Warnf(ctx, "Failed to record heap profile: %v", err)
+			}
+		}
+	}
+}
+
+// record records a profile of the given kind and writes it to the profile
+// location, if any. It returns the profile.
+func (p *profiler) record(ctx context.Context, kind string, d time.Duration) ([]byte, error) {
+	var buf bytes.Buffer
+	switch kind {
+	case "cpu":
+		if err := p.cpu(ctx, &buf, d); err != nil {
+			return nil, err
+		}
+	case "heap":
+		if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown profile %q, want cpu or heap", kind)
+	}
+	if p.location == "" {
+		return buf.Bytes(), nil
+	}
+	return buf.Bytes(), p.write(ctx, kind, buf.Bytes())
+}
+
+// cpu records a CPU profile for the given duration, or until the context is
+// done. It fails if a CPU profile is already being recorded.
+func (p *profiler) cpu(ctx context.Context, buf *bytes.Buffer, d time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := pprof.StartCPUProfile(buf); err != nil {
+		return err
+	}
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+	pprof.StopCPUProfile()
+	return nil
+}
+
+// write writes the profile to the profile location.
+func (p *profiler) write(ctx context.Context, kind string, data []byte) error {
+	name := fmt.Sprintf("%v-%v-%v.prof", p.host, kind, time.Now().UTC().Format("20060102-150405"))
+
+	i := strings.Index(p.location, "://")
+	if i < 0 {
+		if err := os.MkdirAll(p.location, 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(p.location, name), data, 0644)
+	}
+
+	scheme := p.location[:i]
+	uploadersMu.Lock()
+	u, ok := uploaders[scheme]
+	uploadersMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no profile uploader registered for %v", p.location)
+	}
+	return u.Upload(ctx, p.location, name, data)
+}
+
+// profileHandler records a profile on demand, given by the "kind" parameter
+// as "cpu" or "heap". CPU profiles last for the given "seconds", 30 by
+// default. The profile is written to the profile location, if any, and
+// returned.
+func profileHandler(p *profiler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind := r.FormValue("kind")
+		if kind == "" {
+			kind = "cpu"
+		}
+		d := 30 * time.Second
+		if s := r.FormValue("seconds"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				http.Error(w, fmt.Sprintf("invalid seconds %q", s), http.StatusBadRequest)
+				return
+			}
+			d = time.Duration(n) * time.Second
+		}
+
+		data, err := p.record(r.Context(), kind, d)
+		if data == nil {
+			http.Error(w, fmt.Sprintf("failed to record %v profile: %v", kind, err), http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			log.Warnf(r.Context(), "Failed to write %v profile: %v", kind, err)
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", kind+".prof"))
+		w.Write(data)
+	})
+}
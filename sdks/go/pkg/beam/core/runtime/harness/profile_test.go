@@ -0,0 +1,97 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := &profiler{location: dir, host: "worker"}
+	server := httptest.NewServer(statusHandler(&control{}, p))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/profile?kind=heap")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Fatalf("GET /profile = %v with %v bytes, want heap profile", resp.Status, len(body))
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(files) != 1 || !strings.HasPrefix(files[0].Name(), "worker-heap-") {
+		t.Fatalf("profile location has %v, want one worker-heap profile", files)
+	}
+	if files[0].Size() != int64(len(body)) {
+		t.Errorf("written profile has %v bytes, want %v", files[0].Size(), len(body))
+	}
+
+	resp, err = server.Client().Get(server.URL + "/profile?kind=goroutine")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("GET /profile?kind=goroutine = %v, want %v", resp.Status, http.StatusInternalServerError)
+	}
+}
+
+type fakeUploader struct {
+	location, name string
+	data           []byte
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, location, name string, data []byte) error {
+	u.location, u.name, u.data = location, name, data
+	return nil
+}
+
+func TestProfileUploader(t *testing.T) {
+	ctx := context.Background()
+	u := &fakeUploader{}
+	RegisterProfileUploader("fake", u)
+
+	p := &profiler{location: "fake://profiles", host: "worker"}
+	if err := p.write(ctx, "heap", []byte("profile")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if u.location != "fake://profiles" || !strings.HasPrefix(u.name, "worker-heap-") || string(u.data) != "profile" {
+		t.Errorf("uploaded %v to %v/%v, want profile to fake://profiles/worker-heap-*", string(u.data), u.location, u.name)
+	}
+
+	p = &profiler{location: "none://profiles", host: "worker"}
+	if err := p.write(ctx, "heap", []byte("profile")); err == nil {
+		t.Error("write to location without uploader succeeded, want error")
+	}
+}
@@ -0,0 +1,83 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+)
+
+// teardownRoot is a root unit that records whether it was torn down.
+type teardownRoot struct {
+	down bool
+}
+
+func (n *teardownRoot) ID() exec.UnitID                   { return 1 }
+func (n *teardownRoot) Up(ctx context.Context) error      { return nil }
+func (n *teardownRoot) Process(ctx context.Context) error { return nil }
+
+func (n *teardownRoot) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	return nil
+}
+
+func (n *teardownRoot) FinishBundle(ctx context.Context) error { return nil }
+
+func (n *teardownRoot) Down(ctx context.Context) error {
+	n.down = true
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	ctx := context.Background()
+
+	ran, idle := &teardownRoot{}, &teardownRoot{}
+	p1, err := exec.NewPlan("ran", []exec.Unit{ran})
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	if err := p1.Execute(ctx, "bundle", nil); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	p2, err := exec.NewPlan("idle", []exec.Unit{idle})
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	c := &control{plans: map[string]*exec.Plan{"ran": p1, "idle": p2}}
+
+	defer func(timeout time.Duration) { ShutdownTimeout = timeout }(ShutdownTimeout)
+	ShutdownTimeout = 50 * time.Millisecond
+
+	// A bundle that does not finish must not block shutdown.
+	var bundles sync.WaitGroup
+	bundles.Add(1)
+	defer bundles.Done()
+
+	start := time.Now()
+	c.shutdown(ctx, &bundles)
+	if elapsed := time.Since(start); elapsed < ShutdownTimeout {
+		t.Errorf("shutdown returned after %v, want to wait for bundles for %v", elapsed, ShutdownTimeout)
+	}
+	if !ran.down {
+		t.Errorf("executed plan not torn down")
+	}
+	if idle.down {
+		t.Errorf("plan torn down without having been brought up")
+	}
+}
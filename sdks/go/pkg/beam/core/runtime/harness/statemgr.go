@@ -0,0 +1,403 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/fnexecution_v1"
+	"google.golang.org/grpc"
+)
+
+// sideInputCacheSize is the capacity of the side input cache in bytes. The
+// harness sets it from the "side_input_cache_mb" pipeline option, if present.
+// Zero disables caching.
+var sideInputCacheSize = int64(100 << 20)
+
+// bundleData is the DataManager of a bundle. It also reads the side inputs of
+// the bundle through the State API, and serves them from the side input cache
+// under the cache tokens of the ProcessBundleRequest.
+type bundleData struct {
+	*DataManager
+	state  *StateChannelManager
+	cache  *sideInputCache
+	tokens [][]byte
+}
+
+// OpenSideInput opens the values of the side input, reading them through the
+// State API unless cached. The whole side input is read as an iterable under
+// the empty key.
+func (d *bundleData) OpenSideInput(ctx context.Context, id exec.StreamID, w []byte) (io.ReadCloser, error) {
+	key := sideInputKey{transform: id.Target.ID, sideInput: id.Target.Name, window: string(w)}
+	if data, ok := d.cache.get(key, d.tokens); ok {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	ch, err := d.state.Open(ctx, id.Port)
+	if err != nil {
+		return nil, err
+	}
+	r := &stateReader{
+		ctx:    ctx,
+		ch:     ch,
+		instID: id.InstID,
+		key: &pb.StateKey{
+			Type: &pb.StateKey_MultimapSideInput_{
+				MultimapSideInput: &pb.StateKey_MultimapSideInput{
+					PtransformId: id.Target.ID,
+					SideInputId:  id.Target.Name,
+					Window:       w,
+				},
+			},
+		},
+		cache:  d.cache,
+		ckey:   key,
+		tokens: d.tokens,
+	}
+	return r, nil
+}
+
+// stateReader reads the logical byte stream of a state key chunk by chunk,
+// following the continuation tokens of the runner. If the runner returns a
+// cache token of the bundle with the first chunk, the stream is also collected
+// and added to the side input cache once read completely, if it fits.
+type stateReader struct {
+	ctx    context.Context
+	ch     *StateChannel
+	instID string
+	key    *pb.StateKey
+
+	buf     []byte // unread data of the current chunk
+	next    []byte // continuation token of the next chunk
+	started bool
+	done    bool
+
+	cache   *sideInputCache
+	ckey    sideInputKey
+	tokens  [][]byte
+	caching bool
+	all     []byte // data read so far, if caching
+}
+
+func (r *stateReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fetch gets the next chunk of the stream.
+func (r *stateReader) fetch() error {
+	resp, err := r.ch.Send(r.ctx, &pb.StateRequest{
+		InstructionReference: r.instID,
+		StateKey:             r.key,
+		Request: &pb.StateRequest_Get{
+			Get: &pb.StateGetRequest{ContinuationToken: r.next},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if !r.started {
+		r.started = true
+		if token := resp.GetCacheToken(); hasToken(r.tokens, token) && r.cache.capacity > 0 {
+			r.caching = true
+			r.ckey.token = string(token)
+		}
+	}
+
+	get := resp.GetGet()
+	r.buf = get.GetData()
+	r.next = get.GetContinuationToken()
+	r.done = len(r.next) == 0
+
+	if r.caching {
+		r.all = append(r.all, r.buf...)
+		if int64(len(r.all)) > r.cache.capacity {
+			r.caching, r.all = false, nil
+		} else if r.done {
+			r.cache.put(r.ckey, r.all)
+			r.caching, r.all = false, nil
+		}
+	}
+	return nil
+}
+
+func (r *stateReader) Close() error {
+	return nil
+}
+
+// hasToken returns true iff the token is one of the given cache tokens.
+func hasToken(tokens [][]byte, token []byte) bool {
+	if len(token) == 0 {
+		return false
+	}
+	for _, t := range tokens {
+		if bytes.Equal(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// sideInputCache is an LRU cache of the encoded values of side inputs read
+// through the State API, shared by the bundles of the harness. Values are
+// cached under the cache token the runner returned with them and are only
+// served to bundles whose ProcessBundleRequest lists that token, so the runner
+// invalidates them by no longer sending it. The least recently used values
+// are evicted once the cache exceeds its capacity in bytes.
+type sideInputCache struct {
+	capacity int64
+	size     int64
+	entries  map[sideInputKey]*list.Element
+	lru      *list.List // of *cacheEntry, most recently used first
+	mu       sync.Mutex
+}
+
+// sideInputKey identifies the cached values of a side input.
+type sideInputKey struct {
+	transform, sideInput string
+	window               string // encoded
+	token                string
+}
+
+type cacheEntry struct {
+	key  sideInputKey
+	data []byte
+}
+
+func newSideInputCache(capacity int64) *sideInputCache {
+	return &sideInputCache{
+		capacity: capacity,
+		entries:  make(map[sideInputKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// get returns the cached values of the side input under any of the given
+// cache tokens, if present. The token of the key is ignored.
+func (c *sideInputCache) get(key sideInputKey, tokens [][]byte) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, t := range tokens {
+		key.token = string(t)
+		if e, ok := c.entries[key]; ok {
+			c.lru.MoveToFront(e)
+			return e.Value.(*cacheEntry).data, true
+		}
+	}
+	return nil, false
+}
+
+// put caches the values of the side input and evicts the least recently used
+// values, if the cache exceeds its capacity.
+func (c *sideInputCache) put(key sideInputKey, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.size -= int64(len(e.Value.(*cacheEntry).data))
+		c.lru.Remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, data: data})
+	c.size += int64(len(data))
+
+	for c.size > c.capacity {
+		e := c.lru.Back()
+		entry := e.Value.(*cacheEntry)
+		c.lru.Remove(e)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.data))
+	}
+}
+
+// This is a reduced version of the full gRPC interface to help with testing.
+type stateClient interface {
+	Send(*pb.StateRequest) error
+	Recv() (*pb.StateResponse, error)
+}
+
+// StateChannelManager manages the State API channels to the runner, by port.
+// A failed channel is replaced when next opened.
+type StateChannelManager struct {
+	ports map[string]*StateChannel
+	mu    sync.Mutex
+}
+
+// Open returns the State API channel for the given port, connecting it if
+// needed.
+func (m *StateChannelManager) Open(ctx context.Context, port exec.Port) (*StateChannel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ports == nil {
+		m.ports = make(map[string]*StateChannel)
+	}
+	if ch, ok := m.ports[port.URL]; ok && ch.failed() == nil {
+		return ch, nil
+	}
+
+	ch, err := NewStateChannel(ctx, port)
+	if err != nil {
+		return nil, err
+	}
+	m.ports[port.URL] = ch
+	return ch, nil
+}
+
+// StateChannel multiplexes the State API requests of the bundles of the
+// harness over a single stream, and matches the responses to the requests
+// by id. If the stream fails, the pending and later requests fail.
+type StateChannel struct {
+	cc     *grpc.ClientConn
+	client stateClient
+	port   exec.Port
+
+	sendMu  sync.Mutex // gRPC requires a single sender at a time
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *pb.StateResponse // protected by mu
+	err     error                             // protected by mu, set once the stream fails
+}
+
+// NewStateChannel connects a State API channel to the given port.
+func NewStateChannel(ctx context.Context, port exec.Port) (*StateChannel, error) {
+	cc, err := dial(ctx, port.URL, 15*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %v", err)
+	}
+	client, err := pb.NewBeamFnStateClient(cc).State(ctx)
+	if err != nil {
+		cc.Close()
+		return nil, fmt.Errorf("failed to connect to state service: %v", err)
+	}
+	return makeStateChannel(ctx, cc, client, port), nil
+}
+
+func makeStateChannel(ctx context.Context, cc *grpc.ClientConn, client stateClient, port exec.Port) *StateChannel {
+	ret := &StateChannel{
+		cc:      cc,
+		client:  client,
+		port:    port,
+		pending: make(map[string]chan *pb.StateResponse),
+	}
+	go ret.read(ctx)
+	return ret
+}
+
+// read passes the responses to the pending requests until the stream fails.
+func (c *StateChannel) read(ctx context.Context) {
+	for {
+		resp, err := c.client.Recv()
+		if err != nil {
+			if err == io.EOF {
+				log.Warnf(ctx, "StateChannel %v closed", c.port)
+			} else {
+				log.Errorf(ctx, "StateChannel %v failed: %v", c.port, err)
+			}
+
+			c.mu.Lock()
+			c.err = fmt.Errorf("state channel %v failed: %v", c.port, err)
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[resp.GetId()]
+		delete(c.pending, resp.GetId())
+		c.mu.Unlock()
+
+		if !ok {
+			log.Warnf(ctx, "StateChannel %v: response for unknown request %v", c.port, resp.GetId())
+			continue
+		}
+		ch <- resp
+	}
+}
+
+// failed returns the error of the channel, if its stream failed.
+func (c *StateChannel) failed() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Send sends the request with a new id and waits for its response. A response
+// with an error fails the request.
+func (c *StateChannel) Send(ctx context.Context, req *pb.StateRequest) (*pb.StateResponse, error) {
+	ch := make(chan *pb.StateResponse, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	id := fmt.Sprintf("%v", c.nextID)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	req.Id = id
+	c.sendMu.Lock()
+	err := c.client.Send(req)
+	c.sendMu.Unlock()
+	if err != nil {
+		c.cancel(id)
+		return nil, fmt.Errorf("failed to send state request: %v", err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, c.failed()
+		}
+		if resp.GetError() != "" {
+			return nil, fmt.Errorf("state request %v failed: %v", id, resp.GetError())
+		}
+		return resp, nil
+	case <-ctx.Done():
+		c.cancel(id)
+		return nil, ctx.Err()
+	}
+}
+
+// cancel forgets the pending request with the given id.
+func (c *StateChannel) cancel(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
@@ -0,0 +1,168 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/fnexecution_v1"
+)
+
+// fakeStateClient serves the values of side inputs in chunks of 2 bytes,
+// under the given cache token, and counts the requests.
+type fakeStateClient struct {
+	values map[string]string // by side input id
+	token  []byte
+	resps  chan *pb.StateResponse
+
+	mu       sync.Mutex
+	requests int
+}
+
+func newFakeStateClient(values map[string]string, token string) *fakeStateClient {
+	return &fakeStateClient{values: values, token: []byte(token), resps: make(chan *pb.StateResponse, 10)}
+}
+
+func (f *fakeStateClient) Send(req *pb.StateRequest) error {
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+
+	value, ok := f.values[req.GetStateKey().GetMultimapSideInput().GetSideInputId()]
+	if !ok {
+		f.resps <- &pb.StateResponse{Id: req.GetId(), Error: "unknown side input"}
+		return nil
+	}
+	start := 0
+	if t := req.GetGet().GetContinuationToken(); len(t) > 0 {
+		start, _ = strconv.Atoi(string(t))
+	}
+	get := &pb.StateGetResponse{Data: []byte(value[start:])}
+	if end := start + 2; end < len(value) {
+		get = &pb.StateGetResponse{Data: []byte(value[start:end]), ContinuationToken: []byte(strconv.Itoa(end))}
+	}
+	f.resps <- &pb.StateResponse{Id: req.GetId(), CacheToken: f.token, Response: &pb.StateResponse_Get{Get: get}}
+	return nil
+}
+
+func (f *fakeStateClient) Recv() (*pb.StateResponse, error) {
+	resp, ok := <-f.resps
+	if !ok {
+		return nil, io.EOF
+	}
+	return resp, nil
+}
+
+func (f *fakeStateClient) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+// readSideInput reads the side input with the given id in a bundle with the
+// given cache tokens.
+func readSideInput(t *testing.T, d *bundleData, id string) string {
+	sid := exec.StreamID{Port: exec.Port{URL: "state"}, Target: exec.Target{ID: "pardo", Name: id}, InstID: "bundle"}
+	r, err := d.OpenSideInput(context.Background(), sid, nil)
+	if err != nil {
+		t.Fatalf("OpenSideInput(%v) failed: %v", id, err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading side input %v failed: %v", id, err)
+	}
+	return string(data)
+}
+
+func TestSideInputCache(t *testing.T) {
+	client := newFakeStateClient(map[string]string{"i1": "abcdefg"}, "token1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	state := &StateChannelManager{ports: map[string]*StateChannel{
+		"state": makeStateChannel(ctx, nil, client, exec.Port{URL: "state"}),
+	}}
+	cache := newSideInputCache(1 << 20)
+
+	// The side input is read in chunks and cached under the token of the
+	// first bundle, which later bundles with the token are served from.
+
+	for i := 0; i < 2; i++ {
+		d := &bundleData{state: state, cache: cache, tokens: [][]byte{[]byte("token1")}}
+		if got := readSideInput(t, d, "i1"); got != "abcdefg" {
+			t.Errorf("bundle %v read %q, want abcdefg", i, got)
+		}
+		if got := client.count(); got != 4 {
+			t.Errorf("bundle %v: %v state requests, want 4", i, got)
+		}
+	}
+
+	// Bundles without the token read the side input again.
+
+	d := &bundleData{state: state, cache: cache, tokens: [][]byte{[]byte("token2")}}
+	if got := readSideInput(t, d, "i1"); got != "abcdefg" {
+		t.Errorf("read %q with other token, want abcdefg", got)
+	}
+	if got := client.count(); got != 8 {
+		t.Errorf("%v state requests with other token, want 8", got)
+	}
+
+	// Failed requests fail the read.
+
+	sid := exec.StreamID{Port: exec.Port{URL: "state"}, Target: exec.Target{ID: "pardo", Name: "i2"}, InstID: "bundle"}
+	r, err := d.OpenSideInput(context.Background(), sid, nil)
+	if err != nil {
+		t.Fatalf("OpenSideInput(i2) failed: %v", err)
+	}
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Error("reading unknown side input succeeded, want error")
+	}
+}
+
+func TestSideInputCacheEviction(t *testing.T) {
+	c := newSideInputCache(10)
+	tokens := [][]byte{[]byte("t")}
+	key := func(id string) sideInputKey {
+		return sideInputKey{transform: "pardo", sideInput: id, token: "t"}
+	}
+
+	c.put(key("a"), []byte("aaaa"))
+	c.put(key("b"), []byte("bbbb"))
+	if _, ok := c.get(key("a"), tokens); !ok {
+		t.Fatal("a not cached")
+	}
+
+	// The least recently used side input is evicted once the cache is full.
+
+	c.put(key("c"), []byte("cccc"))
+	if _, ok := c.get(key("b"), tokens); ok {
+		t.Error("b cached, want evicted")
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, ok := c.get(key(id), tokens); !ok {
+			t.Errorf("%v not cached", id)
+		}
+	}
+	if _, ok := c.get(key("a"), [][]byte{[]byte("other")}); ok {
+		t.Error("a served for other token")
+	}
+}
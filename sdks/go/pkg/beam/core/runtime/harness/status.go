@@ -0,0 +1,78 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/pprof"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+// StatusAddressOption is the pipeline option for the address to serve the
+// worker status page on, such as "localhost:8078". The page reports the
+// active bundles with their duration, the transform processing an element
+// and a sample of the element, as well as the memory use and a dump of all
+// goroutines, such that stuck workers can be diagnosed. Profiles are
+// recorded on demand from /profile, see ProfileLocationOption. It is not
+// served if the option is unset. The model has no FnAPI worker status
+// service yet, so the status is only served over HTTP.
+const StatusAddressOption = "worker_status_address"
+
+// setupStatus serves the worker status page, if enabled by the pipeline
+// options, until the context is done.
+func setupStatus(ctx context.Context, c *control, p *profiler) {
+	addr := runtime.GlobalOptions.Get(StatusAddressOption)
+	if addr == "" {
+		return
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Errorf(ctx, "Failed to serve worker status: %v", err)
+		return
+	}
+	server := &http.Server{Handler: statusHandler(c, p)}
+	go server.Serve(lis)
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	log.Infof(ctx, "Worker status: http://%v", lis.Addr())
+}
+
+// statusHandler serves the status of the harness as plain text and records
+// profiles on demand.
+func statusHandler(c *control, p *profiler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeStatus(w, c)
+	})
+	mux.Handle("/profile", profileHandler(p))
+	return mux
+}
+
+// writeStatus writes the status of the harness, followed by a dump of all
+// goroutines.
+func writeStatus(w io.Writer, c *control) {
+	fmt.Fprintf(w, "Worker status %v\n\nGoroutines:\n\n", snapshot(c))
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+}
@@ -0,0 +1,53 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package harness
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+)
+
+func TestStatus(t *testing.T) {
+	out := &exec.Discard{UID: 2}
+	plan, err := exec.NewPlan("stage", []exec.Unit{&exec.DataSource{UID: 1, Out: out}, out})
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	c := &control{
+		active:  map[string]*exec.Plan{"bundle": plan},
+		started: map[string]time.Time{"bundle": time.Now().Add(-time.Minute)},
+	}
+
+	server := httptest.NewServer(statusHandler(c, &profiler{}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	for _, want := range []string{"bundle bundle of stage stage", "in 1m0", "heap", "goroutine", "TestStatus"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("status does not contain %q:\n%s", want, body)
+		}
+	}
+}
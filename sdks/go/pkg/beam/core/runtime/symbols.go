@@ -32,6 +32,8 @@ var (
 )
 
 func init() {
+	reflectx.RegisterSymbolNamer(addr2Sym)
+
 	// First try the Linux location, since it's the most reliable.
 	if r, err := symtab.New("/proc/self/exe"); err == nil {
 		Resolver = r
@@ -51,10 +53,27 @@ type SymbolResolver interface {
 	Sym2Addr(string) (uintptr, error)
 }
 
+// SymbolNamer is an optional interface for symbol resolvers that can also
+// name the symbol at a given address.
+type SymbolNamer interface {
+	// Addr2Sym returns the symbol name for the given address.
+	Addr2Sym(uintptr) (string, error)
+}
+
+// addr2Sym names the symbol at the given address using the resolver, if it
+// supports it. It is used to name instantiated generic functions.
+func addr2Sym(ptr uintptr) (string, error) {
+	if n, ok := Resolver.(SymbolNamer); ok {
+		return n.Addr2Sym(ptr)
+	}
+	return "", fmt.Errorf("symbol resolver %v cannot name symbols", Resolver)
+}
+
 // RegisterFunction allows function registration. It is beneficial for performance
 // and is needed for functions -- such as custom coders -- serialized during unit
-// tests, where the underlying symbol table is not available. It should be called
-// in init() only. Returns the external key for the function.
+// tests, where the underlying symbol table is not available. Instantiated
+// generic functions, such as ParseJSON[MyType], must be registered if the
+// binary has no symbol table. It should be called in init() only.
 func RegisterFunction(fn interface{}) {
 	if initialized {
 		panic("Init hooks have already run. Register function during init() instead.")
@@ -79,6 +98,9 @@ func ResolveFunction(name string, t reflect.Type) (interface{}, error) {
 
 	ptr, err := Resolver.Sym2Addr(name)
 	if err != nil {
+		if reflectx.IsElidedName(name) {
+			return 0, fmt.Errorf("generic function %v must be registered with RegisterFunction: %v", name, err)
+		}
 		return 0, err
 	}
 	val := reflectx.LoadFunction(ptr, t)
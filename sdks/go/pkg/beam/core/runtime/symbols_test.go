@@ -0,0 +1,75 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+type event struct {
+	ID string
+}
+
+func parse[T any](s string) T {
+	var t T
+	return t
+}
+
+func TestGenericFunctionName(t *testing.T) {
+	a := reflectx.FunctionName(parse[event])
+	b := reflectx.FunctionName(parse[int])
+	if a == b {
+		t.Fatalf("FunctionName(parse[event]) = FunctionName(parse[int]) = %v, want distinct names", a)
+	}
+
+	if _, ok := Resolver.(SymbolNamer); !ok {
+		t.Skip("no symbol table")
+	}
+	if want := ".parse[github.com/apache/beam/sdks/go/pkg/beam/core/runtime.event]"; !strings.HasSuffix(a, want) {
+		t.Errorf("FunctionName(parse[event]) = %v, want suffix %v", a, want)
+	}
+	if want := ".parse[int]"; !strings.HasSuffix(b, want) {
+		t.Errorf("FunctionName(parse[int]) = %v, want suffix %v", b, want)
+	}
+
+	fn, err := ResolveFunction(b, reflect.TypeOf(parse[int]))
+	if err != nil {
+		t.Fatalf("ResolveFunction(%v) failed: %v", b, err)
+	}
+	if got := fn.(func(string) int)("1"); got != 0 {
+		t.Errorf("%v(\"1\") = %v, want 0", b, got)
+	}
+}
+
+func TestShortName(t *testing.T) {
+	tests := []struct {
+		Name, Short string
+	}{
+		{"github.com/apache/beam/sdks/go/pkg/beam/transforms/stats.Count", "stats.Count"},
+		{"main.extractFn", "main.extractFn"},
+		{"github.com/foo/model.Parse[github.com/foo/model.Event]", "model.Parse[model.Event]"},
+		{"example.com/x.Join[int,example.com/y/z.Row]", "x.Join[int,z.Row]"},
+	}
+	for _, test := range tests {
+		if got := reflectx.ShortName(test.Name); got != test.Short {
+			t.Errorf("ShortName(%v) = %v, want %v", test.Name, got, test.Short)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sdf contains the API of splittable DoFns. A splittable DoFn
+// processes each element as a restriction, such as a range of offsets in a
+// file, that is claimed piece by piece through a restriction tracker. The
+// runner may split off the unclaimed part of a restriction while the element
+// is processed and process it elsewhere, which is known as liquid sharding.
+//
+// A DoFn is splittable, if its ProcessElement method takes a restriction
+// tracker before the main input. It must then also implement methods to
+// create the initial restriction of an element and a tracker for a
+// restriction, and may implement a method to split the initial restriction
+// up front:
+//
+//    func (f *readFn) CreateInitialRestriction(filename string) offsetrange.Restriction
+//    func (f *readFn) SplitRestriction(filename string, r offsetrange.Restriction) []offsetrange.Restriction
+//    func (f *readFn) CreateTracker(r offsetrange.Restriction) *offsetrange.Tracker
+//
+//    func (f *readFn) ProcessElement(rt *offsetrange.Tracker, filename string, emit func(string)) error {
+//        for i := rt.GetRestriction().(offsetrange.Restriction).Start; rt.TryClaim(i); i++ {
+//            ...
+//        }
+//        return nil
+//    }
+//
+// ProcessElement must stop processing as soon as TryClaim fails and must
+// claim all of the restriction otherwise. Restrictions must be encodable as
+// JSON.
+//
+// When a pipeline is drained, restrictions that have not started processing
+// are passed through the optional TruncateRestriction method first. DoFns
+// with unbounded restrictions, such as reading a stream, use it to reduce
+// the restriction to the work that is already available:
+//
+//    func (f *readFn) TruncateRestriction(topic string, r offsetrange.Restriction) offsetrange.Restriction
+//
+// ProcessElement may also return a ProcessContinuation before the error, if
+// any, to stop processing before all of the restriction is claimed and
+// resume it later. The runner then checkpoints the unclaimed part of the
+// restriction and processes it after the requested delay. The continuation
+// may carry the watermark of the output of the remainder, which the runner
+// holds back until processing resumes:
+//
+//    func (f *readFn) ProcessElement(rt *offsetrange.Tracker, topic string, emit func(string)) (sdf.ProcessContinuation, error) {
+//        for i := rt.GetRestriction().(offsetrange.Restriction).Start; ; i++ {
+//            if !available(topic, i) {
+//                return sdf.ResumeProcessingIn(10 * time.Second).WithWatermark(watermark(topic)), nil
+//            }
+//            if !rt.TryClaim(i) {
+//                return sdf.StopProcessing(), nil
+//            }
+//            ...
+//        }
+//    }
+package sdf
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// RTracker tracks the progress of processing a restriction. Trackers are
+// accessed by the processing DoFn and by the runner concurrently, so they
+// must be safe for concurrent use.
+type RTracker interface {
+	// TryClaim claims the block of work at the given position. It returns
+	// false, if the position is outside of the restriction, such as after a
+	// split, in which case processing must stop. Positions must be claimed in
+	// increasing order.
+	TryClaim(pos interface{}) bool
+
+	// TrySplit splits off the unclaimed part of the restriction, keeping
+	// the given fraction of it, between 0 and 1. The restriction of the
+	// tracker becomes the primary restriction. It returns a nil residual, if
+	// no work could be split off.
+	TrySplit(fraction float64) (primary, residual interface{}, err error)
+
+	// GetProgress returns the amount of work done and remaining in the
+	// restriction, in arbitrary units.
+	GetProgress() (done, remaining float64)
+
+	// IsDone returns true iff all of the restriction has been claimed or
+	// processing was stopped by a failed claim.
+	IsDone() bool
+
+	// GetError returns the error, if any, that occurred while tracking, such
+	// as a claim out of order.
+	GetError() error
+
+	// GetRestriction returns the current restriction of the tracker.
+	GetRestriction() interface{}
+}
+
+// RTrackerType is the reflect.Type of RTracker.
+var RTrackerType = reflect.TypeOf((*RTracker)(nil)).Elem()
+
+// ProcessContinuation tells the runner whether the unclaimed part of the
+// restriction should be processed later. The zero value stops processing.
+type ProcessContinuation struct {
+	resume    bool
+	delay     time.Duration
+	watermark time.Time
+}
+
+// StopProcessing returns a continuation that indicates that the restriction
+// is fully processed.
+func StopProcessing() ProcessContinuation {
+	return ProcessContinuation{}
+}
+
+// ResumeProcessingIn returns a continuation that indicates that the
+// unclaimed part of the restriction should be processed after the given
+// delay, such as when no more input is available for now.
+func ResumeProcessingIn(delay time.Duration) ProcessContinuation {
+	return ProcessContinuation{resume: true, delay: delay}
+}
+
+// ShouldResume returns true iff the unclaimed part of the restriction should
+// be processed later.
+func (c ProcessContinuation) ShouldResume() bool {
+	return c.resume
+}
+
+// ResumeDelay returns the delay after which processing should resume.
+func (c ProcessContinuation) ResumeDelay() time.Duration {
+	return c.delay
+}
+
+// WithWatermark returns the continuation with the given watermark: a lower
+// bound on the timestamps of the elements that the unclaimed part of the
+// restriction produces. The watermark of the output is otherwise held at the
+// timestamp of the element.
+func (c ProcessContinuation) WithWatermark(t time.Time) ProcessContinuation {
+	c.watermark = t
+	return c
+}
+
+// Watermark returns the watermark of the continuation, if set.
+func (c ProcessContinuation) Watermark() (time.Time, bool) {
+	return c.watermark, !c.watermark.IsZero()
+}
+
+func (c ProcessContinuation) String() string {
+	if !c.resume {
+		return "Stop"
+	}
+	return fmt.Sprintf("Resume[%v]", c.delay)
+}
+
+// ProcessContinuationType is the reflect.Type of ProcessContinuation.
+var ProcessContinuationType = reflect.TypeOf((*ProcessContinuation)(nil)).Elem()
@@ -0,0 +1,158 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Codec serializes state values. By default, state values are stored as-is
+// and the runner decides on their representation. A cell with a codec
+// stores the encoded bytes instead, which allows a more compact encoding
+// than the coder of the corresponding PCollection type. For example:
+//
+//    type countFn struct {
+//        Seen state.Value `json:"seen"`
+//    }
+//
+//    fn := &countFn{Seen: state.MakeValue("seen").WithCodec("bitset")}
+//
+// where "bitset" is a Codec registered in init() with RegisterCodec.
+type Codec interface {
+	// Encode serializes the value.
+	Encode(value interface{}) ([]byte, error)
+	// Decode deserializes a value of the given type.
+	Decode(data []byte, t reflect.Type) (interface{}, error)
+}
+
+var (
+	codecs   = make(map[string]Codec)
+	codecsMu sync.Mutex
+)
+
+// RegisterCodec registers a state codec under the given name. It should be
+// called in init() only. The "json" and "proto" codecs are predefined.
+func RegisterCodec(name string, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	if _, exists := codecs[name]; exists {
+		panic(fmt.Sprintf("state codec %v already registered", name))
+	}
+	codecs[name] = c
+}
+
+// LookupCodec returns the state codec with the given name.
+func LookupCodec(name string) (Codec, error) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	if c, ok := codecs[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("state codec %v not registered. Use state.RegisterCodec in init()", name)
+}
+
+// Encoded is implemented by state cells that support codecs.
+type Encoded interface {
+	// StateCodec returns the name of the codec of the cell, if any.
+	StateCodec() string
+}
+
+func init() {
+	RegisterCodec("json", jsonCodec{})
+	RegisterCodec("proto", protoCodec{})
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Decode(data []byte, t reflect.Type) (interface{}, error) {
+	ptr := reflect.New(t)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Encode(value interface{}) ([]byte, error) {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("value of type %T is not a proto message", value)
+	}
+	return proto.Marshal(msg)
+}
+
+func (protoCodec) Decode(data []byte, t reflect.Type) (interface{}, error) {
+	if t.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("type %v is not a proto message", t)
+	}
+	msg, ok := reflect.New(t.Elem()).Interface().(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("type %v is not a proto message", t)
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// encode serializes the value with the named codec, if any.
+func encode(codec string, value interface{}) (interface{}, error) {
+	if codec == "" {
+		return value, nil
+	}
+	c, err := LookupCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state value with codec %v: %v", codec, err)
+	}
+	return data, nil
+}
+
+// decode deserializes a stored value of the given type with the named
+// codec, if any.
+func decode(codec string, stored interface{}, t reflect.Type) (interface{}, error) {
+	if codec == "" {
+		return stored, nil
+	}
+	c, err := LookupCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := stored.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("state value of type %T was not encoded with codec %v", stored, codec)
+	}
+	v, err := c.Decode(data, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode state value with codec %v: %v", codec, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,204 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// TimestampedValue is a value of an ordered list and its timestamp. Ordered
+// lists are stored as a slice of TimestampedValue sorted by timestamp.
+type TimestampedValue struct {
+	Timestamp typex.EventTime
+	Value     interface{}
+}
+
+// OrderedList is a state cell holding a collection of timestamped values,
+// ordered by timestamp. Values can be read and cleared by timestamp range,
+// such as to reassemble events that arrive out of order:
+//
+//    Events state.OrderedList `json:"events"`
+//
+//    ... f.Events.Add(ctx, t, v)
+//    ... ts, err := f.Events.ReadRange(ctx, start, end, &list)
+//
+// Values with the same timestamp are kept in the order they were added.
+type OrderedList struct {
+	Key   string `json:"key"`
+	Codec string `json:"codec,omitempty"`
+}
+
+// MakeOrderedList returns an ordered list cell with the given key.
+func MakeOrderedList(key string) OrderedList {
+	return OrderedList{Key: key}
+}
+
+// WithCodec returns a copy of the cell that stores its values encoded with
+// the named Codec.
+func (l OrderedList) WithCodec(codec string) OrderedList {
+	l.Codec = codec
+	return l
+}
+
+// StateKey returns the key of the cell.
+func (l OrderedList) StateKey() string {
+	return l.Key
+}
+
+// StateCodec returns the name of the codec of the cell, if any.
+func (l OrderedList) StateCodec() string {
+	return l.Codec
+}
+
+// Add adds a value with the given timestamp to the list.
+func (l OrderedList) Add(ctx context.Context, t typex.EventTime, val interface{}) error {
+	p, list, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	stored, err := encode(l.Codec, val)
+	if err != nil {
+		return err
+	}
+	i := sort.Search(len(list), func(i int) bool {
+		return time.Time(list[i].Timestamp).After(time.Time(t))
+	})
+	// The stored list may be shared, such as by a state cache, so it is
+	// copied rather than modified in place.
+	ret := make([]TimestampedValue, 0, len(list)+1)
+	ret = append(ret, list[:i]...)
+	ret = append(ret, TimestampedValue{Timestamp: t, Value: stored})
+	ret = append(ret, list[i:]...)
+	return p.Write(l.Key, ret)
+}
+
+// Read reads all values of the list in timestamp order into the slice
+// pointed to by ptr and returns their timestamps. It returns no timestamps
+// and leaves ptr unchanged, if the list is empty.
+func (l OrderedList) Read(ctx context.Context, ptr interface{}) ([]typex.EventTime, error) {
+	_, list, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return l.assign(list, ptr)
+}
+
+// ReadRange reads the values of the list with timestamps in [start, end)
+// like Read.
+func (l OrderedList) ReadRange(ctx context.Context, start, end typex.EventTime, ptr interface{}) ([]typex.EventTime, error) {
+	_, list, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i, j := searchRange(list, start, end)
+	return l.assign(list[i:j], ptr)
+}
+
+// Clear empties the list.
+func (l OrderedList) Clear(ctx context.Context) error {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return p.Clear(l.Key)
+}
+
+// ClearRange removes the values of the list with timestamps in [start, end).
+func (l OrderedList) ClearRange(ctx context.Context, start, end typex.EventTime) error {
+	p, list, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	i, j := searchRange(list, start, end)
+	switch {
+	case i == j:
+		return nil
+	case j-i == len(list):
+		return p.Clear(l.Key)
+	}
+	ret := make([]TimestampedValue, 0, len(list)-(j-i))
+	ret = append(ret, list[:i]...)
+	ret = append(ret, list[j:]...)
+	return p.Write(l.Key, ret)
+}
+
+func (l OrderedList) read(ctx context.Context) (Provider, []TimestampedValue, error) {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, _, err := p.Read(l.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, _ := stored.([]TimestampedValue)
+	return p, list, nil
+}
+
+func (l OrderedList) assign(list []TimestampedValue, ptr interface{}) ([]typex.EventTime, error) {
+	slice := reflect.ValueOf(ptr)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("ordered list %v must be read into a pointer to slice, got %T", l.Key, ptr)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	ts := make([]typex.EventTime, len(list))
+	ret := reflect.MakeSlice(slice.Elem().Type(), len(list), len(list))
+	for i, tv := range list {
+		if err := assignDecoded(l.Codec, ret.Index(i).Addr().Interface(), tv.Value); err != nil {
+			return nil, err
+		}
+		ts[i] = tv.Timestamp
+	}
+	slice.Elem().Set(ret)
+	return ts, nil
+}
+
+// searchRange returns the indices of the values of the sorted list with
+// timestamps in [start, end).
+func searchRange(list []TimestampedValue, start, end typex.EventTime) (int, int) {
+	i := sort.Search(len(list), func(i int) bool {
+		return !time.Time(list[i].Timestamp).Before(time.Time(start))
+	})
+	j := sort.Search(len(list), func(i int) bool {
+		return !time.Time(list[i].Timestamp).Before(time.Time(end))
+	})
+	if j < i {
+		j = i
+	}
+	return i, j
+}
+
+// MergeOrderedLists returns the values of both sorted lists in timestamp
+// order. It is used by runners to merge the state of merged windows.
+func MergeOrderedLists(a, b []TimestampedValue) []TimestampedValue {
+	ret := make([]TimestampedValue, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if time.Time(b[0].Timestamp).Before(time.Time(a[0].Timestamp)) {
+			ret, b = append(ret, b[0]), b[1:]
+		} else {
+			ret, a = append(ret, a[0]), a[1:]
+		}
+	}
+	return append(append(ret, a...), b...)
+}
@@ -0,0 +1,280 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package state implements the user state API for stateful DoFns. State is
+// partitioned by the key of the KV main input and by window, and is only
+// accessible while processing an element or a timer of that key and window.
+// When windows are merged, such as sessions, bags and ordered lists are
+// combined and other cells keep the value of the window merged into.
+//
+// State cells are declared as exported fields of the DoFn struct, which
+// marks the DoFn as stateful. Runners supply the state of the current key via
+// the context.Context, so using state requires that the processing method
+// takes a context.Context argument:
+//
+//    type countFn struct {
+//        Count state.Value `json:"count"`
+//    }
+//
+//    func newCountFn() *countFn {
+//        return &countFn{Count: state.MakeValue("count")}
+//    }
+//
+//    func (f *countFn) ProcessElement(ctx context.Context, key string, _ int) error {
+//        var n int
+//        if _, err := f.Count.Read(ctx, &n); err != nil {
+//            return err
+//        }
+//        return f.Count.Write(ctx, n+1)
+//    }
+//
+// Stateful DoFns are only supported by some runners, notably the direct runner.
+package state
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// Provider is the runner-supplied state and timers of the current key.
+// Values are stored as-is and keyed by the cell or timer key.
+type Provider interface {
+	// Read returns the value of the cell, if any.
+	Read(key string) (interface{}, bool, error)
+	// Write sets the value of the cell.
+	Write(key string, value interface{}) error
+	// Clear removes the value of the cell.
+	Clear(key string) error
+
+	// SetTimer sets the timer with the given tag to fire at the given event
+	// time, replacing any earlier setting. The tag is empty, unless the timer
+	// is one of a family of timers.
+	SetTimer(key, tag string, t typex.EventTime) error
+	// SetProcessingTimer sets the timer with the given tag to fire at the
+	// given processing time, replacing any earlier setting.
+	SetProcessingTimer(key, tag string, t time.Time) error
+	// ClearTimer cancels the timer with the given tag, if set.
+	ClearTimer(key, tag string) error
+}
+
+type ctxKey string
+
+const providerKey ctxKey = "beam:state"
+
+// SetProvider sets the state provider of the current key.
+func SetProvider(ctx context.Context, p Provider) context.Context {
+	return context.WithValue(ctx, providerKey, p)
+}
+
+// GetProvider returns the state provider of the current key. It fails if
+// the context carries no provider, such as outside of a stateful DoFn.
+func GetProvider(ctx context.Context) (Provider, error) {
+	if p, ok := ctx.Value(providerKey).(Provider); ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("no state in context: state is only accessible in ProcessElement and OnTimer of a stateful DoFn")
+}
+
+// Cell is implemented by all state cells. It is used to detect stateful
+// DoFns.
+type Cell interface {
+	// StateKey returns the key of the cell, which must be unique within the
+	// DoFn.
+	StateKey() string
+}
+
+// Value is a state cell holding a single value.
+type Value struct {
+	Key   string `json:"key"`
+	Codec string `json:"codec,omitempty"`
+}
+
+// MakeValue returns a value cell with the given key.
+func MakeValue(key string) Value {
+	return Value{Key: key}
+}
+
+// WithCodec returns a copy of the cell that stores its value encoded with
+// the named Codec.
+func (v Value) WithCodec(codec string) Value {
+	v.Codec = codec
+	return v
+}
+
+// StateKey returns the key of the cell.
+func (v Value) StateKey() string {
+	return v.Key
+}
+
+// StateCodec returns the name of the codec of the cell, if any.
+func (v Value) StateCodec() string {
+	return v.Codec
+}
+
+// Read reads the value of the cell into the value pointed to by ptr. It
+// returns false and leaves ptr unchanged, if the cell is empty.
+func (v Value) Read(ctx context.Context, ptr interface{}) (bool, error) {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return false, err
+	}
+	val, ok, err := p.Read(v.Key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, assignDecoded(v.Codec, ptr, val)
+}
+
+// Write sets the value of the cell.
+func (v Value) Write(ctx context.Context, val interface{}) error {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	stored, err := encode(v.Codec, val)
+	if err != nil {
+		return err
+	}
+	return p.Write(v.Key, stored)
+}
+
+// Clear empties the cell.
+func (v Value) Clear(ctx context.Context) error {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return p.Clear(v.Key)
+}
+
+// Bag is a state cell holding an unordered collection of values, which are
+// added one at a time and read all together.
+type Bag struct {
+	Key   string `json:"key"`
+	Codec string `json:"codec,omitempty"`
+}
+
+// MakeBag returns a bag cell with the given key.
+func MakeBag(key string) Bag {
+	return Bag{Key: key}
+}
+
+// WithCodec returns a copy of the cell that stores its values encoded with
+// the named Codec.
+func (b Bag) WithCodec(codec string) Bag {
+	b.Codec = codec
+	return b
+}
+
+// StateKey returns the key of the cell.
+func (b Bag) StateKey() string {
+	return b.Key
+}
+
+// StateCodec returns the name of the codec of the cell, if any.
+func (b Bag) StateCodec() string {
+	return b.Codec
+}
+
+// Add adds a value to the bag.
+func (b Bag) Add(ctx context.Context, val interface{}) error {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	list, _, err := p.Read(b.Key)
+	if err != nil {
+		return err
+	}
+	stored, err := encode(b.Codec, val)
+	if err != nil {
+		return err
+	}
+	values, _ := list.([]interface{})
+	return p.Write(b.Key, append(values, stored))
+}
+
+// Read reads all values of the bag into the slice pointed to by ptr. It
+// returns false and leaves ptr unchanged, if the bag is empty.
+func (b Bag) Read(ctx context.Context, ptr interface{}) (bool, error) {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return false, err
+	}
+	list, ok, err := p.Read(b.Key)
+	if err != nil || !ok {
+		return false, err
+	}
+	slice := reflect.ValueOf(ptr)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return false, fmt.Errorf("bag %v must be read into a pointer to slice, got %T", b.Key, ptr)
+	}
+	values := list.([]interface{})
+	ret := reflect.MakeSlice(slice.Elem().Type(), len(values), len(values))
+	for i, val := range values {
+		if err := assignDecoded(b.Codec, ret.Index(i).Addr().Interface(), val); err != nil {
+			return false, err
+		}
+	}
+	slice.Elem().Set(ret)
+	return true, nil
+}
+
+// Clear empties the bag.
+func (b Bag) Clear(ctx context.Context) error {
+	p, err := GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return p.Clear(b.Key)
+}
+
+// assignDecoded decodes the stored value with the named codec, if any, and
+// assigns it to the value pointed to by ptr.
+func assignDecoded(codec string, ptr, stored interface{}) error {
+	if codec == "" {
+		return assign(ptr, stored)
+	}
+	t := reflect.TypeOf(ptr)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("state must be read into a non-nil pointer, got %T", ptr)
+	}
+	val, err := decode(codec, stored, t.Elem())
+	if err != nil {
+		return err
+	}
+	return assign(ptr, val)
+}
+
+func assign(ptr, val interface{}) error {
+	dst := reflect.ValueOf(ptr)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("state must be read into a non-nil pointer, got %T", ptr)
+	}
+	if val == nil {
+		dst.Elem().Set(reflect.Zero(dst.Elem().Type()))
+		return nil
+	}
+	src := reflect.ValueOf(val)
+	if !src.Type().AssignableTo(dst.Elem().Type()) {
+		return fmt.Errorf("state value of type %v cannot be read into %v", src.Type(), dst.Elem().Type())
+	}
+	dst.Elem().Set(src)
+	return nil
+}
@@ -0,0 +1,207 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// mapProvider is a Provider for a single key backed by a map.
+type mapProvider map[string]interface{}
+
+func (m mapProvider) Read(key string) (interface{}, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+func (m mapProvider) Write(key string, value interface{}) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapProvider) Clear(key string) error {
+	delete(m, key)
+	return nil
+}
+
+func (m mapProvider) SetTimer(key, tag string, t typex.EventTime) error {
+	return nil
+}
+
+func (m mapProvider) SetProcessingTimer(key, tag string, t time.Time) error {
+	return nil
+}
+
+func (m mapProvider) ClearTimer(key, tag string) error {
+	return nil
+}
+
+// uint16Codec encodes ints as 2 bytes.
+type uint16Codec struct{}
+
+func (uint16Codec) Encode(value interface{}) ([]byte, error) {
+	n, ok := value.(int)
+	if !ok || n < 0 || n > 1<<16-1 {
+		return nil, fmt.Errorf("value %v is not a uint16", value)
+	}
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(n))
+	return buf[:], nil
+}
+
+func (uint16Codec) Decode(data []byte, t reflect.Type) (interface{}, error) {
+	return int(binary.BigEndian.Uint16(data)), nil
+}
+
+func init() {
+	RegisterCodec("uint16", uint16Codec{})
+}
+
+func TestValueCodec(t *testing.T) {
+	m := mapProvider{}
+	ctx := SetProvider(context.Background(), m)
+
+	v := MakeValue("v").WithCodec("uint16")
+	if err := v.Write(ctx, 513); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got, want := m["v"], []byte{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored value = %v, want %v", got, want)
+	}
+	var n int
+	if ok, err := v.Read(ctx, &n); !ok || err != nil || n != 513 {
+		t.Errorf("Read = (%v, %v, %v), want (513, true, nil)", n, ok, err)
+	}
+	if err := v.Write(ctx, -1); err == nil {
+		t.Errorf("Write(-1) succeeded, want encoding error")
+	}
+
+	j := MakeValue("j").WithCodec("json")
+	if err := j.Write(ctx, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var obj map[string]int
+	if ok, err := j.Read(ctx, &obj); !ok || err != nil || obj["a"] != 1 {
+		t.Errorf("Read = (%v, %v, %v), want (map[a:1], true, nil)", obj, ok, err)
+	}
+}
+
+func TestBagCodec(t *testing.T) {
+	m := mapProvider{}
+	ctx := SetProvider(context.Background(), m)
+
+	b := MakeBag("b").WithCodec("uint16")
+	for _, n := range []int{1, 256} {
+		if err := b.Add(ctx, n); err != nil {
+			t.Fatalf("Add(%v) failed: %v", n, err)
+		}
+	}
+	if got, want := m["b"], []interface{}{[]byte{0, 1}, []byte{1, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored values = %v, want %v", got, want)
+	}
+	var list []int
+	if ok, err := b.Read(ctx, &list); !ok || err != nil || !reflect.DeepEqual(list, []int{1, 256}) {
+		t.Errorf("Read = (%v, %v, %v), want ([1 256], true, nil)", list, ok, err)
+	}
+}
+
+func TestUnknownCodec(t *testing.T) {
+	ctx := SetProvider(context.Background(), mapProvider{})
+	if err := MakeValue("v").WithCodec("unknown").Write(ctx, 1); err == nil {
+		t.Errorf("Write with unknown codec succeeded, want error")
+	}
+}
+
+func TestOrderedList(t *testing.T) {
+	m := mapProvider{}
+	ctx := SetProvider(context.Background(), m)
+	at := func(sec int64) typex.EventTime {
+		return typex.EventTime(time.Unix(sec, 0))
+	}
+
+	l := MakeOrderedList("l").WithCodec("uint16")
+	// Values are the timestamp times 10 plus the order of addition, so
+	// values with equal timestamps keep their order.
+	for i, n := range []int{5, 1, 3, 30, 10, 3} {
+		if err := l.Add(ctx, at(int64(n)), n*10+i); err != nil {
+			t.Fatalf("Add(%v) failed: %v", n, err)
+		}
+	}
+
+	var list []int
+	ts, err := l.Read(ctx, &list)
+	if err != nil || !reflect.DeepEqual(list, []int{11, 32, 35, 50, 104, 303}) {
+		t.Errorf("Read = (%v, %v), want [11 32 35 50 104 303]", list, err)
+	}
+	if want := []typex.EventTime{at(1), at(3), at(3), at(5), at(10), at(30)}; !reflect.DeepEqual(ts, want) {
+		t.Errorf("Read timestamps = %v, want %v", ts, want)
+	}
+
+	tests := []struct {
+		start, end int64
+		want       []int
+	}{
+		{3, 10, []int{32, 35, 50}},
+		{0, 1, nil},
+		{11, 30, nil},
+		{10, 100, []int{104, 303}},
+		{20, 10, nil},
+	}
+	for _, test := range tests {
+		var got []int
+		if _, err := l.ReadRange(ctx, at(test.start), at(test.end), &got); err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ReadRange(%v, %v) = (%v, %v), want %v", test.start, test.end, got, err, test.want)
+		}
+	}
+
+	if err := l.ClearRange(ctx, at(2), at(10)); err != nil {
+		t.Fatalf("ClearRange failed: %v", err)
+	}
+	list = nil
+	if _, err := l.Read(ctx, &list); err != nil || !reflect.DeepEqual(list, []int{11, 104, 303}) {
+		t.Errorf("Read after ClearRange = (%v, %v), want [11 104 303]", list, err)
+	}
+	if err := l.ClearRange(ctx, at(0), at(100)); err != nil {
+		t.Fatalf("ClearRange failed: %v", err)
+	}
+	if ts, err := l.Read(ctx, &list); len(ts) != 0 || err != nil {
+		t.Errorf("Read after clearing all = (%v, %v), want empty", ts, err)
+	}
+	if _, ok := m["l"]; ok {
+		t.Errorf("cleared list is still stored: %v", m["l"])
+	}
+}
+
+func TestMergeOrderedLists(t *testing.T) {
+	tv := func(sec int64, v string) TimestampedValue {
+		return TimestampedValue{Timestamp: typex.EventTime(time.Unix(sec, 0)), Value: v}
+	}
+	a := []TimestampedValue{tv(1, "a1"), tv(5, "a5")}
+	b := []TimestampedValue{tv(1, "b1"), tv(3, "b3"), tv(7, "b7")}
+
+	got := MergeOrderedLists(a, b)
+	want := []TimestampedValue{tv(1, "a1"), tv(1, "b1"), tv(3, "b3"), tv(5, "a5"), tv(7, "b7")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeOrderedLists(%v, %v) = %v, want %v", a, b, got, want)
+	}
+}
@@ -0,0 +1,215 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timers implements the user timer API for stateful DoFns. Timers
+// are partitioned by key like state and fire a callback, OnTimer, for the key
+// they were set in. Timers are declared as exported fields of the DoFn
+// struct:
+//
+//    type flushFn struct {
+//        Buffer state.Bag        `json:"buffer"`
+//        Flush  timers.EventTime `json:"flush"`
+//    }
+//
+//    func (f *flushFn) ProcessElement(ctx context.Context, t typex.EventTime, key string, v int, _ func(string, []int)) error {
+//        if err := f.Buffer.Add(ctx, v); err != nil {
+//            return err
+//        }
+//        return f.Flush.Set(ctx, t)
+//    }
+//
+//    func (f *flushFn) OnTimer(ctx context.Context, key, timer string, emit func(string, []int)) error {
+//        var list []int
+//        if _, err := f.Buffer.Read(ctx, &list); err != nil {
+//            return err
+//        }
+//        emit(key, list)
+//        return f.Buffer.Clear(ctx)
+//    }
+//
+// OnTimer takes the same context, key, side inputs and emitters as
+// ProcessElement, and the key of the timer that fired in place of the value.
+// An optional typex.EventTime parameter receives the firing time. Emitters
+// used by OnTimer only must be declared by ProcessElement as well.
+//
+// Processing-time timers fire when the wall clock of the runner, or the
+// processing time of a TestStream, passes the time they are set to. They
+// are useful for timeouts:
+//
+//    Timeout timers.ProcessingTime `json:"timeout"`
+//
+//    ... f.Timeout.SetAfter(ctx, time.Minute)
+//
+// They fire with the event time of the input watermark.
+//
+// A timer family holds any number of independent timers per key, told
+// apart by a dynamic tag, such as one timer per pending request:
+//
+//    Expiry timers.EventTime `json:"expiry"`
+//
+//    ... f.Expiry.SetTag(ctx, requestID, t)
+//
+// An OnTimer that takes a timers.Fired in place of the timer key receives
+// the tag of the timer that fired:
+//
+//    func (f *requestFn) OnTimer(ctx context.Context, key string, timer timers.Fired, emit func(string)) {
+//        emit(timer.Tag)
+//    }
+//
+// Set and Clear use the empty tag.
+package timers
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// EventTime is a timer that fires when the input watermark passes the time
+// it is set to.
+type EventTime struct {
+	Key string `json:"key"`
+}
+
+// InEventTime returns an event-time timer with the given key.
+func InEventTime(key string) EventTime {
+	return EventTime{Key: key}
+}
+
+// StateKey returns the key of the timer. Timers share the key space of the
+// state cells of the DoFn.
+func (t EventTime) StateKey() string {
+	return t.Key
+}
+
+// Set sets the timer to fire at the given event time, replacing any earlier
+// setting for the current key.
+func (t EventTime) Set(ctx context.Context, at typex.EventTime) error {
+	return t.SetTag(ctx, "", at)
+}
+
+// SetTag sets the timer of the family with the given tag to fire at the
+// given event time, replacing any earlier setting for the current key.
+func (t EventTime) SetTag(ctx context.Context, tag string, at typex.EventTime) error {
+	p, err := state.GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return p.SetTimer(t.Key, tag, at)
+}
+
+// Clear cancels the timer for the current key, if set.
+func (t EventTime) Clear(ctx context.Context) error {
+	return t.ClearTag(ctx, "")
+}
+
+// ClearTag cancels the timer of the family with the given tag for the
+// current key, if set.
+func (t EventTime) ClearTag(ctx context.Context, tag string) error {
+	p, err := state.GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return p.ClearTimer(t.Key, tag)
+}
+
+// ProcessingTime is a timer that fires when the processing time passes the
+// time it is set to.
+type ProcessingTime struct {
+	Key string `json:"key"`
+}
+
+// InProcessingTime returns a processing-time timer with the given key.
+func InProcessingTime(key string) ProcessingTime {
+	return ProcessingTime{Key: key}
+}
+
+// StateKey returns the key of the timer. Timers share the key space of the
+// state cells of the DoFn.
+func (t ProcessingTime) StateKey() string {
+	return t.Key
+}
+
+// Set sets the timer to fire at the given processing time, replacing any
+// earlier setting for the current key.
+func (t ProcessingTime) Set(ctx context.Context, at time.Time) error {
+	return t.SetTag(ctx, "", at)
+}
+
+// SetTag sets the timer of the family with the given tag to fire at the
+// given processing time, replacing any earlier setting for the current key.
+func (t ProcessingTime) SetTag(ctx context.Context, tag string, at time.Time) error {
+	p, err := state.GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return p.SetProcessingTimer(t.Key, tag, at)
+}
+
+// SetAfter sets the timer to fire the given duration after the current
+// processing time, replacing any earlier setting for the current key.
+func (t ProcessingTime) SetAfter(ctx context.Context, d time.Duration) error {
+	return t.Set(ctx, Now(ctx).Add(d))
+}
+
+// SetTagAfter sets the timer of the family with the given tag to fire the
+// given duration after the current processing time.
+func (t ProcessingTime) SetTagAfter(ctx context.Context, tag string, d time.Duration) error {
+	return t.SetTag(ctx, tag, Now(ctx).Add(d))
+}
+
+// Clear cancels the timer for the current key, if set.
+func (t ProcessingTime) Clear(ctx context.Context) error {
+	return t.ClearTag(ctx, "")
+}
+
+// ClearTag cancels the timer of the family with the given tag for the
+// current key, if set.
+func (t ProcessingTime) ClearTag(ctx context.Context, tag string) error {
+	p, err := state.GetProvider(ctx)
+	if err != nil {
+		return err
+	}
+	return p.ClearTimer(t.Key, tag)
+}
+
+// Fired identifies the timer that fired. OnTimer may take it in place of the
+// key of the timer to receive the tag of a timer family.
+type Fired struct {
+	Key string // key of the timer
+	Tag string // tag of the timer; empty unless set with SetTag
+}
+
+type ctxKey string
+
+const nowKey ctxKey = "beam:processingtime"
+
+// SetNow returns a context with the current processing time. It is used by
+// runners whose processing time is not the wall clock, such as when
+// replaying a TestStream.
+func SetNow(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, nowKey, now)
+}
+
+// Now returns the current processing time: the time set by the runner, if
+// any, and the wall clock time otherwise.
+func Now(ctx context.Context) time.Time {
+	if now, ok := ctx.Value(nowKey).(time.Time); ok {
+		return now
+	}
+	return time.Now()
+}
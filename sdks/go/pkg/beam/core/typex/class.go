@@ -21,6 +21,7 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -65,13 +66,21 @@ func ClassOf(t reflect.Type) Class {
 		return Composite
 	case IsContainer(t): // overrules IsConcrete
 		return Container
-	case IsConcrete(t):
+	case IsConcrete(t), IsNullable(t):
 		return Concrete
 	default:
 		return Invalid
 	}
 }
 
+// IsNullable returns true iff the given type is a pointer to a concrete data
+// type. Such data may be nil and is coded with a nullable coder. Pointers are
+// not themselves concrete, so they are not valid as struct fields, container
+// elements or combiner accumulators.
+func IsNullable(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Ptr && IsConcrete(t.Elem())
+}
+
 // IsConcrete returns true iff the given type is a valid "concrete" data type. Such
 // data must be fully serializable. Functions and channels are examples of invalid
 // types. Aggregate types with no universals are considered concrete here.
@@ -85,6 +94,9 @@ func IsConcrete(t reflect.Type) bool {
 	if t.Implements(protoMessageType) {
 		return true
 	}
+	if t == reflectx.Time {
+		return true
+	}
 
 	switch t.Kind() {
 	case reflect.Invalid, reflect.UnsafePointer, reflect.Uintptr, reflect.Interface:
@@ -93,14 +105,17 @@ func IsConcrete(t reflect.Type) bool {
 	case reflect.Chan, reflect.Func:
 		return false // no unserializable types
 
-	case reflect.Map, reflect.Array:
+	case reflect.Array:
 		return false // TBD
 
+	case reflect.Map:
+		return IsConcrete(t.Key()) && IsConcrete(t.Elem())
+
 	case reflect.Slice:
 		return IsConcrete(t.Elem())
 
 	case reflect.Ptr:
-		return false // TBD
+		return false // TBD; see IsNullable
 
 	case reflect.Struct:
 		for i := 0; i < t.NumField(); i++ {
@@ -143,24 +158,43 @@ func IsConcrete(t reflect.Type) bool {
 }
 
 // IsContainer returns true iff the given type is an container data type,
-// such as []int or []T.
+// such as []int, []T, map[string]int or map[T]U.
 func IsContainer(t reflect.Type) bool {
 	switch {
 	case IsList(t):
-		if IsUniversal(t.Elem()) || IsConcrete(t.Elem()) {
-			return true
-		}
-		return IsContainer(t.Elem())
+		return isContainerElement(t.Elem())
+	case IsMap(t):
+		return isContainerElement(t.Key()) && isContainerElement(t.Elem())
 	default:
 		return false
 	}
 }
 
+func isContainerElement(t reflect.Type) bool {
+	return IsUniversal(t) || IsConcrete(t) || IsContainer(t)
+}
+
 // IsList returns true iff the given type is a slice.
 func IsList(t reflect.Type) bool {
 	return t.Kind() == reflect.Slice
 }
 
+// IsMap returns true iff the given type is a map.
+func IsMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map
+}
+
+// IsSet returns true iff the given type is a set, i.e., a map with empty
+// struct values such as map[string]struct{}.
+func IsSet(t reflect.Type) bool {
+	return IsMap(t) && t.Elem() == EmptyType
+}
+
+// SetOf returns the set type with the given element type.
+func SetOf(t reflect.Type) reflect.Type {
+	return reflect.MapOf(t, EmptyType)
+}
+
 // IsUniversal returns true iff the given type is one of the predefined
 // universal types: T, U, V, W, X, Y or Z.
 func IsUniversal(t reflect.Type) bool {
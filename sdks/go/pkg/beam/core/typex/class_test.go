@@ -16,6 +16,7 @@
 package typex
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
 
@@ -40,6 +41,7 @@ func TestClassOf(t *testing.T) {
 		{reflectx.Uint32, Concrete},
 		{reflectx.Uint64, Concrete},
 		{reflectx.String, Concrete},
+		{reflectx.Time, Concrete},
 		{reflect.TypeOf(struct{ A int }{}), Concrete},
 		{reflect.TypeOf(struct {
 			A int
@@ -47,11 +49,22 @@ func TestClassOf(t *testing.T) {
 		}{}), Concrete},
 		{reflect.TypeOf(struct{ A []int }{}), Concrete},
 		{reflect.TypeOf(reflect.Value{}), Concrete}, // ok: private fields
+		{reflect.TypeOf((*int)(nil)), Concrete},
+		{reflect.TypeOf((*struct{ A string })(nil)), Concrete},
+		{reflect.TypeOf(big.Int{}), Concrete},
+		{reflect.TypeOf((*big.Int)(nil)), Concrete},
+		{reflect.TypeOf((*big.Rat)(nil)), Concrete},
+		{reflect.TypeOf((*big.Float)(nil)), Concrete},
 
 		{reflect.TypeOf([]X{}), Container},
 		{reflect.TypeOf([][][]X{}), Container},
 		{reflect.TypeOf([]int{}), Container},
 		{reflect.TypeOf([][][]uint16{}), Container},
+		{reflect.TypeOf(map[string]int{}), Container},
+		{reflect.TypeOf(map[X]Y{}), Container},
+		{reflect.TypeOf(map[string][]X{}), Container},
+		{reflect.TypeOf(map[string]struct{}{}), Container},
+		{reflect.TypeOf(struct{ A map[string]int }{}), Concrete},
 
 		{TType, Universal},
 		{UType, Universal},
@@ -71,6 +84,11 @@ func TestClassOf(t *testing.T) {
 		{reflect.TypeOf(func() {}), Invalid},                  // function
 		{reflect.TypeOf(make(chan int)), Invalid},             // chan
 		{reflect.TypeOf(struct{ A error }{}), Invalid},        // public interface field
+		{reflect.TypeOf((*chan int)(nil)), Invalid},           // pointer to chan
+		{reflect.TypeOf((**int)(nil)), Invalid},               // pointer to pointer
+		{reflect.TypeOf(struct{ A *string }{}), Invalid},      // public pointer field
+		{reflect.TypeOf([]*int{}), Invalid},                   // pointer elements
+		{reflect.TypeOf(map[string]func(){}), Invalid},        // map of functions
 	}
 
 	for _, test := range tests {
@@ -92,6 +110,10 @@ func TestIsConcrete(t *testing.T) {
 		{reflect.TypeOf([][][]uint16{}), true},
 		{reflect.TypeOf([]Y{}), false},
 		{reflect.TypeOf([][][]Z{}), false},
+		{reflect.TypeOf(map[string]int{}), true},
+		{reflect.TypeOf(map[string]X{}), false},
+		{reflect.TypeOf((*int)(nil)), false},
+		{reflect.TypeOf(map[string]*int{}), false},
 	}
 
 	for _, test := range tests {
@@ -102,3 +124,25 @@ func TestIsConcrete(t *testing.T) {
 	}
 
 }
+
+func TestIsNullable(t *testing.T) {
+	tests := []struct {
+		t   reflect.Type
+		exp bool
+	}{
+		{reflect.TypeOf((*int)(nil)), true},
+		{reflect.TypeOf((*[]string)(nil)), true},
+		{reflect.TypeOf((*big.Int)(nil)), true},
+		{reflect.TypeOf(0), false},
+		{reflect.TypeOf((**int)(nil)), false},
+		{reflect.TypeOf((*chan int)(nil)), false},
+		{reflect.TypeOf((*X)(nil)), false},
+	}
+
+	for _, test := range tests {
+		actual := IsNullable(test.t)
+		if actual != test.exp {
+			t.Errorf("IsNullable(%v) = %v, want %v", test.t, actual, test.exp)
+		}
+	}
+}
@@ -64,6 +64,9 @@ func (t *tree) String() string {
 		if IsList(t.t) {
 			return fmt.Sprintf("[]%v", t.components[0])
 		}
+		if IsMap(t.t) {
+			return fmt.Sprintf("map[%v]%v", t.components[0], t.components[1])
+		}
 		return fmt.Sprintf("<invalid: %v>", t.t)
 	case Composite:
 		var args []string
@@ -105,6 +108,9 @@ func New(t reflect.Type, components ...FullType) FullType {
 		case reflect.Slice:
 			// We include the child type as a component for convenience.
 			return &tree{class, t, []FullType{New(t.Elem())}}
+		case reflect.Map:
+			// We include the key and value types as components for convenience.
+			return &tree{class, t, []FullType{New(t.Key()), New(t.Elem())}}
 		default:
 			panic(fmt.Sprintf("Unexpected aggregate type: %v", t))
 		}
@@ -227,7 +233,15 @@ func IsStructurallyAssignable(from, to FullType) bool {
 		return to.Class() == Universal || to.Class() == Concrete || to.Class() == Container
 	case Container:
 		if to.Class() == Container {
-			return IsList(from.Type()) && IsList(to.Type()) && IsStructurallyAssignable(from.Components()[0], to.Components()[0])
+			switch {
+			case IsList(from.Type()) && IsList(to.Type()):
+				return IsStructurallyAssignable(from.Components()[0], to.Components()[0])
+			case IsMap(from.Type()) && IsMap(to.Type()):
+				return IsStructurallyAssignable(from.Components()[0], to.Components()[0]) &&
+					IsStructurallyAssignable(from.Components()[1], to.Components()[1])
+			default:
+				return false
+			}
 		}
 		return to.Class() == Universal
 	case Composite:
@@ -296,26 +310,83 @@ func IsBound(t FullType) bool {
 // corresponding type. For example, Bind(KV<T,int>, KV<string, int>) would
 // produce {"T" -> string}.
 func Bind(types, models []FullType) (map[string]reflect.Type, error) {
+	var origins []string
+	for i := range types {
+		origins = append(origins, fmt.Sprintf("input %v", i))
+	}
+	m, _, err := BindTrace(types, models, origins)
+	return m, err
+}
+
+// Binding records that a universal type was bound to a concrete type and
+// where the constraint came from.
+type Binding struct {
+	// Name is the name of the universal type, such as "T".
+	Name string
+	// Type is the type it was bound to.
+	Type reflect.Type
+	// Origin describes the type that constrained the universal, such as
+	// "main input KV<T,int>".
+	Origin string
+	// Path is the position of the universal within the origin type, such as
+	// "key". It is empty if the origin type is the universal itself.
+	Path string
+}
+
+func (b Binding) String() string {
+	if b.Path == "" {
+		return fmt.Sprintf("%v=%v (%v)", b.Name, b.Type, b.Origin)
+	}
+	return fmt.Sprintf("%v=%v (%v, %v)", b.Name, b.Type, b.Origin, b.Path)
+}
+
+// BindingError is a type checking error for universal types. It includes the
+// trace of bindings that led to the error, in order.
+type BindingError struct {
+	// Msg describes the error, such as a conflict.
+	Msg string
+	// Trace holds the bindings made before the error.
+	Trace []Binding
+}
+
+func (e *BindingError) Error() string {
+	if len(e.Trace) == 0 {
+		return e.Msg
+	}
+	var lines []string
+	for _, b := range e.Trace {
+		lines = append(lines, "\t"+b.String())
+	}
+	return fmt.Sprintf("%v\nbinding trace:\n%v", e.Msg, strings.Join(lines, "\n"))
+}
+
+// BindTrace is like Bind, but also returns the trace of bindings made. Each
+// binding refers to the origin with the same index as the type that produced
+// it, which lets callers describe types in terms of parameters. Errors are
+// returned as a *BindingError.
+func BindTrace(types, models []FullType, origins []string) (map[string]reflect.Type, []Binding, error) {
 	if len(types) != len(models) {
-		return nil, fmt.Errorf("invalid number of modes: %v, want %v", len(models), len(types))
+		return nil, nil, fmt.Errorf("invalid number of modes: %v, want %v", len(models), len(types))
 	}
 
 	m := make(map[string]reflect.Type)
+	var trace []Binding
 	for i := 0; i < len(types); i++ {
 		t := types[i]
 		model := models[i]
 
 		if !IsStructurallyAssignable(model, t) {
-			return nil, fmt.Errorf("%v is not assignable to %v", model, t)
+			return nil, trace, &BindingError{Msg: fmt.Sprintf("%v is not assignable to %v (%v)", model, t, origins[i]), Trace: trace}
 		}
-		if err := walk(t, model, m); err != nil {
-			return nil, err
+		origin := fmt.Sprintf("%v %v", origins[i], t)
+		if err := walk(t, model, m, origin, "", &trace); err != nil {
+			return nil, trace, err
 		}
 	}
-	return m, nil
+	return m, trace, nil
 }
 
-func walk(t, model FullType, m map[string]reflect.Type) error {
+func walk(t, model FullType, m map[string]reflect.Type, origin, path string, trace *[]Binding) error {
 	switch t.Class() {
 	case Universal:
 		// By checking that the model is assignable to t, we know that they are
@@ -324,14 +395,23 @@ func walk(t, model FullType, m map[string]reflect.Type) error {
 		// construct such a type.
 
 		name := t.Type().Name()
+		b := Binding{Name: name, Type: model.Type(), Origin: origin, Path: path}
 		if current, ok := m[name]; ok && current != model.Type() {
-			return fmt.Errorf("bind conflict for %v: %v != %v", name, current, model.Type())
+			return &BindingError{
+				Msg:   fmt.Sprintf("bind conflict for %v: %v != %v", name, current, model.Type()),
+				Trace: append(*trace, b),
+			}
 		}
 		m[name] = model.Type()
+		*trace = append(*trace, b)
 		return nil
 	case Composite, Container:
 		for i, elm := range t.Components() {
-			if err := walk(elm, model.Components()[i], m); err != nil {
+			p := componentName(t, i)
+			if path != "" {
+				p = path + "." + p
+			}
+			if err := walk(elm, model.Components()[i], m, origin, p, trace); err != nil {
 				return err
 			}
 		}
@@ -341,6 +421,29 @@ func walk(t, model FullType, m map[string]reflect.Type) error {
 	}
 }
 
+// componentName returns a readable name of the i'th component of the given
+// composite or container type.
+func componentName(t FullType, i int) string {
+	switch {
+	case t.Type() == KVType, IsMap(t.Type()):
+		if i == 0 {
+			return "key"
+		}
+		return "value"
+	case t.Type() == CoGBKType:
+		if i == 0 {
+			return "key"
+		}
+		return fmt.Sprintf("values %v", i)
+	case t.Type() == WindowedValueType:
+		return "value"
+	case IsList(t.Type()):
+		return "element"
+	default:
+		return fmt.Sprintf("component %v", i)
+	}
+}
+
 // Substitute returns types identical to the given types, but with all
 // universals substituted. All free type variables must be present in the
 // substitution.
@@ -373,6 +476,12 @@ func substitute(t FullType, m map[string]reflect.Type) (FullType, error) {
 		if IsList(t.Type()) {
 			return New(reflect.SliceOf(comp[0].Type()), comp...), nil
 		}
+		if IsMap(t.Type()) {
+			if !comp[0].Type().Comparable() {
+				return nil, fmt.Errorf("invalid map key type: %v", comp[0])
+			}
+			return New(reflect.MapOf(comp[0].Type(), comp[1].Type()), comp...), nil
+		}
 		panic(fmt.Sprintf("Unexpected aggregate: %v", t))
 	case Composite:
 		comp, err := substituteList(t.Components(), m)
@@ -60,6 +60,9 @@ func TestIsStructurallyAssignable(t *testing.T) {
 		{NewKV(New(reflectx.String), New(reflectx.Int)), NewKV(New(TType), New(TType)), true},
 		{NewKV(New(reflectx.Int), New(reflectx.Int)), NewKV(New(TType), New(TType)), true},
 		{NewKV(New(reflectx.Int), New(reflectx.String)), NewKV(New(TType), New(reflectx.String)), true},
+		{New(reflect.TypeOf(map[string]int{})), New(reflect.TypeOf(map[X]Y{})), true},
+		{New(reflect.TypeOf(map[string]int{})), New(reflect.TypeOf(map[X]X{})), true},
+		{New(reflect.TypeOf(map[string]int{})), New(reflect.TypeOf([]X{})), false},
 	}
 
 	for _, test := range tests {
@@ -103,6 +106,18 @@ func TestBindSubstitute(t *testing.T) {
 			NewCoGBK(New(YType), New(XType)),
 			NewCoGBK(New(XType), New(ZType)),
 		},
+		{
+			New(reflect.TypeOf(map[string]int{})),
+			New(reflect.TypeOf(map[X]Y{})),
+			New(reflect.TypeOf(map[Y]X{})),
+			New(reflect.TypeOf(map[int]string{})),
+		},
+		{
+			NewKV(New(reflectx.String), New(reflectx.Int)),
+			NewKV(New(XType), New(YType)),
+			New(SetOf(XType)),
+			New(SetOf(reflectx.String)),
+		},
 	}
 
 	for _, test := range tests {
@@ -34,6 +34,9 @@ var (
 
 	EventTimeType = reflect.TypeOf((*EventTime)(nil)).Elem()
 
+	// EmptyType is the empty struct type, used as the value type of sets.
+	EmptyType = reflect.TypeOf(struct{}{})
+
 	KVType            = reflect.TypeOf((*KV)(nil)).Elem()
 	CoGBKType         = reflect.TypeOf((*CoGBK)(nil)).Elem()
 	WindowedValueType = reflect.TypeOf((*WindowedValue)(nil)).Elem()
@@ -53,6 +56,15 @@ type Z interface{}
 // EventTime is a time.Time that Beam understands as attached to an element.
 type EventTime time.Time
 
+// Window is a concrete window that elements are assigned to, such as the
+// global window or an interval of event time.
+type Window interface {
+	// MaxTimestamp returns the largest timestamp in the window.
+	MaxTimestamp() EventTime
+	// Equals returns true iff the windows are identical.
+	Equals(o Window) bool
+}
+
 // KV, CoGBK, WindowedValue are composite generic types. They are not used
 // directly in user code signatures, but only in FullTypes. The fields below
 // are for documentation only.
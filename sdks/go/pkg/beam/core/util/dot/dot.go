@@ -17,114 +17,160 @@
 package dot
 
 import (
+	"bufio"
 	"fmt"
 	"io"
-	"text/template"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
 )
 
-var (
-	header = `
-digraph execution_plan {
-  label="execution_plan"
+const (
+	header = `digraph execution_plan {
+  label="execution_plan";
   labeljust="l";
   fontname="Ubuntu";
   fontsize="13";
-  bgcolor="lightgray";
+  bgcolor="#e6ecfa";
   style="solid";
   penwidth="0.5";
-	concentrate="true";
+  concentrate="true";
 
-	// Node definition used for multiedge
-  node [shape="rectangle" style="filled" fillcolor="honeydew" fontname="Ubuntu" penwidth="1.0" margin="0.05,0.0.05"];
-
-	bgcolor="#e6ecfa";
-`
-
-	nodeText = `  "{{.Name}}" [ shape="ellipse" fillcolor = "lightblue" label="{{.Label}}"]
+  // Node definition used for multiedge
+  node [shape="rectangle" style="filled" fillcolor="honeydew" fontname="Ubuntu" penwidth="1.0" margin="0.05,0.05"];
 `
-	edgeText = `  "{{.From}}" -> "{{.To}}"
-`
-	footer = `
-}
+	footer = `}
 `
-	nodeTmpl = template.Must(template.New("node").Parse(nodeText))
-	edgeTmpl = template.Must(template.New("edge").Parse(edgeText))
 )
 
-type nodeLinks struct {
-	From *graph.Outbound
-	To   *graph.Inbound
-	Node *graph.Node
-}
+// Render produces a DOT-compatible representation of the graph into the
+// supplied io.Writer. Transforms are drawn as boxes, grouped into clusters by
+// scope, and PCollections as ellipses annotated with their type, windowing
+// and coder. The output is deterministic.
+func Render(edges []*graph.MultiEdge, nodes []*graph.Node, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(header)
 
-func (n *nodeLinks) String() string {
-	status := ""
-	if n.Node == nil {
-		status = "UNKNOWN NODE\n"
-	}
+	// (1) Transforms, nested by scope.
 
-	if n.From != nil && n.To != nil {
-		return fmt.Sprintf("%sModel: %d: %v\nAs: %d: %v", status, n.To.From.ID(), n.To.Type, n.From.To.ID(), n.From.Type)
-	}
+	tree := newScopeTree(edges)
+	tree.render(bw, "  ")
+
+	// (2) PCollections. Unconnected nodes shouldn't happen in practice, but
+	// are marked to help debugging construction.
 
-	if n.To != nil {
-		return fmt.Sprintf("%sModel: %d: %v", status, n.To.From.ID(), n.To.Type)
+	connected := make(map[*graph.Node]bool)
+	for _, edge := range edges {
+		for _, in := range edge.Input {
+			connected[in.From] = true
+		}
+		for _, out := range edge.Output {
+			connected[out.To] = true
+		}
+	}
+	sorted := append([]*graph.Node(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID() < sorted[j].ID() })
+
+	bw.WriteString("\n")
+	for _, n := range sorted {
+		lines := []string{
+			fmt.Sprintf("%v: %v", n.ID(), n.Type()),
+			fmt.Sprintf("window: %v", n.Window()),
+			fmt.Sprintf("coder: %v", n.Coder),
+		}
+		if !connected[n] {
+			lines = append(lines, "UNCONNECTED NODE")
+		}
+		fmt.Fprintf(bw, "  %q [shape=\"ellipse\" fillcolor=\"lightblue\" label=\"%v\"];\n", nodeID(n), label(lines...))
 	}
 
-	if n.From != nil {
-		return fmt.Sprintf("%sAs: %d %v", status, n.From.To.ID(), n.From.Type)
+	// (3) Connections. Side inputs are labelled with their kind.
+
+	bw.WriteString("\n")
+	for _, edge := range edges {
+		for _, in := range edge.Input {
+			if in.Kind == graph.Main {
+				fmt.Fprintf(bw, "  %q -> %q;\n", nodeID(in.From), edgeID(edge))
+			} else {
+				fmt.Fprintf(bw, "  %q -> %q [style=\"dashed\" label=\"%v\"];\n", nodeID(in.From), edgeID(edge), label(string(in.Kind)))
+			}
+		}
+		for _, out := range edge.Output {
+			fmt.Fprintf(bw, "  %q -> %q;\n", edgeID(edge), nodeID(out.To))
+		}
 	}
 
-	return fmt.Sprintf("%sUNCONNECTED NODE", status)
+	bw.WriteString(footer)
+	return bw.Flush()
 }
 
-// Render produces a DOT-compatible representation of the graph into the supplied io.Writer
-func Render(edges []*graph.MultiEdge, nodes []*graph.Node, w io.Writer) error {
-	uniqNodes := make(map[*graph.Node]*nodeLinks)
+// scopeTree holds the edges of a scope and its nested scopes, in order of
+// first appearance.
+type scopeTree struct {
+	scope    *graph.Scope
+	edges    []*graph.MultiEdge
+	children []*scopeTree
+}
 
-	// Create a lookup structure for all the created nodes. This helps identify
-	// unconnected nodes (which shouldn't happen in practice).
-	for _, node := range nodes {
-		uniqNodes[node] = &nodeLinks{Node: node, From: nil, To: nil}
-	}
+func newScopeTree(edges []*graph.MultiEdge) *scopeTree {
+	root := &scopeTree{}
+	trees := make(map[*graph.Scope]*scopeTree)
 
-	// Extract the node connectivity from the graph
-	for _, edge := range edges {
-		for _, ib := range edge.Input {
-			uniqNodes[ib.From].To = ib
+	var lookup func(s *graph.Scope) *scopeTree
+	lookup = func(s *graph.Scope) *scopeTree {
+		if s.Parent == nil {
+			return root // ignore the root scope
 		}
-		for _, ob := range edge.Output {
-			uniqNodes[ob.To].From = ob
+		if t, ok := trees[s]; ok {
+			return t
 		}
+		t := &scopeTree{scope: s}
+		trees[s] = t
+		parent := lookup(s.Parent)
+		parent.children = append(parent.children, t)
+		return t
 	}
 
-	// Render the graph elements: nodes and the edges
-	w.Write([]byte(header))
-	for node := range uniqNodes {
-		err := nodeTmpl.Execute(w, struct{ Name, Label string }{node.String(), uniqNodes[node].String()})
-		if err != nil {
-			return err
-		}
+	for _, edge := range edges {
+		t := lookup(edge.Scope())
+		t.edges = append(t.edges, edge)
 	}
+	return root
+}
 
-	for _, edge := range edges {
-		e := fmt.Sprintf("%d: %s", edge.ID(), edge.Op)
-		for _, ib := range edge.Input {
-			err := edgeTmpl.Execute(w, struct{ From, To string }{ib.From.String(), e})
-			if err != nil {
-				return fmt.Errorf("render DOT failed: %v", err)
-			}
-		}
-		for _, ob := range edge.Output {
-			uniqNodes[ob.To].From = ob
-			err := edgeTmpl.Execute(w, struct{ From, To string }{e, ob.To.String()})
-			if err != nil {
-				return fmt.Errorf("render DOT failed: %v", err)
-			}
+func (t *scopeTree) render(w io.Writer, indent string) {
+	for _, edge := range t.edges {
+		lines := []string{fmt.Sprintf("%v: %v", edge.ID(), edge.Op)}
+		if name := edge.Name(); name != string(edge.Op) {
+			lines = append(lines, path.Base(name))
 		}
+		fmt.Fprintf(w, "%v%q [label=\"%v\"];\n", indent, edgeID(edge), label(lines...))
+	}
+	for _, child := range t.children {
+		fmt.Fprintf(w, "%vsubgraph \"cluster_%v\" {\n", indent, child.scope.ID())
+		fmt.Fprintf(w, "%v  label=\"%v\";\n", indent, label(child.scope.Label))
+		child.render(w, indent+"  ")
+		fmt.Fprintf(w, "%v}\n", indent)
+	}
+}
+
+func nodeID(n *graph.Node) string {
+	return fmt.Sprintf("n%v", n.ID())
+}
+
+func edgeID(e *graph.MultiEdge) string {
+	return fmt.Sprintf("e%v", e.ID())
+}
+
+// label escapes the given lines for use in a quoted DOT label.
+func label(lines ...string) string {
+	var escaped []string
+	for _, l := range lines {
+		l = strings.Replace(l, `\`, `\\`, -1)
+		l = strings.Replace(l, `"`, `\"`, -1)
+		escaped = append(escaped, l)
 	}
-	w.Write([]byte(footer))
-	return nil
+	return strings.Join(escaped, `\n`)
 }
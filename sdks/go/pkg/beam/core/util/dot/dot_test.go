@@ -0,0 +1,61 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dot_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+)
+
+func addFn(a int, b []int) int {
+	for _, v := range b {
+		a += v
+	}
+	return a
+}
+
+func init() {
+	beam.RegisterFunction(addFn)
+}
+
+func TestRender(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	col := beam.Create(s, 1, 2, 3)
+	side := beam.Create(s, 4)
+	beam.ParDo(s.Scope("outer").Scope("inner"), addFn, col, beam.SideInput{Input: side})
+
+	var buf bytes.Buffer
+	if err := beam.RenderDOT(p, &buf); err != nil {
+		t.Fatalf("RenderDOT failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"subgraph \"cluster_1\" {\n    label=\"outer\";\n    subgraph \"cluster_2\" {\n      label=\"inner\";\n      \"e5\" [label=\"5: ParDo\\ndot_test.addFn\"];",
+		`label="4: int\nwindow: GW\ncoder: int[varintz]"`,
+		`"n4" -> "e5" [style="dashed" label="Slice"];`,
+		`"e5" -> "n5";`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderDOT() = %v, want it to contain %v", out, want)
+		}
+	}
+}
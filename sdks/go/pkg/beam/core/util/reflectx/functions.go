@@ -18,28 +18,93 @@ package reflectx
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"runtime"
+	"strings"
+	"sync"
 	"unsafe"
 )
 
 // TODO(herohde) 7/21/2017: we rely on the happy fact that the function name is
 // also the symbol name. We should perhaps make that connection explicit.
 
+// elidedTypeArgs is how the Go runtime names the type arguments of
+// instantiated generic functions, such as "main.ParseJSON[...]".
+const elidedTypeArgs = "[...]"
+
+var (
+	symbolNamer  func(ptr uintptr) (string, error)
+	genericNames = make(map[uintptr]string)
+	genericMu    sync.Mutex
+)
+
+// RegisterSymbolNamer registers a lookup of the symbol name of the function
+// at the given address, such as a symbol table. It is used to name
+// instantiated generic functions. It should be called in init() only.
+func RegisterSymbolNamer(namer func(ptr uintptr) (string, error)) {
+	symbolNamer = namer
+}
+
 // FunctionName returns the symbol name of a function. It panics if the given
 // value is not a function.
+//
+// The runtime elides the type arguments of instantiated generic functions,
+// so they are named by their symbol, such as "main.ParseJSON[main.MyType]",
+// if a symbol namer is registered. Otherwise, the function type is appended
+// to the elided name, which distinguishes most instantiations but is only
+// valid for registered functions.
 func FunctionName(fn interface{}) string {
 	val := reflect.ValueOf(fn)
 	if val.Kind() != reflect.Func {
 		panic(fmt.Sprintf("value %v is not a function", fn))
 	}
 
-	return runtime.FuncForPC(uintptr(val.Pointer())).Name()
+	ptr := uintptr(val.Pointer())
+	name := runtime.FuncForPC(ptr).Name()
+	if !IsElidedName(name) {
+		return name
+	}
+
+	genericMu.Lock()
+	defer genericMu.Unlock()
+
+	if ret, ok := genericNames[ptr]; ok {
+		return ret
+	}
+	ret := fmt.Sprintf("%v %v", name, val.Type())
+	if symbolNamer != nil {
+		if sym, err := symbolNamer(ptr); err == nil {
+			ret = sym
+		}
+	}
+	genericNames[ptr] = ret
+	return ret
+}
+
+// IsElidedName returns true iff the function name is an elided name of an
+// instantiated generic function, which is not a valid symbol.
+func IsElidedName(name string) bool {
+	return strings.Contains(name, elidedTypeArgs)
+}
+
+var pkgPathPrefix = regexp.MustCompile(`[\w.\-]+/`)
+
+// ShortName returns the name of a function or type without package paths,
+// including those of any type arguments. For example,
+// "github.com/foo/stats.Count[github.com/bar/model.Event]" is shortened to
+// "stats.Count[model.Event]".
+func ShortName(name string) string {
+	return pkgPathPrefix.ReplaceAllString(name, "")
 }
 
 // LoadFunction loads a function from a pointer and type. Assumes the pointer
 // points to a valid function implementation.
 func LoadFunction(ptr uintptr, t reflect.Type) interface{} {
+	// A function value points to the code pointer, which must be kept on the
+	// heap.
+	code := new(uintptr)
+	*code = ptr
 	v := reflect.New(t).Elem()
-	*(*uintptr)(unsafe.Pointer(v.Addr().Pointer())) = (uintptr)(unsafe.Pointer(&ptr))
+	*(*unsafe.Pointer)(unsafe.Pointer(v.Addr().Pointer())) = unsafe.Pointer(code)
 	return v.Interface()
 }
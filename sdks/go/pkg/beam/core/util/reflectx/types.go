@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 // Well-known reflected types. Convenience definitions.
@@ -43,6 +44,7 @@ var (
 	Context   = reflect.TypeOf((*context.Context)(nil)).Elem()
 	Type      = reflect.TypeOf((*reflect.Type)(nil)).Elem()
 	ByteSlice = reflect.TypeOf((*[]byte)(nil)).Elem()
+	Time      = reflect.TypeOf((*time.Time)(nil)).Elem()
 )
 
 // IsNumber returns true iff the given type is an integer, float, or complex.
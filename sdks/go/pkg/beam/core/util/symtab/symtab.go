@@ -93,9 +93,10 @@ func (s *SymbolTable) Addr2Sym(addr uintptr) (string, error) {
 		}
 
 		if e.Tag == dwarf.TagSubprogram {
-			nf := e.Field[1]
-			if nf.Val.(uint64) == uint64(addr) {
-				return e.Field[0].Val.(string), nil
+			if pc, ok := e.Val(dwarf.AttrLowpc).(uint64); ok && pc == uint64(addr) {
+				if name, ok := e.Val(dwarf.AttrName).(string); ok {
+					return name, nil
+				}
 			}
 		}
 	}
@@ -116,10 +117,10 @@ func (s *SymbolTable) Sym2Addr(symbol string) (uintptr, error) {
 		}
 
 		if e.Tag == dwarf.TagSubprogram {
-			nf := e.Field[0]
-			if nf.Attr.String() == "Name" && nf.Val.(string) == symbol {
-				addr := e.Field[1].Val.(uint64)
-				return uintptr(addr), nil
+			if name, ok := e.Val(dwarf.AttrName).(string); ok && name == symbol {
+				if pc, ok := e.Val(dwarf.AttrLowpc).(uint64); ok {
+					return uintptr(pc), nil
+				}
 			}
 		}
 	}
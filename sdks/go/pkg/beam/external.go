@@ -47,6 +47,7 @@ func TryExternal(s Scope, spec string, payload []byte, in []PCollection, out []F
 		ins = append(ins, col.n)
 	}
 	edge := graph.NewExternal(s.real, s.scope, &graph.Payload{URN: spec, Data: payload}, ins, out)
+	edge.Label = s.name
 
 	var ret []PCollection
 	for _, out := range edge.Output {
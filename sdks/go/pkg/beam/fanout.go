@@ -0,0 +1,252 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func init() {
+	RegisterType(reflect.TypeOf((*shardKeyFn)(nil)).Elem())
+	RegisterType(reflect.TypeOf((*unshardKeyFn)(nil)).Elem())
+	RegisterType(reflect.TypeOf((*fanoutCombineFn)(nil)).Elem())
+}
+
+// CombineOption is an option for CombinePerKey.
+type CombineOption interface {
+	combineOption()
+}
+
+type hotKeyFanout struct {
+	n  int
+	fn interface{}
+}
+
+func (hotKeyFanout) combineOption() {}
+
+// WithHotKeyFanout spreads the values of each key over multiple shards that
+// are combined in parallel, before the partial results are merged per key.
+// It reduces the load of hot keys at the cost of an extra grouping. The
+// fanout is either a fixed number of shards, an int, or a function of the
+// key, K -> int, such that only known hot keys are sharded. For example:
+//
+//    counts := beam.CombinePerKey(s, sumFn, col, beam.WithHotKeyFanout(16))
+//
+// A fanout less than 2 disables sharding for the key. Keyed CombineFns are
+// not supported, because the key is not available while combining shards.
+func WithHotKeyFanout(fanout interface{}) CombineOption {
+	switch f := fanout.(type) {
+	case int:
+		return hotKeyFanout{n: f}
+	default:
+		t := reflect.TypeOf(fanout)
+		if t == nil || t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 1 || t.Out(0) != reflectx.Int {
+			panic(fmt.Sprintf("hot key fanout %v must be an int or a function K -> int", fanout))
+		}
+		return hotKeyFanout{fn: fanout}
+	}
+}
+
+func parseCombineOpts(opts []CombineOption) *hotKeyFanout {
+	var ret *hotKeyFanout
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case hotKeyFanout:
+			ret = &o
+		default:
+			panic(fmt.Sprintf("Unexpected combine option: %v", opt))
+		}
+	}
+	return ret
+}
+
+// tryCombinePerKeyWithFanout expands a per-key combine with hot key fanout
+// into a partial combine of the sharded keys, which outputs accumulators,
+// and a final combine that merges the accumulators of each key. Sharded
+// keys are the encoded key followed by the shard number.
+func tryCombinePerKeyWithFanout(s Scope, combinefn interface{}, col PCollection, fanout *hotKeyFanout) (PCollection, error) {
+	s = s.Scope("CombinePerKey.HotKeyFanout")
+
+	fn, err := graph.NewCombineFn(combinefn)
+	if err != nil {
+		return PCollection{}, fmt.Errorf("invalid CombineFn: %v", err)
+	}
+	if f := fn.AddInputFn(); f != nil && len(f.Params(funcx.FnValue)) > 2 {
+		return PCollection{}, fmt.Errorf("hot key fanout does not support keyed CombineFn %v", fn.Name())
+	}
+	if f := fn.CreateAccumulatorFn(); f != nil && len(f.Params(funcx.FnValue)) > 0 {
+		return PCollection{}, fmt.Errorf("hot key fanout does not support keyed CombineFn %v", fn.Name())
+	}
+
+	c := col.Coder()
+	if !c.IsValid() || !coder.IsKV(c.coder) {
+		return PCollection{}, fmt.Errorf("hot key fanout requires a KV coder: %v", c)
+	}
+	kc := c.coder.Components[0]
+	if err := validateKeyCoder(kc); err != nil {
+		return PCollection{}, fmt.Errorf("invalid key coder for hot key fanout: %v", err)
+	}
+
+	shard := &shardKeyFn{KeyCoder: EncodedCoder{Coder{kc}}, Fanout: fanout.n}
+	if fanout.fn != nil {
+		funcx.MustSatisfy(fanout.fn, &funcx.Signature{Args: []reflect.Type{kc.T.Type()}, Return: []reflect.Type{reflectx.Int}})
+		shard.FanoutFn = &EncodedFunc{Fn: reflectx.MakeFunc(fanout.fn)}
+	}
+	// A binary merge function is its own partial and final phase.
+	partialFn, finalFn := combinefn, combinefn
+	if reflect.TypeOf(combinefn).Kind() != reflect.Func {
+		data, err := json.Marshal(combinefn)
+		if err != nil {
+			return PCollection{}, fmt.Errorf("failed to encode CombineFn: %v", err)
+		}
+		t := &EncodedType{T: reflect.TypeOf(combinefn)}
+		partialFn = &fanoutCombineFn{Type: t, Data: string(data)}
+		finalFn = &fanoutCombineFn{Type: t, Data: string(data), Final: true}
+	}
+
+	sharded := ParDo(s, shard, col)
+	partial, err := TryCombinePerKey(s, partialFn, sharded)
+	if err != nil {
+		return PCollection{}, err
+	}
+	unsharded := ParDo(s, &unshardKeyFn{KeyCoder: EncodedCoder{Coder{kc}}}, partial, TypeDefinition{Var: XType, T: kc.T.Type()})
+	return TryCombinePerKey(s, finalFn, unsharded)
+}
+
+// shardKeyFn replaces each key with a sharded key.
+type shardKeyFn struct {
+	KeyCoder EncodedCoder `json:"key_coder"`
+	Fanout   int          `json:"fanout,omitempty"`
+	FanoutFn *EncodedFunc `json:"fanout_fn,omitempty"`
+
+	enc exec.ElementEncoder
+	fn  reflectx.Func1x1
+}
+
+func (f *shardKeyFn) Setup() {
+	f.enc = exec.MakeElementEncoder(f.KeyCoder.Coder.coder)
+	if f.FanoutFn != nil {
+		f.fn = reflectx.ToFunc1x1(f.FanoutFn.Fn)
+	}
+}
+
+func (f *shardKeyFn) ProcessElement(key X, value Y) ([]byte, Y, error) {
+	n := f.Fanout
+	if f.fn != nil {
+		n = f.fn.Call1x1(key).(int)
+	}
+	var buf bytes.Buffer
+	if err := f.enc.Encode(exec.FullValue{Elm: key}, &buf); err != nil {
+		return nil, nil, err
+	}
+	var shard uint32
+	if n > 1 {
+		shard = uint32(rand.Intn(n))
+	}
+	binary.Write(&buf, binary.BigEndian, shard)
+	return buf.Bytes(), value, nil
+}
+
+// unshardKeyFn restores the original key of each sharded key.
+type unshardKeyFn struct {
+	KeyCoder EncodedCoder `json:"key_coder"`
+
+	dec exec.ElementDecoder
+}
+
+func (f *unshardKeyFn) Setup() {
+	f.dec = exec.MakeElementDecoder(f.KeyCoder.Coder.coder)
+}
+
+func (f *unshardKeyFn) ProcessElement(key []byte, value Y) (X, Y, error) {
+	if len(key) < 4 {
+		return nil, nil, fmt.Errorf("invalid sharded key: %v", key)
+	}
+	v, err := f.dec.Decode(bytes.NewReader(key[:len(key)-4]))
+	if err != nil {
+		return nil, nil, err
+	}
+	return v.Elm, value, nil
+}
+
+// fanoutCombineFn is one phase of a struct CombineFn with hot key fanout.
+// The partial phase adds inputs to accumulators, but does not extract
+// outputs. The final phase merges accumulators and extracts the outputs.
+type fanoutCombineFn struct {
+	// Type and Data hold the CombineFn.
+	Type *EncodedType `json:"type"`
+	Data string       `json:"data"`
+	// Final is true for the final phase.
+	Final bool `json:"final"`
+}
+
+// Funcs returns the methods of the CombineFn for the phase.
+func (f *fanoutCombineFn) Funcs() map[string]reflectx.Func {
+	recv, err := reflectx.UnmarshalJSON(f.Type.T, f.Data)
+	if err != nil {
+		panic(fmt.Sprintf("failed to decode CombineFn %v: %v", f.Type.T, err))
+	}
+	fn, err := graph.NewCombineFn(recv)
+	if err != nil {
+		panic(fmt.Sprintf("invalid CombineFn: %v", err))
+	}
+
+	ret := make(map[string]reflectx.Func)
+	add := func(name string, f *funcx.Fn) {
+		if f != nil {
+			ret[name] = f.Fn
+		}
+	}
+	add("Setup", fn.SetupFn())
+	add("Teardown", fn.TeardownFn())
+	add("CreateAccumulator", fn.CreateAccumulatorFn())
+	add("MergeAccumulators", fn.MergeAccumulatorsFn())
+	add("Compact", fn.CompactFn())
+	if !f.Final {
+		add("AddInput", fn.AddInputFn())
+		return ret
+	}
+	add("ExtractOutput", fn.ExtractOutputFn())
+	if merge := fn.MergeAccumulatorsFn(); len(merge.Params(funcx.FnValue)) == 1 {
+		// The final phase adds accumulators, which requires a binary merge.
+		ret["AddInput"] = binaryMerge(merge, fn.AccumulatorType())
+	}
+	return ret
+}
+
+// binaryMerge returns a binary merge function, (A, A) -> A, for a
+// MergeAccumulators function of the form []A -> A.
+func binaryMerge(merge *funcx.Fn, a reflect.Type) reflectx.Func {
+	t := reflect.FuncOf([]reflect.Type{a, a}, []reflect.Type{a}, false)
+	return reflectx.MakeFunc(reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		list := reflect.MakeSlice(reflect.SliceOf(a), 2, 2)
+		list.Index(0).Set(args[0])
+		list.Index(1).Set(args[1])
+		ret := merge.Fn.Call([]interface{}{list.Interface()})
+		return []reflect.Value{reflect.ValueOf(ret[0])}
+	}).Interface())
+}
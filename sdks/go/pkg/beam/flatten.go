@@ -66,6 +66,7 @@ func TryFlatten(s Scope, cols ...PCollection) (PCollection, error) {
 	if err != nil {
 		return PCollection{}, err
 	}
+	edge.Label = s.name
 	ret := PCollection{edge.Output[0].To}
 	ret.SetCoder(cols[0].Coder())
 	return ret, nil
@@ -16,9 +16,14 @@
 package beam
 
 import (
+	"context"
 	"reflect"
+	"time"
 
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 )
 
@@ -50,6 +55,82 @@ func RegisterFunction(fn interface{}) {
 	runtime.RegisterFunction(fn)
 }
 
+// RegisterOutputCapacity registers a static hint of the expected number of
+// outputs per input element for the given DoFn function or struct, which is
+// used to pre-size output buffers. Struct DoFns may instead implement an
+// "OutputCapacity() int" method. It should be called in init() only.
+func RegisterOutputCapacity(fn interface{}, n int) {
+	graph.RegisterOutputCapacity(fn, n)
+}
+
+// SkewPolicy defines how output timestamps that are earlier than the input
+// timestamp by more than the allowed skew are handled.
+type SkewPolicy = graph.SkewPolicy
+
+// Timestamp skew policies.
+const (
+	SkewError = graph.SkewError
+	SkewClamp = graph.SkewClamp
+)
+
+// RegisterTimestampSkew registers how far the output timestamps of the given
+// DoFn may be earlier than the timestamp of the input element, and whether
+// violations fail the bundle or are clamped to the earliest allowed time. By
+// default, output timestamps are not checked. Struct DoFns may instead
+// implement an "AllowedTimestampSkew() time.Duration" method, which checks
+// outputs with SkewError unless another policy is registered. It should be
+// called in init() only.
+func RegisterTimestampSkew(fn interface{}, allowed time.Duration, policy SkewPolicy) {
+	graph.RegisterTimestampSkew(fn, allowed, policy)
+}
+
+// DisplayItem is a key/value pair of static information about a transform,
+// such as a file pattern or threshold, shown in runner UIs. DoFns and
+// CombineFns provide display data by implementing a
+// "DisplayData() []beam.DisplayItem" method.
+type DisplayItem = graph.DisplayItem
+
+// WindowingStrategy defines how the elements of a PCollection are assigned
+// to windows. Use PCollection.WindowingStrategy to inspect it.
+type WindowingStrategy = window.Window
+
+// WindowKind is the semantic type of a windowing strategy.
+type WindowKind = window.Kind
+
+// Windowing kinds. Windowing strategies of each kind are created by the
+// constructors of the window package, such as window.NewFixedWindows, and
+// applied with WindowInto.
+const (
+	// GlobalWindow is the default windowing, where all elements are in a
+	// single window.
+	GlobalWindow = window.GlobalWindow
+	// FixedWindows places elements into non-overlapping windows of a fixed
+	// size.
+	FixedWindows = window.FixedWindows
+	// SlidingWindows places elements into overlapping windows of a fixed
+	// size that start every period.
+	SlidingWindows = window.SlidingWindows
+	// Sessions places elements into windows that are merged per key when
+	// elements are no further apart than the gap.
+	Sessions = window.Sessions
+	// Custom places elements into windows assigned, and optionally merged,
+	// by user functions. See window.NewCustom.
+	Custom = window.Custom
+)
+
+// DecodeError is the error of an element that cannot be decoded on a worker.
+// It holds the coder, the consuming transform and the first bytes of the
+// element.
+type DecodeError = exec.DecodeError
+
+// RegisterDecodeErrorHandler registers a handler for elements whose custom
+// coder fails to decode them. If the handler returns nil, the element is
+// skipped instead of failing the bundle, such as after writing it to a
+// dead-letter location. It should be called in init() only.
+func RegisterDecodeErrorHandler(h func(ctx context.Context, err *DecodeError) error) {
+	exec.RegisterDecodeErrorHandler(h)
+}
+
 // RegisterInit registers an Init hook. Hooks are expected to be able to
 // figure out whether they apply on their own, notably if invoked in a remote
 // execution environment. They are all executed regardless of the runner.
@@ -17,8 +17,11 @@ package beam
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/coderx"
 )
 
 // GroupByKey is a PTransform that takes a PCollection of type KV<A,B>,
@@ -35,7 +38,11 @@ import (
 // Two keys of type A are compared for equality by first encoding each of the
 // keys using the Coder of the keys of the input PCollection, and then
 // comparing the encoded bytes. This admits efficient parallel evaluation.
-// Note that this requires that the Coder of the keys be deterministic.
+// Note that this requires that the Coder of the keys be deterministic, which
+// is verified at construction time: keys with a JSON Coder that would drop
+// unexported struct fields or with a proto Coder for messages with map fields,
+// for example, are rejected. Use RegisterDeterministicType to override the
+// check for a key type known to encode deterministically.
 //
 // By default, input and output PCollections share a key Coder and iterable
 // values in the input and output PCollection share an element Coder.
@@ -67,7 +74,20 @@ func TryGroupByKey(s Scope, a PCollection) (PCollection, error) {
 	return TryCoGroupByKey(s, a)
 }
 
-// CoGroupByKey inserts a CoGBK transform into the pipeline.
+// CoGroupByKey inserts a CoGBK transform into the pipeline. It takes N keyed
+// PCollections, PCollection<KV<K,A>>, .., PCollection<KV<K,Z>>, with the same
+// key Coder and windowing, and returns a PCollection<CoGBK<K,A,..,Z>> that
+// holds, for each key, one iterable of values per input in input order. The
+// value types and Coders of the inputs may differ. It is the basis of joins:
+//
+//    emails := ...   // PCollection<KV<string,string>>
+//    phones := ...   // PCollection<KV<string,int>>
+//    joined := beam.CoGroupByKey(s, emails, phones)
+//    results := beam.ParDo(s, func(name string, emailIter func(*string) bool, phoneIter func(*int) bool) {
+//          // ... process all emails and phone numbers of the name ...
+//    }, joined)
+//
+// As for GroupByKey, the key Coder must be deterministic.
 func CoGroupByKey(s Scope, cols ...PCollection) PCollection {
 	return Must(TryCoGroupByKey(s, cols...))
 }
@@ -91,14 +111,83 @@ func TryCoGroupByKey(s Scope, cols ...PCollection) (PCollection, error) {
 	for _, s := range cols {
 		in = append(in, s.n)
 	}
-
-	fmt.Println(in)
+	for i, n := range in {
+		if n.Coder == nil || !coder.IsKV(n.Coder) {
+			continue // rejected by graph.NewCoGBK
+		}
+		if err := validateKeyCoder(n.Coder.Components[0]); err != nil {
+			return PCollection{}, fmt.Errorf("invalid key coder to CoGBK: index %v: %v", i, err)
+		}
+	}
 
 	edge, err := graph.NewCoGBK(s.real, s.scope, in)
 	if err != nil {
 		return PCollection{}, err
 	}
+	edge.Label = s.name
 	ret := PCollection{edge.Output[0].To}
 	ret.SetCoder(NewCoder(ret.Type()))
 	return ret, nil
 }
+
+var deterministicTypes = make(map[reflect.Type]bool)
+
+// RegisterDeterministicType asserts that the Coder of the given type is
+// deterministic, even though it is not known to be so. It disables the
+// construction-time check of GroupByKey keys of that type and should be used
+// only if equal values are guaranteed to be encoded to identical bytes. It
+// should be called in init() only.
+func RegisterDeterministicType(t reflect.Type) {
+	deterministicTypes[t] = true
+}
+
+// validateKeyCoder returns an error if the given key coder is known not to be
+// deterministic. Custom coders other than the inferred ones are assumed to be
+// deterministic.
+func validateKeyCoder(c *coder.Coder) error {
+	if deterministicTypes[c.T.Type()] {
+		return nil
+	}
+
+	switch c.Kind {
+	case coder.KV:
+		for _, comp := range c.Components {
+			if err := validateKeyCoder(comp); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case coder.Custom:
+		ok := true
+		switch c.Custom.Name {
+		case "json", "jsonx", "map", "nullable":
+			ok = coderx.IsDeterministic(c.Custom.Type)
+		case "proto":
+			// Proto map fields are encoded in random order.
+			ok = !hasMapField(c.Custom.Type)
+		}
+		if !ok {
+			return fmt.Errorf("coder %v of key type %v is not deterministic; use a key type with a deterministic coder or call RegisterDeterministicType", c, c.T)
+		}
+		return nil
+
+	default:
+		return nil
+	}
+}
+
+func hasMapField(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() == reflect.Map {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+type plainKey struct {
+	Name string
+	Tags map[string]int
+}
+
+type lossyKey struct {
+	Name string
+	id   int
+}
+
+type overriddenKey struct {
+	Name string `json:"-"`
+}
+
+func init() {
+	beam.RegisterDeterministicType(reflect.TypeOf(overriddenKey{}))
+}
+
+func TestGroupByKeyDeterministicKeys(t *testing.T) {
+	tests := []struct {
+		fn interface{}
+		ok bool
+	}{
+		{func(s string) (string, int) { return s, 1 }, true},
+		{func(s string) (plainKey, int) { return plainKey{Name: s}, 1 }, true},
+		{func(s string) (map[string]bool, int) { return nil, 1 }, true},
+		{func(s string) (*big.Int, int) { return big.NewInt(1), 1 }, true},
+		{func(s string) (lossyKey, int) { return lossyKey{Name: s}, 1 }, false},
+		{func(s string) (*lossyKey, int) { return nil, 1 }, false},
+		{func(s string) (map[lossyKey]int, int) { return nil, 1 }, false},
+		{func(s string) (*pb.Components, int) { return nil, 1 }, false},
+		{func(s string) (*pb.FunctionSpec, int) { return nil, 1 }, true},
+		{func(s string) (overriddenKey, int) { return overriddenKey{}, 1 }, true},
+	}
+
+	for _, test := range tests {
+		p := beam.NewPipeline()
+		s := p.Root()
+
+		col := beam.ParDo(s, test.fn, beam.Create(s, "a"))
+		_, err := beam.TryGroupByKey(s, col)
+		if test.ok && err != nil {
+			t.Errorf("GroupByKey(%v) failed: %v", col.Type(), err)
+		}
+		if !test.ok && (err == nil || !strings.Contains(err.Error(), "not deterministic")) {
+			t.Errorf("GroupByKey(%v) = %v, want non-deterministic key error", col.Type(), err)
+		}
+	}
+}
+
+func splitEmail(s string) (string, string) {
+	i := strings.Index(s, "@")
+	return s[:i], s[i+1:]
+}
+
+func splitPhone(s string) (string, int) {
+	i := strings.Index(s, ":")
+	var n int
+	fmt.Sscan(s[i+1:], &n)
+	return s[:i], n
+}
+
+func formatJoin(name string, emailIter func(*string) bool, phoneIter func(*int) bool) string {
+	var emails []string
+	var email string
+	for emailIter(&email) {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	var phones []int
+	var phone int
+	for phoneIter(&phone) {
+		phones = append(phones, phone)
+	}
+	sort.Ints(phones)
+	return fmt.Sprintf("%v:%v:%v", name, emails, phones)
+}
+
+func TestCoGroupByKey(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	emails := beam.ParDo(s, splitEmail, beam.Create(s, "amy@a.com", "carl@c.com", "amy@b.com"))
+	phones := beam.ParDo(s, splitPhone, beam.Create(s, "amy:111", "bob:222", "amy:333"))
+	joined := beam.CoGroupByKey(s, emails, phones)
+	passert.Equals(s, beam.ParDo(s, formatJoin, joined),
+		"amy:[a.com b.com]:[111 333]", "bob:[]:[222]", "carl:[c.com]:[]")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("CoGroupByKey failed: %v", err)
+	}
+}
+
+func TestCoGroupByKeyMismatchedKeys(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	emails := beam.ParDo(s, splitEmail, beam.Create(s, "amy@a.com"))
+	ids := beam.ParDo(s, func(n int) (int, string) { return n, "x" }, beam.Create(s, 1))
+	if _, err := beam.TryCoGroupByKey(s, emails, ids); err == nil {
+		t.Errorf("CoGroupByKey(%v, %v) succeeded, want key coder mismatch error", emails.Type(), ids.Type())
+	}
+}
@@ -0,0 +1,78 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(twice[int])
+	beam.RegisterFunction(twice[float64])
+	beam.RegisterType(reflect.TypeOf((*scaleFn[int])(nil)).Elem())
+}
+
+func twice[T int | float64](v T) T {
+	return v + v
+}
+
+type scaleFn[T int | float64] struct {
+	Factor T `json:"factor"`
+}
+
+func (f *scaleFn[T]) ProcessElement(v T) T {
+	return v * f.Factor
+}
+
+// TestGenericDoFns verifies that instantiated generic functions and methods
+// of instantiated generic types can be used as DoFns.
+func TestGenericDoFns(t *testing.T) {
+	p, s, col := ptest.Create([]interface{}{1, 2, 3})
+	passert.Equals(s, beam.ParDo(s, twice[int], col), 2, 4, 6)
+	passert.Equals(s, beam.ParDo(s, &scaleFn[int]{Factor: 3}, col), 3, 6, 9)
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("pipeline failed: %v", err)
+	}
+}
+
+// TestGenericFunctionSerialization verifies that distinct instantiations of a
+// generic function have distinct names and can be serialized.
+func TestGenericFunctionSerialization(t *testing.T) {
+	a, b := reflectx.FunctionName(twice[int]), reflectx.FunctionName(twice[float64])
+	if a == b {
+		t.Fatalf("FunctionName(twice[int]) = FunctionName(twice[float64]) = %v, want distinct names", a)
+	}
+
+	data, err := graphx.EncodeFn(reflectx.MakeFunc(twice[float64]))
+	if err != nil {
+		t.Fatalf("EncodeFn(%v) failed: %v", b, err)
+	}
+	fn, err := graphx.DecodeFn(data)
+	if err != nil {
+		t.Fatalf("DecodeFn(%v) failed: %v", b, err)
+	}
+	if got := fn.Call([]interface{}{1.5})[0]; got != 3.0 {
+		t.Errorf("%v(1.5) = %v, want 3", fn.Name(), got)
+	}
+}
@@ -0,0 +1,158 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/timers"
+)
+
+func init() {
+	RegisterType(reflect.TypeOf((*groupIntoBatchesFn)(nil)).Elem())
+}
+
+// BatchOption is an option for GroupIntoBatches.
+type BatchOption interface {
+	batchOption()
+}
+
+type maxBufferingDuration time.Duration
+
+func (maxBufferingDuration) batchOption() {}
+
+// WithMaxBufferingDuration limits how long values of a key are buffered
+// before an incomplete batch is output. The duration is measured in event
+// time from the timestamp of the first value in the batch, i.e., the batch
+// is output once the watermark passes that time plus the duration.
+func WithMaxBufferingDuration(d time.Duration) BatchOption {
+	return maxBufferingDuration(d)
+}
+
+// GroupIntoBatches groups the values of each key of a PCollection<KV<K,V>>
+// into batches of at most size values and returns a PCollection<KV<K,[]V>>.
+// Unlike GroupByKey, the values of a key may be output in multiple batches,
+// such as to make bulk RPC calls of bounded size downstream:
+//
+//    batches := beam.GroupIntoBatches(s, 100, keyed)
+//    beam.ParDo0(s, func(ctx context.Context, key string, values []Record) error {
+//          return client.BulkWrite(ctx, key, values)
+//    }, batches)
+//
+// Incomplete batches are output when the input ends or the maximum buffering
+// duration, if any, expires. GroupIntoBatches buffers values in per-key state
+// and is supported by runners that support stateful DoFns.
+func GroupIntoBatches(s Scope, size int, col PCollection, opts ...BatchOption) PCollection {
+	return Must(TryGroupIntoBatches(s, size, col, opts...))
+}
+
+// TryGroupIntoBatches inserts a GroupIntoBatches transform into the
+// pipeline. It returns an error on failure.
+func TryGroupIntoBatches(s Scope, size int, col PCollection, opts ...BatchOption) (PCollection, error) {
+	s = s.Scope("beam.GroupIntoBatches")
+	if size < 1 {
+		return PCollection{}, fmt.Errorf("invalid batch size %v: must be positive", size)
+	}
+	if c := col.Coder(); !c.IsValid() || !coder.IsKV(c.coder) {
+		return PCollection{}, fmt.Errorf("GroupIntoBatches requires a KV coder: %v", c)
+	}
+
+	fn := &groupIntoBatchesFn{
+		Size:   size,
+		Buffer: state.MakeBag("buffer"),
+		Count:  state.MakeValue("count"),
+		Flush:  timers.InEventTime("flush"),
+	}
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case maxBufferingDuration:
+			fn.MaxBuffering = time.Duration(o)
+		default:
+			panic(fmt.Sprintf("Unexpected batch option: %v", opt))
+		}
+	}
+
+	ret, err := TryParDo(s, fn, col)
+	if err != nil {
+		return PCollection{}, err
+	}
+	return ret[0], nil
+}
+
+// groupIntoBatchesFn buffers the values of each key and outputs them when
+// the batch is full or the flush timer fires.
+type groupIntoBatchesFn struct {
+	Size         int           `json:"size"`
+	MaxBuffering time.Duration `json:"max_buffering,omitempty"`
+
+	Buffer state.Bag        `json:"buffer"`
+	Count  state.Value      `json:"count"`
+	Flush  timers.EventTime `json:"flush"`
+}
+
+func (f *groupIntoBatchesFn) ProcessElement(ctx context.Context, t EventTime, key X, value Y, emit func(X, []Y)) error {
+	if err := f.Buffer.Add(ctx, value); err != nil {
+		return err
+	}
+	var n int
+	if _, err := f.Count.Read(ctx, &n); err != nil {
+		return err
+	}
+	n++
+	if n >= f.Size {
+		return f.flush(ctx, key, emit)
+	}
+	if n == 1 {
+		// Incomplete batches are output at the end of the global window,
+		// unless the maximum buffering duration expires first.
+		at := exec.EndOfTime
+		if f.MaxBuffering > 0 {
+			at = EventTime(time.Time(t).Add(f.MaxBuffering))
+		}
+		if err := f.Flush.Set(ctx, at); err != nil {
+			return err
+		}
+	}
+	return f.Count.Write(ctx, n)
+}
+
+func (f *groupIntoBatchesFn) OnTimer(ctx context.Context, key X, _ string, emit func(X, []Y)) error {
+	return f.flush(ctx, key, emit)
+}
+
+func (f *groupIntoBatchesFn) flush(ctx context.Context, key X, emit func(X, []Y)) error {
+	var values []Y
+	ok, err := f.Buffer.Read(ctx, &values)
+	if err != nil {
+		return err
+	}
+	if ok && len(values) > 0 {
+		emit(key, values)
+	}
+	if err := f.Buffer.Clear(ctx); err != nil {
+		return err
+	}
+	if err := f.Count.Clear(ctx); err != nil {
+		return err
+	}
+	return f.Flush.Clear(ctx)
+}
@@ -0,0 +1,65 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func keyByMod3(t beam.EventTime, n int) (beam.EventTime, string, int) {
+	return beam.EventTime(time.Unix(int64(n), 0)), fmt.Sprintf("k%v", n%3), n
+}
+
+func formatSortedBatch(key string, values []int) string {
+	sort.Ints(values)
+	return fmt.Sprintf("%v:%v", key, values)
+}
+
+func TestGroupIntoBatches(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	keyed := beam.ParDo(s, keyByMod3, beam.Create(s, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13))
+	batches := beam.GroupIntoBatches(s, 2, keyed)
+	passert.Equals(s, beam.ParDo(s, formatSortedBatch, batches),
+		"k1:[1 4]", "k1:[7 10]", "k1:[13]", "k2:[2 5]", "k2:[8 11]", "k0:[3 6]", "k0:[9 12]")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("GroupIntoBatches failed: %v", err)
+	}
+}
+
+func TestGroupIntoBatchesInvalid(t *testing.T) {
+	s := beam.NewPipeline().Root()
+	if _, err := beam.TryGroupIntoBatches(s, 2, beam.Create(s, 1, 2)); err == nil {
+		t.Errorf("GroupIntoBatches on non-KV input succeeded, want error")
+	}
+
+	keyed := beam.ParDo(s, keyByMod3, beam.Create(s, 1, 2))
+	if _, err := beam.TryGroupIntoBatches(s, 0, keyed); err == nil {
+		t.Errorf("GroupIntoBatches with size 0 succeeded, want error")
+	}
+	if _, err := beam.TryGroupIntoBatches(s, 10, keyed, beam.WithMaxBufferingDuration(time.Minute)); err != nil {
+		t.Errorf("GroupIntoBatches with max buffering duration failed: %v", err)
+	}
+}
@@ -28,6 +28,8 @@ import (
 	"cloud.google.com/go/bigquery"
 	"github.com/apache/beam/sdks/go/pkg/beam"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/cleanup"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/connectors"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 )
@@ -38,6 +40,40 @@ const writeRowLimit = 10000
 func init() {
 	beam.RegisterType(reflect.TypeOf((*queryFn)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*writeFn)(nil)).Elem())
+	cleanup.RegisterDeleter(CleanupKind, deleteTable)
+
+	connectors.Register(connectors.Connector{
+		Name:     "bigqueryio",
+		URN:      "beam:go:io:bigquery:v1",
+		Package:  "github.com/apache/beam/sdks/go/pkg/beam/io/bigqueryio",
+		Features: []connectors.Feature{connectors.Read, connectors.Query, connectors.Write},
+		Doc:      "Reads, queries and writes BigQuery tables.",
+	})
+}
+
+// CleanupKind is the cleanup resource kind for temporary tables. Tables
+// tracked under this kind are named by their qualified table name, such as
+// by a DoFn that stages rows in a table of its own:
+//
+//    cleanup.Track(ctx, bigqueryio.CleanupKind, "project:dataset.staging", "stage")
+const CleanupKind = "bigquery"
+
+// deleteTable deletes a temporary table tracked for cleanup.
+func deleteTable(ctx context.Context, name string) error {
+	qn, err := NewQualifiedTableName(name)
+	if err != nil {
+		return err
+	}
+	client, err := bigquery.NewClient(ctx, qn.Project)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.DatasetInProject(qn.Project, qn.Dataset).Table(qn.Table).Delete(ctx); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
 }
 
 // QualifiedTableName is a fully qualified name of a bigquery table.
@@ -0,0 +1,164 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package databaseio provides transformations for writing to SQL databases
+// through database/sql. The database driver must be registered in the
+// pipeline binary, such as by importing it for its side effect, so that it is
+// available on the workers as well.
+package databaseio
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/connectors"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*writeTxFn)(nil)).Elem())
+
+	connectors.Register(connectors.Connector{
+		Name:     "databaseio",
+		URN:      "beam:go:io:database:v1",
+		Package:  "github.com/apache/beam/sdks/go/pkg/beam/io/databaseio",
+		Features: []connectors.Feature{connectors.Write, connectors.Transactional},
+		Doc:      "Writes to SQL databases in transactions.",
+	})
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	txType      = reflect.TypeOf((*sql.Tx)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+
+	sig = &funcx.Signature{
+		Args:   []reflect.Type{contextType, txType, beam.TType},
+		Return: []reflect.Type{errorType},
+	} // (context.Context, *sql.Tx, T) -> error
+)
+
+// WriteTx writes the elements of a PCollection<A> to a database with one
+// transaction per bundle. The given function, which must be of the form
+// (context.Context, *sql.Tx, A) -> error, performs the writes of a single
+// element within the transaction. For example:
+//
+//    beam.RegisterFunction(insertUser)
+//
+//    func insertUser(ctx context.Context, tx *sql.Tx, u User) error {
+//        _, err := tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES (?, ?)", u.ID, u.Name)
+//        return err
+//    }
+//
+//    databaseio.WriteTx(s, "mysql", dsn, insertUser, users)
+//
+// The transaction is begun when the bundle starts and committed when it
+// finishes, so the writes of a bundle are either all visible or none are. If
+// the bundle fails, including when the function returns an error or the
+// commit fails, its transaction is rolled back and the runner may retry the
+// bundle. The function may thus be called more than once per element.
+//
+// A failed commit is not retried, because its outcome is unknown: the
+// transaction may have been committed even though the commit reported an
+// error, such as when the connection is lost. A retried bundle may then write
+// its elements twice, so the writes should be idempotent, such as upserts by
+// primary key, if duplicates are not acceptable.
+func WriteTx(s beam.Scope, driver, dsn string, fn interface{}, col beam.PCollection) {
+	s = s.Scope("databaseio.WriteTx")
+
+	funcx.MustSatisfy(fn, funcx.Replace(sig, beam.TType, col.Type().Type()))
+
+	beam.ParDo0(s, &writeTxFn{
+		Driver: driver,
+		DSN:    dsn,
+		Write:  beam.EncodedFunc{Fn: reflectx.MakeFunc(fn)},
+	}, col)
+}
+
+type writeTxFn struct {
+	// Driver is the name of the database/sql driver.
+	Driver string `json:"driver"`
+	// DSN is the driver-specific data source name.
+	DSN string `json:"dsn"`
+	// Write is the encoded function that writes a single element.
+	Write beam.EncodedFunc `json:"write"`
+
+	db *sql.DB
+	tx *sql.Tx
+	fn reflectx.Func3x1
+}
+
+func (f *writeTxFn) Setup() error {
+	db, err := sql.Open(f.Driver, f.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	f.db = db
+	f.fn = reflectx.ToFunc3x1(f.Write.Fn)
+	return nil
+}
+
+func (f *writeTxFn) StartBundle(ctx context.Context) error {
+	// Roll back the transaction of a previously failed bundle, if any.
+	f.rollback(ctx)
+	return f.begin(ctx)
+}
+
+func (f *writeTxFn) ProcessElement(ctx context.Context, elm beam.T) error {
+	if ret := f.fn.Call3x1(ctx, f.tx, elm); ret != nil {
+		return ret.(error)
+	}
+	return nil
+}
+
+func (f *writeTxFn) FinishBundle(ctx context.Context) error {
+	err := f.tx.Commit()
+	f.tx = nil
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func (f *writeTxFn) Teardown() error {
+	f.rollback(context.Background())
+	return f.db.Close()
+}
+
+func (f *writeTxFn) begin(ctx context.Context) error {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	f.tx = tx
+	return nil
+}
+
+// rollback aborts the open transaction, if any. Errors are only logged,
+// because the transaction is discarded either way.
+func (f *writeTxFn) rollback(ctx context.Context) {
+	if f.tx == nil {
+		return
+	}
+	if err := f.tx.Rollback(); err != nil {
+		log.Warnf(ctx, "Failed to roll back transaction: %v", err)
+	}
+	f.tx = nil
+}
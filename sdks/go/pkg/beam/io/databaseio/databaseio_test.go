@@ -0,0 +1,146 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+	beam.RegisterFunction(insertFn)
+}
+
+// fakeDB is an in-memory database, which records the committed values
+// written by any connection. The first failCommits commits fail.
+var fakeDB struct {
+	mu          sync.Mutex
+	committed   []string
+	failCommits int
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConn struct {
+	pending []string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c: c}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.pending = nil
+	return &fakeTx{c: c}, nil
+}
+
+type fakeTx struct {
+	c *fakeConn
+}
+
+func (t *fakeTx) Commit() error {
+	fakeDB.mu.Lock()
+	defer fakeDB.mu.Unlock()
+
+	if fakeDB.failCommits > 0 {
+		fakeDB.failCommits--
+		return errors.New("commit failed")
+	}
+	fakeDB.committed = append(fakeDB.committed, t.c.pending...)
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.c.pending = nil
+	return nil
+}
+
+type fakeStmt struct {
+	c *fakeConn
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return 1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.pending = append(s.c.pending, args[0].(string))
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func insertFn(ctx context.Context, tx *sql.Tx, v string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO t (v) VALUES (?)", v)
+	return err
+}
+
+func TestWriteTx(t *testing.T) {
+	tests := []struct {
+		failCommits int
+		ok          bool
+	}{
+		{0, true},
+		{1, false},
+	}
+
+	for _, test := range tests {
+		fakeDB.committed = nil
+		fakeDB.failCommits = test.failCommits
+
+		p, s, col := ptest.Create([]interface{}{"a", "b", "c"})
+		WriteTx(s, "fakedb", "", insertFn, col)
+
+		err := ptest.Run(p)
+		if (err == nil) != test.ok {
+			t.Errorf("WriteTx with %v failed commits failed: %v, want ok=%v", test.failCommits, err, test.ok)
+			continue
+		}
+
+		var want []string
+		if test.ok {
+			want = []string{"a", "b", "c"}
+		}
+		sort.Strings(fakeDB.committed)
+		if !reflect.DeepEqual(fakeDB.committed, want) {
+			t.Errorf("WriteTx with %v failed commits committed %v, want %v", test.failCommits, fakeDB.committed, want)
+		}
+	}
+}
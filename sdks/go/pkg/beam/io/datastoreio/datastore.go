@@ -31,6 +31,7 @@ import (
 	"github.com/apache/beam/sdks/go/pkg/beam"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/connectors"
 	"google.golang.org/api/iterator"
 )
 
@@ -41,6 +42,14 @@ const (
 func init() {
 	beam.RegisterType(reflect.TypeOf((*queryFn)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*splitQueryFn)(nil)).Elem())
+
+	connectors.Register(connectors.Connector{
+		Name:     "datastoreio",
+		URN:      "beam:go:io:datastore:v1",
+		Package:  "github.com/apache/beam/sdks/go/pkg/beam/io/datastoreio",
+		Features: []connectors.Feature{connectors.Read},
+		Doc:      "Reads all entities of a Datastore kind.",
+	})
 }
 
 // Read reads all rows from the given kind. The kind must have a schema  compatible with the given type, t, and Read
@@ -0,0 +1,278 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsubio
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	pubsub "cloud.google.com/go/pubsub/apiv1"
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/util/pubsubx"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/api/option"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*publishOrderedFn)(nil)).Elem())
+	beam.RegisterFunction(marshalKeyedMessageFn)
+}
+
+const (
+	// maxPublishBatch is the maximum number of messages in a publish request.
+	maxPublishBatch = 1000
+	// defaultMaxBuffered is the default maximum number of messages per key
+	// held in memory.
+	defaultMaxBuffered = 10000
+)
+
+// WriteOrderedOptions represents options for writing to PubSub with ordering
+// keys.
+type WriteOrderedOptions struct {
+	// MaxBatchSize is the maximum number of messages published in a single
+	// request. Defaults to 1000, the PubSub limit.
+	MaxBatchSize int
+	// MaxBufferedMessages is the maximum number of messages per key held in
+	// memory. Keys with more messages are published in several passes over
+	// their values, each of which publishes the next earliest messages.
+	// Defaults to 10000.
+	MaxBufferedMessages int
+	// Endpoint overrides the PubSub endpoint, such as "localhost:8085" for
+	// the PubSub emulator. Connections to it are insecure and
+	// unauthenticated.
+	Endpoint string
+}
+
+// WriteOrdered writes a PCollection<KV<string,[]byte>> or
+// PCollection<KV<string,*PubSubMessage>> to the given pubsub topic, where
+// the key is used as the ordering key of the message. Messages with the same
+// key are published in event time order with a single batch in flight per
+// key, so consumers that rely on per-key ordering, such as state machines,
+// observe the same order. The order of messages with the same timestamp is
+// the order in which the key group is iterated and hence not defined. At
+// most MaxBufferedMessages messages per key are held in memory at a time.
+//
+// Unlike Write, it publishes directly from the workers and works on any
+// runner. The topic must have message ordering enabled for subscribers to
+// observe the order. Messages may be published more than once, if a bundle
+// is retried.
+func WriteOrdered(s beam.Scope, project, topic string, col beam.PCollection, opts *WriteOrderedOptions) {
+	s = s.Scope("pubsubio.WriteOrdered")
+
+	if !typex.IsKV(col.Type()) || col.Type().Components()[0].Type() != reflectx.String {
+		panic(fmt.Sprintf("pubsubio.WriteOrdered requires a KV<string,[]byte> or KV<string,*PubSubMessage> input, got %v", col.Type()))
+	}
+
+	fn := &publishOrderedFn{
+		Topic:        pubsubx.MakeQualifiedTopicName(project, topic),
+		MaxBatchSize: maxPublishBatch,
+		MaxBuffered:  defaultMaxBuffered,
+	}
+	if opts != nil {
+		if opts.MaxBatchSize > 0 && opts.MaxBatchSize < maxPublishBatch {
+			fn.MaxBatchSize = opts.MaxBatchSize
+		}
+		if opts.MaxBufferedMessages > 0 {
+			fn.MaxBuffered = opts.MaxBufferedMessages
+		}
+		fn.Endpoint = opts.Endpoint
+	}
+
+	keyed := col
+	if col.Type().Components()[1].Type() != reflectx.ByteSlice {
+		keyed = beam.ParDo(s, marshalKeyedMessageFn, col)
+		fn.WithAttributes = true
+	}
+	beam.ParDo0(s, fn, beam.GroupByKey(s, keyed))
+}
+
+func marshalKeyedMessageFn(key string, msg *pb.PubsubMessage) (string, []byte, error) {
+	data, err := proto.Marshal(msg)
+	return key, data, err
+}
+
+// publishOrderedFn publishes the messages of each key in order, one batch at
+// a time.
+type publishOrderedFn struct {
+	// Topic is the fully-qualified topic name.
+	Topic string `json:"topic"`
+	// WithAttributes indicates that the values are marshalled PubSubMessages
+	// rather than payloads.
+	WithAttributes bool `json:"with_attributes"`
+	// MaxBatchSize is the maximum number of messages per request.
+	MaxBatchSize int `json:"max_batch_size"`
+	// MaxBuffered is the maximum number of messages per key held in memory.
+	MaxBuffered int `json:"max_buffered"`
+	// Endpoint is the PubSub endpoint, if not the default.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	client *pubsub.PublisherClient
+}
+
+func (f *publishOrderedFn) Setup(ctx context.Context) error {
+	var opts []option.ClientOption
+	if f.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(f.Endpoint), option.WithoutAuthentication(), option.WithGRPCDialOption(grpc.WithInsecure()))
+	}
+	client, err := pubsub.NewPublisherClient(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	f.client = client
+	return nil
+}
+
+func (f *publishOrderedFn) ProcessElement(ctx context.Context, key string, values func() func(*typex.EventTime, *[]byte) bool) error {
+	// Each pass over the values buffers the next earliest messages, so the
+	// memory used is bounded regardless of the number of messages per key.
+
+	var last *bufferedMessage
+	for {
+		buf := nextMessages(values(), last, f.MaxBuffered)
+		if err := f.publish(ctx, key, buf); err != nil {
+			return err
+		}
+		if len(buf) < f.MaxBuffered {
+			return nil
+		}
+		last = &buf[len(buf)-1]
+	}
+}
+
+func (f *publishOrderedFn) publish(ctx context.Context, key string, buf []bufferedMessage) error {
+	var msgs []timestampedMessage
+	for _, m := range buf {
+		msg := &pb.PubsubMessage{Data: m.data}
+		if f.WithAttributes {
+			msg = &pb.PubsubMessage{}
+			if err := proto.Unmarshal(m.data, msg); err != nil {
+				return err
+			}
+		}
+		msgs = append(msgs, timestampedMessage{ts: m.ts, msg: msg})
+	}
+
+	for _, batch := range orderedBatches(key, msgs, f.MaxBatchSize) {
+		// Wait for each batch to be acknowledged before sending the next, so
+		// a failed batch is never overtaken by a later one for the same key.
+		req := &pb.PublishRequest{Topic: f.Topic, Messages: batch}
+		if _, err := f.client.Publish(ctx, req); err != nil {
+			return fmt.Errorf("failed to publish messages with ordering key %v to %v: %v", key, f.Topic, err)
+		}
+	}
+	return nil
+}
+
+func (f *publishOrderedFn) Teardown() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
+
+// bufferedMessage is a message payload with its position in the event time
+// order of the values of a key. Messages with the same timestamp are ordered
+// by their index in the iteration.
+type bufferedMessage struct {
+	ts    time.Time
+	index int
+	data  []byte
+}
+
+func (m *bufferedMessage) before(o *bufferedMessage) bool {
+	if m.ts.Equal(o.ts) {
+		return m.index < o.index
+	}
+	return m.ts.Before(o.ts)
+}
+
+// nextMessages returns the up to max earliest messages after the given one,
+// if any, in order.
+func nextMessages(values func(*typex.EventTime, *[]byte) bool, after *bufferedMessage, max int) []bufferedMessage {
+	var h messageHeap
+
+	var ts typex.EventTime
+	var data []byte
+	for i := 0; values(&ts, &data); i++ {
+		m := bufferedMessage{ts: time.Time(ts), index: i, data: data}
+		if after != nil && !after.before(&m) {
+			continue
+		}
+		if len(h) < max {
+			heap.Push(&h, m)
+		} else if m.before(&h[0]) {
+			h[0] = m
+			heap.Fix(&h, 0)
+		}
+	}
+
+	ret := make([]bufferedMessage, len(h))
+	for i := len(h) - 1; i >= 0; i-- {
+		ret[i] = heap.Pop(&h).(bufferedMessage)
+	}
+	return ret
+}
+
+// messageHeap is a max-heap of messages, so that the latest buffered message
+// is evicted first.
+type messageHeap []bufferedMessage
+
+func (h messageHeap) Len() int            { return len(h) }
+func (h messageHeap) Less(i, j int) bool  { return h[j].before(&h[i]) }
+func (h messageHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *messageHeap) Push(x interface{}) { *h = append(*h, x.(bufferedMessage)) }
+func (h *messageHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type timestampedMessage struct {
+	ts  time.Time
+	msg *pb.PubsubMessage
+}
+
+// orderedBatches sorts the messages by timestamp, sets the ordering key and
+// splits them into batches of at most max messages.
+func orderedBatches(key string, msgs []timestampedMessage, max int) [][]*pb.PubsubMessage {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].ts.Before(msgs[j].ts)
+	})
+
+	var ret [][]*pb.PubsubMessage
+	var batch []*pb.PubsubMessage
+	for _, m := range msgs {
+		m.msg.OrderingKey = key
+		batch = append(batch, m.msg)
+		if len(batch) == max {
+			ret = append(ret, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		ret = append(ret, batch)
+	}
+	return ret
+}
@@ -0,0 +1,193 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsubio
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	beam.RegisterFunction(parseMessageFn)
+}
+
+func TestOrderedBatches(t *testing.T) {
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := func(offset time.Duration, data string) timestampedMessage {
+		return timestampedMessage{ts: base.Add(offset), msg: &pb.PubsubMessage{Data: []byte(data)}}
+	}
+
+	msgs := []timestampedMessage{
+		msg(3*time.Second, "d"),
+		msg(time.Second, "b"),
+		msg(0, "a"),
+		msg(time.Second, "c"),
+		msg(4*time.Second, "e"),
+	}
+
+	var got [][]string
+	for _, batch := range orderedBatches("k", msgs, 2) {
+		var data []string
+		for _, m := range batch {
+			if m.OrderingKey != "k" {
+				t.Errorf("message %s has ordering key %q, want %q", m.Data, m.OrderingKey, "k")
+			}
+			data = append(data, string(m.Data))
+		}
+		got = append(got, data)
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderedBatches(%v) = %v, want %v", msgs, got, want)
+	}
+}
+
+func TestNextMessages(t *testing.T) {
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	offsets := []int{3, 1, 0, 1, 4, 2}
+
+	values := func(ts *typex.EventTime, data *[]byte) bool { return false }
+	iter := func() func(*typex.EventTime, *[]byte) bool {
+		i := 0
+		return func(ts *typex.EventTime, data *[]byte) bool {
+			if i == len(offsets) {
+				return false
+			}
+			*ts = typex.EventTime(base.Add(time.Duration(offsets[i]) * time.Second))
+			*data = []byte(fmt.Sprint(i))
+			i++
+			return true
+		}
+	}
+	if got := nextMessages(values, nil, 2); len(got) != 0 {
+		t.Errorf("nextMessages(empty) = %v, want none", got)
+	}
+
+	// Each pass returns the next earliest messages. Messages with the same
+	// timestamp are in iteration order.
+
+	var got [][]string
+	var last *bufferedMessage
+	for {
+		buf := nextMessages(iter(), last, 4)
+		var data []string
+		for _, m := range buf {
+			data = append(data, string(m.data))
+		}
+		got = append(got, data)
+		if len(buf) < 4 {
+			break
+		}
+		last = &buf[len(buf)-1]
+	}
+
+	want := [][]string{{"2", "1", "3", "5"}, {"0", "4"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nextMessages passes = %v, want %v", got, want)
+	}
+}
+
+// fakePublisher is a PubSub publisher service that records publish requests.
+type fakePublisher struct {
+	pb.PublisherServer
+
+	mu   sync.Mutex
+	reqs []*pb.PublishRequest
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, req *pb.PublishRequest) (*pb.PublishResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.reqs = append(f.reqs, req)
+	resp := &pb.PublishResponse{}
+	for range req.Messages {
+		resp.MessageIds = append(resp.MessageIds, fmt.Sprint(len(resp.MessageIds)))
+	}
+	return resp, nil
+}
+
+// parseMessageFn parses "key:offset:data" into a message of the key with
+// the offset in seconds as timestamp.
+func parseMessageFn(elm string, emit func(typex.EventTime, string, []byte)) error {
+	parts := strings.Split(elm, ":")
+	var offset int
+	if _, err := fmt.Sscan(parts[1], &offset); err != nil {
+		return err
+	}
+	ts := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(offset) * time.Second)
+	emit(typex.EventTime(ts), parts[0], []byte(parts[2]))
+	return nil
+}
+
+// TestWriteOrdered verifies that messages are published per key in timestamp
+// order in bounded batches, also if a key has more messages than are buffered.
+func TestWriteOrdered(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	fake := &fakePublisher{}
+	pb.RegisterPublisherServer(server, fake)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	p, s, col := ptest.CreateList([]string{"a:3:d", "a:1:b", "b:2:y", "a:0:a", "a:4:e", "b:1:x", "a:2:c"})
+	msgs := beam.ParDo(s, parseMessageFn, col)
+	WriteOrdered(s, "project", "topic", msgs, &WriteOrderedOptions{
+		MaxBatchSize:        2,
+		MaxBufferedMessages: 3,
+		Endpoint:            lis.Addr().String(),
+	})
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+
+	got := make(map[string][]string)
+	for _, req := range fake.reqs {
+		if req.Topic != "projects/project/topics/topic" {
+			t.Errorf("published to %v, want projects/project/topics/topic", req.Topic)
+		}
+		if len(req.Messages) > 2 {
+			t.Errorf("published %v messages in one request, want at most 2", len(req.Messages))
+		}
+		for _, m := range req.Messages {
+			got[m.OrderingKey] = append(got[m.OrderingKey], string(m.Data))
+		}
+	}
+
+	want := map[string][]string{
+		"a": {"a", "b", "c", "d", "e"},
+		"b": {"x", "y"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
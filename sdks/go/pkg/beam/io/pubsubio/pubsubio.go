@@ -26,6 +26,7 @@ import (
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 	"github.com/apache/beam/sdks/go/pkg/beam/io/pubsubio/v1"
 	"github.com/apache/beam/sdks/go/pkg/beam/util/pubsubx"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/connectors"
 	"github.com/golang/protobuf/proto"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
 )
@@ -33,6 +34,15 @@ import (
 func init() {
 	beam.RegisterType(reflect.TypeOf((*pb.PubsubMessage)(nil)).Elem())
 	beam.RegisterFunction(unmarshalMessageFn)
+
+	connectors.Register(connectors.Connector{
+		Name:      "pubsubio",
+		URN:       v1.PubSubPayloadURN,
+		Package:   "github.com/apache/beam/sdks/go/pkg/beam/io/pubsubio",
+		Unbounded: true,
+		Features:  []connectors.Feature{connectors.Read, connectors.Write, connectors.Ordered},
+		Doc:       "Reads and writes Pub/Sub topics and subscriptions.",
+	})
 }
 
 // ReadOptions represents options for reading from PubSub.
@@ -63,6 +73,7 @@ func Read(s beam.Scope, project, topic string, opts *ReadOptions) beam.PCollecti
 	}
 
 	out := beam.External(s, v1.PubSubPayloadURN, protox.MustEncode(payload), nil, []beam.FullType{typex.New(reflectx.ByteSlice)})
+	out[0].SetUnbounded()
 	if opts.WithAttributes {
 		return beam.ParDo(s, unmarshalMessageFn, out[0])
 	}
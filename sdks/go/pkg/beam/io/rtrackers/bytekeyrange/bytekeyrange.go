@@ -0,0 +1,221 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bytekeyrange defines a restriction and tracker for a range of
+// byte string keys, such as the row keys of a key-ordered store. Keys are
+// ordered lexicographically.
+package bytekeyrange
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// Restriction is the range of keys [Start, End). An empty End is the end of
+// the key space.
+type Restriction struct {
+	Start, End []byte
+}
+
+// Contains returns true iff the key is in the range.
+func (r Restriction) Contains(key []byte) bool {
+	return bytes.Compare(key, r.Start) >= 0 && (len(r.End) == 0 || bytes.Compare(key, r.End) < 0)
+}
+
+func (r Restriction) String() string {
+	return fmt.Sprintf("[%x, %x)", r.Start, r.End)
+}
+
+// Tracker tracks the keys of a Restriction claimed in increasing order. It
+// is safe for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	rest    Restriction
+	last    []byte // last key attempted to claim, if claimed
+	claimed bool   // a key has been attempted to claim
+	stopped bool   // a claim failed
+	err     error
+}
+
+// NewTracker returns a tracker of the given range.
+func NewTracker(r Restriction) *Tracker {
+	return &Tracker{rest: r}
+}
+
+// TryClaim claims the given []byte key. It returns false, if the key is at
+// or past the end of the range. Claiming the empty key, which denotes the end
+// of the key space, marks the range as fully processed.
+func (t *Tracker) TryClaim(pos interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped || t.err != nil {
+		return false
+	}
+	key, ok := pos.([]byte)
+	if !ok {
+		t.err = fmt.Errorf("byte key range position must be []byte, got %T", pos)
+		return false
+	}
+	if len(key) == 0 {
+		t.stopped = true
+		return false
+	}
+	switch {
+	case bytes.Compare(key, t.rest.Start) < 0:
+		t.err = fmt.Errorf("key %x is before the range %v", key, t.rest)
+		return false
+	case t.claimed && bytes.Compare(key, t.last) <= 0:
+		t.err = fmt.Errorf("key %x must be greater than the last claimed key %x", key, t.last)
+		return false
+	}
+	t.last, t.claimed = append([]byte(nil), key...), true
+	if len(t.rest.End) > 0 && bytes.Compare(key, t.rest.End) >= 0 {
+		t.stopped = true
+		return false
+	}
+	return true
+}
+
+// next returns the smallest key that has not been attempted to claim.
+func (t *Tracker) next() []byte {
+	if !t.claimed {
+		return t.rest.Start
+	}
+	return append(append([]byte(nil), t.last...), 0)
+}
+
+// TrySplit splits off the unclaimed keys, keeping about the given fraction
+// of them. The split key is interpolated, assuming that keys are evenly
+// distributed. A fraction of 0 splits off all unclaimed keys.
+func (t *Tracker) TrySplit(fraction float64) (primary, residual interface{}, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped || t.err != nil {
+		return t.rest, nil, nil
+	}
+	if fraction < 0 || fraction > 1 {
+		return t.rest, nil, fmt.Errorf("split fraction %v must be between 0 and 1", fraction)
+	}
+	next := t.next()
+	split, ok := interpolate(next, t.rest.End, fraction)
+	if !ok {
+		return t.rest, nil, nil
+	}
+	res := Restriction{Start: split, End: t.rest.End}
+	t.rest.End = split
+	return t.rest, res, nil
+}
+
+// GetProgress returns the fraction of the key space of the range claimed
+// and remaining, assuming that keys are evenly distributed.
+func (t *Tracker) GetProgress() (done, remaining float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := width(t.rest.Start, t.rest.End, t.next())
+	start, end, next := toInt(t.rest.Start, n), toEnd(t.rest.End, n), toInt(t.next(), n)
+	if next.Cmp(end) > 0 {
+		next = end
+	}
+	total := new(big.Float).SetInt(new(big.Int).Sub(end, start))
+	if total.Sign() == 0 {
+		return 0, 0
+	}
+	d, _ := new(big.Float).Quo(new(big.Float).SetInt(new(big.Int).Sub(next, start)), total).Float64()
+	return d, 1 - d
+}
+
+// IsDone returns true iff a claim failed, such as of the empty key, or no
+// key remains in the range, such as after splitting off all unclaimed keys.
+// Ranges of keys of arbitrary length cannot be fully claimed otherwise.
+func (t *Tracker) IsDone() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stopped || t.err != nil || (len(t.rest.End) > 0 && bytes.Compare(t.next(), t.rest.End) >= 0)
+}
+
+// GetError returns the error of an invalid claim, if any.
+func (t *Tracker) GetError() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+// GetRestriction returns the current Restriction.
+func (t *Tracker) GetRestriction() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.rest
+}
+
+// interpolate returns the key at the given fraction between the keys. The
+// keys are interpreted as fractions of the key space, with one more byte of
+// precision than the longest key. It returns false, if the key is not
+// before end.
+func interpolate(start, end []byte, fraction float64) ([]byte, bool) {
+	n := width(start, end)
+	from, to := toInt(start, n), toEnd(end, n)
+
+	diff := new(big.Float).SetInt(new(big.Int).Sub(to, from))
+	offset, _ := diff.Mul(diff, big.NewFloat(fraction)).Int(nil)
+	sum := new(big.Int).Add(from, offset)
+	if sum.Cmp(to) >= 0 {
+		return nil, false
+	}
+	key := sum.Bytes()
+
+	// Restore the leading zeros and trim trailing zeros that the key does not
+	// need to stay at or after start.
+	ret := make([]byte, n)
+	copy(ret[n-len(key):], key)
+	for len(ret) > len(start) && ret[len(ret)-1] == 0 {
+		ret = ret[:len(ret)-1]
+	}
+	return ret, true
+}
+
+// width returns the number of bytes used to interpolate between the keys.
+func width(keys ...[]byte) int {
+	n := 0
+	for _, k := range keys {
+		if len(k) > n {
+			n = len(k)
+		}
+	}
+	return n + 1
+}
+
+// toInt returns the key padded to n bytes as a big-endian integer.
+func toInt(key []byte, n int) *big.Int {
+	buf := make([]byte, n)
+	copy(buf, key)
+	return new(big.Int).SetBytes(buf)
+}
+
+// toEnd returns the end key padded to n bytes as a big-endian integer. The
+// empty key is the end of the key space.
+func toEnd(key []byte, n int) *big.Int {
+	if len(key) == 0 {
+		return new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	}
+	return toInt(key, n)
+}
@@ -0,0 +1,114 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bytekeyrange
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+)
+
+var _ sdf.RTracker = (*Tracker)(nil)
+
+func TestTryClaim(t *testing.T) {
+	rt := NewTracker(Restriction{Start: []byte("b"), End: []byte("d")})
+	var claimed []string
+	for _, key := range []string{"b", "bz", "c", "d"} {
+		if !rt.TryClaim([]byte(key)) {
+			break
+		}
+		claimed = append(claimed, key)
+	}
+	if want := []string{"b", "bz", "c"}; !reflect.DeepEqual(claimed, want) {
+		t.Errorf("claimed %v, want %v", claimed, want)
+	}
+	if !rt.IsDone() || rt.GetError() != nil {
+		t.Errorf("IsDone() = %v, GetError() = %v, want done without error", rt.IsDone(), rt.GetError())
+	}
+
+	rt = NewTracker(Restriction{Start: []byte("b")})
+	if !rt.TryClaim([]byte("zzz")) || rt.IsDone() {
+		t.Errorf("TryClaim(zzz) in unbounded range failed or is done")
+	}
+	if rt.TryClaim([]byte{}) || !rt.IsDone() || rt.GetError() != nil {
+		t.Errorf("TryClaim(empty) did not mark the range done")
+	}
+
+	rt = NewTracker(Restriction{Start: []byte("b")})
+	if rt.TryClaim([]byte("a")) || rt.GetError() == nil {
+		t.Errorf("TryClaim(a) before the range did not fail")
+	}
+	rt = NewTracker(Restriction{Start: []byte("b")})
+	if !rt.TryClaim([]byte("c")) || rt.TryClaim([]byte("c")) || rt.GetError() == nil {
+		t.Errorf("TryClaim(c) twice did not fail")
+	}
+}
+
+func TestTrySplit(t *testing.T) {
+	tests := []struct {
+		rest     Restriction
+		claimed  string // last claimed key, if any
+		fraction float64
+		split    []byte // start of the residual, if any
+	}{
+		{Restriction{Start: []byte{0x00}, End: []byte{0x80}}, "", 0.5, []byte{0x40}},
+		{Restriction{Start: []byte{}, End: []byte{}}, "", 0.5, []byte{0x80}},
+		{Restriction{Start: []byte{0x10}, End: []byte{0x20}}, "", 0, []byte{0x10}},
+		{Restriction{Start: []byte{0x10}, End: []byte{0x20}}, "\x10", 0, []byte{0x10, 0x00}},
+		{Restriction{Start: []byte{0x10}, End: []byte{0x11}}, "", 0.5, []byte{0x10, 0x80}},
+		{Restriction{Start: []byte{0x10}, End: []byte{0x20}}, "", 1, nil},
+	}
+	for _, test := range tests {
+		rt := NewTracker(test.rest)
+		if test.claimed != "" {
+			rt.TryClaim([]byte(test.claimed))
+		}
+		primary, residual, err := rt.TrySplit(test.fraction)
+		if err != nil {
+			t.Errorf("TrySplit(%v) of %v failed: %v", test.fraction, test.rest, err)
+			continue
+		}
+		if test.split == nil {
+			if residual != nil {
+				t.Errorf("TrySplit(%v) of %v = %v, want no split", test.fraction, test.rest, residual)
+			}
+			continue
+		}
+		p, r := primary.(Restriction), residual.(Restriction)
+		if !bytes.Equal(p.Start, test.rest.Start) || !bytes.Equal(p.End, test.split) || !bytes.Equal(r.Start, test.split) || !bytes.Equal(r.End, test.rest.End) {
+			t.Errorf("TrySplit(%v) of %v = (%v, %v), want split at %x", test.fraction, test.rest, p, r, test.split)
+		}
+		if test.claimed != "" && !p.Contains([]byte(test.claimed)) {
+			t.Errorf("primary %v does not contain claimed key %x", p, test.claimed)
+		}
+		if test.fraction == 0 && !rt.IsDone() {
+			t.Errorf("IsDone() after TrySplit(0) of %v = false, want true", test.rest)
+		}
+	}
+}
+
+func TestGetProgress(t *testing.T) {
+	rt := NewTracker(Restriction{Start: []byte{0x00}, End: []byte{0x80}})
+	if done, remaining := rt.GetProgress(); done != 0 || remaining != 1 {
+		t.Errorf("GetProgress() = (%v, %v), want (0, 1)", done, remaining)
+	}
+	rt.TryClaim([]byte{0x3f, 0xff})
+	if done, _ := rt.GetProgress(); done < 0.49 || done > 0.51 {
+		t.Errorf("GetProgress() = %v done, want 0.5", done)
+	}
+}
@@ -0,0 +1,185 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package offsetrange defines a restriction and tracker for a range of
+// offsets, such as the byte offsets of a file or the indices of a list.
+package offsetrange
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Restriction is the range of offsets [Start, End).
+type Restriction struct {
+	Start, End int64
+}
+
+// Size returns the number of offsets in the range.
+func (r Restriction) Size() int64 {
+	if r.End < r.Start {
+		return 0
+	}
+	return r.End - r.Start
+}
+
+// EvenSplits splits the range into the given number of ranges of about the
+// same size. It returns fewer ranges, if the range has fewer offsets.
+func (r Restriction) EvenSplits(num int64) []Restriction {
+	size := r.Size()
+	if num <= 1 || size <= 1 {
+		return []Restriction{r}
+	}
+	if num > size {
+		num = size
+	}
+	var ret []Restriction
+	for i := int64(0); i < num; i++ {
+		ret = append(ret, Restriction{Start: r.Start + size*i/num, End: r.Start + size*(i+1)/num})
+	}
+	return ret
+}
+
+// SizedSplits splits the range into ranges of the given size. The last range
+// may be smaller.
+func (r Restriction) SizedSplits(size int64) []Restriction {
+	if size <= 0 || r.Size() <= size {
+		return []Restriction{r}
+	}
+	var ret []Restriction
+	for start := r.Start; start < r.End; start += size {
+		end := start + size
+		if end > r.End {
+			end = r.End
+		}
+		ret = append(ret, Restriction{Start: start, End: end})
+	}
+	return ret
+}
+
+func (r Restriction) String() string {
+	return fmt.Sprintf("[%v, %v)", r.Start, r.End)
+}
+
+// Tracker tracks the offsets of a Restriction claimed in increasing order.
+// It is safe for concurrent use.
+type Tracker struct {
+	mu        sync.Mutex
+	rest      Restriction
+	attempted int64 // last offset attempted to claim, if claimed
+	claimed   bool  // an offset has been attempted to claim
+	stopped   bool  // a claim failed
+	err       error
+}
+
+// NewTracker returns a tracker of the given range.
+func NewTracker(r Restriction) *Tracker {
+	return &Tracker{rest: r}
+}
+
+// TryClaim claims the given int64 offset. It returns false, if the offset is
+// at or past the end of the range.
+func (t *Tracker) TryClaim(pos interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped || t.err != nil {
+		return false
+	}
+	offset, ok := pos.(int64)
+	if !ok {
+		t.err = fmt.Errorf("offset range position must be int64, got %T", pos)
+		return false
+	}
+	switch {
+	case offset < t.rest.Start:
+		t.err = fmt.Errorf("offset %v is before the range %v", offset, t.rest)
+		return false
+	case t.claimed && offset <= t.attempted:
+		t.err = fmt.Errorf("offset %v must be greater than the last claimed offset %v", offset, t.attempted)
+		return false
+	}
+	t.attempted, t.claimed = offset, true
+	if offset >= t.rest.End {
+		t.stopped = true
+		return false
+	}
+	return true
+}
+
+// TrySplit splits off the unclaimed offsets, keeping the given fraction of
+// them. A fraction of 0 splits off all unclaimed offsets.
+func (t *Tracker) TrySplit(fraction float64) (primary, residual interface{}, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped || t.err != nil {
+		return t.rest, nil, nil
+	}
+	if fraction < 0 || fraction > 1 {
+		return t.rest, nil, fmt.Errorf("split fraction %v must be between 0 and 1", fraction)
+	}
+	next := t.rest.Start
+	if t.claimed {
+		next = t.attempted + 1
+	}
+	split := next + int64(math.Ceil(fraction*float64(t.rest.End-next)))
+	if split >= t.rest.End {
+		return t.rest, nil, nil
+	}
+	res := Restriction{Start: split, End: t.rest.End}
+	t.rest.End = split
+	return t.rest, res, nil
+}
+
+// GetProgress returns the number of offsets claimed and remaining.
+func (t *Tracker) GetProgress() (done, remaining float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next := t.rest.Start
+	if t.claimed {
+		next = t.attempted + 1
+	}
+	if next > t.rest.End {
+		next = t.rest.End
+	}
+	return float64(next - t.rest.Start), float64(t.rest.End - next)
+}
+
+// IsDone returns true iff all offsets have been claimed or a claim failed.
+func (t *Tracker) IsDone() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stopped || t.err != nil || t.rest.Size() == 0 || (t.claimed && t.attempted >= t.rest.End-1)
+}
+
+// GetError returns the error of an invalid claim, if any.
+func (t *Tracker) GetError() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+// GetRestriction returns the current Restriction.
+func (t *Tracker) GetRestriction() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.rest
+}
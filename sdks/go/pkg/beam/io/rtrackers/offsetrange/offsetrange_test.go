@@ -0,0 +1,115 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offsetrange
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+)
+
+var _ sdf.RTracker = (*Tracker)(nil)
+
+func TestSplits(t *testing.T) {
+	r := Restriction{Start: 0, End: 10}
+	if got, want := r.EvenSplits(3), []Restriction{{0, 3}, {3, 6}, {6, 10}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EvenSplits(3) = %v, want %v", got, want)
+	}
+	if got, want := (Restriction{0, 2}).EvenSplits(5), []Restriction{{0, 1}, {1, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EvenSplits(5) = %v, want %v", got, want)
+	}
+	if got, want := r.SizedSplits(4), []Restriction{{0, 4}, {4, 8}, {8, 10}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SizedSplits(4) = %v, want %v", got, want)
+	}
+}
+
+func TestTryClaim(t *testing.T) {
+	rt := NewTracker(Restriction{Start: 5, End: 8})
+	var claimed []int64
+	for i := int64(5); rt.TryClaim(i); i++ {
+		claimed = append(claimed, i)
+	}
+	if want := []int64{5, 6, 7}; !reflect.DeepEqual(claimed, want) {
+		t.Errorf("claimed %v, want %v", claimed, want)
+	}
+	if !rt.IsDone() || rt.GetError() != nil {
+		t.Errorf("IsDone() = %v, GetError() = %v, want done without error", rt.IsDone(), rt.GetError())
+	}
+
+	rt = NewTracker(Restriction{Start: 5, End: 8})
+	if rt.TryClaim(int64(4)) || rt.GetError() == nil {
+		t.Errorf("TryClaim(4) before the range did not fail")
+	}
+	rt = NewTracker(Restriction{Start: 5, End: 8})
+	if rt.TryClaim(5) || rt.GetError() == nil {
+		t.Errorf("TryClaim(int) did not fail")
+	}
+	rt = NewTracker(Restriction{Start: 5, End: 8})
+	if !rt.TryClaim(int64(6)) || rt.TryClaim(int64(6)) || rt.GetError() == nil {
+		t.Errorf("TryClaim(6) twice did not fail")
+	}
+}
+
+func TestTrySplit(t *testing.T) {
+	tests := []struct {
+		claimed  int64 // last claimed offset, if not negative
+		fraction float64
+		primary  Restriction
+		residual interface{}
+	}{
+		{-1, 0, Restriction{0, 0}, Restriction{0, 100}},
+		{-1, 0.5, Restriction{0, 50}, Restriction{50, 100}},
+		{9, 0, Restriction{0, 10}, Restriction{10, 100}},
+		{9, 0.5, Restriction{0, 55}, Restriction{55, 100}},
+		{98, 0.5, Restriction{0, 100}, nil},
+		{99, 0, Restriction{0, 100}, nil},
+	}
+	for _, test := range tests {
+		rt := NewTracker(Restriction{Start: 0, End: 100})
+		for i := int64(0); i <= test.claimed; i++ {
+			rt.TryClaim(i)
+		}
+		primary, residual, err := rt.TrySplit(test.fraction)
+		if err != nil || primary != test.primary || residual != test.residual {
+			t.Errorf("TrySplit(%v) after claiming %v = (%v, %v, %v), want (%v, %v)", test.fraction, test.claimed, primary, residual, err, test.primary, test.residual)
+		}
+		if got := rt.GetRestriction(); got != test.primary {
+			t.Errorf("GetRestriction() after split = %v, want %v", got, test.primary)
+		}
+	}
+
+	rt := NewTracker(Restriction{Start: 0, End: 10})
+	rt.TryClaim(int64(0))
+	rt.TrySplit(0)
+	if rt.TryClaim(int64(1)) || !rt.IsDone() {
+		t.Errorf("TryClaim(1) after split at 1 succeeded")
+	}
+	if _, _, err := rt.TrySplit(2); err != nil {
+		t.Errorf("TrySplit(2) of done tracker failed: %v", err)
+	}
+}
+
+func TestGetProgress(t *testing.T) {
+	rt := NewTracker(Restriction{Start: 10, End: 20})
+	if done, remaining := rt.GetProgress(); done != 0 || remaining != 10 {
+		t.Errorf("GetProgress() = (%v, %v), want (0, 10)", done, remaining)
+	}
+	rt.TryClaim(int64(12))
+	if done, remaining := rt.GetProgress(); done != 3 || remaining != 7 {
+		t.Errorf("GetProgress() = (%v, %v), want (3, 7)", done, remaining)
+	}
+}
@@ -47,3 +47,23 @@ type FileSystem interface {
 	// overwritten.
 	OpenWrite(ctx context.Context, filename string) (io.WriteCloser, error)
 }
+
+// Renamer is an optional interface for file systems that can move a file,
+// replacing any existing file of the new name. Write uses it to write files
+// through a temporary file, such that they are never partially written.
+type Renamer interface {
+	Rename(ctx context.Context, oldname, newname string) error
+}
+
+var tempTracker func(ctx context.Context, filename string) error
+
+// RegisterTempFileTracker registers a function that is called with the name of
+// each temporary file before Write creates it, such as to delete temporary
+// files that are left behind by failed bundles. It should be called in init()
+// only.
+func RegisterTempFileTracker(fn func(ctx context.Context, filename string) error) {
+	if tempTracker != nil {
+		panic("temporary file tracker already registered")
+	}
+	tempTracker = fn
+}
@@ -20,17 +20,21 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"path/filepath"
 	"strings"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
 	"github.com/apache/beam/sdks/go/pkg/beam/util/gcsx"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/cleanup"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/storage/v1"
 )
 
 func init() {
 	textio.RegisterFileSystem("gs", New)
+	cleanup.RegisterDeleter("gs", remove)
 }
 
 type fs struct {
@@ -135,3 +139,22 @@ func (w *writer) Write(data []byte) (n int, err error) {
 func (w *writer) Close() error {
 	return gcsx.WriteObject(w.client, w.bucket, w.object, &w.buf)
 }
+
+// remove deletes a GCS object tracked for cleanup.
+func remove(ctx context.Context, filename string) error {
+	bucket, object, err := gcsx.ParseObject(filename)
+	if err != nil {
+		return err
+	}
+	client, err := gcsx.NewClient(ctx, storage.DevstorageReadWriteScope)
+	if err != nil {
+		return err
+	}
+	if err := client.Objects.Delete(bucket, object).Context(ctx).Do(); err != nil {
+		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -22,10 +22,12 @@ import (
 	"path/filepath"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/cleanup"
 )
 
 func init() {
 	textio.RegisterFileSystem("default", New)
+	cleanup.RegisterDeleter("default", remove)
 }
 
 type fs struct{}
@@ -53,3 +55,15 @@ func (f *fs) OpenWrite(ctx context.Context, filename string) (io.WriteCloser, er
 	}
 	return os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 }
+
+func (f *fs) Rename(ctx context.Context, oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+// remove deletes a local file tracked for cleanup.
+func remove(ctx context.Context, filename string) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -19,18 +19,29 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"math/rand"
 	"os"
 	"reflect"
 	"strings"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/connectors"
 )
 
 func init() {
 	beam.RegisterType(reflect.TypeOf((*writeFileFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*globFn)(nil)).Elem())
 	beam.RegisterFunction(readFn)
 	beam.RegisterFunction(expandFn)
+
+	connectors.Register(connectors.Connector{
+		Name:     "textio",
+		URN:      "beam:go:io:textio:v1",
+		Package:  "github.com/apache/beam/sdks/go/pkg/beam/io/textio",
+		Features: []connectors.Feature{connectors.Read, connectors.Write},
+		Doc:      "Reads and writes lines of text files on registered file systems.",
+	})
 }
 
 // Read reads a set of file and returns the lines as a PCollection<string>. The
@@ -42,6 +53,35 @@ func Read(s beam.Scope, glob string) beam.PCollection {
 	return read(s, beam.Create(s, glob))
 }
 
+// ReadValue reads a set of file, whose glob may be deferred until run time,
+// and returns the lines as a PCollection<string>. Deferred globs are
+// validated only when the pipeline executes.
+func ReadValue(s beam.Scope, glob beam.ValueProvider[string]) beam.PCollection {
+	if !glob.IsRuntime() {
+		return Read(s, glob.Value)
+	}
+
+	s = s.Scope("textio.ReadValue")
+	return read(s, beam.ParDo(s, &globFn{Glob: glob}, beam.Impulse(s)))
+}
+
+// globFn resolves a deferred glob at run time.
+type globFn struct {
+	Glob beam.ValueProvider[string] `json:"glob"`
+}
+
+func (f *globFn) ProcessElement(_ []byte, emit func(string)) error {
+	glob, err := f.Glob.Get()
+	if err != nil {
+		return err
+	}
+	if _, ok := registry[getScheme(glob)]; !ok {
+		return fmt.Errorf("textio scheme %v not registered for %v", getScheme(glob), glob)
+	}
+	emit(glob)
+	return nil
+}
+
 func validateScheme(glob string) {
 	if strings.TrimSpace(glob) == "" {
 		panic("empty file glob provided")
@@ -59,7 +99,9 @@ func getScheme(glob string) string {
 	return "default"
 }
 
-func newFileSystem(ctx context.Context, glob string) (FileSystem, error) {
+// NewFileSystem returns a FileSystem for the scheme of the given path or
+// glob. It fails if no file system is registered for the scheme.
+func NewFileSystem(ctx context.Context, glob string) (FileSystem, error) {
 	scheme := getScheme(glob)
 	mkfs, ok := registry[scheme]
 	if !ok {
@@ -87,7 +129,7 @@ func expandFn(ctx context.Context, glob string, emit func(string)) error {
 		return nil // ignore empty string elements here
 	}
 
-	fs, err := newFileSystem(ctx, glob)
+	fs, err := NewFileSystem(ctx, glob)
 	if err != nil {
 		return err
 	}
@@ -106,7 +148,7 @@ func expandFn(ctx context.Context, glob string, emit func(string)) error {
 func readFn(ctx context.Context, filename string, emit func(string)) error {
 	log.Infof(ctx, "Reading from %v", filename)
 
-	fs, err := newFileSystem(ctx, filename)
+	fs, err := NewFileSystem(ctx, filename)
 	if err != nil {
 		return err
 	}
@@ -151,34 +193,57 @@ type writeFileFn struct {
 }
 
 func (w *writeFileFn) ProcessElement(ctx context.Context, _ int, lines func(*string) bool) error {
-	fs, err := newFileSystem(ctx, w.Filename)
+	fs, err := NewFileSystem(ctx, w.Filename)
 	if err != nil {
 		return err
 	}
 	defer fs.Close()
 
-	fd, err := fs.OpenWrite(ctx, w.Filename)
+	// If the file system can move files, the lines are written to a temporary
+	// file next to the output, which is moved into place once complete.
+
+	filename := w.Filename
+	r, rename := fs.(Renamer)
+	if rename {
+		filename = fmt.Sprintf("%v.beam-temp-%x", w.Filename, rand.Int63())
+		if tempTracker != nil {
+			if err := tempTracker(ctx, filename); err != nil {
+				return fmt.Errorf("failed to track temporary file %v: %v", filename, err)
+			}
+		}
+	}
+
+	fd, err := fs.OpenWrite(ctx, filename)
 	if err != nil {
 		return err
 	}
 	buf := bufio.NewWriterSize(fd, 1<<20) // use 1MB buffer
 
-	log.Infof(ctx, "Writing to %v", w.Filename)
+	log.Infof(ctx, "Writing to %v", filename)
 
 	var line string
 	for lines(&line) {
 		if _, err := buf.WriteString(line); err != nil {
+			fd.Close()
 			return err
 		}
 		if _, err := buf.Write([]byte{'\n'}); err != nil {
+			fd.Close()
 			return err
 		}
 	}
 
 	if err := buf.Flush(); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
 		return err
 	}
-	return fd.Close()
+	if rename {
+		return r.Rename(ctx, filename, w.Filename)
+	}
+	return nil
 }
 
 // Immediate reads a local file at pipeline construction-time and embeds the
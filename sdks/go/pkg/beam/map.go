@@ -0,0 +1,179 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func init() {
+	RegisterType(reflect.TypeOf((*filterFn)(nil)).Elem())
+	RegisterType(reflect.TypeOf((*flatMapFn)(nil)).Elem())
+}
+
+// Map applies the element-wise function fn : A -> B to each element of a
+// PCollection<A>. It returns a PCollection<B>. It is a ParDo without the
+// emitter boilerplate. For example:
+//
+//    lengths := beam.Map(s, func(w string) int { return len(w) }, words)
+func Map(s Scope, fn interface{}, col PCollection) PCollection {
+	return Must(TryMap(s, fn, col))
+}
+
+// TryMap attempts to insert a Map transform into the pipeline. It may fail
+// for multiple reasons, notably that the function is not of the form A -> B.
+func TryMap(s Scope, fn interface{}, col PCollection) (PCollection, error) {
+	if _, _, err := validateElementwise("Map", fn, col); err != nil {
+		return PCollection{}, err
+	}
+	return tryParDo1(s, fn, col)
+}
+
+// Filter keeps the elements of a PCollection<A> for which the predicate
+// fn : A -> bool returns true. It returns a PCollection<A>. For example:
+//
+//    short := beam.Filter(s, func(w string) bool { return len(w) < 3 }, words)
+func Filter(s Scope, fn interface{}, col PCollection) PCollection {
+	return Must(TryFilter(s, fn, col))
+}
+
+// TryFilter attempts to insert a Filter transform into the pipeline. It may
+// fail for multiple reasons, notably that the function is not a predicate.
+func TryFilter(s Scope, fn interface{}, col PCollection) (PCollection, error) {
+	_, out, err := validateElementwise("Filter", fn, col)
+	if err != nil {
+		return PCollection{}, err
+	}
+	if out != reflectx.Bool {
+		return PCollection{}, fmt.Errorf("Filter: fn must be of the form A -> bool: %v", reflect.TypeOf(fn))
+	}
+
+	s = s.Scope("beam.Filter")
+	return tryParDo1(s, &filterFn{Predicate: EncodedFunc{Fn: reflectx.MakeFunc(fn)}}, col)
+}
+
+// FlatMap applies the function fn : A -> []B to each element of a
+// PCollection<A> and flattens the results. It returns a PCollection<B>. For
+// example:
+//
+//    words := beam.FlatMap(s, func(line string) []string { return strings.Fields(line) }, lines)
+//
+// Functions that output a variable number of elements without collecting
+// them in a slice can be used with ParDo and an emitter instead.
+func FlatMap(s Scope, fn interface{}, col PCollection) PCollection {
+	return Must(TryFlatMap(s, fn, col))
+}
+
+// TryFlatMap attempts to insert a FlatMap transform into the pipeline. It may
+// fail for multiple reasons, notably that the function is not of the form
+// A -> []B.
+func TryFlatMap(s Scope, fn interface{}, col PCollection) (PCollection, error) {
+	_, out, err := validateElementwise("FlatMap", fn, col)
+	if err != nil {
+		return PCollection{}, err
+	}
+	if out.Kind() != reflect.Slice || out == reflectx.ByteSlice {
+		return PCollection{}, fmt.Errorf("FlatMap: fn must be of the form A -> []B: %v", reflect.TypeOf(fn))
+	}
+	if typex.IsUniversal(out.Elem()) {
+		return PCollection{}, fmt.Errorf("FlatMap: output element type of fn must be concrete: %v", reflect.TypeOf(fn))
+	}
+
+	s = s.Scope("beam.FlatMap")
+	return tryParDo1(s, &flatMapFn{Fn: EncodedFunc{Fn: reflectx.MakeFunc(fn)}}, col, TypeDefinition{Var: UType, T: out.Elem()})
+}
+
+// tryParDo1 inserts a ParDo with a single output.
+func tryParDo1(s Scope, dofn interface{}, col PCollection, opts ...Option) (PCollection, error) {
+	ret, err := TryParDo(s, dofn, col, opts...)
+	if err != nil {
+		return PCollection{}, err
+	}
+	if len(ret) != 1 {
+		return PCollection{}, fmt.Errorf("expected 1 output, got %v", len(ret))
+	}
+	return ret[0], nil
+}
+
+// validateElementwise validates that fn is a function A -> B and that A
+// matches the element type of the non-KV input, if not universal. It returns
+// the types A and B.
+func validateElementwise(name string, fn interface{}, col PCollection) (reflect.Type, reflect.Type, error) {
+	if !col.IsValid() {
+		return nil, nil, fmt.Errorf("%v: invalid input PCollection", name)
+	}
+	if col.Type().Class() == typex.Composite {
+		return nil, nil, fmt.Errorf("%v: input must be a non-KV PCollection: %v", name, col.Type())
+	}
+	if t := reflect.TypeOf(fn); t == nil || t.Kind() != reflect.Func {
+		return nil, nil, fmt.Errorf("%v: fn must be a function: %v", name, fn)
+	}
+
+	u, err := funcx.New(reflectx.MakeFunc(fn))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: invalid fn: %v", name, err)
+	}
+	if len(u.Param) != 1 || u.Param[0].Kind != funcx.FnValue || len(u.Ret) != 1 || u.Ret[0].Kind != funcx.RetValue {
+		return nil, nil, fmt.Errorf("%v: fn must be of the form A -> B: %v", name, u.Fn.Type())
+	}
+	in, out := u.Param[0].T, u.Ret[0].T
+	if !typex.IsUniversal(in) && in != col.Type().Type() {
+		return nil, nil, fmt.Errorf("%v: fn input type %v does not match %v", name, in, col.Type())
+	}
+	return in, out, nil
+}
+
+// filterFn emits the elements that satisfy the predicate.
+type filterFn struct {
+	// Predicate is the encoded predicate.
+	Predicate EncodedFunc `json:"predicate"`
+
+	fn reflectx.Func1x1
+}
+
+func (f *filterFn) Setup() {
+	f.fn = reflectx.ToFunc1x1(f.Predicate.Fn)
+}
+
+func (f *filterFn) ProcessElement(elm T, emit func(T)) {
+	if f.fn.Call1x1(elm).(bool) {
+		emit(elm)
+	}
+}
+
+// flatMapFn emits each element of the slice returned by the function.
+type flatMapFn struct {
+	// Fn is the encoded function.
+	Fn EncodedFunc `json:"fn"`
+
+	fn reflectx.Func1x1
+}
+
+func (f *flatMapFn) Setup() {
+	f.fn = reflectx.ToFunc1x1(f.Fn.Fn)
+}
+
+func (f *flatMapFn) ProcessElement(elm T, emit func(U)) {
+	out := reflect.ValueOf(f.fn.Call1x1(elm))
+	for i := 0; i < out.Len(); i++ {
+		emit(out.Index(i).Interface())
+	}
+}
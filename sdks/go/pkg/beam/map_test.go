@@ -0,0 +1,81 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(wordLen)
+	beam.RegisterFunction(isShort)
+	beam.RegisterFunction(strings.Fields)
+}
+
+func wordLen(w string) int {
+	return len(w)
+}
+
+func isShort(w string) bool {
+	return len(w) < 3
+}
+
+func TestMapFilterFlatMap(t *testing.T) {
+	p, s, lines := ptest.Create([]interface{}{"a long line", "", "to be"})
+
+	words := beam.FlatMap(s, strings.Fields, lines)
+	passert.Equals(s, words, "a", "long", "line", "to", "be")
+	passert.Equals(s, beam.Map(s, wordLen, words), 1, 4, 4, 2, 2)
+	passert.Equals(s, beam.Filter(s, isShort, words), "a", "to", "be")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("pipeline failed: %v", err)
+	}
+}
+
+func TestMapFilterFlatMapBadFn(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	words := beam.Create(s, "a", "b")
+	kvs := beam.AddFixedKey(s, words)
+
+	tests := []struct {
+		name string
+		try  func() (beam.PCollection, error)
+	}{
+		{"map of non-function", func() (beam.PCollection, error) { return beam.TryMap(s, 5, words) }},
+		{"map with emitter", func() (beam.PCollection, error) {
+			return beam.TryMap(s, func(w string, emit func(string)) {}, words)
+		}},
+		{"map of wrong type", func() (beam.PCollection, error) { return beam.TryMap(s, func(n int) int { return n }, words) }},
+		{"map of KV", func() (beam.PCollection, error) { return beam.TryMap(s, wordLen, kvs) }},
+		{"filter of non-predicate", func() (beam.PCollection, error) { return beam.TryFilter(s, wordLen, words) }},
+		{"flatmap of non-slice", func() (beam.PCollection, error) { return beam.TryFlatMap(s, wordLen, words) }},
+		{"flatmap of bytes", func() (beam.PCollection, error) {
+			return beam.TryFlatMap(s, func(w string) []byte { return []byte(w) }, words)
+		}},
+	}
+	for _, test := range tests {
+		if _, err := test.try(); err == nil {
+			t.Errorf("%v succeeded, want error", test.name)
+		}
+	}
+}
@@ -42,12 +42,19 @@ var (
 	// specified, the binary is produced via go build.
 	WorkerBinary = flag.String("worker_binary", "", "Worker binary (optional)")
 
+	// Artifacts are additional files to stage with the worker binary.
+	Artifacts = flag.String("artifacts", "", "Comma-separated list of additional files to stage with the worker binary, such as config.json (optional).")
+
 	// Experiments toggle experimental features in the runner.
 	Experiments = flag.String("experiments", "", "Comma-separated list of experiments (optional).")
 
 	// Async determines whether to wait for job completion.
 	Async = flag.Bool("async", false, "Do not wait for job completion.")
 
+	// JobMessages is the minimum severity of job messages to print while
+	// waiting for job completion.
+	JobMessages = flag.String("job_messages", "info", "Minimum severity of job messages and worker logs to print to stdout while waiting for job completion: debug, info, warn, error or off.")
+
 	// InternalJavaRunner is the java class needed at this time for Java runners.
 	// To be removed.
 	InternalJavaRunner = flag.String("internal_java_runner", "", "Internal java runner class.")
@@ -89,3 +96,30 @@ func GetExperiments() []string {
 	}
 	return strings.Split(*Experiments, ",")
 }
+
+// GetArtifacts returns the additional files to stage.
+func GetArtifacts() []string {
+	if *Artifacts == "" {
+		return nil
+	}
+	return strings.Split(*Artifacts, ",")
+}
+
+// GetJobMessageLevel returns the minimum severity of job messages to print.
+// It returns log.SevUnspecified if printing is turned off.
+func GetJobMessageLevel() (log.Severity, error) {
+	switch strings.ToLower(*JobMessages) {
+	case "debug":
+		return log.SevDebug, nil
+	case "info", "":
+		return log.SevInfo, nil
+	case "warn", "warning":
+		return log.SevWarn, nil
+	case "error":
+		return log.SevError, nil
+	case "off", "none":
+		return log.SevUnspecified, nil
+	default:
+		return log.SevUnspecified, fmt.Errorf("invalid job message level %q: want debug, info, warn, error or off", *JobMessages)
+	}
+}
@@ -0,0 +1,313 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package structopts defines pipeline options as tagged structs instead of
+// individual flags. Each exported field of a registered struct becomes a
+// command-line flag, with optional default value, usage and requirement
+// given by field tags:
+//
+//    type Options struct {
+//        Input  string        `flag:"input" usage:"Input file glob." required:"true"`
+//        Limit  int           `flag:"limit" default:"100" usage:"Maximum number of results."`
+//        Period time.Duration `flag:"period" default:"1m"`
+//    }
+//
+//    var opts = &Options{}
+//
+//    func init() {
+//        structopts.Register(opts)
+//    }
+//
+// Fields without a flag tag use the lower-cased field name. Supported field
+// types are string, bool, int, int64, uint, uint64, float64, time.Duration
+// and []string, which is given as a comma-separated list. If the struct has
+// a method Validate() error, it is called in addition to the required checks.
+//
+// The options are validated and shipped to workers with the pipeline when
+// Export is called before submission, which beamx.Run does. DoFns retrieve
+// them through their context:
+//
+//    func (f *myFn) ProcessElement(ctx context.Context, line string, emit func(string)) error {
+//        var opts Options
+//        if err := structopts.Get(ctx, &opts); err != nil {
+//            return err
+//        }
+//        ...
+//    }
+package structopts
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+)
+
+var (
+	registry = make(map[reflect.Type]interface{})
+	mu       sync.Mutex
+)
+
+// Register registers the fields of the given struct pointer as flags in the
+// default command-line flag set and sets their default values. Each struct
+// type can be registered once. It must be called in an init() function.
+func Register(opts interface{}) {
+	register(flag.CommandLine, opts)
+}
+
+func register(fs *flag.FlagSet, opts interface{}) {
+	v := reflect.ValueOf(opts)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("options %v must be a pointer to a struct", v.Type()))
+	}
+	t := v.Elem().Type()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := registry[t]; ok {
+		panic(fmt.Sprintf("options %v already registered", t))
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		fv := &fieldValue{v: v.Elem().Field(i)}
+		if !fv.supported() {
+			panic(fmt.Sprintf("options %v: field %v has unsupported type %v", t, f.Name, f.Type))
+		}
+		if def, ok := f.Tag.Lookup("default"); ok {
+			if err := fv.Set(def); err != nil {
+				panic(fmt.Sprintf("options %v: invalid default for field %v: %v", t, f.Name, err))
+			}
+		}
+		fs.Var(fv, flagName(f), f.Tag.Get("usage"))
+	}
+	registry[t] = opts
+}
+
+func flagName(f reflect.StructField) string {
+	if name := f.Tag.Get("flag"); name != "" {
+		return name
+	}
+	return strings.ToLower(f.Name)
+}
+
+func optionKey(t reflect.Type) string {
+	return fmt.Sprintf("structopts:%v.%v", t.PkgPath(), t.Name())
+}
+
+// Validate checks that all required fields of the registered options are
+// set and calls their Validate methods, if present.
+func Validate() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for t, opts := range registry {
+		if err := validate(t, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validate(t reflect.Type, opts interface{}) error {
+	v := reflect.ValueOf(opts).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("required") != "true" {
+			continue
+		}
+		if reflect.DeepEqual(v.Field(i).Interface(), reflect.Zero(f.Type).Interface()) {
+			return fmt.Errorf("options %v: required flag --%v not set", t, flagName(f))
+		}
+	}
+	if val, ok := opts.(interface {
+		Validate() error
+	}); ok {
+		if err := val.Validate(); err != nil {
+			return fmt.Errorf("options %v: %v", t, err)
+		}
+	}
+	return nil
+}
+
+// Export validates the registered options and records their values in the
+// pipeline options, so that they are available on workers. It must be called
+// after flag parsing and before the pipeline is submitted.
+func Export() error {
+	if err := Validate(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for t, opts := range registry {
+		data, err := json.Marshal(opts)
+		if err != nil {
+			return fmt.Errorf("failed to encode options %v: %v", t, err)
+		}
+		runtime.GlobalOptions.Set(optionKey(t), string(data))
+	}
+	return nil
+}
+
+type ctxKey string
+
+const optionsKey ctxKey = "beam:structopts"
+
+// WithOptions returns a context in which the given options, a struct
+// pointer, override the registered options of the same type.
+func WithOptions(ctx context.Context, opts interface{}) context.Context {
+	m := make(map[reflect.Type]interface{})
+	if prev, ok := ctx.Value(optionsKey).(map[reflect.Type]interface{}); ok {
+		for k, v := range prev {
+			m[k] = v
+		}
+	}
+	m[reflect.TypeOf(opts).Elem()] = opts
+	return context.WithValue(ctx, optionsKey, m)
+}
+
+// Get copies the options of the type pointed to by opts into it. The values
+// are those exported with the pipeline, if any, or else the current values
+// of the registered options, such as during construction or when executing
+// in the launching process.
+func Get(ctx context.Context, opts interface{}) error {
+	v := reflect.ValueOf(opts)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("options %v must be a pointer to a struct", v.Type())
+	}
+	t := v.Elem().Type()
+
+	if m, ok := ctx.Value(optionsKey).(map[reflect.Type]interface{}); ok {
+		if o, ok := m[t]; ok {
+			v.Elem().Set(reflect.ValueOf(o).Elem())
+			return nil
+		}
+	}
+
+	mu.Lock()
+	o, ok := registry[t]
+	mu.Unlock()
+
+	if raw := runtime.GlobalOptions.Get(optionKey(t)); raw != "" {
+		v.Elem().Set(reflect.Zero(t))
+		if err := json.Unmarshal([]byte(raw), opts); err != nil {
+			return fmt.Errorf("failed to decode options %v: %v", t, err)
+		}
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("options %v not registered", t)
+	}
+	v.Elem().Set(reflect.ValueOf(o).Elem())
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldValue is a flag.Value for a struct field.
+type fieldValue struct {
+	v reflect.Value
+}
+
+func (f *fieldValue) supported() bool {
+	if f.v.Type() == durationType {
+		return true
+	}
+	switch f.v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return f.v.Type().Elem().Kind() == reflect.String
+	default:
+		return false
+	}
+}
+
+func (f *fieldValue) String() string {
+	if !f.v.IsValid() {
+		return "" // zero value used by the flag package
+	}
+	if f.v.Kind() == reflect.Slice {
+		return strings.Join(f.v.Interface().([]string), ",")
+	}
+	return fmt.Sprint(f.v.Interface())
+}
+
+func (f *fieldValue) Set(s string) error {
+	if f.v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.v.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.v.Kind() {
+	case reflect.String:
+		f.v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			return err
+		}
+		f.v.SetInt(n)
+	case reflect.Uint, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			return err
+		}
+		f.v.SetUint(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f.v.SetFloat(n)
+	case reflect.Slice:
+		var list []string
+		for _, elm := range strings.Split(s, ",") {
+			if elm = strings.TrimSpace(elm); elm != "" {
+				list = append(list, elm)
+			}
+		}
+		f.v.Set(reflect.ValueOf(list))
+	}
+	return nil
+}
+
+// IsBoolFlag allows boolean fields to be given as --name without a value.
+func (f *fieldValue) IsBoolFlag() bool {
+	return f.v.IsValid() && f.v.Kind() == reflect.Bool
+}
@@ -0,0 +1,89 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structopts
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testOptions struct {
+	Input   string        `flag:"input" usage:"Input file." required:"true"`
+	Limit   int           `flag:"limit" default:"100"`
+	Verbose bool          `usage:"Verbose output."`
+	Period  time.Duration `flag:"period" default:"1m"`
+	Tags    []string      `flag:"tags" default:"a,b"`
+
+	internal int
+}
+
+func (o *testOptions) Validate() error {
+	if o.Limit <= 0 {
+		return fmt.Errorf("limit must be positive: %v", o.Limit)
+	}
+	return nil
+}
+
+func TestOptions(t *testing.T) {
+	opts := &testOptions{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	register(fs, opts)
+
+	want := testOptions{Limit: 100, Period: time.Minute, Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(*opts, want) {
+		t.Errorf("defaults = %+v, want %+v", *opts, want)
+	}
+	if err := Validate(); err == nil {
+		t.Errorf("Validate() succeeded without required input, want error")
+	}
+
+	args := []string{"--input=foo", "--limit=-1", "--verbose", "--tags=x, y", "--period=5s"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%v) failed: %v", args, err)
+	}
+	if err := Export(); err == nil {
+		t.Errorf("Export() succeeded with negative limit, want error")
+	}
+	if err := fs.Parse([]string{"--limit=7"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if err := Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	// Clobber the registered options to verify that the exported values
+	// are used.
+	*opts = testOptions{}
+
+	ctx := context.Background()
+	var got testOptions
+	if err := Get(ctx, &got); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	want = testOptions{Input: "foo", Limit: 7, Verbose: true, Period: 5 * time.Second, Tags: []string{"x", "y"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+
+	override := &testOptions{Input: "bar"}
+	if err := Get(WithOptions(ctx, override), &got); err != nil || got.Input != "bar" {
+		t.Errorf("Get() with override = %+v, %v, want input bar", got, err)
+	}
+}
@@ -43,6 +43,7 @@ func TryParDo(s Scope, dofn interface{}, col PCollection, opts ...Option) ([]PCo
 	if err != nil {
 		return nil, err
 	}
+	edge.Label = s.name
 
 	var ret []PCollection
 	for _, out := range edge.Output {
@@ -50,7 +50,6 @@ func (p PCollection) IsValid() bool {
 }
 
 // TODO(herohde) 5/30/2017: add name for PCollections? Java supports it.
-// TODO(herohde) 5/30/2017: add windowing strategy and documentation.
 
 // Type returns the full type 'A' of the elements. 'A' must be a concrete
 // type, such as int or KV<int,string>.
@@ -82,6 +81,36 @@ func (p PCollection) SetCoder(c Coder) error {
 	return nil
 }
 
+// IsBounded returns true iff the collection is bounded.
+func (p PCollection) IsBounded() bool {
+	if !p.IsValid() {
+		panic("Invalid PCollection")
+	}
+	return p.n.Bounded()
+}
+
+// WindowingStrategy returns the windowing strategy of the collection. The
+// collection properties, i.e., Type, Coder, IsBounded and WindowingStrategy,
+// allow composite transforms to adapt to their input, such as by choosing a
+// streaming write path for unbounded collections.
+func (p PCollection) WindowingStrategy() *WindowingStrategy {
+	if !p.IsValid() {
+		panic("Invalid PCollection")
+	}
+	return p.n.Window()
+}
+
+// SetUnbounded marks the collection as unbounded. It is intended for the
+// output of unbounded sources, such as pubsubio.Read, and must be called
+// before the collection is used as input. Downstream collections are
+// unbounded as well.
+func (p PCollection) SetUnbounded() {
+	if !p.IsValid() {
+		panic("Invalid PCollection")
+	}
+	p.n.SetBounded(false)
+}
+
 func (p PCollection) String() string {
 	if !p.IsValid() {
 		return "(invalid)"
@@ -0,0 +1,48 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+)
+
+func TestPCollectionProperties(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	col := beam.Create(s, "a", "b")
+	if got := col.Type().Type(); got != reflect.TypeOf("") {
+		t.Errorf("Type() = %v, want string", got)
+	}
+	if got := col.Coder().Type().Type(); got != reflect.TypeOf("") {
+		t.Errorf("Coder().Type() = %v, want string", got)
+	}
+	if !col.IsBounded() {
+		t.Errorf("IsBounded() = false, want true")
+	}
+	if got := col.WindowingStrategy().Kind(); got != beam.GlobalWindow {
+		t.Errorf("WindowingStrategy().Kind() = %v, want %v", got, beam.GlobalWindow)
+	}
+
+	col.SetUnbounded()
+	out := beam.ParDo(s, func(s string) int { return len(s) }, col)
+	if out.IsBounded() {
+		t.Errorf("IsBounded() = true for output of unbounded collection, want false")
+	}
+}
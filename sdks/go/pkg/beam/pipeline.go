@@ -16,7 +16,12 @@
 package beam
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/dot"
 )
 
 // Scope is a hierarchical grouping for composite transforms. Scopes can be
@@ -28,6 +33,8 @@ type Scope struct {
 	scope *graph.Scope
 	// real is the enclosing graph.
 	real *graph.Graph
+	// name is the user-provided name of transforms applied in the scope, if any.
+	name string
 }
 
 // IsValid returns true iff the Scope is valid. Any use of an invalid Scope
@@ -37,7 +44,16 @@ func (s Scope) IsValid() bool {
 }
 
 // Scope returns a sub-scope with the given name. The name provided may
-// be augmented to ensure uniqueness.
+// be augmented to ensure uniqueness. Composite transforms should apply their
+// transforms in a sub-scope, so that runners show them as a single named
+// step with the underlying transforms nested:
+//
+//    func Sessionize(s beam.Scope, events beam.PCollection) beam.PCollection {
+//        s = s.Scope("MyTeam.Sessionize")
+//
+//        keyed := beam.ParDo(s, keyByUserFn, events)
+//        return beam.ParDo(s, sessionizeFn, beam.GroupByKey(s, keyed))
+//    }
 func (s Scope) Scope(name string) Scope {
 	if !s.IsValid() {
 		panic("Invalid Scope")
@@ -46,6 +62,46 @@ func (s Scope) Scope(name string) Scope {
 	return Scope{scope: scope, real: s.real}
 }
 
+// WithEnvironment returns a sub-scope with the given name, where transforms
+// are pinned to the environment with the given container image instead of
+// the default environment of the pipeline. It allows a few transforms to run
+// in a variant image, such as one with extra native libraries:
+//
+//    native := s.WithEnvironment("OCR", "gcr.io/my-project/beam-go-ocr:latest")
+//    text := beam.ParDo(native, ocrFn, images)
+//
+// The image must contain the same pipeline binary. Nested scopes inherit the
+// environment unless pinned themselves. Runners that do not support multiple
+// environments reject pipelines with pinned transforms.
+func (s Scope) WithEnvironment(name, image string) Scope {
+	if image == "" {
+		panic("empty environment image")
+	}
+	ret := s.Scope(name)
+	ret.scope.Environment = image
+	return ret
+}
+
+// Named returns the same scope, except that primitive transforms applied
+// directly with it, such as ParDo or GroupByKey, are given the supplied name
+// instead of one derived from the function or kind of transform. The names of
+// transforms are qualified by the enclosing scopes and must be stable across
+// code changes for pipeline updates. Explicit names are not affected by
+// refactorings that rename or move functions:
+//
+//    words := beam.ParDo(s.Named("ExtractWords"), extractFn, lines)
+//
+// Composite transforms should be named by applying them in a sub-scope
+// instead. Transforms with the same name in a scope are disambiguated with a
+// suffix, such as '1, in order of construction.
+func (s Scope) Named(name string) Scope {
+	if name == "" {
+		panic("empty transform name")
+	}
+	s.name = name
+	return s
+}
+
 func (s Scope) String() string {
 	if !s.IsValid() {
 		return "<invalid>"
@@ -57,7 +113,10 @@ func (s Scope) String() string {
 // PCollections that the PTransforms consume and produce. Each Pipeline is
 // self-contained and isolated from any other Pipeline. The Pipeline owns the
 // PCollections and PTransforms and they can by used by that Pipeline only.
-// Pipelines can safely be executed concurrently.
+// Pipelines can safely be executed concurrently. Transforms may be applied
+// from multiple goroutines, such as when building many similar branches, but
+// a PCollection should not be modified, such as with SetCoder, while other
+// goroutines use it.
 type Pipeline struct {
 	// real is the deferred execution Graph as it is being constructed.
 	real *graph.Graph
@@ -82,6 +141,43 @@ func (p *Pipeline) Build() ([]*graph.MultiEdge, []*graph.Node, error) {
 	return p.real.Build()
 }
 
+// Validate checks the Pipeline for problems, such as unconsumed PCollections,
+// missing coders, grouping of non-KV or unwindowed unbounded input and type
+// mismatches. All problems are reported together in a ValidationError. It
+// should be called before submission to catch problems that would otherwise
+// surface one at a time or only once the job runs. Problems caught during
+// construction, such as binding errors, still panic at the offending call.
+func (p *Pipeline) Validate() error {
+	if problems := p.real.Validate(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ValidationError is the set of problems found by Pipeline.Validate.
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	var lines []string
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.Error())
+	}
+	return fmt.Sprintf("pipeline has %v problem(s):\n%v", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+// RenderDOT writes a Graphviz DOT graph of the pipeline to w for debugging
+// construction. Transforms are grouped by scope and PCollections are annotated
+// with their type, windowing and coder.
+func RenderDOT(p *Pipeline, w io.Writer) error {
+	edges, nodes, err := p.Build()
+	if err != nil {
+		return err
+	}
+	return dot.Render(edges, nodes, w)
+}
+
 func (p *Pipeline) String() string {
 	return p.real.String()
 }
@@ -0,0 +1,141 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+// TestConcurrentConstruction verifies that branches of a pipeline can be
+// applied from multiple goroutines.
+func TestConcurrentConstruction(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	col := beam.Create(s, 1, 2, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			s := s.Scope(fmt.Sprintf("branch%v", i))
+			out := beam.ParDo(s, inc, beam.ParDo(s, double, col))
+			passert.Equals(s, out, 3, 5, 7)
+		}(i)
+	}
+	wg.Wait()
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
+
+func pairWithOne(w string) (string, int) { return w, 1 }
+
+func sumValues(w string, iter func(*int) bool) (string, int) {
+	sum, n := 0, 0
+	for iter(&n) {
+		sum += n
+	}
+	return w, sum
+}
+
+func addOffset(w string, n, offset int) (string, int) { return w, n + offset }
+
+// TestValidate verifies that Validate reports all problems together and
+// accepts well-formed pipelines.
+func TestValidate(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	words := beam.Create(s, "a", "b", "a")
+	counts := beam.ParDo(s, sumValues, beam.GroupByKey(s, beam.ParDo(s, pairWithOne, words)))
+	sums := beam.CombinePerKey(s, func(a, b int) int { return a + b }, beam.ParDo(s, pairWithOne, words))
+	offset := beam.Create(s, 1)
+	all := beam.Flatten(s, counts, beam.ParDo(s, addOffset, sums, beam.SideInput{Input: offset}))
+	passert.Equals(s, beam.DropKey(s, all), 1, 2, 2, 3)
+
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate() failed: %v", err)
+	}
+
+	p = beam.NewPipeline()
+	s = p.Root()
+	words = beam.Create(s, "a", "b", "a")
+	beam.ParDo(s, pairWithOne, words) // unconsumed
+	words.SetUnbounded()
+	grouped := beam.GroupByKey(s, beam.ParDo(s, pairWithOne, words))
+	beam.ParDo0(s, func(string, func(*int) bool) {}, grouped)
+
+	err := p.Validate()
+	verr, ok := err.(*beam.ValidationError)
+	if !ok {
+		t.Fatalf("Validate() = %v, want ValidationError", err)
+	}
+	if len(verr.Problems) != 2 {
+		t.Fatalf("Validate() = %v, want 2 problems", err)
+	}
+	for i, want := range []string{"never consumed", "must be windowed"} {
+		if got := verr.Problems[i].Error(); !strings.Contains(got, want) {
+			t.Errorf("problem %v = %v, want it to contain %q", i, got, want)
+		}
+	}
+
+	// Unbounded input in the global window may be grouped with a trigger.
+	p = beam.NewPipeline()
+	s = p.Root()
+	words = beam.Create(s, "a", "b", "a")
+	words.SetUnbounded()
+	ws := window.NewGlobalWindow().WithTrigger(window.TriggerRepeat(window.TriggerAfterCount(1)))
+	grouped = beam.GroupByKey(s, beam.WindowInto(s, ws, beam.ParDo(s, pairWithOne, words)))
+	beam.ParDo0(s, func(string, func(*int) bool) {}, grouped)
+
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate() with trigger failed: %v", err)
+	}
+}
+
+// TestNamed verifies that explicit names apply to the transforms applied
+// with the named scope only.
+func TestNamed(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	col := beam.ParDo(s.Named("Double"), double, beam.Create(s, 1, 2, 3))
+	beam.ParDo(s, inc, col)
+
+	edges, _, err := p.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, edge := range edges {
+		names = append(names, edge.Name())
+	}
+	if got, want := names[2], "Double"; got != want {
+		t.Errorf("name of ParDo(double) = %v, want %v", got, want)
+	}
+	if got, want := names[3], "github.com/apache/beam/sdks/go/pkg/beam_test.inc"; got != want {
+		t.Errorf("name of ParDo(inc) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+)
+
+func init() {
+	RegisterFunction(addRandomKeyFn)
+	RegisterType(reflect.TypeOf((*reifyValueFn)(nil)).Elem())
+	RegisterType(reflect.TypeOf((*unreifyValueFn)(nil)).Elem())
+}
+
+// Reshuffle redistributes the elements of the PCollection<A> through a
+// shuffle and returns an identical PCollection<A>. Elements keep their
+// timestamps and windows. It is used as a barrier: a runner may not fuse
+// the transforms on either side, so that a high-fanout ParDo is spread
+// over workers and its expensive output is checkpointed and not recomputed
+// when downstream processing is retried. For example:
+//
+//    expanded := beam.ParDo(s, expandFn, seeds)
+//    processed := beam.ParDo(s, processFn, beam.Reshuffle(s, expanded))
+//
+func Reshuffle(s Scope, col PCollection) PCollection {
+	s = s.Scope("Reshuffle")
+
+	keyed := ParDo(s, addRandomKeyFn, col)
+	return DropKey(s, ReshufflePerKey(s, keyed))
+}
+
+func addRandomKeyFn(elm T) (int, T) {
+	return rand.Int(), elm
+}
+
+// ReshufflePerKey is Reshuffle for PCollection<KV<K,V>>, where elements with
+// the same key end up together. The key coder must be deterministic.
+func ReshufflePerKey(s Scope, col PCollection) PCollection {
+	return Must(TryReshufflePerKey(s, col))
+}
+
+// TryReshufflePerKey inserts a ReshufflePerKey transform into the pipeline.
+// It returns an error on failure.
+func TryReshufflePerKey(s Scope, col PCollection) (PCollection, error) {
+	s = s.Scope("ReshufflePerKey")
+
+	c := col.Coder()
+	if !c.IsValid() || !coder.IsKV(c.coder) {
+		return PCollection{}, fmt.Errorf("reshuffle requires a KV coder: %v", c)
+	}
+	vc := EncodedCoder{Coder{c.coder.Components[1]}}
+
+	// The timestamp of each value is encoded with the value, because the
+	// shuffle does not preserve timestamps of grouped values.
+	reified, err := TryParDo(s, &reifyValueFn{ValueCoder: vc}, col)
+	if err != nil {
+		return PCollection{}, err
+	}
+	grouped, err := TryGroupByKey(s, reified[0])
+	if err != nil {
+		return PCollection{}, err
+	}
+	ret, err := TryParDo(s, &unreifyValueFn{ValueCoder: vc}, grouped, TypeDefinition{Var: YType, T: vc.Coder.Type().Type()})
+	if err != nil {
+		return PCollection{}, err
+	}
+	return ret[0], nil
+}
+
+// reifyValueFn encodes each value with its timestamp.
+type reifyValueFn struct {
+	ValueCoder EncodedCoder `json:"value_coder"`
+
+	enc  exec.ElementEncoder
+	wEnc exec.WindowEncoder
+}
+
+func (f *reifyValueFn) Setup() {
+	f.enc = exec.MakeElementEncoder(f.ValueCoder.Coder.coder)
+	f.wEnc = exec.MakeWindowEncoder(coder.NewGlobalWindow())
+}
+
+func (f *reifyValueFn) ProcessElement(t EventTime, key X, value Y) (X, []byte, error) {
+	var buf bytes.Buffer
+	if err := exec.EncodeWindowedValueHeader(f.wEnc, nil, t, &buf); err != nil {
+		return nil, nil, err
+	}
+	if err := f.enc.Encode(exec.FullValue{Elm: value}, &buf); err != nil {
+		return nil, nil, err
+	}
+	return key, buf.Bytes(), nil
+}
+
+// unreifyValueFn emits each grouped value with its original timestamp.
+type unreifyValueFn struct {
+	ValueCoder EncodedCoder `json:"value_coder"`
+
+	dec  exec.ElementDecoder
+	wDec exec.WindowDecoder
+}
+
+func (f *unreifyValueFn) Setup() {
+	f.dec = exec.MakeElementDecoder(f.ValueCoder.Coder.coder)
+	f.wDec = exec.MakeWindowDecoder(coder.NewGlobalWindow())
+}
+
+func (f *unreifyValueFn) ProcessElement(key X, values func(*[]byte) bool, emit func(EventTime, X, Y)) error {
+	var data []byte
+	for values(&data) {
+		r := bytes.NewReader(data)
+		_, t, err := exec.DecodeWindowedValueHeader(f.wDec, r)
+		if err != nil {
+			return err
+		}
+		v, err := f.dec.Decode(r)
+		if err != nil {
+			return err
+		}
+		emit(t, key, v.Elm)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func withTimestamp(n int) (beam.EventTime, int) {
+	return beam.EventTime(time.Unix(int64(n), 0)), n
+}
+
+func formatTimestamped(t beam.EventTime, n int) string {
+	return fmt.Sprintf("%v@%v", n, time.Time(t).Unix())
+}
+
+func formatTimestampedKV(t beam.EventTime, k string, n int) string {
+	return fmt.Sprintf("%v:%v@%v", k, n, time.Time(t).Unix())
+}
+
+func TestReshuffle(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	col := beam.ParDo(s, withTimestamp, beam.Create(s, 1, 2, 3))
+	out := beam.Reshuffle(s, col)
+	passert.Equals(s, beam.ParDo(s, formatTimestamped, out), "1@1", "2@2", "3@3")
+
+	kvs := beam.ParDo(s, func(t beam.EventTime, n int) (beam.EventTime, string, int) {
+		return t, fmt.Sprintf("k%v", n%2), n
+	}, col)
+	passert.Equals(s, beam.ParDo(s, formatTimestampedKV, beam.ReshufflePerKey(s, kvs)), "k1:1@1", "k0:2@2", "k1:3@3")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Reshuffle failed: %v", err)
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
 )
 
@@ -27,12 +29,57 @@ import (
 // verification, but require that it is stored in Init and used for Run.
 
 var (
-	runners = make(map[string]func(ctx context.Context, p *Pipeline) error)
+	runners = make(map[string]func(ctx context.Context, p *Pipeline) (PipelineResult, error))
 )
 
+// JobState is the state of a pipeline job.
+type JobState string
+
+// Job states. A job is finished once DONE, FAILED or CANCELLED.
+const (
+	JobUnknown   JobState = "UNKNOWN"
+	JobRunning   JobState = "RUNNING"
+	JobDone      JobState = "DONE"
+	JobFailed    JobState = "FAILED"
+	JobCancelled JobState = "CANCELLED"
+)
+
+// IsTerminal returns true iff the job is finished in the state.
+func (s JobState) IsTerminal() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// PipelineResult is the handle of a pipeline job started by Run. With an
+// asynchronous runner, the job may still be running when Run returns.
+type PipelineResult interface {
+	// JobID returns the runner-specific id of the job, if any.
+	JobID() string
+	// State returns the current state of the job.
+	State(ctx context.Context) (JobState, error)
+	// Wait blocks until the job is finished. It returns an error if the job
+	// failed.
+	Wait(ctx context.Context) error
+	// Cancel requests the job to stop, discarding the data in flight. Wait
+	// blocks until it is cancelled.
+	Cancel(ctx context.Context) error
+	// Drain requests the job to stop consuming input and to finish once the
+	// data in flight is processed, such as for streaming jobs. Wait blocks
+	// until it is finished.
+	Drain(ctx context.Context) error
+	// Metrics returns the user metrics of the job, aggregated over bundles.
+	// Metrics of a running job are the values reported so far. The progress
+	// of the transforms, such as their element counts, is kept apart from the
+	// user metrics, and is only reported by some runners.
+	Metrics(ctx context.Context) (*metrics.ResultSet, error)
+	// BuildInfo returns the information about the binary that submitted the
+	// job, such as the SDK and Go versions and the VCS revision, as recorded
+	// in the display data of the pipeline.
+	BuildInfo() buildinfo.Info
+}
+
 // RegisterRunner associates the name with the supplied runner, making it available
 // to execute a pipeline via Run.
-func RegisterRunner(name string, fn func(ctx context.Context, p *Pipeline) error) {
+func RegisterRunner(name string, fn func(ctx context.Context, p *Pipeline) (PipelineResult, error)) {
 	if _, ok := runners[name]; ok {
 		panic(fmt.Sprintf("runner %v already defined", name))
 	}
@@ -41,8 +88,9 @@ func RegisterRunner(name string, fn func(ctx context.Context, p *Pipeline) error
 
 // Run executes the pipeline using the selected registred runner. It is customary
 // to define a "runner" with no default as a flag to let users control runner
-// selection.
-func Run(ctx context.Context, runner string, p *Pipeline) error {
+// selection. The returned result queries the state and metrics of the job.
+// Runners that do not run a job, such as the dot runner, return a nil result.
+func Run(ctx context.Context, runner string, p *Pipeline) (PipelineResult, error) {
 	fn, ok := runners[runner]
 	if !ok {
 		log.Exitf(ctx, "Runner %v not registered. Forgot to _ import it?", runner)
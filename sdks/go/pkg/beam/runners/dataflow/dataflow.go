@@ -29,7 +29,10 @@ import (
 	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/harness"
 	// Importing to get the side effect of the remote execution hook. See init().
 	_ "github.com/apache/beam/sdks/go/pkg/beam/core/runtime/harness/init"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/hooks"
@@ -58,7 +61,14 @@ var (
 	network         = flag.String("network", "", "GCP network (optional)")
 	tempLocation    = flag.String("temp_location", "", "Temp location (optional)")
 	machineType     = flag.String("worker_machine_type", "", "GCE machine type (optional)")
-	streaming       = flag.Bool("streaming", false, "Streaming job")
+	streaming       = flag.Bool("streaming", false, "Streaming job. Pipelines with unbounded PCollections always run as streaming jobs.")
+	streamingEngine = flag.Bool("enable_streaming_engine", false, "Run the streaming job on Streaming Engine, which moves state and shuffle off the workers (optional).")
+
+	update               = flag.Bool("update", false, "Update the running streaming job of the same name in place (optional).")
+	transformNameMapping = flag.String("transform_name_mapping", "", "JSON map of the names of transforms in the running job to their names in the updated pipeline, such as {\"old\":\"new\"} (optional).")
+
+	submitRetries    = flag.Int("submit_retries", 3, "Number of times to retry job submission on transient failures (optional).")
+	submitRetryDelay = flag.Duration("submit_retry_delay", 5*time.Second, "Initial delay between job submission retries (optional).")
 
 	dryRun         = flag.Bool("dry_run", false, "Dry run. Just print the job, but don't submit it.")
 	teardownPolicy = flag.String("teardown_policy", "", "Job teardown policy (internal only).")
@@ -73,6 +83,7 @@ func init() {
 	beam.RegisterRunner("dataflow", Execute)
 
 	perf.RegisterProfCaptureHook("gcs_profile_writer", gcsRecorderHook)
+	harness.RegisterProfileUploader("gs", gcsProfileUploader{})
 }
 
 type dataflowOptions struct {
@@ -81,14 +92,26 @@ type dataflowOptions struct {
 }
 
 // Execute runs the given pipeline on Google Cloud Dataflow. It uses the
-// default application credentials to submit the job.
-func Execute(ctx context.Context, p *beam.Pipeline) error {
+// default application credentials to submit the job. Pipelines with unbounded
+// PCollections run as streaming jobs, optionally on Streaming Engine, and
+// replace the running job of the same name with --update. With
+// --template_location, the job is staged as a classic template instead of
+// being run, and with --flex_template_location, a Flex Template spec is
+// written. The runtime parameters of the pipeline become template parameters.
+//
+// Unless --async, Execute waits for the job to finish. The result queries the
+// state and metrics of the job. If the job is not submitted, such as with
+// --dry_run or a template location, the result is nil.
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
 	project := *gcpopts.Project
 	if project == "" {
-		return errors.New("no Google Cloud project specified. Use --project=<project>")
+		return nil, errors.New("no Google Cloud project specified. Use --project=<project>")
+	}
+	if *flexTemplateLocation != "" {
+		return nil, writeFlexTemplate(ctx, p, jobopts.GetJobName(), *flexTemplateImage, *flexTemplateLocation)
 	}
 	if *stagingLocation == "" {
-		return errors.New("no GCS staging location specified. Use --staging_location=gs://<bucket>/<path>")
+		return nil, errors.New("no GCS staging location specified. Use --staging_location=gs://<bucket>/<path>")
 	}
 	if *image == "" {
 		*image = jobopts.GetContainerImage(ctx)
@@ -97,7 +120,12 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 
 	edges, _, err := p.Build()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, edge := range edges {
+		if env := edge.Scope().Env(); env != "" && env != *image {
+			return nil, fmt.Errorf("transform %v is pinned to environment %v: multiple environments are not supported by Dataflow", edge.Name(), env)
+		}
 	}
 
 	if *cpuProfiling != "" {
@@ -122,7 +150,7 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 	if *jobopts.WorkerBinary == "" {
 		worker, err := runnerlib.BuildTempWorkerBinary(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer os.Remove(worker)
 
@@ -133,16 +161,16 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 
 	binary, err := stageWorker(ctx, project, *stagingLocation, *jobopts.WorkerBinary)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	model, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: *image})
 	if err != nil {
-		return fmt.Errorf("failed to generate model pipeline: %v", err)
+		return nil, fmt.Errorf("failed to generate model pipeline: %v", err)
 	}
 	modelURL, err := stageModel(ctx, project, *stagingLocation, protox.MustEncode(model))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Info(ctx, proto.MarshalTextString(model))
 
@@ -150,24 +178,34 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 
 	steps, err := translate(edges)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	isStreaming := *streaming || unbounded(edges)
+	if !isStreaming && (*streamingEngine || *update) {
+		return nil, errors.New("--enable_streaming_engine and --update require a streaming job. Use --streaming")
 	}
 
 	jobType := "JOB_TYPE_BATCH"
 	apiJobType := "FNAPI_BATCH"
-	if *streaming {
+	if isStreaming {
 		jobType = "JOB_TYPE_STREAMING"
 		apiJobType = "FNAPI_STREAMING"
 	}
+	experiments := jobopts.GetExperiments()
+	if *streamingEngine {
+		experiments = append(experiments, "enable_streaming_engine", "enable_windmill_service")
+	}
 
 	job := &df.Job{
-		ProjectId: project,
-		Name:      jobName,
-		Type:      jobType,
+		ProjectId:       project,
+		Name:            jobName,
+		Type:            jobType,
+		ClientRequestId: newClientRequestID(),
 		Environment: &df.Environment{
 			UserAgent: newMsg(userAgent{
 				Name:    "Apache Beam SDK for Go",
-				Version: "0.3.0",
+				Version: buildinfo.SDKVersion,
 			}),
 			Version: newMsg(version{
 				JobType: apiJobType,
@@ -194,7 +232,7 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 				Zone:                        *zone,
 			}},
 			TempStoragePrefix: *stagingLocation + "/tmp",
-			Experiments:       jobopts.GetExperiments(),
+			Experiments:       experiments,
 		},
 		Steps: steps,
 	}
@@ -208,26 +246,40 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 	if *tempLocation != "" {
 		job.Environment.TempStoragePrefix = *tempLocation
 	}
-	if *streaming {
+	if isStreaming && !*streamingEngine {
 		// Add separate data disk for streaming jobs
 		job.Environment.WorkerPools[0].DataDisks = []*df.Disk{{}}
 	}
+	if *transformNameMapping != "" {
+		if err := json.Unmarshal([]byte(*transformNameMapping), &job.TransformNameMapping); err != nil {
+			return nil, fmt.Errorf("invalid --transform_name_mapping %v: %v", *transformNameMapping, err)
+		}
+	}
 	printJob(ctx, job)
 
+	if *templateLocation != "" {
+		return nil, writeTemplate(ctx, p, job, *templateLocation)
+	}
 	if *dryRun {
 		log.Info(ctx, "Dry-run: not submitting job!")
-		return nil
+		return nil, nil
 	}
 
 	// (4) Submit job.
 
 	client, err := newClient(ctx, *endpoint)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	upd, err := client.Projects.Locations.Jobs.Create(project, *region, job).Do()
+	if *update {
+		if job.ReplaceJobId, err = lookupJobID(ctx, client, project, *region, jobName); err != nil {
+			return nil, err
+		}
+		log.Infof(ctx, "Updating job: %v", job.ReplaceJobId)
+	}
+	upd, err := submitJob(ctx, client, project, *region, job, *submitRetries, *submitRetryDelay)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Infof(ctx, "Submitted job: %v", upd.Id)
@@ -237,38 +289,26 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 	}
 	log.Infof(ctx, "Logs: https://console.cloud.google.com/logs/viewer?project=%v&resource=dataflow_step%%2Fjob_id%%2F%v", project, upd.Id)
 
+	res := newResult(client, project, *region, upd.Id, job.Steps)
 	if *jobopts.Async {
-		return nil
+		return res, nil
 	}
+	if err := res.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
 
-	time.Sleep(1 * time.Minute)
-	for {
-		j, err := client.Projects.Locations.Jobs.Get(project, *region, upd.Id).Do()
-		if err != nil {
-			return fmt.Errorf("failed to get job: %v", err)
-		}
-
-		switch j.CurrentState {
-		case "JOB_STATE_DONE":
-			log.Info(ctx, "Job succeeded!")
-			return nil
-
-		case "JOB_STATE_CANCELLED":
-			log.Info(ctx, "Job cancelled")
-			return nil
-
-		case "JOB_STATE_FAILED":
-			return fmt.Errorf("job %s failed", upd.Id)
-
-		case "JOB_STATE_RUNNING":
-			log.Info(ctx, "Job still running ...")
-
-		default:
-			log.Infof(ctx, "Job state: %v ...", j.CurrentState)
+// unbounded returns true iff the pipeline has unbounded PCollections.
+func unbounded(edges []*graph.MultiEdge) bool {
+	for _, edge := range edges {
+		for _, out := range edge.Output {
+			if !out.To.Bounded() {
+				return true
+			}
 		}
-
-		time.Sleep(30 * time.Second)
 	}
+	return false
 }
 
 // stageModel uploads the pipeline model to GCS as a unique object.
@@ -330,9 +370,15 @@ func findPipelineFlags() []*displayData {
 
 	// TODO(herohde) 2/15/2017: decide if we want all set flags.
 	flag.Visit(func(f *flag.Flag) {
-		ret = append(ret, newDisplayData(f.Name, "", "flag", f.Value.(flag.Getter).Get()))
+		if g, ok := f.Value.(flag.Getter); ok {
+			ret = append(ret, newDisplayData(f.Name, "", "flag", g.Get()))
+		} else {
+			ret = append(ret, newDisplayData(f.Name, "", "flag", f.Value.String()))
+		}
 	})
-
+	for _, item := range buildinfo.Get().DisplayData() {
+		ret = append(ret, newDisplayData(item.Key, item.Label, "build", item.Value))
+	}
 	return ret
 }
 
@@ -376,3 +422,18 @@ func gcsRecorderHook(opts []string) perf.CaptureHook {
 		return gcsx.WriteObject(client, bucket, path.Join(prefix, spec), r)
 	}
 }
+
+// gcsProfileUploader uploads worker profiles to GCS.
+type gcsProfileUploader struct{}
+
+func (gcsProfileUploader) Upload(ctx context.Context, location, name string, data []byte) error {
+	bucket, prefix, err := gcsx.ParseObject(location)
+	if err != nil {
+		return err
+	}
+	client, err := gcsx.NewClient(ctx, storage.DevstorageReadWriteScope)
+	if err != nil {
+		return fmt.Errorf("couldn't establish GCS client: %v", err)
+	}
+	return gcsx.WriteObject(client, bucket, path.Join(prefix, name), bytes.NewReader(data))
+}
@@ -18,6 +18,7 @@ package dataflow
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
@@ -164,11 +165,17 @@ type displayData struct {
 }
 
 func findDisplayDataType(value interface{}) (string, interface{}) {
-	switch value.(type) {
+	switch v := value.(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
 		return "INTEGER", value
+	case float32, float64:
+		return "FLOAT", value
 	case bool:
 		return "BOOLEAN", value
+	case time.Time:
+		return "TIMESTAMP", v.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		return "DURATION", v.Nanoseconds() / int64(time.Millisecond)
 	case string:
 		return "STRING", value
 	default:
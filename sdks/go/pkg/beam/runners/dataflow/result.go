@@ -0,0 +1,228 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+// result is the result of a submitted Dataflow job.
+type result struct {
+	client                 *df.Service
+	project, region, jobID string
+	steps                  map[string]string // step name -> user name
+	info                   buildinfo.Info
+}
+
+// newResult returns the result of the submitted job with the given id and
+// steps.
+func newResult(client *df.Service, project, region, jobID string, list []*df.Step) *result {
+	steps := make(map[string]string)
+	for _, step := range list {
+		var prop properties
+		if err := json.Unmarshal(step.Properties, &prop); err == nil && prop.UserName != "" {
+			steps[step.Name] = prop.UserName
+		}
+	}
+	return &result{client: client, project: project, region: region, jobID: jobID, steps: steps, info: buildinfo.Get()}
+}
+
+func (r *result) JobID() string {
+	return r.jobID
+}
+
+func (r *result) State(ctx context.Context) (beam.JobState, error) {
+	j, err := r.client.Projects.Locations.Jobs.Get(r.project, r.region, r.jobID).Context(ctx).Do()
+	if err != nil {
+		return beam.JobUnknown, fmt.Errorf("failed to get job: %v", err)
+	}
+	return jobState(j.CurrentState), nil
+}
+
+// Wait polls the state of the job until it is finished.
+func (r *result) Wait(ctx context.Context) error {
+	delay := 1 * time.Minute
+	for {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay = 30 * time.Second
+
+		j, err := r.client.Projects.Locations.Jobs.Get(r.project, r.region, r.jobID).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to get job: %v", err)
+		}
+
+		switch j.CurrentState {
+		case "JOB_STATE_DONE":
+			log.Info(ctx, "Job succeeded!")
+			return nil
+
+		case "JOB_STATE_CANCELLED":
+			log.Info(ctx, "Job cancelled")
+			return nil
+
+		case "JOB_STATE_DRAINED":
+			log.Info(ctx, "Job drained")
+			return nil
+
+		case "JOB_STATE_UPDATED":
+			log.Info(ctx, "Job replaced by an update")
+			return nil
+
+		case "JOB_STATE_FAILED":
+			return fmt.Errorf("job %s failed", r.jobID)
+
+		case "JOB_STATE_RUNNING":
+			log.Info(ctx, "Job still running ...")
+
+		default:
+			log.Infof(ctx, "Job state: %v ...", j.CurrentState)
+		}
+	}
+}
+
+// Cancel requests the job to be cancelled.
+func (r *result) Cancel(ctx context.Context) error {
+	return r.requestState(ctx, "JOB_STATE_CANCELLED")
+}
+
+// Drain requests the job to be drained. Only streaming jobs can be drained.
+func (r *result) Drain(ctx context.Context) error {
+	return r.requestState(ctx, "JOB_STATE_DRAINED")
+}
+
+// requestState requests the job to transition to the given state.
+func (r *result) requestState(ctx context.Context, state string) error {
+	job := &df.Job{RequestedState: state}
+	if _, err := r.client.Projects.Locations.Jobs.Update(r.project, r.region, r.jobID, job).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to request state %v for job %v: %v", state, r.jobID, err)
+	}
+	log.Infof(ctx, "Requested state %v for job %v", state, r.jobID)
+	return nil
+}
+
+// Metrics returns the committed user metrics of the job. Metrics are keyed by
+// the user name of their step, as for other runners.
+func (r *result) Metrics(ctx context.Context) (*metrics.ResultSet, error) {
+	resp, err := r.client.Projects.Locations.Jobs.GetMetrics(r.project, r.region, r.jobID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics of job %v: %v", r.jobID, err)
+	}
+
+	var ret []metrics.Result
+	for _, m := range resp.Metrics {
+		if res, ok := r.metricResult(m); ok {
+			ret = append(ret, res)
+		}
+	}
+	return metrics.NewResultSet(ret), nil
+}
+
+func (r *result) BuildInfo() buildinfo.Info {
+	return r.info
+}
+
+// metricResult converts a committed user metric update to a result.
+func (r *result) metricResult(m *df.MetricUpdate) (metrics.Result, bool) {
+	if m.Name == nil || m.Name.Origin != "user" {
+		return metrics.Result{}, false
+	}
+	if _, tentative := m.Name.Context["tentative"]; tentative {
+		return metrics.Result{}, false // attempted, not committed
+	}
+
+	step := m.Name.Context["step"]
+	if name, ok := r.steps[step]; ok {
+		step = name
+	}
+	ret := metrics.Result{
+		PTransform: step,
+		Namespace:  m.Name.Context["namespace"],
+		Name:       m.Name.Name,
+	}
+
+	switch {
+	case m.Distribution != nil:
+		d, ok := m.Distribution.(map[string]interface{})
+		if !ok {
+			return metrics.Result{}, false
+		}
+		ret.Kind = metrics.DistributionKind
+		ret.Count = toInt64(d["count"])
+		ret.Sum = toInt64(d["sum"])
+		ret.Min = toInt64(d["min"])
+		ret.Max = toInt64(d["max"])
+
+	case m.Gauge != nil:
+		ret.Kind = metrics.GaugeKind
+		ret.Value = toInt64(m.Gauge)
+		ret.Timestamp, _ = time.Parse(time.RFC3339, m.UpdateTime)
+
+	case m.Scalar != nil:
+		ret.Kind = metrics.CounterKind
+		ret.Value = toInt64(m.Scalar)
+
+	default:
+		return metrics.Result{}, false
+	}
+	return ret, true
+}
+
+// toInt64 converts a numeric value of a metric update, which may be encoded
+// as a JSON number or string, to an int64.
+func toInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	default:
+		return 0
+	}
+}
+
+// jobState converts a Dataflow job state to a beam.JobState.
+func jobState(s string) beam.JobState {
+	switch s {
+	case "JOB_STATE_PENDING", "JOB_STATE_QUEUED", "JOB_STATE_RUNNING", "JOB_STATE_DRAINING", "JOB_STATE_CANCELLING":
+		return beam.JobRunning
+	case "JOB_STATE_DONE", "JOB_STATE_DRAINED", "JOB_STATE_UPDATED":
+		return beam.JobDone
+	case "JOB_STATE_FAILED":
+		return beam.JobFailed
+	case "JOB_STATE_CANCELLED":
+		return beam.JobCancelled
+	default:
+		return beam.JobUnknown
+	}
+}
@@ -0,0 +1,91 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataflow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+func TestMetricResult(t *testing.T) {
+	r := newResult(nil, "project", "region", "job", []*df.Step{
+		{Name: "s2", Properties: newMsg(properties{UserName: "main.extractFn"})},
+	})
+	user := func(step, name string, context ...string) *df.MetricStructuredName {
+		ctx := map[string]string{"step": step, "namespace": "ns"}
+		for i := 0; i+1 < len(context); i += 2 {
+			ctx[context[i]] = context[i+1]
+		}
+		return &df.MetricStructuredName{Origin: "user", Name: name, Context: ctx}
+	}
+
+	tests := []struct {
+		name string
+		m    *df.MetricUpdate
+		want *metrics.Result
+	}{
+		{
+			name: "counter",
+			m:    &df.MetricUpdate{Name: user("s2", "words"), Scalar: float64(42)},
+			want: &metrics.Result{PTransform: "main.extractFn", Namespace: "ns", Name: "words", Kind: metrics.CounterKind, Value: 42},
+		},
+		{
+			name: "distribution",
+			m: &df.MetricUpdate{Name: user("s3", "lengths"), Distribution: map[string]interface{}{
+				"count": float64(3), "sum": "12", "min": float64(1), "max": float64(7),
+			}},
+			want: &metrics.Result{PTransform: "s3", Namespace: "ns", Name: "lengths", Kind: metrics.DistributionKind, Count: 3, Sum: 12, Min: 1, Max: 7},
+		},
+		{
+			name: "tentative",
+			m:    &df.MetricUpdate{Name: user("s2", "words", "tentative", "true"), Scalar: float64(43)},
+		},
+		{
+			name: "system",
+			m:    &df.MetricUpdate{Name: &df.MetricStructuredName{Origin: "dataflow/v1b3", Name: "ElementCount"}, Scalar: float64(5)},
+		},
+	}
+	for _, test := range tests {
+		got, ok := r.metricResult(test.m)
+		if test.want == nil {
+			if ok {
+				t.Errorf("metricResult(%v) = %+v, want none", test.name, got)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, *test.want) {
+			t.Errorf("metricResult(%v) = %+v, %v, want %+v", test.name, got, ok, *test.want)
+		}
+	}
+}
+
+func TestJobState(t *testing.T) {
+	for state, want := range map[string]string{
+		"JOB_STATE_RUNNING":   "RUNNING",
+		"JOB_STATE_DRAINING":  "RUNNING",
+		"JOB_STATE_DONE":      "DONE",
+		"JOB_STATE_FAILED":    "FAILED",
+		"JOB_STATE_CANCELLED": "CANCELLED",
+		"JOB_STATE_UNKNOWN":   "UNKNOWN",
+	} {
+		if got := jobState(state); string(got) != want {
+			t.Errorf("jobState(%v) = %v, want %v", state, got, want)
+		}
+	}
+}
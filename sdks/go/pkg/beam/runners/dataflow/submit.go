@@ -0,0 +1,167 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataflow
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	df "google.golang.org/api/dataflow/v1b3"
+	"google.golang.org/api/googleapi"
+)
+
+// newClientRequestID returns a unique identifier for a job submission. Dataflow
+// treats submissions with the same job name and client request ID as the same
+// request, so retries of a submission with the same ID never create duplicate
+// jobs.
+func newClientRequestID() string {
+	var b [4]byte
+	rand.Read(b[:])
+	return fmt.Sprintf("%v-%x", time.Now().UTC().Format("20060102150405"), b)
+}
+
+// submitJob submits the job to Dataflow, retrying transient failures up to
+// the given number of times with exponential backoff. The job must have a
+// client request ID set for the retries to be idempotent: if an earlier
+// attempt reached the service, the retry is rejected as a conflict and the
+// already-created job is returned instead.
+func submitJob(ctx context.Context, client *df.Service, project, region string, job *df.Job, retries int, delay time.Duration) (*df.Job, error) {
+	create := func() (*df.Job, error) {
+		return client.Projects.Locations.Jobs.Create(project, region, job).Context(ctx).Do()
+	}
+	lookup := func() (*df.Job, error) {
+		var ret *df.Job
+		err := client.Projects.Locations.Jobs.List(project, region).Filter("ACTIVE").View("JOB_VIEW_ALL").Pages(ctx, func(resp *df.ListJobsResponse) error {
+			for _, j := range resp.Jobs {
+				if j.Name == job.Name && j.ClientRequestId == job.ClientRequestId {
+					ret = j
+				}
+			}
+			return nil
+		})
+		return ret, err
+	}
+	return retrySubmit(ctx, create, lookup, retries, delay)
+}
+
+// lookupJobID returns the id of the active job of the given name, which
+// is replaced by an update.
+func lookupJobID(ctx context.Context, client *df.Service, project, region, name string) (string, error) {
+	var id string
+	err := client.Projects.Locations.Jobs.List(project, region).Filter("ACTIVE").Pages(ctx, func(resp *df.ListJobsResponse) error {
+		for _, j := range resp.Jobs {
+			if j.Name == name {
+				id = j.Id
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list jobs: %v", err)
+	}
+	if id == "" {
+		return "", fmt.Errorf("no running job named %v to update", name)
+	}
+	return id, nil
+}
+
+// retrySubmit calls create until it succeeds, fails permanently or runs out
+// of retries. If a retry conflicts with an existing job, lookup is used to
+// find the job created by an earlier attempt, if any.
+func retrySubmit(ctx context.Context, create, lookup func() (*df.Job, error), retries int, delay time.Duration) (*df.Job, error) {
+	for attempt := 0; ; attempt++ {
+		upd, err := create()
+		if err == nil {
+			return upd, nil
+		}
+		if attempt > 0 && isConflict(err) {
+			if existing, lerr := lookup(); lerr == nil && existing != nil {
+				log.Infof(ctx, "Job was created by an earlier submission attempt: %v", existing.Id)
+				return existing, nil
+			}
+		}
+		if attempt >= retries || !isTransient(err) {
+			return nil, describeSubmitError(err)
+		}
+
+		log.Warnf(ctx, "Job submission failed (attempt %v of %v), retrying in %v: %v", attempt+1, retries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func isConflict(err error) bool {
+	e, ok := err.(*googleapi.Error)
+	return ok && e.Code == http.StatusConflict
+}
+
+// isTransient returns true iff the submission error is likely to go away
+// on retry, such as a server-side failure, rate limiting or a network error.
+func isTransient(err error) bool {
+	if e, ok := err.(*googleapi.Error); ok {
+		switch e.Code {
+		case http.StatusTooManyRequests:
+			// Rate limiting is transient, but exhausted quota is not.
+			return !isQuotaError(e)
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+func isQuotaError(e *googleapi.Error) bool {
+	for _, item := range e.Errors {
+		if strings.Contains(strings.ToLower(item.Reason), "quota") {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(e.Message), "quota")
+}
+
+// describeSubmitError adds remediation hints to common, non-transient
+// submission failures.
+func describeSubmitError(err error) error {
+	e, ok := err.(*googleapi.Error)
+	if !ok {
+		return fmt.Errorf("failed to submit job: %v", err)
+	}
+	switch {
+	case e.Code == http.StatusUnauthorized:
+		return fmt.Errorf("failed to submit job: %v. Check that the application default credentials are valid, e.g. with 'gcloud auth application-default login'", err)
+	case e.Code == http.StatusForbidden:
+		return fmt.Errorf("failed to submit job: %v. Check that the Dataflow API is enabled for the project and that the credentials have the Dataflow Developer role", err)
+	case e.Code == http.StatusConflict:
+		return fmt.Errorf("failed to submit job: %v. A job with the same name is already running; use a different --job_name", err)
+	case isQuotaError(e):
+		return fmt.Errorf("failed to submit job: %v. The project is out of quota; request an increase or reduce --num_workers", err)
+	default:
+		return fmt.Errorf("failed to submit job: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataflow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	df "google.golang.org/api/dataflow/v1b3"
+	"google.golang.org/api/googleapi"
+)
+
+func TestRetrySubmit(t *testing.T) {
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable, Message: "backend unavailable"}
+	forbidden := &googleapi.Error{Code: http.StatusForbidden, Message: "permission denied"}
+	quota := &googleapi.Error{Code: http.StatusTooManyRequests, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}
+	conflict := &googleapi.Error{Code: http.StatusConflict, Message: "already exists"}
+	created := &df.Job{Id: "created"}
+
+	tests := []struct {
+		name     string
+		errs     []error
+		existing *df.Job
+		retries  int
+		id       string
+		calls    int
+		hint     string
+	}{
+		{"success", nil, nil, 3, "job", 1, ""},
+		{"transient", []error{unavailable, unavailable}, nil, 3, "job", 3, ""},
+		{"exhausted", []error{unavailable, unavailable, unavailable}, nil, 2, "", 3, "backend unavailable"},
+		{"permission", []error{forbidden}, nil, 3, "", 1, "Dataflow Developer role"},
+		{"quota", []error{quota}, nil, 3, "", 1, "out of quota"},
+		{"retried conflict", []error{unavailable, conflict}, created, 3, "created", 2, ""},
+		{"first conflict", []error{conflict}, created, 3, "", 1, "--job_name"},
+		{"unknown", []error{errors.New("bad request")}, nil, 3, "", 1, "failed to submit job"},
+	}
+
+	for _, test := range tests {
+		calls := 0
+		create := func() (*df.Job, error) {
+			calls++
+			if calls <= len(test.errs) {
+				return nil, test.errs[calls-1]
+			}
+			return &df.Job{Id: "job"}, nil
+		}
+		lookup := func() (*df.Job, error) {
+			return test.existing, nil
+		}
+
+		job, err := retrySubmit(context.Background(), create, lookup, test.retries, 0)
+		if calls != test.calls {
+			t.Errorf("%v: retrySubmit made %v attempts, want %v", test.name, calls, test.calls)
+		}
+		if test.hint != "" {
+			if err == nil || !strings.Contains(err.Error(), test.hint) {
+				t.Errorf("%v: retrySubmit failed with %v, want error containing %q", test.name, err, test.hint)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: retrySubmit failed: %v", test.name, err)
+			continue
+		}
+		if job.Id != test.id {
+			t.Errorf("%v: retrySubmit = %v, want %v", test.name, job.Id, test.id)
+		}
+	}
+}
+
+func TestNewClientRequestID(t *testing.T) {
+	a, b := newClientRequestID(), newClientRequestID()
+	if a == b {
+		t.Errorf("newClientRequestID() returned %v twice, want unique IDs", a)
+	}
+}
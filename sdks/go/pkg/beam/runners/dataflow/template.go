@@ -0,0 +1,145 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/go/pkg/beam/util/gcsx"
+	df "google.golang.org/api/dataflow/v1b3"
+	"google.golang.org/api/storage/v1"
+)
+
+var (
+	templateLocation     = flag.String("template_location", "", "GCS location to stage the job as a classic template to, instead of running it, such as gs://bucket/templates/job (optional).")
+	flexTemplateLocation = flag.String("flex_template_location", "", "GCS location to write a Flex Template spec to, instead of running the job, such as gs://bucket/templates/job.json (optional). Requires --flex_template_image.")
+	flexTemplateImage    = flag.String("flex_template_image", "", "Container image with the pipeline binary and the Flex Template launcher, for --flex_template_location.")
+)
+
+// templateMetadata describes a template and its parameters to the operators
+// that launch it.
+type templateMetadata struct {
+	Name       string              `json:"name"`
+	Parameters []templateParameter `json:"parameters"`
+}
+
+type templateParameter struct {
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	HelpText   string `json:"helpText"`
+	IsOptional bool   `json:"isOptional,omitempty"`
+}
+
+// flexTemplateSpec is the spec of a Flex Template. The launcher in the image
+// constructs and submits the pipeline with the parameters of each launch.
+type flexTemplateSpec struct {
+	Image    string           `json:"image"`
+	SdkInfo  sdkInfo          `json:"sdkInfo"`
+	Metadata templateMetadata `json:"metadata"`
+}
+
+type sdkInfo struct {
+	Language string `json:"language"`
+}
+
+// newTemplateMetadata returns the metadata of a template with the declared
+// runtime parameters of the pipeline. Parameters with a default are optional.
+func newTemplateMetadata(name string, p *beam.Pipeline) templateMetadata {
+	md := templateMetadata{Name: name, Parameters: []templateParameter{}}
+
+	params := p.RuntimeParameters()
+	var names []string
+	for param := range params {
+		names = append(names, param)
+	}
+	sort.Strings(names)
+
+	for _, param := range names {
+		def := params[param]
+		help := fmt.Sprintf("Runtime parameter %v.", param)
+		if def != "" {
+			help = fmt.Sprintf("Runtime parameter %v. Defaults to %q.", param, def)
+		}
+		md.Parameters = append(md.Parameters, templateParameter{
+			Name:       param,
+			Label:      param,
+			HelpText:   help,
+			IsOptional: def != "",
+		})
+	}
+	return md
+}
+
+// writeTemplate stages the job as a classic template at the given location.
+// The metadata is written next to it, with the suffix "_metadata". Runs of
+// the template set the runtime parameters of the pipeline.
+func writeTemplate(ctx context.Context, p *beam.Pipeline, job *df.Job, location string) error {
+	if err := writeJSON(ctx, location, job); err != nil {
+		return fmt.Errorf("failed to write template: %v", err)
+	}
+	if err := writeJSON(ctx, location+"_metadata", newTemplateMetadata(job.Name, p)); err != nil {
+		return fmt.Errorf("failed to write template metadata: %v", err)
+	}
+	log.Infof(ctx, "Template staged at %v", location)
+	return nil
+}
+
+// writeFlexTemplate writes the spec of a Flex Template with the given image
+// to the given location.
+func writeFlexTemplate(ctx context.Context, p *beam.Pipeline, name, image, location string) error {
+	if image == "" {
+		return errors.New("no Flex Template image specified. Use --flex_template_image=<image>")
+	}
+	spec := flexTemplateSpec{
+		Image:    image,
+		SdkInfo:  sdkInfo{Language: "GO"},
+		Metadata: newTemplateMetadata(name, p),
+	}
+	if err := writeJSON(ctx, location, spec); err != nil {
+		return fmt.Errorf("failed to write Flex Template spec: %v", err)
+	}
+	log.Infof(ctx, "Flex Template spec written to %v", location)
+	return nil
+}
+
+func writeJSON(ctx context.Context, location string, v interface{}) error {
+	bucket, obj, err := gcsx.ParseObject(location)
+	if err != nil {
+		return fmt.Errorf("invalid location %v: %v", location, err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		log.Infof(ctx, "Dry-run: not writing %v:\n%s", location, data)
+		return nil
+	}
+
+	client, err := gcsx.NewClient(ctx, storage.DevstorageReadWriteScope)
+	if err != nil {
+		return err
+	}
+	return gcsx.WriteObject(client, bucket, obj, bytes.NewReader(data))
+}
@@ -0,0 +1,50 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataflow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+)
+
+func TestNewTemplateMetadata(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	beam.RuntimeValue(s, "df_test_input", "")
+	beam.RuntimeValue(s, "df_test_output", "gs://bucket/out")
+	beam.RuntimeValue(s, "df_test_limit", int64(0))
+
+	md := newTemplateMetadata("job", p)
+	if md.Name != "job" {
+		t.Errorf("name = %v, want job", md.Name)
+	}
+
+	optional := make(map[string]bool)
+	for _, p := range md.Parameters {
+		optional[p.Name] = p.IsOptional
+	}
+	want := map[string]bool{"df_test_input": false, "df_test_output": true, "df_test_limit": false}
+	if !reflect.DeepEqual(optional, want) {
+		t.Errorf("parameters = %v, want %v", optional, want)
+	}
+
+	// Runtime parameters are declared on their pipeline only.
+	if md := newTemplateMetadata("other", beam.NewPipeline()); len(md.Parameters) != 0 {
+		t.Errorf("parameters of empty pipeline = %v, want none", md.Parameters)
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"bytes"
 	"fmt"
 	"net/url"
-	"path"
 	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
@@ -30,6 +29,7 @@ import (
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx/v1"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/protox"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
 	pubsub_v1 "github.com/apache/beam/sdks/go/pkg/beam/io/pubsubio/v1"
 	rnapi_pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
 	"github.com/golang/protobuf/proto"
@@ -41,6 +41,7 @@ const (
 	parDoKind   = "ParallelDo"
 	flattenKind = "Flatten"
 	gbkKind     = "GroupByKey"
+	windowKind  = "Bucket"
 
 	sideInputKind = "CollectionToSingleton"
 
@@ -66,11 +67,12 @@ func translate(edges []*graph.MultiEdge) ([]*df.Step, error) {
 	// preserve the creation order.
 
 	nodes := translateNodes(edges)
+	names := translateNames(edges)
 
 	var steps []*df.Step
 	for _, edge := range edges {
 		if edge.Op == graph.CoGBK && len(edge.Input) > 1 {
-			expanded, err := expandCoGBK(nodes, edge)
+			expanded, err := expandCoGBK(nodes, names[edge], edge)
 			if err != nil {
 				return nil, err
 			}
@@ -82,6 +84,7 @@ func translate(edges []*graph.MultiEdge) ([]*df.Step, error) {
 		if err != nil {
 			return nil, err
 		}
+		prop.UserName = names[edge]
 
 		if edge.Op == graph.Flatten {
 			for _, in := range edge.Input {
@@ -96,7 +99,7 @@ func translate(edges []*graph.MultiEdge) ([]*df.Step, error) {
 				// be before the present one.
 
 				ref := nodes[edge.Input[i].From.ID()]
-				c, err := encodeCoderRef(edge.Input[i].From.Coder)
+				c, err := encodeCoderRef(edge.Input[i].From.Coder, edge.Input[i].From.Window())
 				if err != nil {
 					return nil, err
 				}
@@ -111,7 +114,7 @@ func translate(edges []*graph.MultiEdge) ([]*df.Step, error) {
 							OutputName: "out",
 							Encoding:   graphx.WrapExtraWindowedValue(c),
 						}},
-						UserName: buildName(edge.Scope(), "AsView"),
+						UserName: fmt.Sprintf("%v_view%v", names[edge], i),
 					}),
 				}
 				steps = append(steps, side)
@@ -122,7 +125,7 @@ func translate(edges []*graph.MultiEdge) ([]*df.Step, error) {
 
 		for _, out := range edge.Output {
 			ref := nodes[out.To.ID()]
-			coder, err := encodeCoderRef(out.To.Coder)
+			coder, err := encodeCoderRef(out.To.Coder, out.To.Window())
 			if err != nil {
 				return nil, err
 			}
@@ -137,7 +140,7 @@ func translate(edges []*graph.MultiEdge) ([]*df.Step, error) {
 			// Dataflow seems to require at least one output. We insert
 			// a bogus one (named "bogus") and remove it in the harness.
 
-			coder, err := encodeCoderRef(edge.Input[0].From.Coder)
+			coder, err := encodeCoderRef(edge.Input[0].From.Coder, edge.Input[0].From.Window())
 			if err != nil {
 				return nil, err
 			}
@@ -167,15 +170,16 @@ func translate(edges []*graph.MultiEdge) ([]*df.Step, error) {
 	return steps, nil
 }
 
-func expandCoGBK(nodes map[int]*outputReference, edge *graph.MultiEdge) ([]*df.Step, error) {
+func expandCoGBK(nodes map[int]*outputReference, name string, edge *graph.MultiEdge) ([]*df.Step, error) {
 	// TODO(BEAM-490): replace once CoGBK is a primitive. For now, we have to translate
 	// CoGBK with multiple PCollections as described in graphx/cogbk.go.
 
-	kvCoder, err := encodeCoderRef(graphx.MakeKVUnionCoder(edge))
+	w := edge.Input[0].From.Window()
+	kvCoder, err := encodeCoderRef(graphx.MakeKVUnionCoder(edge), w)
 	if err != nil {
 		return nil, err
 	}
-	gbkCoder, err := encodeCoderRef(graphx.MakeGBKUnionCoder(edge))
+	gbkCoder, err := encodeCoderRef(graphx.MakeGBKUnionCoder(edge), w)
 	if err != nil {
 		return nil, err
 	}
@@ -203,7 +207,7 @@ func expandCoGBK(nodes map[int]*outputReference, edge *graph.MultiEdge) ([]*df.S
 					OutputName: out.OutputName,
 					Encoding:   kvCoder,
 				}},
-				UserName: buildName(edge.Scope(), injectID),
+				UserName: fmt.Sprintf("%v_inject%v", name, i),
 			}),
 		}
 
@@ -226,7 +230,7 @@ func expandCoGBK(nodes map[int]*outputReference, edge *graph.MultiEdge) ([]*df.S
 				OutputName: out.OutputName,
 				Encoding:   kvCoder,
 			}},
-			UserName: buildName(edge.Scope(), flattenID),
+			UserName: fmt.Sprintf("%v_flatten", name),
 		}),
 	}
 	steps = append(steps, flatten)
@@ -236,8 +240,7 @@ func expandCoGBK(nodes map[int]*outputReference, edge *graph.MultiEdge) ([]*df.S
 	gbkID := fmt.Sprintf("%v_expand", edge.ID())
 	gbkOut := newOutputReference(gbkID, "out")
 
-	w := edge.Input[0].From.Window()
-	sfn, err := encodeSerializedFn(translateWindow(w))
+	sfn, err := translateWindow(w)
 	if err != nil {
 		return nil, err
 	}
@@ -252,7 +255,7 @@ func expandCoGBK(nodes map[int]*outputReference, edge *graph.MultiEdge) ([]*df.S
 				OutputName: gbkOut.OutputName,
 				Encoding:   gbkCoder,
 			}},
-			UserName:                buildName(edge.Scope(), "group"),
+			UserName:                name,
 			DisallowCombinerLifting: true,
 			SerializedFn:            sfn,
 		}),
@@ -262,7 +265,7 @@ func expandCoGBK(nodes map[int]*outputReference, edge *graph.MultiEdge) ([]*df.S
 	// Expand
 
 	ref := nodes[edge.Output[0].To.ID()]
-	coder, err := encodeCoderRef(edge.Output[0].To.Coder)
+	coder, err := encodeCoderRef(edge.Output[0].To.Coder, edge.Output[0].To.Window())
 	if err != nil {
 		return nil, err
 	}
@@ -277,7 +280,7 @@ func expandCoGBK(nodes map[int]*outputReference, edge *graph.MultiEdge) ([]*df.S
 				OutputName: ref.OutputName,
 				Encoding:   coder,
 			}},
-			UserName: buildName(edge.Scope(), "expand"),
+			UserName: fmt.Sprintf("%v_expand", name),
 			SerializedFn: makeSerializedFnPayload(&v1.TransformPayload{
 				Urn: graphx.URNExpand,
 			}),
@@ -309,6 +312,16 @@ func translateNodes(edges []*graph.MultiEdge) map[int]*outputReference {
 // TODO(herohde) 2/15/2017: user names encode composite names via "/"-separation.
 // We'll need to ensure that scopes are uniquely named.
 
+// translateDisplayData translates the display data of an edge, using the
+// transform name as namespace.
+func translateDisplayData(edge *graph.MultiEdge) []displayData {
+	var ret []displayData
+	for _, item := range edge.DisplayData {
+		ret = append(ret, *newDisplayData(item.Key, item.Label, edge.Name(), item.Value))
+	}
+	return ret
+}
+
 // translateEdge translates part of a MultiEdge to the Dataflow kind and
 // step-specific properties. We can't conveniently return a Step, because we
 // need to add more properties and the (partly-encoded) Step form prevents such
@@ -320,7 +333,7 @@ func translateEdge(edge *graph.MultiEdge) (string, properties, error) {
 		// URL Query-escaped windowed _unnested_ value. It is read back in
 		// a nested context at runtime.
 		var buf bytes.Buffer
-		if err := exec.EncodeWindowedValueHeader(beam.EventTime(time.Now()), &buf); err != nil {
+		if err := exec.EncodeWindowedValueHeader(exec.MakeWindowEncoder(coder.NewGlobalWindow()), nil, beam.EventTime(time.Now()), &buf); err != nil {
 			return "", properties{}, err
 		}
 		value := string(append(buf.Bytes(), edge.Value...))
@@ -328,40 +341,35 @@ func translateEdge(edge *graph.MultiEdge) (string, properties, error) {
 		// log.Printf("Impulse data: %v", url.QueryEscape(value))
 
 		return impulseKind, properties{
-			UserName: buildName(edge.Scope(), "create"),
-			Element:  []string{url.QueryEscape(value)},
+			Element: []string{url.QueryEscape(value)},
 		}, nil
 
 	case graph.ParDo:
 		return parDoKind, properties{
-			UserName:     buildName(edge.Scope(), edge.DoFn.Name()),
 			SerializedFn: serializeFn(edge),
+			DisplayData:  translateDisplayData(edge),
 		}, nil
 
 	case graph.Combine:
 		// TODO(flaviocf) 8/08/2017: When combiners are supported, change "ParallelDo" to
 		// "CombineValues", encode accumulator coder and pass it as a property "Encoding".
 		return parDoKind, properties{
-			UserName:     buildName(edge.Scope(), edge.CombineFn.Name()),
 			SerializedFn: serializeFn(edge),
+			DisplayData:  translateDisplayData(edge),
 		}, nil
 
 	case graph.CoGBK:
-		w := edge.Input[0].From.Window()
-		sfn, err := encodeSerializedFn(translateWindow(w))
+		sfn, err := translateWindow(edge.Input[0].From.Window())
 		if err != nil {
 			return "", properties{}, err
 		}
 		return gbkKind, properties{
-			UserName:                buildName(edge.Scope(), "group"), // TODO: user-defined
 			DisallowCombinerLifting: true,
 			SerializedFn:            sfn,
 		}, nil
 
 	case graph.Flatten:
-		return flattenKind, properties{
-			UserName: buildName(edge.Scope(), "flatten"), // TODO: user-defined
-		}, nil
+		return flattenKind, properties{}, nil
 
 	case graph.External:
 		switch edge.Payload.URN {
@@ -373,7 +381,6 @@ func translateEdge(edge *graph.MultiEdge) (string, properties, error) {
 				return "", properties{}, fmt.Errorf("bad pubsub payload: %v", err)
 			}
 			prop := properties{
-				UserName:             buildName(edge.Scope(), fmt.Sprintf("%v", msg.Op)),
 				Format:               "pubsub",
 				PubSubTopic:          msg.GetTopic(),
 				PubSubSubscription:   msg.GetSubscription(),
@@ -390,7 +397,7 @@ func translateEdge(edge *graph.MultiEdge) (string, properties, error) {
 				return readKind, prop, nil
 
 			case pubsub_v1.PubSubPayload_WRITE:
-				c, _ := encodeCoderRef(coder.NewBytes())
+				c, _ := encodeCoderRef(coder.NewBytes(), edge.Input[0].From.Window())
 				prop.Encoding = c
 				return writeKind, prop, nil
 
@@ -402,6 +409,18 @@ func translateEdge(edge *graph.MultiEdge) (string, properties, error) {
 			return "", properties{}, fmt.Errorf("bad external urn: %v", edge.Payload.URN)
 		}
 
+	case graph.WindowInto:
+		if len(edge.Output) > 1 {
+			return "", properties{}, fmt.Errorf("late data output is not supported by Dataflow: %v", edge)
+		}
+		sfn, err := translateWindow(edge.Output[0].To.Window())
+		if err != nil {
+			return "", properties{}, err
+		}
+		return windowKind, properties{
+			SerializedFn: sfn,
+		}, nil
+
 	default:
 		return "", properties{}, fmt.Errorf("bad opcode: %v", edge)
 	}
@@ -424,28 +443,72 @@ func makeSerializedFnPayload(payload *v1.TransformPayload) string {
 	return protox.MustEncodeBase64(payload)
 }
 
-func encodeCoderRef(c *coder.Coder) (*graphx.CoderRef, error) {
-	// TODO(herohde) 3/16/2018: ensure windowed values for Dataflow
-	return graphx.EncodeCoderRef(coder.NewW(c, window.NewGlobalWindow()))
+// encodeCoderRef encodes the windowed value coder of a PCollection with the
+// given element coder and windowing strategy. Elements in non-global windows
+// are encoded with their interval windows, so that they keep their windows
+// across fusion boundaries.
+func encodeCoderRef(c *coder.Coder, w *window.Window) (*graphx.CoderRef, error) {
+	return graphx.EncodeCoderRef(coder.NewW(c, coder.NewWindowCoder(w)))
+}
+
+// translateNames computes the user names of the steps for the edges, as
+// composite names understood by the Dataflow UI. The names are derived from
+// the scope hierarchy, separated by "/", and the edge labels, if provided, or
+// else the function name or kind of transform. Conflicts within a scope are
+// resolved in order of construction, so the names are stable as long as the
+// structure of the pipeline is. Pipeline updates rely on that.
+func translateNames(edges []*graph.MultiEdge) map[*graph.MultiEdge]string {
+	tree := graphx.NewScopeTree(edges)
+	defaultNames(tree)
+	graphx.EnsureUniqueNames(tree)
+	graphx.QualifyNames(tree)
+
+	names := make(map[*graph.MultiEdge]string)
+	collectNames(tree, names)
+	return names
+}
+
+// defaultNames replaces the names of unlabelled edges with the short names
+// Dataflow has always used, so that existing jobs remain updatable.
+func defaultNames(tree *graphx.ScopeTree) {
+	for i, edge := range tree.Edges {
+		if edge.Edge.Label == "" {
+			tree.Edges[i].Name = defaultName(edge.Edge)
+		}
+	}
+	for _, s := range tree.Children {
+		defaultNames(s)
+	}
 }
 
-// buildName computes a Dataflow composite name understood by the Dataflow UI,
-// determined by the scope nesting. Dataflow simply uses "/" to separate
-// composite transforms, so we must remove them from the otherwise qualified
-// package names of DoFns, etc.
-func buildName(scope *graph.Scope, name string) string {
-	if scope.Parent == nil {
-		// Ignore "root" node in naming.
-		return path.Base(name)
+func defaultName(edge *graph.MultiEdge) string {
+	switch edge.Op {
+	case graph.Impulse:
+		return "create"
+	case graph.CoGBK:
+		return "group"
+	case graph.Flatten:
+		return "flatten"
+	case graph.External:
+		if edge.Payload.URN == pubsub_v1.PubSubPayloadURN {
+			var msg pubsub_v1.PubSubPayload
+			if err := proto.Unmarshal(edge.Payload.Data, &msg); err == nil {
+				return msg.Op.String()
+			}
+		}
+		return "external"
+	default:
+		return reflectx.ShortName(edge.Name())
 	}
-	return buildScopeName(scope) + "/" + path.Base(name)
 }
 
-func buildScopeName(scope *graph.Scope) string {
-	if scope.Parent.Parent == nil {
-		return scope.Label
+func collectNames(tree *graphx.ScopeTree, names map[*graph.MultiEdge]string) {
+	for _, edge := range tree.Edges {
+		names[edge.Edge] = edge.Name
+	}
+	for _, s := range tree.Children {
+		collectNames(s, names)
 	}
-	return buildScopeName(scope.Parent) + "/" + scope.Label
 }
 
 // stepID converts a MultiEdge ID, i, to a Step ID, "s"+i. The name has no
@@ -454,47 +517,22 @@ func stepID(id int) string {
 	return fmt.Sprintf("s%v", id)
 }
 
-func translateWindow(w *window.Window) proto.Message {
-	// TODO: The only windowing strategy we support is the global window.
-	if w.Kind() != window.GlobalWindow {
-		panic(fmt.Sprintf("Unsupported window type supplied: %v", w))
+// translateWindow translates a window into the serialized windowing strategy
+// of a GBK or WindowInto step, including its trigger, accumulation mode and
+// allowed lateness.
+func translateWindow(w *window.Window) (string, error) {
+	c := graphx.NewCoderMarshaller()
+	ws, err := graphx.MarshalWindowingStrategy(c, w)
+	if err != nil {
+		return "", fmt.Errorf("unsupported window %v: %v", w, err)
 	}
-	// We compute the fixed content of this message for use in workflows.
-	msg := rnapi_pb.MessageWithComponents{
-		Components: &rnapi_pb.Components{
-			Coders: map[string]*rnapi_pb.Coder{
-				"Coder": &rnapi_pb.Coder{
-					Spec: &rnapi_pb.SdkFunctionSpec{
-						Spec: &rnapi_pb.FunctionSpec{
-							Urn: "urn:beam:coders:global_window:0.1",
-						},
-					},
-				},
-			},
-		},
+	msg := &rnapi_pb.MessageWithComponents{
+		Components: &rnapi_pb.Components{Coders: c.Build()},
 		Root: &rnapi_pb.MessageWithComponents_WindowingStrategy{
-			WindowingStrategy: &rnapi_pb.WindowingStrategy{
-				WindowFn: &rnapi_pb.SdkFunctionSpec{
-					Spec: &rnapi_pb.FunctionSpec{
-						Urn: "beam:windowfn:global_windows:v0.1",
-					},
-				},
-				MergeStatus:      rnapi_pb.MergeStatus_NON_MERGING,
-				AccumulationMode: rnapi_pb.AccumulationMode_DISCARDING,
-				WindowCoderId:    "Coder",
-				Trigger: &rnapi_pb.Trigger{
-					Trigger: &rnapi_pb.Trigger_Default_{
-						Default: &rnapi_pb.Trigger_Default{},
-					},
-				},
-				OutputTime:      rnapi_pb.OutputTime_END_OF_WINDOW,
-				ClosingBehavior: rnapi_pb.ClosingBehavior_EMIT_IF_NONEMPTY,
-				AllowedLateness: 0,
-			},
+			WindowingStrategy: ws,
 		},
 	}
-
-	return &msg
+	return encodeSerializedFn(msg)
 }
 
 func encodeSerializedFn(in proto.Message) (string, error) {
@@ -0,0 +1,123 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataflow
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/protox"
+	rnapi_pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+)
+
+func TestTranslateWindowInto(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	ws := window.NewFixedWindows(time.Minute).WithTrigger(window.TriggerAfterCount(2))
+	beam.WindowInto(s, ws, beam.Create(s, 1, 2, 3))
+
+	edges, _, err := p.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	steps, err := translate(edges)
+	if err != nil {
+		t.Fatalf("translate failed: %v", err)
+	}
+
+	var prop properties
+	for _, step := range steps {
+		if step.Kind == windowKind {
+			if err := json.Unmarshal(step.Properties, &prop); err != nil {
+				t.Fatalf("bad properties: %v", err)
+			}
+		}
+	}
+	if prop.SerializedFn == "" {
+		t.Fatalf("translate produced no %v step: %v", windowKind, steps)
+	}
+
+	var msg rnapi_pb.MessageWithComponents
+	if err := protox.DecodeQueryEscaped(prop.SerializedFn, &msg); err != nil {
+		t.Fatalf("bad serialized windowing strategy: %v", err)
+	}
+	strategy := msg.GetWindowingStrategy()
+	if urn := strategy.GetWindowFn().GetSpec().GetUrn(); urn != graphx.URNFixedWindowsWindowFn {
+		t.Errorf("window fn = %v, want %v", urn, graphx.URNFixedWindowsWindowFn)
+	}
+	if n := strategy.GetTrigger().GetElementCount().GetElementCount(); n != 2 {
+		t.Errorf("trigger = %v, want element count of 2", strategy.GetTrigger())
+	}
+	if _, ok := msg.GetComponents().GetCoders()[strategy.GetWindowCoderId()]; !ok {
+		t.Errorf("window coder %v missing from components", strategy.GetWindowCoderId())
+	}
+}
+
+func TestTranslateWindowedCoder(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	beam.WindowInto(s, window.NewFixedWindows(time.Minute), beam.Create(s, 1, 2, 3))
+
+	edges, _, err := p.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	steps, err := translate(edges)
+	if err != nil {
+		t.Fatalf("translate failed: %v", err)
+	}
+
+	// Elements are globally windowed until the WindowInto step, after which
+	// they are encoded with their interval windows.
+
+	windows := make(map[string]string)
+	for _, step := range steps {
+		var prop properties
+		if err := json.Unmarshal(step.Properties, &prop); err != nil {
+			t.Fatalf("bad properties: %v", err)
+		}
+		for _, out := range prop.OutputInfo {
+			if out.Encoding.Type != graphx.WindowedValueType || len(out.Encoding.Components) != 2 {
+				t.Fatalf("encoding of %v = %v, want windowed value", step.Kind, out.Encoding)
+			}
+			windows[step.Kind] = out.Encoding.Components[1].Type
+		}
+	}
+	if w := windows[impulseKind]; w != graphx.GlobalWindowType {
+		t.Errorf("window of %v output = %v, want %v", impulseKind, w, graphx.GlobalWindowType)
+	}
+	if w := windows[windowKind]; w != graphx.IntervalWindowType {
+		t.Errorf("window of %v output = %v, want %v", windowKind, w, graphx.IntervalWindowType)
+	}
+}
+
+func TestTranslateLateData(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	beam.WindowIntoWithLateData(s, window.NewFixedWindows(time.Minute), beam.Create(s, 1, 2, 3))
+
+	edges, _, err := p.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if _, err := translate(edges); err == nil {
+		t.Errorf("translate succeeded, want error for late data output")
+	}
+}
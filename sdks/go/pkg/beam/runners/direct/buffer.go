@@ -54,6 +54,16 @@ func (n *buffer) ProcessElement(ctx context.Context, elm exec.FullValue, values
 	return nil
 }
 
+// Reserve grows the buffer for the given number of elements.
+func (n *buffer) Reserve(elms int) {
+	if cap(n.buf)-len(n.buf) >= elms {
+		return
+	}
+	buf := make([]exec.FullValue, len(n.buf), 2*len(n.buf)+elms)
+	copy(buf, n.buf)
+	n.buf = buf
+}
+
 func (n *buffer) FinishBundle(ctx context.Context) error {
 	n.done = true
 	return n.notify(ctx)
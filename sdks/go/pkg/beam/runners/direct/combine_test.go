@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/runners/direct"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+// keyedCountFn counts the values of each key, prefixed by the key.
+type keyedCountFn struct{}
+
+func (keyedCountFn) AddInput(a int, key string, v int) int { return a + 1 }
+func (keyedCountFn) MergeAccumulators(list []int) int {
+	var ret int
+	for _, a := range list {
+		ret += a
+	}
+	return ret
+}
+func (keyedCountFn) ExtractOutput(a int) string { return fmt.Sprintf("n=%v", a) }
+
+func sumInts(a, b int) int {
+	return a + b
+}
+
+func formatKV(k string, v beam.T) string {
+	return fmt.Sprintf("%v:%v", k, v)
+}
+
+func TestLiftedCombine(t *testing.T) {
+	tests := []struct {
+		fn  interface{}
+		exp []interface{}
+	}{
+		{sumInts, []interface{}{"a:6", "b:10"}},
+		{&keyedCountFn{}, []interface{}{"a:n=3", "b:n=1"}},
+	}
+
+	for _, test := range tests {
+		p, s, col := ptest.Create([]interface{}{1, 2, 3, 10})
+		kvs := beam.ParDo(s, func(v int) (string, int) {
+			if v < 10 {
+				return "a", v
+			}
+			return "b", v
+		}, col)
+		out := beam.CombinePerKey(s, test.fn, kvs)
+		passert.Equals(s, beam.ParDo(s, formatKV, out), test.exp...)
+
+		edges, _, err := p.Build()
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		plan, err := direct.Compile(edges)
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		if !strings.Contains(plan.String(), "LiftedCombine") {
+			t.Errorf("combine %T not lifted:\n%v", test.fn, plan)
+		}
+
+		if err := ptest.Run(p); err != nil {
+			t.Errorf("CombinePerKey(%T) failed: %v", test.fn, err)
+		}
+	}
+}
@@ -17,48 +17,123 @@ package direct
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"path"
+	"sync"
+	"sync/atomic"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
-	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/go/pkg/beam/options/jobopts"
 )
 
+// parallelism is the number of workers that process each parallel segment of
+// the pipeline.
+var parallelism = flag.Int("direct_parallelism", 1, "Number of goroutines that process elements in parallel in the direct runner (optional).")
+
+// planID is the id of the last compiled plan. Plans have distinct ids, such
+// that the metrics of concurrent jobs are separate.
+var planID int32
+
 func init() {
 	beam.RegisterRunner("direct", Execute)
 }
 
-// Execute runs the pipeline in-process.
-func Execute(ctx context.Context, p *beam.Pipeline) error {
+// Execute runs the pipeline in-process. Stateful DoFns keep their state in
+// memory, per key and window, and merge the state of merged windows. Combines
+// that solely consume a GroupByKey are lifted, such that values are partially
+// combined before grouping. Windowed values are grouped per window and emitted
+// in panes as determined by the trigger of the windowing strategy.
+//
+// The input of each ParDo is processed as a single bundle, while a simulated
+// watermark is tracked per stage. Event-time timers and triggers fire as the
+// watermark of their stage advances. Processing-time timers fire as the
+// processing time of a TestStream advances, or once the input is exhausted:
+//
+//   - An Impulse advances the watermark to the end of time once emitted.
+//   - The events of a TestStream are replayed in order, after the other
+//     inputs, advancing its watermark and processing time as scripted.
+//   - A splittable DoFn holds back the watermark at the watermark of the
+//     restrictions it checkpoints, which default to the element timestamp.
+//     Checkpoints are resumed once the input is exhausted, after their
+//     delay, such that unbounded sources advance the watermark as they go.
+//   - A Flatten holds back the watermark at the minimum of its inputs.
+//
+// With --direct_parallelism greater than 1, chains of ParDos are replicated
+// and process their input in bundles across that many goroutines. Each
+// replica has its own copy of the DoFn, as if deserialized. DoFns that cannot
+// be serialized are executed serially. The input of a stateful ParDo is
+// partitioned by key, such that the elements of each key are processed by a
+// single replica in the order they arrive. Pipelines with a TestStream are
+// executed serially.
+//
+// With --direct_bundle_retries greater than 0, the input of ParDos that are
+// neither stateful nor splittable is processed in bundles, which are retried
+// with exponential backoff if they fail, from --direct_retry_backoff up to
+// --direct_retry_max_backoff. The output and metrics of a failed bundle are
+// discarded and its DoFn is torn down, such that a retried bundle is
+// processed as if it never failed. Linear chains of such ParDos are retried
+// together, passing elements between them one at a time, such that only the
+// output of the chain is held back.
+//
+// With --direct_gbk_memory_mb greater than 0, GroupByKeys of globally
+// windowed input with the default trigger spill their values to disk in
+// sorted runs once they exceed that many MB, and merge the runs once their
+// input is exhausted.
+//
+// The job is finished once Execute returns, unless --async. An asynchronous
+// job is executed in the background, such that streaming tests can cancel or
+// drain it. Draining stops the replay of TestStreams and truncates the
+// restrictions of splittable DoFns that support it, as they are resumed. The
+// metrics of the result are those reported by the pipeline. Their progress
+// holds the elements processed and output by each ParDo.
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
 	log.Info(ctx, "Pipeline:")
 	log.Info(ctx, p)
 
 	edges, _, err := p.Build()
 	if err != nil {
-		return fmt.Errorf("invalid pipeline: %v", err)
+		return nil, fmt.Errorf("invalid pipeline: %v", err)
 	}
 	plan, err := Compile(edges)
 	if err != nil {
-		return fmt.Errorf("translation failed: %v", err)
+		return nil, fmt.Errorf("translation failed: %v", err)
 	}
 
-	if err = plan.Execute(ctx, "", nil); err != nil {
-		plan.Down(ctx) // ignore any teardown errors
-		return err
+	res := execute(ctx, plan)
+	if *jobopts.Async {
+		return res, nil
 	}
-	if err = plan.Down(ctx); err != nil {
-		return err
+	if err := res.Wait(ctx); err != nil {
+		return nil, err
 	}
-	metrics.DumpToLog(ctx)
-	return nil
+	return res, nil
 }
 
 // Compile translates a pipeline to a multi-bundle execution plan.
 func Compile(edges []*graph.MultiEdge) (*exec.Plan, error) {
+	return CompileWithProbe(edges, nil)
+}
+
+// Probe observes the PCollections of a pipeline as it is executed. It must be
+// safe for concurrent use, as PCollections may be processed in parallel.
+type Probe interface {
+	// Element is called for each element of the PCollection of the given
+	// graph node.
+	Element(node int)
+	// Time is called as the time of the PCollection of the given graph node
+	// advances.
+	Time(node int, t exec.Time)
+}
+
+// CompileWithProbe translates a pipeline to a multi-bundle execution plan,
+// like Compile, in which the given probe observes each PCollection.
+func CompileWithProbe(edges []*graph.MultiEdge, probe Probe) (*exec.Plan, error) {
 	// (1) Preprocess graph structure to allow insertion of Multiplex,
 	// Flatten and Discard.
 
@@ -81,25 +156,58 @@ func Compile(edges []*graph.MultiEdge) (*exec.Plan, error) {
 	// (2) Constructs the plan units recursively.
 
 	b := &builder{
-		prev:  prev,
-		succ:  succ,
-		edges: edgeMap,
-		nodes: make(map[int]exec.Node),
-		links: make(map[linkID]exec.Node),
-		idgen: &exec.GenID{},
+		prev:     prev,
+		succ:     succ,
+		edges:    edgeMap,
+		nodes:    make(map[int]exec.Node),
+		links:    make(map[linkID]exec.Node),
+		idgen:    &exec.GenID{},
+		impulses: make(map[int]bool),
+		probe:    probe,
+	}
+
+	serial := *parallelism > 1
+	for _, edge := range edges {
+		if edge.Op != graph.Impulse {
+			continue
+		}
+		b.impulses[edge.Output[0].To.ID()] = true
+		if _, _, ok := b.testStreamFn(edge); ok {
+			serial = false // time is advanced by a single stream
+		}
+	}
+	if serial {
+		b.serial = &sync.Mutex{}
 	}
 
-	var roots []exec.Unit
+	// TestStreams are replayed after the other roots have processed their
+	// input, such that bounded inputs do not hold back their watermark.
+
+	var roots, streams []exec.Unit
 
 	for _, edge := range edges {
 		switch edge.Op {
 		case graph.Impulse:
+			if pardo, fn, ok := b.testStreamFn(edge); ok {
+				out, err := b.makeNode(pardo.Output[0].To.ID())
+				if err != nil {
+					return nil, err
+				}
+
+				u := &TestStream{UID: b.idgen.New(), Fn: fn, Out: out}
+				streams = append(streams, u)
+				continue
+			}
+
 			out, err := b.makeNode(edge.Output[0].To.ID())
 			if err != nil {
 				return nil, err
 			}
 
-			u := &Impulse{UID: b.idgen.New(), Value: edge.Value, Out: out}
+			var u exec.Root = &Impulse{UID: b.idgen.New(), Value: edge.Value, Out: out}
+			if b.serial != nil {
+				u = &serialRoot{Root: u, Serial: b.serial}
+			}
 			roots = append(roots, u)
 
 		default:
@@ -107,7 +215,8 @@ func Compile(edges []*graph.MultiEdge) (*exec.Plan, error) {
 		}
 	}
 
-	return exec.NewPlan("plan", append(roots, b.units...))
+	roots = append(roots, streams...)
+	return exec.NewPlan(fmt.Sprintf("plan%v", atomic.AddInt32(&planID, 1)), append(roots, b.units...))
 }
 
 // linkID represents an incoming data link to an Edge.
@@ -127,6 +236,14 @@ type builder struct {
 
 	units []exec.Unit // result
 	idgen *exec.GenID
+
+	impulses map[int]bool // nodeIDs of impulse outputs
+	probe    Probe        // observes the nodes, if any
+	serial   *sync.Mutex  // serial lock, if executed in parallel
+	parent   *builder     // builder of the shared nodes, if a replica
+	group    *replicas    // merges into the shared nodes, if a replica
+	replica  int          // index of the replica, if a replica
+	chain    *chain       // chain joined by the next ParDo built, if any
 }
 
 func (b *builder) makeNodes(out []*graph.Outbound) ([]exec.Node, error) {
@@ -141,8 +258,27 @@ func (b *builder) makeNodes(out []*graph.Outbound) ([]exec.Node, error) {
 	return ret, nil
 }
 
+// makeNode returns the node for the PCollection of the given graph node,
+// observed by the probe, if any. Nodes shared by replicas are observed
+// outside the segment.
 func (b *builder) makeNode(id int) (exec.Node, error) {
+	if b.group != nil && b.prev[id] > 1 {
+		return b.shareNode(id)
+	}
+	n, err := b.buildNode(id)
+	if err != nil || b.probe == nil {
+		return n, err
+	}
+	u := &probe{UID: b.idgen.New(), Node: id, Probe: b.probe, Out: n}
+	b.units = append(b.units, u)
+	return u, nil
+}
+
+func (b *builder) buildNode(id int) (exec.Node, error) {
 	if n, ok := b.nodes[id]; ok {
+		if f, ok := n.(*flatten); ok {
+			return b.flattenInput(f), nil
+		}
 		return n, nil
 	}
 
@@ -172,7 +308,10 @@ func (b *builder) makeNode(id int) (exec.Node, error) {
 		// Guard node with Flatten, if needed.
 
 		b.units = append(b.units, u)
-		u = &exec.Flatten{UID: b.idgen.New(), N: count, Out: u}
+		f := &flatten{UID: b.idgen.New(), N: count, Out: u}
+		b.nodes[id] = f
+		b.units = append(b.units, f)
+		return b.flattenInput(f), nil
 	}
 
 	b.nodes[id] = u
@@ -180,6 +319,13 @@ func (b *builder) makeNode(id int) (exec.Node, error) {
 	return u, nil
 }
 
+// flattenInput returns a new input of the flatten.
+func (b *builder) flattenInput(f *flatten) exec.Node {
+	in := f.input(b.idgen.New())
+	b.units = append(b.units, in)
+	return in
+}
+
 func (b *builder) makeLinks(ids []linkID) ([]exec.Node, error) {
 	var ret []exec.Node
 	for _, id := range ids {
@@ -193,6 +339,9 @@ func (b *builder) makeLinks(ids []linkID) ([]exec.Node, error) {
 }
 
 func (b *builder) makeLink(id linkID) (exec.Node, error) {
+	c, fused := b.chain, b.chain != nil
+	b.chain = nil
+
 	if n, ok := b.links[id]; ok {
 		return n, nil
 	}
@@ -203,7 +352,29 @@ func (b *builder) makeLink(id linkID) (exec.Node, error) {
 
 	edge := b.edges[id.to]
 
-	out, err := b.makeNodes(edge.Output)
+	if b.group != nil && !b.replicable(id) {
+		return b.shareLink(id)
+	}
+	if b.group == nil && b.parallelizable(id) {
+		return b.makeParallel(id)
+	}
+
+	if edge.Op == graph.CoGBK {
+		if combine, ok := b.liftable(edge); ok {
+			return b.makeLiftedCombine(edge, combine)
+		}
+	}
+
+	var out []exec.Node
+	var err error
+	if edge.Op == graph.ParDo && *bundleRetries > 0 && retryable(edge.DoFn) {
+		if c == nil {
+			c = &chain{}
+		}
+		out, err = b.makeChainNodes(c, edge.Output)
+	} else {
+		out, err = b.makeNodes(edge.Output)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -212,18 +383,43 @@ func (b *builder) makeLink(id linkID) (exec.Node, error) {
 	switch edge.Op {
 	case graph.ParDo:
 		pardo := &exec.ParDo{UID: b.idgen.New(), Fn: edge.DoFn, Inbound: edge.Input, Out: out}
+		if b.group != nil {
+			fn, err := cloneFn((*graph.Fn)(edge.DoFn))
+			if err != nil {
+				return nil, err
+			}
+			if pardo.Fn, err = graph.AsDoFn(fn); err != nil {
+				return nil, err
+			}
+		}
 		pardo.PID = path.Base(pardo.Fn.Name())
+		if pardo.Fn.IsStateful() {
+			pardo.State = newStateStore()
+		}
+		pardo.Resume = pardo.Fn.IsSplittable()
+
+		var main exec.Node = pardo
+		if c != nil {
+			c.pardos = append(c.pardos, pardo)
+			if fused {
+				// Run by the retry of the head of the chain.
+
+				b.links[id] = pardo
+				return pardo, nil
+			}
+			main = b.makeRetry(c)
+		}
 		if len(edge.Input) == 1 {
-			u = pardo
+			u = main
 			break
 		}
 
 		// ParDo w/ side input. We need to insert buffering and wait. We also need to
 		// ensure that we return the correct link node.
 
-		b.units = append(b.units, pardo)
+		b.units = append(b.units, main)
 
-		w := &wait{UID: b.idgen.New(), need: len(edge.Input) - 1, next: pardo}
+		w := &wait{UID: b.idgen.New(), need: len(edge.Input) - 1, next: main}
 		b.units = append(b.units, w)
 		b.links[linkID{edge.ID(), 0}] = w
 
@@ -244,19 +440,24 @@ func (b *builder) makeLink(id linkID) (exec.Node, error) {
 		u = &exec.Combine{UID: b.idgen.New(), Fn: edge.CombineFn, IsPerKey: isPerKey, UsesKey: usesKey, Out: out[0]}
 
 	case graph.CoGBK:
-		u = &CoGBK{UID: b.idgen.New(), Edge: edge, Out: out[0]}
+		u = &CoGBK{UID: b.idgen.New(), Edge: edge, Budget: int64(*gbkMemory) << 20, Out: out[0]}
 		b.units = append(b.units, u)
 
 		// CoGBK needs injection of each incoming index. If > 1 incoming,
 		// insert Flatten as well.
 
+		var f *flatten
 		if len(edge.Input) > 1 {
-			u = &exec.Flatten{UID: b.idgen.New(), N: len(edge.Input), Out: u}
-			b.units = append(b.units, u)
+			f = &flatten{UID: b.idgen.New(), N: len(edge.Input), Out: u}
+			b.units = append(b.units, f)
 		}
 
 		for i := 0; i < len(edge.Input); i++ {
-			n := &Inject{UID: b.idgen.New(), N: i, Out: u}
+			out := u
+			if f != nil {
+				out = b.flattenInput(f)
+			}
+			n := &Inject{UID: b.idgen.New(), N: i, Out: out}
 
 			b.units = append(b.units, n)
 			b.links[linkID{edge.ID(), i}] = n
@@ -264,13 +465,23 @@ func (b *builder) makeLink(id linkID) (exec.Node, error) {
 
 		return b.links[id], nil
 
+	case graph.WindowInto:
+		w := &exec.WindowInto{UID: b.idgen.New(), Fn: edge.WindowFn, Out: out[0]}
+		if len(out) > 1 {
+			w.Late = out[1]
+		}
+		u = w
+
 	case graph.Flatten:
-		u = &exec.Flatten{UID: b.idgen.New(), N: len(edge.Input), Out: out[0]}
+		f := &flatten{UID: b.idgen.New(), N: len(edge.Input), Out: out[0]}
+		b.units = append(b.units, f)
 
 		for i := 0; i < len(edge.Input); i++ {
-			b.links[linkID{edge.ID(), i}] = u
+			b.links[linkID{edge.ID(), i}] = b.flattenInput(f)
 		}
 
+		return b.links[id], nil
+
 	default:
 		return nil, fmt.Errorf("unexpected edge: %v", edge)
 	}
@@ -279,3 +490,235 @@ func (b *builder) makeLink(id linkID) (exec.Node, error) {
 	b.units = append(b.units, u)
 	return u, nil
 }
+
+// makeChainNodes returns the nodes for the outputs of a ParDo of the chain.
+// Outputs solely consumed by a ParDo that can be retried as part of the chain
+// are fused. Other outputs are held back by commit nodes.
+func (b *builder) makeChainNodes(c *chain, out []*graph.Outbound) ([]exec.Node, error) {
+	var ret []exec.Node
+	for _, o := range out {
+		id := o.To.ID()
+		if b.fusable(id) {
+			b.chain = c
+			n, err := b.makeNode(id)
+			b.chain = nil
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, n)
+			continue
+		}
+
+		n, err := b.makeNode(id)
+		if err != nil {
+			return nil, err
+		}
+		u := &commit{UID: b.idgen.New(), Out: n}
+		b.units = append(b.units, u)
+		c.out = append(c.out, u)
+		ret = append(ret, u)
+	}
+	return ret, nil
+}
+
+// fusable returns true iff the PCollection of the given graph node is solely
+// consumed by a ParDo that can join the chain of its producer: a ParDo
+// without side inputs that can be retried and is built by the same builder.
+func (b *builder) fusable(id int) bool {
+	list := b.succ[id]
+	if len(list) != 1 || b.prev[id] != 1 {
+		return false
+	}
+	edge := b.edges[list[0].to]
+	if edge.Op != graph.ParDo || len(edge.Input) != 1 || !retryable(edge.DoFn) {
+		return false
+	}
+	if b.group != nil {
+		return b.replicable(list[0])
+	}
+	return !b.parallelizable(list[0])
+}
+
+// retryable returns true iff bundles of the DoFn can be retried: it is
+// neither stateful nor splittable.
+func retryable(fn *graph.DoFn) bool {
+	return !fn.IsStateful() && !fn.IsSplittable()
+}
+
+// makeRetry returns a retry of the given chain. Each time a bundle fails,
+// the ParDos of the chain are replaced by ones with copies of their DoFns,
+// if the DoFns can be copied.
+func (b *builder) makeRetry(c *chain) *retry {
+	r := &retry{UID: b.idgen.New(), Out: c.out, Retries: *bundleRetries, Backoff: *retryBackoff, MaxBackoff: *retryMaxBackoff, pardos: c.pardos}
+
+	first := true
+	r.New = func() ([]*exec.ParDo, error) {
+		if first {
+			first = false
+			return c.pardos, nil
+		}
+
+		var ret []*exec.ParDo
+		clones := make(map[exec.Node]exec.Node) // old -> new
+		for _, p := range c.pardos {
+			q := &exec.ParDo{UID: p.UID, Fn: p.Fn, Inbound: p.Inbound, Side: p.Side, PID: p.PID}
+			if fn, err := cloneFn((*graph.Fn)(p.Fn)); err == nil {
+				if q.Fn, err = graph.AsDoFn(fn); err != nil {
+					return nil, err
+				}
+			}
+			for _, o := range p.Out {
+				q.Out = append(q.Out, relink(o, clones))
+			}
+			clones[p] = q
+			ret = append(ret, q)
+		}
+		return ret, nil
+	}
+	return r
+}
+
+// parallelizable returns true iff the ParDo of the link heads a segment that
+// is replicated across workers. ParDos that consume the single element of an
+// impulse, such as Create, are not worth replicating.
+func (b *builder) parallelizable(id linkID) bool {
+	edge := b.edges[id.to]
+	return b.serial != nil && edge.Op == graph.ParDo && len(edge.Input) == 1 && !b.impulses[edge.Input[0].From.ID()] && copyable(edge.DoFn)
+}
+
+// replicable returns true iff the edge of the link is part of the segment
+// being replicated. Stateful ParDos only head a segment, as their input must
+// be partitioned by key. DoFns that cannot be copied are not replicated.
+func (b *builder) replicable(id linkID) bool {
+	edge := b.edges[id.to]
+	switch edge.Op {
+	case graph.ParDo:
+		return len(edge.Input) == 1 && (!edge.DoFn.IsStateful() || id == b.group.head) && copyable(edge.DoFn)
+	case graph.WindowInto:
+		return true
+	default:
+		return false
+	}
+}
+
+// makeParallel builds the segment headed by the ParDo of the link once per
+// worker and returns the node that distributes the input to them.
+func (b *builder) makeParallel(id linkID) (exec.Node, error) {
+	edge := b.edges[id.to]
+	group := &replicas{n: *parallelism, head: id, nodes: make(map[int]*merge), links: make(map[linkID]*merge)}
+	u := &parallel{UID: b.idgen.New(), Serial: b.serial}
+	if edge.DoFn.IsStateful() {
+		u.KeyEnc = exec.MakeElementEncoder(edge.Input[0].From.Coder.Components[0])
+	}
+
+	for i := 0; i < group.n; i++ {
+		r := &builder{
+			prev:    b.prev,
+			succ:    b.succ,
+			edges:   b.edges,
+			nodes:   make(map[int]exec.Node),
+			links:   make(map[linkID]exec.Node),
+			idgen:   b.idgen,
+			serial:  b.serial,
+			probe:   b.probe,
+			parent:  b,
+			group:   group,
+			replica: i,
+		}
+		out, err := r.makeLink(id)
+		if err != nil {
+			return nil, err
+		}
+		u.Out = append(u.Out, out)
+		b.units = append(b.units, r.units...)
+	}
+
+	b.links[id] = u
+	b.units = append(b.units, u)
+	return u, nil
+}
+
+// shareNode returns the input of the replica to the merge of the replicas
+// into the node built outside the segment.
+func (b *builder) shareNode(id int) (exec.Node, error) {
+	if m, ok := b.group.nodes[id]; ok {
+		return b.mergeInput(m), nil
+	}
+	out, err := b.parent.makeNode(id)
+	if err != nil {
+		return nil, err
+	}
+	u := &merge{UID: b.idgen.New(), N: b.group.n, Out: out, Serial: b.serial}
+	b.group.nodes[id] = u
+	b.units = append(b.units, u)
+	return b.mergeInput(u), nil
+}
+
+// shareLink returns the input of the replica to the merge of the replicas
+// into the link built outside the segment.
+func (b *builder) shareLink(id linkID) (exec.Node, error) {
+	if m, ok := b.group.links[id]; ok {
+		return b.mergeInput(m), nil
+	}
+	out, err := b.parent.makeLink(id)
+	if err != nil {
+		return nil, err
+	}
+	u := &merge{UID: b.idgen.New(), N: b.group.n, Out: out, Serial: b.serial}
+	b.group.links[id] = u
+	b.units = append(b.units, u)
+	return b.mergeInput(u), nil
+}
+
+func (b *builder) mergeInput(m *merge) exec.Node {
+	in := m.input(b.idgen.New(), b.replica)
+	b.units = append(b.units, in)
+	return in
+}
+
+// liftable returns the Combine edge that solely consumes the output of the
+// given GBK, if any. Such a combine is lifted: the values of each key are
+// partially combined before the GBK and only the accumulators are grouped.
+// Only globally windowed input with the default trigger is lifted.
+func (b *builder) liftable(gbk *graph.MultiEdge) (*graph.MultiEdge, bool) {
+	if len(gbk.Input) != 1 || !gbk.Input[0].From.Window().Equals(window.NewGlobalWindow()) {
+		return nil, false
+	}
+	list := b.succ[gbk.Output[0].To.ID()]
+	if len(list) != 1 {
+		return nil, false
+	}
+	combine := b.edges[list[0].to]
+	if combine.Op != graph.Combine {
+		return nil, false
+	}
+	return combine, true
+}
+
+// makeLiftedCombine builds the lifted form of the GBK and Combine:
+//
+//	LiftedCombine -> Inject -> CoGBK -> MergeAccumulators
+//
+// and returns the node for the GBK input.
+func (b *builder) makeLiftedCombine(gbk, combine *graph.MultiEdge) (exec.Node, error) {
+	out, err := b.makeNodes(combine.Output)
+	if err != nil {
+		return nil, err
+	}
+	usesKey := typex.IsKV(combine.Input[0].Type)
+
+	merge := &exec.MergeAccumulators{
+		Combine: &exec.Combine{UID: b.idgen.New(), Fn: combine.CombineFn, IsPerKey: true, UsesKey: usesKey, Out: out[0]},
+	}
+	grp := &CoGBK{UID: b.idgen.New(), Edge: gbk, Out: merge}
+	inject := &Inject{UID: b.idgen.New(), N: 0, Out: grp}
+	lifted := &exec.LiftedCombine{
+		Combine:  &exec.Combine{UID: b.idgen.New(), Fn: combine.CombineFn, IsPerKey: true, UsesKey: usesKey, Out: inject},
+		KeyCoder: gbk.Input[0].From.Coder.Components[0],
+	}
+	b.units = append(b.units, merge, grp, inject, lifted)
+
+	id := linkID{gbk.ID(), 0}
+	b.links[id] = lifted
+	return lifted, nil
+}
@@ -0,0 +1,152 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// flatten merges N inputs, like exec.Flatten, but holds the watermark of its
+// output at the minimum of the watermarks of its inputs. Time thus only
+// advances downstream once every input has advanced. Each input feeds the
+// flatten through its own flattenInput, such that they can be told apart.
+type flatten struct {
+	UID exec.UnitID
+	N   int
+	Out exec.Node
+
+	inputs     int               // flattenInputs created
+	watermarks []typex.EventTime // watermark per input
+	now        exec.Time         // time of the output
+	active     bool
+	seen       int
+}
+
+// input returns a new input of the flatten.
+func (n *flatten) input(uid exec.UnitID) *flattenInput {
+	in := &flattenInput{UID: uid, N: n.inputs, Out: n}
+	n.inputs++
+	return in
+}
+
+func (n *flatten) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *flatten) Up(ctx context.Context) error {
+	n.watermarks = make([]typex.EventTime, n.N)
+	return nil
+}
+
+func (n *flatten) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	if n.active {
+		return nil // ok: ignore multiple start bundles. We just want the first one.
+	}
+	n.active = true
+	n.seen = 0
+	for i := range n.watermarks {
+		n.watermarks[i] = typex.EventTime{}
+	}
+	n.now = exec.Time{}
+
+	return n.Out.StartBundle(ctx, id, data)
+}
+
+func (n *flatten) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	return n.Out.ProcessElement(ctx, elm, values...)
+}
+
+// advance records the time of the given input and forwards the time of the
+// output downstream. The processing time is the latest of any input.
+func (n *flatten) advance(ctx context.Context, index int, t exec.Time) error {
+	n.watermarks[index] = t.Watermark
+
+	min := n.watermarks[0]
+	for _, w := range n.watermarks[1:] {
+		if time.Time(w).Before(time.Time(min)) {
+			min = w
+		}
+	}
+	n.now.Watermark = min
+	if t.ProcessingTime.After(n.now.ProcessingTime) {
+		n.now.ProcessingTime = t.ProcessingTime
+	}
+	return exec.MultiAdvanceTime(ctx, n.now, n.Out)
+}
+
+func (n *flatten) FinishBundle(ctx context.Context) error {
+	n.seen++
+	if n.seen < n.N {
+		return nil // ok: wait for last FinishBundle.
+	}
+	n.active = false
+
+	return n.Out.FinishBundle(ctx)
+}
+
+func (n *flatten) Down(ctx context.Context) error {
+	return nil
+}
+
+func (n *flatten) String() string {
+	return fmt.Sprintf("Flatten[%v]. Out:%v", n.N, n.Out.ID())
+}
+
+// flattenInput is a single input of a flatten. It advances the time of that
+// input only.
+type flattenInput struct {
+	UID exec.UnitID
+	N   int
+	Out *flatten
+}
+
+func (n *flattenInput) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *flattenInput) Up(ctx context.Context) error {
+	return nil
+}
+
+func (n *flattenInput) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	return n.Out.StartBundle(ctx, id, data)
+}
+
+func (n *flattenInput) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	return n.Out.ProcessElement(ctx, elm, values...)
+}
+
+// AdvanceTime advances the time of the input.
+func (n *flattenInput) AdvanceTime(ctx context.Context, t exec.Time) error {
+	return n.Out.advance(ctx, n.N, t)
+}
+
+func (n *flattenInput) FinishBundle(ctx context.Context) error {
+	return n.Out.FinishBundle(ctx)
+}
+
+func (n *flattenInput) Down(ctx context.Context) error {
+	return nil
+}
+
+func (n *flattenInput) String() string {
+	return fmt.Sprintf("FlattenInput[%v]. Out:%v", n.N, n.Out.ID())
+}
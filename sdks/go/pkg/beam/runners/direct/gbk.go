@@ -21,22 +21,63 @@ import (
 	"fmt"
 
 	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
 )
 
 type group struct {
-	key    exec.FullValue
-	values [][]exec.FullValue
+	key     exec.FullValue
+	values  [][]exec.FullValue
+	trigger *triggerState
+}
+
+// pending returns true iff values were added since the last pane.
+func (g *group) pending() bool {
+	return g.trigger.count > 0
+}
+
+func (g *group) empty() bool {
+	for _, list := range g.values {
+		if len(list) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// end returns the largest timestamp of the window of the group.
+func (g *group) end() typex.EventTime {
+	if g.key.Windows == nil {
+		return window.SingleGlobalWindow{}.MaxTimestamp()
+	}
+	return g.key.Windows[0].MaxTimestamp()
 }
 
 // CoGBK buffers all input and continues on FinishBundle. Use with small single-bundle data only.
+// Values are grouped by key and window. Merging windows, such as sessions, are merged per key
+// as elements arrive. A pane is emitted whenever the trigger of a window fires, as elements
+// arrive or as time advances, and any remaining values are emitted on FinishBundle. Fired
+// panes are discarded, unless the windowing strategy is accumulating, in which case each
+// pane holds all values of the window so far. Values are dropped once the watermark has passed the end of their
+// window by more than the allowed lateness.
+//
+// If Budget is positive, globally windowed input with the default trigger is
+// grouped externally: once the encoded values held exceed Budget bytes, they
+// are spilled to disk in runs sorted by key, which are merged on
+// FinishBundle.
 type CoGBK struct {
-	UID  exec.UnitID
-	Edge *graph.MultiEdge
-	Out  exec.Node
+	UID    exec.UnitID
+	Edge   *graph.MultiEdge
+	Budget int64
+	Out    exec.Node
 
-	enc exec.ElementEncoder // key encoder for coder-equality
-	m   map[string]*group
+	enc      exec.ElementEncoder // key encoder for coder-equality
+	wfn      *window.Window
+	now      exec.Time
+	m        map[string]*group   // groups by key and window, if not merging
+	sessions map[string][]*group // merged groups by key, if merging
+	spill    *spiller            // if grouped externally
 }
 
 func (n *CoGBK) ID() exec.UnitID {
@@ -45,7 +86,12 @@ func (n *CoGBK) ID() exec.UnitID {
 
 func (n *CoGBK) Up(ctx context.Context) error {
 	n.enc = exec.MakeElementEncoder(n.Edge.Input[0].From.Coder.Components[0])
+	n.wfn = n.Edge.Input[0].From.Window()
 	n.m = make(map[string]*group)
+	n.sessions = make(map[string][]*group)
+	if n.Budget > 0 && n.wfn.Kind() == window.GlobalWindow && n.wfn.Trigger().Kind == window.DefaultTrigger {
+		n.spill = newSpiller(n.Edge, n.Budget)
+	}
 	return nil
 }
 
@@ -63,33 +109,276 @@ func (n *CoGBK) ProcessElement(ctx context.Context, elm exec.FullValue, _ ...exe
 	}
 	key := buf.String()
 
+	if value.Windows == nil {
+		// Global window.
+
+		if err := n.add(ctx, n.lookup(key, value, nil), index, value, nil); err != nil {
+			return err
+		}
+		if n.spill == nil {
+			return nil
+		}
+		full, err := n.spill.add(index, exec.FullValue{Elm: value.Elm2})
+		if err != nil || !full {
+			return err
+		}
+		if err := n.spill.write(n.m); err != nil {
+			return err
+		}
+		n.m = make(map[string]*group)
+		return nil
+	}
+
+	// An element in multiple windows is grouped separately into each.
+
+	for _, w := range value.Windows {
+		if n.wfn.IsExpired(w, n.now.Watermark) {
+			continue // window expired: drop value
+		}
+		ws := []typex.Window{w}
+
+		if n.wfn.IsMerging() {
+			g := n.newGroup(value, ws)
+			n.sessions[key] = append(n.sessions[key], g)
+			if err := n.add(ctx, g, index, value, ws); err != nil {
+				return err
+			}
+			merged, err := n.merge(key)
+			if err != nil {
+				return err
+			}
+			if err := n.fire(ctx, merged); err != nil {
+				return err
+			}
+			continue
+		}
+
+		g := n.lookup(fmt.Sprintf("%v%v", key, w), value, ws)
+		if err := n.add(ctx, g, index, value, ws); err != nil {
+			return err
+		}
+		if err := n.fire(ctx, g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds the value to the current pane of the group, unless the trigger of
+// the window has finished and the window is closed.
+func (n *CoGBK) add(ctx context.Context, g *group, index int, value exec.FullValue, ws []typex.Window) error {
+	if g.trigger.finished {
+		return nil // window closed: drop value
+	}
+	if ws == nil && g.empty() {
+		g.key.Timestamp = value.Timestamp
+	}
+	g.values[index] = append(g.values[index], exec.FullValue{Elm: value.Elm2, Timestamp: value.Timestamp, Windows: ws})
+	g.trigger.add(n.now.ProcessingTime)
+
+	if ws == nil {
+		return n.fire(ctx, g)
+	}
+	return nil
+}
+
+// lookup returns the group for the given key and window, creating it if needed.
+func (n *CoGBK) lookup(key string, value exec.FullValue, ws []typex.Window) *group {
 	g, ok := n.m[key]
 	if !ok {
-		g = &group{
-			key:    exec.FullValue{Elm: value.Elm, Timestamp: value.Timestamp},
-			values: make([][]exec.FullValue, len(n.Edge.Input)),
-		}
+		g = n.newGroup(value, ws)
 		n.m[key] = g
 	}
-	g.values[index] = append(g.values[index], exec.FullValue{Elm: value.Elm2, Timestamp: value.Timestamp})
-	return nil
+	return g
 }
 
-func (n *CoGBK) FinishBundle(ctx context.Context) error {
-	for key, g := range n.m {
-		values := make([]exec.ReStream, len(g.values))
-		for i, list := range g.values {
-			values[i] = &exec.FixedReStream{Buf: list}
+func (n *CoGBK) newGroup(value exec.FullValue, ws []typex.Window) *group {
+	g := &group{
+		key:     exec.FullValue{Elm: value.Elm, Timestamp: value.Timestamp, Windows: ws},
+		values:  make([][]exec.FullValue, len(n.Edge.Input)),
+		trigger: newTriggerState(n.wfn.Trigger()),
+	}
+	if ws != nil {
+		// The grouped key is output at the end of the window.
+		g.key.Timestamp = ws[0].MaxTimestamp()
+	}
+	return g
+}
+
+// merge merges the groups of the key whose windows overlap. It returns the
+// group that the most recently added group was merged into.
+func (n *CoGBK) merge(key string) (*group, error) {
+	list := n.sessions[key]
+	intervals := make([]window.IntervalWindow, len(list))
+	for i, g := range list {
+		iw, ok := g.key.Windows[0].(window.IntervalWindow)
+		if !ok {
+			return nil, fmt.Errorf("cannot merge window %v: not an interval window", g.key.Windows[0])
 		}
-		if err := n.Out.ProcessElement(ctx, g.key, values...); err != nil {
+		intervals[i] = iw
+	}
+	merged, index, err := n.wfn.MergeWindows(intervals)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*group, len(merged))
+	for i, g := range list {
+		if m := groups[index[i]]; m != nil {
+			for j, values := range g.values {
+				m.values[j] = append(m.values[j], values...)
+			}
+			m.trigger.merge(g.trigger)
+			continue
+		}
+		w := merged[index[i]]
+		g.key.Windows, g.key.Timestamp = []typex.Window{w}, w.MaxTimestamp()
+		groups[index[i]] = g
+	}
+	n.sessions[key] = groups
+	return groups[index[len(list)-1]], nil
+}
+
+// fire emits the current pane of the group, if its trigger fires.
+func (n *CoGBK) fire(ctx context.Context, g *group) error {
+	c := triggerContext{watermark: n.now.Watermark, now: n.now.ProcessingTime, end: g.end()}
+	if !g.trigger.fire(c) {
+		return nil
+	}
+	g.trigger.clear()
+	if g.empty() {
+		return nil
+	}
+	return n.emit(ctx, g)
+}
+
+// emit emits the current pane of the group. The values are discarded, unless
+// accumulating.
+func (n *CoGBK) emit(ctx context.Context, g *group) error {
+	values := make([]exec.ReStream, len(g.values))
+	for i, list := range g.values {
+		if g.key.Windows != nil {
+			for j := range list {
+				list[j].Windows = g.key.Windows
+			}
+		}
+		values[i] = &exec.FixedReStream{Buf: list}
+	}
+	if n.wfn.AccumulationMode() == window.Discarding {
+		g.values = make([][]exec.FullValue, len(g.values))
+	}
+	return n.Out.ProcessElement(ctx, g.key, values...)
+}
+
+// AdvanceTime fires the triggers of all windows at the new time and forwards
+// the time downstream. Windows that have expired emit a final pane, if values
+// arrived since the last pane, and are discarded.
+func (n *CoGBK) AdvanceTime(ctx context.Context, t exec.Time) error {
+	n.now = t
+	if (triggerContext{watermark: t.Watermark, end: window.SingleGlobalWindow{}.MaxTimestamp()}).pastEnd() {
+		if err := n.emitSpilled(ctx); err != nil {
+			return err
+		}
+	}
+	for key, g := range n.m {
+		if err := n.fire(ctx, g); err != nil {
 			return err
 		}
+		if n.expired(g) {
+			if err := n.expire(ctx, g); err != nil {
+				return err
+			}
+			delete(n.m, key)
+		}
+	}
+	for key, list := range n.sessions {
+		var live []*group
+		for _, g := range list {
+			if err := n.fire(ctx, g); err != nil {
+				return err
+			}
+			if n.expired(g) {
+				if err := n.expire(ctx, g); err != nil {
+					return err
+				}
+				continue
+			}
+			live = append(live, g)
+		}
+		if len(live) == 0 {
+			delete(n.sessions, key)
+		} else {
+			n.sessions[key] = live
+		}
+	}
+	return exec.MultiAdvanceTime(ctx, t, n.Out)
+}
+
+// emitSpilled emits the groups, if any were spilled. The groups in memory are
+// spilled as the last run, such that all groups are emitted from the merged
+// runs.
+func (n *CoGBK) emitSpilled(ctx context.Context) error {
+	if n.spill == nil || !n.spill.spilled() {
+		return nil
+	}
+	if err := n.spill.write(n.m); err != nil {
+		return err
+	}
+	n.m = make(map[string]*group)
+	err := n.spill.merge(func(key exec.FullValue, values []exec.ReStream) error {
+		return n.Out.ProcessElement(ctx, key, values...)
+	})
+	if cerr := n.spill.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (n *CoGBK) expired(g *group) bool {
+	return g.key.Windows != nil && n.wfn.IsExpired(g.key.Windows[0], n.now.Watermark)
+}
+
+// expire emits the final pane of an expired group, if needed.
+func (n *CoGBK) expire(ctx context.Context, g *group) error {
+	if !g.pending() {
+		return nil
+	}
+	return n.emit(ctx, g)
+}
+
+func (n *CoGBK) FinishBundle(ctx context.Context) error {
+	if err := n.emitSpilled(ctx); err != nil {
+		return err
+	}
+
+	// The input is exhausted and all windows expire. Windows with values
+	// since their last pane emit a final pane.
+
+	for key, g := range n.m {
+		if g.pending() {
+			if err := n.emit(ctx, g); err != nil {
+				return err
+			}
+		}
 		delete(n.m, key)
 	}
+	for key, list := range n.sessions {
+		for _, g := range list {
+			if g.pending() {
+				if err := n.emit(ctx, g); err != nil {
+					return err
+				}
+			}
+		}
+		delete(n.sessions, key)
+	}
 	return n.Out.FinishBundle(ctx)
 }
 
 func (n *CoGBK) Down(ctx context.Context) error {
+	if n.spill != nil {
+		return n.spill.Close()
+	}
 	return nil
 }
 
@@ -118,13 +407,18 @@ func (n *Inject) StartBundle(ctx context.Context, id string, data exec.DataManag
 }
 
 func (n *Inject) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
-	return n.Out.ProcessElement(ctx, exec.FullValue{Elm: n.N, Elm2: elm, Timestamp: elm.Timestamp}, values...)
+	return n.Out.ProcessElement(ctx, exec.FullValue{Elm: n.N, Elm2: elm, Timestamp: elm.Timestamp, Windows: elm.Windows}, values...)
 }
 
 func (n *Inject) FinishBundle(ctx context.Context) error {
 	return n.Out.FinishBundle(ctx)
 }
 
+// AdvanceTime forwards the time downstream.
+func (n *Inject) AdvanceTime(ctx context.Context, t exec.Time) error {
+	return exec.MultiAdvanceTime(ctx, t, n.Out)
+}
+
 func (n *Inject) Down(ctx context.Context) error {
 	return nil
 }
@@ -22,7 +22,8 @@ import (
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
 )
 
-// Impulse emits its single element in one invocation.
+// Impulse emits its single element in one invocation. The input is then
+// exhausted and the watermark advances to the end of time downstream.
 type Impulse struct {
 	UID   exec.UnitID
 	Value []byte
@@ -45,7 +46,11 @@ func (n *Impulse) Process(ctx context.Context) error {
 	value := exec.FullValue{Elm: n.Value}
 	// TODO(herohde) 6/23/2017: set value.Timestamp
 
-	return n.Out.ProcessElement(ctx, value)
+	if err := n.Out.ProcessElement(ctx, value); err != nil {
+		return err
+	}
+	now := exec.Time{Watermark: exec.EndOfTime, ProcessingTime: clockOf(ctx).Now()}
+	return exec.MultiAdvanceTime(ctx, now, n.Out)
 }
 
 func (n *Impulse) FinishBundle(ctx context.Context) error {
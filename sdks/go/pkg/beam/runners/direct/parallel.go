@@ -0,0 +1,435 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/util/errorx"
+)
+
+// bundleSize is the number of elements passed to a worker at a time.
+const bundleSize = 100
+
+// item is an element, with its GBK or CoGBK result values, if any, or an
+// advance of time.
+type item struct {
+	elm    exec.FullValue
+	values []exec.ReStream
+	now    *exec.Time      // time to advance to instead, if set
+	done   *sync.WaitGroup // done once the time is advanced
+}
+
+// parallel processes its input on a pool of workers. Each worker owns a
+// replica of the downstream segment, which it runs as a single bundle. The
+// input is passed to the workers in bundles of elements, in turn. Elements of
+// the same key are passed to the same worker, if keyed, such that they are
+// processed in order. Time is advanced in every replica, after the elements
+// passed before.
+//
+// The rest of the plan runs serially under the serial lock, which the caller
+// holds. It is released while waiting for the workers.
+type parallel struct {
+	UID    exec.UnitID
+	Out    []exec.Node         // segment replicas, one per worker
+	KeyEnc exec.ElementEncoder // partitions the input by key, if set
+	Serial *sync.Mutex
+
+	work []chan []item // per worker
+	bufs [][]item      // pending bundles per worker
+	next int           // worker of the next bundle, if not keyed
+	wg   sync.WaitGroup
+	err  errorx.GuardedError
+}
+
+func (n *parallel) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *parallel) Up(ctx context.Context) error {
+	return nil
+}
+
+func (n *parallel) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	n.work, n.bufs, n.next = nil, nil, 0
+
+	for _, out := range n.Out {
+		in := make(chan []item, 1)
+		n.work = append(n.work, in)
+		n.bufs = append(n.bufs, nil)
+
+		n.wg.Add(1)
+		go func(out exec.Node, in <-chan []item) {
+			defer n.wg.Done()
+			if err := n.run(ctx, id, data, out, in); err != nil {
+				n.err.TrySetError(err)
+			}
+		}(out, in)
+	}
+	return nil
+}
+
+// run processes the bundles of a worker with its replica of the segment. The
+// remaining input is discarded after a failure. Advances of time may be
+// passed out of order by concurrent callers, so the replica is only advanced
+// to the latest time.
+func (n *parallel) run(ctx context.Context, id string, data exec.DataManager, out exec.Node, in <-chan []item) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic in %v: %v", out, p)
+		}
+		for list := range in {
+			for _, it := range list {
+				if it.now != nil {
+					it.done.Done()
+				}
+			}
+		}
+	}()
+
+	if err := out.StartBundle(ctx, id, data); err != nil {
+		return err
+	}
+	var now exec.Time
+	for list := range in {
+		for _, it := range list {
+			if it.now != nil {
+				now = latest(now, *it.now)
+				err := exec.MultiAdvanceTime(ctx, now, out)
+				it.done.Done()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := out.ProcessElement(ctx, it.elm, it.values...); err != nil {
+				return err
+			}
+		}
+	}
+	return out.FinishBundle(ctx)
+}
+
+func (n *parallel) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	if err := n.err.Error(); err != nil {
+		return err
+	}
+
+	i := n.next
+	if n.KeyEnc != nil {
+		var buf bytes.Buffer
+		if err := n.KeyEnc.Encode(exec.FullValue{Elm: elm.Elm}, &buf); err != nil {
+			return fmt.Errorf("failed to encode key %v: %v", elm.Elm, err)
+		}
+		h := fnv.New32a()
+		h.Write(buf.Bytes())
+		i = int(h.Sum32() % uint32(len(n.work)))
+	}
+
+	n.bufs[i] = append(n.bufs[i], item{elm: elm, values: values})
+	if len(n.bufs[i]) < bundleSize {
+		return nil
+	}
+	n.flush(i)
+	if n.KeyEnc == nil {
+		n.next = (n.next + 1) % len(n.work)
+	}
+	return n.err.Error()
+}
+
+// AdvanceTime passes the pending bundles and then the time to every worker.
+// It waits until all replicas have advanced, such that the time advances
+// downstream before any later element is processed.
+func (n *parallel) AdvanceTime(ctx context.Context, t exec.Time) error {
+	if err := n.err.Error(); err != nil {
+		return err
+	}
+
+	var done sync.WaitGroup
+	for i := range n.work {
+		n.flush(i)
+		done.Add(1)
+		n.send(i, []item{{now: &t, done: &done}})
+	}
+
+	n.Serial.Unlock()
+	done.Wait()
+	n.Serial.Lock()
+
+	return n.err.Error()
+}
+
+// flush passes the pending bundle of the given worker, if any.
+func (n *parallel) flush(i int) {
+	if list := n.bufs[i]; len(list) > 0 {
+		n.bufs[i] = nil
+		n.send(i, list)
+	}
+}
+
+// send passes the list to the given worker. The serial lock is released
+// while waiting for the worker to accept it, so that workers can make
+// progress.
+func (n *parallel) send(i int, list []item) {
+	n.Serial.Unlock()
+	defer n.Serial.Lock()
+	n.work[i] <- list
+}
+
+func (n *parallel) FinishBundle(ctx context.Context) error {
+	for i := range n.work {
+		n.flush(i)
+	}
+	n.close()
+
+	n.Serial.Unlock()
+	n.wg.Wait()
+	n.Serial.Lock()
+
+	return n.err.Error()
+}
+
+// close ends the input of the workers.
+func (n *parallel) close() {
+	for _, in := range n.work {
+		close(in)
+	}
+	n.work = nil
+}
+
+// Down stops the workers of a failed bundle, if any.
+func (n *parallel) Down(ctx context.Context) error {
+	n.close()
+	return nil
+}
+
+func (n *parallel) String() string {
+	return fmt.Sprintf("Parallel[%v] Keyed:%v Out:%v", len(n.Out), n.KeyEnc != nil, exec.IDs(n.Out...))
+}
+
+// merge passes the output of the replicas of a segment to a node outside the
+// segment under the serial lock. It starts the bundle of the node on the
+// first StartBundle and finishes it once all replicas have finished. Like
+// flatten, it holds the watermark of its output at the minimum of the
+// watermarks of the replicas, which feed it through their own mergeInputs.
+type merge struct {
+	UID    exec.UnitID
+	N      int // replicas
+	Out    exec.Node
+	Serial *sync.Mutex
+
+	started, finished int
+	watermarks        []typex.EventTime // watermark per replica
+	now               exec.Time         // time of the output
+}
+
+// input returns the input of the merge for the given replica.
+func (n *merge) input(uid exec.UnitID, replica int) *mergeInput {
+	return &mergeInput{UID: uid, N: replica, Out: n}
+}
+
+func (n *merge) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *merge) Up(ctx context.Context) error {
+	n.started, n.finished = 0, 0
+	n.watermarks = make([]typex.EventTime, n.N)
+	return nil
+}
+
+func (n *merge) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	n.Serial.Lock()
+	defer n.Serial.Unlock()
+
+	n.started++
+	if n.started > 1 {
+		return nil
+	}
+	n.finished = 0
+	for i := range n.watermarks {
+		n.watermarks[i] = typex.EventTime{}
+	}
+	n.now = exec.Time{}
+	return n.Out.StartBundle(ctx, id, data)
+}
+
+func (n *merge) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	n.Serial.Lock()
+	defer n.Serial.Unlock()
+
+	return n.Out.ProcessElement(ctx, elm, values...)
+}
+
+// advance records the time of the given replica and forwards the time of the
+// output downstream. The processing time is the latest of any replica.
+func (n *merge) advance(ctx context.Context, replica int, t exec.Time) error {
+	n.Serial.Lock()
+	defer n.Serial.Unlock()
+
+	n.watermarks[replica] = t.Watermark
+
+	min := n.watermarks[0]
+	for _, w := range n.watermarks[1:] {
+		if time.Time(w).Before(time.Time(min)) {
+			min = w
+		}
+	}
+	n.now.Watermark = min
+	if t.ProcessingTime.After(n.now.ProcessingTime) {
+		n.now.ProcessingTime = t.ProcessingTime
+	}
+	return exec.MultiAdvanceTime(ctx, n.now, n.Out)
+}
+
+// latest returns the later watermark and processing time of a and b.
+func latest(a, b exec.Time) exec.Time {
+	if time.Time(b.Watermark).After(time.Time(a.Watermark)) {
+		a.Watermark = b.Watermark
+	}
+	if b.ProcessingTime.After(a.ProcessingTime) {
+		a.ProcessingTime = b.ProcessingTime
+	}
+	return a
+}
+
+func (n *merge) FinishBundle(ctx context.Context) error {
+	n.Serial.Lock()
+	defer n.Serial.Unlock()
+
+	n.finished++
+	if n.finished < n.N {
+		return nil
+	}
+	n.started = 0
+	return n.Out.FinishBundle(ctx)
+}
+
+func (n *merge) Down(ctx context.Context) error {
+	return nil
+}
+
+func (n *merge) String() string {
+	return fmt.Sprintf("Merge[%v] Out:%v", n.N, n.Out.ID())
+}
+
+// mergeInput is the input of a merge from a single replica. It advances the
+// time of that replica only.
+type mergeInput struct {
+	UID exec.UnitID
+	N   int
+	Out *merge
+}
+
+func (n *mergeInput) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *mergeInput) Up(ctx context.Context) error {
+	return nil
+}
+
+func (n *mergeInput) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	return n.Out.StartBundle(ctx, id, data)
+}
+
+func (n *mergeInput) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	return n.Out.ProcessElement(ctx, elm, values...)
+}
+
+// AdvanceTime advances the time of the replica.
+func (n *mergeInput) AdvanceTime(ctx context.Context, t exec.Time) error {
+	return n.Out.advance(ctx, n.N, t)
+}
+
+func (n *mergeInput) FinishBundle(ctx context.Context) error {
+	return n.Out.FinishBundle(ctx)
+}
+
+func (n *mergeInput) Down(ctx context.Context) error {
+	return nil
+}
+
+func (n *mergeInput) String() string {
+	return fmt.Sprintf("MergeInput[%v] Out:%v", n.N, n.Out.ID())
+}
+
+// serialRoot runs a root under the serial lock.
+type serialRoot struct {
+	exec.Root
+	Serial *sync.Mutex
+}
+
+func (n *serialRoot) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	n.Serial.Lock()
+	defer n.Serial.Unlock()
+	return n.Root.StartBundle(ctx, id, data)
+}
+
+func (n *serialRoot) Process(ctx context.Context) error {
+	n.Serial.Lock()
+	defer n.Serial.Unlock()
+	return n.Root.Process(ctx)
+}
+
+func (n *serialRoot) FinishBundle(ctx context.Context) error {
+	n.Serial.Lock()
+	defer n.Serial.Unlock()
+	return n.Root.FinishBundle(ctx)
+}
+
+// replicas is the state shared by the builders of the replicas of a
+// segment: the merges into the nodes outside the segment.
+type replicas struct {
+	n     int
+	head  linkID
+	nodes map[int]*merge
+	links map[linkID]*merge
+}
+
+// copyable returns true iff the DoFn can be copied for a replica.
+func copyable(fn *graph.DoFn) bool {
+	_, err := cloneFn((*graph.Fn)(fn))
+	return err == nil
+}
+
+// cloneFn returns a copy of the struct receiver of the function, if any, as
+// if serialized, so that replicas do not share mutable state.
+func cloneFn(fn *graph.Fn) (*graph.Fn, error) {
+	if fn.Recv == nil {
+		return fn, nil
+	}
+	data, err := json.Marshal(fn.Recv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %v: %v", fn.Name(), err)
+	}
+	recv, err := reflectx.UnmarshalJSON(reflect.TypeOf(fn.Recv), string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %v: %v", fn.Name(), err)
+	}
+	return graph.NewFn(recv)
+}
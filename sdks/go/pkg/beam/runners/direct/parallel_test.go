@@ -0,0 +1,97 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/timers"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func square(x int) int {
+	return x * x
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func formatKeyed(key string, v int) string {
+	return fmt.Sprintf("%v:%v", key, v)
+}
+
+func TestParallelism(t *testing.T) {
+	defer flag.Set("direct_parallelism", flag.Lookup("direct_parallelism").Value.String())
+	if err := flag.Set("direct_parallelism", "4"); err != nil {
+		t.Fatalf("failed to set parallelism: %v", err)
+	}
+
+	// The input spans several bundles. The final running sum of each key is
+	// only correct, if no two workers process the same key.
+
+	const n = 1000
+	var in, squares []interface{}
+	for i := 1; i <= n; i++ {
+		in = append(in, i)
+		squares = append(squares, i*i)
+	}
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	values := beam.Create(s, in...)
+
+	passert.Equals(s, beam.ParDo(s, square, values), squares...)
+
+	keyed := beam.ParDo(s, keyByParity, values)
+	running := beam.ParDo(s, &runningSumFn{Sum: state.MakeValue("sum")}, keyed)
+	totals := beam.ParDo(s, formatKeyed, beam.CombinePerKey(s, maxInt, running))
+	passert.Equals(s, totals, "odd:250000", "even:250500")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
+
+func TestParallelismWatermark(t *testing.T) {
+	defer flag.Set("direct_parallelism", flag.Lookup("direct_parallelism").Value.String())
+	if err := flag.Set("direct_parallelism", "4"); err != nil {
+		t.Fatalf("failed to set parallelism: %v", err)
+	}
+
+	// The watermark advances through the replicas of keyByParity, so the
+	// timer of each number fires before the next number is emitted.
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	numbers := beam.ParDo(s, &pollNumbersFn{}, beam.Create(s, 4))
+
+	keyed := beam.ParDo(s, keyByParity, numbers)
+	batches := beam.ParDo(s, &batchFn{Buffer: state.MakeBag("buffer"), Flush: timers.InEventTime("flush")}, keyed)
+	passert.Equals(s, beam.ParDo(s, formatBatch, batches), "even:[0]", "odd:[1]", "even:[2]", "odd:[3]")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
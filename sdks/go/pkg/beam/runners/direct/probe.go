@@ -0,0 +1,67 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+)
+
+// probe reports the elements and time of a PCollection to a Probe and
+// forwards them downstream.
+type probe struct {
+	UID   exec.UnitID
+	Node  int
+	Probe Probe
+	Out   exec.Node
+}
+
+func (n *probe) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *probe) Up(ctx context.Context) error {
+	return nil
+}
+
+func (n *probe) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	return n.Out.StartBundle(ctx, id, data)
+}
+
+func (n *probe) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	n.Probe.Element(n.Node)
+	return n.Out.ProcessElement(ctx, elm, values...)
+}
+
+// AdvanceTime reports the time and forwards it downstream.
+func (n *probe) AdvanceTime(ctx context.Context, t exec.Time) error {
+	n.Probe.Time(n.Node, t)
+	return exec.MultiAdvanceTime(ctx, t, n.Out)
+}
+
+func (n *probe) FinishBundle(ctx context.Context) error {
+	return n.Out.FinishBundle(ctx)
+}
+
+func (n *probe) Down(ctx context.Context) error {
+	return nil
+}
+
+func (n *probe) String() string {
+	return fmt.Sprintf("Probe[%v]. Out:%v", n.Node, n.Out.ID())
+}
@@ -0,0 +1,153 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+// result is the result of a pipeline executed in-process.
+type result struct {
+	plan   *exec.Plan
+	cancel context.CancelFunc
+	done   chan struct{}
+	info   buildinfo.Info
+
+	mu        sync.Mutex
+	finished  bool
+	cancelled bool
+	err       error              // error of the finished job
+	metrics   *metrics.ResultSet // metrics of the finished job
+}
+
+// execute executes the plan in the background.
+func execute(ctx context.Context, plan *exec.Plan) *result {
+	r := &result{plan: plan, done: make(chan struct{}), info: buildinfo.Get()}
+
+	var run context.Context
+	run, r.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(r.done)
+		defer r.cancel()
+
+		err := plan.Execute(run, "", nil)
+		if err != nil {
+			plan.Down(ctx) // ignore any teardown errors
+		} else {
+			err = plan.Down(ctx)
+		}
+
+		// The metrics of a successful plan are cleared, such that they are
+		// not aggregated with those of later runs in the same process.
+
+		set := metrics.NewResultSet(metrics.BundleResults(plan.ID())).WithProgress(metrics.BundleProgress(plan.ID()))
+		if err == nil {
+			metrics.DumpToLog(ctx)
+			metrics.ClearBundleData(plan.ID())
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.finished, r.err, r.metrics = true, err, set
+		if r.cancelled {
+			log.Infof(ctx, "Job cancelled")
+			r.err = nil
+		}
+	}()
+	return r
+}
+
+func (r *result) JobID() string {
+	return r.plan.ID()
+}
+
+func (r *result) State(ctx context.Context) (beam.JobState, error) {
+	select {
+	case <-r.done:
+	default:
+		return beam.JobRunning, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case r.cancelled:
+		return beam.JobCancelled, nil
+	case r.err != nil:
+		return beam.JobFailed, nil
+	default:
+		return beam.JobDone, nil
+	}
+}
+
+func (r *result) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Cancel cancels the context of the job, which stops it once the current
+// element is processed. It has no effect on a finished job.
+func (r *result) Cancel(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.finished {
+		r.cancelled = true
+		r.cancel()
+	}
+	return nil
+}
+
+// Drain drains the units of the plan. It has no effect on a finished job.
+func (r *result) Drain(ctx context.Context) error {
+	select {
+	case <-r.done:
+	default:
+		r.plan.Drain()
+	}
+	return nil
+}
+
+// Metrics returns the metrics reported so far, if the job is running.
+func (r *result) Metrics(ctx context.Context) (*metrics.ResultSet, error) {
+	select {
+	case <-r.done:
+	default:
+		return metrics.NewResultSet(metrics.BundleResults(r.plan.ID())).WithProgress(metrics.BundleProgress(r.plan.ID())), nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.metrics, nil
+}
+
+func (r *result) BuildInfo() buildinfo.Info {
+	return r.info
+}
@@ -0,0 +1,192 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"context"
+	"flag"
+	"math"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/go/pkg/beam/runners/direct"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*tickFn)(nil)).Elem())
+}
+
+var (
+	elements = beam.NewCounter("test", "elements")
+	ticks    = beam.NewCounter("test", "ticks")
+)
+
+func countFn(ctx context.Context, x int) int {
+	elements.Inc(ctx, 1)
+	return x
+}
+
+func TestResultMetrics(t *testing.T) {
+	// The metrics of each run are separate.
+
+	for i := 0; i < 2; i++ {
+		p := beam.NewPipeline()
+		s := p.Root()
+		beam.ParDo(s, countFn, beam.Create(s, 1, 2, 3))
+
+		res, err := direct.Execute(context.Background(), p)
+		if err != nil {
+			t.Fatalf("pipeline failed: %v", err)
+		}
+		if state, err := res.State(context.Background()); err != nil || state != beam.JobDone {
+			t.Errorf("State() = %v, %v, want DONE", state, err)
+		}
+		if err := res.Wait(context.Background()); err != nil {
+			t.Errorf("Wait() failed: %v", err)
+		}
+
+		set, err := res.Metrics(context.Background())
+		if err != nil {
+			t.Fatalf("Metrics() failed: %v", err)
+		}
+		q := set.Query(metrics.Filter{Step: "direct_test.countFn", Name: "elements"})
+		if len(q.Counters) != 1 || q.Counters[0].Value != 3 {
+			t.Errorf("run %v: counters = %+v, want elements of 3", i, q.Counters)
+		}
+
+		// The element counts are reported as progress, not as user metrics.
+
+		if all := set.All(); len(all) != 1 {
+			t.Errorf("run %v: metrics = %+v, want only the user counter", i, all)
+		}
+		var found bool
+		for _, pr := range set.Progress() {
+			if pr.PTransform == "direct_test.countFn" {
+				found = true
+				if pr.Processed["i0"] != 3 || pr.Output["i0"] != 3 {
+					t.Errorf("run %v: progress = %+v, want 3 elements processed and output", i, pr)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("run %v: progress = %+v, want progress of countFn", i, set.Progress())
+		}
+	}
+}
+
+func TestResultBuildInfo(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	beam.ParDo(s, countFn, beam.Create(s, 1))
+
+	res, err := direct.Execute(context.Background(), p)
+	if err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+	if info := res.BuildInfo(); info.SDKVersion != buildinfo.SDKVersion || info.GoVersion != runtime.Version() {
+		t.Errorf("BuildInfo() = %+v, want SDK %v and Go %v", info, buildinfo.SDKVersion, runtime.Version())
+	}
+}
+
+// tickFn emits increasing numbers a millisecond apart from an unbounded
+// restriction, which is truncated to nothing when drained.
+type tickFn struct{}
+
+func (f *tickFn) CreateInitialRestriction(_ int) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: math.MaxInt64}
+}
+
+func (f *tickFn) CreateTracker(r offsetrange.Restriction) *offsetrange.Tracker {
+	return offsetrange.NewTracker(r)
+}
+
+func (f *tickFn) TruncateRestriction(_ int, r offsetrange.Restriction) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: r.Start, End: r.Start}
+}
+
+func (f *tickFn) ProcessElement(ctx context.Context, rt *offsetrange.Tracker, _ int, emit func(int64)) sdf.ProcessContinuation {
+	i := rt.GetRestriction().(offsetrange.Restriction).Start
+	if !rt.TryClaim(i) {
+		return sdf.StopProcessing()
+	}
+	ticks.Inc(ctx, 1)
+	emit(i)
+	return sdf.ResumeProcessingIn(time.Millisecond)
+}
+
+func TestResultStop(t *testing.T) {
+	defer flag.Set("async", flag.Lookup("async").Value.String())
+	if err := flag.Set("async", "true"); err != nil {
+		t.Fatalf("failed to set async: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		stop func(beam.PipelineResult, context.Context) error
+		want beam.JobState
+	}{
+		{"drain", beam.PipelineResult.Drain, beam.JobDone},
+		{"cancel", beam.PipelineResult.Cancel, beam.JobCancelled},
+	}
+	for _, test := range tests {
+		p := beam.NewPipeline()
+		s := p.Root()
+		beam.ParDo(s, &tickFn{}, beam.Create(s, 0))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		res, err := direct.Execute(ctx, p)
+		if err != nil {
+			t.Fatalf("%v: pipeline failed: %v", test.name, err)
+		}
+
+		// The job runs until stopped.
+
+		for n := int64(0); n < 3; {
+			if ctx.Err() != nil {
+				t.Fatalf("%v: job did not start: %v", test.name, ctx.Err())
+			}
+			time.Sleep(10 * time.Millisecond)
+			set, err := res.Metrics(ctx)
+			if err != nil {
+				t.Fatalf("%v: Metrics() failed: %v", test.name, err)
+			}
+			if q := set.Query(metrics.Filter{Name: "ticks"}); len(q.Counters) == 1 {
+				n = q.Counters[0].Value
+			}
+		}
+		if state, _ := res.State(ctx); state != beam.JobRunning {
+			t.Errorf("%v: State() = %v, want RUNNING", test.name, state)
+		}
+
+		if err := test.stop(res, ctx); err != nil {
+			t.Fatalf("%v: stopping failed: %v", test.name, err)
+		}
+		if err := res.Wait(ctx); err != nil {
+			t.Errorf("%v: Wait() failed: %v", test.name, err)
+		}
+		if state, _ := res.State(ctx); state != test.want {
+			t.Errorf("%v: State() = %v, want %v", test.name, state, test.want)
+		}
+		cancel()
+	}
+}
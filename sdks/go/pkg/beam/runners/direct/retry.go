@@ -0,0 +1,299 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+var (
+	bundleRetries   = flag.Int("direct_bundle_retries", 0, "Number of times the direct runner retries a failed bundle of a ParDo. Disabled if 0 (optional).")
+	retryBackoff    = flag.Duration("direct_retry_backoff", 100*time.Millisecond, "Delay before the first retry of a failed bundle in the direct runner, doubled for each further retry (optional).")
+	retryMaxBackoff = flag.Duration("direct_retry_max_backoff", 10*time.Second, "Maximum delay before retrying a failed bundle in the direct runner (optional).")
+)
+
+// retry processes the input of a chain of ParDos in bundles, which are
+// retried with exponential backoff if they fail. The ParDos of the chain are
+// fused: each ParDo outputs directly to the next, such that elements pass
+// through the chain one at a time. Only the outputs of the chain are held
+// back until the bundle succeeds, such that a retried bundle is processed as
+// if it never failed: the ParDos of a failed bundle are torn down and
+// replaced by new ones, as if their DoFns were deserialized anew, and the
+// metrics reported by the bundle are discarded. The PCollections within the
+// chain are observed by the probe, if any, for failed bundles as well.
+//
+// Time is advanced downstream directly, as the ParDos are neither stateful
+// nor splittable.
+type retry struct {
+	UID        exec.UnitID
+	New        func() ([]*exec.ParDo, error) // returns a new chain that outputs to Out, the head last
+	Out        []*commit
+	Retries    int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	pardos   []*exec.ParDo
+	id       string
+	data     exec.DataManager
+	pending  []item
+	attempts int
+}
+
+func (n *retry) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *retry) Up(ctx context.Context) error {
+	n.attempts = 0
+	return n.replace(ctx)
+}
+
+// replace sets up a new chain.
+func (n *retry) replace(ctx context.Context) error {
+	list, err := n.New()
+	if err != nil {
+		return err
+	}
+	n.pardos = list
+	for _, p := range n.pardos {
+		if err := p.Up(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// head returns the first ParDo of the chain.
+func (n *retry) head() *exec.ParDo {
+	return n.pardos[len(n.pardos)-1]
+}
+
+func (n *retry) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	n.id, n.data, n.pending = id, data, nil
+	return exec.MultiStartBundle(ctx, id, data, n.downstream()...)
+}
+
+func (n *retry) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	n.pending = append(n.pending, item{elm: elm, values: values})
+	if len(n.pending) < bundleSize {
+		return nil
+	}
+	return n.flush(ctx)
+}
+
+func (n *retry) AdvanceTime(ctx context.Context, t exec.Time) error {
+	if err := n.flush(ctx); err != nil {
+		return err
+	}
+	return exec.MultiAdvanceTime(ctx, t, n.downstream()...)
+}
+
+func (n *retry) FinishBundle(ctx context.Context) error {
+	if err := n.flush(ctx); err != nil {
+		return err
+	}
+	return exec.MultiFinishBundle(ctx, n.downstream()...)
+}
+
+// flush processes the pending input as a bundle, retrying it until it
+// succeeds or the retries are exhausted. The output of the bundle is then
+// passed downstream.
+func (n *retry) flush(ctx context.Context) error {
+	if len(n.pending) == 0 {
+		return nil
+	}
+
+	backoff := n.Backoff
+	for retries := 0; ; retries++ {
+		err := n.attempt(ctx)
+		if err == nil {
+			break
+		}
+		if retries == n.Retries {
+			return err
+		}
+
+		log.Warnf(ctx, "Bundle of %v failed, retrying in %v: %v", n.head().Fn.Name(), backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > n.MaxBackoff {
+			backoff = n.MaxBackoff
+		}
+
+		n.Down(ctx) // ignore any teardown errors
+		if err := n.replace(ctx); err != nil {
+			return err
+		}
+	}
+	n.pending = nil
+
+	for _, c := range n.Out {
+		if err := c.commit(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt processes the pending input as a bundle of the chain. The metrics
+// of the bundle are merged into those of the plan, if it succeeds, and
+// discarded otherwise.
+func (n *retry) attempt(ctx context.Context) (err error) {
+	n.attempts++
+	plan := metrics.BundleID(ctx)
+	bundle := fmt.Sprintf("%v/retry%v.%v", plan, n.UID, n.attempts)
+	ctx = metrics.SetBundleID(ctx, bundle)
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic in %v: %v %s", n.head().Fn.Name(), p, debug.Stack())
+		}
+		if err != nil {
+			metrics.ClearBundleData(bundle)
+			for _, c := range n.Out {
+				c.discard()
+			}
+			return
+		}
+		metrics.MergeBundleData(bundle, plan)
+	}()
+
+	head := n.head()
+	if err := head.StartBundle(ctx, n.id, n.data); err != nil {
+		return err
+	}
+	for _, it := range n.pending {
+		if err := head.ProcessElement(ctx, it.elm, it.values...); err != nil {
+			return err
+		}
+	}
+	return head.FinishBundle(ctx)
+}
+
+// downstream returns the nodes that the chain outputs to.
+func (n *retry) downstream() []exec.Node {
+	var ret []exec.Node
+	for _, c := range n.Out {
+		ret = append(ret, c.Out)
+	}
+	return ret
+}
+
+func (n *retry) Down(ctx context.Context) error {
+	var ret error
+	for _, p := range n.pardos {
+		if err := p.Down(ctx); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+func (n *retry) String() string {
+	return fmt.Sprintf("Retry[%v] Chain:%v Out:%v", n.Retries, len(n.pardos), exec.IDs(n.downstream()...))
+}
+
+// chain is a linear chain of ParDos being built for a retry, in order of
+// construction: each ParDo is built after those it outputs to, so the head
+// is last. Outputs that are not consumed within the chain are held back by
+// the commit nodes.
+type chain struct {
+	pardos []*exec.ParDo
+	out    []*commit
+}
+
+// relink returns the node that a new ParDo outputs to, given the node of
+// the ParDo it replaces and the replacements made so far.
+func relink(n exec.Node, clones map[exec.Node]exec.Node) exec.Node {
+	if p, ok := n.(*probe); ok {
+		if q, ok := clones[p.Out]; ok {
+			cp := *p
+			cp.Out = q
+			return &cp
+		}
+	}
+	if q, ok := clones[n]; ok {
+		return q
+	}
+	return n
+}
+
+// commit holds back an output of a chain of ParDos until the bundle that produced it
+// succeeds. The bundle of the downstream node is started and finished by the
+// retry.
+type commit struct {
+	UID exec.UnitID
+	Out exec.Node
+
+	buf []item
+}
+
+func (n *commit) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *commit) Up(ctx context.Context) error {
+	return nil
+}
+
+func (n *commit) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	return nil
+}
+
+func (n *commit) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	n.buf = append(n.buf, item{elm: elm, values: values})
+	return nil
+}
+
+// commit passes the output held back downstream.
+func (n *commit) commit(ctx context.Context) error {
+	buf := n.buf
+	n.buf = nil
+	for _, it := range buf {
+		if err := n.Out.ProcessElement(ctx, it.elm, it.values...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discard drops the output held back.
+func (n *commit) discard() {
+	n.buf = nil
+}
+
+func (n *commit) FinishBundle(ctx context.Context) error {
+	return nil
+}
+
+func (n *commit) Down(ctx context.Context) error {
+	return nil
+}
+
+func (n *commit) String() string {
+	return fmt.Sprintf("Commit Out:%v", n.Out.ID())
+}
@@ -0,0 +1,132 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/runners/direct"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+)
+
+var (
+	processed = beam.NewCounter("test", "processed")
+
+	// flakyFailures is the number of times flakyFn fails.
+	flakyFailures int32
+)
+
+// flakyFn fails while processing the element 5 until it has failed
+// flakyFailures times. The output of the failed bundles is discarded.
+func flakyFn(ctx context.Context, x int, emit func(int)) error {
+	emit(x)
+	if x == 5 && atomic.AddInt32(&flakyFailures, -1) >= 0 {
+		return errors.New("transient failure")
+	}
+	processed.Inc(ctx, 1)
+	return nil
+}
+
+func TestBundleRetry(t *testing.T) {
+	for _, name := range []string{"direct_bundle_retries", "direct_retry_backoff"} {
+		defer flag.Set(name, flag.Lookup(name).Value.String())
+	}
+	flag.Set("direct_bundle_retries", "2")
+	flag.Set("direct_retry_backoff", "1ms")
+
+	tests := []struct {
+		failures int32
+		ok       bool
+	}{
+		{0, true},
+		{2, true},
+		{3, false}, // retries exhausted
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&flakyFailures, test.failures)
+
+		p := beam.NewPipeline()
+		s := p.Root()
+		col := beam.ParDo(s, countFn, beam.Create(s, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10))
+		out := beam.ParDo(s, flakyFn, col)
+		passert.Equals(s, out, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+		res, err := direct.Execute(context.Background(), p)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%v failures: pipeline succeeded, want error", test.failures)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v failures: pipeline failed: %v", test.failures, err)
+		}
+
+		// The metrics of the failed bundles are discarded, including those
+		// of the ParDos fused with the failing one.
+
+		set, _ := res.Metrics(context.Background())
+		for _, name := range []string{"elements", "processed"} {
+			if q := set.Query(metrics.Filter{Name: name}); len(q.Counters) != 1 || q.Counters[0].Value != 10 {
+				t.Errorf("%v failures: %v = %+v, want 10", test.failures, name, q.Counters)
+			}
+		}
+	}
+}
+
+func TestRetryChain(t *testing.T) {
+	for _, name := range []string{"direct_bundle_retries", "direct_parallelism"} {
+		defer flag.Set(name, flag.Lookup(name).Value.String())
+	}
+	flag.Set("direct_bundle_retries", "1")
+	// Chains are built once per replica otherwise, so the plan is serial.
+	flag.Set("direct_parallelism", "1")
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	col := beam.ParDo(s, countFn, beam.Create(s, 1, 2, 3))
+	out := beam.ParDo(s, flakyFn, col)
+	beam.ParDo(s, countFn, out)
+	beam.ParDo(s, countFn, out)
+
+	edges, _, err := p.Build()
+	if err != nil {
+		t.Fatalf("invalid pipeline: %v", err)
+	}
+	plan, err := direct.Compile(edges)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	// Create, countFn and flakyFn are fused into a single chain, as each
+	// output is consumed by the next alone. The output of flakyFn has two
+	// consumers, which each start a chain.
+
+	str := plan.String()
+	if n := strings.Count(str, "Retry["); n != 3 {
+		t.Errorf("plan has %v retries, want 3:\n%v", n, str)
+	}
+	if !strings.Contains(str, "Chain:3") {
+		t.Errorf("plan has no chain of 3 ParDos:\n%v", str)
+	}
+}
@@ -0,0 +1,172 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/timers"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*wordsFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*badTrackerFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*pollWordsFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*continuationFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*pollNumbersFn)(nil)).Elem())
+}
+
+// wordsFn emits the words of a line, processing the line as a range of word
+// indices split into chunks of two words.
+type wordsFn struct{}
+
+func (f *wordsFn) CreateInitialRestriction(line string) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: int64(len(strings.Fields(line)))}
+}
+
+func (f *wordsFn) SplitRestriction(line string, r offsetrange.Restriction) []offsetrange.Restriction {
+	return r.SizedSplits(2)
+}
+
+func (f *wordsFn) CreateTracker(r offsetrange.Restriction) *offsetrange.Tracker {
+	return offsetrange.NewTracker(r)
+}
+
+func (f *wordsFn) ProcessElement(rt *offsetrange.Tracker, line string, emit func(string)) {
+	words := strings.Fields(line)
+	for i := rt.GetRestriction().(offsetrange.Restriction).Start; rt.TryClaim(i); i++ {
+		emit(words[i])
+	}
+}
+
+// pollWordsFn emits a single word of a line at a time and asks to resume
+// the rest of the line later, as if the words arrived over time.
+type pollWordsFn struct {
+	wordsFn
+}
+
+func (f *pollWordsFn) ProcessElement(rt *offsetrange.Tracker, line string, emit func(string)) sdf.ProcessContinuation {
+	i := rt.GetRestriction().(offsetrange.Restriction).Start
+	if !rt.TryClaim(i) {
+		return sdf.StopProcessing()
+	}
+	emit(strings.Fields(line)[i])
+	return sdf.ResumeProcessingIn(time.Millisecond)
+}
+
+// pollNumbersFn emits the numbers below n, one at a time and a minute apart
+// in event time, as if they arrived from an unbounded source. The watermark
+// is reported to be at the next number.
+type pollNumbersFn struct{}
+
+func (f *pollNumbersFn) CreateInitialRestriction(n int) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: int64(n)}
+}
+
+func (f *pollNumbersFn) CreateTracker(r offsetrange.Restriction) *offsetrange.Tracker {
+	return offsetrange.NewTracker(r)
+}
+
+func (f *pollNumbersFn) ProcessElement(rt *offsetrange.Tracker, n int, emit func(typex.EventTime, int)) sdf.ProcessContinuation {
+	i := rt.GetRestriction().(offsetrange.Restriction).Start
+	if !rt.TryClaim(i) {
+		return sdf.StopProcessing()
+	}
+	emit(typex.EventTime(minutes(i)), int(i))
+	return sdf.ResumeProcessingIn(time.Millisecond).WithWatermark(minutes(i + 1))
+}
+
+func minutes(i int64) time.Time {
+	return time.Unix(1000, 0).Add(time.Duration(i) * time.Minute)
+}
+
+// continuationFn returns a process continuation without being splittable.
+type continuationFn struct{}
+
+func (f *continuationFn) ProcessElement(line string) sdf.ProcessContinuation {
+	return sdf.StopProcessing()
+}
+
+// badTrackerFn creates a tracker of a restriction type other than the
+// initial restriction.
+type badTrackerFn struct {
+	wordsFn
+}
+
+func (f *badTrackerFn) CreateTracker(r int64) *offsetrange.Tracker {
+	return offsetrange.NewTracker(offsetrange.Restriction{End: r})
+}
+
+func TestSplittableDoFn(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	lines := beam.Create(s, "a b c", "d e", "", "f g h i j")
+
+	words := beam.ParDo(s, &wordsFn{}, lines)
+	passert.Equals(s, words, "a", "b", "c", "d", "e", "f", "g", "h", "i", "j")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+
+	if _, err := beam.TryParDo(s, &badTrackerFn{}, lines); err == nil {
+		t.Errorf("TryParDo(badTrackerFn) succeeded, want error")
+	}
+}
+
+func TestSplittableDoFnCheckpoint(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	lines := beam.Create(s, "a b c", "d e", "", "f g h i j")
+
+	words := beam.ParDo(s, &pollWordsFn{}, lines)
+	passert.Equals(s, words, "a", "b", "c", "d", "e", "f", "g", "h", "i", "j")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+
+	if _, err := beam.TryParDo(s, &continuationFn{}, lines); err == nil {
+		t.Errorf("TryParDo(continuationFn) succeeded, want error")
+	}
+}
+
+func TestSplittableDoFnWatermark(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	numbers := beam.ParDo(s, &pollNumbersFn{}, beam.Create(s, 4))
+
+	// The timer of each number fires as the watermark advances past it,
+	// before the next number is emitted.
+
+	keyed := beam.ParDo(s, keyByParity, numbers)
+	batches := beam.ParDo(s, &batchFn{Buffer: state.MakeBag("buffer"), Flush: timers.InEventTime("flush")}, keyed)
+	passert.Equals(s, beam.ParDo(s, formatBatch, batches), "even:[0]", "odd:[1]", "even:[2]", "odd:[3]")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
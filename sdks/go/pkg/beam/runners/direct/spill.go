@@ -0,0 +1,305 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// gbkMemory is the memory budget of each GroupByKey in MB.
+var gbkMemory = flag.Int("direct_gbk_memory_mb", 0, "Memory budget in MB for the values held by each GroupByKey in the direct runner, above which they are spilled to disk in sorted runs. Only applies to globally windowed input with the default trigger. Disabled if 0 (optional).")
+
+// spiller groups the values of a CoGBK externally. Whenever the encoded size
+// of the values held in memory exceeds the budget, the groups are written to
+// a temporary file as a run sorted by encoded key. Once the input is
+// exhausted, the runs are merged by key. The values of a single key are held
+// in memory when emitted.
+type spiller struct {
+	budget int64
+	key    exec.ElementDecoder
+	encs   []exec.ElementEncoder // values, by input index
+	decs   []exec.ElementDecoder
+
+	size int64    // encoded size of the values in memory
+	runs []string // paths of the spill files
+	buf  bytes.Buffer
+}
+
+func newSpiller(edge *graph.MultiEdge, budget int64) *spiller {
+	s := &spiller{budget: budget, key: exec.MakeElementDecoder(edge.Input[0].From.Coder.Components[0])}
+	for _, in := range edge.Input {
+		c := in.From.Coder.Components[1]
+		s.encs = append(s.encs, exec.MakeElementEncoder(c))
+		s.decs = append(s.decs, exec.MakeElementDecoder(c))
+	}
+	return s
+}
+
+// add accounts for a value added to the groups in memory. It returns true if
+// the groups exceed the budget and should be spilled.
+func (s *spiller) add(index int, value exec.FullValue) (bool, error) {
+	s.buf.Reset()
+	if err := s.encs[index].Encode(value, &s.buf); err != nil {
+		return false, fmt.Errorf("failed to encode value %v for CoGBK: %v", value, err)
+	}
+	s.size += int64(s.buf.Len())
+	return s.size > s.budget, nil
+}
+
+// spilled returns true iff any runs were written.
+func (s *spiller) spilled() bool {
+	return len(s.runs) > 0
+}
+
+// write writes the groups, by encoded key, as a sorted run. Each group is
+// written as its key and timestamp followed by the number and the values of
+// each input, with their timestamps.
+func (s *spiller) write(groups map[string]*group) (err error) {
+	f, err := ioutil.TempFile("", "beam-gbk-")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %v", err)
+	}
+	s.runs = append(s.runs, f.Name())
+	defer func() {
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("failed to close spill file: %v", cerr)
+		}
+	}()
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := bufio.NewWriter(f)
+	for _, key := range keys {
+		if err := writeBytes([]byte(key), w); err != nil {
+			return fmt.Errorf("failed to spill key: %v", err)
+		}
+		g := groups[key]
+		if err := writeTime(g.key.Timestamp, w); err != nil {
+			return fmt.Errorf("failed to spill key: %v", err)
+		}
+		for i, list := range g.values {
+			if err := coder.EncodeVarInt(int32(len(list)), w); err != nil {
+				return fmt.Errorf("failed to spill values: %v", err)
+			}
+			for _, v := range list {
+				if err := writeTime(v.Timestamp, w); err != nil {
+					return fmt.Errorf("failed to spill value: %v", err)
+				}
+				if err := s.encs[i].Encode(v, w); err != nil {
+					return fmt.Errorf("failed to spill value: %v", err)
+				}
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %v", err)
+	}
+	s.size = 0
+	return nil
+}
+
+// merge merge-reads the runs and emits the values of each key, in the order
+// they were added.
+func (s *spiller) merge(emit func(key exec.FullValue, values []exec.ReStream) error) error {
+	var h runHeap
+	for i, path := range s.runs {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open spill file: %v", err)
+		}
+		defer f.Close()
+
+		r := &run{index: i, r: bufio.NewReader(f), decs: s.decs}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+
+	for len(h) > 0 {
+		key, t := h[0].key, h[0].timestamp
+		lists := make([][]exec.FullValue, len(s.decs))
+		for len(h) > 0 && h[0].key == key {
+			r := h[0]
+			for i, list := range r.values {
+				lists[i] = append(lists[i], list...)
+			}
+			ok, err := r.next()
+			if err != nil {
+				return err
+			}
+			if ok {
+				heap.Fix(&h, 0)
+			} else {
+				heap.Pop(&h)
+			}
+		}
+
+		k, err := s.key.Decode(bytes.NewReader([]byte(key)))
+		if err != nil {
+			return fmt.Errorf("failed to decode spilled key: %v", err)
+		}
+		values := make([]exec.ReStream, len(lists))
+		for i, list := range lists {
+			values[i] = &exec.FixedReStream{Buf: list}
+		}
+		if err := emit(exec.FullValue{Elm: k.Elm, Timestamp: t}, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close removes the spill files.
+func (s *spiller) Close() error {
+	var ret error
+	for _, path := range s.runs {
+		if err := os.Remove(path); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	s.runs = nil
+	s.size = 0
+	return ret
+}
+
+// run reads the groups of a spill file in order.
+type run struct {
+	index int // runs are merged in the order they were written
+	r     *bufio.Reader
+	decs  []exec.ElementDecoder
+
+	key       string
+	timestamp typex.EventTime
+	values    [][]exec.FullValue
+}
+
+// next reads the next group. It returns false at the end of the run.
+func (r *run) next() (bool, error) {
+	key, err := readBytes(r.r)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read spilled key: %v", err)
+	}
+	r.key = string(key)
+	if r.timestamp, err = readTime(r.r); err != nil {
+		return false, fmt.Errorf("failed to read spilled key: %v", err)
+	}
+	r.values = make([][]exec.FullValue, len(r.decs))
+	for i, dec := range r.decs {
+		n, err := coder.DecodeVarInt(r.r)
+		if err != nil {
+			return false, fmt.Errorf("failed to read spilled values: %v", err)
+		}
+		for j := int32(0); j < n; j++ {
+			t, err := readTime(r.r)
+			if err != nil {
+				return false, fmt.Errorf("failed to read spilled value: %v", err)
+			}
+			v, err := dec.Decode(r.r)
+			if err != nil {
+				return false, fmt.Errorf("failed to read spilled value: %v", err)
+			}
+			v.Timestamp = t
+			r.values[i] = append(r.values[i], v)
+		}
+	}
+	return true, nil
+}
+
+// runHeap orders runs by their current key and then by the order they were
+// written.
+type runHeap []*run
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	if h[i].key != h[j].key {
+		return h[i].key < h[j].key
+	}
+	return h[i].index < h[j].index
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+func writeBytes(b []byte, w io.Writer) error {
+	if err := coder.EncodeVarInt(int32(len(b)), w); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// writeTime writes the timestamp, which is zero unless set by a source or a
+// DoFn in the direct runner.
+func writeTime(t typex.EventTime, w io.Writer) error {
+	if time.Time(t).IsZero() {
+		return coder.EncodeVarInt(0, w)
+	}
+	if err := coder.EncodeVarInt(1, w); err != nil {
+		return err
+	}
+	return coder.EncodeEventTime(t, w)
+}
+
+func readTime(r io.Reader) (typex.EventTime, error) {
+	set, err := coder.DecodeVarInt(r)
+	if err != nil || set == 0 {
+		return typex.EventTime{}, err
+	}
+	return coder.DecodeEventTime(r)
+}
+
+func readBytes(r io.Reader) ([]byte, error) {
+	n, err := coder.DecodeVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,203 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func TestSpillerMerge(t *testing.T) {
+	c := coder.NewVarInt()
+	enc, dec := exec.MakeElementEncoder(c), exec.MakeElementDecoder(c)
+	s := &spiller{budget: 0, key: dec, encs: []exec.ElementEncoder{enc}, decs: []exec.ElementDecoder{dec}}
+
+	groups := func(kvs map[int32][]int32) map[string]*group {
+		ret := make(map[string]*group)
+		for k, vs := range kvs {
+			var buf bytes.Buffer
+			enc.Encode(exec.FullValue{Elm: k}, &buf)
+			g := &group{key: exec.FullValue{Elm: k, Timestamp: at(int64(k))}, values: make([][]exec.FullValue, 1)}
+			for _, v := range vs {
+				if full, err := s.add(0, exec.FullValue{Elm: v}); err != nil || !full {
+					t.Fatalf("add(%v) = %v, %v, want over budget", v, full, err)
+				}
+				g.values[0] = append(g.values[0], exec.FullValue{Elm: v, Timestamp: at(int64(v))})
+			}
+			ret[buf.String()] = g
+		}
+		return ret
+	}
+
+	// The values of a key in several runs are emitted in the order of the runs.
+
+	runs := []map[int32][]int32{
+		{1: {1, 2}, 3: {3}},
+		{1: {4}, 2: {5}},
+	}
+	for _, run := range runs {
+		if err := s.write(groups(run)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if !s.spilled() {
+		t.Fatalf("spilled() = false, want true")
+	}
+	paths := s.runs
+
+	got := make(map[int32][]int32)
+	var keys []int32
+	err := s.merge(func(key exec.FullValue, values []exec.ReStream) error {
+		k := key.Elm.(int32)
+		if key.Timestamp != at(int64(k)) {
+			t.Errorf("key %v timestamp = %v, want %v", k, key.Timestamp, at(int64(k)))
+		}
+		keys = append(keys, k)
+		stream := values[0].Open()
+		defer stream.Close()
+		for {
+			v, err := stream.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if v.Timestamp != at(int64(v.Elm.(int32))) {
+				t.Errorf("value %v timestamp = %v, want %v", v.Elm, v.Timestamp, at(int64(v.Elm.(int32))))
+			}
+			got[k] = append(got[k], v.Elm.(int32))
+		}
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if want := []int32{1, 2, 3}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("merged keys = %v, want %v", keys, want)
+	}
+	if want := map[int32][]int32{1: {1, 2, 4}, 2: {5}, 3: {3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged values = %v, want %v", got, want)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("spill file %v not removed: %v", path, err)
+		}
+	}
+}
+
+// collect collects the grouped values of each key.
+type collect struct {
+	exec.Discard
+	got map[string][]int
+}
+
+func (n *collect) ProcessElement(ctx context.Context, elm exec.FullValue, values ...exec.ReStream) error {
+	stream := values[0].Open()
+	defer stream.Close()
+	for {
+		v, err := stream.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		key := exec.Convert(elm.Elm, reflectx.String).(string)
+		n.got[key] = append(n.got[key], v.Elm.(int))
+	}
+}
+
+func TestCoGBKSpill(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	kvs := beam.ParDo(s, func(x int) (string, int) {
+		if x%2 == 0 {
+			return "even", x
+		}
+		return "odd", x
+	}, beam.Create(s, 1))
+	beam.GroupByKey(s, kvs)
+
+	edges, _, err := p.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	var edge *graph.MultiEdge
+	for _, e := range edges {
+		if e.Op == graph.CoGBK {
+			edge = e
+		}
+	}
+
+	// With a budget of a single byte, every value spills the groups in memory.
+
+	ctx := context.Background()
+	out := &collect{Discard: exec.Discard{UID: 2}, got: make(map[string][]int)}
+	n := &CoGBK{UID: 1, Edge: edge, Budget: 1, Out: out}
+	if err := n.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := n.StartBundle(ctx, "bundle", nil); err != nil {
+		t.Fatalf("StartBundle failed: %v", err)
+	}
+	for i := 1; i <= 6; i++ {
+		key := "odd"
+		if i%2 == 0 {
+			key = "even"
+		}
+		elm := exec.FullValue{Elm: 0, Elm2: exec.FullValue{Elm: key, Elm2: i}}
+		if err := n.ProcessElement(ctx, elm); err != nil {
+			t.Fatalf("ProcessElement(%v) failed: %v", i, err)
+		}
+	}
+	if len(n.spill.runs) != 6 {
+		t.Errorf("spilled %v runs, want 6", len(n.spill.runs))
+	}
+	paths := n.spill.runs
+
+	if err := n.FinishBundle(ctx); err != nil {
+		t.Fatalf("FinishBundle failed: %v", err)
+	}
+	for _, list := range out.got {
+		sort.Ints(list)
+	}
+	if want := map[string][]int{"odd": {1, 3, 5}, "even": {2, 4, 6}}; !reflect.DeepEqual(out.got, want) {
+		t.Errorf("grouped values = %v, want %v", out.got, want)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("spill file %v not removed: %v", path, err)
+		}
+	}
+	if err := n.Down(ctx); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+}
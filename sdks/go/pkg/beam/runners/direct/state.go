@@ -0,0 +1,228 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// stateStore is an in-memory exec.StateStore for a single stateful ParDo.
+// State of the global window lives for the duration of the pipeline. State of
+// other windows is discarded once they expire.
+type stateStore struct {
+	keys   map[stateID]*keyState
+	active map[string][]window.IntervalWindow // merged windows by key
+	timers map[timerID]*timer
+	seq    int // order in which timers are set
+}
+
+func newStateStore() *stateStore {
+	return &stateStore{
+		keys:   make(map[stateID]*keyState),
+		active: make(map[string][]window.IntervalWindow),
+		timers: make(map[timerID]*timer),
+	}
+}
+
+func (s *stateStore) Provider(id string, key interface{}, w typex.Window) state.Provider {
+	sid := stateID{key: id, window: windowID(w)}
+	k, ok := s.keys[sid]
+	if !ok {
+		k = &keyState{id: sid, key: key, w: w, values: make(map[string]interface{}), store: s}
+		s.keys[sid] = k
+	}
+	return k
+}
+
+func (s *stateStore) NextTimer(now exec.Time) (exec.Timer, bool) {
+	var next *timer
+	for _, t := range s.timers {
+		limit := time.Time(now.Watermark)
+		if t.processing {
+			limit = now.ProcessingTime
+		}
+		if time.Time(t.at).After(limit) {
+			continue
+		}
+		if next == nil || t.before(next) {
+			next = t
+		}
+	}
+	if next == nil {
+		return exec.Timer{}, false
+	}
+	delete(s.timers, next.id)
+	k := s.keys[next.id.state]
+	return exec.Timer{Key: k.key, Window: k.w, Name: next.id.timer, Tag: next.id.tag, At: next.at, ProcessingTime: next.processing}, true
+}
+
+// MergeWindow merges the window into the active windows of the key and moves
+// the state and timers of windows that are merged into another.
+func (s *stateStore) MergeWindow(id string, key interface{}, w window.IntervalWindow, wfn *window.Window, merge exec.StateMergeFn) (window.IntervalWindow, error) {
+	list := append(s.active[id], w)
+	merged, index, err := wfn.MergeWindows(list)
+	if err != nil {
+		return window.IntervalWindow{}, err
+	}
+	for i, from := range list {
+		if into := merged[index[i]]; !from.Equals(into) {
+			s.move(stateID{key: id, window: windowID(from)}, s.Provider(id, key, into).(*keyState), merge)
+		}
+	}
+	s.active[id] = merged
+	return merged[index[len(list)-1]], nil
+}
+
+// Expire discards the state and timers of expired windows, such that state
+// does not grow without bound in long-running pipelines.
+func (s *stateStore) Expire(watermark typex.EventTime, wfn *window.Window) error {
+	for id, k := range s.keys {
+		if k.w != nil && wfn.IsExpired(k.w, watermark) {
+			delete(s.keys, id)
+		}
+	}
+	for id := range s.timers {
+		if _, ok := s.keys[id.state]; !ok {
+			delete(s.timers, id)
+		}
+	}
+	for id, list := range s.active {
+		var live []window.IntervalWindow
+		for _, w := range list {
+			if !wfn.IsExpired(w, watermark) {
+				live = append(live, w)
+			}
+		}
+		if len(live) == 0 {
+			delete(s.active, id)
+		} else {
+			s.active[id] = live
+		}
+	}
+	return nil
+}
+
+// move moves the state and timers of the given key and window into the
+// state of another window of the key.
+func (s *stateStore) move(from stateID, into *keyState, merge exec.StateMergeFn) {
+	src, ok := s.keys[from]
+	if !ok {
+		return
+	}
+	for cell, value := range src.values {
+		if old, ok := into.values[cell]; ok {
+			value = merge(cell, old, value)
+		}
+		into.values[cell] = value
+	}
+	for id, t := range s.timers {
+		if id.state != from {
+			continue
+		}
+		delete(s.timers, id)
+		id.state = into.id
+		if old, ok := s.timers[id]; ok && old.processing == t.processing && !time.Time(t.at).After(time.Time(old.at)) {
+			continue // keep the later timer
+		}
+		t.id = id
+		s.timers[id] = t
+	}
+	delete(s.keys, from)
+}
+
+// stateID identifies the state of a key and window by encoded key and window.
+type stateID struct {
+	key, window string
+}
+
+func windowID(w typex.Window) string {
+	if w == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", w)
+}
+
+// timerID identifies a timer by state, timer key and tag.
+type timerID struct {
+	state      stateID
+	timer, tag string
+}
+
+type timer struct {
+	id         timerID
+	at         typex.EventTime // processing time, if processing
+	processing bool
+	seq        int
+}
+
+func (t *timer) before(o *timer) bool {
+	if t.processing != o.processing {
+		return o.processing
+	}
+	if a, b := time.Time(t.at), time.Time(o.at); !a.Equal(b) {
+		return a.Before(b)
+	}
+	return t.seq < o.seq
+}
+
+// keyState is the state of a single key and window.
+type keyState struct {
+	id     stateID
+	key    interface{}
+	w      typex.Window // nil if global
+	values map[string]interface{}
+	store  *stateStore
+}
+
+func (k *keyState) Read(key string) (interface{}, bool, error) {
+	v, ok := k.values[key]
+	return v, ok, nil
+}
+
+func (k *keyState) Write(key string, value interface{}) error {
+	k.values[key] = value
+	return nil
+}
+
+func (k *keyState) Clear(key string) error {
+	delete(k.values, key)
+	return nil
+}
+
+func (k *keyState) SetTimer(key, tag string, t typex.EventTime) error {
+	id := timerID{state: k.id, timer: key, tag: tag}
+	k.store.seq++
+	k.store.timers[id] = &timer{id: id, at: t, seq: k.store.seq}
+	return nil
+}
+
+func (k *keyState) SetProcessingTimer(key, tag string, t time.Time) error {
+	id := timerID{state: k.id, timer: key, tag: tag}
+	k.store.seq++
+	k.store.timers[id] = &timer{id: id, at: typex.EventTime(t), processing: true, seq: k.store.seq}
+	return nil
+}
+
+func (k *keyState) ClearTimer(key, tag string) error {
+	delete(k.store.timers, timerID{state: k.id, timer: key, tag: tag})
+	return nil
+}
@@ -0,0 +1,118 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+func at(sec int64) typex.EventTime {
+	return typex.EventTime(time.Unix(sec, 0))
+}
+
+func TestStateStoreExpire(t *testing.T) {
+	wfn := window.NewFixedWindows(10 * time.Second).WithAllowedLateness(5 * time.Second)
+	first := window.IntervalWindow{Start: at(0), End: at(10)}
+	second := window.IntervalWindow{Start: at(10), End: at(20)}
+
+	s := newStateStore()
+	for _, w := range []typex.Window{nil, first, second} {
+		p := s.Provider("k", "k", w)
+		p.Write("count", 1)
+		p.SetTimer("flush", "", at(100))
+	}
+
+	// The first window expires once the watermark passes its end by more
+	// than the allowed lateness.
+
+	for _, wm := range []int64{12, 14} {
+		if err := s.Expire(at(wm), wfn); err != nil {
+			t.Fatalf("Expire(%v) failed: %v", wm, err)
+		}
+		if len(s.keys) != 3 || len(s.timers) != 3 {
+			t.Errorf("Expire(%v) left %v states and %v timers, want 3 and 3", wm, len(s.keys), len(s.timers))
+		}
+	}
+	if err := s.Expire(at(15), wfn); err != nil {
+		t.Fatalf("Expire(15) failed: %v", err)
+	}
+	if len(s.keys) != 2 || len(s.timers) != 2 {
+		t.Errorf("Expire(15) left %v states and %v timers, want 2 and 2", len(s.keys), len(s.timers))
+	}
+	if _, ok, _ := s.Provider("k", "k", first).Read("count"); ok {
+		t.Errorf("state of %v present after expiry", first)
+	}
+	for _, w := range []typex.Window{nil, second} {
+		if v, _, _ := s.Provider("k", "k", w).Read("count"); v != 1 {
+			t.Errorf("state of %v = %v, want 1", w, v)
+		}
+	}
+
+	// The global window never expires.
+
+	if err := s.Expire(exec.EndOfTime, wfn); err != nil {
+		t.Fatalf("Expire(EndOfTime) failed: %v", err)
+	}
+	if timer, ok := s.NextTimer(exec.Time{Watermark: exec.EndOfTime}); !ok || timer.Window != nil {
+		t.Errorf("NextTimer() = %v, %v, want global window timer", timer, ok)
+	}
+}
+
+func TestStateStoreTimers(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	s := newStateStore()
+	p := s.Provider("k", "k", nil)
+
+	// Timers are due in their own time domain. Event-time timers are
+	// returned first.
+
+	tests := []struct {
+		now  exec.Time
+		want []string
+	}{
+		{exec.Time{Watermark: at(5), ProcessingTime: start}, nil},
+		{exec.Time{Watermark: at(5), ProcessingTime: start.Add(time.Minute)}, []string{"timeout"}},
+		{exec.Time{Watermark: at(10), ProcessingTime: start.Add(time.Hour)}, []string{"event", "timeout"}},
+	}
+	for _, test := range tests {
+		p.SetTimer("event", "", at(10))
+		p.SetProcessingTimer("timeout", "", start.Add(time.Minute))
+
+		var got []string
+		for {
+			timer, ok := s.NextTimer(test.now)
+			if !ok {
+				break
+			}
+			if timer.ProcessingTime != (timer.Name == "timeout") {
+				t.Errorf("NextTimer() = %+v, want ProcessingTime only for timeout", timer)
+			}
+			got = append(got, timer.Name)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("NextTimer(%v) = %v, want %v", test.now, got, test.want)
+		}
+		p.ClearTimer("event", "")
+		p.ClearTimer("timeout", "")
+	}
+}
@@ -0,0 +1,253 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/timers"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*runningSumFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*batchFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*expiryFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*reorderFn)(nil)).Elem())
+}
+
+// runningSumFn emits the running sum per key.
+type runningSumFn struct {
+	Sum state.Value `json:"sum"`
+}
+
+func (f *runningSumFn) ProcessElement(ctx context.Context, key string, v int) (string, int, error) {
+	var sum int
+	if _, err := f.Sum.Read(ctx, &sum); err != nil {
+		return "", 0, err
+	}
+	sum += v
+	return key, sum, f.Sum.Write(ctx, sum)
+}
+
+// batchFn buffers the values per key and emits them sorted when the timer
+// fires. The timer is set to the latest timestamp seen for the key.
+type batchFn struct {
+	Buffer state.Bag        `json:"buffer"`
+	Flush  timers.EventTime `json:"flush"`
+}
+
+func (f *batchFn) ProcessElement(ctx context.Context, t typex.EventTime, key string, v int, _ func(string, []int)) error {
+	if err := f.Buffer.Add(ctx, v); err != nil {
+		return err
+	}
+	return f.Flush.Set(ctx, t)
+}
+
+func (f *batchFn) OnTimer(ctx context.Context, key, timer string, emit func(string, []int)) error {
+	var list []int
+	if _, err := f.Buffer.Read(ctx, &list); err != nil {
+		return err
+	}
+	sort.Ints(list)
+	emit(key, list)
+	return f.Buffer.Clear(ctx)
+}
+
+// reorderFn buffers the values per key by timestamp and emits them in
+// timestamp order when the timer fires.
+type reorderFn struct {
+	Events state.OrderedList `json:"events"`
+	Flush  timers.EventTime  `json:"flush"`
+}
+
+func (f *reorderFn) ProcessElement(ctx context.Context, t typex.EventTime, key string, v int, _ func(string, []int)) error {
+	if err := f.Events.Add(ctx, t, v); err != nil {
+		return err
+	}
+	return f.Flush.Set(ctx, t)
+}
+
+func (f *reorderFn) OnTimer(ctx context.Context, key, timer string, emit func(string, []int)) error {
+	var list []int
+	if _, err := f.Events.Read(ctx, &list); err != nil {
+		return err
+	}
+	emit(key, list)
+	return f.Events.Clear(ctx)
+}
+
+// expiryFn sets a timer per request ID and emits the IDs of the requests
+// whose timer fires. A negative value answers the request -v and clears its
+// timer.
+type expiryFn struct {
+	Expiry timers.EventTime `json:"expiry"`
+}
+
+func (f *expiryFn) ProcessElement(ctx context.Context, t typex.EventTime, key string, v int, _ func(string)) error {
+	if v < 0 {
+		return f.Expiry.ClearTag(ctx, fmt.Sprint(-v))
+	}
+	return f.Expiry.SetTag(ctx, fmt.Sprint(v), t)
+}
+
+func (f *expiryFn) OnTimer(ctx context.Context, key string, timer timers.Fired, emit func(string)) error {
+	if timer.Key != "expiry" {
+		return fmt.Errorf("unexpected timer: %v", timer.Key)
+	}
+	emit(key + ":" + timer.Tag)
+	return nil
+}
+
+func keyByParity(v int) (string, int) {
+	if v%2 == 0 {
+		return "even", v
+	}
+	return "odd", v
+}
+
+func formatBatch(key string, list []int) string {
+	return fmt.Sprintf("%v:%v", key, list)
+}
+
+func TestStatefulParDo(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	keyed := beam.ParDo(s, keyByParity, beam.Create(s, 1, 2, 3, 4, 5))
+
+	sums := beam.ParDo(s, &runningSumFn{Sum: state.MakeValue("sum")}, keyed)
+	passert.Equals(s, beam.DropKey(s, sums), 1, 4, 9, 2, 6)
+
+	batches := beam.ParDo(s, &batchFn{Buffer: state.MakeBag("buffer"), Flush: timers.InEventTime("flush")}, keyed)
+	passert.Equals(s, beam.ParDo(s, formatBatch, batches), "odd:[1 3 5]", "even:[2 4]")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
+
+func TestTimerFamily(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	keyed := beam.ParDo(s, keyByParity, beam.Create(s, 1, 2, 3, 4, -1, 5, -5))
+
+	expired := beam.ParDo(s, &expiryFn{Expiry: timers.InEventTime("expiry")}, keyed)
+	passert.Equals(s, expired, "odd:3", "even:2", "even:4")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
+
+func TestOrderedListState(t *testing.T) {
+	// Events arrive out of order and are reassembled per window. Merged
+	// sessions merge their lists by timestamp.
+
+	tests := []struct {
+		ws   *beam.WindowingStrategy
+		want []interface{}
+	}{
+		{
+			window.NewFixedWindows(10 * time.Second),
+			[]interface{}{"key:[1 3]", "key:[12 14]", "key:[30]"},
+		},
+		{
+			window.NewSessions(10 * time.Second),
+			[]interface{}{"key:[1 3 12 14]", "key:[30]"},
+		},
+	}
+
+	for _, test := range tests {
+		p := beam.NewPipeline()
+		s := p.Root()
+		windowed := beam.WindowInto(s, test.ws, beam.ParDo(s, atSeconds, beam.Create(s, 14, 3, 30, 12, 1)))
+
+		events := beam.ParDo(s, &reorderFn{Events: state.MakeOrderedList("events"), Flush: timers.InEventTime("flush")}, windowed)
+		formatted := beam.ParDo(s, formatBatch, events)
+		passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindow(), formatted), test.want...)
+
+		if err := ptest.Run(p); err != nil {
+			t.Errorf("pipeline with %v failed: %v", test.ws, err)
+		}
+	}
+}
+
+func TestStatefulParDoRequiresKV(t *testing.T) {
+	s := beam.NewPipeline().Root()
+	if _, err := beam.TryParDo(s, &runningSumFn{Sum: state.MakeValue("sum")}, beam.Create(s, 1, 2)); err == nil {
+		t.Errorf("TryParDo(runningSumFn) on non-KV input succeeded, want error")
+	}
+}
+
+func TestStatefulParDoStateCodec(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	keyed := beam.ParDo(s, keyByParity, beam.Create(s, 1, 2, 3, 4, 5))
+
+	sums := beam.ParDo(s, &runningSumFn{Sum: state.MakeValue("sum").WithCodec("json")}, keyed)
+	passert.Equals(s, beam.DropKey(s, sums), 1, 4, 9, 2, 6)
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+
+	if _, err := beam.TryParDo(s, &runningSumFn{Sum: state.MakeValue("sum").WithCodec("unknown")}, keyed); err == nil {
+		t.Errorf("TryParDo(runningSumFn) with unknown state codec succeeded, want error")
+	}
+}
+
+func TestStatefulParDoWindowed(t *testing.T) {
+	// State and timers are partitioned by window. Merged sessions combine
+	// the buffers of their windows.
+
+	tests := []struct {
+		ws   *beam.WindowingStrategy
+		want []interface{}
+	}{
+		{
+			window.NewFixedWindows(10 * time.Second),
+			[]interface{}{"key:[1 3]", "key:[12 14]", "key:[30]"},
+		},
+		{
+			window.NewSessions(10 * time.Second),
+			[]interface{}{"key:[1 3 12 14]", "key:[30]"},
+		},
+	}
+
+	for _, test := range tests {
+		p := beam.NewPipeline()
+		s := p.Root()
+		windowed := beam.WindowInto(s, test.ws, beam.ParDo(s, atSeconds, beam.Create(s, 1, 3, 12, 14, 30)))
+
+		batches := beam.ParDo(s, &batchFn{Buffer: state.MakeBag("buffer"), Flush: timers.InEventTime("flush")}, windowed)
+		formatted := beam.ParDo(s, formatBatch, batches)
+		passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindow(), formatted), test.want...)
+
+		if err := ptest.Run(p); err != nil {
+			t.Errorf("pipeline with %v failed: %v", test.ws, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/teststream"
+)
+
+// TestStream replays the events of a TestStream in a single bundle. Elements
+// are emitted at their timestamps and the watermark and processing time are
+// advanced downstream as scripted. Processing time starts at the time of the
+// clock of the context, which is the wall clock unless set with WithClock.
+// Once drained, the remaining events are dropped and the watermark advances
+// to the end of time.
+type TestStream struct {
+	UID exec.UnitID
+	Fn  *teststream.StreamFn
+	Out exec.Node
+
+	mu       sync.Mutex
+	draining bool
+}
+
+// Clock provides the initial processing time of the direct runner.
+type Clock interface {
+	// Now returns the current processing time.
+	Now() time.Time
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+type clockKey string
+
+const clockCtxKey clockKey = "beam:direct:clock"
+
+// WithClock returns a context that makes the direct runner start the
+// processing time of TestStreams at the time of the given clock. A fixed
+// clock makes processing-time timers deterministic in tests:
+//
+//    ctx := direct.WithClock(context.Background(), fixedClock)
+//    _, err := direct.Execute(ctx, p)
+func WithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockCtxKey, c)
+}
+
+func clockOf(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockCtxKey).(Clock); ok {
+		return c
+	}
+	return wallClock{}
+}
+
+// testStreamFn returns the TestStream that the given Impulse feeds, if any.
+func (b *builder) testStreamFn(impulse *graph.MultiEdge) (*graph.MultiEdge, *teststream.StreamFn, bool) {
+	list := b.succ[impulse.Output[0].To.ID()]
+	if len(list) != 1 {
+		return nil, nil, false
+	}
+	edge := b.edges[list[0].to]
+	if edge.Op != graph.ParDo {
+		return nil, nil, false
+	}
+	fn, ok := edge.DoFn.Recv.(*teststream.StreamFn)
+	return edge, fn, ok
+}
+
+func (n *TestStream) ID() exec.UnitID {
+	return n.UID
+}
+
+func (n *TestStream) Up(ctx context.Context) error {
+	return nil
+}
+
+func (n *TestStream) StartBundle(ctx context.Context, id string, data exec.DataManager) error {
+	return n.Out.StartBundle(ctx, id, data)
+}
+
+func (n *TestStream) Process(ctx context.Context) error {
+	now := exec.Time{ProcessingTime: clockOf(ctx).Now()}
+	if err := exec.MultiAdvanceTime(ctx, now, n.Out); err != nil {
+		return err
+	}
+
+	for _, e := range n.Fn.Events {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if n.isDraining() {
+			now.Watermark = exec.EndOfTime
+			return exec.MultiAdvanceTime(ctx, now, n.Out)
+		}
+
+		switch e.Kind {
+		case teststream.ElementEvent:
+			values, err := e.Elements(n.Fn.T.T)
+			if err != nil {
+				return err
+			}
+			for _, value := range values {
+				if err := n.Out.ProcessElement(ctx, exec.FullValue{Elm: value, Timestamp: typex.EventTime(e.Timestamp)}); err != nil {
+					return err
+				}
+			}
+			continue
+
+		case teststream.WatermarkEvent:
+			now.Watermark = typex.EventTime(e.Timestamp)
+
+		case teststream.ProcessingTimeEvent:
+			now.ProcessingTime = now.ProcessingTime.Add(e.Advance)
+
+		default:
+			return fmt.Errorf("unexpected teststream event: %v", e.Kind)
+		}
+		if err := exec.MultiAdvanceTime(ctx, now, n.Out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Drain drops the events not yet replayed.
+func (n *TestStream) Drain() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.draining = true
+}
+
+func (n *TestStream) isDraining() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.draining
+}
+
+func (n *TestStream) FinishBundle(ctx context.Context) error {
+	return n.Out.FinishBundle(ctx)
+}
+
+func (n *TestStream) Down(ctx context.Context) error {
+	return nil
+}
+
+func (n *TestStream) String() string {
+	return fmt.Sprintf("TestStream[%v]. Out:%v", len(n.Fn.Events), n.Out.ID())
+}
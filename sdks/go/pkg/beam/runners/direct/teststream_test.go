@@ -0,0 +1,157 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/timers"
+	"github.com/apache/beam/sdks/go/pkg/beam/runners/direct"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/teststream"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*timeoutFn)(nil)).Elem())
+}
+
+// timeoutFn buffers the values per key and emits them with the processing
+// time once no value has arrived for a minute.
+type timeoutFn struct {
+	Buffer  state.Bag             `json:"buffer"`
+	Timeout timers.ProcessingTime `json:"timeout"`
+}
+
+func (f *timeoutFn) ProcessElement(ctx context.Context, key string, v int, _ func(string)) error {
+	if err := f.Buffer.Add(ctx, v); err != nil {
+		return err
+	}
+	return f.Timeout.SetAfter(ctx, time.Minute)
+}
+
+func (f *timeoutFn) OnTimer(ctx context.Context, key, timer string, emit func(string)) error {
+	var list []int
+	if _, err := f.Buffer.Read(ctx, &list); err != nil {
+		return err
+	}
+	sort.Ints(list)
+	emit(fmt.Sprintf("%v@%v", list, timers.Now(ctx).Unix()))
+	return f.Buffer.Clear(ctx)
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestTestStream(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	c := teststream.NewConfig()
+	steps := []error{
+		c.AddElements(start, 1, 2, 3),
+		c.AdvanceProcessingTime(time.Minute),
+		c.AdvanceWatermark(start),
+		c.AddElements(start.Add(time.Minute), 4, 5),
+		c.AdvanceWatermarkToInfinity(),
+	}
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %v failed: %v", i, err)
+		}
+	}
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	keyed := beam.ParDo(s, keyByParity, teststream.Create(s, c))
+
+	// The timers of the first elements fire when the watermark advances,
+	// before the later elements arrive.
+
+	batches := beam.ParDo(s, &batchFn{Buffer: state.MakeBag("buffer"), Flush: timers.InEventTime("flush")}, keyed)
+	passert.Equals(s, beam.ParDo(s, formatBatch, batches), "odd:[1 3]", "even:[2]", "odd:[5]", "even:[4]")
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
+
+func TestProcessingTimeTimer(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	c := teststream.NewConfig()
+	steps := []error{
+		c.AddElements(start, 1, 2),
+		c.AdvanceProcessingTime(30 * time.Second),
+		c.AddElements(start, 3),
+		c.AdvanceProcessingTime(40 * time.Second),
+		c.AdvanceProcessingTime(30 * time.Second),
+		c.AddElements(start, 4),
+		c.AdvanceWatermarkToInfinity(),
+	}
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %v failed: %v", i, err)
+		}
+	}
+
+	// Processing time starts at 5000s. The timeout is reset by the element 3
+	// at 5030s and fires once processing time reaches 5100s. The last
+	// timeout fires when the input is exhausted.
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	keyed := beam.ParDo(s, withKey, teststream.Create(s, c))
+	batches := beam.ParDo(s, &timeoutFn{Buffer: state.MakeBag("buffer"), Timeout: timers.InProcessingTime("timeout")}, keyed)
+	passert.Equals(s, batches, "[1 2 3]@5100", "[4]@5100")
+
+	ctx := direct.WithClock(context.Background(), fixedClock(time.Unix(5000, 0)))
+	if _, err := direct.Execute(ctx, p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
+
+func TestTestStreamConfig(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	c := teststream.NewConfig()
+	if err := c.AddElements(start, 1, "a"); err == nil {
+		t.Errorf("AddElements with mixed types succeeded, want error")
+	}
+	if err := c.AdvanceWatermark(start); err != nil {
+		t.Fatalf("AdvanceWatermark failed: %v", err)
+	}
+	if err := c.AdvanceWatermark(start); err == nil {
+		t.Errorf("AdvanceWatermark to the same time succeeded, want error")
+	}
+	if err := c.AdvanceProcessingTime(0); err == nil {
+		t.Errorf("AdvanceProcessingTime(0) succeeded, want error")
+	}
+
+	s := beam.NewPipeline().Root()
+	if _, err := teststream.TryCreate(s, teststream.NewConfig()); err == nil {
+		t.Errorf("TryCreate with no elements succeeded, want error")
+	}
+}
@@ -0,0 +1,203 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// triggerContext is the time at which a trigger of a window is evaluated.
+type triggerContext struct {
+	watermark typex.EventTime
+	now       time.Time
+	end       typex.EventTime // largest timestamp of the window
+}
+
+func (c triggerContext) pastEnd() bool {
+	return time.Time(c.watermark).After(time.Time(c.end))
+}
+
+// triggerState tracks the trigger of a single key and window.
+type triggerState struct {
+	t *window.Trigger
+
+	count    int32     // elements since the last firing
+	arrival  time.Time // processing time of the first element since the last firing
+	started  bool      // true iff arrival is set
+	onTime   bool      // AfterWatermark: the on-time pane has fired
+	fired    bool      // AfterAll: the subtrigger has fired
+	current  int       // AfterEach: the current subtrigger
+	finished bool      // the trigger will not fire again
+
+	subs []*triggerState
+}
+
+func newTriggerState(t *window.Trigger) *triggerState {
+	s := &triggerState{t: t}
+	switch t.Kind {
+	case window.AfterWatermarkTrigger:
+		s.subs = []*triggerState{nil, nil}
+		if t.Early != nil {
+			s.subs[0] = newTriggerState(t.Early)
+		}
+		if t.Late != nil {
+			s.subs[1] = newTriggerState(t.Late)
+		}
+	default:
+		for _, sub := range t.Sub {
+			s.subs = append(s.subs, newTriggerState(sub))
+		}
+	}
+	return s
+}
+
+// add records the arrival of an element.
+func (s *triggerState) add(now time.Time) {
+	s.count++
+	if !s.started {
+		s.arrival, s.started = now, true
+	}
+	for _, sub := range s.subs {
+		if sub != nil {
+			sub.add(now)
+		}
+	}
+}
+
+// merge combines the state of a window merged into this one.
+func (s *triggerState) merge(o *triggerState) {
+	s.count += o.count
+	if o.started && (!s.started || o.arrival.Before(s.arrival)) {
+		s.arrival, s.started = o.arrival, true
+	}
+	s.onTime = s.onTime && o.onTime
+	s.fired = s.fired && o.fired
+	s.finished = s.finished && o.finished
+	if o.current < s.current {
+		s.current = o.current
+	}
+	for i, sub := range s.subs {
+		if sub != nil {
+			sub.merge(o.subs[i])
+		}
+	}
+}
+
+// reset clears the state, such as for a repeated trigger.
+func (s *triggerState) reset() {
+	*s = *newTriggerState(s.t)
+}
+
+// clear starts a new pane after the trigger has fired.
+func (s *triggerState) clear() {
+	s.count, s.started = 0, false
+	for _, sub := range s.subs {
+		if sub != nil {
+			sub.clear()
+		}
+	}
+}
+
+// fire returns true iff the trigger fires in the given context. The state
+// is updated as if the trigger fired.
+func (s *triggerState) fire(c triggerContext) bool {
+	if s.finished {
+		return false
+	}
+
+	switch s.t.Kind {
+	case window.DefaultTrigger:
+		return c.pastEnd() && s.count > 0
+
+	case window.AlwaysTrigger:
+		return s.count > 0
+
+	case window.NeverTrigger:
+		return false
+
+	case window.AfterWatermarkTrigger:
+		early, late := s.subs[0], s.subs[1]
+		if !c.pastEnd() {
+			return early != nil && early.fire(c)
+		}
+		if !s.onTime {
+			s.onTime = true
+			s.finished = late == nil
+			return true
+		}
+		return late != nil && late.fire(c)
+
+	case window.AfterProcessingTimeTrigger:
+		if s.started && !c.now.Before(s.arrival.Add(s.t.Delay)) {
+			s.finished = true
+			return true
+		}
+		return false
+
+	case window.AfterCountTrigger:
+		if s.count >= s.t.Count {
+			s.finished = true
+			return true
+		}
+		return false
+
+	case window.RepeatTrigger:
+		sub := s.subs[0]
+		if sub.fire(c) {
+			sub.reset()
+			return true
+		}
+		return false
+
+	case window.AfterAnyTrigger:
+		for _, sub := range s.subs {
+			if sub.fire(c) {
+				s.finished = true
+				return true
+			}
+		}
+		return false
+
+	case window.AfterAllTrigger:
+		all := true
+		for _, sub := range s.subs {
+			if !sub.fired && sub.fire(c) {
+				sub.fired = true
+			}
+			all = all && sub.fired
+		}
+		s.finished = all
+		return all
+
+	case window.AfterEachTrigger:
+		sub := s.subs[s.current]
+		if !sub.fire(c) {
+			return false
+		}
+		if sub.finished {
+			s.current++
+			s.finished = s.current == len(s.subs)
+		}
+		return true
+
+	default:
+		panic(fmt.Sprintf("unexpected trigger: %v", s.t))
+	}
+}
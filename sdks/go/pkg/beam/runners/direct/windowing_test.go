@@ -0,0 +1,205 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package direct_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/teststream"
+	"github.com/apache/beam/sdks/go/pkg/beam/transforms/stats"
+)
+
+// atSeconds keys each element and uses it as its event time in seconds.
+func atSeconds(x int) (beam.EventTime, string, int) {
+	return beam.EventTime(time.Unix(int64(x), 0)), "key", x
+}
+
+// formatWindow formats a sum with the end of its window in seconds, as the
+// grouped key has the largest timestamp of its window.
+func formatWindow(t beam.EventTime, _ string, sum int) string {
+	return fmt.Sprintf("%v:%v", time.Time(t).Add(time.Millisecond).Unix(), sum)
+}
+
+// shortSession places each element into a window of three seconds from its
+// timestamp, which are merged as sessions.
+func shortSession(t beam.EventTime) []window.IntervalWindow {
+	return []window.IntervalWindow{{Start: t, End: beam.EventTime(time.Time(t).Add(3 * time.Second))}}
+}
+
+func TestWindowInto(t *testing.T) {
+	custom, err := window.NewCustom("short", shortSession, window.MergeIntervals)
+	if err != nil {
+		t.Fatalf("NewCustom failed: %v", err)
+	}
+
+	tests := []struct {
+		ws   *beam.WindowingStrategy
+		want []interface{}
+	}{
+		{
+			window.NewFixedWindows(10 * time.Second),
+			[]interface{}{"10:6", "20:23", "30:25"},
+		},
+		{
+			window.NewSlidingWindows(5*time.Second, 10*time.Second),
+			[]interface{}{"5:6", "10:6", "15:23", "20:23", "30:25", "35:25"},
+		},
+		{
+			window.NewSessions(5 * time.Second),
+			[]interface{}{"8:6", "17:23", "30:25"},
+		},
+		{
+			custom,
+			[]interface{}{"6:6", "15:23", "28:25"},
+		},
+	}
+
+	for _, test := range tests {
+		p := beam.NewPipeline()
+		s := p.Root()
+		timed := beam.ParDo(s, atSeconds, beam.Create(s, 1, 2, 3, 11, 12, 25))
+		windowed := beam.WindowInto(s, test.ws, timed)
+		sums := beam.ParDo(s, formatWindow, stats.SumPerKey(s, windowed))
+
+		// Assert in the global window, where all sums are compared together.
+		passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindow(), sums), test.want...)
+
+		if err := ptest.Run(p); err != nil {
+			t.Errorf("pipeline with %v failed: %v", test.ws, err)
+		}
+	}
+}
+
+func withKey(x int) (string, int) {
+	return "key", x
+}
+
+func formatSum(_ string, sum int) string {
+	return fmt.Sprintf("%v", sum)
+}
+
+func TestTrigger(t *testing.T) {
+	// Panes of two elements in the global window. The last pane is emitted
+	// when the input is exhausted. Accumulating panes include the elements
+	// of all prior panes.
+
+	ws := window.NewGlobalWindow().WithTrigger(window.TriggerRepeat(window.TriggerAfterCount(2)))
+	tests := []struct {
+		ws   *beam.WindowingStrategy
+		want []interface{}
+	}{
+		{
+			ws,
+			[]interface{}{"3", "7", "5"},
+		},
+		{
+			ws.WithAccumulationMode(window.Accumulating),
+			[]interface{}{"3", "10", "15"},
+		},
+	}
+
+	for _, test := range tests {
+		p := beam.NewPipeline()
+		s := p.Root()
+		keyed := beam.WindowInto(s, test.ws, beam.ParDo(s, withKey, beam.Create(s, 1, 2, 3, 4, 5)))
+		sums := beam.ParDo(s, formatSum, stats.SumPerKey(s, keyed))
+		passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindow(), sums), test.want...)
+
+		if err := ptest.Run(p); err != nil {
+			t.Errorf("pipeline with %v failed: %v", test.ws, err)
+		}
+	}
+}
+
+func TestTriggerStream(t *testing.T) {
+	at := func(sec int64) time.Time {
+		return time.Unix(sec, 0)
+	}
+
+	c := teststream.NewConfig()
+	steps := []error{
+		c.AddElements(at(1), 1, 2),
+		c.AddElements(at(2), 3),
+		c.AdvanceWatermark(at(15)),
+		c.AddElements(at(3), 4),
+		c.AddElements(at(12), 5),
+		c.AdvanceWatermarkToInfinity(),
+	}
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %v failed: %v", i, err)
+		}
+	}
+
+	// The first window fires early after two elements and on time when the
+	// watermark passes its end. It then closes, so the late element 4 is
+	// dropped.
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	ws := window.NewFixedWindows(10 * time.Second).WithTrigger(window.TriggerAfterWatermark().EarlyFiring(window.TriggerAfterCount(2)))
+	keyed := beam.WindowInto(s, ws, beam.ParDo(s, withKey, teststream.Create(s, c)))
+	sums := beam.ParDo(s, formatWindow, stats.SumPerKey(s, keyed))
+	passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindow(), sums), "10:3", "10:3", "20:5")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("pipeline with %v failed: %v", ws, err)
+	}
+}
+
+func TestAllowedLateness(t *testing.T) {
+	at := func(sec int64) time.Time {
+		return time.Unix(sec, 0)
+	}
+
+	c := teststream.NewConfig()
+	steps := []error{
+		c.AddElements(at(1), 1),
+		c.AdvanceWatermark(at(12)),
+		c.AddElements(at(2), 2),
+		c.AdvanceWatermark(at(20)),
+		c.AddElements(at(3), 3),
+		c.AddElements(at(21), 4),
+		c.AdvanceWatermarkToInfinity(),
+	}
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %v failed: %v", i, err)
+		}
+	}
+
+	// The first window fires on time and again for the element 2 that arrives
+	// within the allowed lateness. The element 3 arrives after the window has
+	// expired and is returned as late data.
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	ws := window.NewFixedWindows(10 * time.Second).WithAllowedLateness(5 * time.Second)
+	keyed, late := beam.WindowIntoWithLateData(s, ws, beam.ParDo(s, withKey, teststream.Create(s, c)))
+	sums := beam.ParDo(s, formatWindow, stats.SumPerKey(s, keyed))
+	passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindow(), sums), "10:1", "10:2", "30:4")
+	passert.Equals(s, beam.ParDo(s, formatSum, late), "3")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("pipeline with %v failed: %v", ws, err)
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"io/ioutil"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
-	dotlib "github.com/apache/beam/sdks/go/pkg/beam/core/util/dot"
 )
 
 func init() {
@@ -36,20 +35,16 @@ func init() {
 
 var dotFile = flag.String("dot_file", "", "DOT output file to create")
 
-// Execute produces a DOT representation of the pipeline.
-func Execute(ctx context.Context, p *beam.Pipeline) error {
+// Execute produces a DOT representation of the pipeline. As the pipeline is
+// not run, there is no result.
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
 	if *dotFile == "" {
-		return errors.New("must supply dot_file argument")
-	}
-
-	edges, nodes, err := p.Build()
-	if err != nil {
-		return errors.New("can't get data to render")
+		return nil, errors.New("must supply dot_file argument")
 	}
 
 	var buf bytes.Buffer
-	if err := dotlib.Render(edges, nodes, &buf); err != nil {
-		return err
+	if err := beam.RenderDOT(p, &buf); err != nil {
+		return nil, err
 	}
-	return ioutil.WriteFile(*dotFile, buf.Bytes(), 0644)
+	return nil, ioutil.WriteFile(*dotFile, buf.Bytes(), 0644)
 }
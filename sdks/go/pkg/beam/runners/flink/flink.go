@@ -18,6 +18,7 @@ package flink
 
 import (
 	"context"
+	"flag"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
 	"github.com/apache/beam/sdks/go/pkg/beam/options/jobopts"
@@ -28,11 +29,32 @@ func init() {
 	beam.RegisterRunner("flink", Execute)
 }
 
+var (
+	// SavepointPath is the savepoint to restore the job from, if any.
+	SavepointPath = flag.String("savepoint_path", "", "Savepoint to restore the job from (optional).")
+
+	// AllowNonRestoredState allows restoring from a savepoint that contains
+	// state for transforms no longer present in the pipeline.
+	AllowNonRestoredState = flag.Bool("allow_non_restored_state", false, "Allow savepoint state that cannot be mapped to the pipeline.")
+)
+
 // Execute runs the given pipeline on Flink. Convenience wrapper over the
 // universal runner.
-func Execute(ctx context.Context, p *beam.Pipeline) error {
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
 	if *jobopts.InternalJavaRunner == "" {
 		*jobopts.InternalJavaRunner = "org.apache.beam.runners.flink.FlinkRunner"
 	}
-	return universal.Execute(ctx, p)
+	return universal.ExecuteWithOptions(ctx, p, runnerOptions())
+}
+
+// runnerOptions returns the Flink-specific pipeline options.
+func runnerOptions() map[string]interface{} {
+	opts := make(map[string]interface{})
+	if *SavepointPath != "" {
+		opts["savepoint_path"] = *SavepointPath
+	}
+	if *AllowNonRestoredState {
+		opts["allow_non_restored_state"] = true
+	}
+	return opts
 }
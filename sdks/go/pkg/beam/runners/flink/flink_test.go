@@ -0,0 +1,41 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flink
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunnerOptions(t *testing.T) {
+	defer func(path string, allow bool) {
+		*SavepointPath, *AllowNonRestoredState = path, allow
+	}(*SavepointPath, *AllowNonRestoredState)
+
+	*SavepointPath, *AllowNonRestoredState = "", false
+	if got := runnerOptions(); len(got) != 0 {
+		t.Errorf("runnerOptions() = %v, want none", got)
+	}
+
+	*SavepointPath, *AllowNonRestoredState = "/tmp/sp/savepoint-1", true
+	want := map[string]interface{}{
+		"savepoint_path":           "/tmp/sp/savepoint-1",
+		"allow_non_restored_state": true,
+	}
+	if got := runnerOptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("runnerOptions() = %v, want %v", got, want)
+	}
+}
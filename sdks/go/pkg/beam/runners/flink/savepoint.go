@@ -0,0 +1,145 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+// The job service API does not support savepoints, so they are managed
+// through the Flink REST API of the cluster running the job. A streaming job
+// is upgraded by taking a savepoint, optionally cancelling the job, and then
+// resubmitting the new pipeline with --savepoint_path set to the returned
+// location.
+
+// savepointPollInterval is the delay between savepoint status requests.
+var savepointPollInterval = time.Second
+
+// LookupJobID returns the Flink job id of the running job with the given
+// name, such as the --job_name used for submission. The endpoint is the
+// Flink REST endpoint, such as "http://localhost:8081".
+func LookupJobID(ctx context.Context, endpoint, name string) (string, error) {
+	var resp struct {
+		Jobs []struct {
+			ID    string `json:"jid"`
+			Name  string `json:"name"`
+			State string `json:"state"`
+		} `json:"jobs"`
+	}
+	if err := call(ctx, http.MethodGet, restURL(endpoint, "jobs/overview"), nil, &resp); err != nil {
+		return "", err
+	}
+	for _, job := range resp.Jobs {
+		if job.Name == name && job.State == "RUNNING" {
+			return job.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no running Flink job named %v", name)
+}
+
+// TriggerSavepoint triggers a savepoint of the given Flink job into the target
+// directory and waits for it to complete. If cancel is true, the job is
+// cancelled once the savepoint is taken. It returns the savepoint location,
+// which can be used as --savepoint_path to restore an upgraded pipeline.
+func TriggerSavepoint(ctx context.Context, endpoint, jobID, targetDir string, cancel bool) (string, error) {
+	req := map[string]interface{}{
+		"target-directory": targetDir,
+		"cancel-job":       cancel,
+	}
+	var trigger struct {
+		RequestID string `json:"request-id"`
+	}
+	if err := call(ctx, http.MethodPost, restURL(endpoint, "jobs", jobID, "savepoints"), req, &trigger); err != nil {
+		return "", fmt.Errorf("failed to trigger savepoint for %v: %v", jobID, err)
+	}
+
+	log.Infof(ctx, "Triggered savepoint for Flink job %v: %v", jobID, trigger.RequestID)
+
+	for {
+		var status struct {
+			Status struct {
+				ID string `json:"id"`
+			} `json:"status"`
+			Operation struct {
+				Location     string `json:"location"`
+				FailureCause struct {
+					Class      string `json:"class"`
+					StackTrace string `json:"stack-trace"`
+				} `json:"failure-cause"`
+			} `json:"operation"`
+		}
+		if err := call(ctx, http.MethodGet, restURL(endpoint, "jobs", jobID, "savepoints", trigger.RequestID), nil, &status); err != nil {
+			return "", fmt.Errorf("failed to get savepoint status for %v: %v", jobID, err)
+		}
+
+		if status.Status.ID == "COMPLETED" {
+			if status.Operation.Location == "" {
+				return "", fmt.Errorf("savepoint for %v failed: %v", jobID, status.Operation.FailureCause.Class)
+			}
+			log.Infof(ctx, "Savepoint for Flink job %v completed: %v", jobID, status.Operation.Location)
+			return status.Operation.Location, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(savepointPollInterval):
+		}
+	}
+}
+
+func restURL(endpoint string, path ...string) string {
+	return strings.TrimSuffix(endpoint, "/") + "/" + strings.Join(path, "/")
+}
+
+// call issues a JSON request to the Flink REST API and decodes the response
+// into out.
+func call(ctx context.Context, method, url string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("%v %v: %v %v", method, url, resp.Status, strings.Join(e.Errors, "; "))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
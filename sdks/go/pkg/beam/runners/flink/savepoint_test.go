@@ -0,0 +1,74 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSavepoint(t *testing.T) {
+	savepointPollInterval = time.Millisecond
+
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs/overview", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jobs":[{"jid":"old","name":"job","state":"FINISHED"},{"jid":"abc","name":"job","state":"RUNNING"}]}`)
+	})
+	mux.HandleFunc("/jobs/abc/savepoints", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Method != http.MethodPost {
+			t.Errorf("bad trigger request: %v %v", r.Method, err)
+		}
+		if req["target-directory"] != "/tmp/sp" || req["cancel-job"] != true {
+			t.Errorf("trigger request = %v, want target and cancel", req)
+		}
+		fmt.Fprint(w, `{"request-id":"r1"}`)
+	})
+	mux.HandleFunc("/jobs/abc/savepoints/r1", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 3 {
+			fmt.Fprint(w, `{"status":{"id":"IN_PROGRESS"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":{"id":"COMPLETED"},"operation":{"location":"/tmp/sp/savepoint-1"}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+
+	id, err := LookupJobID(ctx, server.URL, "job")
+	if err != nil || id != "abc" {
+		t.Fatalf("LookupJobID(job) = %v, %v, want abc", id, err)
+	}
+	if _, err := LookupJobID(ctx, server.URL, "missing"); err == nil {
+		t.Errorf("LookupJobID(missing) succeeded, want error")
+	}
+
+	loc, err := TriggerSavepoint(ctx, server.URL, id, "/tmp/sp", true)
+	if err != nil {
+		t.Fatalf("TriggerSavepoint failed: %v", err)
+	}
+	if loc != "/tmp/sp/savepoint-1" {
+		t.Errorf("TriggerSavepoint = %v, want /tmp/sp/savepoint-1", loc)
+	}
+}
@@ -0,0 +1,171 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package local contains an in-process portable runner with a web UI. The
+// pipeline is translated to the model pipeline and back before execution,
+// as a portable runner would, so DoFns and types that cannot be serialized
+// fail locally. The transforms are fused into stages and a small web UI
+// shows their progress while the job runs:
+//
+//    go run main.go --runner=local --local_ui=localhost:8074
+//
+// The UI shows the state of the job, its error, if failed, and, per stage,
+// the transforms, the number of elements output and the watermark. The same
+// status is served as JSON at /api/job. Stages are executed by the direct
+// runner, so the flags of the direct runner apply.
+package local
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/go/pkg/beam/options/jobopts"
+	"github.com/apache/beam/sdks/go/pkg/beam/runners/direct"
+)
+
+var (
+	uiAddr   = flag.String("local_ui", "localhost:8074", "Address to serve the web UI of the local runner on, or empty to disable it (optional).")
+	uiLinger = flag.Duration("local_ui_linger", 0, "Time to keep serving the web UI of the local runner after the job has finished (optional).")
+)
+
+func init() {
+	beam.RegisterRunner("local", Execute)
+}
+
+// Execute runs the pipeline in-process, serving the web UI while it runs. The
+// job is finished once Execute returns.
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
+	edges, _, err := p.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid pipeline: %v", err)
+	}
+	edges, err = roundTrip(edges)
+	if err != nil {
+		return nil, err
+	}
+	m := newMonitor(jobopts.GetJobName(), edges)
+
+	if *uiAddr != "" {
+		lis, err := net.Listen("tcp", *uiAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serve web UI: %v", err)
+		}
+		server := &http.Server{Handler: newHandler(m)}
+		go server.Serve(lis)
+		defer server.Close()
+
+		log.Infof(ctx, "Job UI: http://%v", lis.Addr())
+	}
+
+	res, err := run(ctx, edges, m)
+	if *uiAddr != "" && *uiLinger > 0 {
+		log.Infof(ctx, "Job finished. Serving the UI for %v", *uiLinger)
+		select {
+		case <-time.After(*uiLinger):
+		case <-ctx.Done():
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// roundTrip translates the edges to the model pipeline and back.
+func roundTrip(edges []*graph.MultiEdge) ([]*graph.MultiEdge, error) {
+	model, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: "local"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate model pipeline: %v", err)
+	}
+	g, err := graphx.Unmarshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate model pipeline: %v", err)
+	}
+	ret, _, err := g.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid model pipeline: %v", err)
+	}
+	return ret, nil
+}
+
+// run executes the edges, reporting progress to the monitor.
+func run(ctx context.Context, edges []*graph.MultiEdge, m *monitor) (*result, error) {
+	plan, err := direct.CompileWithProbe(edges, m)
+	if err != nil {
+		err = fmt.Errorf("translation failed: %v", err)
+		m.finish(err)
+		return nil, err
+	}
+	m.begin()
+	if err = plan.Execute(ctx, "", nil); err != nil {
+		plan.Down(ctx) // ignore any teardown errors
+		m.finish(err)
+		return nil, err
+	}
+	if err = plan.Down(ctx); err != nil {
+		m.finish(err)
+		return nil, err
+	}
+	m.finish(nil)
+
+	res := &result{metrics: metrics.NewResultSet(metrics.BundleResults(plan.ID())), info: buildinfo.Get()}
+	metrics.ClearBundleData(plan.ID())
+	return res, nil
+}
+
+// result is the result of a pipeline executed by the local runner.
+type result struct {
+	metrics *metrics.ResultSet
+	info    buildinfo.Info
+}
+
+func (r *result) JobID() string {
+	return ""
+}
+
+func (r *result) State(ctx context.Context) (beam.JobState, error) {
+	return beam.JobDone, nil
+}
+
+func (r *result) Wait(ctx context.Context) error {
+	return nil
+}
+
+// Cancel has no effect, as the job is finished.
+func (r *result) Cancel(ctx context.Context) error {
+	return nil
+}
+
+// Drain has no effect, as the job is finished.
+func (r *result) Drain(ctx context.Context) error {
+	return nil
+}
+
+func (r *result) Metrics(ctx context.Context) (*metrics.ResultSet, error) {
+	return r.metrics, nil
+}
+
+func (r *result) BuildInfo() buildinfo.Info {
+	return r.info
+}
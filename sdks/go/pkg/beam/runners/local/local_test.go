@@ -0,0 +1,126 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+)
+
+func init() {
+	beam.RegisterFunction(withKey)
+	beam.RegisterFunction(sumValues)
+}
+
+func withKey(v int) (string, int) {
+	return "key", v
+}
+
+func sumValues(key string, values func(*int) bool) int {
+	sum, v := 0, 0
+	for values(&v) {
+		sum += v
+	}
+	return sum
+}
+
+func newTestPipeline() *beam.Pipeline {
+	p := beam.NewPipeline()
+	s := p.Root()
+	keyed := beam.ParDo(s, withKey, beam.Create(s, 1, 2, 3))
+	beam.ParDo(s, sumValues, beam.GroupByKey(s, keyed))
+	return p
+}
+
+func TestFuse(t *testing.T) {
+	edges, _, err := newTestPipeline().Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	// The GBK starts a new stage.
+
+	stages := fuse(edges)
+	if len(stages) != 2 {
+		t.Fatalf("len(fuse()) = %v, want 2", len(stages))
+	}
+	if got := stages[0].Transforms; len(got) != 3 || got[2] != "local.withKey" {
+		t.Errorf("stage 1 = %v, want [Impulse, createFn, local.withKey]", got)
+	}
+	if got := stages[1].Transforms; len(got) != 2 || got[0] != "CoGBK" || got[1] != "local.sumValues" {
+		t.Errorf("stage 2 = %v, want [CoGBK, local.sumValues]", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	edges, _, err := newTestPipeline().Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if edges, err = roundTrip(edges); err != nil {
+		t.Fatalf("roundTrip failed: %v", err)
+	}
+
+	m := newMonitor("job", edges)
+	if _, err := run(context.Background(), edges, m); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	server := httptest.NewServer(newHandler(m))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/job")
+	if err != nil {
+		t.Fatalf("GET /api/job failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var status jobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("bad status: %v", err)
+	}
+	if status.State != done || len(status.Stages) != 2 {
+		t.Fatalf("status = %+v, want DONE with 2 stages", status)
+	}
+	// The first stage outputs the impulse, 3 values and 3 keyed values. The
+	// second outputs 1 group and its sum.
+	if first, second := status.Stages[0].Elements, status.Stages[1].Elements; first != 7 || second != 2 {
+		t.Errorf("elements = %v, %v, want 7, 2", first, second)
+	}
+	if wm := status.Stages[0].Watermark; wm != "end of time" {
+		t.Errorf("watermark = %q, want end of time", wm)
+	}
+
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var page bytes.Buffer
+	if _, err := page.ReadFrom(resp.Body); err != nil {
+		t.Fatalf("bad page: %v", err)
+	}
+	if !strings.Contains(page.String(), "local.sumValues") {
+		t.Errorf("page does not show the transforms:\n%v", page.String())
+	}
+}
@@ -0,0 +1,136 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+)
+
+// Job states.
+const (
+	pending = "PENDING"
+	running = "RUNNING"
+	done    = "DONE"
+	failed  = "FAILED"
+)
+
+// jobStatus is the status of the job, as shown by the UI.
+type jobStatus struct {
+	Name   string        `json:"name"`
+	State  string        `json:"state"`
+	Start  time.Time     `json:"start"`
+	End    time.Time     `json:"end"`
+	Error  string        `json:"error,omitempty"`
+	Stages []stageStatus `json:"stages"`
+}
+
+// stageStatus is the status of a stage. The watermark is the minimum of the
+// watermarks of its outputs that have advanced, if any.
+type stageStatus struct {
+	ID         int      `json:"id"`
+	Transforms []string `json:"transforms"`
+	Elements   int64    `json:"elements"`
+	Watermark  string   `json:"watermark,omitempty"`
+}
+
+// monitor tracks the progress of a job. It observes the PCollections of the
+// job as a direct.Probe.
+type monitor struct {
+	name   string
+	stages []*stage
+
+	mu         sync.Mutex
+	state      string
+	start, end time.Time
+	err        error
+	elements   map[int]int64           // nodeID -> #elements
+	watermarks map[int]typex.EventTime // nodeID -> watermark
+}
+
+func newMonitor(name string, edges []*graph.MultiEdge) *monitor {
+	return &monitor{
+		name:       name,
+		stages:     fuse(edges),
+		state:      pending,
+		elements:   make(map[int]int64),
+		watermarks: make(map[int]typex.EventTime),
+	}
+}
+
+func (m *monitor) Element(node int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.elements[node]++
+}
+
+func (m *monitor) Time(node int, t exec.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watermarks[node] = t.Watermark
+}
+
+func (m *monitor) begin() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state, m.start = running, time.Now()
+}
+
+func (m *monitor) finish(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state, m.end, m.err = done, time.Now(), err
+	if err != nil {
+		m.state = failed
+	}
+}
+
+// status returns a snapshot of the status of the job.
+func (m *monitor) status() jobStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ret := jobStatus{Name: m.name, State: m.state, Start: m.start, End: m.end}
+	if m.err != nil {
+		ret.Error = m.err.Error()
+	}
+	for _, s := range m.stages {
+		st := stageStatus{ID: s.ID, Transforms: s.Transforms}
+		var wm *typex.EventTime
+		for _, id := range s.outputs() {
+			st.Elements += m.elements[id]
+			if t, ok := m.watermarks[id]; ok && (wm == nil || time.Time(t).Before(time.Time(*wm))) {
+				wm = &t
+			}
+		}
+		if wm != nil {
+			st.Watermark = formatWatermark(*wm)
+		}
+		ret.Stages = append(ret.Stages, st)
+	}
+	return ret
+}
+
+func formatWatermark(t typex.EventTime) string {
+	if !time.Time(t).Before(time.Time(exec.EndOfTime)) {
+		return "end of time"
+	}
+	return time.Time(t).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,100 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package local
+
+import (
+	"path"
+	"sort"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+)
+
+// stage is a set of transforms fused for execution, such that the
+// PCollections between them are not materialized. The input of a GBK and side
+// inputs are materialized, so they start new stages.
+type stage struct {
+	ID         int
+	Transforms []string
+	edges      []*graph.MultiEdge
+}
+
+// fuse partitions the edges into stages, in the order of their first edge.
+func fuse(edges []*graph.MultiEdge) []*stage {
+	producer := make(map[int]int) // nodeID -> edgeID
+	parent := make(map[int]int)   // edgeID -> edgeID, union-find
+	for _, edge := range edges {
+		parent[edge.ID()] = edge.ID()
+		for _, out := range edge.Output {
+			producer[out.To.ID()] = edge.ID()
+		}
+	}
+
+	var find func(int) int
+	find = func(id int) int {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	for _, edge := range edges {
+		if edge.Op == graph.CoGBK {
+			continue
+		}
+		for i, in := range edge.Input {
+			if i > 0 && edge.Op != graph.Flatten {
+				continue // side input
+			}
+			if from, ok := producer[in.From.ID()]; ok {
+				parent[find(edge.ID())] = find(from)
+			}
+		}
+	}
+
+	var ret []*stage
+	stages := make(map[int]*stage) // root edgeID -> stage
+	for _, edge := range edges {
+		root := find(edge.ID())
+		s, ok := stages[root]
+		if !ok {
+			s = &stage{ID: len(ret) + 1}
+			stages[root] = s
+			ret = append(ret, s)
+		}
+		s.edges = append(s.edges, edge)
+		s.Transforms = append(s.Transforms, transformName(edge))
+	}
+	return ret
+}
+
+// outputs returns the graph nodes of the PCollections output by the stage.
+func (s *stage) outputs() []int {
+	var ret []int
+	for _, edge := range s.edges {
+		for _, out := range edge.Output {
+			ret = append(ret, out.To.ID())
+		}
+	}
+	sort.Ints(ret)
+	return ret
+}
+
+func transformName(edge *graph.MultiEdge) string {
+	name := path.Base(edge.Name())
+	for s := edge.Scope(); s != nil && s.Parent != nil; s = s.Parent {
+		name = s.Label + "/" + name
+	}
+	return name
+}
@@ -0,0 +1,70 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package local
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+)
+
+// page is the web UI. It reloads every two seconds while the job runs.
+var page = template.Must(template.New("job").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>{{.Name}}</title>
+{{if eq .State "PENDING" "RUNNING"}}<meta http-equiv="refresh" content="2">{{end}}
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; vertical-align: top; }
+.FAILED { color: #c00; }
+.DONE { color: #080; }
+pre { color: #c00; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>State: <span class="{{.State}}">{{.State}}</span>{{if not .Start.IsZero}}, started {{.Start.Format "15:04:05"}}{{end}}{{if not .End.IsZero}}, finished {{.End.Format "15:04:05"}}{{end}}</p>
+{{with .Error}}<pre>{{.}}</pre>{{end}}
+<table>
+<tr><th>Stage</th><th>Transforms</th><th>Elements</th><th>Watermark</th></tr>
+{{range .Stages}}<tr><td>{{.ID}}</td><td>{{range .Transforms}}{{.}}<br>{{end}}</td><td>{{.Elements}}</td><td>{{.Watermark}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// newHandler returns the handler of the web UI of the monitored job.
+func newHandler(m *monitor) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Execute(w, m.status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/api/job", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m.status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
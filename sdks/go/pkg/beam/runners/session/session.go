@@ -257,11 +257,12 @@ func (l *loggingServer) Logging(stream fnapi_pb.BeamFnLogging_LoggingServer) err
 	}
 }
 
-// Execute launches the supplied pipeline using a session file as the source of inputs.
-func Execute(ctx context.Context, p *beam.Pipeline) error {
+// Execute launches the supplied pipeline using a session file as the source of
+// inputs. The session is replayed without a job, so there is no result.
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
 	worker, err := buildLocalBinary(ctx)
 	if err != nil {
-		return fmt.Errorf("Couldn't build worker binary: %v", err)
+		return nil, fmt.Errorf("Couldn't build worker binary: %v", err)
 	}
 
 	log.Infof(ctx, "built worker binary at %s\n", worker)
@@ -302,7 +303,7 @@ func Execute(ctx context.Context, p *beam.Pipeline) error {
 	go cmd.Start()
 
 	wg.Wait()
-	return nil
+	return nil, nil
 }
 
 // buildLocalBinary is cribbed from the Dataflow runner, but doesn't force the
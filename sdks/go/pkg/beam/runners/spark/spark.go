@@ -0,0 +1,52 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spark contains the Spark runner.
+package spark
+
+import (
+	"context"
+	"flag"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/options/jobopts"
+	"github.com/apache/beam/sdks/go/pkg/beam/runners/universal"
+)
+
+func init() {
+	beam.RegisterRunner("spark", Execute)
+}
+
+// MasterURL is the Spark master to run the job on, if not the default of
+// the job service.
+var MasterURL = flag.String("spark_master_url", "", "Spark master URL, such as spark://host:7077 (optional).")
+
+// Execute runs the given pipeline on Spark. Convenience wrapper over the
+// universal runner.
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
+	if *jobopts.InternalJavaRunner == "" {
+		*jobopts.InternalJavaRunner = "org.apache.beam.runners.spark.SparkRunner"
+	}
+	return universal.ExecuteWithOptions(ctx, p, runnerOptions())
+}
+
+// runnerOptions returns the Spark-specific pipeline options.
+func runnerOptions() map[string]interface{} {
+	opts := make(map[string]interface{})
+	if *MasterURL != "" {
+		opts["spark_master"] = *MasterURL
+	}
+	return opts
+}
@@ -21,6 +21,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
 	jobpb "github.com/apache/beam/sdks/go/pkg/beam/model/jobmanagement_v1"
 	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
@@ -28,20 +30,21 @@ import (
 )
 
 // Execute executes a pipeline on the universal runner serving the given endpoint.
-// Convenience function.
-func Execute(ctx context.Context, p *pb.Pipeline, endpoint string, opt *JobOptions, async bool) (string, error) {
+// Convenience function. Unless async, it waits for the job to finish. It
+// returns the result of the job.
+func Execute(ctx context.Context, p *pb.Pipeline, endpoint string, opt *JobOptions, async bool) (beam.PipelineResult, error) {
 	// (1) Prepare job to obtain artifact staging instructions.
 
 	cc, err := grpcx.Dial(ctx, endpoint, 2*time.Minute)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to job service: %v", err)
+		return nil, fmt.Errorf("failed to connect to job service: %v", err)
 	}
 	defer cc.Close()
 	client := jobpb.NewJobServiceClient(cc)
 
 	prepID, artifactEndpoint, err := Prepare(ctx, client, p, opt)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	log.Infof(ctx, "Prepared job with id: %v", prepID)
@@ -51,7 +54,7 @@ func Execute(ctx context.Context, p *pb.Pipeline, endpoint string, opt *JobOptio
 	if opt.Worker == "" {
 		worker, err := BuildTempWorkerBinary(ctx)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		defer os.Remove(worker)
 
@@ -60,26 +63,32 @@ func Execute(ctx context.Context, p *pb.Pipeline, endpoint string, opt *JobOptio
 		log.Infof(ctx, "Using specified worker binary: '%v'", opt.Worker)
 	}
 
-	token, err := Stage(ctx, prepID, artifactEndpoint, opt.Worker)
+	token, err := Stage(ctx, prepID, artifactEndpoint, opt.Worker, opt.Artifacts...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	log.Infof(ctx, "Staged binary artifact with token: %v", token)
+	log.Infof(ctx, "Staged binary and %v artifacts with token: %v", len(opt.Artifacts), token)
 
 	// (3) Submit job
 
 	jobID, err := Submit(ctx, client, prepID, token)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	log.Infof(ctx, "Submitted job: %v", jobID)
 
 	// (4) Wait for completion.
 
+	res := &result{endpoint: endpoint, jobID: jobID, messages: opt.Messages, level: opt.MessageLevel, info: buildinfo.Get()}
 	if async {
-		return jobID, nil
+		return res, nil
 	}
-	return jobID, WaitForCompletion(ctx, client, jobID)
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if err := res.wait(ctx, client); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
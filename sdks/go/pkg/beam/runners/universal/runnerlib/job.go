@@ -17,16 +17,19 @@ package runnerlib
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/artifact"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
 	"github.com/apache/beam/sdks/go/pkg/beam/log"
 	jobpb "github.com/apache/beam/sdks/go/pkg/beam/model/jobmanagement_v1"
 	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
 	"github.com/apache/beam/sdks/go/pkg/beam/provision"
 	"github.com/golang/protobuf/proto"
+	google_protobuf "github.com/golang/protobuf/ptypes/struct"
 )
 
 // JobOptions capture the various options for submitting jobs
@@ -41,9 +44,24 @@ type JobOptions struct {
 
 	// Worker is the worker binary override.
 	Worker string
+	// Artifacts are additional files to stage with the worker binary.
+	Artifacts []artifact.KeyedFile
 
 	// InternalJavaRunner is the class of the receiving Java runner. To be removed.
 	InternalJavaRunner string
+
+	// RunnerOptions are additional runner-specific pipeline options, keyed by
+	// their snake_case name, such as "savepoint_path" for Flink. The values
+	// must be JSON-serializable.
+	RunnerOptions map[string]interface{}
+
+	// Messages is the writer to print job state changes and messages to while
+	// waiting for completion, if any.
+	Messages io.Writer
+	// MessageLevel is the minimum severity of job messages to print. Job
+	// messages include worker logs forwarded by the runner. If unspecified,
+	// only state changes are printed.
+	MessageLevel log.Severity
 }
 
 // Prepare prepares a job to the given job service. It returns the preparation id
@@ -56,7 +74,7 @@ func Prepare(ctx context.Context, client jobpb.JobServiceClient, p *pb.Pipeline,
 		Experiments: append(opt.Experiments, "beam_fn_api"),
 	}
 
-	options, err := provision.OptionsToProto(raw)
+	options, err := runnerOptionsToProto(raw, opt.RunnerOptions)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to produce pipeline options: %v", err)
 	}
@@ -72,6 +90,27 @@ func Prepare(ctx context.Context, client jobpb.JobServiceClient, p *pb.Pipeline,
 	return resp.GetPreparationId(), resp.GetArtifactStagingEndpoint().GetUrl(), nil
 }
 
+// runnerOptionsToProto converts the pipeline options to proto, adding any
+// runner-specific options as top-level "beam:option:<name>:v1" entries.
+func runnerOptionsToProto(raw runtime.RawOptionsWrapper, extra map[string]interface{}) (*google_protobuf.Struct, error) {
+	if len(extra) == 0 {
+		return provision.OptionsToProto(raw)
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		m[fmt.Sprintf("beam:option:%v:v1", k)] = v
+	}
+	return provision.OptionsToProto(m)
+}
+
 // Submit submits a job to the given job service. It returns a jobID, if successful.
 func Submit(ctx context.Context, client jobpb.JobServiceClient, id, token string) (string, error) {
 	req := &jobpb.RunJobRequest{
@@ -89,11 +128,21 @@ func Submit(ctx context.Context, client jobpb.JobServiceClient, id, token string
 // WaitForCompletion monitors the given job until completion. It logs any messages
 // and state changes received.
 func WaitForCompletion(ctx context.Context, client jobpb.JobServiceClient, jobID string) error {
+	return WaitForCompletionWithMessages(ctx, client, jobID, nil, log.SevUnspecified)
+}
+
+// WaitForCompletionWithMessages monitors the given job until completion. In
+// addition to logging, it prints state changes and messages of at least the
+// given severity to w, if not nil, so that users see failures in the output
+// of the submitting process. If the job fails, the returned error includes
+// the last error message received.
+func WaitForCompletionWithMessages(ctx context.Context, client jobpb.JobServiceClient, jobID string, w io.Writer, level log.Severity) error {
 	stream, err := client.GetMessageStream(ctx, &jobpb.JobMessagesRequest{JobId: jobID})
 	if err != nil {
 		return fmt.Errorf("failed to get job stream: %v", err)
 	}
 
+	var lastErr string
 	for {
 		msg, err := stream.Recv()
 		if err != nil {
@@ -108,19 +157,32 @@ func WaitForCompletion(ctx context.Context, client jobpb.JobServiceClient, jobID
 			resp := msg.GetStateResponse()
 
 			log.Infof(ctx, "Job state: %v", resp.GetState().String())
+			if w != nil {
+				fmt.Fprintf(w, "Job %v: %v\n", jobID, resp.GetState())
+			}
 
 			switch resp.State {
 			case jobpb.JobState_DONE, jobpb.JobState_CANCELLED:
 				return nil
 			case jobpb.JobState_FAILED:
+				if lastErr != "" {
+					return fmt.Errorf("job %v failed: %v", jobID, lastErr)
+				}
 				return fmt.Errorf("job %v failed", jobID)
 			}
 
 		case msg.GetMessageResponse() != nil:
 			resp := msg.GetMessageResponse()
+			sev := messageSeverity(resp.GetImportance())
 
 			text := fmt.Sprintf("%v (%v): %v", resp.GetTime(), resp.GetMessageId(), resp.GetMessageText())
-			log.Output(ctx, messageSeverity(resp.GetImportance()), 1, text)
+			log.Output(ctx, sev, 1, text)
+			if sev == log.SevError {
+				lastErr = resp.GetMessageText()
+			}
+			if w != nil && level != log.SevUnspecified && sev >= level {
+				fmt.Fprintf(w, "%v %v\n", severityTag(sev), text)
+			}
 
 		default:
 			return fmt.Errorf("unexpected job update: %v", proto.MarshalTextString(msg))
@@ -128,6 +190,21 @@ func WaitForCompletion(ctx context.Context, client jobpb.JobServiceClient, jobID
 	}
 }
 
+func severityTag(sev log.Severity) string {
+	switch sev {
+	case log.SevDebug:
+		return "[DEBUG]"
+	case log.SevInfo:
+		return "[INFO]"
+	case log.SevWarn:
+		return "[WARN]"
+	case log.SevError:
+		return "[ERROR]"
+	default:
+		return "[?]"
+	}
+}
+
 func messageSeverity(importance jobpb.JobMessage_MessageImportance) log.Severity {
 	switch importance {
 	case jobpb.JobMessage_JOB_MESSAGE_ERROR:
@@ -0,0 +1,145 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runnerlib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	jobpb "github.com/apache/beam/sdks/go/pkg/beam/model/jobmanagement_v1"
+	"google.golang.org/grpc"
+)
+
+// fakeJobService serves a fixed message stream.
+type fakeJobService struct {
+	jobpb.JobServiceClient
+	msgs []*jobpb.JobMessagesResponse
+}
+
+func (f *fakeJobService) GetMessageStream(ctx context.Context, in *jobpb.JobMessagesRequest, opts ...grpc.CallOption) (jobpb.JobService_GetMessageStreamClient, error) {
+	return &fakeStream{msgs: f.msgs}, nil
+}
+
+type fakeStream struct {
+	grpc.ClientStream
+	msgs []*jobpb.JobMessagesResponse
+}
+
+func (f *fakeStream) Recv() (*jobpb.JobMessagesResponse, error) {
+	if len(f.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := f.msgs[0]
+	f.msgs = f.msgs[1:]
+	return msg, nil
+}
+
+func state(s jobpb.JobState_Enum) *jobpb.JobMessagesResponse {
+	return &jobpb.JobMessagesResponse{Response: &jobpb.JobMessagesResponse_StateResponse{
+		StateResponse: &jobpb.GetJobStateResponse{State: s},
+	}}
+}
+
+func message(importance jobpb.JobMessage_MessageImportance, text string) *jobpb.JobMessagesResponse {
+	return &jobpb.JobMessagesResponse{Response: &jobpb.JobMessagesResponse_MessageResponse{
+		MessageResponse: &jobpb.JobMessage{Importance: importance, MessageText: text},
+	}}
+}
+
+func TestWaitForCompletionWithMessages(t *testing.T) {
+	client := &fakeJobService{msgs: []*jobpb.JobMessagesResponse{
+		state(jobpb.JobState_RUNNING),
+		message(jobpb.JobMessage_JOB_MESSAGE_DEBUG, "debug detail"),
+		message(jobpb.JobMessage_JOB_MESSAGE_WARNING, "slow worker"),
+		message(jobpb.JobMessage_JOB_MESSAGE_ERROR, "worker crashed"),
+		state(jobpb.JobState_FAILED),
+	}}
+
+	var buf bytes.Buffer
+	err := WaitForCompletionWithMessages(context.Background(), client, "job1", &buf, log.SevWarn)
+	if err == nil || !strings.Contains(err.Error(), "worker crashed") {
+		t.Errorf("WaitForCompletionWithMessages = %v, want failure with last error message", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"Job job1: RUNNING", "[WARN]", "slow worker", "[ERROR]", "worker crashed", "Job job1: FAILED"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%v", want, out)
+		}
+	}
+	if strings.Contains(out, "debug detail") {
+		t.Errorf("output contains message below level:\n%v", out)
+	}
+}
+
+func TestWaitForCompletionDone(t *testing.T) {
+	client := &fakeJobService{msgs: []*jobpb.JobMessagesResponse{
+		state(jobpb.JobState_RUNNING),
+		message(jobpb.JobMessage_JOB_MESSAGE_BASIC, "hello"),
+		state(jobpb.JobState_DONE),
+	}}
+
+	var buf bytes.Buffer
+	if err := WaitForCompletionWithMessages(context.Background(), client, "job2", &buf, log.SevUnspecified); err != nil {
+		t.Errorf("WaitForCompletionWithMessages failed: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "hello") || !strings.Contains(out, "Job job2: DONE") {
+		t.Errorf("unexpected output with messages off:\n%v", out)
+	}
+}
+
+func TestRunnerOptionsToProto(t *testing.T) {
+	raw := runtime.RawOptionsWrapper{
+		Runner:      "org.apache.beam.runners.flink.FlinkRunner",
+		AppName:     "job",
+		Experiments: []string{"beam_fn_api"},
+	}
+	extra := map[string]interface{}{
+		"savepoint_path":           "/tmp/sp/savepoint-1",
+		"allow_non_restored_state": true,
+	}
+
+	options, err := runnerOptionsToProto(raw, extra)
+	if err != nil {
+		t.Fatalf("runnerOptionsToProto failed: %v", err)
+	}
+	fields := options.GetFields()
+	if got := fields["beam:option:savepoint_path:v1"].GetStringValue(); got != "/tmp/sp/savepoint-1" {
+		t.Errorf("savepoint_path = %q, want /tmp/sp/savepoint-1", got)
+	}
+	if got := fields["beam:option:allow_non_restored_state:v1"].GetBoolValue(); !got {
+		t.Errorf("allow_non_restored_state = %v, want true", got)
+	}
+	if got := fields["beam:option:runner:v1"].GetStringValue(); got != raw.Runner {
+		t.Errorf("runner = %q, want %q", got, raw.Runner)
+	}
+	if got := fields["beam:option:app_name:v1"].GetStringValue(); got != "job" {
+		t.Errorf("app_name = %q, want job", got)
+	}
+
+	options, err = runnerOptionsToProto(raw, nil)
+	if err != nil {
+		t.Fatalf("runnerOptionsToProto without runner options failed: %v", err)
+	}
+	if _, ok := options.GetFields()["beam:option:savepoint_path:v1"]; ok {
+		t.Errorf("savepoint_path set without runner options: %v", options)
+	}
+}
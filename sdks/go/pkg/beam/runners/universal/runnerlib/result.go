@@ -0,0 +1,131 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runnerlib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/buildinfo"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	jobpb "github.com/apache/beam/sdks/go/pkg/beam/model/jobmanagement_v1"
+	"github.com/apache/beam/sdks/go/pkg/beam/util/grpcx"
+)
+
+// result is the result of a job submitted to a job service. The job API does
+// not report metrics, so the metrics of the result are empty.
+type result struct {
+	endpoint, jobID string
+	messages        io.Writer
+	level           log.Severity
+	info            buildinfo.Info
+
+	mu   sync.Mutex
+	done bool
+	err  error // error of the finished job
+}
+
+func (r *result) JobID() string {
+	return r.jobID
+}
+
+func (r *result) State(ctx context.Context) (beam.JobState, error) {
+	cc, err := grpcx.Dial(ctx, r.endpoint, 2*time.Minute)
+	if err != nil {
+		return beam.JobUnknown, fmt.Errorf("failed to connect to job service: %v", err)
+	}
+	defer cc.Close()
+
+	resp, err := jobpb.NewJobServiceClient(cc).GetState(ctx, &jobpb.GetJobStateRequest{JobId: r.jobID})
+	if err != nil {
+		return beam.JobUnknown, fmt.Errorf("failed to get state of job %v: %v", r.jobID, err)
+	}
+	return jobState(resp.GetState()), nil
+}
+
+func (r *result) Wait(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done {
+		return r.err
+	}
+
+	cc, err := grpcx.Dial(ctx, r.endpoint, 2*time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to connect to job service: %v", err)
+	}
+	defer cc.Close()
+
+	return r.wait(ctx, jobpb.NewJobServiceClient(cc))
+}
+
+// wait waits for completion of the job with the given client. It must be
+// called with mu held.
+func (r *result) wait(ctx context.Context, client jobpb.JobServiceClient) error {
+	err := WaitForCompletionWithMessages(ctx, client, r.jobID, r.messages, r.level)
+	if ctx.Err() == nil {
+		r.done, r.err = true, err
+	}
+	return err
+}
+
+func (r *result) Cancel(ctx context.Context) error {
+	cc, err := grpcx.Dial(ctx, r.endpoint, 2*time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to connect to job service: %v", err)
+	}
+	defer cc.Close()
+
+	if _, err := jobpb.NewJobServiceClient(cc).Cancel(ctx, &jobpb.CancelJobRequest{JobId: r.jobID}); err != nil {
+		return fmt.Errorf("failed to cancel job %v: %v", r.jobID, err)
+	}
+	return nil
+}
+
+// Drain returns an error, as the job API does not support draining.
+func (r *result) Drain(ctx context.Context) error {
+	return fmt.Errorf("failed to drain job %v: draining is not supported by the job service", r.jobID)
+}
+
+func (r *result) Metrics(ctx context.Context) (*metrics.ResultSet, error) {
+	return metrics.NewResultSet(nil), nil
+}
+
+func (r *result) BuildInfo() buildinfo.Info {
+	return r.info
+}
+
+// jobState converts a job service state to a beam.JobState.
+func jobState(s jobpb.JobState_Enum) beam.JobState {
+	switch s {
+	case jobpb.JobState_STOPPED, jobpb.JobState_STARTING, jobpb.JobState_RUNNING, jobpb.JobState_DRAINING, jobpb.JobState_CANCELLING:
+		return beam.JobRunning
+	case jobpb.JobState_DONE, jobpb.JobState_DRAINED, jobpb.JobState_UPDATED:
+		return beam.JobDone
+	case jobpb.JobState_FAILED:
+		return beam.JobFailed
+	case jobpb.JobState_CANCELLED:
+		return beam.JobCancelled
+	default:
+		return beam.JobUnknown
+	}
+}
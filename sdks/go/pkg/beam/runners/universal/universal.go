@@ -14,14 +14,23 @@
 // limitations under the License.
 
 // Package universal contains a general-purpose runner that can submit jobs
-// to any portable Beam runner.
+// to any portable Beam runner, such as Flink or Spark, through its job
+// service:
+//
+//    go run main.go --runner=universal --endpoint=localhost:8099
+//
+// The worker binary and any --artifacts are staged with the job, and the job
+// state and messages are printed until it completes, unless --async.
 package universal
 
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/artifact"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
 	// Importing to get the side effect of the remote execution hook. See init().
 	_ "github.com/apache/beam/sdks/go/pkg/beam/core/runtime/harness/init"
@@ -35,27 +44,52 @@ func init() {
 }
 
 // Execute executes the pipeline on a universal beam runner.
-func Execute(ctx context.Context, p *beam.Pipeline) error {
+func Execute(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
+	return ExecuteWithOptions(ctx, p, nil)
+}
+
+// ExecuteWithOptions executes the pipeline on a universal beam runner with
+// additional runner-specific pipeline options. It returns the result of the
+// job. The job service does not report metrics, so the metrics of the result
+// are empty.
+func ExecuteWithOptions(ctx context.Context, p *beam.Pipeline, runnerOpts map[string]interface{}) (beam.PipelineResult, error) {
 	endpoint, err := jobopts.GetEndpoint()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	edges, _, err := p.Build()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	pipeline, err := graphx.Marshal(edges, &graphx.Options{ContainerImageURL: jobopts.GetContainerImage(ctx)})
 	if err != nil {
-		return fmt.Errorf("failed to generate model pipeline: %v", err)
+		return nil, fmt.Errorf("failed to generate model pipeline: %v", err)
+	}
+
+	level, err := jobopts.GetJobMessageLevel()
+	if err != nil {
+		return nil, err
 	}
 
 	opt := &runnerlib.JobOptions{
 		Name:               jobopts.GetJobName(),
 		Experiments:        jobopts.GetExperiments(),
 		Worker:             *jobopts.WorkerBinary,
+		Artifacts:          artifacts(),
 		InternalJavaRunner: *jobopts.InternalJavaRunner,
+		RunnerOptions:      runnerOpts,
+		MessageLevel:       level,
+		Messages:           os.Stdout,
+	}
+	return runnerlib.Execute(ctx, pipeline, endpoint, opt, *jobopts.Async)
+}
+
+// artifacts returns the additional files to stage, keyed by their base name.
+func artifacts() []artifact.KeyedFile {
+	var ret []artifact.KeyedFile
+	for _, name := range jobopts.GetArtifacts() {
+		ret = append(ret, artifact.KeyedFile{Key: filepath.Base(name), Filename: name})
 	}
-	_, err = runnerlib.Execute(ctx, pipeline, endpoint, opt, *jobopts.Async)
-	return err
+	return ret
 }
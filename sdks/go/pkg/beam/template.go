@@ -0,0 +1,168 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+)
+
+// TemplateTimeOption is the pipeline option that holds the time used by
+// template values, in RFC 3339 format. It is set to the construction time,
+// unless given, such that all workers of a run interpolate the same time.
+// Setting it explicitly, such as --template_time=2018-06-01T00:00:00Z,
+// re-runs a scheduled pipeline for a past date.
+const TemplateTimeOption = "template_time"
+
+// templateData is the data available to templates.
+type templateData struct {
+	// Time is the time of the run in UTC.
+	Time time.Time
+	// Date is the date of the run, formatted as 2006-01-02.
+	Date string
+	// Options are the pipeline options.
+	Options map[string]string
+}
+
+// TemplateValue returns a ValueProvider that interpolates the given
+// text/template when read, for parameterizing file patterns, table names or
+// topics of scheduled runs. For example:
+//
+//    glob := beam.TemplateValue("gs://bucket/logs/{{.Date}}/*.txt")
+//    lines := textio.ReadValue(s, glob)
+//
+// The template has access to the following:
+//
+//    {{.Date}}             the date of the run, such as 2018-06-01
+//    {{.Time}}             the time of the run, a time.Time in UTC
+//    {{.Options.name}}     the pipeline option "name", which may be a
+//                          runtime parameter
+//    {{env "NAME"}}        the environment variable NAME at construction time
+//
+// The time of the run is given by the TemplateTimeOption. Template values
+// are deferred until run time, so runtime parameters can be used. The
+// template is validated immediately and it panics if invalid.
+func TemplateValue(tmpl string) ValueProvider[string] {
+	env, err := captureEnv(tmpl)
+	if err != nil {
+		panic(err)
+	}
+	return ValueProvider[string]{Template: tmpl, Env: env}
+}
+
+// Interpolate interpolates the given template at construction time. It
+// supports the same template as TemplateValue and is useful for IOs that are
+// configured with plain strings. For example:
+//
+//    table := beam.MustInterpolate("project:dataset.events_{{.Time.Format \"20060102\"}}")
+func Interpolate(tmpl string) (string, error) {
+	env, err := captureEnv(tmpl)
+	if err != nil {
+		return "", err
+	}
+	return interpolate(tmpl, env)
+}
+
+// MustInterpolate interpolates the given template at construction time. It
+// panics if the template is invalid.
+func MustInterpolate(tmpl string) string {
+	ret, err := Interpolate(tmpl)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// captureEnv parses the template and returns the environment variables it
+// uses. It also fixes the time of the run, if not already set.
+func captureEnv(tmpl string) (map[string]string, error) {
+	if runtime.GlobalOptions.Get(TemplateTimeOption) == "" {
+		runtime.GlobalOptions.Set(TemplateTimeOption, time.Now().UTC().Format(time.RFC3339))
+	}
+
+	env := make(map[string]string)
+	lookup := func(name string) string {
+		value := os.Getenv(name)
+		env[name] = value
+		return value
+	}
+	t, err := parseTemplate(tmpl, lookup)
+	if err != nil {
+		return nil, err
+	}
+	// Execute the template to find the environment variables used. Runtime
+	// parameters may not be set yet, so the result is discarded.
+	data, err := newTemplateData()
+	if err != nil {
+		return nil, err
+	}
+	t.Option("missingkey=zero")
+	if err := t.Execute(&bytes.Buffer{}, data); err != nil {
+		return nil, fmt.Errorf("invalid template %q: %v", tmpl, err)
+	}
+	if len(env) == 0 {
+		return nil, nil
+	}
+	return env, nil
+}
+
+// interpolate executes the template with the captured environment.
+func interpolate(tmpl string, env map[string]string) (string, error) {
+	t, err := parseTemplate(tmpl, func(name string) string { return env[name] })
+	if err != nil {
+		return "", err
+	}
+	data, err := newTemplateData()
+	if err != nil {
+		return "", err
+	}
+	t.Option("missingkey=error")
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to interpolate template %q: %v", tmpl, err)
+	}
+	return buf.String(), nil
+}
+
+func parseTemplate(tmpl string, env func(string) string) (*template.Template, error) {
+	t, err := template.New("value").Funcs(template.FuncMap{"env": env}).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %v", tmpl, err)
+	}
+	return t, nil
+}
+
+func newTemplateData() (templateData, error) {
+	t := time.Now().UTC()
+	if value := runtime.GlobalOptions.Get(TemplateTimeOption); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return templateData{}, fmt.Errorf("invalid %v option: %v", TemplateTimeOption, err)
+		}
+		t = parsed.UTC()
+	}
+	return templateData{
+		Time:    t,
+		Date:    t.Format("2006-01-02"),
+		Options: runtime.GlobalOptions.Export().Options,
+	}, nil
+}
@@ -0,0 +1,237 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package passert
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+)
+
+// maxFieldDiffs limits the number of field differences reported per value.
+const maxFieldDiffs = 10
+
+// failDiffFn fails with a report of the unexpected and missing values. Each
+// unexpected value is paired with the most similar missing value, if any, and
+// reported as a list of field-level differences with nested paths, such as
+// ".Address.ZipCode: got "12345", want "54321"".
+type failDiffFn struct{}
+
+func (f *failDiffFn) ProcessElement(_ []byte, unexpected, missing func(*beam.T) bool) error {
+	var got, want []beam.T
+
+	var val beam.T
+	for unexpected(&val) {
+		got = append(got, val)
+	}
+	for missing(&val) {
+		want = append(want, val)
+	}
+	if len(got) == 0 && len(want) == 0 {
+		return nil
+	}
+	return fmt.Errorf("actual PCollection does not match expected values:\n%v", diffReport(got, want))
+}
+
+// diffReport pairs unexpected values with their closest missing value and
+// formats the differences.
+func diffReport(got, want []beam.T) string {
+	var lines []string
+
+	used := make([]bool, len(want))
+	for _, g := range got {
+		best, bestDiffs := -1, []string(nil)
+		for i, w := range want {
+			if used[i] {
+				continue
+			}
+			diffs, ok := fieldDiffs(reflect.ValueOf(g), reflect.ValueOf(w))
+			if !ok {
+				continue
+			}
+			if best < 0 || len(diffs) < len(bestDiffs) {
+				best, bestDiffs = i, diffs
+			}
+		}
+
+		if best < 0 {
+			lines = append(lines, fmt.Sprintf("\tvalue %v present, but not expected", g))
+			continue
+		}
+		used[best] = true
+
+		lines = append(lines, fmt.Sprintf("\tvalue %v present, but not expected; closest expected value %v differs in:", g, want[best]))
+		for i, d := range bestDiffs {
+			if i == maxFieldDiffs {
+				lines = append(lines, fmt.Sprintf("\t\t... and %v more", len(bestDiffs)-maxFieldDiffs))
+				break
+			}
+			lines = append(lines, "\t\t"+d)
+		}
+	}
+	for i, w := range want {
+		if !used[i] {
+			lines = append(lines, fmt.Sprintf("\tvalue %v expected, but not present", w))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fieldDiffs returns the differences between two values of the same struct
+// type, or pointers to such. It returns false if the values are not
+// comparable field by field or share no fields at all.
+func fieldDiffs(a, b reflect.Value) ([]string, bool) {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() {
+		return nil, false
+	}
+	t := a.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || !hasExportedFields(t) {
+		return nil, false
+	}
+
+	var diffs []string
+	diff("", a, b, &diffs)
+	if countFields(t) <= len(diffs) {
+		return nil, false // nothing in common
+	}
+	return diffs, true
+}
+
+func countFields(t reflect.Type) int {
+	n := 0
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && hasExportedFields(ft) {
+			n += countFields(ft)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// diff appends the differences between a and b, which have the same type, at
+// the given path.
+func diff(path string, a, b reflect.Value, diffs *[]string) {
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		switch {
+		case a.IsNil() && b.IsNil():
+		case a.IsNil() || b.IsNil():
+			report(path, a, b, diffs)
+		default:
+			if a.Kind() == reflect.Interface && a.Elem().Type() != b.Elem().Type() {
+				report(path, a, b, diffs)
+				return
+			}
+			diff(path, a.Elem(), b.Elem(), diffs)
+		}
+
+	case reflect.Struct:
+		t := a.Type()
+		if !hasExportedFields(t) {
+			// Opaque struct, such as time.Time.
+			if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+				report(path, a, b, diffs)
+			}
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			diff(path+"."+t.Field(i).Name, a.Field(i), b.Field(i), diffs)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.Len() != b.Len() {
+			*diffs = append(*diffs, fmt.Sprintf("%v: got len %v, want len %v", root(path), a.Len(), b.Len()))
+		}
+		n := a.Len()
+		if b.Len() < n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			diff(fmt.Sprintf("%v[%v]", path, i), a.Index(i), b.Index(i), diffs)
+		}
+
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, k := range a.MapKeys() {
+			keys[fmt.Sprint(k)] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[fmt.Sprint(k)] = k
+		}
+		var names []string
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			k := keys[name]
+			p := fmt.Sprintf("%v[%v]", path, name)
+			av, bv := a.MapIndex(k), b.MapIndex(k)
+			switch {
+			case !av.IsValid():
+				*diffs = append(*diffs, fmt.Sprintf("%v: missing, want %v", p, bv))
+			case !bv.IsValid():
+				*diffs = append(*diffs, fmt.Sprintf("%v: got %v, want missing", p, av))
+			default:
+				diff(p, av, bv, diffs)
+			}
+		}
+
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			report(path, a, b, diffs)
+		}
+	}
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func report(path string, a, b reflect.Value, diffs *[]string) {
+	*diffs = append(*diffs, fmt.Sprintf("%v: got %#v, want %#v", root(path), a.Interface(), b.Interface()))
+}
+
+func root(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
@@ -0,0 +1,105 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package passert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+type address struct {
+	Street  string
+	ZipCode string
+}
+
+type person struct {
+	Name    string
+	Age     int
+	Home    *address
+	Tags    []string
+	Scores  map[string]int
+	private int
+}
+
+func TestFieldDiffs(t *testing.T) {
+	a := person{
+		Name:   "alice",
+		Age:    30,
+		Home:   &address{Street: "Main", ZipCode: "12345"},
+		Tags:   []string{"a", "b"},
+		Scores: map[string]int{"x": 1, "y": 2},
+	}
+	b := person{
+		Name:    "alice",
+		Age:     31,
+		Home:    &address{Street: "Main", ZipCode: "54321"},
+		Tags:    []string{"a"},
+		Scores:  map[string]int{"x": 1, "z": 3},
+		private: 1,
+	}
+
+	diffs, ok := fieldDiffs(reflect.ValueOf(a), reflect.ValueOf(b))
+	if !ok {
+		t.Fatalf("fieldDiffs(%v, %v) failed", a, b)
+	}
+	want := []string{
+		`.Age: got 30, want 31`,
+		`.Home.ZipCode: got "12345", want "54321"`,
+		`.Tags: got len 2, want len 1`,
+		`.Scores[y]: got 2, want missing`,
+		`.Scores[z]: missing, want 3`,
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("fieldDiffs(%v, %v) = %q, want %q", a, b, diffs, want)
+	}
+
+	if _, ok := fieldDiffs(reflect.ValueOf(1), reflect.ValueOf(2)); ok {
+		t.Errorf("fieldDiffs(1, 2) succeeded, want non-struct failure")
+	}
+	if _, ok := fieldDiffs(reflect.ValueOf(address{"a", "b"}), reflect.ValueOf(address{"c", "d"})); ok {
+		t.Errorf("fieldDiffs succeeded for values with nothing in common, want failure")
+	}
+}
+
+func TestDiffReport(t *testing.T) {
+	got := []beam.T{address{"Main", "1"}, address{"Elm", "9"}}
+	want := []beam.T{address{"Oak", "5"}, address{"Main", "2"}}
+
+	report := diffReport(got, want)
+	for _, s := range []string{
+		"closest expected value {Main 2} differs in:\n\t\t.ZipCode: got \"1\", want \"2\"",
+		"value {Elm 9} present, but not expected\n",
+		"value {Oak 5} expected, but not present",
+	} {
+		if !strings.Contains(report, s) {
+			t.Errorf("diffReport(%v, %v) = %v, want it to contain %q", got, want, report, s)
+		}
+	}
+}
+
+func TestEqualsFieldDiff(t *testing.T) {
+	p, s, col := ptest.CreateList([]address{{"Main", "1"}, {"Elm", "2"}})
+	Equals(s, col, address{"Main", "1"}, address{"Elm", "3"})
+
+	err := ptest.Run(p)
+	if err == nil || !strings.Contains(err.Error(), `.ZipCode: got "2", want "3"`) {
+		t.Errorf("Equals failed with %v, want field difference", err)
+	}
+}
@@ -32,6 +32,7 @@ import (
 
 func init() {
 	beam.RegisterType(reflect.TypeOf((*diffFn)(nil)))
+	beam.RegisterType(reflect.TypeOf((*failDiffFn)(nil)))
 	beam.RegisterType(reflect.TypeOf((*failFn)(nil)))
 	beam.RegisterType(reflect.TypeOf((*failKVFn)(nil)))
 	beam.RegisterType(reflect.TypeOf((*failGBKFn)(nil)))
@@ -52,11 +53,12 @@ func Equals(s beam.Scope, col beam.PCollection, values ...interface{}) beam.PCol
 	return equals(s, col, other)
 }
 
-// equals verifies that the actual values match the expected ones.
+// equals verifies that the actual values match the expected ones. Mismatched
+// struct values are reported with field-level differences.
 func equals(s beam.Scope, actual, expected beam.PCollection) beam.PCollection {
 	bad, _, bad2 := Diff(s, actual, expected)
-	fail(s, bad, "value %v present, but not expected")
-	fail(s, bad2, "value %v expected, but not present")
+	imp := beam.Impulse(s)
+	beam.ParDo0(s, &failDiffFn{}, imp, beam.SideInput{Input: bad}, beam.SideInput{Input: bad2})
 	return actual
 }
 
@@ -55,5 +55,6 @@ func CreateList2(a, b interface{}) (*beam.Pipeline, beam.Scope, beam.PCollection
 // Run runs a pipeline for testing. The semantics of the pipeline is expected
 // to be verified through passert.
 func Run(p *beam.Pipeline) error {
-	return direct.Execute(context.Background(), p)
+	_, err := direct.Execute(context.Background(), p)
+	return err
 }
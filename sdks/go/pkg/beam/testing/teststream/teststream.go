@@ -0,0 +1,198 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package teststream contains TestStream, a source that replays a scripted
+// sequence of elements, watermark advances and processing time advances to
+// make streaming behavior, such as timers, deterministically testable.
+//
+// For example, the following stream produces two elements at 10:00, moves
+// the watermark past them and then produces a late element:
+//
+//    c := teststream.NewConfig()
+//    c.AddElements(ten, "a", "b")
+//    c.AdvanceWatermark(ten.Add(time.Minute))
+//    c.AddElements(ten, "late")
+//    c.AdvanceWatermarkToInfinity()
+//
+//    col := teststream.Create(s, c)  // col : string
+//
+// TestStream is supported by the direct runner. Other runners emit all
+// elements at their timestamps in a single bundle, ignoring the advances.
+package teststream
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*StreamFn)(nil)).Elem())
+}
+
+// EventKind is the kind of a TestStream event.
+type EventKind string
+
+const (
+	// ElementEvent adds elements to the stream.
+	ElementEvent EventKind = "elements"
+	// WatermarkEvent advances the watermark.
+	WatermarkEvent EventKind = "watermark"
+	// ProcessingTimeEvent advances the processing time.
+	ProcessingTimeEvent EventKind = "processing_time"
+)
+
+// Event is a single step of a TestStream.
+type Event struct {
+	Kind EventKind `json:"kind"`
+	// Timestamp is the timestamp of the elements or the new watermark.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	// Values are the JSON-encoded elements.
+	Values []string `json:"values,omitempty"`
+	// Advance is the amount processing time advances.
+	Advance time.Duration `json:"advance,omitempty"`
+}
+
+// Elements returns the decoded elements of the event, which are of the given
+// type.
+func (e Event) Elements(t reflect.Type) ([]interface{}, error) {
+	var ret []interface{}
+	for _, str := range e.Values {
+		value, err := reflectx.UnmarshalJSON(t, str)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, value)
+	}
+	return ret, nil
+}
+
+// Config is the script of a TestStream. Elements must all be of the same
+// type and the watermark can only move forward.
+type Config struct {
+	t         reflect.Type
+	events    []Event
+	watermark time.Time
+}
+
+// NewConfig returns an empty TestStream script.
+func NewConfig() *Config {
+	return &Config{}
+}
+
+// AddElements adds elements with the given event time to the stream.
+func (c *Config) AddElements(timestamp time.Time, values ...interface{}) error {
+	if len(values) == 0 {
+		return fmt.Errorf("no elements to add")
+	}
+
+	e := Event{Kind: ElementEvent, Timestamp: timestamp}
+	for i, value := range values {
+		t := reflect.TypeOf(value)
+		if c.t == nil {
+			c.t = t
+		}
+		if t != c.t {
+			return fmt.Errorf("value %v at index %v has type %v, want %v", value, i, t, c.t)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("marshalling of %v failed: %v", value, err)
+		}
+		e.Values = append(e.Values, string(data))
+	}
+	c.events = append(c.events, e)
+	return nil
+}
+
+// AdvanceWatermark advances the watermark of the stream to the given time.
+// Timers set no later than the watermark fire when it advances.
+func (c *Config) AdvanceWatermark(t time.Time) error {
+	if !t.After(c.watermark) {
+		return fmt.Errorf("watermark must advance: %v is not after %v", t, c.watermark)
+	}
+	c.watermark = t
+	c.events = append(c.events, Event{Kind: WatermarkEvent, Timestamp: t})
+	return nil
+}
+
+// AdvanceWatermarkToInfinity advances the watermark past all timestamps,
+// which signals that the stream has no more elements.
+func (c *Config) AdvanceWatermarkToInfinity() error {
+	return c.AdvanceWatermark(time.Time(exec.EndOfTime))
+}
+
+// AdvanceProcessingTime advances the processing time of the stream by the
+// given duration.
+func (c *Config) AdvanceProcessingTime(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("processing time must advance: %v", d)
+	}
+	c.events = append(c.events, Event{Kind: ProcessingTimeEvent, Advance: d})
+	return nil
+}
+
+// Create inserts a TestStream that replays the given script into the pipeline.
+// It returns a PCollection of the element type. The script must contain at
+// least one element.
+func Create(s beam.Scope, c *Config) beam.PCollection {
+	return beam.Must(TryCreate(s, c))
+}
+
+// TryCreate inserts a TestStream into the pipeline. It fails if the script
+// contains no elements.
+func TryCreate(s beam.Scope, c *Config) (beam.PCollection, error) {
+	if c.t == nil {
+		return beam.PCollection{}, fmt.Errorf("teststream has no elements")
+	}
+
+	s = s.Scope("teststream.Create")
+	imp := beam.Impulse(s)
+	fn := &StreamFn{Events: append([]Event(nil), c.events...), T: beam.EncodedType{T: c.t}}
+	ret, err := beam.TryParDo(s, fn, imp, beam.TypeDefinition{Var: beam.TType, T: c.t})
+	if err != nil {
+		return beam.PCollection{}, err
+	}
+	return ret[0], nil
+}
+
+// StreamFn emits the elements of a TestStream. Runners that support
+// TestStream replay its events instead of invoking it.
+type StreamFn struct {
+	Events []Event          `json:"events"`
+	T      beam.EncodedType `json:"type"`
+}
+
+// ProcessElement emits all elements at their timestamps.
+func (f *StreamFn) ProcessElement(_ []byte, emit func(beam.EventTime, beam.T)) error {
+	for _, e := range f.Events {
+		if e.Kind != ElementEvent {
+			continue
+		}
+		values, err := e.Elements(f.T.T)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			emit(beam.EventTime(e.Timestamp), value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package convert contains lightweight transformations for converting elements
+// between string, []byte and JSON representations.
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+var jsonRawMessageType = reflect.TypeOf(json.RawMessage{})
+
+func init() {
+	beam.RegisterFunction(bytesToStringFn)
+	beam.RegisterFunction(stringToBytesFn)
+	beam.RegisterFunction(toJSONFn)
+	beam.RegisterType(reflect.TypeOf((*fromJSONFn)(nil)).Elem())
+}
+
+// BytesToString converts a PCollection<[]byte> to a PCollection<string>.
+func BytesToString(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("convert.BytesToString")
+	return beam.ParDo(s, bytesToStringFn, col)
+}
+
+func bytesToStringFn(b []byte) string {
+	return string(b)
+}
+
+// StringToBytes converts a PCollection<string> to a PCollection<[]byte>.
+func StringToBytes(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("convert.StringToBytes")
+	return beam.ParDo(s, stringToBytesFn, col)
+}
+
+func stringToBytesFn(str string) []byte {
+	return []byte(str)
+}
+
+// buffers is a pool of encoding and decoding buffers.
+var buffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// ToJSON encodes each element of a PCollection<A> as JSON. It returns a
+// PCollection<json.RawMessage>. For example:
+//
+//    events := ...                    // PCollection<Event>
+//    raw := convert.ToJSON(s, events) // PCollection<json.RawMessage>
+//
+func ToJSON(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("convert.ToJSON")
+	return beam.ParDo(s, toJSONFn, col)
+}
+
+func toJSONFn(elm beam.T) (json.RawMessage, error) {
+	buf := buffers.Get().(*bytes.Buffer)
+	defer buffers.Put(buf)
+	buf.Reset()
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(elm); err != nil {
+		return nil, err
+	}
+	// Copy the encoding without the trailing newline added by the encoder.
+	data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	return append(json.RawMessage(nil), data...), nil
+}
+
+// FromJSON decodes each element of a PCollection<string>, PCollection<[]byte>
+// or PCollection<json.RawMessage> as JSON into a value of the given type. The
+// type should be registered with beam.RegisterType, so that it is decoded as-is
+// on remote workers. It returns a PCollection<A>, where A is the given type.
+// For example:
+//
+//    lines := textio.Read(s, "...")  // PCollection<string>
+//    events := convert.FromJSON(s, reflect.TypeOf(Event{}), lines)
+//
+func FromJSON(s beam.Scope, t reflect.Type, col beam.PCollection) beam.PCollection {
+	s = s.Scope("convert.FromJSON")
+
+	switch in := col.Type().Type(); in {
+	case reflectx.String, reflectx.ByteSlice, jsonRawMessageType:
+	default:
+		panic(fmt.Sprintf("FromJSON requires string, []byte or json.RawMessage input: %v", in))
+	}
+	return beam.ParDo(s, &fromJSONFn{Type: beam.EncodedType{T: t}}, col, beam.TypeDefinition{Var: beam.TType, T: t})
+}
+
+type fromJSONFn struct {
+	// Type is the type decoded into.
+	Type beam.EncodedType `json:"type"`
+}
+
+func (f *fromJSONFn) ProcessElement(in beam.X) (beam.T, error) {
+	var data []byte
+	switch v := in.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	case string:
+		// Avoid allocating a []byte copy of the string for each element.
+		buf := buffers.Get().(*bytes.Buffer)
+		defer buffers.Put(buf)
+		buf.Reset()
+		buf.WriteString(v)
+		data = buf.Bytes()
+	default:
+		return nil, fmt.Errorf("unexpected JSON input type %T", in)
+	}
+
+	ret := reflect.New(f.Type.T)
+	if err := json.Unmarshal(data, ret.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %v", f.Type.T, err)
+	}
+	return ret.Elem().Interface(), nil
+}
@@ -0,0 +1,74 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+type event struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func init() {
+	beam.RegisterType(reflect.TypeOf(event{}))
+}
+
+func TestBytesToString(t *testing.T) {
+	p, s, words := ptest.CreateList([]string{"a", "bb", ""})
+	out := BytesToString(s, StringToBytes(s, words))
+	passert.Equals(s, out, "a", "bb", "")
+
+	if err := ptest.Run(p); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	events := []event{{"a", 1}, {"b<c>", 2}}
+
+	p, s, col := ptest.CreateList(events)
+	raw := ToJSON(s, col)
+	passert.Equals(s, BytesToString(s, beam.ParDo(s, rawToBytes, raw)), `{"name":"a","count":1}`, `{"name":"b<c>","count":2}`)
+	passert.Equals(s, FromJSON(s, reflect.TypeOf(event{}), raw), events[0], events[1])
+
+	lines := beam.Create(s, `{"name":"c","count":3}`)
+	passert.Equals(s, FromJSON(s, reflect.TypeOf(event{}), lines), event{"c", 3})
+
+	if err := ptest.Run(p); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFromJSONInvalid(t *testing.T) {
+	p, s, lines := ptest.CreateList([]string{`{"name":`})
+	FromJSON(s, reflect.TypeOf(event{}), lines)
+
+	if err := ptest.Run(p); err == nil {
+		t.Error("FromJSON succeeded on invalid input, want error")
+	}
+}
+
+func rawToBytes(m json.RawMessage) []byte {
+	return m
+}
@@ -0,0 +1,130 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package periodic contains transforms that emit elements at a fixed
+// interval, such as to refresh slowly-changing side inputs or to drive
+// heartbeat logic in streaming pipelines.
+package periodic
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/rtrackers/offsetrange"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*sequenceFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*SequenceDefinition)(nil)).Elem())
+}
+
+// SequenceDefinition defines a periodic sequence of times from Start,
+// inclusive, to End, exclusive, at the given Interval.
+type SequenceDefinition struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Interval time.Duration `json:"interval"`
+}
+
+func (d SequenceDefinition) validate() error {
+	if d.Interval <= 0 {
+		return fmt.Errorf("interval must be positive: %v", d.Interval)
+	}
+	if d.End.Before(d.Start) {
+		return fmt.Errorf("end %v is before start %v", d.End, d.Start)
+	}
+	return nil
+}
+
+// Impulse emits the times from start, inclusive, to end, exclusive, at the
+// given interval. It returns an unbounded PCollection<time.Time>, where each
+// element has its time as event time. Elements are emitted no earlier than
+// their time, so times in the past are emitted immediately and times in the
+// future as they pass. For example, a side input that is refreshed every 5
+// minutes for a day can be driven by:
+//
+//    start := time.Now()
+//    ticks := periodic.Impulse(s, start, start.Add(24*time.Hour), 5*time.Minute)
+//
+// The sequence is emitted by a splittable DoFn, which checkpoints while it
+// waits for the next time to pass instead of holding on to the bundle. The
+// watermark of the output advances with the times emitted.
+func Impulse(s beam.Scope, start, end time.Time, interval time.Duration) beam.PCollection {
+	s = s.Scope("periodic.Impulse")
+
+	def := SequenceDefinition{Start: start, End: end, Interval: interval}
+	if err := def.validate(); err != nil {
+		panic(fmt.Sprintf("invalid periodic impulse: %v", err))
+	}
+	return Sequence(s, beam.Create(s, def))
+}
+
+// Sequence emits the times of each SequenceDefinition of the input
+// PCollection<SequenceDefinition>. It returns an unbounded
+// PCollection<time.Time>, where each element has its time as event time. See
+// Impulse.
+func Sequence(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("periodic.Sequence")
+
+	out := beam.ParDo(s, &sequenceFn{}, col)
+	out.SetUnbounded()
+	return out
+}
+
+// sequenceFn is a splittable DoFn that emits the times of a sequence. The
+// restriction is the range of indices of the times in the sequence.
+type sequenceFn struct {
+	now func() time.Time
+}
+
+func (f *sequenceFn) Setup() {
+	if f.now == nil {
+		f.now = time.Now
+	}
+}
+
+func (f *sequenceFn) CreateInitialRestriction(def SequenceDefinition) offsetrange.Restriction {
+	if def.validate() != nil {
+		return offsetrange.Restriction{}
+	}
+	n := (def.End.Sub(def.Start) + def.Interval - 1) / def.Interval
+	return offsetrange.Restriction{Start: 0, End: int64(n)}
+}
+
+func (f *sequenceFn) CreateTracker(r offsetrange.Restriction) *offsetrange.Tracker {
+	return offsetrange.NewTracker(r)
+}
+
+func (f *sequenceFn) ProcessElement(rt *offsetrange.Tracker, def SequenceDefinition, emit func(beam.EventTime, time.Time)) (sdf.ProcessContinuation, error) {
+	if err := def.validate(); err != nil {
+		return sdf.StopProcessing(), err
+	}
+	for i := rt.GetRestriction().(offsetrange.Restriction).Start; ; i++ {
+		t := def.Start.Add(time.Duration(i) * def.Interval)
+
+		// Checkpoint the rest of the sequence until the next time passes. No
+		// later element is emitted before then, so it is the watermark.
+		if wait := t.Sub(f.now()); wait > 0 && i < rt.GetRestriction().(offsetrange.Restriction).End {
+			return sdf.ResumeProcessingIn(wait).WithWatermark(t), nil
+		}
+		if !rt.TryClaim(i) {
+			return sdf.StopProcessing(), nil
+		}
+		emit(beam.EventTime(t), t)
+	}
+}
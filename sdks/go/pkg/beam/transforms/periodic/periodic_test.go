@@ -0,0 +1,112 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package periodic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(formatTick)
+}
+
+func formatTick(t beam.EventTime, tick time.Time) string {
+	if !time.Time(t).Equal(tick) {
+		return "bad timestamp"
+	}
+	return tick.UTC().Format("15:04")
+}
+
+func TestImpulse(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	ticks := Impulse(s, start, start.Add(time.Hour), 15*time.Minute)
+	passert.Equals(s, beam.ParDo(s, formatTick, ticks), "12:00", "12:15", "12:30", "12:45")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Impulse failed: %v", err)
+	}
+}
+
+func TestImpulseFuture(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	// The times in the future are emitted as they pass, after checkpoints.
+	start := time.Now().Truncate(time.Second).Add(time.Second)
+	ticks := Impulse(s, start, start.Add(150*time.Millisecond), 50*time.Millisecond)
+	passert.Equals(s, beam.ParDo(s, formatTick, ticks), start.UTC().Format("15:04"), start.Add(50*time.Millisecond).UTC().Format("15:04"), start.Add(100*time.Millisecond).UTC().Format("15:04"))
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Impulse failed: %v", err)
+	}
+	if time.Now().Before(start.Add(100 * time.Millisecond)) {
+		t.Errorf("Impulse finished at %v, before its last time", time.Now())
+	}
+}
+
+func TestSequenceFnPacing(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	fn := &sequenceFn{now: func() time.Time { return now }}
+	fn.Setup()
+
+	def := SequenceDefinition{Start: now.Add(-time.Minute), End: now.Add(2 * time.Minute), Interval: time.Minute}
+	r := fn.CreateInitialRestriction(def)
+	if r != (offsetrange.Restriction{Start: 0, End: 3}) {
+		t.Fatalf("CreateInitialRestriction() = %v, want [0, 3)", r)
+	}
+
+	var got []time.Time
+	emit := func(_ beam.EventTime, t time.Time) { got = append(got, t) }
+
+	// The past and current times are emitted immediately and the rest is
+	// checkpointed until the next time.
+	rt := fn.CreateTracker(r)
+	pc, err := fn.ProcessElement(rt, def, emit)
+	if err != nil {
+		t.Fatalf("ProcessElement failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("emitted %v, want 2 times", got)
+	}
+	if wm, ok := pc.Watermark(); !pc.ShouldResume() || pc.ResumeDelay() != time.Minute || !ok || !wm.Equal(now.Add(time.Minute)) {
+		t.Errorf("ProcessElement() = %v, watermark %v, want resume in 1m with watermark %v", pc, wm, now.Add(time.Minute))
+	}
+	if _, residual, _ := rt.TrySplit(0); residual != (offsetrange.Restriction{Start: 2, End: 3}) {
+		t.Errorf("residual = %v, want [2, 3)", residual)
+	}
+
+	now = now.Add(time.Minute)
+	pc, err = fn.ProcessElement(fn.CreateTracker(offsetrange.Restriction{Start: 2, End: 3}), def, emit)
+	if err != nil {
+		t.Fatalf("ProcessElement failed: %v", err)
+	}
+	if len(got) != 3 || pc.ShouldResume() {
+		t.Errorf("emitted %v and %v, want 3 times and stop", got, pc)
+	}
+
+	if _, err := fn.ProcessElement(fn.CreateTracker(offsetrange.Restriction{}), SequenceDefinition{Interval: 0}, emit); err == nil {
+		t.Error("ProcessElement with zero interval succeeded, want error")
+	}
+}
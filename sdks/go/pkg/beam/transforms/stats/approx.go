@@ -0,0 +1,157 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*approxCountDistinctFn)(nil)).Elem())
+}
+
+// precision is the number of hash bits that select a HyperLogLog register.
+// The sketch has 2^precision registers, which gives a relative standard
+// error of about 1.04/sqrt(2^precision), or 1.6%.
+const precision = 12
+
+// ApproximateCountDistinct estimates the number of distinct elements in a
+// collection, under coder equality, using a HyperLogLog sketch. It expects a
+// PCollection<T> as input and returns a singleton PCollection<int64>. Unlike
+// counting the output of filter.Distinct, it uses constant memory per worker
+// and a single, small accumulator of a few KB.
+//
+// For example:
+//
+//    col := beam.Create(s, "a", "b", "a", "c")
+//    n := stats.ApproximateCountDistinct(s, col)   // PCollection<int64> with 3 as the only element.
+//
+func ApproximateCountDistinct(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("stats.ApproximateCountDistinct")
+
+	t := beam.ValidateNonCompositeType(col)
+	return beam.Combine(s, &approxCountDistinctFn{Coder: beam.EncodedCoder{Coder: beam.NewCoder(t)}}, col)
+}
+
+// ApproximateCountDistinctPerKey estimates the number of distinct values for
+// each key in a collection. It expects a PCollection<KV<K,V>> as input and
+// returns a PCollection<KV<K,int64>>.
+func ApproximateCountDistinctPerKey(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("stats.ApproximateCountDistinctPerKey")
+
+	_, t := beam.ValidateKVType(col)
+	return beam.CombinePerKey(s, &approxCountDistinctFn{Coder: beam.EncodedCoder{Coder: beam.NewCoder(t)}}, col)
+}
+
+// approxCountDistinctFn is a CombineFn that adds the hashes of the encoded
+// elements to a HyperLogLog sketch. The accumulator is the slice of
+// registers, which is empty until the first element is added.
+type approxCountDistinctFn struct {
+	Coder beam.EncodedCoder `json:"coder"`
+
+	enc exec.ElementEncoder
+	buf bytes.Buffer
+}
+
+func (f *approxCountDistinctFn) Setup() {
+	f.enc = exec.MakeElementEncoder(beam.UnwrapCoder(f.Coder.Coder))
+}
+
+func (f *approxCountDistinctFn) CreateAccumulator() []byte {
+	return nil
+}
+
+func (f *approxCountDistinctFn) AddInput(a []byte, val beam.T) ([]byte, error) {
+	f.buf.Reset()
+	if err := f.enc.Encode(exec.FullValue{Elm: val}, &f.buf); err != nil {
+		return nil, err
+	}
+	if a == nil {
+		a = make([]byte, 1<<precision)
+	}
+	h := hash64(f.buf.Bytes())
+	index := h >> (64 - precision)
+	rank := byte(bits.LeadingZeros64(h<<precision|1<<(precision-1)) + 1)
+	if rank > a[index] {
+		a[index] = rank
+	}
+	return a, nil
+}
+
+func (f *approxCountDistinctFn) MergeAccumulators(list [][]byte) []byte {
+	var ret []byte
+	for _, a := range list {
+		if a == nil {
+			continue
+		}
+		if ret == nil {
+			ret = make([]byte, 1<<precision)
+		}
+		for i, r := range a {
+			if r > ret[i] {
+				ret[i] = r
+			}
+		}
+	}
+	return ret
+}
+
+func (f *approxCountDistinctFn) ExtractOutput(a []byte) int64 {
+	return estimate(a)
+}
+
+// estimate returns the HyperLogLog cardinality estimate of the registers.
+func estimate(registers []byte) int64 {
+	if registers == nil {
+		return 0
+	}
+	m := float64(len(registers))
+	var sum float64
+	var zeros int
+	for _, r := range registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// Small range correction: linear counting.
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(e + 0.5)
+}
+
+// hash64 returns a 64-bit hash of the data. The FNV hash is followed by the
+// MurmurHash3 finalizer to spread the bits, which HyperLogLog relies on.
+func hash64(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,89 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(withinTolerance)
+}
+
+// withinTolerance maps an estimate to whether it is within 5% of the exact
+// count in the key.
+func withinTolerance(exact int, estimate int64) bool {
+	return math.Abs(float64(estimate)-float64(exact)) <= 0.05*float64(exact)
+}
+
+// TestApproximateCountDistinct verifies that the estimates are exact for
+// small collections and close for large ones.
+func TestApproximateCountDistinct(t *testing.T) {
+	var small []interface{}
+	for _, s := range []string{"a", "b", "a", "c", "b", "a"} {
+		small = append(small, s)
+	}
+	p, s, in := ptest.Create(small)
+	passert.Equals(s, ApproximateCountDistinct(s, in), int64(3))
+
+	var large []interface{}
+	for i := 0; i < 30000; i++ {
+		large = append(large, fmt.Sprintf("elm%v", i%10000))
+	}
+	col := beam.Create(s, large...)
+	keyed := beam.ParDo(s, func(v string) (int, string) { return 10000, v }, col)
+	passert.Equals(s, beam.ParDo(s, withinTolerance, ApproximateCountDistinctPerKey(s, keyed)), true)
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("ApproximateCountDistinct failed: %v", err)
+	}
+}
+
+// TestApproximateCountDistinctMerge verifies that merged sketches estimate
+// the union of their inputs.
+func TestApproximateCountDistinctMerge(t *testing.T) {
+	fn := &approxCountDistinctFn{Coder: beam.EncodedCoder{Coder: beam.NewCoder(beam.ValidateNonCompositeType(beam.Create(beam.NewPipeline().Root(), 1)))}}
+	fn.Setup()
+
+	a, b, union := fn.CreateAccumulator(), fn.CreateAccumulator(), fn.CreateAccumulator()
+	var err error
+	for i := 0; i < 1000; i++ {
+		if a, err = fn.AddInput(a, i); err != nil {
+			t.Fatal(err)
+		}
+		if b, err = fn.AddInput(b, i+500); err != nil {
+			t.Fatal(err)
+		}
+		union, _ = fn.AddInput(union, i)
+		union, _ = fn.AddInput(union, i+500)
+	}
+	merged := fn.MergeAccumulators([][]byte{a, nil, b})
+	if got, want := fn.ExtractOutput(merged), fn.ExtractOutput(union); got != want {
+		t.Errorf("merged estimate = %v, want %v", got, want)
+	}
+	if got := fn.ExtractOutput(merged); !withinTolerance(1500, got) {
+		t.Errorf("merged estimate = %v, want 1500 +/- 5%%", got)
+	}
+	if got := fn.ExtractOutput(fn.MergeAccumulators(nil)); got != 0 {
+		t.Errorf("empty estimate = %v, want 0", got)
+	}
+}
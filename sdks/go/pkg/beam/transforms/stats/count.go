@@ -21,6 +21,8 @@ import (
 
 func init() {
 	beam.RegisterFunction(mapFn)
+	beam.RegisterFunction(oneFn)
+	beam.RegisterFunction(keyedOneFn)
 }
 
 // Count counts the number of elements in a collection. It expects a
@@ -36,3 +38,30 @@ func Count(s beam.Scope, col beam.PCollection) beam.PCollection {
 func mapFn(elm beam.T) (beam.T, int) {
 	return elm, 1
 }
+
+// CountElms counts the number of elements in a collection. It expects a
+// PCollection<T> as input and returns a PCollection<int> with the count as
+// the only element.
+func CountElms(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("stats.CountElms")
+
+	pre := beam.ParDo(s, oneFn, col)
+	return Sum(s, pre)
+}
+
+func oneFn(_ beam.T) int {
+	return 1
+}
+
+// CountPerKey counts the number of values for each key in a collection. It
+// expects a PCollection<KV<K,V>> as input and returns a PCollection<KV<K,int>>.
+func CountPerKey(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("stats.CountPerKey")
+
+	pre := beam.ParDo(s, keyedOneFn, col)
+	return SumPerKey(s, pre)
+}
+
+func keyedOneFn(key beam.X, _ beam.Y) (beam.X, int) {
+	return key, 1
+}
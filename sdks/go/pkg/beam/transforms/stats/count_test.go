@@ -67,3 +67,25 @@ func TestCountInt(t *testing.T) {
 		}
 	}
 }
+
+// TestCountElms verifies that CountElms counts all elements.
+func TestCountElms(t *testing.T) {
+	p, s, in := ptest.Create([]interface{}{1, -4, 1, -1})
+	passert.Equals(s, CountElms(s, in), 4)
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("CountElms failed: %v", err)
+	}
+}
+
+// TestCountPerKey verifies that CountPerKey counts the values of each key.
+func TestCountPerKey(t *testing.T) {
+	p, s, in := ptest.Create([]interface{}{1, -4, 1, -1, 3})
+	keyed := beam.ParDo(s, func(n int) (int, string) { return n, "x" }, in)
+	formatted := beam.ParDo(s, kvToCount, CountPerKey(s, keyed))
+	passert.Equals(s, formatted, count{1, 2}, count{-4, 1}, count{-1, 1}, count{3, 1})
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("CountPerKey failed: %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*quantilesFn)(nil)).Elem())
+}
+
+// quantilesCapacity is the number of elements per level of the quantiles
+// sketch. The rank error is roughly proportional to the number of levels,
+// log2(n/capacity), divided by the capacity.
+const quantilesCapacity = 1024
+
+// ApproximateQuantiles returns the approximate quantiles of the elements of
+// a PCollection<T> under the order defined by the comparator, less : T x T ->
+// bool. It returns a singleton PCollection<[]T> with numQuantiles elements:
+// the minimum, the numQuantiles-2 intermediate quantiles and the maximum.
+// The minimum and maximum are exact. The quantiles are computed with a
+// mergeable sketch of constant size, so billions of elements can be
+// summarized cheaply.
+//
+// For example:
+//
+//    latencies := ...   // PCollection<float64>
+//    percentiles := stats.ApproximateQuantiles(s, latencies, 101, less)   // PCollection<[]float64>
+//
+// where less is func(a, b float64) bool { return a < b }.
+func ApproximateQuantiles(s beam.Scope, col beam.PCollection, numQuantiles int, less interface{}) beam.PCollection {
+	s = s.Scope(fmt.Sprintf("stats.ApproximateQuantiles(%v)", numQuantiles))
+
+	t := beam.ValidateNonCompositeType(col)
+	validateQuantiles(t, numQuantiles, less)
+
+	return beam.Combine(s, newQuantilesFn(numQuantiles, less), col)
+}
+
+// ApproximateQuantilesPerKey returns the approximate quantiles of the values
+// for each key of a PCollection<KV<K,T>>. It returns a PCollection<KV<K,[]T>>.
+func ApproximateQuantilesPerKey(s beam.Scope, col beam.PCollection, numQuantiles int, less interface{}) beam.PCollection {
+	s = s.Scope(fmt.Sprintf("stats.ApproximateQuantilesPerKey(%v)", numQuantiles))
+
+	_, t := beam.ValidateKVType(col)
+	validateQuantiles(t, numQuantiles, less)
+
+	return beam.CombinePerKey(s, newQuantilesFn(numQuantiles, less), col)
+}
+
+func validateQuantiles(t typex.FullType, numQuantiles int, less interface{}) {
+	if numQuantiles < 2 {
+		panic(fmt.Sprintf("numQuantiles must be >= 2: %v", numQuantiles))
+	}
+	funcx.MustSatisfy(less, funcx.Replace(funcx.MakePredicate(beam.TType, beam.TType), beam.TType, t.Type()))
+}
+
+func newQuantilesFn(numQuantiles int, less interface{}) *quantilesFn {
+	return &quantilesFn{
+		Less:         beam.EncodedFunc{Fn: reflectx.MakeFunc(less)},
+		NumQuantiles: numQuantiles,
+		Capacity:     quantilesCapacity,
+	}
+}
+
+// quantilesAccum is a sketch of a multiset. Level h holds elements of weight
+// 2^h. The exact minimum and maximum are kept separately.
+type quantilesAccum struct {
+	levels   [][]interface{}
+	min, max interface{}
+}
+
+// quantilesFn is a CombineFn that computes approximate quantiles. When a
+// level exceeds the capacity, it is sorted and every other element, from a
+// random offset, is promoted to the next level with twice the weight.
+type quantilesFn struct {
+	// Less is the < order on the underlying type, A.
+	Less beam.EncodedFunc `json:"less"`
+	// NumQuantiles is the number of quantiles to output.
+	NumQuantiles int `json:"num_quantiles"`
+	// Capacity is the number of elements per level.
+	Capacity int `json:"capacity"`
+
+	less reflectx.Func2x1
+}
+
+func (f *quantilesFn) Setup() {
+	f.less = reflectx.ToFunc2x1(f.Less.Fn)
+}
+
+func (f *quantilesFn) CreateAccumulator() quantilesAccum {
+	return quantilesAccum{}
+}
+
+func (f *quantilesFn) AddInput(a quantilesAccum, val beam.T) quantilesAccum {
+	elm := exec.Convert(val, f.Less.Fn.Type().In(0)) // unwrap T
+	if a.min == nil || f.lt(elm, a.min) {
+		a.min = elm
+	}
+	if a.max == nil || f.lt(a.max, elm) {
+		a.max = elm
+	}
+	if len(a.levels) == 0 {
+		a.levels = [][]interface{}{nil}
+	}
+	a.levels[0] = append(a.levels[0], elm)
+	return f.compact(a)
+}
+
+func (f *quantilesFn) MergeAccumulators(list []quantilesAccum) quantilesAccum {
+	var ret quantilesAccum
+	for _, a := range list {
+		if a.min == nil {
+			continue // skip: empty
+		}
+		if ret.min == nil || f.lt(a.min, ret.min) {
+			ret.min = a.min
+		}
+		if ret.max == nil || f.lt(ret.max, a.max) {
+			ret.max = a.max
+		}
+		for h, level := range a.levels {
+			if h == len(ret.levels) {
+				ret.levels = append(ret.levels, nil)
+			}
+			ret.levels[h] = append(ret.levels[h], level...)
+		}
+	}
+	return f.compact(ret)
+}
+
+func (f *quantilesFn) ExtractOutput(a quantilesAccum) []beam.T {
+	if a.min == nil {
+		return nil
+	}
+
+	type weighted struct {
+		elm    interface{}
+		weight int64
+	}
+	var items []weighted
+	var total int64
+	for h, level := range a.levels {
+		for _, elm := range level {
+			items = append(items, weighted{elm, 1 << uint(h)})
+			total += 1 << uint(h)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return f.lt(items[i].elm, items[j].elm)
+	})
+
+	ret := make([]beam.T, f.NumQuantiles)
+	ret[0], ret[f.NumQuantiles-1] = a.min, a.max
+	var i int
+	cum := items[0].weight
+	for q := 1; q < f.NumQuantiles-1; q++ {
+		// The q'th quantile is the element of 0-based rank q*(total-1)/(n-1).
+		rank := int64(q) * (total - 1) / int64(f.NumQuantiles-1)
+		for cum <= rank && i < len(items)-1 {
+			i++
+			cum += items[i].weight
+		}
+		ret[q] = items[i].elm
+	}
+	return ret
+}
+
+// compact compacts all levels that exceed the capacity, from the bottom.
+func (f *quantilesFn) compact(a quantilesAccum) quantilesAccum {
+	for h := 0; h < len(a.levels); h++ {
+		level := a.levels[h]
+		if len(level) <= f.Capacity {
+			continue
+		}
+		sort.SliceStable(level, func(i, j int) bool {
+			return f.lt(level[i], level[j])
+		})
+		if h+1 == len(a.levels) {
+			a.levels = append(a.levels, nil)
+		}
+		// An odd element out stays at this level.
+		var rest []interface{}
+		if len(level)%2 == 1 {
+			rest = []interface{}{level[len(level)-1]}
+			level = level[:len(level)-1]
+		}
+		for i := rand.Intn(2); i < len(level); i += 2 {
+			a.levels[h+1] = append(a.levels[h+1], level[i])
+		}
+		a.levels[h] = rest
+	}
+	return a
+}
+
+func (f *quantilesFn) lt(a, b interface{}) bool {
+	if f.less == nil {
+		f.Setup()
+	}
+	return f.less.Call2x1(a, b).(bool)
+}
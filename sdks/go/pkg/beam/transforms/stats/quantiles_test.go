@@ -0,0 +1,96 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(lessInt)
+}
+
+func lessInt(a, b int) bool {
+	return a < b
+}
+
+// TestApproximateQuantiles verifies that the quantiles of small collections
+// are exact.
+func TestApproximateQuantiles(t *testing.T) {
+	var in []interface{}
+	for i := 101; i > 0; i-- {
+		in = append(in, i)
+	}
+	p, s, col := ptest.Create(in)
+	passert.Equals(s, ApproximateQuantiles(s, col, 5, lessInt), []int{1, 26, 51, 76, 101})
+
+	keyed := beam.ParDo(s, func(n int) (string, int) {
+		if n%2 == 0 {
+			return "even", n
+		}
+		return "odd", n
+	}, col)
+	quantiles := ApproximateQuantilesPerKey(s, keyed, 3, lessInt)
+	passert.Equals(s, beam.DropKey(s, quantiles), []int{2, 50, 100}, []int{1, 51, 101})
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("ApproximateQuantiles failed: %v", err)
+	}
+}
+
+// TestQuantilesSketch verifies the rank error of large, merged sketches.
+func TestQuantilesSketch(t *testing.T) {
+	fn := &quantilesFn{Less: beam.EncodedFunc{Fn: reflectx.MakeFunc(lessInt)}, NumQuantiles: 11, Capacity: 128}
+
+	const n = 200000
+	perm := rand.Perm(n)
+	var parts []quantilesAccum
+	for i := 0; i < 4; i++ {
+		a := fn.CreateAccumulator()
+		for _, v := range perm[i*n/4 : (i+1)*n/4] {
+			a = fn.AddInput(a, v)
+		}
+		parts = append(parts, a)
+	}
+	got := fn.ExtractOutput(fn.MergeAccumulators(append(parts, fn.CreateAccumulator())))
+	if len(got) != 11 || got[0] != 0 || got[10] != n-1 {
+		t.Fatalf("quantiles = %v, want 11 quantiles from 0 to %v", got, n-1)
+	}
+	if !sort.SliceIsSorted(got, func(i, j int) bool { return got[i].(int) < got[j].(int) }) {
+		t.Errorf("quantiles = %v, want sorted", got)
+	}
+	for q := 1; q < 10; q++ {
+		want := q * (n - 1) / 10
+		if diff := got[q].(int) - want; diff < -n/50 || diff > n/50 {
+			t.Errorf("quantile %v = %v, want %v +/- 2%%", q, got[q], want)
+		}
+	}
+
+	if got := fn.ExtractOutput(fn.MergeAccumulators(nil)); got != nil {
+		t.Errorf("quantiles of empty sketch = %v, want nil", got)
+	}
+	if got := fn.ExtractOutput(fn.AddInput(fn.CreateAccumulator(), 7)); !reflect.DeepEqual(got, []beam.T{7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7}) {
+		t.Errorf("quantiles of singleton sketch = %v, want 7s", got)
+	}
+}
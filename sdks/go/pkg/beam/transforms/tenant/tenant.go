@@ -0,0 +1,288 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tenant contains transformations for multi-tenant pipelines, where
+// the data of many tenants is processed in the same way but must be written
+// to separate destinations.
+package tenant
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+func init() {
+	beam.RegisterFunction(makePartitionFn)
+	beam.RegisterType(reflect.TypeOf((*writeFn)(nil)).Elem())
+}
+
+var unknown = beam.NewCounter("tenant", "unknown")
+
+// Strategy defines how tenants are laid out in the pipeline.
+type Strategy int
+
+const (
+	// Branches builds an isolated branch per tenant, each in its own scope
+	// with its own sink. The elements are partitioned by tenant in a single
+	// step. A slow or failing tenant sink does not share bundles with other
+	// tenants, but the pipeline grows with the number of tenants.
+	Branches Strategy = iota
+	// Keyed processes all tenants in a single branch keyed by tenant ID
+	// and routes each element to the destination of its tenant at runtime.
+	// The pipeline has the same shape regardless of the number of tenants.
+	Keyed
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case Branches:
+		return "branches"
+	case Keyed:
+		return "keyed"
+	default:
+		return fmt.Sprintf("Strategy(%d)", int(s))
+	}
+}
+
+// ParseStrategy returns the strategy with the given name, "branches" or
+// "keyed". It is intended for selecting the strategy with a flag:
+//
+//    var strategy = flag.String("tenant_strategy", "branches", "Tenant layout: branches or keyed.")
+//
+//    st, err := tenant.ParseStrategy(*strategy)
+//
+func ParseStrategy(name string) (Strategy, error) {
+	switch strings.ToLower(name) {
+	case "branches":
+		return Branches, nil
+	case "keyed":
+		return Keyed, nil
+	default:
+		return 0, fmt.Errorf("unknown tenant strategy %q: must be branches or keyed", name)
+	}
+}
+
+// Config is the configuration of a single tenant.
+type Config struct {
+	// ID is the tenant identifier. It must be unique and non-empty and is
+	// used as the key of the tenant's elements and in scope names.
+	ID string
+	// Destination is the sink destination of the tenant, such as a filename.
+	Destination string
+}
+
+// ForEach builds an isolated branch per tenant from a PCollection<KV<string,T>>
+// keyed by tenant ID. Each branch is built by fn in the scope "tenant.<ID>"
+// and is given a PCollection<T> with the elements of that tenant only.
+// Elements of unknown tenants are dropped and counted in the "tenant.unknown"
+// counter. For example:
+//
+//    tenant.ForEach(s, tenants, events, func(s beam.Scope, cfg tenant.Config, col beam.PCollection) {
+//        lines := beam.ParDo(s, formatFn, col)
+//        textio.Write(s, cfg.Destination, lines)
+//    })
+//
+func ForEach(s beam.Scope, tenants []Config, col beam.PCollection, fn func(beam.Scope, Config, beam.PCollection)) {
+	ids := validate(tenants)
+	s = s.Scope("tenant.ForEach")
+
+	parts := partition(s, ids, col)
+	for _, cfg := range tenants {
+		branch := s.Scope("tenant." + cfg.ID)
+		fn(branch, cfg, parts[sort.SearchStrings(ids, cfg.ID)])
+	}
+}
+
+// WriteText writes a PCollection<KV<string,string>> keyed by tenant ID as
+// lines to the Destination file of each tenant using the given strategy.
+// Elements of unknown tenants are dropped and counted in the "tenant.unknown"
+// counter. A file is written for each tenant in the Branches strategy, but
+// only for tenants with data in the Keyed strategy.
+func WriteText(s beam.Scope, strategy Strategy, tenants []Config, col beam.PCollection) {
+	validate(tenants)
+	for _, cfg := range tenants {
+		if strings.TrimSpace(cfg.Destination) == "" {
+			panic(fmt.Sprintf("tenant %v has no destination", cfg.ID))
+		}
+	}
+
+	switch strategy {
+	case Branches:
+		ForEach(s, tenants, col, func(s beam.Scope, cfg Config, col beam.PCollection) {
+			textio.Write(s, cfg.Destination, col)
+		})
+	case Keyed:
+		s = s.Scope("tenant.WriteText")
+
+		dests := make(map[string]string)
+		for _, cfg := range tenants {
+			dests[cfg.ID] = cfg.Destination
+		}
+		beam.ParDo0(s, &writeFn{Destinations: dests}, beam.GroupByKey(s, col))
+	default:
+		panic(fmt.Sprintf("invalid tenant strategy: %v", strategy))
+	}
+}
+
+// validate checks that the tenant IDs are non-empty and unique and returns
+// them in sorted order.
+func validate(tenants []Config) []string {
+	if len(tenants) == 0 {
+		panic("no tenants provided")
+	}
+	seen := make(map[string]bool)
+	var ids []string
+	for _, cfg := range tenants {
+		if cfg.ID == "" {
+			panic("empty tenant ID provided")
+		}
+		if seen[cfg.ID] {
+			panic(fmt.Sprintf("duplicate tenant ID: %v", cfg.ID))
+		}
+		seen[cfg.ID] = true
+		ids = append(ids, cfg.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// partition splits a PCollection<KV<string,T>> into a PCollection<T> per
+// tenant in the sorted list of IDs, in the same order.
+func partition(s beam.Scope, ids []string, col beam.PCollection) []beam.PCollection {
+	t := col.Type().Components()[1].Type()
+
+	// The partitionFn has an emitter per tenant for the type of the values,
+	// so it uses a dynamic function like beam.Partition.
+
+	emit := reflect.FuncOf([]reflect.Type{beam.EventTimeType, t}, nil, false)
+	in := []reflect.Type{reflectx.Context, beam.EventTimeType, reflectx.String, t}
+	for range ids {
+		in = append(in, emit)
+	}
+	fnT := reflect.FuncOf(in, nil, false)
+
+	data, err := json.Marshal(partitionData{IDs: ids})
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode tenant IDs: %v", err))
+	}
+	return beam.ParDoN(s, &graph.DynFn{Name: "tenant.partitionFn", Data: data, T: fnT, Gen: makePartitionFn}, col)
+}
+
+// partitionData contains the data needed for the partition DoFn generator.
+type partitionData struct {
+	IDs []string `json:"ids"`
+}
+
+// partitionFn is a Func with the following underlying type:
+//
+//     fn : (context.Context, EventTime, string, T, emit_1, ..., emit_N) -> ()
+//
+// where emit_i : (EventTime, T) -> () and N is the number of tenants. It
+// emits each value to the emitter of its tenant. Values of unknown tenants
+// are dropped and counted.
+type partitionFn struct {
+	name string
+	t    reflect.Type
+	ids  []string
+}
+
+func (f *partitionFn) Name() string {
+	return f.name
+}
+
+func (f *partitionFn) Type() reflect.Type {
+	return f.t
+}
+
+func (f *partitionFn) Call(args []interface{}) []interface{} {
+	ctx := args[0].(context.Context)
+	id := args[2].(string)
+
+	i := sort.SearchStrings(f.ids, id)
+	if i == len(f.ids) || f.ids[i] != id {
+		unknown.Inc(ctx, 1)
+		return nil
+	}
+	reflectx.MakeFunc2x0(args[i+4]).Call2x0(args[1], args[3])
+	return nil
+}
+
+func makePartitionFn(name string, t reflect.Type, enc []byte) reflectx.Func {
+	var data partitionData
+	if err := json.Unmarshal(enc, &data); err != nil {
+		panic(fmt.Sprintf("failed to unmarshal partitionFn data: %v", err))
+	}
+	return &partitionFn{name: name, t: t, ids: data.IDs}
+}
+
+// writeFn writes the lines of a tenant to its destination, which is looked
+// up at runtime.
+type writeFn struct {
+	Destinations map[string]string `json:"destinations"`
+}
+
+func (w *writeFn) ProcessElement(ctx context.Context, id string, lines func(*string) bool) error {
+	filename, ok := w.Destinations[id]
+	if !ok {
+		var line string
+		for lines(&line) {
+			unknown.Inc(ctx, 1)
+		}
+		return nil
+	}
+
+	fs, err := textio.NewFileSystem(ctx, filename)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	fd, err := fs.OpenWrite(ctx, filename)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriterSize(fd, 1<<20) // use 1MB buffer
+
+	log.Infof(ctx, "Writing tenant %v to %v", id, filename)
+
+	var line string
+	for lines(&line) {
+		if _, err := buf.WriteString(line); err != nil {
+			fd.Close()
+			return err
+		}
+		if err := buf.WriteByte('\n'); err != nil {
+			fd.Close()
+			return err
+		}
+	}
+
+	if err := buf.Flush(); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
@@ -0,0 +1,185 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenant
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	_ "github.com/apache/beam/sdks/go/pkg/beam/io/textio/local"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(splitFn)
+	textio.RegisterFileSystem("failwrite", func(ctx context.Context) textio.FileSystem {
+		return failingFS{}
+	})
+}
+
+// splitFn splits "tenant:value" into a KV.
+func splitFn(s string) (string, string) {
+	parts := strings.SplitN(s, ":", 2)
+	return parts[0], parts[1]
+}
+
+var input = []string{"a:1", "b:2", "a:3", "c:4", "x:5"}
+
+func TestParseStrategy(t *testing.T) {
+	for _, st := range []Strategy{Branches, Keyed} {
+		if got, err := ParseStrategy(strings.ToUpper(st.String())); err != nil || got != st {
+			t.Errorf("ParseStrategy(%v) = %v, %v, want %v", st, got, err, st)
+		}
+	}
+	if _, err := ParseStrategy("fanout"); err == nil {
+		t.Errorf("ParseStrategy(fanout) succeeded, want error")
+	}
+}
+
+func TestForEach(t *testing.T) {
+	p, s, lines := ptest.CreateList(input)
+	col := beam.ParDo(s, splitFn, lines)
+
+	tenants := []Config{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	want := map[string][]interface{}{
+		"a": {"1", "3"},
+		"b": {"2"},
+		"c": {"4"},
+	}
+	ForEach(s, tenants, col, func(s beam.Scope, cfg Config, col beam.PCollection) {
+		passert.Equals(s, col, want[cfg.ID]...)
+	})
+
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	for _, st := range []Strategy{Branches, Keyed} {
+		dir, err := ioutil.TempDir("", "tenant")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		var tenants []Config
+		for _, id := range []string{"a", "b", "c"} {
+			tenants = append(tenants, Config{ID: id, Destination: filepath.Join(dir, id+".txt")})
+		}
+
+		p, s, lines := ptest.CreateList(input)
+		WriteText(s, st, tenants, beam.ParDo(s, splitFn, lines))
+		if err := ptest.Run(p); err != nil {
+			t.Fatalf("%v: pipeline failed: %v", st, err)
+		}
+
+		want := map[string][]string{
+			"a": {"1", "3"},
+			"b": {"2"},
+			"c": {"4"},
+		}
+		for _, cfg := range tenants {
+			data, err := ioutil.ReadFile(cfg.Destination)
+			if err != nil {
+				t.Fatalf("%v: %v", st, err)
+			}
+			got := strings.Fields(string(data))
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want[cfg.ID]) {
+				t.Errorf("%v: tenant %v wrote %v, want %v", st, cfg.ID, got, want[cfg.ID])
+			}
+		}
+	}
+}
+
+// failingFS is a file system whose files fail all writes. It counts the
+// files that are open.
+type failingFS struct {
+	textio.FileSystem
+}
+
+var (
+	openFiles   int
+	openFilesMu sync.Mutex
+)
+
+func (failingFS) OpenWrite(ctx context.Context, filename string) (io.WriteCloser, error) {
+	openFilesMu.Lock()
+	defer openFilesMu.Unlock()
+	openFiles++
+	return failingFile{}, nil
+}
+
+func (failingFS) Close() error {
+	return nil
+}
+
+type failingFile struct{}
+
+func (failingFile) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func (failingFile) Close() error {
+	openFilesMu.Lock()
+	defer openFilesMu.Unlock()
+	openFiles--
+	return nil
+}
+
+func TestWriteTextClosesOnError(t *testing.T) {
+	tenants := []Config{{ID: "a", Destination: "failwrite://a.txt"}}
+
+	p, s, lines := ptest.CreateList([]string{"a:1", "a:2"})
+	WriteText(s, Keyed, tenants, beam.ParDo(s, splitFn, lines))
+	if err := ptest.Run(p); err == nil {
+		t.Fatalf("pipeline succeeded, want write error")
+	}
+	if openFiles != 0 {
+		t.Errorf("%v files left open after failed write, want 0", openFiles)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := [][]Config{
+		nil,
+		{{ID: ""}},
+		{{ID: "a"}, {ID: "a"}},
+	}
+	for _, tenants := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("validate(%v) succeeded, want panic", tenants)
+				}
+			}()
+			validate(tenants)
+		}()
+	}
+}
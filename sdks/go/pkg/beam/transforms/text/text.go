@@ -0,0 +1,206 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package text contains transformations for processing text, such as
+// regexp extraction and replacement, case conversion and word counting.
+package text
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/transforms/stats"
+	"github.com/apache/beam/sdks/go/pkg/beam/transforms/top"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*findFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*splitFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*replaceFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*WordCount)(nil)).Elem())
+	beam.RegisterFunction(strings.ToLower)
+	beam.RegisterFunction(strings.ToUpper)
+	beam.RegisterFunction(strings.TrimSpace)
+	beam.RegisterFunction(toWordCountFn)
+	beam.RegisterFunction(lessWordCountFn)
+}
+
+// WordRegexp is the regular expression used by Words to extract words. It
+// matches runs of ASCII letters with an optional trailing contraction, such
+// as "don't".
+const WordRegexp = `[a-zA-Z]+('[a-z])?`
+
+// Find returns a PCollection<string> with all matches of the regular
+// expression in the elements of a PCollection<string>. For example:
+//
+//    lines := beam.Create(s, "order 12 of 3")
+//    numbers := text.Find(s, `[0-9]+`, lines)
+//
+// Here, "numbers" will contain "12" and "3" at runtime.
+func Find(s beam.Scope, expr string, col beam.PCollection) beam.PCollection {
+	s = s.Scope("text.Find")
+
+	mustCompile(expr)
+	return beam.ParDo(s, &findFn{Expr: expr}, col)
+}
+
+// Split returns a PCollection<string> with the elements of a PCollection<string>
+// split around matches of the regular expression. Empty substrings are
+// dropped. For example:
+//
+//    lines := beam.Create(s, "a, b,,c")
+//    fields := text.Split(s, `,\s*`, lines)
+//
+// Here, "fields" will contain "a", "b" and "c" at runtime.
+func Split(s beam.Scope, expr string, col beam.PCollection) beam.PCollection {
+	s = s.Scope("text.Split")
+
+	mustCompile(expr)
+	return beam.ParDo(s, &splitFn{Expr: expr}, col)
+}
+
+// Replace returns a PCollection<string> with all matches of the regular
+// expression in the elements of a PCollection<string> replaced by repl,
+// which may refer to submatches as $1 and ${name}, as in regexp.ReplaceAllString.
+func Replace(s beam.Scope, expr, repl string, col beam.PCollection) beam.PCollection {
+	s = s.Scope("text.Replace")
+
+	mustCompile(expr)
+	return beam.ParDo(s, &replaceFn{Expr: expr, Repl: repl}, col)
+}
+
+// ToLower returns a PCollection<string> with the elements of a
+// PCollection<string> converted to lower case.
+func ToLower(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("text.ToLower")
+	return beam.ParDo(s, strings.ToLower, col)
+}
+
+// ToUpper returns a PCollection<string> with the elements of a
+// PCollection<string> converted to upper case.
+func ToUpper(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("text.ToUpper")
+	return beam.ParDo(s, strings.ToUpper, col)
+}
+
+// TrimSpace returns a PCollection<string> with leading and trailing white
+// space removed from the elements of a PCollection<string>.
+func TrimSpace(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("text.TrimSpace")
+	return beam.ParDo(s, strings.TrimSpace, col)
+}
+
+// Words returns a PCollection<string> with the words of the lines in a
+// PCollection<string>, as matched by WordRegexp.
+func Words(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("text.Words")
+	return beam.ParDo(s, &findFn{Expr: WordRegexp}, col)
+}
+
+// WordCount is a word and its number of occurrences.
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+func (w WordCount) String() string {
+	return fmt.Sprintf("%v: %v", w.Word, w.Count)
+}
+
+// TopWords returns the n most frequent words in a PCollection<string> of
+// lines. It returns a single-element PCollection<[]WordCount> ordered by
+// decreasing count. Words with the same count are ordered alphabetically.
+// For example:
+//
+//    lines := textio.Read(s, "gs://apache-beam-samples/shakespeare/kinglear.txt")
+//    top := text.TopWords(s, 10, text.ToLower(s, lines))
+//
+func TopWords(s beam.Scope, n int, col beam.PCollection) beam.PCollection {
+	s = s.Scope(fmt.Sprintf("text.TopWords(%v)", n))
+
+	counted := stats.Count(s, Words(s, col))
+	return top.Largest(s, beam.ParDo(s, toWordCountFn, counted), n, lessWordCountFn)
+}
+
+func toWordCountFn(word string, count int) WordCount {
+	return WordCount{Word: word, Count: count}
+}
+
+// lessWordCountFn orders by count and then reverse alphabetically, so that
+// the largest elements are the most frequent words in alphabetical order.
+func lessWordCountFn(a, b WordCount) bool {
+	if a.Count != b.Count {
+		return a.Count < b.Count
+	}
+	return a.Word > b.Word
+}
+
+func mustCompile(expr string) {
+	if _, err := regexp.Compile(expr); err != nil {
+		panic(fmt.Sprintf("invalid regexp %q: %v", expr, err))
+	}
+}
+
+type findFn struct {
+	Expr string `json:"expr"`
+
+	re *regexp.Regexp
+}
+
+func (f *findFn) Setup() {
+	f.re = regexp.MustCompile(f.Expr)
+}
+
+func (f *findFn) ProcessElement(line string, emit func(string)) {
+	for _, match := range f.re.FindAllString(line, -1) {
+		emit(match)
+	}
+}
+
+type splitFn struct {
+	Expr string `json:"expr"`
+
+	re *regexp.Regexp
+}
+
+func (f *splitFn) Setup() {
+	f.re = regexp.MustCompile(f.Expr)
+}
+
+func (f *splitFn) ProcessElement(line string, emit func(string)) {
+	for _, field := range f.re.Split(line, -1) {
+		if field != "" {
+			emit(field)
+		}
+	}
+}
+
+type replaceFn struct {
+	Expr string `json:"expr"`
+	Repl string `json:"repl"`
+
+	re *regexp.Regexp
+}
+
+func (f *replaceFn) Setup() {
+	f.re = regexp.MustCompile(f.Expr)
+}
+
+func (f *replaceFn) ProcessElement(line string) string {
+	return f.re.ReplaceAllString(line, f.Repl)
+}
@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func TestFind(t *testing.T) {
+	p, s, col := ptest.CreateList([]string{"order 12 of 3", "none"})
+	passert.Equals(s, Find(s, `[0-9]+`, col), "12", "3")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Find failed: %v", err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	p, s, col := ptest.CreateList([]string{"a, b,,c", ""})
+	passert.Equals(s, Split(s, `,\s*`, col), "a", "b", "c")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Split failed: %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	p, s, col := ptest.CreateList([]string{"2018-03-15", "n/a"})
+	passert.Equals(s, Replace(s, `(\d+)-(\d+)-(\d+)`, "$3/$2/$1", col), "15/03/2018", "n/a")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Replace failed: %v", err)
+	}
+}
+
+func TestCase(t *testing.T) {
+	p, s, col := ptest.CreateList([]string{" Foo ", "BaR"})
+	passert.Equals(s, ToLower(s, col), " foo ", "bar")
+	passert.Equals(s, ToUpper(s, TrimSpace(s, col)), "FOO", "BAR")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("case conversion failed: %v", err)
+	}
+}
+
+func TestTopWords(t *testing.T) {
+	p, s, col := ptest.CreateList([]string{
+		"the cat and the hat",
+		"the dog and the cat",
+		"don't panic",
+	})
+	top := TopWords(s, 3, col)
+	passert.Equals(s, top, []WordCount{{"the", 4}, {"and", 2}, {"cat", 2}})
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("TopWords failed: %v", err)
+	}
+}
+
+func TestInvalidRegexp(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Find with invalid regexp succeeded, want panic")
+		}
+	}()
+	p := beam.NewPipeline()
+	s := p.Root()
+	Find(s, `(`, beam.Create(s, "a"))
+}
@@ -0,0 +1,138 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timeout contains transformations that bound the processing time
+// of individual elements, so that a stuck call to an external service does
+// not hang the bundle indefinitely.
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*timeoutFn)(nil)).Elem())
+}
+
+// ParDo applies the given function to each element of a PCollection<A> with
+// a per-element deadline. The function must be of the form:
+//
+//    (context.Context, A) -> (B, error)
+//
+// and must respect the cancellation of its context. If the function does not
+// return before the deadline, its context is cancelled and the element is
+// emitted to the timeout output, a PCollection<A>, instead of the main
+// output, a PCollection<B>. Any error returned in time, including a panic,
+// fails the bundle, as does the cancellation of the bundle itself. For
+// example:
+//
+//    pages, stuck := timeout.ParDo(s, fetchFn, urls, 30*time.Second)
+//
+// A timed-out call is abandoned rather than awaited, so its result is
+// discarded even if it eventually completes.
+func ParDo(s beam.Scope, fn interface{}, col beam.PCollection, d time.Duration) (beam.PCollection, beam.PCollection) {
+	s = s.Scope("timeout.ParDo")
+
+	if d <= 0 {
+		panic(fmt.Sprintf("invalid timeout %v: must be positive", d))
+	}
+	out := validate(fn, col.Type().Type())
+	f := &timeoutFn{Fn: beam.EncodedFunc{Fn: reflectx.MakeFunc(fn)}, Timeout: d}
+	return beam.ParDo2(s, f, col, beam.TypeDefinition{Var: beam.UType, T: out})
+}
+
+// validate checks that fn is of the form (context.Context, A) -> (B, error)
+// and returns B.
+func validate(fn interface{}, in reflect.Type) reflect.Type {
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		panic(fmt.Sprintf("timeout function %v must be a function", fn))
+	}
+	if t.NumIn() != 2 || t.In(0) != reflectx.Context || t.In(1) != in ||
+		t.NumOut() != 2 || t.Out(1) != reflectx.Error {
+		panic(fmt.Sprintf("timeout function %v must be of the form (context.Context, %v) -> (B, error)", t, in))
+	}
+	return t.Out(0)
+}
+
+type timeoutFn struct {
+	// Fn is the encoded function.
+	Fn beam.EncodedFunc `json:"fn"`
+	// Timeout is the per-element deadline.
+	Timeout time.Duration `json:"timeout"`
+
+	fn reflectx.Func2x2
+}
+
+type result struct {
+	out interface{}
+	err error
+}
+
+func (f *timeoutFn) Setup() {
+	f.fn = reflectx.ToFunc2x2(f.Fn.Fn)
+}
+
+func (f *timeoutFn) ProcessElement(parent context.Context, elm beam.T, emit func(beam.U), timedOut func(beam.T)) error {
+	ctx, cancel := context.WithTimeout(parent, f.Timeout)
+	defer cancel()
+
+	done := make(chan result, 1) // buffered, so an abandoned call can complete
+	go f.call(ctx, elm, done)
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return r.err
+		}
+		emit(r.out)
+		return nil
+	case <-ctx.Done():
+		// Only the deadline of the element times it out. If the bundle is
+		// cancelled, or its own deadline expires first, the bundle fails.
+		if err := parent.Err(); err != nil {
+			return err
+		}
+		log.Warnf(ctx, "Element %v timed out after %v", elm, f.Timeout)
+		timedOut(elm)
+		return nil
+	}
+}
+
+// call invokes the function and sends its result. A panic is returned as an
+// error, because it would otherwise crash the worker from the goroutine.
+func (f *timeoutFn) call(ctx context.Context, elm beam.T, done chan<- result) {
+	var r result
+	defer func() {
+		if p := recover(); p != nil {
+			r = result{err: fmt.Errorf("panic: %v %s", p, debug.Stack())}
+		}
+		done <- r
+	}()
+
+	out, err := f.fn.Call2x2(ctx, elm)
+	r.out = out
+	if err != nil {
+		r.err = err.(error)
+	}
+}
@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeout_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/go/pkg/beam/transforms/timeout"
+)
+
+func stuckFn(ctx context.Context, a int) (string, error) {
+	if a%2 == 0 {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return fmt.Sprintf("x%v", a), nil
+}
+
+func failFn(ctx context.Context, a int) (string, error) {
+	return "", fmt.Errorf("failed on %v", a)
+}
+
+func TestParDo(t *testing.T) {
+	p, s, in := ptest.Create([]interface{}{1, 2, 3, 4})
+	out, timedOut := timeout.ParDo(s, stuckFn, in, 10*time.Millisecond)
+	passert.Equals(s, out, "x1", "x3")
+	passert.Equals(s, timedOut, 2, 4)
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("ParDo(stuckFn) failed: %v", err)
+	}
+}
+
+func TestParDoError(t *testing.T) {
+	p, s, in := ptest.Create([]interface{}{1})
+	timeout.ParDo(s, failFn, in, time.Minute)
+
+	if err := ptest.Run(p); err == nil {
+		t.Errorf("ParDo(failFn) succeeded, want error")
+	}
+}
+
+func panicFn(ctx context.Context, a int) (string, error) {
+	panic(fmt.Sprintf("bad element %v", a))
+}
+
+func TestParDoPanic(t *testing.T) {
+	p, s, in := ptest.Create([]interface{}{1})
+	timeout.ParDo(s, panicFn, in, time.Minute)
+
+	if err := ptest.Run(p); err == nil || !strings.Contains(err.Error(), "bad element 1") {
+		t.Errorf("ParDo(panicFn) failed with %v, want panic error", err)
+	}
+}
+
+func TestParDoBadFn(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ParDo with bad function succeeded, want panic")
+		}
+	}()
+
+	p := beam.NewPipeline()
+	s := p.Root()
+	in := beam.Create(s, 1)
+	timeout.ParDo(s, func(a int) string { return "" }, in, time.Second)
+}
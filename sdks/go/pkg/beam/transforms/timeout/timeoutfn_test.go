@@ -0,0 +1,64 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
+func blockFn(ctx context.Context, a int) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestTimeoutFnCancelled(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"cancelled", cancelled, context.Canceled},
+		{"expired", expired, context.DeadlineExceeded},
+	}
+
+	for _, test := range tests {
+		f := &timeoutFn{Fn: beam.EncodedFunc{Fn: reflectx.MakeFunc(blockFn)}, Timeout: time.Minute}
+		f.Setup()
+
+		var timedOut []beam.T
+		err := f.ProcessElement(test.ctx, 1, func(beam.U) {
+			t.Errorf("%v: unexpected output", test.name)
+		}, func(elm beam.T) {
+			timedOut = append(timedOut, elm)
+		})
+		if err != test.want {
+			t.Errorf("%v: ProcessElement() = %v, want %v", test.name, err, test.want)
+		}
+		if len(timedOut) != 0 {
+			t.Errorf("%v: timed out %v, want none", test.name, timedOut)
+		}
+	}
+}
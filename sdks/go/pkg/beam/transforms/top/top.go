@@ -44,7 +44,7 @@ func init() {
 // Example use:
 //
 //    col := beam.Create(s, 1, 11, 7, 5, 10)
-//    top2 := stats.Largest(s, col, 2, less)  // PCollection<[]int> with [11, 10] as the only element.
+//    top2 := top.Largest(s, col, 2, less)  // PCollection<[]int> with [11, 10] as the only element.
 //
 func Largest(s beam.Scope, col beam.PCollection, n int, less interface{}) beam.PCollection {
 	s = s.Scope(fmt.Sprintf("top.Largest(%v)", n))
@@ -75,7 +75,7 @@ func LargestPerKey(s beam.Scope, col beam.PCollection, n int, less interface{})
 // Example use:
 //
 //    col := beam.Create(s, 1, 11, 7, 5, 10)
-//    bottom2 := stats.Smallest(s, col, 2, less)  // PCollection<[]int> with [1, 5] as the only element.
+//    bottom2 := top.Smallest(s, col, 2, less)  // PCollection<[]int> with [1, 5] as the only element.
 //
 func Smallest(s beam.Scope, col beam.PCollection, n int, less interface{}) beam.PCollection {
 	s = s.Scope(fmt.Sprintf("top.Smallest(%v)", n))
@@ -96,7 +96,7 @@ func SmallestPerKey(s beam.Scope, col beam.PCollection, n int, less interface{})
 	_, t := beam.ValidateKVType(col)
 	validate(t, n, less)
 
-	return beam.Combine(s, &combineFn{Less: beam.EncodedFunc{Fn: reflectx.MakeFunc(less)}, N: n, Reversed: true}, col)
+	return beam.CombinePerKey(s, &combineFn{Less: beam.EncodedFunc{Fn: reflectx.MakeFunc(less)}, N: n, Reversed: true}, col)
 }
 
 func validate(t typex.FullType, n int, less interface{}) {
@@ -16,13 +16,47 @@
 package top
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
 	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
 )
 
+func lessInt(a, b int) bool {
+	return a < b
+}
+
+func keyByParity(n int) (string, int) {
+	if n%2 == 0 {
+		return "even", n
+	}
+	return "odd", n
+}
+
+func formatTop(key string, list []int) string {
+	return fmt.Sprintf("%v:%v", key, list)
+}
+
+// TestPerKey verifies that LargestPerKey and SmallestPerKey select the
+// elements of each key.
+func TestPerKey(t *testing.T) {
+	p, s, in := ptest.Create([]interface{}{1, 11, 7, 5, 10, 2, 8, 4})
+	keyed := beam.ParDo(s, keyByParity, in)
+
+	largest := LargestPerKey(s, keyed, 2, lessInt)
+	passert.Equals(s, beam.ParDo(s, formatTop, largest), "odd:[11 7]", "even:[10 8]")
+	smallest := SmallestPerKey(s, keyed, 2, lessInt)
+	passert.Equals(s, beam.ParDo(s, formatTop, smallest), "odd:[1 5]", "even:[2 4]")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("PerKey failed: %v", err)
+	}
+}
+
 // TestCombineFn3String verifies that the accumulator correctly
 // maintains the top 3 longest strings.
 func TestCombineFn3String(t *testing.T) {
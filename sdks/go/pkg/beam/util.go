@@ -15,12 +15,24 @@
 
 package beam
 
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/util/reflectx"
+)
+
 func init() {
 	RegisterFunction(addFixedKeyFn)
+	RegisterType(reflect.TypeOf((*withKeysFn)(nil)).Elem())
 	RegisterFunction(dropKeyFn)
 	RegisterFunction(dropValueFn)
 	RegisterFunction(swapKVFn)
 	RegisterFunction(explodeFn)
+	RegisterFunction(explodeWithIndexFn)
+	RegisterFunction(ungroupFn)
+	RegisterType(reflect.TypeOf((*batchFn)(nil)).Elem())
 }
 
 // We have some freedom to create various utilities, users can use depending on
@@ -32,16 +44,36 @@ func init() {
 // look exactly like the more primitive sources/sinks, but be picked at
 // pipeline construction time.
 
-// Seq is a convenience helper to chain single-input/single-output ParDos together
-// in a sequence.
-func Seq(s Scope, col PCollection, dofns ...interface{}) PCollection {
+// Seq is a convenience helper to chain single-input/single-output ParDos and
+// transforms together in a sequence. Each step is either a DoFn, which is
+// applied with ParDo, or a transform of the form
+//
+//    func(Scope, PCollection) PCollection
+//
+// which is applied in a scope named after the function. For example,
+//
+//    words := beam.Seq(s, lines, extractFn, strings.ToLower, stats.Count)
+//
+// is equivalent to applying extractFn and strings.ToLower with ParDo followed
+// by stats.Count in the "stats.Count" scope.
+func Seq(s Scope, col PCollection, steps ...interface{}) PCollection {
 	cur := col
-	for _, dofn := range dofns {
-		cur = ParDo(s, dofn, cur)
+	for _, step := range steps {
+		if fn, ok := step.(func(Scope, PCollection) PCollection); ok {
+			cur = fn(s.Scope(seqName(fn)), cur)
+			continue
+		}
+		cur = ParDo(s, step, cur)
 	}
 	return cur
 }
 
+// seqName returns the unqualified name of the given transform function,
+// such as "stats.Count".
+func seqName(fn interface{}) string {
+	return reflectx.ShortName(reflectx.FunctionName(fn))
+}
+
 // AddFixedKey adds a fixed key (0) to every element.
 func AddFixedKey(s Scope, col PCollection) PCollection {
 	return ParDo(s, addFixedKeyFn, col)
@@ -51,6 +83,48 @@ func addFixedKeyFn(elm T) (int, T) {
 	return 0, elm
 }
 
+// WithKeys keys every element of a PCollection<A> by the function
+// fn : A -> K. It returns a PCollection<KV<K,A>>. For example:
+//
+//    byUser := beam.WithKeys(s, func(e Event) string { return e.User }, events)
+//
+// The key type must be concrete.
+func WithKeys(s Scope, fn interface{}, col PCollection) PCollection {
+	return Must(TryWithKeys(s, fn, col))
+}
+
+// TryWithKeys attempts to insert a WithKeys transform into the pipeline. It
+// may fail for multiple reasons, notably that the function is not of the
+// form A -> K.
+func TryWithKeys(s Scope, fn interface{}, col PCollection) (PCollection, error) {
+	_, k, err := validateElementwise("WithKeys", fn, col)
+	if err != nil {
+		return PCollection{}, err
+	}
+	if typex.IsUniversal(k) {
+		return PCollection{}, fmt.Errorf("WithKeys: key type of fn must be concrete: %v", reflect.TypeOf(fn))
+	}
+
+	s = s.Scope("beam.WithKeys")
+	return tryParDo1(s, &withKeysFn{Fn: EncodedFunc{Fn: reflectx.MakeFunc(fn)}}, col, TypeDefinition{Var: UType, T: k})
+}
+
+// withKeysFn keys each element by the function.
+type withKeysFn struct {
+	// Fn is the encoded key function.
+	Fn EncodedFunc `json:"fn"`
+
+	fn reflectx.Func1x1
+}
+
+func (f *withKeysFn) Setup() {
+	f.fn = reflectx.ToFunc1x1(f.Fn.Fn)
+}
+
+func (f *withKeysFn) ProcessElement(elm T) (U, T) {
+	return f.fn.Call1x1(elm), elm
+}
+
 // DropKey drops the key for an input PCollection<KV<A,B>>. It returns
 // a PCollection<B>.
 func DropKey(s Scope, col PCollection) PCollection {
@@ -82,7 +156,9 @@ func swapKVFn(x X, y Y) (Y, X) {
 }
 
 // Explode is a PTransform that takes a single PCollection<[]A> and returns a
-// PCollection<A> containing all the elements for each incoming slice.
+// PCollection<A> containing all the elements for each incoming slice. If
+// given the grouped output of GroupByKey, PCollection<CoGBK<K,A>>, it
+// returns the ungrouped PCollection<KV<K,A>>.
 //
 // Example of use:
 //
@@ -91,6 +167,12 @@ func swapKVFn(x X, y Y) (Y, X) {
 //
 func Explode(s Scope, col PCollection) PCollection {
 	s = s.Scope("beam.Explode")
+	if typex.IsCoGBK(col.Type()) {
+		if len(col.Type().Components()) != 2 {
+			panic(fmt.Sprintf("Explode requires single-input grouped values: %v", col.Type()))
+		}
+		return ParDo(s, ungroupFn, col)
+	}
 	return ParDo(s, explodeFn, col)
 }
 
@@ -100,6 +182,67 @@ func explodeFn(list []T, emit func(T)) {
 	}
 }
 
+func ungroupFn(key X, iter func(*Y) bool, emit func(X, Y)) {
+	var elm Y
+	for iter(&elm) {
+		emit(key, elm)
+	}
+}
+
+// ExplodeWithIndex is like Explode, but takes a PCollection<[]A> and returns
+// a PCollection<KV<int,A>> with the index of each element in its slice.
+func ExplodeWithIndex(s Scope, col PCollection) PCollection {
+	s = s.Scope("beam.ExplodeWithIndex")
+	return ParDo(s, explodeWithIndexFn, col)
+}
+
+func explodeWithIndexFn(list []T, emit func(int, T)) {
+	for i, elm := range list {
+		emit(i, elm)
+	}
+}
+
+// Batch is the inverse of Explode. It takes a PCollection<A> and returns a
+// PCollection<[]A> of the same elements collected into slices of the given
+// size. Elements are collected per bundle, so the last slice of each bundle
+// may be smaller. For example:
+//
+//    batches := beam.Batch(s, ids, 100)    // PCollection<[]A>
+//    rows := beam.ParDo(s, lookupFn, batches)
+//
+func Batch(s Scope, col PCollection, size int) PCollection {
+	s = s.Scope("beam.Batch")
+	if size < 1 {
+		panic(fmt.Sprintf("invalid batch size %v: must be positive", size))
+	}
+	return ParDo(s, &batchFn{Size: size}, col)
+}
+
+type batchFn struct {
+	Size int `json:"size"`
+
+	buf []T
+}
+
+func (f *batchFn) StartBundle(_ func([]T)) {
+	f.buf = nil
+}
+
+func (f *batchFn) ProcessElement(elm T, emit func([]T)) {
+	f.buf = append(f.buf, elm)
+	if len(f.buf) >= f.Size {
+		emit(f.buf)
+		f.buf = nil
+	}
+}
+
+func (f *batchFn) FinishBundle(emit func([]T)) {
+	if len(f.buf) > 0 {
+		emit(f.buf)
+		f.buf = nil
+	}
+}
+
 // The MustX functions are convenience helpers to create error-less functions.
 
 // MustN returns the input, but panics if err != nil.
@@ -21,19 +21,30 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 // Dial is a convenience wrapper over grpc.Dial. It can be overridden
 // to provide a customized dialing behavior.
 var Dial = DefaultDial
 
+// Keepalive is the interval of the keepalive pings on connections with
+// active streams made by DefaultDial. The pings detect broken connections
+// that would otherwise go unnoticed. It defaults to the shortest interval
+// that gRPC servers permit by default. Zero disables keepalive pings.
+var Keepalive = 5 * time.Minute
+
 // DefaultDial is a dialer that specifies an insecure blocking connection with a timeout.
 func DefaultDial(ctx context.Context, endpoint string, timeout time.Duration) (*grpc.ClientConn, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	cc, err := grpc.DialContext(ctx, endpoint, grpc.WithInsecure(), grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(50<<20)))
+	opts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(50 << 20))}
+	if Keepalive > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: Keepalive, Timeout: 20 * time.Second}))
+	}
+	cc, err := grpc.DialContext(ctx, endpoint, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial server at %v: %v", endpoint, err)
 	}
@@ -0,0 +1,124 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(double)
+	beam.RegisterFunction(inc)
+	beam.RegisterFunction(eventUser)
+	beam.RegisterFunction(formatEvent)
+}
+
+func double(n int) int { return 2 * n }
+
+func inc(n int) int { return n + 1 }
+
+func incAll(s beam.Scope, col beam.PCollection) beam.PCollection {
+	return beam.ParDo(s, inc, col)
+}
+
+func TestSeq(t *testing.T) {
+	p, s, in, exp := ptest.CreateList2([]int{1, 2, 3}, []int{6, 10, 14})
+	out := beam.Seq(s, in, double, incAll, double)
+	passert.Equals(s, out, exp)
+
+	if err := ptest.Run(p); err != nil {
+		t.Error(err)
+	}
+}
+
+func sumList(list []int) int {
+	var ret int
+	for _, n := range list {
+		ret += n
+	}
+	return ret
+}
+
+func formatIndexed(i int, s string) string {
+	return fmt.Sprintf("%v:%v", i, s)
+}
+
+func formatKV2(k, v int) string {
+	return fmt.Sprintf("%v:%v", k, v)
+}
+
+func TestExplode(t *testing.T) {
+	p := beam.NewPipeline()
+	s := p.Root()
+	lists := beam.Create(s, []string{"a", "b"}, []string{"c"})
+	passert.Equals(s, beam.Explode(s, lists), "a", "b", "c")
+	passert.Equals(s, beam.ParDo(s, formatIndexed, beam.ExplodeWithIndex(s, lists)), "0:a", "1:b", "0:c")
+
+	kvs := beam.ParDo(s, func(n int) (int, int) { return n % 2, n }, beam.Create(s, 1, 2, 3))
+	ungrouped := beam.Explode(s, beam.GroupByKey(s, kvs))
+	passert.Equals(s, beam.ParDo(s, formatKV2, ungrouped), "1:1", "0:2", "1:3")
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Explode failed: %v", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	p, s, in := ptest.Create([]interface{}{1, 2, 3, 4, 5, 6, 7})
+	batches := beam.Batch(s, in, 3)
+	passert.Equals(s, beam.ParDo(s, func(list []int) int { return len(list) }, batches), 3, 3, 1)
+	passert.Equals(s, beam.ParDo(s, sumList, batches), 6, 15, 7)
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("Batch failed: %v", err)
+	}
+}
+
+type event struct {
+	User  string
+	Count int
+}
+
+func eventUser(e event) string {
+	return e.User
+}
+
+func formatEvent(k string, e event) string {
+	return fmt.Sprintf("%v:%v", k, e.Count)
+}
+
+func TestKeyUtilities(t *testing.T) {
+	p, s, events := ptest.Create([]interface{}{event{"a", 1}, event{"b", 2}, event{"a", 3}})
+
+	keyed := beam.WithKeys(s, eventUser, events)
+	passert.Equals(s, beam.ParDo(s, formatEvent, keyed), "a:1", "b:2", "a:3")
+	passert.Equals(s, beam.DropValue(s, keyed), "a", "b", "a")
+	passert.Equals(s, beam.DropKey(s, beam.SwapKV(s, keyed)), "a", "b", "a")
+	passert.Equals(s, beam.DropValue(s, beam.AddFixedKey(s, beam.DropValue(s, keyed))), 0, 0, 0)
+
+	if _, err := beam.TryWithKeys(s, func(e event) beam.X { return e }, events); err == nil {
+		t.Error("WithKeys with universal key type succeeded, want error")
+	}
+
+	if err := ptest.Run(p); err != nil {
+		t.Errorf("pipeline failed: %v", err)
+	}
+}
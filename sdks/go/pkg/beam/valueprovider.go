@@ -0,0 +1,184 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+)
+
+// ValueProvider is a value of type T that is either known at pipeline
+// construction time or deferred until run time, such as a Dataflow template
+// parameter. DoFns and IOs hold a ValueProvider in place of a plain value and
+// resolve it during execution with Get. For example:
+//
+//     type filterFn struct {
+//         Pattern beam.ValueProvider[string] `json:"pattern"`
+//         Limit   beam.ValueProvider[int64]  `json:"limit"`
+//     }
+//
+//     func (f *filterFn) ProcessElement(line string, emit func(string)) error {
+//         pattern, err := f.Pattern.Get()
+//         ...
+//     }
+//
+//     fn := &filterFn{
+//         Pattern: beam.RuntimeValue(s, "pattern", ".*"),
+//         Limit:   beam.StaticValue(int64(100)),
+//     }
+//     beam.ParDo(s, fn, lines)
+//
+// Runtime values are read from the pipeline options of the running job,
+// keyed by the parameter name. Strings are used as is and other types are
+// decoded as JSON, so that an int64 parameter is given as --limit=100.
+// Template values are interpolated when read. ValueProvider is serialized
+// with the DoFn, so it must be held in an exported field and T must be
+// JSON-serializable.
+type ValueProvider[T any] struct {
+	// Value is the static value.
+	Value T `json:"value,omitempty"`
+	// Param is the name of the runtime parameter, if deferred.
+	Param string `json:"param,omitempty"`
+	// Default is the value of the runtime parameter, if not set. The zero
+	// value means that the parameter has no default.
+	Default T `json:"default,omitempty"`
+	// Template is the template to interpolate, if deferred. See TemplateValue.
+	Template string `json:"template,omitempty"`
+	// Env holds the environment variables used by the template, as captured
+	// at construction time.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// StaticValue returns a ValueProvider with the given construction-time value.
+func StaticValue[T any](value T) ValueProvider[T] {
+	return ValueProvider[T]{Value: value}
+}
+
+// RuntimeValue returns a ValueProvider deferred to the runtime parameter of
+// the given name. If the parameter is not set, the default is used, unless
+// it is the zero value. The parameter is declared on the pipeline of the
+// scope for runners that must declare runtime parameters upfront, such as
+// when creating templates. It panics if the parameter is already declared
+// with a different default.
+func RuntimeValue[T any](s Scope, param string, def T) ValueProvider[T] {
+	if !s.IsValid() {
+		panic("invalid scope")
+	}
+	if param == "" {
+		panic("runtime value parameter must be non-empty")
+	}
+
+	var encoded string
+	if !isZeroValue(def) {
+		v, err := encodeValue(def)
+		if err != nil {
+			panic(fmt.Sprintf("invalid default for runtime value parameter %v: %v", param, err))
+		}
+		encoded = v
+	}
+	if err := s.real.DeclareParameter(param, encoded); err != nil {
+		panic(err)
+	}
+	return ValueProvider[T]{Param: param, Default: def}
+}
+
+// RuntimeParameters returns the declared runtime parameters of the pipeline
+// and their defaults, as they would be given as pipeline options. The default
+// is empty if the parameter has none.
+func (p *Pipeline) RuntimeParameters() map[string]string {
+	return p.real.Parameters()
+}
+
+// IsRuntime returns true iff the value is deferred until run time.
+func (v ValueProvider[T]) IsRuntime() bool {
+	return v.Param != "" || v.Template != ""
+}
+
+// IsAccessible returns true iff the value can be resolved now. Runtime values
+// are generally not accessible during pipeline construction.
+func (v ValueProvider[T]) IsAccessible() bool {
+	_, err := v.Get()
+	return err == nil
+}
+
+// Get returns the value. It fails if the value is deferred, but the runtime
+// parameter is not set and has no default, or if the value of the parameter
+// or template is not a valid T.
+func (v ValueProvider[T]) Get() (T, error) {
+	if v.Template != "" {
+		value, err := interpolate(v.Template, v.Env)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		return v.decode(value)
+	}
+	if !v.IsRuntime() {
+		return v.Value, nil
+	}
+	if value := runtime.GlobalOptions.Get(v.Param); value != "" {
+		return v.decode(value)
+	}
+	if !isZeroValue(v.Default) {
+		return v.Default, nil
+	}
+	var zero T
+	return zero, fmt.Errorf("runtime value %v not set", v.Param)
+}
+
+// decode decodes the given option or template value as a T.
+func (v ValueProvider[T]) decode(value string) (T, error) {
+	var ret T
+	if p, ok := any(&ret).(*string); ok {
+		*p = value
+		return ret, nil
+	}
+	if err := json.Unmarshal([]byte(value), &ret); err != nil {
+		return ret, fmt.Errorf("invalid %T value %q for %v: %v", ret, value, v, err)
+	}
+	return ret, nil
+}
+
+func (v ValueProvider[T]) String() string {
+	if v.Template != "" {
+		return fmt.Sprintf("TemplateValue(%q)", v.Template)
+	}
+	if v.IsRuntime() {
+		return fmt.Sprintf("RuntimeValue(%v, default=%#v)", v.Param, v.Default)
+	}
+	return fmt.Sprintf("StaticValue(%#v)", v.Value)
+}
+
+// encodeValue encodes the given value as it would be given as a pipeline
+// option. It is the inverse of decode.
+func encodeValue(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func isZeroValue(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	return !v.IsValid() || v.IsZero()
+}
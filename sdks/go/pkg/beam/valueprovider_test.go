@@ -0,0 +1,121 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestValueProvider(t *testing.T) {
+	static := StaticValue("foo")
+	if v, err := static.Get(); err != nil || v != "foo" {
+		t.Errorf("%v.Get() = %v, %v, want foo", static, v, err)
+	}
+
+	p := NewPipeline()
+	s := p.Root()
+
+	deferred := RuntimeValue(s, "vp_test_size", int64(0))
+	if deferred.IsAccessible() {
+		t.Errorf("%v accessible before being set", deferred)
+	}
+	withDefault := RuntimeValue(s, "vp_test_flag", true)
+	if v, err := withDefault.Get(); err != nil || !v {
+		t.Errorf("%v.Get() = %v, %v, want true", withDefault, v, err)
+	}
+
+	// The provider must survive serialization with its DoFn.
+	data, err := json.Marshal(deferred)
+	if err != nil {
+		t.Fatalf("Marshal(%v) failed: %v", deferred, err)
+	}
+	var decoded ValueProvider[int64]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+	}
+
+	PipelineOptions.Set("vp_test_size", "42")
+	if v, err := decoded.Get(); err != nil || v != 42 {
+		t.Errorf("%v.Get() = %v, %v, want 42", decoded, v, err)
+	}
+	PipelineOptions.Set("vp_test_size", "foo")
+	if v, err := decoded.Get(); err == nil {
+		t.Errorf("%v.Get() = %v, want error", decoded, v)
+	}
+
+	want := map[string]string{"vp_test_size": "", "vp_test_flag": "true"}
+	if got := p.RuntimeParameters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RuntimeParameters() = %v, want %v", got, want)
+	}
+	// Parameters are scoped to the pipeline that declares them.
+	if got := NewPipeline().RuntimeParameters(); len(got) != 0 {
+		t.Errorf("RuntimeParameters() of empty pipeline = %v, want none", got)
+	}
+}
+
+func TestRuntimeValueConflict(t *testing.T) {
+	s := NewPipeline().Root()
+	RuntimeValue(s, "vp_test_conflict", "a")
+	RuntimeValue(s.Scope("sub"), "vp_test_conflict", "a")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RuntimeValue with a different default succeeded, want panic")
+		}
+	}()
+	RuntimeValue(s, "vp_test_conflict", "b")
+}
+
+func TestTemplateValue(t *testing.T) {
+	PipelineOptions.Set(TemplateTimeOption, "2018-06-01T12:00:00Z")
+	PipelineOptions.Set("tv_test_table", "events")
+	os.Setenv("TV_TEST_ENV", "prod")
+
+	v := TemplateValue(`gs://bucket/{{env "TV_TEST_ENV"}}/{{.Date}}/{{.Time.Format "15"}}/*`)
+	if !v.IsRuntime() {
+		t.Errorf("%v is not deferred", v)
+	}
+
+	// The environment is captured at construction time.
+	os.Setenv("TV_TEST_ENV", "dev")
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal(%v) failed: %v", v, err)
+	}
+	var decoded ValueProvider[string]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+	}
+	if got, err := decoded.Get(); err != nil || got != "gs://bucket/prod/2018-06-01/12/*" {
+		t.Errorf("%v.Get() = %v, %v, want gs://bucket/prod/2018-06-01/12/*", decoded, got, err)
+	}
+
+	if got, err := Interpolate("p:d.{{.Options.tv_test_table}}_{{.Time.Format \"20060102\"}}"); err != nil || got != "p:d.events_20180601" {
+		t.Errorf("Interpolate() = %v, %v, want p:d.events_20180601", got, err)
+	}
+
+	// Options must be set when the value is read.
+	missing := TemplateValue("{{.Options.tv_test_missing}}")
+	if got, err := missing.Get(); err == nil {
+		t.Errorf("%v.Get() = %v, want error", missing, got)
+	}
+	if _, err := Interpolate("{{.Date"); err == nil {
+		t.Error("Interpolate of invalid template succeeded, want error")
+	}
+}
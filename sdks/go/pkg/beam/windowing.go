@@ -0,0 +1,91 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"fmt"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/graph"
+)
+
+// WindowInto is a PTransform that assigns the elements of a PCollection of
+// type 'A' to windows of the given windowing strategy, based on their event
+// times. It returns a PCollection of type 'A' with the new strategy, which is
+// propagated downstream and determines how GroupByKey, CoGroupByKey and
+// Combine group elements: per key and window. For example:
+//
+//    hourly := beam.WindowInto(s, window.NewFixedWindows(time.Hour), events)
+//    counts := stats.Count(s, hourly)    // counts per hour
+//
+// Elements of overlapping sliding windows are grouped in each of them and
+// session windows of a key are merged when grouped.
+func WindowInto(s Scope, ws *WindowingStrategy, col PCollection) PCollection {
+	return Must(TryWindowInto(s, ws, col))
+}
+
+// TryWindowInto attempts to insert a WindowInto transform.
+func TryWindowInto(s Scope, ws *WindowingStrategy, col PCollection) (PCollection, error) {
+	if !s.IsValid() {
+		return PCollection{}, fmt.Errorf("invalid scope")
+	}
+	if !col.IsValid() {
+		return PCollection{}, fmt.Errorf("invalid input pcollection")
+	}
+	if ws == nil {
+		return PCollection{}, fmt.Errorf("windowing strategy must not be nil")
+	}
+
+	edge := graph.NewWindowInto(s.real, s.scope, ws, col.n)
+	edge.Label = s.name
+	ret := PCollection{edge.Output[0].To}
+	ret.SetCoder(col.Coder())
+	return ret, nil
+}
+
+// WindowIntoWithLateData is a WindowInto that also returns the elements that
+// would otherwise be silently dropped, because they arrive after the
+// watermark has passed the end of their window by more than the allowed
+// lateness of the windowing strategy. Late elements retain their timestamps
+// and are returned in the global window, such that they can be monitored or
+// reprocessed:
+//
+//    ws := window.NewFixedWindows(time.Minute).WithAllowedLateness(time.Hour)
+//    windowed, late := beam.WindowIntoWithLateData(s, ws, events)
+func WindowIntoWithLateData(s Scope, ws *WindowingStrategy, col PCollection) (PCollection, PCollection) {
+	return Must2(TryWindowIntoWithLateData(s, ws, col))
+}
+
+// TryWindowIntoWithLateData attempts to insert a WindowInto transform with
+// an output for late data.
+func TryWindowIntoWithLateData(s Scope, ws *WindowingStrategy, col PCollection) (PCollection, PCollection, error) {
+	if !s.IsValid() {
+		return PCollection{}, PCollection{}, fmt.Errorf("invalid scope")
+	}
+	if !col.IsValid() {
+		return PCollection{}, PCollection{}, fmt.Errorf("invalid input pcollection")
+	}
+	if ws == nil {
+		return PCollection{}, PCollection{}, fmt.Errorf("windowing strategy must not be nil")
+	}
+
+	edge := graph.NewWindowIntoWithLateData(s.real, s.scope, ws, col.n)
+	edge.Label = s.name
+	ret := PCollection{edge.Output[0].To}
+	ret.SetCoder(col.Coder())
+	late := PCollection{edge.Output[1].To}
+	late.SetCoder(col.Coder())
+	return ret, late, nil
+}
@@ -0,0 +1,67 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beamx
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/metrics"
+)
+
+// systemNamespace is the namespace of the job-level metrics added on export.
+const systemNamespace = "beam.system"
+
+// exportMetrics writes the metrics of a finished job to the given file using
+// its textio filesystem. Besides the user metrics of the job, it includes
+// job-level system metrics: the duration and whether the job failed. Without
+// a job result, such as if the job failed, the user metrics collected in this
+// process are written instead. The format is CSV for .csv files and JSON
+// otherwise.
+func exportMetrics(ctx context.Context, filename string, res beam.PipelineResult, duration time.Duration, jobErr error) error {
+	failed := int64(0)
+	if jobErr != nil {
+		failed = 1
+	}
+	user := metrics.Results()
+	if res != nil {
+		set, err := res.Metrics(ctx)
+		if err != nil {
+			return err
+		}
+		user = set.All()
+	}
+	results := append([]metrics.Result{
+		{Namespace: systemNamespace, Name: "job_duration_msecs", Kind: metrics.CounterKind, Value: int64(duration / time.Millisecond)},
+		{Namespace: systemNamespace, Name: "job_failed", Kind: metrics.CounterKind, Value: failed},
+	}, user...)
+
+	var buf bytes.Buffer
+	if strings.HasSuffix(filename, ".csv") {
+		if err := metrics.WriteCSV(&buf, results); err != nil {
+			return err
+		}
+	} else {
+		if err := metrics.WriteJSON(&buf, results); err != nil {
+			return err
+		}
+	}
+
+	return writeFile(ctx, filename, buf.Bytes())
+}
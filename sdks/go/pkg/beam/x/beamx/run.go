@@ -17,10 +17,16 @@
 package beamx
 
 import (
+	"bytes"
 	"context"
 	"flag"
+	"time"
 
 	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/go/pkg/beam/options/structopts"
+	"github.com/apache/beam/sdks/go/pkg/beam/x/features"
 	// Import the reflection-optimized runtime.
 	_ "github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec/optimized"
 	_ "github.com/apache/beam/sdks/go/pkg/beam/io/textio/gcs"
@@ -30,14 +36,104 @@ import (
 	_ "github.com/apache/beam/sdks/go/pkg/beam/runners/direct"
 	_ "github.com/apache/beam/sdks/go/pkg/beam/runners/dot"
 	_ "github.com/apache/beam/sdks/go/pkg/beam/runners/flink"
+	_ "github.com/apache/beam/sdks/go/pkg/beam/runners/local"
+	_ "github.com/apache/beam/sdks/go/pkg/beam/runners/spark"
 	_ "github.com/apache/beam/sdks/go/pkg/beam/runners/universal"
 )
 
-var runner = flag.String("runner", "direct", "Pipeline runner.")
+var (
+	runner        = flag.String("runner", "direct", "Pipeline runner.")
+	dotFile       = flag.String("dot", "", "File to write a Graphviz DOT graph of the pipeline to before running it, such as out.dot.")
+	validate      = flag.Bool("validate", false, "Validate the pipeline before running it and report all problems found.")
+	savePipeline  = flag.String("save_pipeline", "", "File to write the model pipeline to before running it, for checking later versions with --check_update.")
+	checkUpdate   = flag.String("check_update", "", "File with the model pipeline of the running job, as written by --save_pipeline. If set, the pipeline must be update compatible with it to run.")
+	featureFlags  = flag.String("features", "", "Comma-separated runtime feature flags of the form name or name=value, queryable by DoFns with the features package.")
+	metricsExport = flag.String("metrics_export", "", "File to export pipeline metrics to when the job finishes, such as gs://bucket/metrics.json. The format is CSV for .csv files and JSON otherwise.")
+)
 
 // Run invokes beam.Run with the runner supplied by the flag "runner". It
 // defaults to the direct runner, but all beam-distributed runners and textio
-// filesystems are implicitly registered.
+// filesystems are implicitly registered. If the flag "dot" is set, a DOT graph
+// of the pipeline is written before running it. If the flag "metrics_export"
+// is set, the pipeline metrics are exported when the job finishes. If the flag
+// "validate" is set, the pipeline is validated before running it. If the flag
+// "check_update" is set, the pipeline is checked for update compatibility with
+// the given saved pipeline, such as one written with the flag "save_pipeline".
+// The feature flags given by the flag "features" are set for the pipeline.
+// Options registered with structopts are validated and exported.
 func Run(ctx context.Context, p *beam.Pipeline) error {
-	return beam.Run(ctx, *runner, p)
+	_, err := RunWithResult(ctx, p)
+	return err
+}
+
+// RunWithResult is like Run, but also returns the result of the job, which
+// queries its state and metrics. The result is nil if the runner does not run
+// a job, such as the dot runner.
+func RunWithResult(ctx context.Context, p *beam.Pipeline) (beam.PipelineResult, error) {
+	if err := structopts.Export(); err != nil {
+		return nil, err
+	}
+	if *featureFlags != "" {
+		f, err := features.Parse(*featureFlags)
+		if err != nil {
+			return nil, err
+		}
+		features.SetAll(f)
+	}
+	if *validate {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if *checkUpdate != "" {
+		if err := CheckUpdate(ctx, p, *checkUpdate); err != nil {
+			return nil, err
+		}
+	}
+	if *savePipeline != "" {
+		if err := SavePipeline(ctx, p, *savePipeline); err != nil {
+			return nil, err
+		}
+	}
+	if *dotFile != "" {
+		var buf bytes.Buffer
+		if err := beam.RenderDOT(p, &buf); err != nil {
+			return nil, err
+		}
+		if err := writeFile(ctx, *dotFile, buf.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	res, err := beam.Run(ctx, *runner, p)
+
+	if *metricsExport != "" {
+		if xerr := exportMetrics(ctx, *metricsExport, res, time.Since(start), err); xerr != nil {
+			log.Errorf(ctx, "Failed to export metrics to %v: %v", *metricsExport, xerr)
+			if err == nil {
+				err = xerr
+			}
+		}
+	}
+	return res, err
+}
+
+// writeFile writes the data to the given file using its textio filesystem.
+func writeFile(ctx context.Context, filename string, data []byte) error {
+	fs, err := textio.NewFileSystem(ctx, filename)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	fd, err := fs.OpenWrite(ctx, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
 }
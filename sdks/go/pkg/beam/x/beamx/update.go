@@ -0,0 +1,97 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beamx
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/graphx"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	pb "github.com/apache/beam/sdks/go/pkg/beam/model/pipeline_v1"
+	"github.com/golang/protobuf/proto"
+)
+
+// SavePipeline writes the model pipeline to the given file using its textio
+// filesystem, so that later versions of the pipeline can be checked for
+// update compatibility with CheckUpdate.
+func SavePipeline(ctx context.Context, p *beam.Pipeline, filename string) error {
+	model, err := marshal(p)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(model)
+	if err != nil {
+		return err
+	}
+	return writeFile(ctx, filename, data)
+}
+
+// CheckUpdate checks whether the pipeline can update the running job, whose
+// model pipeline was saved to the given file with SavePipeline. It reports
+// all incompatibilities together, such as removed transforms or changed
+// coders, without contacting any runner. See graphx.CheckUpdate.
+func CheckUpdate(ctx context.Context, p *beam.Pipeline, filename string) error {
+	data, err := readFile(ctx, filename)
+	if err != nil {
+		return err
+	}
+	var prev pb.Pipeline
+	if err := proto.Unmarshal(data, &prev); err != nil {
+		return fmt.Errorf("invalid pipeline in %v: %v", filename, err)
+	}
+	next, err := marshal(p)
+	if err != nil {
+		return err
+	}
+
+	problems := graphx.CheckUpdate(&prev, next)
+	if len(problems) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, "  "+p.Error())
+	}
+	return fmt.Errorf("pipeline is not update compatible with %v:\n%v", filename, strings.Join(lines, "\n"))
+}
+
+func marshal(p *beam.Pipeline) (*pb.Pipeline, error) {
+	edges, _, err := p.Build()
+	if err != nil {
+		return nil, err
+	}
+	return graphx.Marshal(edges, &graphx.Options{})
+}
+
+// readFile reads the file using its textio filesystem.
+func readFile(ctx context.Context, filename string) ([]byte, error) {
+	fs, err := textio.NewFileSystem(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	fd, err := fs.OpenRead(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ioutil.ReadAll(fd)
+}
@@ -0,0 +1,317 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cleanup tracks intermediate resources, such as temporary tables
+// or staging files, that are created by IOs during pipeline execution and
+// deletes them once the job has completed. Usage:
+//
+//    err := cleanup.Run(ctx, "gs://mybucket/cleanup/job1", func(ctx context.Context) error {
+//        return beamx.Run(ctx, p)
+//    })
+//
+// Workers record each created resource with Track under the given location,
+// which must be accessible from both the launcher and the workers through a
+// registered textio file system. After the job completes, successfully or
+// not, every recorded resource is deleted using the deleter registered for
+// its kind and a manifest describing the outcome is written for audit. The
+// records of deleted resources are removed, while those of resources that
+// could not be deleted are kept for a later Cleanup.
+//
+// The temporary files of textio.Write are tracked automatically. Other IOs and
+// DoFns track the resources they create with Track.
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+const (
+	// locationKey is the global option holding the resource location.
+	locationKey = "cleanup_location"
+
+	recordPrefix = "resource-"
+	recordSuffix = ".json"
+
+	// ManifestName is the name of the audit manifest written to the
+	// location after cleanup.
+	ManifestName = "MANIFEST.json"
+)
+
+func init() {
+	textio.RegisterTempFileTracker(trackTempFile)
+}
+
+var (
+	deleters = make(map[string]func(context.Context, string) error)
+	mu       sync.Mutex
+)
+
+// RegisterDeleter registers a function that deletes resources of the given
+// kind by name. Deleters should treat already-deleted resources as success.
+// It must be called in an init() function.
+func RegisterDeleter(kind string, fn func(ctx context.Context, name string) error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := deleters[kind]; ok {
+		panic(fmt.Sprintf("deleter for %v already registered", kind))
+	}
+	deleters[kind] = fn
+}
+
+func lookupDeleter(kind string) (func(context.Context, string) error, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fn, ok := deleters[kind]
+	return fn, ok
+}
+
+// Resource is an intermediate resource created during execution.
+type Resource struct {
+	// Kind identifies the deleter, such as "bigquery" or a file scheme.
+	Kind string `json:"kind"`
+	// Name is the kind-specific name of the resource.
+	Name string `json:"name"`
+	// Step is the transform that created the resource, for lineage.
+	Step string `json:"step,omitempty"`
+	// Created is the time the resource was tracked.
+	Created time.Time `json:"created"`
+}
+
+func (r Resource) String() string {
+	return fmt.Sprintf("%v:%v", r.Kind, r.Name)
+}
+
+// Entry is the outcome of cleaning up a single resource.
+type Entry struct {
+	Resource Resource `json:"resource"`
+	Deleted  bool     `json:"deleted"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Manifest is the audit record written after cleanup.
+type Manifest struct {
+	// Location is where the resources were tracked.
+	Location string `json:"location"`
+	// JobError is the job failure, if any.
+	JobError string `json:"job_error,omitempty"`
+	// Completed is the time cleanup finished.
+	Completed time.Time `json:"completed"`
+	// Entries holds the outcome for each tracked resource.
+	Entries []Entry `json:"entries"`
+}
+
+// Location returns the resource location for the active job. It returns ""
+// if cleanup is not enabled.
+func Location() string {
+	return runtime.GlobalOptions.Get(locationKey)
+}
+
+// Track records that the given step created a resource of the given kind.
+// It is a no-op, except for a warning, if cleanup is not enabled for the
+// job.
+func Track(ctx context.Context, kind, name, step string) error {
+	location := Location()
+	if location == "" {
+		log.Warnf(ctx, "Resource %v:%v not tracked for cleanup: no location", kind, name)
+		return nil
+	}
+
+	data, err := json.Marshal(Resource{Kind: kind, Name: name, Step: step, Created: time.Now()})
+	if err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("%v%v-%x%v", recordPrefix, time.Now().UnixNano(), rand.Int63(), recordSuffix)
+	return writeFile(ctx, join(location, filename), data)
+}
+
+// TrackFile records that the given step created a file. The file system
+// scheme of the filename is used as the kind.
+func TrackFile(ctx context.Context, filename, step string) error {
+	return Track(ctx, scheme(filename), filename, step)
+}
+
+// trackTempFile records a temporary file of textio.Write, if cleanup is
+// enabled for the job.
+func trackTempFile(ctx context.Context, filename string) error {
+	if Location() == "" {
+		return nil
+	}
+	return TrackFile(ctx, filename, "textio.Write")
+}
+
+// Run enables resource tracking under the given location, invokes run and
+// then deletes all tracked resources, regardless of whether run failed. The
+// location must be set before pipeline submission, so run must submit the
+// job. If run fails, its error is returned. Otherwise any cleanup error is
+// returned.
+func Run(ctx context.Context, location string, run func(context.Context) error) error {
+	if location == "" {
+		return fmt.Errorf("cleanup location must be non-empty")
+	}
+	runtime.GlobalOptions.Set(locationKey, location)
+
+	jobErr := run(ctx)
+	_, err := Cleanup(ctx, location, jobErr)
+	if jobErr != nil {
+		if err != nil {
+			log.Errorf(ctx, "Cleanup after failed job failed: %v", err)
+		}
+		return jobErr
+	}
+	return err
+}
+
+// Cleanup deletes all resources tracked under the given location and writes
+// the manifest. The job error, if any, is recorded in the manifest. It
+// returns an error if any resource could not be deleted.
+func Cleanup(ctx context.Context, location string, jobErr error) (*Manifest, error) {
+	records, err := list(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked resources in %v: %v", location, err)
+	}
+
+	m := &Manifest{Location: location}
+	if jobErr != nil {
+		m.JobError = jobErr.Error()
+	}
+
+	var failed int
+	for _, rec := range records {
+		r := rec.resource
+		e := Entry{Resource: r}
+		if err := remove(ctx, r); err != nil {
+			log.Warnf(ctx, "Failed to delete %v created by %v: %v", r, r.Step, err)
+			e.Error = err.Error()
+			failed++
+		} else {
+			log.Infof(ctx, "Deleted %v created by %v", r, r.Step)
+			e.Deleted = true
+
+			// The record is no longer needed once its resource is deleted.
+			if err := remove(ctx, Resource{Kind: scheme(rec.filename), Name: rec.filename}); err != nil {
+				log.Warnf(ctx, "Failed to delete record %v of %v: %v", rec.filename, r, err)
+			}
+		}
+		m.Entries = append(m.Entries, e)
+	}
+	m.Completed = time.Now()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+	if err := writeFile(ctx, join(location, ManifestName), data); err != nil {
+		return m, fmt.Errorf("failed to write manifest: %v", err)
+	}
+
+	if failed > 0 {
+		return m, fmt.Errorf("failed to delete %v of %v resources, see %v", failed, len(records), join(location, ManifestName))
+	}
+	return m, nil
+}
+
+func remove(ctx context.Context, r Resource) error {
+	fn, ok := lookupDeleter(r.Kind)
+	if !ok {
+		return fmt.Errorf("no deleter registered for %v", r.Kind)
+	}
+	return fn(ctx, r.Name)
+}
+
+// record is a tracked resource and the file recording it.
+type record struct {
+	filename string
+	resource Resource
+}
+
+func list(ctx context.Context, location string) ([]record, error) {
+	fs, err := textio.NewFileSystem(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	files, err := fs.List(ctx, join(location, recordPrefix+"*"+recordSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []record
+	for _, filename := range files {
+		data, err := readFile(ctx, fs, filename)
+		if err != nil {
+			return nil, err
+		}
+		var r Resource
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("bad record %v: %v", filename, err)
+		}
+		ret = append(ret, record{filename: filename, resource: r})
+	}
+	return ret, nil
+}
+
+func readFile(ctx context.Context, fs textio.FileSystem, filename string) ([]byte, error) {
+	fd, err := fs.OpenRead(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	return ioutil.ReadAll(fd)
+}
+
+func writeFile(ctx context.Context, filename string, data []byte) error {
+	fs, err := textio.NewFileSystem(ctx, filename)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	fd, err := fs.OpenWrite(ctx, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func join(location, name string) string {
+	return strings.TrimSuffix(location, "/") + "/" + name
+}
+
+// scheme returns the textio file system scheme of the given filename.
+func scheme(filename string) string {
+	if index := strings.Index(filename, "://"); index > 0 {
+		return filename[:index]
+	}
+	return "default"
+}
@@ -0,0 +1,223 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	textio.RegisterFileSystem("mem", func(context.Context) textio.FileSystem { return memfs })
+	RegisterDeleter("mem", memfs.remove)
+	RegisterDeleter("test", func(ctx context.Context, name string) error {
+		if name == "bad" {
+			return fmt.Errorf("cannot delete %v", name)
+		}
+		deleted = append(deleted, name)
+		return nil
+	})
+}
+
+var (
+	memfs   = &memFS{files: make(map[string][]byte)}
+	deleted []string
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	location := "mem://job/"
+
+	jobErr := fmt.Errorf("job failed")
+	err := Run(ctx, location, func(ctx context.Context) error {
+		for _, name := range []string{"a", "b"} {
+			if err := Track(ctx, "test", name, "step"); err != nil {
+				t.Fatalf("Track(%v) failed: %v", name, err)
+			}
+		}
+		return jobErr
+	})
+	if err != jobErr {
+		t.Errorf("Run() = %v, want %v", err, jobErr)
+	}
+
+	sort.Strings(deleted)
+	if want := []string{"a", "b"}; fmt.Sprint(deleted) != fmt.Sprint(want) {
+		t.Errorf("deleted = %v, want %v", deleted, want)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(memfs.files["mem://job/"+ManifestName], &m); err != nil {
+		t.Fatalf("bad manifest: %v", err)
+	}
+	if m.JobError != jobErr.Error() || len(m.Entries) != 2 {
+		t.Errorf("manifest = %+v, want job error and 2 entries", m)
+	}
+	for _, e := range m.Entries {
+		if !e.Deleted || e.Resource.Step != "step" {
+			t.Errorf("entry = %+v, want deleted resource from step", e)
+		}
+	}
+	if records := memfs.records(); len(records) != 0 {
+		t.Errorf("records after cleanup = %v, want none", records)
+	}
+
+	// Failed deletes are reported and their records kept.
+
+	if err := Track(ctx, "test", "bad", "step"); err != nil {
+		t.Fatalf("Track(bad) failed: %v", err)
+	}
+	if _, err := Cleanup(ctx, location, nil); err == nil {
+		t.Errorf("Cleanup() succeeded, want failure for undeletable resource")
+	}
+	if records := memfs.records(); len(records) != 1 {
+		t.Errorf("records after failed cleanup = %v, want 1", records)
+	}
+	memfs.remove(ctx, memfs.records()[0])
+}
+
+func TestTextioWrite(t *testing.T) {
+	ctx := context.Background()
+
+	err := Run(ctx, "mem://write/", func(ctx context.Context) error {
+		p, s, lines := ptest.CreateList([]string{"a", "b"})
+		textio.Write(s, "mem://out/lines.txt", lines)
+		return ptest.Run(p)
+	})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// The temporary file is tracked, moved into place and its record is
+	// removed by cleanup.
+
+	memfs.mu.Lock()
+	defer memfs.mu.Unlock()
+	if got := string(memfs.files["mem://out/lines.txt"]); len(got) != 4 {
+		t.Errorf("output = %q, want 2 lines", got)
+	}
+	var m Manifest
+	if err := json.Unmarshal(memfs.files["mem://write/"+ManifestName], &m); err != nil {
+		t.Fatalf("bad manifest: %v", err)
+	}
+	if len(m.Entries) != 1 || !m.Entries[0].Deleted || !strings.HasPrefix(m.Entries[0].Resource.Name, "mem://out/lines.txt.beam-temp-") {
+		t.Errorf("manifest = %+v, want deleted temporary file", m)
+	}
+	for name := range memfs.files {
+		if strings.Contains(name, "beam-temp") || strings.Contains(name, recordPrefix) {
+			t.Errorf("file %v left after cleanup", name)
+		}
+	}
+}
+
+// memFS is an in-memory textio file system.
+type memFS struct {
+	files map[string][]byte
+	mu    sync.Mutex
+}
+
+func (f *memFS) Close() error {
+	return nil
+}
+
+func (f *memFS) List(ctx context.Context, glob string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ret []string
+	for name := range f.files {
+		if ok, _ := filepath.Match(glob, name); ok {
+			ret = append(ret, name)
+		}
+	}
+	return ret, nil
+}
+
+func (f *memFS) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.files[filename]
+	if !ok {
+		return nil, fmt.Errorf("%v not found", filename)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (f *memFS) OpenWrite(ctx context.Context, filename string) (io.WriteCloser, error) {
+	return &memFile{fs: f, name: filename}, nil
+}
+
+func (f *memFS) Rename(ctx context.Context, oldname, newname string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.files[oldname]
+	if !ok {
+		return fmt.Errorf("%v not found", oldname)
+	}
+	delete(f.files, oldname)
+	f.files[newname] = data
+	return nil
+}
+
+func (f *memFS) remove(ctx context.Context, filename string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.files, filename)
+	return nil
+}
+
+// records returns the names of the records in the file system.
+func (f *memFS) records() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ret []string
+	for name := range f.files {
+		if strings.Contains(name, recordPrefix) {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+type memFile struct {
+	bytes.Buffer
+	fs   *memFS
+	name string
+}
+
+func (m *memFile) Close() error {
+	m.fs.mu.Lock()
+	defer m.fs.mu.Unlock()
+
+	m.fs.files[m.name] = m.Bytes()
+	return nil
+}
@@ -0,0 +1,161 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package connectors is a registry of IO connectors. Connectors, in-tree or
+// out-of-tree, describe themselves when imported, so that tools can discover
+// them without a central list. Usage:
+//
+//    func init() {
+//        connectors.Register(connectors.Connector{
+//            Name:     "kafkaio",
+//            URN:      "beam:go:io:kafka:v1",
+//            Package:  "github.com/example/kafkaio",
+//            Features: []connectors.Feature{connectors.Read, connectors.Write},
+//            Doc:      "Reads and writes Kafka topics.",
+//        })
+//    }
+//
+// Tools then list the registered connectors with All or find one by URN with
+// Lookup. WriteMarkdown generates a reference table for documentation.
+package connectors
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Feature is a capability of a connector.
+type Feature string
+
+const (
+	// Read indicates that the connector reads data.
+	Read Feature = "read"
+	// Write indicates that the connector writes data.
+	Write Feature = "write"
+	// Query indicates that the connector reads the result of a query.
+	Query Feature = "query"
+	// Ordered indicates that the connector preserves an ordering, such as
+	// per key, when writing.
+	Ordered Feature = "ordered"
+	// Transactional indicates that the connector writes in transactions.
+	Transactional Feature = "transactional"
+)
+
+// Connector describes an IO connector.
+type Connector struct {
+	// Name is the short name of the connector, such as "textio".
+	Name string
+	// URN uniquely identifies the connector. If the connector is translated
+	// as an External transform, it is the URN of its payload.
+	URN string
+	// Package is the import path of the connector.
+	Package string
+	// Unbounded is true if the connector reads unbounded data.
+	Unbounded bool
+	// Features are the capabilities of the connector.
+	Features []Feature
+	// Doc is a one-line description of the connector.
+	Doc string
+}
+
+// Has returns true iff the connector has the given feature.
+func (c Connector) Has(f Feature) bool {
+	for _, elm := range c.Features {
+		if elm == f {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Connector) String() string {
+	return fmt.Sprintf("%v[%v]", c.Name, c.URN)
+}
+
+var (
+	registry = make(map[string]Connector)
+	mu       sync.Mutex
+)
+
+// Register registers a connector. The name and URN are required and the URN
+// must not already be registered. It must be called in an init() function.
+func Register(c Connector) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c.Name == "" || c.URN == "" {
+		panic(fmt.Sprintf("connector %v must have a name and URN", c))
+	}
+	if old, ok := registry[c.URN]; ok {
+		panic(fmt.Sprintf("connector %v already registered as %v", c, old))
+	}
+	c.Features = append([]Feature(nil), c.Features...)
+	registry[c.URN] = c
+}
+
+// Lookup returns the connector registered for the given URN, if any.
+func Lookup(urn string) (Connector, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, ok := registry[urn]
+	return c, ok
+}
+
+// All returns all registered connectors ordered by name and URN.
+func All() []Connector {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var ret []Connector
+	for _, c := range registry {
+		ret = append(ret, c)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Name != ret[j].Name {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].URN < ret[j].URN
+	})
+	return ret
+}
+
+// WriteMarkdown writes a table of all registered connectors in Markdown.
+func WriteMarkdown(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "| Connector | URN | Bounded | Features | Description |\n|---|---|---|---|---|"); err != nil {
+		return err
+	}
+	for _, c := range All() {
+		var features []string
+		for _, f := range c.Features {
+			features = append(features, string(f))
+		}
+		bounded := "yes"
+		if c.Unbounded {
+			bounded = "no"
+		}
+		name := c.Name
+		if c.Package != "" {
+			name = fmt.Sprintf("%v (`%v`)", c.Name, c.Package)
+		}
+		if _, err := fmt.Fprintf(w, "| %v | `%v` | %v | %v | %v |\n", name, c.URN, bounded, strings.Join(features, ", "), c.Doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
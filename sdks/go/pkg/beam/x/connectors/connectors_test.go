@@ -0,0 +1,80 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectors
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	c := Connector{
+		Name:      "testio",
+		URN:       "beam:go:io:test:v1",
+		Unbounded: true,
+		Features:  []Feature{Read},
+		Doc:       "Reads test data.",
+	}
+	Register(c)
+
+	got, ok := Lookup(c.URN)
+	if !ok {
+		t.Fatalf("Lookup(%v) failed", c.URN)
+	}
+	if got.Name != c.Name || !got.Has(Read) || got.Has(Write) {
+		t.Errorf("Lookup(%v) = %+v, want %+v", c.URN, got, c)
+	}
+	if _, ok := Lookup("beam:go:io:missing:v1"); ok {
+		t.Errorf("Lookup(missing) succeeded, want failure")
+	}
+
+	var names []string
+	for _, elm := range All() {
+		names = append(names, elm.Name)
+	}
+	if len(names) != 1 || names[0] != c.Name {
+		t.Errorf("All() = %v, want [%v]", names, c.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	if want := "| testio | `beam:go:io:test:v1` | no | read | Reads test data. |"; !strings.Contains(buf.String(), want) {
+		t.Errorf("WriteMarkdown() = %v, want row %v", buf.String(), want)
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	tests := []Connector{
+		{URN: "beam:go:io:noname:v1"},
+		{Name: "nourn"},
+		{Name: "dup", URN: "beam:go:io:dup:v1"},
+	}
+	Register(Connector{Name: "dup", URN: "beam:go:io:dup:v1"})
+
+	for _, c := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%v) succeeded, want panic", c)
+				}
+			}()
+			Register(c)
+		}()
+	}
+}
@@ -0,0 +1,382 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/go/pkg/beam/io/textio"
+	"github.com/apache/beam/sdks/go/pkg/beam/runners/direct"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*encodeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*encodeKVFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeSampleFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*decodeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*decodeKVFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*collectFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*collectKVFn)(nil)).Elem())
+}
+
+// sampleMagic is the header of sample files.
+const sampleMagic = "beam-sample/v1"
+
+// WriteSample writes the first n elements of a PCollection, which may be a
+// KV, to a single file along with its coder. The filename may be remote,
+// such as gs://bucket/debug/parsed.sample, if the file system is registered
+// with textio. The sample of a production job can then be debugged locally
+// with RunLocal. For example, in the production pipeline:
+//
+//    parsed := beam.ParDo(s, parseFn, lines)
+//    debug.WriteSample(s, parsed, 1000, "gs://bucket/debug/parsed.sample")
+//
+// Timestamps and windows are not preserved.
+func WriteSample(s beam.Scope, col beam.PCollection, n int, filename string) {
+	s = s.Scope("debug.WriteSample")
+
+	if typex.IsCoGBK(col.Type()) {
+		panic(fmt.Sprintf("cannot sample grouped PCollection %v", col))
+	}
+	c := beam.EncodedCoder{Coder: col.Coder()}
+	header := sampleHeader{Coder: c}
+	sample := Head(s, col, n)
+	if typex.IsKV(col.Type()) {
+		for _, comp := range col.Type().Components() {
+			header.Types = append(header.Types, beam.EncodedType{T: comp.Type()})
+		}
+		sample = beam.ParDo(s, &encodeKVFn{Coder: c}, sample)
+	} else {
+		header.Types = []beam.EncodedType{{T: col.Type().Type()}}
+		sample = beam.ParDo(s, &encodeFn{Coder: c}, sample)
+	}
+	beam.ParDo0(s, &writeSampleFn{Filename: filename, Header: header}, beam.Impulse(s), beam.SideInput{Input: sample})
+}
+
+// sampleHeader describes the elements of a sample file. The types are
+// recorded separately, because coders may not preserve them.
+type sampleHeader struct {
+	Coder beam.EncodedCoder  `json:"coder"`
+	Types []beam.EncodedType `json:"types"`
+}
+
+// Sample is a sample of a PCollection written by WriteSample.
+type Sample struct {
+	// Coder is the coder of the elements.
+	Coder beam.Coder
+	// Types holds the element type or the key and value types of KVs.
+	Types []reflect.Type
+	// Data holds the encoded elements.
+	Data [][]byte
+}
+
+// IsKV returns true iff the sample holds KV elements.
+func (s *Sample) IsKV() bool {
+	return len(s.Types) == 2
+}
+
+// ReadSample reads a sample written by WriteSample.
+func ReadSample(ctx context.Context, filename string) (*Sample, error) {
+	fs, err := textio.NewFileSystem(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	fd, err := fs.OpenRead(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	r := bufio.NewReader(fd)
+	var records [][]byte
+	for {
+		record, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample %v: %v", filename, err)
+		}
+		records = append(records, record)
+	}
+	if len(records) < 2 || string(records[0]) != sampleMagic {
+		return nil, fmt.Errorf("invalid sample %v: missing header", filename)
+	}
+	var header sampleHeader
+	if err := json.Unmarshal(records[1], &header); err != nil {
+		return nil, fmt.Errorf("invalid sample %v: bad header: %v", filename, err)
+	}
+	if n := len(header.Types); n != 1 && n != 2 {
+		return nil, fmt.Errorf("invalid sample %v: bad types: %v", filename, header.Types)
+	}
+
+	ret := &Sample{Coder: header.Coder.Coder, Data: records[2:]}
+	for _, t := range header.Types {
+		ret.Types = append(ret.Types, t.T)
+	}
+	return ret, nil
+}
+
+// KV is a key-value element of the output of RunLocal.
+type KV struct {
+	Key, Value interface{}
+}
+
+func (kv KV) String() string {
+	return fmt.Sprintf("(%v,%v)", kv.Key, kv.Value)
+}
+
+// RunLocal runs the DoFn with the direct runner on the sample written by
+// WriteSample and returns the elements of each output. KV outputs are
+// returned as KVs. It allows quick iterations on a fix of a DoFn against
+// the data of a production job. For example:
+//
+//    outs, err := debug.RunLocal(ctx, "gs://bucket/debug/parsed.sample", &enrichFn{...})
+//
+// The DoFn must only have a main input, which must match the sample.
+func RunLocal(ctx context.Context, filename string, dofn interface{}, opts ...beam.Option) ([][]interface{}, error) {
+	sample, err := ReadSample(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	p := beam.NewPipeline()
+	s := p.Root()
+
+	raw := beam.CreateList(s, sample.Data)
+	c := beam.EncodedCoder{Coder: sample.Coder}
+	var col beam.PCollection
+	if sample.IsKV() {
+		k, v := sample.Types[0], sample.Types[1]
+		fn := &decodeKVFn{Coder: c, Key: beam.EncodedType{T: k}, Value: beam.EncodedType{T: v}}
+		col = beam.ParDo(s, fn, raw, beam.TypeDefinition{Var: beam.XType, T: k}, beam.TypeDefinition{Var: beam.YType, T: v})
+	} else {
+		t := sample.Types[0]
+		col = beam.ParDo(s, &decodeFn{Coder: c, Type: beam.EncodedType{T: t}}, raw, beam.TypeDefinition{Var: beam.TType, T: t})
+	}
+	outs, err := beam.TryParDo(s, dofn, col, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	id := atomic.AddInt64(&runs, 1)
+	defer collected.Delete(id)
+
+	ret := make([][]interface{}, len(outs))
+	for i, out := range outs {
+		if typex.IsKV(out.Type()) {
+			beam.ParDo0(s, &collectKVFn{Run: id, Index: i}, out)
+		} else {
+			beam.ParDo0(s, &collectFn{Run: id, Index: i}, out)
+		}
+	}
+	collected.Store(id, &collection{outs: ret})
+
+	if _, err := direct.Execute(ctx, p); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+var (
+	runs      int64
+	collected sync.Map // run -> *collection
+)
+
+// collection holds the outputs of a local run.
+type collection struct {
+	outs [][]interface{}
+	mu   sync.Mutex
+}
+
+func collect(run int64, index int, elm interface{}) {
+	v, ok := collected.Load(run)
+	if !ok {
+		panic(fmt.Sprintf("debug.RunLocal: unknown run %v", run))
+	}
+	c := v.(*collection)
+	c.mu.Lock()
+	c.outs[index] = append(c.outs[index], elm)
+	c.mu.Unlock()
+}
+
+type collectFn struct {
+	Run   int64 `json:"run"`
+	Index int   `json:"index"`
+}
+
+func (f *collectFn) ProcessElement(t beam.T) {
+	collect(f.Run, f.Index, t)
+}
+
+type collectKVFn struct {
+	Run   int64 `json:"run"`
+	Index int   `json:"index"`
+}
+
+func (f *collectKVFn) ProcessElement(x beam.X, y beam.Y) {
+	collect(f.Run, f.Index, KV{Key: x, Value: y})
+}
+
+// encodeFn encodes elements with the coder of the sampled PCollection.
+type encodeFn struct {
+	Coder beam.EncodedCoder `json:"coder"`
+
+	enc exec.ElementEncoder
+	buf bytes.Buffer
+}
+
+func (f *encodeFn) Setup() {
+	f.enc = exec.MakeElementEncoder(beam.UnwrapCoder(f.Coder.Coder))
+}
+
+func (f *encodeFn) ProcessElement(t beam.T) ([]byte, error) {
+	return encodeElement(f.enc, &f.buf, exec.FullValue{Elm: t})
+}
+
+// encodeKVFn encodes KV elements with the coder of the sampled PCollection.
+type encodeKVFn struct {
+	Coder beam.EncodedCoder `json:"coder"`
+
+	enc exec.ElementEncoder
+	buf bytes.Buffer
+}
+
+func (f *encodeKVFn) Setup() {
+	f.enc = exec.MakeElementEncoder(beam.UnwrapCoder(f.Coder.Coder))
+}
+
+func (f *encodeKVFn) ProcessElement(x beam.X, y beam.Y) ([]byte, error) {
+	return encodeElement(f.enc, &f.buf, exec.FullValue{Elm: x, Elm2: y})
+}
+
+func encodeElement(enc exec.ElementEncoder, buf *bytes.Buffer, elm exec.FullValue) ([]byte, error) {
+	buf.Reset()
+	if err := enc.Encode(elm, buf); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// writeSampleFn writes the encoded sample to a single file.
+type writeSampleFn struct {
+	Filename string       `json:"filename"`
+	Header   sampleHeader `json:"header"`
+}
+
+func (f *writeSampleFn) ProcessElement(ctx context.Context, _ []byte, iter func(*[]byte) bool) error {
+	fs, err := textio.NewFileSystem(ctx, f.Filename)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	fd, err := fs.OpenWrite(ctx, f.Filename)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fd)
+
+	header, err := json.Marshal(f.Header)
+	if err != nil {
+		fd.Close()
+		return err
+	}
+	writeRecord(w, []byte(sampleMagic))
+	writeRecord(w, header)
+	var data []byte
+	for iter(&data) {
+		writeRecord(w, data)
+	}
+	if err := w.Flush(); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// decodeFn decodes the elements of a sample.
+type decodeFn struct {
+	Coder beam.EncodedCoder `json:"coder"`
+	Type  beam.EncodedType  `json:"type"`
+
+	dec exec.ElementDecoder
+}
+
+func (f *decodeFn) Setup() {
+	f.dec = exec.MakeElementDecoder(beam.UnwrapCoder(f.Coder.Coder))
+}
+
+func (f *decodeFn) ProcessElement(data []byte) (beam.T, error) {
+	fv, err := f.dec.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return exec.Convert(fv.Elm, f.Type.T), nil
+}
+
+// decodeKVFn decodes the KV elements of a sample.
+type decodeKVFn struct {
+	Coder beam.EncodedCoder `json:"coder"`
+	Key   beam.EncodedType  `json:"key"`
+	Value beam.EncodedType  `json:"value"`
+
+	dec exec.ElementDecoder
+}
+
+func (f *decodeKVFn) Setup() {
+	f.dec = exec.MakeElementDecoder(beam.UnwrapCoder(f.Coder.Coder))
+}
+
+func (f *decodeKVFn) ProcessElement(data []byte) (beam.X, beam.Y, error) {
+	fv, err := f.dec.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	return exec.Convert(fv.Elm, f.Key.T), exec.Convert(fv.Elm2, f.Value.T), nil
+}
+
+func writeRecord(w *bufio.Writer, data []byte) {
+	var size [binary.MaxVarintLen64]byte
+	w.Write(size[:binary.PutUvarint(size[:], uint64(len(data)))])
+	w.Write(data)
+}
+
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
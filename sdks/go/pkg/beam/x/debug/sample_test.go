@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	_ "github.com/apache/beam/sdks/go/pkg/beam/io/textio/local"
+	"github.com/apache/beam/sdks/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	beam.RegisterFunction(formatCount)
+	beam.RegisterFunction(splitEven)
+}
+
+func formatCount(w string, n int) string {
+	return fmt.Sprintf("%v=%v", w, n)
+}
+
+func splitEven(n int, even, odd func(int)) {
+	if n%2 == 0 {
+		even(n)
+	} else {
+		odd(n)
+	}
+}
+
+func TestSampleRunLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ints, kvs := filepath.Join(dir, "ints.sample"), filepath.Join(dir, "kvs.sample")
+
+	p, s, a, b := ptest.CreateList2([]int{1, 2, 3, 4, 5}, []string{"a", "b"})
+	WriteSample(s, a, 3, ints)
+	WriteSample(s, beam.SwapKV(s, beam.ParDo(s, func(w string) (int, string) { return len(w), w }, b)), 10, kvs)
+	if err := ptest.Run(p); err != nil {
+		t.Fatalf("WriteSample failed: %v", err)
+	}
+
+	ctx := context.Background()
+	outs, err := RunLocal(ctx, ints, splitEven)
+	if err != nil {
+		t.Fatalf("RunLocal(%v) failed: %v", ints, err)
+	}
+	if len(outs) != 2 || len(outs[0])+len(outs[1]) != 3 {
+		t.Errorf("RunLocal(%v) = %v, want 3 elements split in 2 outputs", ints, outs)
+	}
+
+	outs, err = RunLocal(ctx, kvs, formatCount)
+	if err != nil {
+		t.Fatalf("RunLocal(%v) failed: %v", kvs, err)
+	}
+	if want := [][]interface{}{{"a=1", "b=1"}}; !reflect.DeepEqual(outs, want) {
+		t.Errorf("RunLocal(%v) = %v, want %v", kvs, outs, want)
+	}
+
+	if _, err := RunLocal(ctx, kvs, splitEven); err == nil {
+		t.Errorf("RunLocal(%v) with mismatched DoFn succeeded, want error", kvs)
+	}
+	if _, err := ReadSample(ctx, filepath.Join(dir, "missing")); err == nil {
+		t.Error("ReadSample of missing file succeeded, want error")
+	}
+}
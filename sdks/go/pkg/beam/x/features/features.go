@@ -0,0 +1,228 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package features provides runtime feature flags, so that behavior can be
+// toggled without changing and redeploying pipeline code. Flags are set at
+// pipeline construction time, such as with the "features" flag of beamx:
+//
+//    --features=new_parser,batch_size=500
+//
+// and are shipped to workers with the pipeline options. DoFns query them
+// through their context:
+//
+//    func (f *myFn) ProcessElement(ctx context.Context, line string, emit func(string)) {
+//        if features.Enabled(ctx, "new_parser") {
+//            ...
+//        }
+//    }
+//
+// Flags can also be refreshed while the job is running by passing a side
+// input of flag specifications, such as lines read from a file, and
+// overriding the flags in the context with Refresh:
+//
+//    func (f *myFn) ProcessElement(ctx context.Context, line string, flags func(*string) bool, emit func(string)) {
+//        ctx = features.Refresh(ctx, flags)
+//        ...
+//    }
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apache/beam/sdks/go/pkg/beam/core/runtime"
+)
+
+// optionKey is the global option holding the JSON-encoded flags.
+const optionKey = "features"
+
+// Flags is a set of feature flags, keyed by name. Flags without an explicit
+// value have the value "true".
+type Flags map[string]string
+
+// Parse parses a comma-separated list of flag specifications of the form
+// "name" or "name=value". Whitespace around names and values is ignored.
+func Parse(spec string) (Flags, error) {
+	ret := make(Flags)
+	for _, s := range strings.Split(spec, ",") {
+		if err := ret.add(s); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func (f Flags) add(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	name, value := s, "true"
+	if i := strings.Index(s, "="); i >= 0 {
+		name, value = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	}
+	if name == "" {
+		return fmt.Errorf("invalid feature flag %q: missing name", s)
+	}
+	f[name] = value
+	return nil
+}
+
+// Value returns the value of the named flag, if set.
+func (f Flags) Value(name string) (string, bool) {
+	v, ok := f[name]
+	return v, ok
+}
+
+// Enabled returns true iff the named flag is set to a value other than a
+// false boolean value, such as "false" or "0".
+func (f Flags) Enabled(name string) bool {
+	v, ok := f[name]
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	return err != nil || b
+}
+
+func (f Flags) String() string {
+	var ret []string
+	for k, v := range f {
+		ret = append(ret, fmt.Sprintf("%v=%v", k, v))
+	}
+	sort.Strings(ret)
+	return strings.Join(ret, ",")
+}
+
+var (
+	// cached holds the flags decoded from the global options, keyed by the
+	// raw option value that produced them.
+	cached    Flags
+	cachedRaw string
+	mu        sync.Mutex
+)
+
+// Set sets the named flag for the pipeline. It must be called before the
+// pipeline is submitted for the flag to be visible on workers.
+func Set(name, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	f := decode(runtime.GlobalOptions.Get(optionKey))
+	f[name] = value
+	data, err := json.Marshal(f)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode feature flags: %v", err))
+	}
+	runtime.GlobalOptions.Set(optionKey, string(data))
+}
+
+// SetAll sets all the given flags for the pipeline.
+func SetAll(f Flags) {
+	for k, v := range f {
+		Set(k, v)
+	}
+}
+
+// Global returns a copy of the flags set for the pipeline.
+func Global() Flags {
+	ret := make(Flags)
+	for k, v := range global() {
+		ret[k] = v
+	}
+	return ret
+}
+
+func global() Flags {
+	mu.Lock()
+	defer mu.Unlock()
+
+	raw := runtime.GlobalOptions.Get(optionKey)
+	if cached == nil || raw != cachedRaw {
+		cached, cachedRaw = decode(raw), raw
+	}
+	return cached
+}
+
+func decode(raw string) Flags {
+	ret := make(Flags)
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &ret); err != nil {
+			panic(fmt.Sprintf("failed to decode feature flags %q: %v", raw, err))
+		}
+	}
+	return ret
+}
+
+type ctxKey string
+
+const flagsKey ctxKey = "beam:features"
+
+// WithFlags returns a context in which the given flags override the flags
+// set for the pipeline.
+func WithFlags(ctx context.Context, f Flags) context.Context {
+	if prev, ok := ctx.Value(flagsKey).(Flags); ok {
+		merged := make(Flags)
+		for k, v := range prev {
+			merged[k] = v
+		}
+		for k, v := range f {
+			merged[k] = v
+		}
+		f = merged
+	}
+	return context.WithValue(ctx, flagsKey, f)
+}
+
+// Refresh returns a context in which the flags read from the given side
+// input override the flags set for the pipeline. Each element of the side
+// input holds one or more comma-separated flag specifications, as accepted
+// by Parse. Malformed specifications are ignored, so that a bad update does
+// not fail the job.
+func Refresh(ctx context.Context, iter func(*string) bool) context.Context {
+	f := make(Flags)
+	var s string
+	for iter(&s) {
+		for _, spec := range strings.Split(s, ",") {
+			f.add(spec)
+		}
+	}
+	return WithFlags(ctx, f)
+}
+
+// Value returns the value of the named flag in the given context, if set.
+func Value(ctx context.Context, name string) (string, bool) {
+	if f, ok := ctx.Value(flagsKey).(Flags); ok {
+		if v, ok := f[name]; ok {
+			return v, true
+		}
+	}
+	return global().Value(name)
+}
+
+// Enabled returns true iff the named flag is enabled in the given context.
+func Enabled(ctx context.Context, name string) bool {
+	if f, ok := ctx.Value(flagsKey).(Flags); ok {
+		if _, ok := f[name]; ok {
+			return f.Enabled(name)
+		}
+	}
+	return global().Enabled(name)
+}
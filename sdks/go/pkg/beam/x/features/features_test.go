@@ -0,0 +1,68 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package features
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	f, err := Parse(" a, b=foo ,c = false,,")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got, want := f.String(), "a=true,b=foo,c=false"; got != want {
+		t.Errorf("Parse = %v, want %v", got, want)
+	}
+	if !f.Enabled("a") || !f.Enabled("b") || f.Enabled("c") || f.Enabled("d") {
+		t.Errorf("Enabled mismatch for %v", f)
+	}
+	if _, err := Parse("a,=b"); err == nil {
+		t.Errorf("Parse(\"a,=b\") succeeded, want error")
+	}
+}
+
+func TestContext(t *testing.T) {
+	Set("global", "1")
+	Set("overridden", "true")
+
+	ctx := context.Background()
+	if !Enabled(ctx, "global") || !Enabled(ctx, "overridden") {
+		t.Errorf("global flags not enabled: %v", Global())
+	}
+
+	lines := []string{"overridden=false", "added=7,bad=x=y", "=ignored"}
+	ctx = Refresh(ctx, func(s *string) bool {
+		if len(lines) == 0 {
+			return false
+		}
+		*s, lines = lines[0], lines[1:]
+		return true
+	})
+	if !Enabled(ctx, "global") {
+		t.Errorf("Enabled(global) = false after refresh, want true")
+	}
+	if Enabled(ctx, "overridden") {
+		t.Errorf("Enabled(overridden) = true after refresh, want false")
+	}
+	if v, ok := Value(ctx, "added"); !ok || v != "7" {
+		t.Errorf("Value(added) = %v, %v, want 7, true", v, ok)
+	}
+	if v, ok := Value(ctx, "bad"); !ok || v != "x=y" {
+		t.Errorf("Value(bad) = %v, %v, want x=y, true", v, ok)
+	}
+}
@@ -0,0 +1,216 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package workflow runs several pipelines with data dependencies from a
+// single binary. Each stage is a separate pipeline that is constructed and
+// executed only once all the stages it depends on have succeeded, so it may
+// read their output. For example:
+//
+//    w := workflow.New(beamx.Run)
+//    w.Add("extract", func(s beam.Scope) error {
+//         textio.Write(s, "gs://mybucket/stage1", extract(s))
+//         return nil
+//    })
+//    w.Add("report", func(s beam.Scope) error {
+//         report(s, textio.Read(s, "gs://mybucket/stage1"))
+//         return nil
+//    }, "extract")
+//    err := w.Run(ctx)
+//
+// Independent stages run concurrently. All stages share the global pipeline
+// options of the binary. If a stage fails, the stages that depend on it,
+// directly or transitively, are skipped, while unrelated stages still run.
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+	"github.com/apache/beam/sdks/go/pkg/beam/log"
+)
+
+// Status is the outcome of a stage.
+type Status int
+
+const (
+	// Pending means the stage has not run.
+	Pending Status = iota
+	// Succeeded means the stage pipeline completed successfully.
+	Succeeded
+	// Failed means the stage failed construction or execution.
+	Failed
+	// Skipped means the stage did not run, because a dependency failed.
+	Skipped
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pending:
+		return "Pending"
+	case Succeeded:
+		return "Succeeded"
+	case Failed:
+		return "Failed"
+	case Skipped:
+		return "Skipped"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Result is the outcome of a stage after the workflow has run.
+type Result struct {
+	Status Status
+	// Err is the stage failure, if Failed, or the dependency failure, if
+	// Skipped.
+	Err error
+}
+
+type stage struct {
+	name  string
+	build func(s beam.Scope) error
+	deps  []string
+
+	done   chan struct{}
+	result Result
+}
+
+// Workflow is a set of pipelines with dependencies. It is not safe to add
+// stages concurrently with Run.
+type Workflow struct {
+	run    func(context.Context, *beam.Pipeline) error
+	stages map[string]*stage
+	order  []string
+
+	// mu serializes pipeline construction, which may rely on global state.
+	mu sync.Mutex
+}
+
+// New returns an empty workflow that executes each stage pipeline with the
+// given function, such as beamx.Run.
+func New(run func(context.Context, *beam.Pipeline) error) *Workflow {
+	return &Workflow{run: run, stages: make(map[string]*stage)}
+}
+
+// Add adds a stage with the given name. The build function constructs the
+// stage pipeline and is invoked only once all the named dependencies have
+// succeeded. Dependencies must be added before the stages that use them,
+// which also rules out cycles. It panics if the name is a duplicate or a
+// dependency is unknown.
+func (w *Workflow) Add(name string, build func(s beam.Scope) error, deps ...string) {
+	if _, ok := w.stages[name]; ok {
+		panic(fmt.Sprintf("stage %v already added", name))
+	}
+	for _, dep := range deps {
+		if _, ok := w.stages[dep]; !ok {
+			panic(fmt.Sprintf("stage %v depends on unknown stage %v", name, dep))
+		}
+	}
+	w.stages[name] = &stage{name: name, build: build, deps: deps}
+	w.order = append(w.order, name)
+}
+
+// Run executes all stages and waits for them to complete. It returns an
+// error naming the failed stages, if any. Run may only be called once.
+func (w *Workflow) Run(ctx context.Context) error {
+	for _, name := range w.order {
+		w.stages[name].done = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range w.order {
+		wg.Add(1)
+		go func(st *stage) {
+			defer wg.Done()
+			defer close(st.done)
+
+			st.result = w.execute(ctx, st)
+		}(w.stages[name])
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, name := range w.order {
+		if w.stages[name].result.Status == Failed {
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("workflow failed: stages %v failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (w *Workflow) execute(ctx context.Context, st *stage) Result {
+	for _, name := range st.deps {
+		dep := w.stages[name]
+		<-dep.done
+
+		if dep.result.Status != Succeeded {
+			log.Warnf(ctx, "Skipping stage %v: dependency %v %v", st.name, name, dep.result.Status)
+			return Result{Status: Skipped, Err: fmt.Errorf("dependency %v %v", name, dep.result.Status)}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{Status: Skipped, Err: err}
+	}
+
+	p, err := w.construct(st)
+	if err != nil {
+		log.Errorf(ctx, "Stage %v construction failed: %v", st.name, err)
+		return Result{Status: Failed, Err: err}
+	}
+
+	log.Infof(ctx, "Running stage %v", st.name)
+	if err := w.run(ctx, p); err != nil {
+		log.Errorf(ctx, "Stage %v failed: %v", st.name, err)
+		return Result{Status: Failed, Err: err}
+	}
+	log.Infof(ctx, "Stage %v succeeded", st.name)
+	return Result{Status: Succeeded}
+}
+
+func (w *Workflow) construct(st *stage) (p *beam.Pipeline, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Construction errors are commonly reported as panics.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	p = beam.NewPipeline()
+	if err := st.build(p.Root().Scope(st.name)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Result returns the outcome of the given stage. It panics if the stage is
+// unknown.
+func (w *Workflow) Result(name string) Result {
+	st, ok := w.stages[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown stage %v", name))
+	}
+	return st.result
+}
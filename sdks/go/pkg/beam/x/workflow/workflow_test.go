@@ -0,0 +1,76 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/apache/beam/sdks/go/pkg/beam"
+)
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+
+	build := func(name string, fail bool) func(s beam.Scope) error {
+		return func(s beam.Scope) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if fail {
+				return fmt.Errorf("%v failed", name)
+			}
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	w := New(func(ctx context.Context, p *beam.Pipeline) error { return nil })
+	w.Add("a", build("a", false))
+	w.Add("b", build("b", false), "a")
+	w.Add("c", build("c", true), "a")
+	w.Add("d", build("d", false), "b", "c")
+	w.Add("e", build("e", false))
+
+	if err := w.Run(context.Background()); err == nil {
+		t.Errorf("Run() succeeded, want failure for stage c")
+	}
+
+	tests := map[string]Status{
+		"a": Succeeded,
+		"b": Succeeded,
+		"c": Failed,
+		"d": Skipped,
+		"e": Succeeded,
+	}
+	for name, want := range tests {
+		if got := w.Result(name).Status; got != want {
+			t.Errorf("Result(%v) = %v, want %v", name, got, want)
+		}
+	}
+
+	// Dependencies must have run before their dependents.
+	index := make(map[string]int)
+	for i, name := range ran {
+		index[name] = i
+	}
+	if index["a"] > index["b"] {
+		t.Errorf("ran %v, want a before b", ran)
+	}
+}